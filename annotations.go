@@ -0,0 +1,136 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+)
+
+// AnnotationKind tells whether an annotation covers an object key or a value.
+type AnnotationKind int
+
+const (
+	// AnnotationKey marks an object key, including its quotes.
+	AnnotationKey AnnotationKind = iota
+	// AnnotationValue marks a value. For objects and arrays the region
+	// spans from the opening to the closing delimiter.
+	AnnotationValue
+)
+
+// String returns "key" or "value".
+func (k AnnotationKind) String() string {
+	if k == AnnotationKey {
+		return "key"
+	}
+	return "value"
+}
+
+// Annotation describes the region of the formatted output occupied by a key or value.
+type Annotation struct {
+	// Path contains the object keys and array indices leading to the key or value,
+	// in the same form as ValueContext.Path. The root value has an empty path.
+	Path []string
+
+	// Kind tells whether the region is an object key or a value.
+	Kind AnnotationKind
+
+	// Start is the byte offset in the output where the region starts.
+	Start int
+
+	// End is the byte offset in the output just after the region.
+	End int
+}
+
+// FormatWithAnnotations formats a JSON string like Format and also returns an
+// annotation for every key and value, giving its path and its byte range in the
+// formatted output. Annotations are in document order, with containers listed
+// before their contents. Tools can use them to highlight, fold, or link regions
+// of the output without parsing it again.
+//
+// The cache set with SetCache is not used.
+//
+// Example:
+//
+//	formatted, annotations, err := formatter.FormatWithAnnotations(`{"a":[1]}`)
+//	for _, a := range annotations {
+//	    fmt.Println(a.Path, a.Kind, formatted[a.Start:a.End])
+//	}
+func (f *Formatter) FormatWithAnnotations(jsonStr string) (result string, annotations []Annotation, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+			annotations = nil
+		}
+	}()
+
+	if f.config.rewritesLines() {
+		return "", nil, NewFormatError("annotations are not available with a post-processor or hard wrapping, as they change output offsets")
+	}
+
+	result, parser, err := f.formatString(jsonStr, func(p *TokenParser) { p.annotating = true })
+	if err != nil {
+		return "", nil, err
+	}
+	return result, parser.annotations, nil
+}
+
+// markValueStart records where the next key or value literal starts in the output
+func (p *TokenParser) markValueStart() {
+	if !p.annotating {
+		return
+	}
+	p.valueStart = p.outputOffset()
+	if p.depth > 0 && !p.isInArray() && !p.expectingKey {
+		// Object values are written together with the space after the colon
+		p.valueStart++
+	}
+}
+
+// annotate records the output region for a token that has just been written
+func (p *TokenParser) annotate(token json.Token, path []string, isKey bool) {
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		p.openRegions = append(p.openRegions, len(p.annotations))
+		p.annotations = append(p.annotations, Annotation{Path: path, Kind: AnnotationValue, Start: p.valueStart})
+	case json.Delim('}'), json.Delim(']'):
+		if len(p.openRegions) == 0 {
+			return
+		}
+		index := p.openRegions[len(p.openRegions)-1]
+		p.openRegions = p.openRegions[:len(p.openRegions)-1]
+		p.annotations[index].End = p.outputOffset()
+	default:
+		if isKey {
+			// The key is followed by the colon, which is not part of the region
+			p.annotations = append(p.annotations, Annotation{Path: p.valuePath(), Kind: AnnotationKey, Start: p.valueStart, End: p.outputOffset() - 1})
+			return
+		}
+		p.annotations = append(p.annotations, Annotation{Path: path, Kind: AnnotationValue, Start: p.valueStart, End: p.outputOffset()})
+	}
+}
+
+// outputOffset returns the number of bytes written to the output so far
+func (p *TokenParser) outputOffset() int {
+	offset := 0
+	if p.capture != nil {
+		offset = p.capture.base
+	}
+	if b, ok := p.builder.(interface{ Len() int }); ok {
+		offset += b.Len()
+	}
+	return offset
+}
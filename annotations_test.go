@@ -0,0 +1,100 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatWithAnnotations(t *testing.T) {
+	result, annotations, err := NewFormatter(DefaultConfig()).FormatWithAnnotations(`{"name":"Alice","tags":["a",{"x":null}],"age":30}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type region struct {
+		Path string
+		Kind AnnotationKind
+		Text string
+	}
+	var got []region
+	for _, a := range annotations {
+		got = append(got, region{strings.Join(a.Path, "."), a.Kind, result[a.Start:a.End]})
+	}
+
+	expected := []region{
+		{"", AnnotationValue, result},
+		{"name", AnnotationKey, `"name"`},
+		{"name", AnnotationValue, `"Alice"`},
+		{"tags", AnnotationKey, `"tags"`},
+		{"tags", AnnotationValue, "[\n    \"a\",\n    {\"x\": null}\n  ]"},
+		{"tags.0", AnnotationValue, `"a"`},
+		{"tags.1", AnnotationValue, `{"x": null}`},
+		{"tags.1.x", AnnotationKey, `"x"`},
+		{"tags.1.x", AnnotationValue, `null`},
+		{"age", AnnotationKey, `"age"`},
+		{"age", AnnotationValue, `30`},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected annotations:\n%#v\n\nGot:\n%#v", expected, got)
+	}
+}
+
+func TestFormatWithAnnotationsMatchesFormat(t *testing.T) {
+	inputs := []string{
+		`"plain"`,
+		`{"users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}}}`,
+		`{"text":"line\nbreak \"quoted\"","名前":"値","list":[1.5e3,-2,true]}`,
+	}
+	configs := map[string]*Config{
+		"default":  DefaultConfig(),
+		"expanded": NewConfig(WithCompactDepth(0)),
+		"width":    NewConfig(WithMaxWidth(30)),
+		"tabs":     NewConfig(WithTabs(), WithMaxWidth(20)),
+	}
+
+	for name, config := range configs {
+		for _, input := range inputs {
+			formatter := NewFormatter(config)
+			expected, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			result, annotations, err := formatter.FormatWithAnnotations(input)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if result != expected {
+				t.Errorf("%s: expected output:\n%s\n\nGot:\n%s", name, expected, result)
+			}
+			if len(annotations) == 0 || annotations[0].Start != 0 || annotations[0].End != len(result) {
+				t.Errorf("%s: root annotation does not cover the output: %+v", name, annotations)
+			}
+			for _, a := range annotations {
+				if a.Start < 0 || a.End > len(result) || a.Start >= a.End {
+					t.Errorf("%s: invalid region %+v for %q", name, a, input)
+					continue
+				}
+				text := result[a.Start:a.End]
+				var decoded interface{}
+				if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+					t.Errorf("%s: region %v (%s) is not valid JSON: %q", name, a.Path, a.Kind, text)
+				}
+				if a.Kind == AnnotationKey && decoded != a.Path[len(a.Path)-1] {
+					t.Errorf("%s: key region %v contains %q", name, a.Path, text)
+				}
+			}
+		}
+	}
+}
+
+func TestFormatWithAnnotationsError(t *testing.T) {
+	_, annotations, err := NewFormatter(DefaultConfig()).FormatWithAnnotations(`{"a":`)
+	if err == nil {
+		t.Fatal("Expected error for incomplete JSON")
+	}
+	if annotations != nil {
+		t.Errorf("Expected no annotations on error, got %+v", annotations)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FakeKind selects the kind of fake data used to anonymize a value.
+type FakeKind int
+
+const (
+	// FakeAuto infers the kind from the key and the original value.
+	FakeAuto FakeKind = iota
+	// FakeName replaces the value with a person name like "Alice Smith".
+	FakeName
+	// FakeEmail replaces the value with an address like "alice.smith@example.com".
+	FakeEmail
+	// FakeUUID replaces the value with a UUID.
+	FakeUUID
+	// FakeToken replaces the value with an opaque string like "anon-1a2b3c4d".
+	FakeToken
+)
+
+var (
+	fakeFirstNames = []string{
+		"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi",
+		"Ivan", "Judy", "Mallory", "Niaj", "Olivia", "Peggy", "Rupert", "Sybil",
+		"Trent", "Uma", "Victor", "Walter",
+	}
+	fakeLastNames = []string{
+		"Smith", "Johnson", "Brown", "Taylor", "Miller", "Wilson", "Moore", "Clark",
+		"Lewis", "Walker", "Young", "King", "Wright", "Hill", "Green", "Baker",
+	}
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// WithAnonymize replaces the values of the given object keys with deterministic
+// fake data. The kind of fake data is inferred: emails are replaced with emails,
+// UUIDs with UUIDs, values of keys containing "name" with person names, and other
+// strings with opaque tokens. Numbers are replaced with numbers of the same
+// number of digits. Booleans and null are kept.
+//
+// Fakes are derived from a hash of the original value, so the same input value
+// always produces the same fake, and references between records stay intact.
+// Without WithAnonymizationSalt anyone can compute the same hashes, so the
+// original values can be recovered by hashing candidates such as known emails
+// or sequential IDs; set a secret salt for output shared outside a team.
+//
+// Example:
+//
+//	config := NewConfig(WithAnonymize("name", "email", "userId"), WithAnonymizationSalt(secret))
+func WithAnonymize(keys ...string) ConfigOption {
+	rules := make(map[string]FakeKind, len(keys))
+	for _, key := range keys {
+		rules[key] = FakeAuto
+	}
+	return WithAnonymizeRules(rules)
+}
+
+// WithAnonymizeRules is like WithAnonymize, but selects the kind of fake data
+// for each key explicitly. The map is copied.
+//
+// Example:
+//
+//	config := NewConfig(WithAnonymizeRules(map[string]FakeKind{
+//	    "owner":   FakeName,
+//	    "contact": FakeEmail,
+//	}))
+func WithAnonymizeRules(rules map[string]FakeKind) ConfigOption {
+	copied := make(map[string]FakeKind, len(rules))
+	for key, kind := range rules {
+		copied[key] = kind
+	}
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		kind, ok := copied[ctx.Key]
+		if !ok {
+			return nil, false
+		}
+		fake, ok := anonymizeValue(ctx.Key, ctx.Value, kind, ctx.salt)
+		if !ok {
+			return nil, false
+		}
+		return AuditedValue{Value: fake, Rule: "anonymize:" + ctx.Key, Action: "anonymized"}, true
+	})
+}
+
+// WithAnonymizationSalt derives the fakes of WithAnonymize and
+// WithAnonymizeRules from an HMAC of the original value keyed by salt, so
+// they cannot be traced back to the original values without the salt. The
+// same salt gives the same fakes, so outputs anonymized separately still
+// match; keep it secret and change it to unlink earlier outputs.
+//
+// Example:
+//
+//	config := NewConfig(WithAnonymize("email"), WithAnonymizationSalt(os.Getenv("ANON_SALT")))
+func WithAnonymizationSalt(salt string) ConfigOption {
+	return func(c *Config) {
+		c.AnonymizationSalt = salt
+	}
+}
+
+// RedactedValue is the string written in place of values by WithRedaction.
+const RedactedValue = "[REDACTED]"
+
+// WithRedaction replaces the values of the given object keys, and every value
+// nested below them, with the string RedactedValue, so secrets such as
+// webhook signatures never reach shared output. Keys are matched without
+// regard to case, as header names often differ in case between senders.
+//
+// Example:
+//
+//	config := NewConfig(WithRedaction("signature", "authorization"))
+//	// {"signature": "sha256=4f2a…"}  →  {"signature": "[REDACTED]"}
+func WithRedaction(keys ...string) ConfigOption {
+	redacted := make(map[string]string, len(keys))
+	for _, key := range keys {
+		redacted[strings.ToLower(key)] = key
+	}
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		for _, segment := range ctx.Path {
+			if key, ok := redacted[strings.ToLower(segment)]; ok {
+				return AuditedValue{Value: RedactedValue, Rule: "redaction:" + key, Action: "redacted"}, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// WithRedactedPaths replaces the values at paths matching the patterns, and
+// every value nested below them, with the string RedactedValue. Patterns are
+// dot-separated paths as in ValueContext.MatchPath.
+//
+// Example:
+//
+//	config := NewConfig(WithRedactedPaths("users.*.password"))
+//	// {"users": [{"password": "hunter2"}]}  →  {"users": [{"password": "[REDACTED]"}]}
+func WithRedactedPaths(patterns ...string) ConfigOption {
+	compiled := compilePatterns(patterns)
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		for i := len(ctx.Path); i > 0; i-- {
+			if pattern, ok := firstMatch(compiled, ctx.Path[:i]); ok {
+				return AuditedValue{Value: RedactedValue, Rule: "redacted-path:" + pattern, Action: "redacted"}, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// anonymizeValue returns the deterministic fake for a single value
+func anonymizeValue(key string, value interface{}, kind FakeKind, salt string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if kind == FakeAuto {
+			kind = inferFakeKind(key, v)
+		}
+		return fakeString(v, kind, salt), true
+	case float64:
+		return fakeNumber(v, salt), true
+	default:
+		return nil, false
+	}
+}
+
+// inferFakeKind picks a kind of fake data from the key and original value
+func inferFakeKind(key, value string) FakeKind {
+	switch {
+	case strings.Contains(value, "@"):
+		return FakeEmail
+	case uuidPattern.MatchString(value):
+		return FakeUUID
+	case strings.Contains(strings.ToLower(key), "name"):
+		return FakeName
+	default:
+		return FakeToken
+	}
+}
+
+// fakeSum returns the hash that fakes of the original value are derived
+// from: an HMAC keyed by the salt, or a plain hash without one
+func fakeSum(value, salt string) [sha256.Size]byte {
+	if salt == "" {
+		return sha256.Sum256([]byte(value))
+	}
+	var sum [sha256.Size]byte
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	copy(sum[:], mac.Sum(nil))
+	return sum
+}
+
+// fakeString generates a fake string of the given kind seeded by the original value
+func fakeString(value string, kind FakeKind, salt string) string {
+	sum := fakeSum(value, salt)
+	first := fakeFirstNames[binary.BigEndian.Uint32(sum[0:4])%uint32(len(fakeFirstNames))]
+	last := fakeLastNames[binary.BigEndian.Uint32(sum[4:8])%uint32(len(fakeLastNames))]
+
+	switch kind {
+	case FakeName:
+		return first + " " + last
+	case FakeEmail:
+		return fmt.Sprintf("%s.%s.%s@example.com", strings.ToLower(first), strings.ToLower(last), hex.EncodeToString(sum[8:10]))
+	case FakeUUID:
+		id := sum[:16]
+		id[6] = (id[6] & 0x0f) | 0x40 // version 4
+		id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+	default:
+		return "anon-" + hex.EncodeToString(sum[:4])
+	}
+}
+
+// fakeNumber generates a fake integer with the same number of digits as the original value
+func fakeNumber(value float64, salt string) float64 {
+	sum := fakeSum(fmt.Sprint(value), salt)
+	digits := 1
+	if abs := math.Abs(value); abs >= 1 {
+		digits = int(math.Log10(abs)) + 1
+	}
+	if digits > 15 {
+		digits = 15
+	}
+	low := math.Pow10(digits - 1)
+	if digits == 1 {
+		low = 0
+	}
+	span := uint64(math.Pow10(digits) - low)
+	fake := low + float64(binary.BigEndian.Uint64(sum[:8])%span)
+	if value < 0 {
+		fake = -fake
+	}
+	return fake
+}
+
+// DateGranularity selects how far dates are coarsened by WithDateCoarsening.
+type DateGranularity int
+
+const (
+	// CoarsenToHour truncates timestamps to the hour.
+	CoarsenToHour DateGranularity = iota
+	// CoarsenToDay truncates timestamps to the day.
+	CoarsenToDay
+	// CoarsenToMonth truncates timestamps to the first day of the month.
+	CoarsenToMonth
+	// CoarsenToYear truncates timestamps to the first day of the year.
+	CoarsenToYear
+)
+
+// dateLayouts are the timestamp layouts recognized by date coarsening
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// WithJitter adds random noise to numbers at the given paths, so shared payloads
+// keep their rough magnitudes but cannot be traced back to exact values.
+// Each number is multiplied by a factor between 1-ratio and 1+ratio, and
+// integers stay integers. The noise is seeded once per option, so the same value
+// is jittered the same way within a configuration but differently across
+// configurations. Paths use the syntax of ValueContext.MatchPath.
+//
+// Example:
+//
+//	config := NewConfig(WithJitter(0.1, "users.*.age", "users.*.balance")) // ±10%
+func WithJitter(ratio float64, paths ...string) ConfigOption {
+	ratio = math.Abs(ratio)
+	var salt [16]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		panic(err) // crypto/rand.Read never returns an error on supported platforms
+	}
+	patterns := compilePatterns(paths)
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		value, ok := ctx.Value.(float64)
+		if !ok {
+			return nil, false
+		}
+		pattern, ok := firstMatch(patterns, ctx.Path)
+		if !ok {
+			return nil, false
+		}
+		return AuditedValue{Value: jitterNumber(value, ratio, salt[:]), Rule: "jitter:" + pattern, Action: "jittered"}, true
+	})
+}
+
+// WithDateCoarsening truncates date and timestamp strings at the given paths to
+// the given granularity, keeping their original layout. RFC 3339 timestamps and
+// "2006-01-02" style dates are recognized; other strings are kept.
+// Paths use the syntax of ValueContext.MatchPath.
+//
+// Example:
+//
+//	config := NewConfig(WithDateCoarsening(CoarsenToMonth, "users.*.birthday"))
+func WithDateCoarsening(granularity DateGranularity, paths ...string) ConfigOption {
+	patterns := compilePatterns(paths)
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		value, ok := ctx.Value.(string)
+		if !ok {
+			return nil, false
+		}
+		pattern, ok := firstMatch(patterns, ctx.Path)
+		if !ok {
+			return nil, false
+		}
+		coarsened, ok := coarsenDate(value, granularity)
+		if !ok {
+			return nil, false
+		}
+		return AuditedValue{Value: coarsened, Rule: "date-coarsening:" + pattern, Action: "coarsened"}, true
+	})
+}
+
+// jitterNumber multiplies the value by a salted, hash-derived factor within ±ratio
+func jitterNumber(value, ratio float64, salt []byte) float64 {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(fmt.Sprint(value)))
+	sum := hash.Sum(nil)
+
+	// Map the hash onto [-1, 1]
+	noise := float64(binary.BigEndian.Uint64(sum[:8]))/math.MaxUint64*2 - 1
+	jittered := value * (1 + noise*ratio)
+	if value == math.Trunc(value) {
+		jittered = math.Round(jittered)
+	}
+	return jittered
+}
+
+// coarsenDate truncates a date string to the granularity, keeping its layout
+func coarsenDate(value string, granularity DateGranularity) (string, bool) {
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		switch granularity {
+		case CoarsenToHour:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		case CoarsenToDay:
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		case CoarsenToMonth:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		default:
+			t = time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+		}
+		if layout == time.RFC3339Nano {
+			return t.Format(time.RFC3339), true
+		}
+		return t.Format(layout), true
+	}
+	return "", false
+}
@@ -0,0 +1,225 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	config := NewConfig(WithAnonymize("name", "email", "id", "token", "age"), WithCompactDepth(0))
+	input := `{"name":"Jane Doe","email":"jane@corp.example","id":"123e4567-e89b-12d3-a456-426614174000","token":"s3cr3t","age":42,"active":true,"city":"Tokyo"}`
+
+	first, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected deterministic output, got:\n%s\n\nand:\n%s", first, second)
+	}
+
+	for _, original := range []string{"Jane Doe", "jane@corp.example", "123e4567-e89b-12d3-a456-426614174000", "s3cr3t", ": 42"} {
+		if strings.Contains(first, original) {
+			t.Errorf("Expected %q to be anonymized, got:\n%s", original, first)
+		}
+	}
+	for _, kept := range []string{`"active": true`, `"city": "Tokyo"`} {
+		if !strings.Contains(first, kept) {
+			t.Errorf("Expected %q to be kept, got:\n%s", kept, first)
+		}
+	}
+}
+
+func TestFakeKinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		kind  FakeKind
+		check func(string) bool
+	}{
+		{
+			name: "inferred email", key: "contact", value: "jane@corp.example", kind: FakeAuto,
+			check: func(s string) bool { return strings.HasSuffix(s, "@example.com") },
+		},
+		{
+			name: "inferred uuid", key: "ref", value: "123e4567-e89b-12d3-a456-426614174000", kind: FakeAuto,
+			check: func(s string) bool { return uuidPattern.MatchString(s) && s[14] == '4' },
+		},
+		{
+			name: "inferred name", key: "displayName", value: "Jane Doe", kind: FakeAuto,
+			check: func(s string) bool { return len(strings.Fields(s)) == 2 },
+		},
+		{
+			name: "inferred token", key: "note", value: "hello", kind: FakeAuto,
+			check: func(s string) bool { return strings.HasPrefix(s, "anon-") && len(s) == 13 },
+		},
+		{
+			name: "explicit name", key: "owner", value: "jane@corp.example", kind: FakeName,
+			check: func(s string) bool { return !strings.Contains(s, "@") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, ok := anonymizeValue(tt.key, tt.value, tt.kind, "")
+			if !ok {
+				t.Fatal("Expected value to be anonymized")
+			}
+			if !tt.check(fake.(string)) {
+				t.Errorf("Unexpected fake %q", fake)
+			}
+		})
+	}
+}
+
+func TestFakeNumberKeepsMagnitude(t *testing.T) {
+	tests := []struct {
+		value    float64
+		min, max float64
+	}{
+		{value: 7, min: 0, max: 9},
+		{value: 42, min: 10, max: 99},
+		{value: -12345, min: -99999, max: -10000},
+	}
+
+	for _, tt := range tests {
+		fake := fakeNumber(tt.value, "")
+		if fake < tt.min || fake > tt.max {
+			t.Errorf("fakeNumber(%v) = %v, expected between %v and %v", tt.value, fake, tt.min, tt.max)
+		}
+		if fake != fakeNumber(tt.value, "") {
+			t.Errorf("fakeNumber(%v) is not deterministic", tt.value)
+		}
+	}
+}
+
+func TestAnonymizationSalt(t *testing.T) {
+	input := `{"email":"jane@corp.example","id":4711}`
+	format := func(options ...ConfigOption) string {
+		result, err := NewFormatter(NewConfig(append([]ConfigOption{WithAnonymize("email", "id")}, options...)...)).Format(input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result
+	}
+
+	unsalted := format()
+	salted := format(WithAnonymizationSalt("s3cret"))
+	if salted == unsalted {
+		t.Errorf("Expected the salt to change the fakes, got:\n%s", salted)
+	}
+	if again := format(WithAnonymizationSalt("s3cret")); again != salted {
+		t.Errorf("Expected the same salt to give the same fakes, got:\n%s\n\nand:\n%s", salted, again)
+	}
+	if other := format(WithAnonymizationSalt("other")); other == salted {
+		t.Errorf("Expected another salt to give other fakes, got:\n%s", other)
+	}
+	if strings.Contains(salted, "jane") || strings.Contains(salted, "4711") {
+		t.Errorf("Expected the original values to be replaced, got:\n%s", salted)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	var seen []float64
+	config := NewConfig(
+		WithJitter(0.1, "users.*.balance"),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			if v, ok := ctx.Value.(float64); ok && ctx.MatchPath("users.*.balance") {
+				seen = append(seen, v)
+			}
+			return nil, false
+		}),
+	)
+
+	result, err := NewFormatter(config).Format(`{"users":[{"id":1,"balance":1000},{"id":2,"balance":250.5}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"id": 1`) || !strings.Contains(result, `"id": 2`) {
+		t.Errorf("Expected values outside the paths to be kept, got:\n%s", result)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("Expected 2 jittered values, got %v", seen)
+	}
+	if seen[0] < 900 || seen[0] > 1100 || seen[0] != float64(int(seen[0])) {
+		t.Errorf("Expected integer within 10%% of 1000, got %v", seen[0])
+	}
+	if seen[1] < 225.45 || seen[1] > 275.55 {
+		t.Errorf("Expected value within 10%% of 250.5, got %v", seen[1])
+	}
+}
+
+func TestDateCoarsening(t *testing.T) {
+	tests := []struct {
+		name        string
+		granularity DateGranularity
+		input       string
+		expected    string
+		ok          bool
+	}{
+		{name: "hour", granularity: CoarsenToHour, input: "2024-03-15T10:22:33.123Z", expected: "2024-03-15T10:00:00Z", ok: true},
+		{name: "day with offset", granularity: CoarsenToDay, input: "2024-03-15T10:22:33+09:00", expected: "2024-03-15T00:00:00+09:00", ok: true},
+		{name: "month of date", granularity: CoarsenToMonth, input: "2024-03-15", expected: "2024-03-01", ok: true},
+		{name: "year", granularity: CoarsenToYear, input: "2024-03-15 10:22:33", expected: "2024-01-01 00:00:00", ok: true},
+		{name: "not a date", granularity: CoarsenToDay, input: "yesterday", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := coarsenDate(tt.input, tt.granularity)
+			if ok != tt.ok || result != tt.expected {
+				t.Errorf("coarsenDate(%q) = %q, %t; expected %q, %t", tt.input, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDateCoarseningPaths(t *testing.T) {
+	config := NewConfig(WithDateCoarsening(CoarsenToMonth, "user.birthday"))
+
+	result, err := NewFormatter(config).Format(`{"user":{"birthday":"1990-07-23","joined":"2020-01-15"}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "user": {
+    "birthday": "1990-07-01",
+    "joined": "2020-01-15"
+  }
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	config := NewConfig(WithRedaction("signature", "authorization"), WithCompactDepth(1))
+	input := `{"headers":{"Authorization":"Bearer abc","X-Request-Id":"42"},"signature":{"alg":"hmac","value":"sha256=4f2a"},"signatures":["kept"],"count":3}`
+	expected := `{"headers": {"Authorization": "[REDACTED]", "X-Request-Id": "42"}, "signature": {"alg": "[REDACTED]", "value": "[REDACTED]"}, "signatures": ["kept"], "count": 3}`
+
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestRedactedPaths(t *testing.T) {
+	config := NewConfig(WithRedactedPaths("users.*.password", "secrets"), WithCompactDepth(1))
+	input := `{"users":[{"name":"a","password":"hunter2"}],"secrets":{"token":"t","keys":[1,2]},"password":"kept"}`
+	expected := `{"users": [{"name": "a", "password": "[REDACTED]"}], "secrets": {"token": "[REDACTED]", "keys": ["[REDACTED]", "[REDACTED]"]}, "password": "kept"}`
+
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
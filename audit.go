@@ -0,0 +1,88 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+// AuditedValue is a value hook result that replaces a value like any other
+// result, and also records an AuditEntry for FormatWithAudit. The redaction
+// and anonymization options return it, and custom hooks that mask values can
+// return it too, so every masked value shows up in the audit.
+//
+// Example:
+//
+//	config := NewConfig(WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+//	    if ctx.Key == "password" {
+//	        return AuditedValue{Value: "********", Rule: "password-mask", Action: "masked"}, true
+//	    }
+//	    return nil, false
+//	}))
+type AuditedValue struct {
+	// Value is the replacement: a string, float64, bool, nil, or RawValue.
+	Value interface{}
+
+	// Rule and Action are copied to the audit entry.
+	Rule   string
+	Action string
+}
+
+// AuditEntry records a value replaced by a redaction or anonymization rule.
+// Entries never contain the original value.
+type AuditEntry struct {
+	// Path contains the object keys and array indices leading to the value,
+	// in the same form as ValueContext.Path.
+	Path []string
+
+	// Rule names the option that fired and the key or pattern that matched:
+	//   - "redaction:signature" for WithRedaction
+	//   - "redacted-path:users.*.password" for WithRedactedPaths
+	//   - "anonymize:email" for WithAnonymize and WithAnonymizeRules
+	//   - "jitter:users.*.age" for WithJitter
+	//   - "date-coarsening:users.*.birthday" for WithDateCoarsening
+	Rule string
+
+	// Action is what the rule did to the value: "redacted", "anonymized",
+	// "jittered", or "coarsened".
+	Action string
+}
+
+// FormatWithAudit formats a JSON string like Format and also returns an audit
+// entry for every value replaced by a redaction or anonymization rule, in
+// document order, so compliance teams can verify exactly what was masked in
+// payloads attached to tickets.
+//
+// The cache set with SetCache is not used.
+//
+// Example:
+//
+//	formatter := NewFormatter(NewConfig(WithRedaction("password"), WithAnonymize("email")))
+//	formatted, audit, err := formatter.FormatWithAudit(body)
+//	for _, entry := range audit {
+//	    log.Printf("%s: %s by %s", strings.Join(entry.Path, "."), entry.Action, entry.Rule)
+//	}
+func (f *Formatter) FormatWithAudit(jsonStr string) (result string, audit []AuditEntry, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+			audit = nil
+		}
+	}()
+
+	result, parser, err := f.formatString(jsonStr, func(p *TokenParser) { p.auditing = true })
+	if err != nil {
+		return "", nil, err
+	}
+	return result, parser.audit, nil
+}
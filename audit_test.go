@@ -0,0 +1,88 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatWithAudit(t *testing.T) {
+	formatter := NewFormatter(NewConfig(
+		WithRedaction("Signature"),
+		WithRedactedPaths("users.*.password"),
+		WithAnonymize("email"),
+		WithJitter(0.1, "users.*.age"),
+		WithDateCoarsening(CoarsenToYear, "users.*.born"),
+	))
+	input := `{"signature":{"alg":"sha256","value":"4f2a"},"users":[{"email":"alice@example.com","password":"hunter2","age":30,"born":"1990-05-17","note":"hi"}]}`
+
+	result, audit, err := formatter.FormatWithAudit(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+
+	var got []string
+	for _, entry := range audit {
+		got = append(got, strings.Join(entry.Path, ".")+" "+entry.Action+" "+entry.Rule)
+	}
+	want := []string{
+		"signature.alg redacted redaction:Signature",
+		"signature.value redacted redaction:Signature",
+		"users.0.email anonymized anonymize:email",
+		"users.0.password redacted redacted-path:users.*.password",
+		"users.0.age jittered jitter:users.*.age",
+		"users.0.born coarsened date-coarsening:users.*.born",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if strings.Contains(result, "hunter2") || strings.Contains(result, "alice@example.com") {
+		t.Errorf("Expected masked values in the output, got:\n%s", result)
+	}
+}
+
+func TestFormatWithAuditCustomHook(t *testing.T) {
+	formatter := NewFormatter(NewConfig(
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			if ctx.Key == "pin" {
+				return AuditedValue{Value: "****", Rule: "pin-mask", Action: "masked"}, true
+			}
+			return nil, false
+		}),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			// Later hooks see the replacement, not the AuditedValue
+			if s, ok := ctx.Value.(string); ok && s == "****" {
+				return s + "!", true
+			}
+			return nil, false
+		}),
+	))
+
+	result, audit, err := formatter.FormatWithAudit(`{"pin":"1234","other":"x"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "{\n  \"pin\": \"****!\",\n  \"other\": \"x\"\n}"; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	expectedAudit := []AuditEntry{{Path: []string{"pin"}, Rule: "pin-mask", Action: "masked"}}
+	if !reflect.DeepEqual(audit, expectedAudit) {
+		t.Errorf("Expected:\n%#v\n\nGot:\n%#v", expectedAudit, audit)
+	}
+}
+
+func TestFormatWithAuditUnsupportedValue(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		return AuditedValue{Value: []int{1}, Rule: "bad", Action: "masked"}, true
+	})))
+	if _, _, err := formatter.FormatWithAudit(`{"a":1}`); err == nil {
+		t.Error("Expected an error for an unsupported audited value")
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithWorkers sets the number of files FormatFiles formats concurrently.
+// Values below 1 use GOMAXPROCS, which is the default.
+//
+// Example:
+//
+//	summary := formatter.FormatFiles(files, WithWorkers(4))
+func WithWorkers(workers int) FileOption {
+	return func(o *fileOptions) {
+		if workers > 0 {
+			o.workers = workers
+		}
+	}
+}
+
+// BatchSummary aggregates the results of FormatFiles.
+type BatchSummary struct {
+	// Results holds one result per path, in the order of the paths.
+	Results []FileResult
+
+	// Changed, Unchanged, and Failed count the files by outcome. In dry-run
+	// mode, Changed counts the files that would change.
+	Changed   int
+	Unchanged int
+	Failed    int
+
+	// Duration is the time formatting all files took.
+	Duration time.Duration
+
+	// Skipped lists files left out before formatting, such as the files
+	// skipped by a Walker. FormatFiles does not set it, but callers can add
+	// them to include them in the JSON report.
+	Skipped []SkippedFile
+}
+
+// Err returns the errors of the failed files joined into one error, each
+// prefixed with its path, or nil if no file failed.
+func (s *BatchSummary) Err() error {
+	var errs []error
+	for _, result := range s.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Path, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FormatFiles formats files like FormatFile, concurrently with a bounded
+// number of workers set by WithWorkers. A file that fails does not stop the
+// others; its error is recorded in its result.
+//
+// Example:
+//
+//	files, _ := (&Walker{}).Walk(".")
+//	summary := formatter.FormatFiles(files)
+//	fmt.Printf("%d changed, %d unchanged, %d failed\n", summary.Changed, summary.Unchanged, summary.Failed)
+//	if err := summary.Err(); err != nil {
+//	    log.Fatal(err)
+//	}
+func (f *Formatter) FormatFiles(paths []string, options ...FileOption) *BatchSummary {
+	var settings fileOptions
+	for _, option := range options {
+		option(&settings)
+	}
+	workers := settings.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	start := time.Now()
+	summary := &BatchSummary{Results: make([]FileResult, len(paths))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				fileStart := time.Now()
+				result, err := f.FormatFile(paths[index], options...)
+				if err != nil {
+					result = &FileResult{Path: paths[index], Err: err}
+				}
+				result.Duration = time.Since(fileStart)
+				summary.Results[index] = *result
+			}
+		}()
+	}
+	for index := range paths {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+	summary.Duration = time.Since(start)
+
+	for _, result := range summary.Results {
+		switch {
+		case result.Err != nil:
+			summary.Failed++
+		case result.Changed:
+			summary.Changed++
+		default:
+			summary.Unchanged++
+		}
+	}
+	return summary
+}
+
+// BatchResult is the outcome of one document of FormatBatch.
+type BatchResult struct {
+	// Output is the formatted document, or empty if it failed.
+	Output string
+
+	// Err is the error formatting the document, or nil.
+	Err error
+}
+
+// FormatBatch formats many small documents in one call, so pipelines that
+// format millions of payloads, such as telemetry, share the compiled
+// configuration of the formatter and a fixed set of workers instead of
+// paying for them per document. With WithRecordWorkers, the documents are
+// formatted concurrently; the results are in the order of docs either way.
+//
+// A document that fails does not stop the others. Its error is recorded in
+// its result, and the returned error joins the errors of all failed
+// documents, each prefixed with its number starting at 1.
+//
+// Example:
+//
+//	f := NewFormatter(NewConfig(WithRecordWorkers(runtime.GOMAXPROCS(0))))
+//	results, err := f.FormatBatch(payloads)
+//	for i, result := range results {
+//	    if result.Err == nil {
+//	        publish(i, result.Output)
+//	    }
+//	}
+func (f *Formatter) FormatBatch(docs []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(docs))
+	workers := min(max(f.config.RecordWorkers, 1), len(docs))
+
+	// Workers take the next document from a shared counter, which costs less
+	// than a channel send per document
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				index := int(next.Add(1)) - 1
+				if index >= len(docs) {
+					return
+				}
+				results[index].Output, results[index].Err = f.Format(docs[index])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("document %d: %w", i+1, result.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// MarshalJSON encodes the summary as a JSON report for CI bots, with the
+// counts of files by outcome and the status of every file. Statuses are
+// "changed", "unchanged", and "failed"; "applied" tells whether a changed file
+// was written, and dry runs include the diff. Durations are in milliseconds.
+//
+// Example output:
+//
+//	{"changed":1,"unchanged":0,"failed":1,"skipped":0,"durationMs":1.5,"files":[
+//	 {"path":"a.json","status":"changed","applied":true,"durationMs":0.7},
+//	 {"path":"b.json","status":"failed","applied":false,"durationMs":0.2,"error":{"code":"syntax",...}}]}
+func (s *BatchSummary) MarshalJSON() ([]byte, error) {
+	type fileReport struct {
+		Path       string          `json:"path"`
+		Status     string          `json:"status"`
+		Applied    bool            `json:"applied"`
+		DurationMS float64         `json:"durationMs"`
+		Diff       string          `json:"diff,omitempty"`
+		Error      json.RawMessage `json:"error,omitempty"`
+	}
+	type skippedReport struct {
+		Path   string `json:"path"`
+		Reason string `json:"reason"`
+	}
+	report := struct {
+		Changed      int             `json:"changed"`
+		Unchanged    int             `json:"unchanged"`
+		Failed       int             `json:"failed"`
+		Skipped      int             `json:"skipped"`
+		DurationMS   float64         `json:"durationMs"`
+		Files        []fileReport    `json:"files"`
+		SkippedFiles []skippedReport `json:"skippedFiles,omitempty"`
+	}{
+		Changed:    s.Changed,
+		Unchanged:  s.Unchanged,
+		Failed:     s.Failed,
+		Skipped:    len(s.Skipped),
+		DurationMS: milliseconds(s.Duration),
+		Files:      make([]fileReport, 0, len(s.Results)),
+	}
+
+	for _, result := range s.Results {
+		file := fileReport{
+			Path:       result.Path,
+			Status:     "unchanged",
+			Applied:    result.Written,
+			DurationMS: milliseconds(result.Duration),
+			Diff:       result.Diff,
+		}
+		switch {
+		case result.Err != nil:
+			file.Status = "failed"
+			var formatErr *FormatError
+			if !errors.As(result.Err, &formatErr) {
+				formatErr = NewFormatError(result.Err.Error())
+			}
+			encoded, err := json.Marshal(formatErr)
+			if err != nil {
+				return nil, err
+			}
+			file.Error = encoded
+		case result.Changed:
+			file.Status = "changed"
+		}
+		report.Files = append(report.Files, file)
+	}
+	for _, skipped := range s.Skipped {
+		entry := skippedReport{Path: skipped.Path}
+		if skipped.Reason != nil {
+			entry.Reason = skipped.Reason.Error()
+		}
+		report.SkippedFiles = append(report.SkippedFiles, entry)
+	}
+	return json.Marshal(report)
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,149 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 30; i++ {
+		var content string
+		switch i % 3 {
+		case 0:
+			content = fmt.Sprintf(`{"id":%d}`, i)
+		case 1:
+			content = fmt.Sprintf("{\n  \"id\": %d\n}\n", i)
+		default:
+			content = `{"id":`
+		}
+		paths = append(paths, writeFileIn(t, dir, fmt.Sprintf("%02d.json", i), content))
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry run %v", dryRun), func(t *testing.T) {
+			options := []FileOption{WithWorkers(4)}
+			if dryRun {
+				options = append(options, WithDryRun())
+			}
+			summary := NewFormatter(DefaultConfig()).FormatFiles(paths, options...)
+
+			if summary.Changed != 10 || summary.Unchanged != 10 || summary.Failed != 10 {
+				t.Errorf("Expected 10 files per outcome, got %d changed, %d unchanged, %d failed", summary.Changed, summary.Unchanged, summary.Failed)
+			}
+			for i, result := range summary.Results {
+				if result.Path != paths[i] {
+					t.Fatalf("Expected result %d for %s, got %s", i, paths[i], result.Path)
+				}
+				if (result.Err != nil) != (i%3 == 2) || result.Changed != (i%3 == 0) || (result.Diff != "") != (dryRun && i%3 == 0) || result.Written != (!dryRun && i%3 == 0) {
+					t.Errorf("Unexpected result for %s: %+v", result.Path, result)
+				}
+			}
+
+			err := summary.Err()
+			if err == nil || !strings.Contains(err.Error(), paths[2]+": ") || strings.Count(err.Error(), "\n") != 9 {
+				t.Errorf("Expected the errors of the failed files, got %v", err)
+			}
+		})
+	}
+
+	if got := readFile(t, paths[0]); got != "{\n  \"id\": 0\n}\n" {
+		t.Errorf("Expected the file to be formatted, got:\n%s", got)
+	}
+}
+
+func TestFormatFilesEmpty(t *testing.T) {
+	summary := NewFormatter(DefaultConfig()).FormatFiles(nil)
+	if len(summary.Results) != 0 || summary.Err() != nil {
+		t.Errorf("Expected an empty summary, got %+v", summary)
+	}
+}
+
+// writeFileIn writes content into a file in dir
+func writeFileIn(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	writeTo(t, path, content)
+	return path
+}
+
+func TestBatchSummaryJSON(t *testing.T) {
+	summary := &BatchSummary{
+		Results: []FileResult{
+			{Path: "a.json", Changed: true, Written: true, Duration: 1500 * time.Microsecond},
+			{Path: "b.json", Changed: true, Diff: "--- b.json\n", Duration: time.Millisecond},
+			{Path: "c.json", Err: NewFormatError("invalid JSON input"), Duration: 0},
+			{Path: "d.json", Err: errors.New("disk on fire")},
+			{Path: "e.json"},
+		},
+		Changed:   2,
+		Unchanged: 1,
+		Failed:    2,
+		Duration:  3 * time.Millisecond,
+		Skipped:   []SkippedFile{{Path: "big.json", Reason: errors.New("file too large")}},
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"changed":2,"unchanged":1,"failed":2,"skipped":1,"durationMs":3,"files":[` +
+		`{"path":"a.json","status":"changed","applied":true,"durationMs":1.5},` +
+		`{"path":"b.json","status":"changed","applied":false,"durationMs":1,"diff":"--- b.json\n"},` +
+		`{"path":"c.json","status":"failed","applied":false,"durationMs":0,"error":{"code":"syntax","message":"invalid JSON input"}},` +
+		`{"path":"d.json","status":"failed","applied":false,"durationMs":0,"error":{"code":"unknown","message":"disk on fire"}},` +
+		`{"path":"e.json","status":"unchanged","applied":false,"durationMs":0}],` +
+		`"skippedFiles":[{"path":"big.json","reason":"file too large"}]}`
+	if string(encoded) != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, encoded)
+	}
+}
+
+func TestFormatBatch(t *testing.T) {
+	docs := make([]string, 100)
+	for i := range docs {
+		docs[i] = fmt.Sprintf(`{"id":%d}`, i)
+	}
+	docs[10] = `{"id":`
+	docs[42] = ``
+
+	for _, workers := range []int{0, 4} {
+		formatter := NewFormatter(NewConfig(WithRecordWorkers(workers)))
+		results, err := formatter.FormatBatch(docs)
+		if len(results) != len(docs) {
+			t.Fatalf("workers %d: expected %d results, got %d", workers, len(docs), len(results))
+		}
+		for i, result := range results {
+			switch i {
+			case 10, 42:
+				if result.Err == nil || result.Output != "" {
+					t.Errorf("workers %d: expected document %d to fail, got %q", workers, i+1, result.Output)
+				}
+			default:
+				expected := fmt.Sprintf("{\n  \"id\": %d\n}", i)
+				if result.Err != nil || result.Output != expected {
+					t.Errorf("workers %d: document %d: expected %q, got %q (%v)", workers, i+1, expected, result.Output, result.Err)
+				}
+			}
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), "document 11: ") || !strings.Contains(err.Error(), "\ndocument 43: ") {
+			t.Errorf("workers %d: unexpected error: %v", workers, err)
+		}
+		if !errors.Is(err, ErrEmptyInput) {
+			t.Errorf("workers %d: expected the joined error to match ErrEmptyInput", workers)
+		}
+	}
+}
+
+func TestFormatBatchEmpty(t *testing.T) {
+	results, err := NewFormatter(DefaultConfig()).FormatBatch(nil)
+	if len(results) != 0 || err != nil {
+		t.Errorf("Expected no results and no error, got %v, %v", results, err)
+	}
+}
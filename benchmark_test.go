@@ -85,6 +85,29 @@ func BenchmarkFormatterLargeJSON(b *testing.B) {
 	}
 }
 
+// BenchmarkEstimateFormattedSize benchmarks estimating the output size of a
+// document of about 13,000 tokens
+func BenchmarkEstimateFormattedSize(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString(`{"items":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(fmt.Sprintf(`{"id":%d,"name":"item%d","data":{"value":%d,"active":true}}`, i, i, i*10))
+	}
+	builder.WriteString(`],"meta":{"count":1000,"generated":true}}`)
+	input := builder.String()
+	config := NewConfig(WithMaxTokens(0))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := EstimateFormattedSize(input, config); err != nil {
+			b.Fatalf("Estimating failed: %v", err)
+		}
+	}
+}
+
 // BenchmarkFormatterDeeplyNested benchmarks formatting of deeply nested JSON structures
 func BenchmarkFormatterDeeplyNested(b *testing.B) {
 	// Create a deeply nested structure
@@ -459,3 +482,31 @@ func BenchmarkFormatterConcurrent(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkEscapeStringPlain benchmarks escaping strings that need no changes
+func BenchmarkEscapeStringPlain(b *testing.B) {
+	parser := &TokenParser{config: DefaultConfig()}
+	s := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+
+	b.SetBytes(int64(len(s)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.escapeString(s); err != nil {
+			b.Fatalf("Escaping failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkFormatBatch(b *testing.B) {
+	docs := make([]string, 1000)
+	for i := range docs {
+		docs[i] = fmt.Sprintf(`{"id":%d,"tags":["a","b"],"ok":true}`, i)
+	}
+	formatter := NewFormatter(NewConfig(WithRecordWorkers(4)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := formatter.FormatBatch(docs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
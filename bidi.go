@@ -0,0 +1,86 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"unicode"
+)
+
+const (
+	// firstStrongIsolate starts a bidi isolate whose direction follows its content
+	firstStrongIsolate = "\u2068"
+	// popDirectionalIsolate ends a bidi isolate
+	popDirectionalIsolate = "\u2069"
+)
+
+// rtlScripts are the scripts written from right to left
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko,
+	unicode.Samaritan, unicode.Mandaic, unicode.Adlam,
+}
+
+// WithBidiIsolation wraps string values that contain right-to-left text, such
+// as Arabic or Hebrew, in Unicode isolate controls (U+2068 and U+2069), so
+// terminals that apply the bidirectional algorithm keep the quotes, commas,
+// and keys around them in place. The controls have no width and are not
+// shown, but they are outside the quotes, so the output is no longer valid
+// JSON. Use this option for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithBidiIsolation())
+//	// {"name": "שלום", "id": 1} is written with U+2068 before and U+2069
+//	// after "שלום", so the comma stays after the value on screen
+func WithBidiIsolation() ConfigOption {
+	return func(c *Config) {
+		c.BidiIsolation = true
+	}
+}
+
+// isolateBidi wraps a string value with right-to-left text in isolate controls
+func (p *TokenParser) isolateBidi(token json.Token) (json.Token, error) {
+	if !p.config.BidiIsolation || p.config.StrictJSON || !p.isScalarValue(token) {
+		return token, nil
+	}
+	value, ok := token.(string)
+	if !ok || !containsRTL(value) {
+		return token, nil
+	}
+	quoted, err := p.compactTokens([]json.Token{value})
+	if err != nil {
+		return nil, err
+	}
+	return RawValue(firstStrongIsolate + quoted + popDirectionalIsolate), nil
+}
+
+// containsRTL reports whether the string contains right-to-left characters
+// or controls that change the direction of the text after them
+func containsRTL(s string) bool {
+	for _, r := range s {
+		if r < 0x590 {
+			continue
+		}
+		switch r {
+		case '\u200f', '\u202b', '\u202e', '\u2067':
+			// Right-to-left mark, embedding, override, and isolate
+			return true
+		}
+		if unicode.In(r, rtlScripts...) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestBidiIsolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "hebrew value",
+			input:    `{"name":"שלום","id":1}`,
+			options:  []ConfigOption{WithBidiIsolation(), WithCompactDepth(1)},
+			expected: "{\"name\": \u2068\"שלום\"\u2069, \"id\": 1}",
+		},
+		{
+			name:     "arabic array element",
+			input:    `["مرحبا","hello"]`,
+			options:  []ConfigOption{WithBidiIsolation(), WithCompactDepth(0)},
+			expected: "[\n  \u2068\"مرحبا\"\u2069,\n  \"hello\"\n]",
+		},
+		{
+			name:     "mixed text with escapes",
+			input:    `{"a":"id <7> שלום"}`,
+			options:  []ConfigOption{WithBidiIsolation(), WithCompactDepth(1)},
+			expected: "{\"a\": \u2068\"id \\u003c7\\u003e שלום\"\u2069}",
+		},
+		{
+			name:     "right-to-left mark",
+			input:    "{\"a\":\"x\u200fy\"}",
+			options:  []ConfigOption{WithBidiIsolation(), WithCompactDepth(1)},
+			expected: "{\"a\": \u2068\"x\u200fy\"\u2069}",
+		},
+		{
+			name:     "left-to-right text and keys",
+			input:    `{"שם":"Alice","n":"日本"}`,
+			options:  []ConfigOption{WithBidiIsolation(), WithCompactDepth(1)},
+			expected: `{"שם": "Alice", "n": "日本"}`,
+		},
+		{
+			name:     "strict JSON",
+			input:    `{"name":"שלום"}`,
+			options:  []ConfigOption{WithBidiIsolation(), WithStrictJSON(), WithCompactDepth(1)},
+			expected: `{"name": "שלום"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
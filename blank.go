@@ -0,0 +1,85 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors for input without any JSON value, matched with errors.Is. The
+// FormatErrors returned for such input all have the code CodeEmptyInput, and
+// match one of these errors to tell why the input is blank, so editor
+// integrations can silently skip formatting a new file instead of showing an
+// error.
+//
+// Example:
+//
+//	formatted, err := formatter.Format(buffer)
+//	if errors.Is(err, ErrWhitespaceInput) || errors.Is(err, ErrCommentInput) {
+//	    return buffer // nothing to format, keep the buffer as it is
+//	}
+var (
+	// ErrEmptyInput matches the error for input without any bytes.
+	ErrEmptyInput = errors.New("input is empty")
+
+	// ErrWhitespaceInput matches the error for input with only whitespace.
+	ErrWhitespaceInput = errors.New("input contains only whitespace")
+
+	// ErrCommentInput matches the error for input with only comments and
+	// whitespace, as a JSONC file may hold before its content is written.
+	// Comments are not JSON, so comments before or after a value are still
+	// syntax errors.
+	ErrCommentInput = errors.New("input contains only comments")
+)
+
+// Is reports whether the error is for blank input of the kind of target,
+// which is one of ErrEmptyInput, ErrWhitespaceInput, and ErrCommentInput, or
+// for embedded JSON nested too deeply when target is ErrEmbeddedTooDeep.
+func (e *FormatError) Is(target error) bool {
+	switch target {
+	case ErrEmbeddedTooDeep:
+		return strings.HasPrefix(e.Msg, target.Error())
+	case ErrEmptyInput:
+		return e.Msg == "input JSON string is empty" || e.Msg == "input contains no valid JSON tokens"
+	case ErrWhitespaceInput, ErrCommentInput:
+		return e.Msg == target.Error()
+	default:
+		return false
+	}
+}
+
+// blankInputError returns the error for input with only whitespace or
+// comments, or nil if the input may hold a value. Unterminated block
+// comments are left to the parser to report as syntax errors.
+func blankInputError(input string) error {
+	if input == "" {
+		return NewFormatError("input JSON string is empty")
+	}
+	rest := strings.TrimLeft(input, " \t\r\n")
+	if rest == "" {
+		return NewFormatError(ErrWhitespaceInput.Error())
+	}
+	for strings.HasPrefix(rest, "//") || strings.HasPrefix(rest, "/*") {
+		if strings.HasPrefix(rest, "/*") && !strings.Contains(rest[2:], "*/") {
+			return nil
+		}
+		rest = strings.TrimLeft(rest[json5CommentEnd(rest):], " \t\r\n")
+	}
+	if rest == "" {
+		return NewFormatError(ErrCommentInput.Error())
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package jsonformat
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBlankInputErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{"empty", "", ErrEmptyInput},
+		{"whitespace", " \n\t\r\n", ErrWhitespaceInput},
+		{"line comment", "// settings go here\n", ErrCommentInput},
+		{"block comment", "  /* TODO */\n// more\n", ErrCommentInput},
+		{"comment without newline", "// end", ErrCommentInput},
+		{"unterminated block comment", "/* TODO", nil},
+		{"comment before value", "// c\n{}", nil},
+	}
+	blank := []error{ErrEmptyInput, ErrWhitespaceInput, ErrCommentInput}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFormatter(DefaultConfig()).Format(tt.input)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, target := range blank {
+				if got := errors.Is(err, target); got != (target == tt.expected) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, target, got)
+				}
+			}
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected a FormatError, got %T", err)
+			}
+			if tt.expected != nil && formatErr.Code() != CodeEmptyInput {
+				t.Errorf("Expected code %s, got %s", CodeEmptyInput, formatErr.Code())
+			}
+			if tt.expected == nil && formatErr.Code() != CodeSyntax {
+				t.Errorf("Expected code %s, got %s", CodeSyntax, formatErr.Code())
+			}
+		})
+	}
+}
+
+func TestBlankInputErrorsWithFilename(t *testing.T) {
+	_, err := NewFormatter(NewConfig(WithFilename("new.jsonc"))).Format("\n\n")
+	if !errors.Is(err, ErrWhitespaceInput) {
+		t.Fatalf("Expected a whitespace error, got %v", err)
+	}
+	if expected := "new.jsonc: input contains only whitespace"; err.Error() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, err.Error())
+	}
+}
+
+func TestBlankInputErrorsStreamed(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{"empty", "", ErrEmptyInput},
+		{"whitespace", "  \n", ErrWhitespaceInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewFormatter(DefaultConfig()).FormatTo(io.Discard, strings.NewReader(tt.input))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// WithExpandBudget limits each top-level member, or element of a root array,
+// to about lines lines of expanded output. Once a member has used its
+// budget, the remaining elements and members of each open container are
+// left out and replaced by a line counting them, so one pathological member
+// cannot flood a dump while small members are written in full. Containers
+// written on one line count as one line. A value of 0 disables the budget.
+// The output is no longer valid JSON, so use this option for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithExpandBudget(3))
+//	// {
+//	//   "small": {
+//	//     "a": 1
+//	//   },
+//	//   "huge": [
+//	//     1,
+//	//     2,
+//	//     3,
+//	//     … 997 more items
+//	//   ]
+//	// }
+func WithExpandBudget(lines int) ConfigOption {
+	return func(c *Config) {
+		if lines >= 0 {
+			c.ExpandBudget = lines
+		}
+	}
+}
+
+// foldedElements is a token that stands for the elements left out of an array
+type foldedElements int
+
+// text returns the line that replaces the elements
+func (n foldedElements) text() string {
+	if n == 1 {
+		return "… 1 more item"
+	}
+	return "… " + strconv.Itoa(int(n)) + " more items"
+}
+
+// startBudgetHiding reports whether the element or member that the token
+// starts is left out because its top-level member has spent the expand
+// budget, and starts skipping it
+func (p *TokenParser) startBudgetHiding(token json.Token) bool {
+	if p.config.ExpandBudget == 0 || p.config.StrictJSON || p.depth < 2 || len(p.path) != len(p.inArray) {
+		return false
+	}
+	if p.budgetLines < p.config.ExpandBudget || p.shouldFormatCompact() {
+		return false
+	}
+	if p.isInArray() {
+		if n, ok := token.(skippedElements); ok {
+			// Elements skipped by a sample are counted with the rest
+			p.path[len(p.path)-1].folded += int(n)
+			return true
+		}
+		if !p.isValueStart(token) {
+			return false
+		}
+	} else if _, ok := token.(string); !ok || !p.expectingKey {
+		return false
+	}
+
+	p.path[len(p.path)-1].folded++
+	p.hiding = true
+	p.hiddenDepth = 0
+	if p.isInArray() {
+		// The token is the element itself
+		return p.hideToken(token) == nil
+	}
+	return true
+}
+
+// handleFoldedElements writes the line counting the elements left out
+func (p *TokenParser) handleFoldedElements(n foldedElements) error {
+	if err := p.writeElementPrefix(); err != nil {
+		return err
+	}
+	if _, err := p.builder.WriteString(n.text()); err != nil {
+		return WrapFormatError("failed to write folded elements", err)
+	}
+	p.isFirstElement = false
+	return nil
+}
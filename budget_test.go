@@ -0,0 +1,87 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestExpandBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "members",
+			input:   `{"small":{"a":1},"huge":[1,2,3,4,5,6],"deep":{"a":{"b":[1,2],"c":3},"d":4,"e":5,"f":6},"after":{"x":1}}`,
+			options: []ConfigOption{WithExpandBudget(3)},
+			expected: `{
+  "small": {
+    "a": 1
+  },
+  "huge": [
+    1,
+    2,
+    3,
+    … 3 more items
+  ],
+  "deep": {
+    "a": {"b": [1, 2], "c": 3},
+    "d": 4,
+    "e": 5,
+    … 1 more key
+  },
+  "after": {
+    "x": 1
+  }
+}`,
+		},
+		{
+			name:    "nested",
+			input:   `[{"a":{"b":1,"c":2,"d":3},"e":4,"f":5},[5]]`,
+			options: []ConfigOption{WithExpandBudget(2), WithCompactDepth(0)},
+			expected: `[
+  {
+    "a": {
+      "b": 1,
+      … 2 more keys
+    },
+    … 2 more keys
+  },
+  [
+    5
+  ]
+]`,
+		},
+		{
+			name:     "compact",
+			input:    `{"huge":[1,2,3,4,5,6]}`,
+			options:  []ConfigOption{WithExpandBudget(1), WithCompactDepth(1)},
+			expected: `{"huge": [1, 2, 3, 4, 5, 6]}`,
+		},
+		{
+			name:    "strict JSON",
+			input:   `{"huge":[1,2,3]}`,
+			options: []ConfigOption{WithExpandBudget(1), WithStrictJSON()},
+			expected: `{
+  "huge": [
+    1,
+    2,
+    3
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// minOutputChunkSize is the size of the first chunk of the output buffer
+	minOutputChunkSize = 512
+
+	// outputChunkSize is the maximum size of each chunk of the output buffer
+	outputChunkSize = 64 * 1024
+)
+
+// outputBuffer is an append-only buffer for formatted output.
+// Text is stored in fixed-size chunks, so growing the buffer never copies
+// what was already written, and large outputs do not need one contiguous
+// allocation until the final string is built. When a writer is set, text is
+// streamed through it instead of being stored.
+type outputBuffer struct {
+	chunks [][]byte
+	size   int
+	writer *flushWriter // Destination for streamed output, or nil to store output
+	column int          // Width of the text written after the last newline
+	width  WidthFunc    // Measures the display width of text, or nil to count runes
+
+	// Lines are held back and passed to postProcess once complete.
+	// Size and column always describe the text before post-processing.
+	postProcess PostProcessFunc
+	line        strings.Builder
+	lineDepth   int
+	linePath    string
+
+	// When indexLines is set, the offsets at which stored lines start are
+	// recorded as the output is written, after post-processing.
+	indexLines bool
+	lineStarts []int
+	stored     int
+
+	// When traced is set, the text written is copied to it before
+	// post-processing.
+	traced *strings.Builder
+}
+
+// WriteString appends s to the buffer, or writes it through to the writer
+func (b *outputBuffer) WriteString(s string) (int, error) {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		b.column = b.measure(s[i+1:])
+	} else {
+		b.column += b.measure(s)
+	}
+
+	b.size += len(s)
+	if b.traced != nil {
+		b.traced.WriteString(s)
+	}
+	if b.postProcess == nil {
+		return b.write(s)
+	}
+
+	written := len(s)
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			b.line.WriteString(s)
+			return written, nil
+		}
+		b.line.WriteString(s[:i+1])
+		if err := b.finishLine(); err != nil {
+			return 0, err
+		}
+		s = s[i+1:]
+	}
+}
+
+// measure returns the display width of text written to the buffer
+func (b *outputBuffer) measure(s string) int {
+	if b.width == nil {
+		return utf8.RuneCountInString(s)
+	}
+	return b.width(s)
+}
+
+// startLine sets the depth and path passed to the post-processor for the current line
+func (b *outputBuffer) startLine(depth int, path string) {
+	b.lineDepth = depth
+	b.linePath = path
+}
+
+// finishLine passes the held back line to the post-processor and writes the result.
+// It is also called once formatting ends, for the last line without a newline.
+func (b *outputBuffer) finishLine() error {
+	if b.postProcess == nil || b.line.Len() == 0 {
+		return nil
+	}
+	line := b.line.String()
+	b.line.Reset()
+	newline := strings.HasSuffix(line, "\n")
+	processed := b.postProcess(strings.TrimSuffix(line, "\n"), b.lineDepth, b.linePath)
+	if newline {
+		processed += "\n"
+	}
+	_, err := b.write(processed)
+	return err
+}
+
+// write stores s, or writes it through to the writer
+func (b *outputBuffer) write(s string) (int, error) {
+	if b.writer != nil {
+		return b.writer.WriteString(s)
+	}
+
+	if b.indexLines {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '\n' {
+				b.lineStarts = append(b.lineStarts, b.stored+i+1)
+			}
+		}
+		b.stored += len(s)
+	}
+
+	written := len(s)
+	for len(s) > 0 {
+		if len(b.chunks) == 0 || len(b.chunks[len(b.chunks)-1]) == cap(b.chunks[len(b.chunks)-1]) {
+			// Chunks double with the total size, so small outputs stay small
+			chunkSize := b.size
+			if chunkSize < minOutputChunkSize {
+				chunkSize = minOutputChunkSize
+			}
+			if chunkSize > outputChunkSize {
+				chunkSize = outputChunkSize
+			}
+			b.chunks = append(b.chunks, make([]byte, 0, chunkSize))
+		}
+		last := &b.chunks[len(b.chunks)-1]
+		n := copy((*last)[len(*last):cap(*last)], s)
+		*last = (*last)[:len(*last)+n]
+		s = s[n:]
+	}
+	return written, nil
+}
+
+// Len returns the number of bytes written to the buffer
+func (b *outputBuffer) Len() int {
+	return b.size
+}
+
+// String returns the stored output, copying it exactly once
+func (b *outputBuffer) String() string {
+	if len(b.chunks) == 1 {
+		return string(b.chunks[0])
+	}
+	var builder strings.Builder
+	builder.Grow(b.size)
+	for _, chunk := range b.chunks {
+		builder.Write(chunk)
+	}
+	return builder.String()
+}
+
+// WriteTo writes the stored output to w chunk by chunk
+func (b *outputBuffer) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, chunk := range b.chunks {
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Flush flushes streamed output to the writer
+func (b *outputBuffer) Flush() error {
+	if b.writer == nil {
+		return nil
+	}
+	return b.writer.Flush()
+}
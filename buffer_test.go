@@ -0,0 +1,97 @@
+package jsonformat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOutputBuffer(t *testing.T) {
+	var buffer outputBuffer
+	var expected strings.Builder
+	for i := 0; i < 5000; i++ {
+		s := strings.Repeat("x", i%97) + "\n"
+		buffer.WriteString(s)
+		expected.WriteString(s)
+	}
+
+	if buffer.Len() != expected.Len() {
+		t.Errorf("Expected length %d, got %d", expected.Len(), buffer.Len())
+	}
+	if buffer.String() != expected.String() {
+		t.Error("Buffer content does not match written text")
+	}
+	for _, chunk := range buffer.chunks {
+		if cap(chunk) > outputChunkSize {
+			t.Fatalf("Expected chunks of at most %d bytes, got %d", outputChunkSize, cap(chunk))
+		}
+	}
+
+	var out bytes.Buffer
+	n, err := buffer.WriteTo(&out)
+	if err != nil || n != int64(expected.Len()) || out.String() != expected.String() {
+		t.Errorf("WriteTo wrote %d bytes with error %v", n, err)
+	}
+}
+
+func TestOutputBufferColumn(t *testing.T) {
+	var buffer outputBuffer
+	buffer.WriteString("{\n  ")
+	buffer.WriteString(`"名前": `)
+	if buffer.column != 8 {
+		t.Errorf("Expected column 8, got %d", buffer.column)
+	}
+	buffer.WriteString("1\n")
+	if buffer.column != 0 {
+		t.Errorf("Expected column 0 after newline, got %d", buffer.column)
+	}
+}
+
+func TestFormatTo(t *testing.T) {
+	input := `{"users":[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}],"meta":{"count":2}}`
+	formatter := NewFormatter(NewConfig(WithMaxWidth(30)))
+
+	expected, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := formatter.FormatTo(&out, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestFormatToErrors(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty input", input: ""},
+		{name: "invalid JSON", input: `{"a":`},
+		{name: "syntax error", input: `{"a" 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := formatter.FormatTo(&out, strings.NewReader(tt.input))
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Expected *FormatError, got %v", err)
+			}
+		})
+	}
+
+	if err := formatter.FormatTo(nil, strings.NewReader("{}")); err == nil {
+		t.Error("Expected error for nil writer")
+	}
+	if err := formatter.FormatTo(&bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected error for nil reader")
+	}
+}
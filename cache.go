@@ -0,0 +1,190 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// Cache is a content-addressed LRU cache of formatted output.
+// Entries are keyed by a hash of the input and a fingerprint of the
+// configuration, so one cache can be shared by formatters with different
+// configurations. It is safe for concurrent use.
+//
+// Example:
+//
+//	cache := NewCache(128)
+//	formatter := NewFormatter(DefaultConfig())
+//	formatter.SetCache(cache)
+type Cache struct {
+	mu        sync.Mutex
+	capacity  int
+	entries   map[cacheKey]*list.Element
+	order     *list.List // Most recently used entries first
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// CacheStats holds the hit and miss counters of a Cache.
+type CacheStats struct {
+	Hits      uint64 // Lookups that returned cached output
+	Misses    uint64 // Lookups that required formatting
+	Evictions uint64 // Entries removed to stay within capacity
+	Entries   int    // Entries currently stored
+}
+
+// cacheKey identifies a cached output by input hash and configuration fingerprint
+type cacheKey struct {
+	input  [sha256.Size]byte
+	config string
+}
+
+// cacheEntry is a single cached output
+type cacheEntry struct {
+	key    cacheKey
+	output string
+}
+
+// NewCache creates a cache that holds up to capacity formatted outputs.
+// A capacity of zero or less creates a cache of one entry.
+func NewCache(capacity int) *Cache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Cache{
+		capacity: capacity,
+		entries:  make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Stats returns the current hit and miss counters.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   c.order.Len(),
+	}
+}
+
+// Clear removes all entries. The counters are kept.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[cacheKey]*list.Element)
+	c.order.Init()
+}
+
+// get returns the cached output for the key and records a hit or miss
+func (c *Cache) get(key cacheKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).output, true
+}
+
+// put stores the output for the key, evicting the least recently used entry if needed
+func (c *Cache) put(key cacheKey, output string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).output = output
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, output: output})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// SetCache sets the cache used by Format and FormatBytes.
+// Inputs that were formatted before with an equivalent configuration are
+// returned from the cache without re-formatting. Configurations with value
+// hooks, other functions, or a schema are only equivalent to themselves as
+// frozen by one call of Freeze, so only formatters created from the same
+// FrozenConfig share their entries. Errors are not cached. A nil cache
+// disables caching.
+func (f *Formatter) SetCache(cache *Cache) {
+	f.cache = cache
+}
+
+// newCacheKey builds the cache key for an input formatted with the config of
+// the snapshot. Configurations that cannot be compared by content are keyed by
+// the snapshot, whose ID is never reused, unlike the address of the config.
+func newCacheKey(input string, config *Config, snapshot uint64) cacheKey {
+	fingerprint := config.fingerprint()
+	if !config.comparable() {
+		fingerprint += fmt.Sprintf(" snapshot=%d", snapshot)
+	}
+	return cacheKey{
+		input:  sha256.Sum256([]byte(input)),
+		config: fingerprint,
+	}
+}
+
+// comparable reports whether configurations producing the same output have
+// the same fingerprint. Functions and schemas cannot be compared, as fmt
+// prints only their addresses, so closures made by one factory would print
+// alike and a schema could change after it is printed.
+func (c *Config) comparable() bool {
+	return len(c.ValueHooks) == 0 && c.PostProcess == nil && c.Width == nil &&
+		c.UnicodeNormalization == nil && c.RecordErrorMarker == nil && c.Checkpoint == nil &&
+		c.Schema == nil
+}
+
+// fingerprint returns a string that is equal for configurations producing
+// the same output, apart from the functions and the schema that comparable
+// checks for.
+func (c *Config) fingerprint() string {
+	// fmt prints maps with sorted keys, so equal configurations print equally
+	settings := *c
+	settings.ValueHooks = nil
+	settings.PostProcess = nil
+	settings.Width = nil
+	settings.UnicodeNormalization = nil
+	settings.RecordErrorMarker = nil
+	settings.Checkpoint = nil
+	settings.Schema = nil
+	// Pointers print as addresses, so their contents are printed instead
+	settings.KeyPattern = nil
+	settings.QuoteStyle = nil
+	// The filename only appears in errors, which are not cached
+	settings.Filename = ""
+	fingerprint := fmt.Sprintf("%+v", settings)
+	if c.KeyPattern != nil {
+		fingerprint += " keyPattern=" + c.KeyPattern.String()
+	}
+	if c.QuoteStyle != nil {
+		fingerprint += fmt.Sprintf(" quoteStyle=%+v", *c.QuoteStyle)
+	}
+	return fingerprint
+}
@@ -0,0 +1,211 @@
+package jsonformat
+
+import (
+	"regexp"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestCacheHitsAndMisses(t *testing.T) {
+	cache := NewCache(8)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetCache(cache)
+
+	input := `{"status":"ok","checks":[{"name":"db","ok":true}]}`
+	first, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected cached output to match, got:\n%s\n\nand:\n%s", first, second)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCacheSeparatesConfigurations(t *testing.T) {
+	cache := NewCache(8)
+	twoSpaces := NewFormatter(DefaultConfig())
+	twoSpaces.SetCache(cache)
+	tabs := NewFormatter(NewConfig(WithTabs()))
+	tabs.SetCache(cache)
+
+	input := `{"a":1}`
+	spaced, _ := twoSpaces.Format(input)
+	tabbed, _ := tabs.Format(input)
+	if spaced == tabbed {
+		t.Error("Expected different configurations not to share cache entries")
+	}
+
+	// An equivalent configuration shares entries
+	other := NewFormatter(DefaultConfig())
+	other.SetCache(cache)
+	if _, err := other.Format(input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCacheDoesNotStoreErrors(t *testing.T) {
+	cache := NewCache(8)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetCache(cache)
+
+	for i := 0; i < 2; i++ {
+		if _, err := formatter.Format(`{"a":`); err == nil {
+			t.Fatal("Expected error for invalid JSON")
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Entries != 0 {
+		t.Errorf("Expected errors not to be cached, got %+v", stats)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache := NewCache(2)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetCache(cache)
+
+	for _, input := range []string{`[1]`, `[2]`, `[1]`, `[3]`, `[2]`} {
+		if _, err := formatter.Format(input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// [2] was the least recently used entry when [3] was added
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Evictions != 2 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	cache.Clear()
+	if stats := cache.Stats(); stats.Entries != 0 || stats.Hits != 1 {
+		t.Errorf("Expected Clear to drop entries but keep counters, got %+v", stats)
+	}
+}
+
+func TestCacheConcurrentUse(t *testing.T) {
+	cache := NewCache(4)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetCache(cache)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := formatter.Format(`{"status":"ok"}`); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 400 {
+		t.Errorf("Expected 400 lookups, got %+v", stats)
+	}
+}
+
+func TestConfigFingerprint(t *testing.T) {
+	a := NewConfig(WithWidthByDepth(map[int]int{3: 120, 5: 80}))
+	b := NewConfig(WithWidthByDepth(map[int]int{5: 80, 3: 120}))
+	if a.fingerprint() != b.fingerprint() {
+		t.Error("Expected equal configurations to have equal fingerprints")
+	}
+
+	hook := func(ctx ValueContext) (interface{}, bool) { return nil, false }
+	c := NewConfig(WithValueHook(hook)).Freeze()
+	d := NewConfig(WithValueHook(hook)).Freeze()
+	if newCacheKey("1", c.config, c.id) == newCacheKey("1", d.config, d.id) {
+		t.Error("Expected snapshots of configurations with hooks to have distinct keys")
+	}
+	if newCacheKey("1", c.config, c.id) != newCacheKey("1", c.config, c.NewFormatter().snapshot) {
+		t.Error("Expected formatters of one snapshot to share keys")
+	}
+
+	// Closures from one factory share their code pointer
+	scaled := func(factor int) WidthFunc {
+		return func(s string) int { return len(s) * factor }
+	}
+
+	cache := NewCache(8)
+	narrow := NewFormatter(NewConfig(WithMaxWidth(30), WithCompactDepth(1), WithWidthFunc(scaled(1))))
+	wide := NewFormatter(NewConfig(WithMaxWidth(30), WithCompactDepth(1), WithWidthFunc(scaled(10))))
+	narrow.SetCache(cache)
+	wide.SetCache(cache)
+	input := `{"a":{"b":1,"c":2}}`
+	first, _ := narrow.Format(input)
+	second, _ := wide.Format(input)
+	if first == second {
+		t.Errorf("Expected the width functions to give different outputs, got %q", first)
+	}
+}
+
+func TestCacheNotSharedAfterCollection(t *testing.T) {
+	cache := NewCache(8)
+	suffix := func(s string) ValueHook {
+		return func(ctx ValueContext) (interface{}, bool) {
+			if value, ok := ctx.Value.(string); ok {
+				return value + s, true
+			}
+			return nil, false
+		}
+	}
+
+	// Formatters that are dropped free their configurations for reuse
+	for i := 0; i < 200; i++ {
+		s := string(rune('a' + i%2))
+		formatter := NewFormatter(NewConfig(WithValueHook(suffix(s))))
+		formatter.SetCache(cache)
+		result, err := formatter.Format(`"x"`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := `"x` + s + `"`; result != expected {
+			t.Fatalf("Run %d: expected %s, got %s", i, expected, result)
+		}
+		runtime.GC()
+	}
+}
+
+func TestConfigFingerprintCoversSettings(t *testing.T) {
+	base := DefaultConfig().fingerprint()
+	for _, option := range []ConfigOption{
+		WithIndentSize(4),
+		WithTabs(),
+		WithCompactDepth(1),
+		WithMaxWidth(80),
+		WithWidthByDepth(map[int]int{2: 40}),
+		WithSpecialFloats(SpecialFloatsAsNull),
+	} {
+		if NewConfig(option).fingerprint() == base {
+			t.Errorf("Expected option to change the fingerprint: %+v", NewConfig(option))
+		}
+	}
+}
+
+func TestConfigFingerprintPointers(t *testing.T) {
+	a := NewConfig(WithKeyPattern(regexp.MustCompile(`^[a-z]+$`)), WithQuoteStyle(QuoteStyle{BareKeys: true}))
+	b := NewConfig(WithKeyPattern(regexp.MustCompile(`^[a-z]+$`)), WithQuoteStyle(QuoteStyle{BareKeys: true}))
+	if a.fingerprint() != b.fingerprint() {
+		t.Error("Expected equal patterns and quote styles to have equal fingerprints")
+	}
+
+	c := NewConfig(WithKeyPattern(regexp.MustCompile(`^[A-Z]+$`)), WithQuoteStyle(QuoteStyle{BareKeys: true}))
+	d := NewConfig(WithKeyPattern(regexp.MustCompile(`^[a-z]+$`)), WithQuoteStyle(QuoteStyle{}))
+	if a.fingerprint() == c.fingerprint() || a.fingerprint() == d.fingerprint() {
+		t.Error("Expected different patterns and quote styles to have distinct fingerprints")
+	}
+}
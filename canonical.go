@@ -0,0 +1,218 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Canonicalize writes the input in the JSON Canonicalization Scheme of RFC
+// 8785: without whitespace, with the members of every object sorted by key,
+// and with numbers and strings in their shortest form. Inputs that differ
+// only in formatting, key order, escapes, or number notation give the same
+// output. Objects with duplicate keys and numbers out of the range of
+// float64 are rejected, as they have no canonical form.
+//
+// Example:
+//
+//	canonical, err := Canonicalize(`{"b": 1.50, "a": "é"}`)
+//	// {"a":"é","b":1.5}
+func Canonicalize(input string) (string, error) {
+	if err := blankInputError(input); err != nil {
+		return "", err
+	}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var builder strings.Builder
+	if err := writeCanonical(&builder, decoder); err != nil {
+		return "", err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", NewFormatErrorWithPosition("invalid JSON input: unexpected data after the value", int(decoder.InputOffset()))
+	}
+	return builder.String(), nil
+}
+
+// CanonicalETag returns a strong HTTP entity tag for a JSON body, computed
+// from the SHA-256 hash of its canonical form as written by Canonicalize, so
+// bodies that differ only in formatting or key order share the tag. The tag
+// is quoted as required for the ETag header.
+//
+// Example:
+//
+//	etag, err := CanonicalETag(body)
+//	if err == nil {
+//	    w.Header().Set("ETag", etag)
+//	}
+func CanonicalETag(input string) (string, error) {
+	canonical, err := Canonicalize(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`, nil
+}
+
+// canonicalMember is an object member being sorted by Canonicalize
+type canonicalMember struct {
+	key   string
+	value string
+}
+
+// writeCanonical writes the next value of the decoder in canonical form
+func writeCanonical(builder *strings.Builder, decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '[' {
+			return writeCanonicalArray(builder, decoder)
+		}
+		return writeCanonicalObject(builder, decoder)
+	case string:
+		writeCanonicalString(builder, value)
+	case json.Number:
+		number, err := canonicalNumber(value)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(number)
+	case bool:
+		builder.WriteString(strconv.FormatBool(value))
+	case nil:
+		builder.WriteString("null")
+	}
+	return nil
+}
+
+// writeCanonicalArray writes the elements of an array after its opening bracket
+func writeCanonicalArray(builder *strings.Builder, decoder *json.Decoder) error {
+	builder.WriteByte('[')
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		if err := writeCanonical(builder, decoder); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+	}
+	builder.WriteByte(']')
+	return nil
+}
+
+// writeCanonicalObject writes the members of an object after its opening
+// brace, sorted by the UTF-16 code units of their keys
+func writeCanonicalObject(builder *strings.Builder, decoder *json.Decoder) error {
+	var members []canonicalMember
+	seen := make(map[string]bool)
+	for decoder.More() {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return WrapFormatErrorWithPosition("invalid JSON input", offset, err)
+		}
+		key := token.(string)
+		if seen[key] {
+			return NewFormatErrorWithPosition(fmt.Sprintf("invalid JSON input: duplicate key %q has no canonical form", key), offset)
+		}
+		seen[key] = true
+		var value strings.Builder
+		if err := writeCanonical(&value, decoder); err != nil {
+			return err
+		}
+		members = append(members, canonicalMember{key: key, value: value.String()})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+	}
+
+	slices.SortFunc(members, func(a, b canonicalMember) int {
+		return slices.Compare(utf16.Encode([]rune(a.key)), utf16.Encode([]rune(b.key)))
+	})
+	builder.WriteByte('{')
+	for i, member := range members {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		writeCanonicalString(builder, member.key)
+		builder.WriteByte(':')
+		builder.WriteString(member.value)
+	}
+	builder.WriteByte('}')
+	return nil
+}
+
+// writeCanonicalString writes a string escaping only quotes, backslashes,
+// and control characters, with the short escapes where JSON has them
+func writeCanonicalString(builder *strings.Builder, s string) {
+	builder.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\b':
+			builder.WriteString(`\b`)
+		case '\f':
+			builder.WriteString(`\f`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(builder, `\u%04x`, r)
+			} else {
+				builder.WriteRune(r)
+			}
+		}
+	}
+	builder.WriteByte('"')
+}
+
+// canonicalNumber returns a number in the shortest form of ECMAScript, which
+// is also the form encoding/json writes float64 values in
+func canonicalNumber(number json.Number) (string, error) {
+	value, err := strconv.ParseFloat(number.String(), 64)
+	if errors.Is(err, strconv.ErrRange) && math.IsInf(value, 0) {
+		return "", NewFormatError(fmt.Sprintf("cannot canonicalize number %s: out of range of float64", number))
+	}
+	if value == 0 {
+		return "0", nil // Negative zero included
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", WrapFormatError(fmt.Sprintf("cannot canonicalize number %s", number), err)
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,105 @@
+package jsonformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "sorted and minified",
+			input:    "{\n  \"b\": [1, 2],\n  \"a\": {\"y\": null, \"x\": true}\n}",
+			expected: `{"a":{"x":true,"y":null},"b":[1,2]}`,
+		},
+		{
+			name:     "numbers",
+			input:    `[1.50, -0, 1E3, 0.000001, 1e-7, 1e21, 123456789012345678901, 4.50e+2]`,
+			expected: `[1.5,0,1000,0.000001,1e-7,1e+21,123456789012345680000,450]`,
+		},
+		{
+			name:     "strings",
+			input:    `["\u00e9\/\u0041", "tab\there", "\u001f", "\"\\"]`,
+			expected: "[\"é/A\",\"tab\\there\",\"\\u001f\",\"\\\"\\\\\"]",
+		},
+		{
+			name:     "keys sorted by UTF-16 code units",
+			input:    `{"\u20ac":1,"\r":2,"\ufb33":3,"1":4,"\ud83d\ude00":5,"\u0080":6,"\u00f6":7}`,
+			expected: "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"ö\":7,\"€\":1,\"😀\":5,\"\ufb33\":3}",
+		},
+		{
+			name:     "scalar",
+			input:    ` "x" `,
+			expected: `"x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Canonicalize(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		code  ErrorCode
+	}{
+		{"empty", "", CodeEmptyInput},
+		{"syntax", `{"a":}`, CodeSyntax},
+		{"trailing data", `{} []`, CodeSyntax},
+		{"duplicate key", `{"a":1,"a":2}`, CodeSyntax},
+		{"number out of range", `[1e400]`, CodeInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Canonicalize(tt.input)
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected a FormatError, got %v", err)
+			}
+			if formatErr.Code() != tt.code {
+				t.Errorf("Expected code %s, got %s (%v)", tt.code, formatErr.Code(), err)
+			}
+		})
+	}
+}
+
+func TestCanonicalETag(t *testing.T) {
+	first, err := CanonicalETag(`{"id": 1, "tags": ["a"]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := CanonicalETag("{\n  \"tags\": [\"a\"],\n  \"id\": 1.0\n}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected equal tags, got %s and %s", first, second)
+	}
+	if !strings.HasPrefix(first, `"`) || !strings.HasSuffix(first, `"`) || len(first) != 45 {
+		t.Errorf("Expected a quoted strong tag, got %s", first)
+	}
+
+	other, _ := CanonicalETag(`{"id": 2, "tags": ["a"]}`)
+	if other == first {
+		t.Errorf("Expected different tags for different bodies, got %s", other)
+	}
+	if _, err := CanonicalETag(`{`); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
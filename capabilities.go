@@ -0,0 +1,91 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+// CapabilityInfo describes what this build of the formatter supports, so
+// editor plugins and wrapper scripts can detect features instead of parsing
+// version numbers. It encodes to JSON with lowerCamel keys.
+type CapabilityInfo struct {
+	// Version is the module version, or "devel" when it is not known.
+	Version string `json:"version"`
+
+	// Modes are the ways of formatting a document, as named by FormatMode.
+	Modes []string `json:"modes"`
+
+	// Dialects are the kinds of input that can be formatted: "json",
+	// "ndjson" (FormatLog), "json-seq" (FormatSeq), "json-in-yaml"
+	// (FormatJSONInYAML), and "csv" (ReadCSVAndFormat). XLSX input is read by
+	// the separate xlsx package.
+	Dialects []string `json:"dialects"`
+
+	// Encodings are the text encodings of input that is converted to UTF-8.
+	Encodings []string `json:"encodings"`
+
+	// Presets are the names accepted by Preset, including registered profiles.
+	Presets []string `json:"presets"`
+
+	// StyleVersions are the layout style versions accepted by
+	// WithStyleVersion, oldest first.
+	StyleVersions []string `json:"styleVersions"`
+
+	// Limits are the limits enforced on input.
+	Limits Limits `json:"limits"`
+}
+
+// Limits are the limits the formatter enforces on input.
+type Limits struct {
+	// MaxDepth is the deepest nesting of objects and arrays.
+	MaxDepth int `json:"maxDepth"`
+
+	// MaxStringBytes is the size of the largest string value in bytes.
+	MaxStringBytes int `json:"maxStringBytes"`
+
+	// MaxIndentWidth is the widest indentation in characters.
+	MaxIndentWidth int `json:"maxIndentWidth"`
+
+	// DefaultMaxTokens is the default of Config.MaxTokens.
+	DefaultMaxTokens int `json:"defaultMaxTokens"`
+}
+
+// Capabilities describes the modes, dialects, presets, and limits of this
+// build of the formatter. Presets include the profiles registered so far.
+//
+// Example:
+//
+//	caps := Capabilities()
+//	if slices.Contains(caps.Dialects, "json-seq") {
+//	    formatted, err = formatter.FormatSeq(input)
+//	}
+func Capabilities() CapabilityInfo {
+	info := CapabilityInfo{
+		Version:   moduleVersion(),
+		Dialects:  []string{"json", "ndjson", "json-seq", "json-in-yaml", "csv"},
+		Encodings: []string{"UTF-8", "UTF-16LE", "UTF-16BE", "UTF-32LE", "UTF-32BE"},
+		Presets:   PresetNames(),
+		Limits: Limits{
+			MaxDepth:         maxDepth,
+			MaxStringBytes:   maxStringBytes,
+			MaxIndentWidth:   maxIndentWidth,
+			DefaultMaxTokens: DefaultConfig().MaxTokens,
+		},
+	}
+	for mode := ModePretty; mode <= ModeOutline; mode++ {
+		info.Modes = append(info.Modes, mode.String())
+	}
+	for version := StyleV1; version <= StyleLatest; version++ {
+		info.StyleVersions = append(info.StyleVersions, version.String())
+	}
+	return info
+}
@@ -0,0 +1,41 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	if caps.Version != "devel" {
+		t.Errorf("Expected version devel, got %q", caps.Version)
+	}
+	if !slices.Equal(caps.Modes, []string{"pretty", "log", "outline"}) {
+		t.Errorf("Unexpected modes: %v", caps.Modes)
+	}
+	if !slices.Equal(caps.StyleVersions, []string{"v1", "v2"}) {
+		t.Errorf("Unexpected style versions: %v", caps.StyleVersions)
+	}
+	if !slices.Equal(caps.Presets, PresetNames()) {
+		t.Errorf("Expected presets %v, got %v", PresetNames(), caps.Presets)
+	}
+	expected := Limits{MaxDepth: 100, MaxStringBytes: 1000000, MaxIndentWidth: 10000, DefaultMaxTokens: 10000}
+	if caps.Limits != expected {
+		t.Errorf("Expected limits %+v, got %+v", expected, caps.Limits)
+	}
+
+	encoded, err := json.Marshal(caps)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, key := range []string{"version", "modes", "dialects", "encodings", "presets", "styleVersions", "limits"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected key %q in %s", key, encoded)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// checkpointVersion is the version of the encoded checkpoint state
+const checkpointVersion = 1
+
+// Checkpoint is the state of FormatTo after part of its input, from which
+// ResumeFormatTo continues formatting after a process restart. Checkpoints
+// are encoded as JSON with encoding/json, and must be resumed with the same
+// configuration.
+type Checkpoint struct {
+	// InputOffset is the number of input bytes read
+	InputOffset int64
+
+	// OutputOffset is the number of output bytes written
+	OutputOffset int64
+
+	state checkpointState
+}
+
+// CheckpointFunc saves a checkpoint. It is called after the output up to the
+// checkpoint has been written, so the output can be persisted first. An
+// error stops formatting.
+type CheckpointFunc func(checkpoint Checkpoint) error
+
+// checkpointState is the parser state saved in a checkpoint
+type checkpointState struct {
+	Version         int               `json:"version"`
+	Tokens          int               `json:"tokens"`
+	MinCompactDepth int               `json:"minCompactDepth,omitempty"`
+	TopLevelStart   int               `json:"topLevelStart"`
+	BudgetLines     int               `json:"budgetLines,omitempty"`
+	Levels          []checkpointLevel `json:"levels"`
+}
+
+// checkpointLevel is the saved state of one open container
+type checkpointLevel struct {
+	Array       bool     `json:"array,omitempty"`
+	Key         string   `json:"key,omitempty"`
+	Index       int      `json:"index,omitempty"`
+	Keys        []string `json:"keys,omitempty"`
+	Homogeneous bool     `json:"homogeneous,omitempty"`
+	Members     int      `json:"members,omitempty"`
+	Folded      int      `json:"folded,omitempty"`
+	SpanStart   int64    `json:"spanStart,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+}
+
+// checkpointJSON is the JSON encoding of a checkpoint
+type checkpointJSON struct {
+	InputOffset  int64           `json:"inputOffset"`
+	OutputOffset int64           `json:"outputOffset"`
+	State        checkpointState `json:"state"`
+}
+
+// MarshalJSON encodes the checkpoint, including the parser state, as JSON
+func (c Checkpoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(checkpointJSON{InputOffset: c.InputOffset, OutputOffset: c.OutputOffset, State: c.state})
+}
+
+// UnmarshalJSON decodes a checkpoint encoded with MarshalJSON
+func (c *Checkpoint) UnmarshalJSON(data []byte) error {
+	var decoded checkpointJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return WrapFormatError("invalid checkpoint", err)
+	}
+	if decoded.State.Version != checkpointVersion {
+		return NewFormatError(fmt.Sprintf("invalid checkpoint: unsupported version %d", decoded.State.Version))
+	}
+	if len(decoded.State.Levels) == 0 {
+		return NewFormatError("invalid checkpoint: no open containers")
+	}
+	*c = Checkpoint{InputOffset: decoded.InputOffset, OutputOffset: decoded.OutputOffset, state: decoded.State}
+	return nil
+}
+
+// WithCheckpoints makes FormatTo and ResumeFormatTo call save with a
+// checkpoint every time at least tokens tokens have been read since the
+// last one, for long-running pipelines that must continue formatting a
+// huge document after a restart. Checkpoints are taken between the elements
+// or members of expanded containers, so a single huge element delays the
+// next checkpoint. A nil function or a tokens value below 1 disables
+// checkpoints.
+//
+// Checkpoints need UTF-8 input read as a stream, so they cannot be combined
+// with WithPostProcess, WithHardWrap, WithRawPaths, or lenient special floats.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxTokens(0), WithCheckpoints(100000, func(c Checkpoint) error {
+//	    if err := output.Sync(); err != nil {
+//	        return err
+//	    }
+//	    state, _ := json.Marshal(c)
+//	    return os.WriteFile("format.checkpoint", state, 0o644)
+//	}))
+func WithCheckpoints(tokens int, save CheckpointFunc) ConfigOption {
+	return func(c *Config) {
+		if tokens >= 1 && save != nil {
+			c.CheckpointTokens = tokens
+			c.Checkpoint = save
+		}
+	}
+}
+
+// ResumeFormatTo continues formatting from a checkpoint saved by FormatTo or
+// an earlier ResumeFormatTo. The input r must continue at the checkpoint's
+// InputOffset, and the output is written to w as if it continued at the
+// checkpoint's OutputOffset, so open the output file, truncate it to
+// OutputOffset, and append to it.
+//
+// Example:
+//
+//	input.Seek(checkpoint.InputOffset, io.SeekStart)
+//	output.Truncate(checkpoint.OutputOffset)
+//	output.Seek(checkpoint.OutputOffset, io.SeekStart)
+//	err := formatter.ResumeFormatTo(output, input, checkpoint)
+func (f *Formatter) ResumeFormatTo(w io.Writer, r io.Reader, checkpoint Checkpoint) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	if w == nil {
+		return NewFormatError("output writer cannot be nil")
+	}
+	if r == nil {
+		return NewFormatError("input reader cannot be nil")
+	}
+	if err := f.config.checkCheckpoints(); err != nil {
+		return err
+	}
+	if len(checkpoint.state.Levels) == 0 {
+		return NewFormatError("invalid checkpoint: no open containers")
+	}
+
+	// The decoder reads the open containers again from a synthetic prefix
+	prefix, prefixTokens := checkpoint.state.prefix()
+	output := outputBuffer{writer: newFlushWriter(f.config.Faults.writer(w), f.config.FlushPolicy, FlushPolicy{}), size: int(checkpoint.OutputOffset)}
+	parser := f.newParser(io.MultiReader(strings.NewReader(prefix), r), &output, 0)
+	parser.inputBase = checkpoint.InputOffset - int64(len(prefix))
+	for i := 0; i < prefixTokens; i++ {
+		if _, err := parser.decoder.Token(); err != nil {
+			return WrapFormatError("invalid checkpoint", err)
+		}
+	}
+	parser.restoreCheckpoint(checkpoint.state)
+
+	position := func() int { return int(parser.inputBase + parser.decoder.InputOffset()) }
+	if err := parser.run(position); err != nil {
+		return parser.locateError(err, "")
+	}
+	if err := output.Flush(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	return nil
+}
+
+// checkCheckpoints reports an error if checkpoints are enabled with options
+// that do not read the input as a stream
+func (c *Config) checkCheckpoints() error {
+	switch {
+	case c.Checkpoint == nil:
+		return nil
+	case c.rewritesLines():
+		return NewFormatError("checkpoints cannot be combined with post-processing or hard wrapping")
+	case len(c.RawPaths) > 0:
+		return NewFormatError("checkpoints cannot be combined with raw paths")
+	case c.SpecialFloats != SpecialFloatsReject:
+		return NewFormatError("checkpoints cannot be combined with lenient special floats")
+	}
+	return nil
+}
+
+// checkpointReader returns a reader for the input of a checkpointed run,
+// which must be UTF-8 without a byte order mark so that offsets can be
+// sought in the original input
+func checkpointReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	prefix, _ := buffered.Peek(4)
+	if encoding, _ := detectEncoding(string(prefix)); encoding != "" {
+		return nil, NewFormatError(fmt.Sprintf("checkpoints need UTF-8 input without a byte order mark, not %s", encoding))
+	}
+	return buffered, nil
+}
+
+// saveCheckpoint calls the checkpoint function if enough tokens were read
+// since the last checkpoint and the parser is between the elements or
+// members of an expanded container
+func (p *TokenParser) saveCheckpoint(tokens int) error {
+	if p.config.Checkpoint == nil || p.output == nil || p.output.writer == nil || tokens-p.checkpointed < p.config.CheckpointTokens {
+		return nil
+	}
+	if p.depth == 0 || len(p.path) != p.depth || len(p.inArray) != p.depth || p.isFirstElement || p.expectingKey == p.isInArray() {
+		return nil
+	}
+	if p.fold != nil || p.records != nil || p.sample != nil || p.order != nil || p.capture != nil || p.hiding || p.truncated || p.shouldFormatCompact() {
+		return nil
+	}
+
+	state := checkpointState{
+		Version:         checkpointVersion,
+		Tokens:          tokens,
+		MinCompactDepth: p.minCompactDepth,
+		TopLevelStart:   p.topLevelStart,
+		BudgetLines:     p.budgetLines,
+	}
+	for i, level := range p.path {
+		saved := checkpointLevel{
+			Array:       p.inArray[i],
+			Key:         level.key,
+			Index:       level.index,
+			Homogeneous: level.homogeneous,
+			Members:     level.members,
+			Folded:      level.folded,
+			SpanStart:   level.spanStart,
+			Namespace:   level.namespace,
+		}
+		for key := range level.keys {
+			saved.Keys = append(saved.Keys, key)
+		}
+		state.Levels = append(state.Levels, saved)
+	}
+
+	if err := p.output.Flush(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	checkpoint := Checkpoint{
+		InputOffset:  p.inputBase + p.decoder.InputOffset(),
+		OutputOffset: int64(p.output.Len()),
+		state:        state,
+	}
+	if err := p.config.Checkpoint(checkpoint); err != nil {
+		return WrapFormatError("failed to save checkpoint", err)
+	}
+	p.checkpointed = tokens
+	return nil
+}
+
+// prefix returns JSON text that opens the containers of the state, with a
+// placeholder for the last element or member read, so that the rest of the
+// input can be decoded after it. It also returns the number of tokens in
+// the text.
+func (s checkpointState) prefix() (string, int) {
+	var prefix strings.Builder
+	tokens := 0
+	for _, level := range s.Levels {
+		if level.Array {
+			prefix.WriteString("[")
+			tokens++
+		} else {
+			prefix.WriteString(`{"":`)
+			tokens += 2
+		}
+	}
+	prefix.WriteString("0")
+	return prefix.String(), tokens + 1
+}
+
+// restoreCheckpoint sets the parser state saved in a checkpoint
+func (p *TokenParser) restoreCheckpoint(state checkpointState) {
+	p.depth = len(state.Levels)
+	p.minCompactDepth = state.MinCompactDepth
+	p.topLevelStart = state.TopLevelStart
+	p.budgetLines = state.BudgetLines
+	p.resumedTokens = state.Tokens
+	p.checkpointed = state.Tokens
+	p.inArray = p.inArray[:0]
+	p.path = p.path[:0]
+	for _, saved := range state.Levels {
+		level := pathLevel{
+			key:         saved.Key,
+			index:       saved.Index,
+			schema:      p.childSchema(),
+			homogeneous: saved.Homogeneous,
+			members:     saved.Members,
+			folded:      saved.Folded,
+			spanStart:   saved.SpanStart,
+			namespace:   saved.Namespace,
+		}
+		if len(saved.Keys) > 0 {
+			level.keys = make(map[string]bool, len(saved.Keys))
+			for _, key := range saved.Keys {
+				level.keys[key] = true
+			}
+		}
+		p.path = append(p.path, level)
+		p.inArray = append(p.inArray, saved.Array)
+	}
+	p.isFirstElement = false
+	p.expectingKey = !p.isInArray()
+}
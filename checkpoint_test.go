@@ -0,0 +1,138 @@
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResumeFormatTo(t *testing.T) {
+	input := `{"items":[{"id":1,"tags":["a","b"]},{"id":2,"nested":{"x":[1,2,{"y":3}]}},3,"four",[5,6]],"meta":{"a":1,"b":{"c":[1,2,3]}},"z":null}`
+	tests := []struct {
+		name    string
+		options []ConfigOption
+	}{
+		{"default", nil},
+		{"expanded", []ConfigOption{WithCompactDepth(0)}},
+		{"leading commas", []ConfigOption{WithLeadingCommas(), WithCompactDepth(0)}},
+		{"wide object fold", []ConfigOption{WithWideObjectFold(1), WithCompactDepth(2)}},
+		{"width limit", []ConfigOption{WithMaxWidth(20)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Checkpoints are saved and restored as JSON, as after a restart
+			var saved [][]byte
+			options := append(tt.options, WithCheckpoints(1, func(checkpoint Checkpoint) error {
+				data, err := json.Marshal(checkpoint)
+				saved = append(saved, data)
+				return err
+			}))
+			formatter := NewFormatter(NewConfig(options...))
+			var output bytes.Buffer
+			if err := formatter.FormatTo(&output, strings.NewReader(input)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != expected {
+				t.Fatalf("Expected:\n%s\n\nGot:\n%s", expected, output.String())
+			}
+			if len(saved) == 0 {
+				t.Fatal("Expected checkpoints")
+			}
+
+			for _, data := range saved {
+				var checkpoint Checkpoint
+				if err := json.Unmarshal(data, &checkpoint); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				resumed := bytes.NewBufferString(expected[:checkpoint.OutputOffset])
+				if err := formatter.ResumeFormatTo(resumed, strings.NewReader(input[checkpoint.InputOffset:]), checkpoint); err != nil {
+					t.Fatalf("Unexpected error resuming from %s: %v", data, err)
+				}
+				if resumed.String() != expected {
+					t.Errorf("Resuming from %s\nExpected:\n%s\n\nGot:\n%s", data, expected, resumed.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	input := `[1,2,3,4,5,6,7,8,9,10]`
+	var offsets []int64
+	formatter := NewFormatter(NewConfig(WithCompactDepth(0), WithCheckpoints(4, func(checkpoint Checkpoint) error {
+		offsets = append(offsets, checkpoint.InputOffset)
+		return nil
+	})))
+	if err := formatter.FormatTo(&bytes.Buffer{}, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Checkpoints follow the fourth and eighth tokens, the third and seventh
+	// elements, and none follows the closing bracket
+	expected := []int64{6, 14}
+	if len(offsets) != len(expected) {
+		t.Fatalf("Expected offsets %v, got %v", expected, offsets)
+	}
+	for i := range expected {
+		if offsets[i] != expected[i] {
+			t.Errorf("Expected offsets %v, got %v", expected, offsets)
+		}
+	}
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	save := func(Checkpoint) error { return nil }
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		input    string
+		expected string
+		code     ErrorCode
+	}{
+		{"post-processing", []ConfigOption{WithPostProcess(func(line string, depth int, path string) string { return line })}, `[1]`, "checkpoints cannot be combined with post-processing or hard wrapping", CodeInvalidConfig},
+		{"raw paths", []ConfigOption{WithRawPaths("a")}, `{"a":1}`, "checkpoints cannot be combined with raw paths", CodeInvalidConfig},
+		{"UTF-16", nil, "\xFF\xFE[\x001\x00]\x00", "checkpoints need UTF-8 input without a byte order mark, not UTF-16LE", CodeInvalidConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append(tt.options, WithCheckpoints(1, save))
+			err := NewFormatter(NewConfig(options...)).FormatTo(&bytes.Buffer{}, strings.NewReader(tt.input))
+			if err == nil || err.Error() != tt.expected {
+				t.Fatalf("Expected error %q, got %v", tt.expected, err)
+			}
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) || formatErr.Code() != tt.code {
+				t.Errorf("Expected code %s, got %v", tt.code, err)
+			}
+		})
+	}
+
+	t.Run("save", func(t *testing.T) {
+		saveErr := errors.New("disk full")
+		formatter := NewFormatter(NewConfig(WithCompactDepth(0), WithCheckpoints(1, func(Checkpoint) error { return saveErr })))
+		err := formatter.FormatTo(&bytes.Buffer{}, strings.NewReader(`[1,2]`))
+		if !errors.Is(err, saveErr) {
+			t.Fatalf("Expected the save error, got %v", err)
+		}
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) || formatErr.Code() != CodeIO {
+			t.Errorf("Expected code %s, got %v", CodeIO, err)
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		var checkpoint Checkpoint
+		err := json.Unmarshal([]byte(`{"inputOffset":1,"outputOffset":1,"state":{"version":99,"levels":[{"array":true}]}}`), &checkpoint)
+		if err == nil || !strings.Contains(err.Error(), "invalid checkpoint: unsupported version 99") {
+			t.Fatalf("Expected a version error, got %v", err)
+		}
+	})
+}
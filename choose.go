@@ -0,0 +1,279 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// FormatMode is the way of formatting a document recommended by ChooseConfig.
+type FormatMode int
+
+const (
+	// ModePretty formats the document with Format, one member per line down
+	// to the compact depth.
+	ModePretty FormatMode = iota
+	// ModeLog formats a stream of records with FormatLog, one line each.
+	ModeLog
+	// ModeOutline formats a large document compactly with wide objects folded,
+	// so its structure can be taken in at a glance. The output is for display
+	// only.
+	ModeOutline
+)
+
+// String returns "pretty", "log", or "outline".
+func (m FormatMode) String() string {
+	switch m {
+	case ModeLog:
+		return "log"
+	case ModeOutline:
+		return "outline"
+	default:
+		return "pretty"
+	}
+}
+
+// Thresholds used by ChooseConfig
+const (
+	outlineSize      = 1 << 20 // Documents from this size are outlined
+	outlineKeys      = 50      // Documents with objects this wide are outlined
+	outlineFoldKeys  = 10      // Members written of folded objects in outlines
+	deepDepth        = 6       // Documents from this depth are compacted early
+	recommendedWidth = 120
+)
+
+// InputMeta describes a document, or a sample of its beginning, to
+// ChooseConfig.
+type InputMeta struct {
+	// Size is the size of the whole document in bytes, which may be larger
+	// than the sample.
+	Size int
+
+	// Records is the number of top-level values, more than one for NDJSON
+	// logs and other streams of records.
+	Records int
+
+	// Depth is the deepest nesting of objects and arrays.
+	Depth int
+
+	// MaxKeys is the largest number of members of an object.
+	MaxKeys int
+
+	// Tables is the number of arrays of at least two objects, and Homogeneous
+	// the number of those whose objects all have the same key set.
+	Tables      int
+	Homogeneous int
+}
+
+// NewInputMeta describes a document from a sample of its beginning and the
+// size of the whole document. A sample cut off in the middle of a value is
+// described up to the cut. A size smaller than the sample is replaced by the
+// size of the sample.
+//
+// Example:
+//
+//	sample := body
+//	if len(sample) > 64<<10 {
+//	    sample = sample[:64<<10]
+//	}
+//	meta := NewInputMeta(sample, len(body))
+func NewInputMeta(sample []byte, size int) InputMeta {
+	meta := InputMeta{Size: max(size, len(sample))}
+	decoder := json.NewDecoder(bytes.NewReader(sample))
+	var open []*metaContainer
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if len(open) == 0 {
+			meta.Records++
+		}
+		var top *metaContainer
+		if len(open) > 0 {
+			top = open[len(open)-1]
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			if top != nil {
+				top.elements++
+			}
+			open = append(open, &metaContainer{array: token == json.Delim('['), homogeneous: true})
+			meta.Depth = max(meta.Depth, len(open))
+		case json.Delim('}'), json.Delim(']'):
+			open = open[:len(open)-1]
+			top.close(&meta)
+			if len(open) > 0 && !top.array {
+				open[len(open)-1].addObject(top.keys)
+			}
+		default:
+			if top == nil {
+				continue
+			}
+			if key, ok := token.(string); ok && !top.array && top.expectKey() {
+				top.keys = append(top.keys, key)
+			} else {
+				top.elements++
+			}
+		}
+	}
+	// Containers cut off by the end of the sample count as far as they go,
+	// leaving out their last element, which may be cut off too
+	for i := len(open) - 1; i >= 0; i-- {
+		if i < len(open)-1 {
+			open[i].elements--
+		}
+		open[i].close(&meta)
+	}
+	return meta
+}
+
+// metaContainer is an object or array being described by NewInputMeta
+type metaContainer struct {
+	array       bool
+	elements    int      // Values held by the container
+	keys        []string // Keys of an object
+	objects     int      // Object elements of an array
+	firstKeys   map[string]bool
+	homogeneous bool
+}
+
+// expectKey reports whether the next string in the object is a key
+func (c *metaContainer) expectKey() bool {
+	return len(c.keys) == c.elements
+}
+
+// addObject records an object element with the given keys
+func (c *metaContainer) addObject(keys []string) {
+	if !c.array {
+		return
+	}
+	c.objects++
+	if c.firstKeys == nil {
+		c.firstKeys = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			c.firstKeys[key] = true
+		}
+		return
+	}
+	if len(keys) != len(c.firstKeys) {
+		c.homogeneous = false
+		return
+	}
+	for _, key := range keys {
+		if !c.firstKeys[key] {
+			c.homogeneous = false
+			return
+		}
+	}
+}
+
+// close adds the closed container to the description
+func (c *metaContainer) close(meta *InputMeta) {
+	if !c.array {
+		meta.MaxKeys = max(meta.MaxKeys, len(c.keys))
+		return
+	}
+	if c.objects < 2 {
+		return
+	}
+	meta.Tables++
+	if c.homogeneous && c.objects == c.elements {
+		meta.Homogeneous++
+	}
+}
+
+// Recommendation is the formatting recommended by ChooseConfig.
+type Recommendation struct {
+	// Mode tells whether to use Format or FormatLog, and whether the output
+	// is for display only.
+	Mode FormatMode
+
+	// Preset is the name of the preset to start from, and Options the options
+	// to apply on top of it.
+	Preset  string
+	Options []ConfigOption
+
+	// Reason explains the recommendation, for logs and debugging.
+	Reason string
+}
+
+// Config returns the recommended configuration.
+func (r Recommendation) Config() *Config {
+	config, _ := Preset(r.Preset, r.Options...)
+	return config
+}
+
+// ChooseConfig recommends how to format a document, so middleware can tune
+// the formatting of each response instead of hardcoding one style:
+//
+//   - streams of several records are formatted with FormatLog (ModeLog)
+//   - documents of 1 MiB or more, or with objects of 50 or more members, are
+//     outlined: compact, with wide objects folded to their first members
+//     (ModeOutline, display only)
+//   - documents made of arrays of objects sharing their keys are formatted
+//     with one object per line (WithAdaptiveCompaction)
+//   - deeply nested documents are compacted from depth 2 within 120 columns
+//   - other documents use the default preset
+//
+// Example:
+//
+//	rec := ChooseConfig(NewInputMeta(sample, len(body)))
+//	formatter := NewFormatter(rec.Config())
+//	if rec.Mode == ModeLog {
+//	    formatted, err = formatter.FormatLog(body)
+//	} else {
+//	    formatted, err = formatter.Format(body)
+//	}
+func ChooseConfig(meta InputMeta) Recommendation {
+	switch {
+	case meta.Records > 1:
+		return Recommendation{
+			Mode:   ModeLog,
+			Preset: "default",
+			Reason: fmt.Sprintf("%d records, formatted one per line", meta.Records),
+		}
+	case meta.Size >= outlineSize || meta.MaxKeys >= outlineKeys:
+		return Recommendation{
+			Mode:    ModeOutline,
+			Preset:  "compact",
+			Options: []ConfigOption{WithMaxWidth(recommendedWidth), WithWideObjectFold(outlineFoldKeys)},
+			Reason:  fmt.Sprintf("%d bytes with up to %d members per object, outlined", meta.Size, meta.MaxKeys),
+		}
+	case meta.Tables > 0 && 2*meta.Homogeneous >= meta.Tables:
+		return Recommendation{
+			Mode:    ModePretty,
+			Preset:  "default",
+			Options: []ConfigOption{WithAdaptiveCompaction(), WithMaxWidth(recommendedWidth)},
+			Reason:  fmt.Sprintf("%d of %d arrays of objects share their keys, written as rows", meta.Homogeneous, meta.Tables),
+		}
+	case meta.Depth >= deepDepth:
+		return Recommendation{
+			Mode:    ModePretty,
+			Preset:  "compact",
+			Options: []ConfigOption{WithMaxWidth(recommendedWidth)},
+			Reason:  fmt.Sprintf("nested %d levels deep, compacted from depth 2", meta.Depth),
+		}
+	default:
+		return Recommendation{
+			Mode:   ModePretty,
+			Preset: "default",
+			Reason: "small document, fully formatted",
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewInputMeta(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   string
+		size     int
+		expected InputMeta
+	}{
+		{
+			name:     "object",
+			sample:   `{"a":{"b":[1,{"c":null}]},"d":"e"}`,
+			expected: InputMeta{Size: 34, Records: 1, Depth: 4, MaxKeys: 2},
+		},
+		{
+			name:     "records",
+			sample:   "{\"level\":\"info\"}\n{\"level\":\"warn\"}\n{\"level\":\"error\"}\n",
+			size:     1000,
+			expected: InputMeta{Size: 1000, Records: 3, Depth: 1, MaxKeys: 1},
+		},
+		{
+			name:     "tables",
+			sample:   `{"users":[{"id":1,"name":"a"},{"name":"b","id":2}],"mixed":[{"id":1},{"name":"b"}],"single":[{"id":1}]}`,
+			expected: InputMeta{Size: 103, Records: 1, Depth: 3, MaxKeys: 3, Tables: 2, Homogeneous: 1},
+		},
+		{
+			name:     "array of objects and scalars",
+			sample:   `[{"id":1},{"id":2},3]`,
+			expected: InputMeta{Size: 21, Records: 1, Depth: 2, MaxKeys: 1, Tables: 1},
+		},
+		{
+			name:     "cut off sample",
+			sample:   `[{"id":1,"name":"a"},{"id":2,"name":"b"},{"id":3,"na`,
+			size:     1 << 20,
+			expected: InputMeta{Size: 1 << 20, Records: 1, Depth: 2, MaxKeys: 2, Tables: 1, Homogeneous: 1},
+		},
+		{
+			name:     "scalar",
+			sample:   `"text"`,
+			expected: InputMeta{Size: 6, Records: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := NewInputMeta([]byte(tt.sample), tt.size)
+			if meta != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, meta)
+			}
+		})
+	}
+}
+
+func TestChooseConfig(t *testing.T) {
+	wide := `{` + strings.Repeat(`"k":1,`, 60) + `"last":1}`
+	tests := []struct {
+		name   string
+		sample string
+		size   int
+		mode   FormatMode
+		preset string
+		check  func(c *Config) bool
+	}{
+		{"records", "{\"a\":1}\n{\"a\":2}\n", 0, ModeLog, "default", nil},
+		{"large", `{"a":1}`, 2 << 20, ModeOutline, "compact", func(c *Config) bool { return c.WideObjectKeys == 10 && c.MaxWidth == 120 }},
+		{"wide object", wide, 0, ModeOutline, "compact", nil},
+		{"table", `{"rows":[{"id":1},{"id":2}]}`, 0, ModePretty, "default", func(c *Config) bool { return c.AdaptiveCompaction }},
+		{"deep", `{"a":{"b":{"c":{"d":{"e":{"f":1}}}}}}`, 0, ModePretty, "compact", func(c *Config) bool { return c.CompactDepth == 2 }},
+		{"plain", `{"a":[1,2],"b":{"c":true}}`, 0, ModePretty, "default", func(c *Config) bool { return reflect.DeepEqual(c, DefaultConfig()) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ChooseConfig(NewInputMeta([]byte(tt.sample), tt.size))
+			if rec.Mode != tt.mode || rec.Preset != tt.preset {
+				t.Errorf("Expected %s mode with preset %s, got %s mode with preset %s (%s)", tt.mode, tt.preset, rec.Mode, rec.Preset, rec.Reason)
+			}
+			if rec.Reason == "" {
+				t.Error("Expected a reason")
+			}
+			if tt.check != nil && !tt.check(rec.Config()) {
+				t.Errorf("Unexpected configuration %+v", rec.Config())
+			}
+		})
+	}
+}
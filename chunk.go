@@ -0,0 +1,255 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Quota limits the output returned by each call to FormatChunk. A limit of
+// 0 or less does not apply; at least one of them must be set.
+type Quota struct {
+	// Bytes is the maximum size of a chunk, not counting the line break
+	// that ends it.
+	Bytes int
+
+	// Lines is the maximum number of lines of a chunk.
+	Lines int
+}
+
+// Chunk is a part of the formatted output returned by FormatChunk.
+type Chunk struct {
+	// Text holds whole lines of the output, without the line break after
+	// the last one.
+	Text string
+
+	// Line is the zero-based index of the first line of the chunk in the
+	// whole output, and Offset its byte offset.
+	Line   int
+	Offset int
+
+	// Continuation is the token to pass to FormatChunk for the next chunk,
+	// or empty if the chunk ends the output.
+	Continuation string
+
+	// NextPath is the path of the first key or value of the next chunk, in
+	// the same form as ValueContext.Path, for showing where the output
+	// continues. A chunk starting with a closing bracket has the path of
+	// the container. It is nil for the last chunk.
+	NextPath []string
+}
+
+// chunkToken is the decoded form of a continuation token
+type chunkToken struct {
+	Offset int      `json:"offset"`
+	Line   int      `json:"line"`
+	Path   []string `json:"path,omitempty"`
+	Sum    string   `json:"sum"`
+}
+
+// FormatChunk formats a JSON string like Format but returns only the part
+// of the output within quota, cut at a line break, and a continuation token
+// for the next part, so servers can paginate pretty-printed documents for
+// constrained UIs without keeping state between requests. Pass an empty
+// continuation for the first chunk. A chunk holds at least one line, even a
+// line longer than quota.Bytes. Joining the texts of all chunks with line
+// breaks gives the output of Format.
+//
+// Each call formats the document from the start, but stops once the chunk
+// is complete, so syntax errors after it are only reported by the call that
+// reaches them. A continuation token is only valid for the same input and
+// configuration; tokens for another input are rejected.
+//
+// Chunks are not available with a post-processor or hard wrapping, as they
+// change output offsets. The cache set with SetCache is not used.
+//
+// Example:
+//
+//	chunk, err := formatter.FormatChunk(body, Quota{Lines: 100}, r.URL.Query().Get("next"))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Fprintln(w, chunk.Text)
+//	if chunk.Continuation != "" {
+//	    fmt.Fprintf(w, "<a href=\"?next=%s\">more</a>", chunk.Continuation)
+//	}
+func (f *Formatter) FormatChunk(jsonStr string, quota Quota, continuation string) (chunk *Chunk, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			chunk = nil
+		}
+	}()
+
+	if quota.Bytes <= 0 && quota.Lines <= 0 {
+		return nil, NewFormatError("invalid chunk quota: Bytes or Lines must be positive")
+	}
+	if f.config.rewritesLines() {
+		return nil, NewFormatError("chunks are not available with a post-processor or hard wrapping, as they change output offsets")
+	}
+	sum := chunkSum(jsonStr)
+	var start chunkToken
+	if continuation != "" {
+		if start, err = decodeChunkToken(continuation); err != nil {
+			return nil, err
+		}
+		if start.Sum != sum {
+			return nil, NewFormatError("invalid continuation token: it was issued for another input")
+		}
+	}
+
+	state := &chunkState{start: start.Offset, quota: quota, paths: map[int][]string{}}
+	text, parser, err := f.formatString(jsonStr, func(p *TokenParser) {
+		p.output.indexLines = true
+		p.chunk = state
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, found := slices.BinarySearch(parser.output.lineStarts, start.Offset); start.Offset > 0 && !found {
+		return nil, NewFormatError("invalid continuation token: the output has no line at offset " + strconv.Itoa(start.Offset))
+	}
+	if !slices.Equal(state.paths[start.Offset], start.Path) {
+		return nil, NewFormatError("invalid continuation token: the output changed since it was issued")
+	}
+
+	chunk = &Chunk{Line: start.Line, Offset: start.Offset}
+	end, ok := state.end(parser.output.lineStarts, len(text), !state.stopped)
+	if !ok {
+		chunk.Text = text[start.Offset:]
+		return chunk, nil
+	}
+	chunk.Text = text[start.Offset : end-1]
+	next := chunkToken{
+		Offset: end,
+		Line:   start.Line + state.lines(parser.output.lineStarts, end),
+		Path:   state.paths[end],
+		Sum:    sum,
+	}
+	encoded, err := json.Marshal(next)
+	if err != nil {
+		return nil, WrapFormatError("failed to encode the continuation token", err)
+	}
+	chunk.Continuation = base64.RawURLEncoding.EncodeToString(encoded)
+	chunk.NextPath = next.Path
+	return chunk, nil
+}
+
+// decodeChunkToken decodes a continuation token returned by FormatChunk
+func decodeChunkToken(continuation string) (chunkToken, error) {
+	var token chunkToken
+	encoded, err := base64.RawURLEncoding.DecodeString(continuation)
+	if err != nil {
+		return token, WrapFormatError("invalid continuation token", err)
+	}
+	if err := json.Unmarshal(encoded, &token); err != nil {
+		return token, WrapFormatError("invalid continuation token", err)
+	}
+	if token.Offset <= 0 || token.Line <= 0 {
+		return token, NewFormatError("invalid continuation token: it does not point after the first line")
+	}
+	return token, nil
+}
+
+// chunkSum returns the checksum of the input identifying continuation tokens
+func chunkSum(input string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// chunkState follows the output of FormatChunk to stop formatting once the
+// chunk is complete
+type chunkState struct {
+	start   int              // Output offset where the chunk starts
+	quota   Quota            // Limits of the chunk
+	paths   map[int][]string // Paths of the first tokens of the lines from start, by output offset
+	stopped bool             // Formatting stopped before the end of the input
+}
+
+// lines returns the number of lines from the start of the chunk up to the
+// line starting at offset end
+func (c *chunkState) lines(lineStarts []int, end int) int {
+	return sort.SearchInts(lineStarts, end) - sort.SearchInts(lineStarts, c.start+1) + 1
+}
+
+// full reports whether the output written so far decides where the chunk
+// ends: it goes past the quota, and a line after the start is complete
+func (c *chunkState) full(b *outputBuffer) bool {
+	first := sort.SearchInts(b.lineStarts, c.start+1)
+	if first == len(b.lineStarts) {
+		return false
+	}
+	overBytes := c.quota.Bytes > 0 && b.stored-c.start > c.quota.Bytes
+	overLines := c.quota.Lines > 0 && len(b.lineStarts)-first >= c.quota.Lines
+	return overBytes || overLines
+}
+
+// end returns the output offset of the line after the chunk, or false if
+// the chunk ends the output, which happens when the output is complete and
+// its rest fits within the quota
+func (c *chunkState) end(lineStarts []int, size int, complete bool) (int, bool) {
+	first := sort.SearchInts(lineStarts, c.start+1)
+	rest := lineStarts[first:]
+	if complete && c.fits(size+1, len(rest)+1) {
+		return 0, false
+	}
+	if len(rest) == 0 {
+		return 0, false
+	}
+	end := rest[0]
+	for i, offset := range rest[1:] {
+		if !c.fits(offset, i+2) {
+			break
+		}
+		end = offset
+	}
+	return end, true
+}
+
+// fits reports whether lines lines ending just before the line starting at
+// offset end fit within the quota
+func (c *chunkState) fits(end, lines int) bool {
+	if c.quota.Bytes > 0 && end-1-c.start > c.quota.Bytes {
+		return false
+	}
+	return c.quota.Lines <= 0 || lines <= c.quota.Lines
+}
+
+// noteLines records path for the lines from the start of the chunk that
+// started since the output had from lines. Lines started by replayed tokens
+// are recorded first, by the tokens themselves.
+func (c *chunkState) noteLines(b *outputBuffer, path []string, from int) {
+	for _, offset := range b.lineStarts[from:] {
+		if _, ok := c.paths[offset]; !ok && offset >= c.start {
+			c.paths[offset] = path
+		}
+	}
+}
+
+// chunkFull reports whether formatting can stop because the chunk is complete
+func (p *TokenParser) chunkFull() bool {
+	if p.chunk == nil || !p.chunk.full(p.output) {
+		return false
+	}
+	p.chunk.stopped = true
+	return true
+}
@@ -0,0 +1,150 @@
+package jsonformat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatChunk(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithCompactDepth(0)))
+	input := `{"users":[{"id":1,"tags":["a","b"]}],"n":2}`
+
+	type chunk struct {
+		Line     int
+		Text     string
+		NextPath string
+	}
+	var got []chunk
+	continuation := ""
+	for {
+		c, err := formatter.FormatChunk(input, Quota{Lines: 3}, continuation)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got = append(got, chunk{c.Line, c.Text, strings.Join(c.NextPath, ".")})
+		if c.Continuation == "" {
+			break
+		}
+		continuation = c.Continuation
+	}
+
+	expected := []chunk{
+		{0, "{\n  \"users\": [\n    {", "users.0.id"},
+		{3, "      \"id\": 1,\n      \"tags\": [\n        \"a\",", "users.0.tags.1"},
+		{6, "        \"b\"\n      ]\n    }", "users"},
+		{9, "  ],\n  \"n\": 2\n}", ""},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%#v\n\nGot:\n%#v", expected, got)
+	}
+}
+
+func TestFormatChunkJoinsToFormat(t *testing.T) {
+	input := `{"users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob","bio":"a rather long biography that overflows"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}}}`
+	configs := map[string]*Config{
+		"default":  DefaultConfig(),
+		"expanded": NewConfig(WithCompactDepth(0)),
+		"width":    NewConfig(WithMaxWidth(30)),
+	}
+	quotas := []Quota{{Lines: 1}, {Lines: 4}, {Bytes: 1}, {Bytes: 50}, {Bytes: 80, Lines: 3}, {Lines: 1000}}
+
+	for name, config := range configs {
+		formatter := NewFormatter(config)
+		expected, err := formatter.Format(input)
+		if err != nil {
+			t.Fatalf("%s: Unexpected error: %v", name, err)
+		}
+		for _, quota := range quotas {
+			var parts []string
+			continuation := ""
+			line := 0
+			for {
+				c, err := formatter.FormatChunk(input, quota, continuation)
+				if err != nil {
+					t.Fatalf("%s %+v: Unexpected error: %v", name, quota, err)
+				}
+				if c.Line != line {
+					t.Errorf("%s %+v: expected chunk at line %d, got %d", name, quota, line, c.Line)
+				}
+				lines := strings.Count(c.Text, "\n") + 1
+				if quota.Lines > 0 && lines > quota.Lines {
+					t.Errorf("%s %+v: chunk of %d lines exceeds the quota", name, quota, lines)
+				}
+				if quota.Bytes > 0 && len(c.Text) > quota.Bytes && lines > 1 {
+					t.Errorf("%s %+v: chunk of %d bytes exceeds the quota", name, quota, len(c.Text))
+				}
+				parts = append(parts, c.Text)
+				line += lines
+				if c.Continuation == "" {
+					break
+				}
+				continuation = c.Continuation
+			}
+			if result := strings.Join(parts, "\n"); result != expected {
+				t.Errorf("%s %+v: Expected:\n%s\n\nGot:\n%s", name, quota, expected, result)
+			}
+		}
+	}
+}
+
+func TestFormatChunkStopsEarly(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithCompactDepth(0)))
+	input := `[1,2,3,4,5,6 oops`
+
+	c, err := formatter.FormatChunk(input, Quota{Lines: 2}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "[\n  1,"; c.Text != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, c.Text)
+	}
+
+	// The syntax error is reported by the call that reaches it
+	for c.Continuation != "" {
+		c, err = formatter.FormatChunk(input, Quota{Lines: 2}, c.Continuation)
+		if err != nil {
+			break
+		}
+	}
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Code() != CodeSyntax {
+		t.Errorf("Expected a syntax error, got %v", err)
+	}
+}
+
+func TestFormatChunkErrors(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	first, err := formatter.FormatChunk(`[1,2,3]`, Quota{Lines: 1}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		formatter    *Formatter
+		input        string
+		quota        Quota
+		continuation string
+		code         ErrorCode
+	}{
+		{"no quota", formatter, `[1]`, Quota{}, "", CodeInvalidArgument},
+		{"garbage token", formatter, `[1,2,3]`, Quota{Lines: 1}, "not a token!", CodeInvalidArgument},
+		{"other input", formatter, `[1,2,4]`, Quota{Lines: 1}, first.Continuation, CodeInvalidArgument},
+		{"other config", NewFormatter(NewConfig(WithCompactDepth(1))), `[1,2,3]`, Quota{Lines: 1}, first.Continuation, CodeInvalidArgument},
+		{"post-processor", NewFormatter(NewConfig(WithPostProcess(func(line string, depth int, path string) string { return line }))), `[1]`, Quota{Lines: 1}, "", CodeInvalidConfig},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.formatter.FormatChunk(test.input, test.quota, test.continuation)
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected a FormatError, got %v", err)
+			}
+			if formatErr.Code() != test.code {
+				t.Errorf("Expected code %s, got %s (%v)", test.code, formatErr.Code(), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// benchResult holds the measurements for a single preset
+type benchResult struct {
+	preset      string
+	iterations  int
+	elapsed     time.Duration
+	allocs      uint64
+	allocBytes  uint64
+	inputBytes  int
+	outputBytes int
+}
+
+// nsPerOp returns the average time per formatting run
+func (r benchResult) nsPerOp() int64 {
+	return r.elapsed.Nanoseconds() / int64(r.iterations)
+}
+
+// mbPerSecond returns the input throughput in megabytes per second
+func (r benchResult) mbPerSecond() float64 {
+	return float64(r.inputBytes) * float64(r.iterations) / 1e6 / r.elapsed.Seconds()
+}
+
+// runBench measures formatting performance of a document for each preset
+func runBench(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	presetList := flags.String("presets", strings.Join(jsonformat.PresetNames(), ","), "comma-separated presets to measure")
+	duration := flags.Duration("benchtime", time.Second, "minimum measurement time per preset")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: jsonformat bench [flags] file")
+		return exitUsage
+	}
+
+	input, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+
+	var results []benchResult
+	for _, name := range strings.Split(*presetList, ",") {
+		name = strings.TrimSpace(name)
+		config, ok := jsonformat.Preset(name)
+		if !ok {
+			fmt.Fprintf(stderr, "jsonformat: unknown preset %q (available: %v)\n", name, jsonformat.PresetNames())
+			return exitUsage
+		}
+		result, err := benchmarkPreset(name, config, input, *duration)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: preset %s: %v\n", name, err)
+			return exitError
+		}
+		results = append(results, result)
+	}
+
+	writeBenchResults(stdout, flags.Arg(0), results)
+	return exitOK
+}
+
+// benchmarkPreset formats the input repeatedly for at least the given duration
+func benchmarkPreset(name string, config *jsonformat.Config, input []byte, duration time.Duration) (benchResult, error) {
+	formatter := jsonformat.NewFormatter(config)
+
+	// Warm up once, which also validates the input and gives the output size
+	output, err := formatter.FormatBytes(input)
+	if err != nil {
+		return benchResult{}, err
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	iterations := 0
+	for time.Since(start) < duration || iterations == 0 {
+		if _, err := formatter.FormatBytes(input); err != nil {
+			return benchResult{}, err
+		}
+		iterations++
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return benchResult{
+		preset:      name,
+		iterations:  iterations,
+		elapsed:     elapsed,
+		allocs:      (after.Mallocs - before.Mallocs) / uint64(iterations),
+		allocBytes:  (after.TotalAlloc - before.TotalAlloc) / uint64(iterations),
+		inputBytes:  len(input),
+		outputBytes: len(output),
+	}, nil
+}
+
+// writeBenchResults prints the results as an aligned table
+func writeBenchResults(w io.Writer, filename string, results []benchResult) {
+	fmt.Fprintf(w, "file: %s\ngo: %s %s/%s\n\n", filename, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "preset\titerations\tns/op\tMB/s\tallocs/op\tB/op\toutput bytes\t")
+	for _, r := range results {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.2f\t%d\t%d\t%d\t\n",
+			r.preset, r.iterations, r.nsPerOp(), r.mbPerSecond(), r.allocs, r.allocBytes, r.outputBytes)
+	}
+	table.Flush()
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// runConfig prints the effective configuration as formatted JSON, an object
+// of the values by name, or with -explain an array documenting every option
+// with its value, default, source, and description
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFlags := addConfigFlags(flags)
+	explain := flags.Bool("explain", false, "document every option with its value, default, source, and description")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "jsonformat: config takes no arguments\n")
+		return exitUsage
+	}
+	_, settings, err := configFlags.config(nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitUsage
+	}
+
+	var output string
+	if *explain {
+		output, err = jsonformat.ExplainConfig(settings)
+	} else {
+		output, err = configValues(settings)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, output)
+	return exitOK
+}
+
+// configValues formats the effective values of the settings as a JSON
+// object, keeping the order of the Config fields
+func configValues(settings []jsonformat.ConfigSetting) (string, error) {
+	docs := jsonformat.DescribeConfig(settings)
+	names := make([]string, len(docs))
+	values := make(map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		names[i] = doc.Name
+		values[doc.Name] = doc.Value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	config := jsonformat.NewConfig(jsonformat.WithKeyOrder("", names...))
+	return jsonformat.NewFormatter(config).Format(string(data))
+}
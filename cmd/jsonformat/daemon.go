@@ -0,0 +1,286 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// JSON-RPC 2.0 error codes returned by the daemon
+const (
+	rpcParseError     = -32700 // The request is not JSON
+	rpcInvalidRequest = -32600 // The request is not a JSON-RPC request
+	rpcMethodNotFound = -32601 // The method is unknown
+	rpcInvalidParams  = -32602 // The parameters or their options are invalid
+	rpcFormatError    = -32000 // The input cannot be formatted
+)
+
+// maxDaemonFormatters is the number of option sets whose formatters the
+// daemon keeps before it starts over
+const maxDaemonFormatters = 256
+
+// rpcRequest is a JSON-RPC 2.0 request; requests without an id are
+// notifications, which are not answered
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response with either a result or an error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a response. For formatting errors, the data holds
+// the jsonformat error code.
+type rpcError struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Data    map[string]string `json:"data,omitempty"`
+}
+
+// formatParams are the parameters of the format method. Options is a style
+// sheet applied on top of the configuration of the daemon.
+type formatParams struct {
+	Input   *string         `json:"input"`
+	Options json.RawMessage `json:"options"`
+}
+
+// daemon answers the requests of all connections with a shared set of
+// formatters, one per option set
+type daemon struct {
+	base       *jsonformat.Config
+	mu         sync.Mutex
+	formatters map[string]*jsonformat.Formatter
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+// runDaemon serves JSON-RPC 2.0 requests, one per line, on standard input
+// and output, or on the connections of a unix socket with -socket, until the
+// input ends or a shutdown request arrives. Editor plugins and build tools
+// format many snippets without starting a process for each.
+func runDaemon(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat daemon", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFlags := addConfigFlags(flags)
+	socket := flags.String("socket", "", "listen on the unix socket at this path instead of standard input and output")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "jsonformat: daemon takes no arguments\n")
+		return exitUsage
+	}
+	config, _, err := configFlags.config(nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitUsage
+	}
+	d := &daemon{
+		base:       config,
+		formatters: map[string]*jsonformat.Formatter{},
+		stop:       make(chan struct{}),
+	}
+
+	if *socket == "" {
+		if err := d.serve(stdin, stdout); err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	listener, err := net.Listen("unix", *socket)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if d.stopped() {
+				return exitOK
+			}
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		go func() {
+			defer conn.Close()
+			if err := d.serve(conn, conn); err != nil {
+				fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			}
+			// Stop listening once the response to shutdown is written
+			if d.stopped() {
+				listener.Close()
+			}
+		}()
+	}
+}
+
+// stopped reports whether a shutdown request arrived
+func (d *daemon) stopped() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// serve answers the requests read from r until it ends or the daemon stops
+func (d *daemon) serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if response := d.handle(line); response != nil {
+				data, err := json.Marshal(response)
+				if err != nil {
+					return err
+				}
+				writer.Write(append(data, '\n'))
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF || d.stopped() {
+			return nil
+		}
+	}
+}
+
+// handle answers a request, returning nil for notifications
+func (d *daemon) handle(line []byte) *rpcResponse {
+	var request rpcRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		return rpcFailure(json.RawMessage("null"), rpcParseError, err.Error())
+	}
+	id := request.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return rpcFailure(id, rpcInvalidRequest, "invalid request")
+	}
+
+	result, failure := d.call(request.Method, request.Params)
+	if request.ID == nil {
+		return nil
+	}
+	if failure != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: id, Error: failure}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return rpcFailure(id, rpcFormatError, err.Error())
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Result: data}
+}
+
+// call runs a method: format, capabilities, or shutdown
+func (d *daemon) call(method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "format":
+		var p formatParams
+		if err := json.Unmarshal(params, &p); err != nil || p.Input == nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "format needs an input string"}
+		}
+		formatter, err := d.formatter(p.Options)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		output, err := formatter.Format(*p.Input)
+		if err != nil {
+			failure := &rpcError{Code: rpcFormatError, Message: err.Error()}
+			var formatErr *jsonformat.FormatError
+			if errors.As(err, &formatErr) {
+				failure.Data = map[string]string{"code": string(formatErr.Code())}
+			}
+			return nil, failure
+		}
+		return map[string]string{"output": output}, nil
+	case "capabilities":
+		return jsonformat.Capabilities(), nil
+	case "shutdown":
+		d.stopOnce.Do(func() { close(d.stop) })
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+// formatter returns the formatter for a style sheet applied on top of the
+// configuration of the daemon, creating it on first use. A preset named in
+// the style sheet replaces the configuration of the daemon.
+func (d *daemon) formatter(options json.RawMessage) (*jsonformat.Formatter, error) {
+	key := string(bytes.TrimSpace(options))
+	if key == "null" {
+		key = ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if formatter, ok := d.formatters[key]; ok {
+		return formatter, nil
+	}
+
+	config := d.base
+	if key != "" {
+		sheetOptions, preset, err := jsonformat.ParseStyleSheet([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		defaults := d.base
+		if preset != "" {
+			var ok bool
+			if defaults, ok = jsonformat.Preset(preset); !ok {
+				return nil, fmt.Errorf("unknown preset %q (available: %v)", preset, jsonformat.PresetNames())
+			}
+		}
+		if config, _, err = jsonformat.ResolveConfig(defaults, sheetOptions, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.formatters) >= maxDaemonFormatters {
+		d.formatters = map[string]*jsonformat.Formatter{}
+	}
+	formatter := jsonformat.NewFormatter(config)
+	d.formatters[key] = formatter
+	return formatter, nil
+}
+
+// rpcFailure returns an error response
+func rpcFailure(id json.RawMessage, code int, message string) *rpcResponse {
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
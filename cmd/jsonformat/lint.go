@@ -0,0 +1,89 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// runLint checks documents against lint rules and reports warnings,
+// returning exitChanges when any document has findings and exitError when
+// any document cannot be read or parsed
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	maxKeyLength := flags.Int("max-key-length", 0, "maximum key length in characters (0 disables)")
+	keyPattern := flags.String("key-pattern", "", "regular expression that every key must match")
+	forbidden := flags.String("forbid", "", "comma-separated keys that must not appear")
+	stdinPath := flags.String("stdin-filepath", "-", "name of the file that standard input comes from, used in the report")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	options := []jsonformat.ConfigOption{jsonformat.WithMaxKeyLength(*maxKeyLength)}
+	if *keyPattern != "" {
+		pattern, err := regexp.Compile(*keyPattern)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: invalid -key-pattern: %v\n", err)
+			return exitUsage
+		}
+		options = append(options, jsonformat.WithKeyPattern(pattern))
+	}
+	if *forbidden != "" {
+		options = append(options, jsonformat.WithForbiddenKeys(strings.Split(*forbidden, ",")...))
+	}
+	formatter := jsonformat.NewFormatter(jsonformat.NewConfig(options...))
+
+	filenames := flags.Args()
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+
+	findings, failed := false, false
+	for _, filename := range filenames {
+		input, err := readInput(filename, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			failed = true
+			continue
+		}
+		if filename == "-" {
+			filename = *stdinPath
+		}
+		_, warnings, err := formatter.FormatWithWarnings(string(input))
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", filename, err)
+			failed = true
+			continue
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(stdout, "%s: %s [%s]\n", filename, warning, warning.Code)
+			findings = true
+		}
+	}
+	switch {
+	case failed:
+		return exitError
+	case findings:
+		return exitChanges
+	}
+	return exitOK
+}
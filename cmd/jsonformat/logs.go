@@ -0,0 +1,112 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// useColor reports whether output to w is colored for the -color mode, and
+// false for ok when the mode is unknown
+func useColor(mode string, w io.Writer) (color, ok bool) {
+	switch mode {
+	case "always":
+		return true, true
+	case "never":
+		return false, true
+	case "auto":
+		return getenv("NO_COLOR") == "" && isTerminal(w), true
+	}
+	return false, false
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatLogs formats NDJSON log records from the named file, or stdin when
+// the name is empty or "-", as they arrive. With follow, the file is read
+// on as it grows.
+func formatLogs(formatter *jsonformat.Formatter, filename string, follow bool, stdin io.Reader, stdout, stderr io.Writer) int {
+	input := stdin
+	if filename != "" && filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		defer file.Close()
+		input = file
+		if follow {
+			input = &followReader{file: file, done: followDone}
+		}
+	}
+
+	if err := formatter.FormatLogTo(stdout, input); err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// followInterval is the time between checks for data appended to a followed file
+const followInterval = 250 * time.Millisecond
+
+// followDone stops following files when closed, and is replaced in tests
+var followDone <-chan struct{}
+
+// followReader reads a file that other processes append to, like tail -f.
+// At the end of the file it waits for more data instead of returning io.EOF,
+// and it starts over when the file is truncated, as by log rotation.
+type followReader struct {
+	file   *os.File
+	offset int64
+	done   <-chan struct{}
+}
+
+// Read reads from the file, waiting at its end until data is appended or
+// following stops
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		r.offset += int64(n)
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+		if info, err := r.file.Stat(); err == nil && info.Size() < r.offset {
+			if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+				return 0, err
+			}
+			r.offset = 0
+			continue
+		}
+		select {
+		case <-r.done:
+			return 0, io.EOF
+		case <-time.After(followInterval):
+		}
+	}
+}
@@ -0,0 +1,631 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command jsonformat formats JSON documents using the jsonformat library.
+//
+// Usage:
+//
+//	jsonformat [flags] [file]        format a file, or standard input; NDJSON records are formatted one by one
+//	jsonformat -w|-d [flags] path... format files and directories in place, or print diffs
+//	jsonformat -logs [flags] [file]  format NDJSON log records, one line each
+//	jsonformat bench [flags] file    measure formatting performance per preset
+//	jsonformat config [-explain] [flags] print the effective options, or document them with -explain
+//	jsonformat daemon [-socket path] [flags] answer JSON-RPC format requests, one per line, on stdio or a unix socket
+//	jsonformat lint [flags] [file...] report warnings and lint rule findings
+//	jsonformat tune [flags] [file]   show what the flags change compared to a preset
+//	jsonformat test-style [flags] dir... check NAME.input.json files against NAME.expected.json
+//	jsonformat types [flags] [path...] report keys whose value types differ across documents
+//	jsonformat -version              print the version
+//	jsonformat -capabilities         print the supported modes, dialects, presets, and limits as JSON
+//
+// Run a command with -h to list its flags.
+//
+// Profiles of other packages, registered with jsonformat.RegisterProfile, are
+// compiled in by importing their packages in a file guarded by a build tag:
+//
+//	//go:build k8s
+//
+//	package main
+//
+//	import _ "example.com/jsonformat-k8s"
+//
+// A binary built with go build -tags k8s accepts the profiles with -preset,
+// and -preset auto uses the first profile that matches the input, if any.
+//
+// Exit codes are 0 when formatting succeeded or nothing changes, 1 when -d
+// finds files to reformat, lint finds problems, tune finds changes, types
+// finds keys with several types, or test-style finds mismatches, 2 for invalid JSON or files that cannot be
+// read or written, and 3 for invalid flags or arguments.
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// Exit codes are part of the command line interface, so scripts and hooks
+// can rely on them across releases
+const (
+	exitOK      = 0 // Formatted, or nothing to change
+	exitChanges = 1 // Files would be reformatted with -d, lint findings, tune changes, type conflicts, or corpus mismatches
+	exitError   = 2 // Invalid JSON, or a file that cannot be read or written
+	exitUsage   = 3 // Invalid flags or arguments
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "bench":
+			return runBench(args[1:], stdout, stderr)
+		case "config":
+			return runConfig(args[1:], stdout, stderr)
+		case "daemon":
+			return runDaemon(args[1:], stdin, stdout, stderr)
+		case "lint":
+			return runLint(args[1:], stdin, stdout, stderr)
+		case "tune":
+			return runTune(args[1:], stdin, stdout, stderr)
+		case "test-style":
+			return runTestStyle(args[1:], stdout, stderr)
+		case "types":
+			return runTypes(args[1:], stdin, stdout, stderr)
+		}
+	}
+	return runFormat(args, stdin, stdout, stderr)
+}
+
+// runFormat formats a single document and writes it to stdout
+func runFormat(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFlags := addConfigFlags(flags)
+	seq := flags.Bool("seq", false, "read and write RFC 7464 JSON text sequences (detected automatically when the input starts with RS)")
+	header := flags.Bool("header", false, "prefix the output with a metadata comment line (not for sequences; the output is no longer valid JSON)")
+	logs := flags.Bool("logs", false, "format newline-delimited JSON log records as one line each, with the log fields first")
+	logFields := flags.String("log-fields", "", "comma-separated log fields written first with -logs, each listing alternative names separated by |")
+	fields := flags.String("fields", "", "comma-separated dot paths of the fields kept with -logs, with * wildcards, e.g. ts,level,msg,http.status")
+	foldFields := flags.Bool("fold-fields", false, "with -fields, end each log line with a +N marker counting the fields left out")
+	var filter []jsonformat.LogPredicate
+	flags.Func("filter", "with -logs, write only records matching a predicate such as level>=warn, status!=200, or msg~timeout (repeatable)", func(s string) error {
+		predicate, err := jsonformat.ParseLogPredicate(s)
+		filter = append(filter, predicate)
+		return err
+	})
+	logSummary := flags.Bool("log-summary", false, "with -logs, end the output with counts by level and status and the most frequent error messages")
+	follow := flags.Bool("follow", false, "with -logs, keep reading the file as it grows, like tail -f")
+	color := flags.String("color", "auto", "color log lines by level with -logs: auto (when writing to a terminal and NO_COLOR is not set), always, or never")
+	levelColors := flags.String("level-colors", "", "comma-separated level=color pairs replacing the default level colors with -logs, e.g. info=green,debug=none")
+	explain := flags.Bool("explain-config", false, "print each effective setting and the layer (default, env, or flag) that set it, then exit")
+	version := flags.Bool("version", false, "print the version, then exit")
+	capabilities := flags.Bool("capabilities", false, "print the supported modes, dialects, presets, and limits as JSON, then exit")
+	stdinPath := flags.String("stdin-filepath", "", "name of the file that standard input comes from, used in errors and the header")
+	write := flags.Bool("w", false, "write the output to the given files instead of stdout; directories are searched for JSON files")
+	mode := &filesMode{walker: &jsonformat.Walker{}}
+	flags.BoolVar(&mode.dryRun, "d", false, "print diffs of the changes to the given files instead of the output; directories are searched for JSON files")
+	flags.BoolVar(&mode.walker.FollowSymlinks, "follow-symlinks", false, "follow symbolic links when searching directories")
+	flags.BoolVar(&mode.walker.NoGitignore, "no-gitignore", false, "include files excluded by .gitignore when searching directories")
+	flags.Int64Var(&mode.walker.MaxFileSize, "max-file-size", 0, "skip files larger than this many bytes when searching directories (0 disables)")
+	flags.IntVar(&mode.workers, "j", 0, "number of files formatted concurrently with -w or -d, or of NDJSON records (0 uses all CPUs)")
+	keepGoing := flags.Bool("keep-going", false, "read the input as NDJSON or concatenated documents, and write a comment line in place of records that cannot be formatted instead of stopping")
+	salvage := flags.Bool("salvage", false, "format the largest well-formed object or array in input with garbage around it, and report the bytes skipped")
+	repair := flags.Bool("repair", false, "complete a document cut off before its end, marking where it ended, and report what was added")
+	flags.StringVar(&mode.summaryFile, "summary", "", "write a JSON report of the files formatted with -w or -d to this file (- for stdout)")
+	flags.BoolVar(&mode.quiet, "quiet", false, "with -w or -d, print nothing but errors; the exit code tells the outcome")
+	flags.BoolVar(&mode.porcelain, "porcelain", false, "with -w or -d, print a stable status<TAB>path line per changed, failed, or skipped file instead of diffs")
+	flags.StringVar(&mode.encoding, "encoding", "", "with -w, write files in UTF-8, UTF-16LE, UTF-16BE, UTF-32LE, or UTF-32BE (default: the encoding of each file)")
+	flags.BoolVar(&mode.bom, "bom", false, "with -w, start UTF-8 files with a byte order mark")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	switch {
+	case *version:
+		fmt.Fprintf(stdout, "jsonformat %s\n", jsonformat.Capabilities().Version)
+		return exitOK
+	case *capabilities:
+		if err := writeCapabilities(stdout); err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	files := *write || mode.dryRun
+	if files && (*seq || *header) {
+		fmt.Fprintln(stderr, "jsonformat: -seq and -header cannot be used with -w or -d")
+		return exitUsage
+	}
+	if *logs && (files || *seq || *header) {
+		fmt.Fprintln(stderr, "jsonformat: -logs cannot be used with -w, -d, -seq, or -header")
+		return exitUsage
+	}
+	if *keepGoing && (files || *logs || *seq || *header) {
+		fmt.Fprintln(stderr, "jsonformat: -keep-going cannot be used with -w, -d, -logs, -seq, or -header")
+		return exitUsage
+	}
+	if *salvage && (files || *logs || *seq || *header || *keepGoing) {
+		fmt.Fprintln(stderr, "jsonformat: -salvage cannot be used with -w, -d, -logs, -seq, -header, or -keep-going")
+		return exitUsage
+	}
+	if *repair && (files || *logs || *seq || *header || *keepGoing || *salvage) {
+		fmt.Fprintln(stderr, "jsonformat: -repair cannot be used with -w, -d, -logs, -seq, -header, -keep-going, or -salvage")
+		return exitUsage
+	}
+	if (*logFields != "" || *levelColors != "" || *fields != "" || *foldFields || filter != nil || *follow || *logSummary) && !*logs {
+		fmt.Fprintln(stderr, "jsonformat: -log-fields, -level-colors, -fields, -fold-fields, -filter, -follow, and -log-summary need -logs")
+		return exitUsage
+	}
+	colors, err := jsonformat.ParseLogColors(*levelColors)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: -level-colors: %v\n", err)
+		return exitUsage
+	}
+	colorize, ok := useColor(*color, stdout)
+	if !ok {
+		fmt.Fprintf(stderr, "jsonformat: -color must be auto, always, or never, not %q\n", *color)
+		return exitUsage
+	}
+	if !files && (mode.quiet || mode.porcelain) {
+		fmt.Fprintln(stderr, "jsonformat: -quiet and -porcelain need -w or -d")
+		return exitUsage
+	}
+	if !*write && (mode.encoding != "" || mode.bom) {
+		fmt.Fprintln(stderr, "jsonformat: -encoding and -bom need -w")
+		return exitUsage
+	}
+	if !files && flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "jsonformat: at most one input file can be given")
+		return exitUsage
+	}
+	name := flags.Arg(0)
+	if name == "-" {
+		name = ""
+	}
+	if *stdinPath != "" {
+		if files || name != "" {
+			fmt.Fprintln(stderr, "jsonformat: -stdin-filepath needs input from standard input")
+			return exitUsage
+		}
+		name = *stdinPath
+	}
+	if *follow && (flags.Arg(0) == "" || flags.Arg(0) == "-") {
+		fmt.Fprintln(stderr, "jsonformat: -follow needs a file")
+		return exitUsage
+	}
+
+	// -preset auto reads the input first to pick a profile for it
+	var input []byte
+	var inputErr error
+	detect := func() string {
+		input, inputErr = readInput(flags.Arg(0), stdin)
+		if profile, ok := jsonformat.DetectProfile(jsonformat.NewDocumentInfo(name, input)); ok {
+			return profile.Name()
+		}
+		return "default"
+	}
+	if files || *logs {
+		detect = nil
+	}
+	config, settings, err := configFlags.config(detect)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitUsage
+	}
+	if inputErr != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", inputErr)
+		return exitError
+	}
+	if *explain {
+		explainConfig(stdout, settings)
+		return exitOK
+	}
+
+	if !files {
+		jsonformat.WithFilename(name)(config)
+	}
+	if *logFields != "" {
+		jsonformat.WithLogFields(strings.Split(*logFields, ",")...)(config)
+	}
+	if *fields != "" {
+		jsonformat.WithLogProjection(strings.Split(*fields, ",")...)(config)
+	}
+	if *foldFields {
+		jsonformat.WithLogFoldMarker()(config)
+	}
+	jsonformat.WithLogFilter(filter...)(config)
+	if *logSummary {
+		jsonformat.WithLogSummary()(config)
+	}
+	if *logs && colorize {
+		jsonformat.WithLogColors(colors)(config)
+	}
+	formatter := jsonformat.NewFormatter(config)
+	if files {
+		return mode.run(formatter, flags.Args(), stdout, stderr)
+	}
+	if *logs {
+		return formatLogs(formatter, flags.Arg(0), *follow, stdin, stdout, stderr)
+	}
+
+	if input == nil {
+		if input, err = readInput(flags.Arg(0), stdin); err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+	}
+
+	if *salvage {
+		formatted, skipped, err := formatter.FormatSalvaged(string(input))
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(stderr, "jsonformat: skipped %s\n", s)
+		}
+		fmt.Fprintln(stdout, formatted)
+		return exitOK
+	}
+
+	if *repair {
+		formatted, repaired, err := formatter.FormatRepaired(string(input))
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		if repaired != nil {
+			fmt.Fprintf(stderr, "jsonformat: %s\n", repaired)
+		}
+		fmt.Fprintln(stdout, formatted)
+		return exitOK
+	}
+
+	kind, _ := jsonformat.Sniff(input)
+	if *seq || kind == jsonformat.KindJSONSeq {
+		formatted, err := formatter.FormatSeq(string(input))
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+		// Records already end with a line feed
+		fmt.Fprint(stdout, formatted)
+		return exitOK
+	}
+
+	if kind == jsonformat.KindNDJSON || *keepGoing {
+		// Only records are formatted concurrently, and the formatter holds a
+		// snapshot of the configuration
+		jsonformat.WithRecordWorkers(cmp.Or(mode.workers, runtime.GOMAXPROCS(0)))(config)
+		if *keepGoing {
+			jsonformat.WithRecordErrorIsolation()(config)
+		}
+		return formatRecords(jsonformat.NewFormatter(config), input, stdout, stderr)
+	}
+
+	if *header {
+		// Only single documents get a header, and the formatter holds a
+		// snapshot of the configuration
+		jsonformat.WithHeaderComment(name)(config)
+		formatter = jsonformat.NewFormatter(config)
+	}
+	formatted, err := formatter.FormatBytes(input)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		if kind == jsonformat.KindJSON5 {
+			fmt.Fprintln(stderr, "jsonformat: the input looks like JSON5, which is not supported")
+		}
+		return exitError
+	}
+	fmt.Fprintln(stdout, string(formatted))
+	return exitOK
+}
+
+// formatRecords formats each value of newline-delimited JSON separately,
+// one after another
+func formatRecords(formatter *jsonformat.Formatter, input []byte, stdout, stderr io.Writer) int {
+	if err := formatter.FormatRecordsTo(stdout, bytes.NewReader(input)); err != nil {
+		// With -keep-going, the errors of all bad records are joined
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(stderr, "jsonformat: %s\n", line)
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// filesMode holds the settings for formatting files with -w or -d
+type filesMode struct {
+	walker      *jsonformat.Walker
+	dryRun      bool
+	workers     int
+	summaryFile string
+	quiet       bool
+	porcelain   bool
+	encoding    string
+	bom         bool
+}
+
+// run formats the files found in the paths in place, or prints the diffs
+// of the changes in dry-run mode, followed by a summary on stderr and
+// optionally a JSON report
+func (m *filesMode) run(formatter *jsonformat.Formatter, paths []string, stdout, stderr io.Writer) int {
+	if len(paths) == 0 {
+		fmt.Fprintln(stderr, "jsonformat: -w and -d need files or directories")
+		return exitUsage
+	}
+	options := []jsonformat.FileOption{jsonformat.WithWorkers(m.workers)}
+	if m.dryRun {
+		options = append(options, jsonformat.WithDryRun())
+	}
+	if m.encoding != "" {
+		options = append(options, jsonformat.WithOutputEncoding(m.encoding))
+	}
+	if m.bom {
+		options = append(options, jsonformat.WithByteOrderMark())
+	}
+
+	files, skipped := m.walker.Walk(paths...)
+	for _, s := range skipped {
+		switch {
+		case m.porcelain:
+			fmt.Fprintf(stdout, "skipped\t%s\n", s.Path)
+		case !m.quiet:
+			fmt.Fprintf(stderr, "jsonformat: skipping %s: %v\n", s.Path, s.Reason)
+		}
+	}
+	summary := formatter.FormatFiles(files, options...)
+	summary.Skipped = skipped
+	for _, result := range summary.Results {
+		if result.Err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %s: %v\n", result.Path, result.Err)
+			if m.porcelain {
+				fmt.Fprintf(stdout, "failed\t%s\n", result.Path)
+			}
+			continue
+		}
+		switch {
+		case m.porcelain:
+			if result.Changed {
+				fmt.Fprintf(stdout, "changed\t%s\n", result.Path)
+			}
+		case !m.quiet:
+			fmt.Fprint(stdout, result.Diff)
+		}
+	}
+	if !m.quiet && !m.porcelain {
+		fmt.Fprintf(stderr, "jsonformat: %d changed, %d unchanged, %d failed, %d skipped\n",
+			summary.Changed, summary.Unchanged, summary.Failed, len(skipped))
+	}
+	if m.summaryFile != "" {
+		if err := writeSummary(formatter, summary, m.summaryFile, stdout); err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			return exitError
+		}
+	}
+
+	switch {
+	case summary.Failed > 0:
+		return exitError
+	case m.dryRun && summary.Changed > 0:
+		return exitChanges
+	}
+	return exitOK
+}
+
+// writeSummary writes the JSON report of a batch run, formatted with the
+// configured formatter, to the named file or stdout
+func writeSummary(formatter *jsonformat.Formatter, summary *jsonformat.BatchSummary, filename string, stdout io.Writer) error {
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	// Reports beyond the formatter limits are written as encoded
+	report := string(encoded)
+	if formatted, err := formatter.Format(report); err == nil {
+		report = formatted
+	}
+	report += "\n"
+
+	if filename == "-" {
+		_, err := io.WriteString(stdout, report)
+		return err
+	}
+	return os.WriteFile(filename, []byte(report), 0o644)
+}
+
+// writeCapabilities writes the capabilities of the library as formatted JSON
+func writeCapabilities(stdout io.Writer) error {
+	encoded, err := json.Marshal(jsonformat.Capabilities())
+	if err != nil {
+		return err
+	}
+	formatted, err := jsonformat.NewFormatter(jsonformat.DefaultConfig()).Format(string(encoded))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, formatted+"\n")
+	return err
+}
+
+// readInput reads the named file, or stdin when the name is empty or "-"
+func readInput(filename string, stdin io.Reader) ([]byte, error) {
+	if filename == "" || filename == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(filename)
+}
+
+// envFlags names the environment variable holding configuration flags,
+// which take precedence over the preset and are overridden by the command line
+const envFlags = "JSONFORMAT_FLAGS"
+
+// getenv reads the environment, and is replaced in tests
+var getenv = os.Getenv
+
+// configFlags holds the flags that select the formatter configuration
+type configFlags struct {
+	preset        *string
+	indent        *int
+	tabs          *bool
+	compactDepth  *int
+	width         *int
+	maxTokens     *int
+	wideFold      *int
+	style         *string
+	specialFloats *string
+	keyOrder      *string
+	styleSheet    *string
+}
+
+// addConfigFlags registers the configuration flags on the flag set
+func addConfigFlags(flags *flag.FlagSet) *configFlags {
+	return &configFlags{
+		preset:        flags.String("preset", "", "base configuration preset or profile, or auto to detect a profile (default \"default\")"),
+		indent:        flags.Int("indent", -1, "number of spaces per indentation level (0-20)"),
+		tabs:          flags.Bool("tabs", false, "indent with tabs instead of spaces"),
+		compactDepth:  flags.Int("compact-depth", -1, "depth at which elements are formatted on one line (0 disables)"),
+		width:         flags.Int("width", -1, "maximum line width for compact elements (0 disables)"),
+		maxTokens:     flags.Int("max-tokens", -1, "maximum number of tokens in a document (0 disables, default 10000)"),
+		wideFold:      flags.Int("wide-fold", -1, "number of members written of objects with more, counting the rest (0 disables)"),
+		style:         flags.String("style", "", "layout style version: 1, 2, or latest (default 1)"),
+		specialFloats: flags.String("special-floats", "", "handling of NaN and Infinity: reject, null, or string (default reject)"),
+		keyOrder:      flags.String("key-order", "", "order of object members: input, scalars-first, or scalars-first-sorted (default input)"),
+		styleSheet:    flags.String("stylesheet", "", "JSON style sheet with settings and path rules, below the environment and flags in precedence"),
+	}
+}
+
+// config resolves the configuration from the preset, the style sheet, the
+// flags in the environment, and the explicitly given flags, in order of
+// precedence. A preset named in the style sheet replaces the default preset.
+// The detect function returns the preset used for "auto", and is nil when
+// there is no single document to detect a profile for.
+func (c *configFlags) config(detect func() string) (*jsonformat.Config, []jsonformat.ConfigSetting, error) {
+	envSet := flag.NewFlagSet(envFlags, flag.ContinueOnError)
+	envSet.SetOutput(io.Discard)
+	env := addConfigFlags(envSet)
+	if err := envSet.Parse(strings.Fields(getenv(envFlags))); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", envFlags, err)
+	}
+	if envSet.NArg() > 0 {
+		return nil, nil, fmt.Errorf("%s: unexpected argument %q", envFlags, envSet.Arg(0))
+	}
+	envOptions, err := env.options()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", envFlags, err)
+	}
+	flagOptions, err := c.options()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fileOptions []jsonformat.ConfigOption
+	preset := "default"
+	styleSheet := *env.styleSheet
+	if *c.styleSheet != "" {
+		styleSheet = *c.styleSheet
+	}
+	if styleSheet != "" {
+		var sheetPreset string
+		fileOptions, sheetPreset, err = jsonformat.LoadStyleSheet(styleSheet)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sheetPreset != "" {
+			preset = sheetPreset
+		}
+	}
+	for _, name := range []string{*env.preset, *c.preset} {
+		if name != "" {
+			preset = name
+		}
+	}
+	if preset == "auto" {
+		if detect == nil {
+			return nil, nil, fmt.Errorf("-preset auto needs a single input document")
+		}
+		preset = detect()
+	}
+	defaults, ok := jsonformat.Preset(preset)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown preset %q (available: %v)", preset, jsonformat.PresetNames())
+	}
+	return jsonformat.ResolveConfig(defaults, fileOptions, envOptions, flagOptions)
+}
+
+// options returns the configuration options for the explicitly given flags
+func (c *configFlags) options() ([]jsonformat.ConfigOption, error) {
+	var options []jsonformat.ConfigOption
+	if *c.indent >= 0 {
+		if *c.indent > 20 {
+			return nil, fmt.Errorf("-indent must be between 0 and 20")
+		}
+		options = append(options, jsonformat.WithIndentSize(*c.indent))
+	}
+	if *c.tabs {
+		options = append(options, jsonformat.WithTabs())
+	}
+	if *c.compactDepth >= 0 {
+		options = append(options, jsonformat.WithCompactDepth(*c.compactDepth))
+	}
+	if *c.width >= 0 {
+		options = append(options, jsonformat.WithMaxWidth(*c.width))
+	}
+	if *c.maxTokens >= 0 {
+		options = append(options, jsonformat.WithMaxTokens(*c.maxTokens))
+	}
+	if *c.wideFold >= 0 {
+		options = append(options, jsonformat.WithWideObjectFold(*c.wideFold))
+	}
+	if *c.style != "" {
+		version, err := jsonformat.ParseStyleVersion(*c.style)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, jsonformat.WithStyleVersion(version))
+	}
+	if *c.specialFloats != "" {
+		policy, err := jsonformat.ParseSpecialFloatPolicy(*c.specialFloats)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, jsonformat.WithSpecialFloats(policy))
+	}
+	if *c.keyOrder != "" {
+		policy, err := jsonformat.ParseKeyOrderPolicy(*c.keyOrder)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, jsonformat.WithKeyOrderPolicy(policy))
+	}
+	return options, nil
+}
+
+// explainConfig writes every effective setting with the layer that set it
+func explainConfig(w io.Writer, settings []jsonformat.ConfigSetting) {
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s = %v (%s)\n", s.Name, s.Value, s.Source)
+	}
+}
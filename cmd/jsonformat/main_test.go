@@ -0,0 +1,989 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// Registered once, as profiles of other packages register from init functions
+func init() {
+	jsonformat.RegisterProfile(jsonformat.NewProfile("test-k8s",
+		func(info jsonformat.DocumentInfo) bool { return info.HasKey("apiVersion") },
+		jsonformat.WithKeyOrder("", "apiVersion", "kind", "*"),
+		jsonformat.WithCompactDepth(1),
+	))
+}
+
+// writeTempFile writes content into a file in a temporary directory
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", filename, err)
+	}
+	return filename
+}
+
+func TestRunFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			name:     "stdin with defaults",
+			args:     nil,
+			stdin:    `{"a":[{"b":1}]}`,
+			expected: "{\n  \"a\": [\n    {\"b\": 1}\n  ]\n}\n",
+		},
+		{
+			name:     "policy flags",
+			args:     []string{"-special-floats", "null", "-key-order", "scalars-first"},
+			stdin:    `{"b":[1],"a":NaN}`,
+			expected: "{\n  \"a\": null,\n  \"b\": [\n    1\n  ]\n}\n",
+		},
+		{
+			name:     "preset and flag override",
+			args:     []string{"-preset", "expanded", "-indent", "4", "-"},
+			stdin:    `{"a":[1]}`,
+			expected: "{\n    \"a\": [\n        1\n    ]\n}\n",
+		},
+		{
+			name:     "wide object fold",
+			args:     []string{"-wide-fold", "1"},
+			stdin:    `{"a":1,"b":2,"c":3}`,
+			expected: "{\n  \"a\": 1,\n  … 2 more keys\n}\n",
+		},
+		{
+			name:     "tabs",
+			args:     []string{"-tabs"},
+			stdin:    `{"a":1}`,
+			expected: "{\n\t\"a\": 1\n}\n",
+		},
+		{
+			name:     "style version",
+			args:     []string{"-style", "2"},
+			stdin:    `{"a":[],"b":{}}`,
+			expected: "{\n  \"a\": [],\n  \"b\": {}\n}\n",
+		},
+		{
+			name:     "json-seq detected",
+			args:     nil,
+			stdin:    "\x1e{\"a\":1}\n\x1e[2]\n",
+			expected: "\x1e{\n  \"a\": 1\n}\n\x1e[\n  2\n]\n",
+		},
+		{
+			name:     "json-seq flag",
+			args:     []string{"-seq"},
+			stdin:    "{\"a\":1}",
+			expected: "\x1e{\n  \"a\": 1\n}\n",
+		},
+		{
+			name:     "NDJSON detected",
+			args:     nil,
+			stdin:    "{\"a\":1}\n{\"a\":2}\n",
+			expected: "{\n  \"a\": 1\n}\n{\n  \"a\": 2\n}\n",
+		},
+		{
+			name:     "registered profile",
+			args:     []string{"-preset", "test-k8s"},
+			stdin:    `{"metadata":{"name":"web"},"kind":"Pod"}`,
+			expected: "{\"kind\": \"Pod\", \"metadata\": {\"name\": \"web\"}}\n",
+		},
+		{
+			name:     "detected profile",
+			args:     []string{"-preset", "auto"},
+			stdin:    `{"metadata":{"name":"web"},"kind":"Pod","apiVersion":"v1"}`,
+			expected: "{\"apiVersion\": \"v1\", \"kind\": \"Pod\", \"metadata\": {\"name\": \"web\"}}\n",
+		},
+		{
+			name:     "no profile detected",
+			args:     []string{"-preset", "auto", "-compact-depth", "0"},
+			stdin:    `{"a":1}`,
+			expected: "{\n  \"a\": 1\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected:\n%q\n\nGot:\n%q", tt.expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunMaxTokens(t *testing.T) {
+	input := "[" + strings.Repeat(`{"id":1,"name":"x"},`, 2000) + "1]"
+
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, strings.NewReader(input), &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for too many tokens, got %d", exitError, code)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-max-tokens", "0"}, strings.NewReader(input), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "  1\n]\n") {
+		t.Errorf("Unexpected output ending %q", stdout.String()[stdout.Len()-20:])
+	}
+}
+
+func TestRunFormatFile(t *testing.T) {
+	filename := writeTempFile(t, "input.json", `[1,2]`)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{filename}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if stdout.String() != "[\n  1,\n  2\n]\n" {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+}
+
+func TestRunFormatHeader(t *testing.T) {
+	filename := writeTempFile(t, "input.json", `[1]`)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-header", filename}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !strings.HasPrefix(lines[0], "// jsonformat ") || !strings.HasSuffix(lines[0], " source="+filename) {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if strings.Join(lines[1:], "\n") != "[\n  1\n]\n" {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+}
+
+func TestRunFormatStdinFilepath(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-stdin-filepath", "config/app.json"}, strings.NewReader(`{"a":`), &stdout, &stderr)
+	if code != exitError {
+		t.Fatalf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.HasPrefix(stderr.String(), "jsonformat: config/app.json: malformed JSON") {
+		t.Errorf("Expected the error to name the file, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"-header", "-stdin-filepath", "config/app.json"}, strings.NewReader(`[1]`), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	if line, _, _ := strings.Cut(stdout.String(), "\n"); !strings.HasSuffix(line, " source=config/app.json") {
+		t.Errorf("Expected the header to name the file, got %q", line)
+	}
+
+	stdout.Reset()
+	code = run([]string{"lint", "-stdin-filepath", "config/app.json"}, strings.NewReader(`{"a":1,"a":2}`), &stdout, &stderr)
+	if code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d", exitChanges, code)
+	}
+	if expected := "config/app.json: a: duplicate key \"a\" [duplicate_key]\n"; stdout.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, stdout.String())
+	}
+}
+
+func TestRunLogs(t *testing.T) {
+	input := `{"time":"12:00:00","level":"INFO","msg":"started","port":8080}` + "\nplain text\n"
+	filename := writeTempFile(t, "app.log", input)
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{name: "stdin", args: []string{"-logs"}, stdin: input, expected: "12:00:00 INFO started {\"port\": 8080}\nplain text\n"},
+		{name: "file", args: []string{"-logs", filename}, expected: "12:00:00 INFO started {\"port\": 8080}\nplain text\n"},
+		{name: "fields", args: []string{"-logs", "-log-fields", "level,message|msg"}, stdin: input, expected: "INFO started {\"time\": \"12:00:00\", \"port\": 8080}\nplain text\n"},
+		{name: "no color for pipes", args: []string{"-logs", "-level-colors", "info=green"}, stdin: input, expected: "12:00:00 INFO started {\"port\": 8080}\nplain text\n"},
+		{name: "projection", args: []string{"-logs", "-fields", "level,msg", "-fold-fields"}, stdin: input, expected: "INFO started +2\nplain text\n"},
+		{name: "filter", args: []string{"-logs", "-filter", "level>=warn"}, stdin: input + `{"level":"error","msg":"failed"}` + "\n", expected: "- error failed\n"},
+		{name: "summary", args: []string{"-logs", "-log-summary"}, stdin: input, expected: "12:00:00 INFO started {\"port\": 8080}\nplain text\n--\nrecords: 1, other lines: 1\nlevel: INFO=1\n"},
+		{name: "colors", args: []string{"-logs", "-color", "always", "-level-colors", "info=green"}, stdin: input, expected: "\x1b[32m12:00:00 INFO started {\"port\": 8080}\x1b[0m\nplain text\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); code != exitOK {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunLogsFollow(t *testing.T) {
+	filename := writeTempFile(t, "app.log", `{"level":"info","msg":"started"}`+"\n")
+	done := make(chan struct{})
+	followDone = done
+	t.Cleanup(func() { followDone = nil })
+
+	output, stdout := io.Pipe()
+	code := make(chan int)
+	go func() {
+		var stderr bytes.Buffer
+		code <- run([]string{"-logs", "-follow", "-filter", "level!=debug", filename}, nil, stdout, &stderr)
+		stdout.Close()
+	}()
+	lines := bufio.NewReader(output)
+	expectLine := func(expected string) {
+		t.Helper()
+		line, err := lines.ReadString('\n')
+		if err != nil || line != expected {
+			t.Fatalf("Expected %q, got %q (%v)", expected, line, err)
+		}
+	}
+	appendLines := func(flag int, content string) {
+		t.Helper()
+		file, err := os.OpenFile(filename, flag|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", filename, err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(content); err != nil {
+			t.Fatalf("Failed to write %s: %v", filename, err)
+		}
+	}
+
+	expectLine("- info started\n")
+	appendLines(os.O_APPEND, `{"level":"debug","msg":"tick"}`+"\n"+`{"level":"warn","msg":"slow"}`+"\n")
+	expectLine("- warn slow\n")
+	// Rotation truncates the file, which is then read from the start
+	appendLines(os.O_TRUNC, `{"msg":"new"}`+"\n")
+	expectLine("- - new\n")
+
+	close(done)
+	if c := <-code; c != exitOK {
+		t.Errorf("Expected exit code %d, got %d", exitOK, c)
+	}
+}
+
+func TestRunFormatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+	}{
+		{name: "invalid JSON", stdin: `{"a":`, code: exitError},
+		{name: "JSON5", stdin: `{a: 1,}`, code: exitError},
+		{name: "invalid NDJSON record", stdin: "{\"a\":1}\n{\"a\":2}\n{", code: exitError},
+		{name: "missing file", args: []string{"does-not-exist.json"}, code: exitError},
+		{name: "unknown preset", args: []string{"-preset", "nope"}, stdin: `{}`, code: exitUsage},
+		{name: "auto preset with -w", args: []string{"-w", "-preset", "auto", "a.json"}, code: exitUsage},
+		{name: "auto preset missing file", args: []string{"-preset", "auto", "does-not-exist.json"}, code: exitError},
+		{name: "unknown style", args: []string{"-style", "v9"}, stdin: `{}`, code: exitUsage},
+		{name: "unknown special floats policy", args: []string{"-special-floats", "nan"}, stdin: `{}`, code: exitUsage},
+		{name: "unknown flag", args: []string{"-nope"}, code: exitUsage},
+		{name: "too many files", args: []string{"a.json", "b.json"}, code: exitUsage},
+		{name: "quiet without files", args: []string{"-quiet"}, stdin: `{}`, code: exitUsage},
+		{name: "porcelain without files", args: []string{"-porcelain"}, stdin: `{}`, code: exitUsage},
+		{name: "encoding with -d", args: []string{"-d", "-encoding", "UTF-16LE", "a.json"}, code: exitUsage},
+		{name: "bom without files", args: []string{"-bom"}, stdin: `{}`, code: exitUsage},
+		{name: "logs with -seq", args: []string{"-logs", "-seq"}, code: exitUsage},
+		{name: "keep going with logs", args: []string{"-logs", "-keep-going"}, code: exitUsage},
+		{name: "log fields without logs", args: []string{"-log-fields", "level"}, stdin: `{}`, code: exitUsage},
+		{name: "unknown color mode", args: []string{"-logs", "-color", "sometimes"}, code: exitUsage},
+		{name: "unknown level color", args: []string{"-logs", "-level-colors", "info=pink"}, code: exitUsage},
+		{name: "level colors without logs", args: []string{"-level-colors", "info=green"}, stdin: `{}`, code: exitUsage},
+		{name: "invalid filter", args: []string{"-logs", "-filter", "msg~("}, code: exitUsage},
+		{name: "follow stdin", args: []string{"-logs", "-follow"}, code: exitUsage},
+		{name: "logs missing file", args: []string{"-logs", "does-not-exist.log"}, code: exitError},
+		{name: "stdin filepath with file", args: []string{"-stdin-filepath", "a.json", "b.json"}, code: exitUsage},
+		{name: "stdin filepath with -w", args: []string{"-w", "-stdin-filepath", "a.json", "b.json"}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d", tt.code, code)
+			}
+			if stderr.Len() == 0 {
+				t.Error("Expected a diagnostic on stderr")
+			}
+		})
+	}
+}
+
+func TestRunFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json":        `{"a":1}`,
+		"sub/b.json":    "[\n  1\n]\n",
+		"sub/big.json":  `{"padding":"` + strings.Repeat("x", 100) + `"}`,
+		"ignored.json":  `{"a":1}`,
+		".gitignore":    "ignored.json\n",
+		"sub/notes.txt": "not JSON",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	a := filepath.Join(dir, "a.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-d", "-max-file-size", "64", dir}, nil, &stdout, &stderr); code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitChanges, code, stderr.String())
+	}
+	expectedDiff := "--- " + a + "\n+++ " + a + "\n@@ -1 +1,3 @@\n-{\"a\":1}\n\\ No newline at end of file\n+{\n+  \"a\": 1\n+}\n"
+	if stdout.String() != expectedDiff {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expectedDiff, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "skipping "+filepath.Join(dir, "sub", "big.json")+": file too large") {
+		t.Errorf("Expected a warning for the large file, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"-w", dir}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	for name, expected := range map[string]string{
+		"a.json":       "{\n  \"a\": 1\n}\n",
+		"ignored.json": `{"a":1}`,
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("Expected %s:\n%s\n\nGot:\n%s", name, expected, content)
+		}
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected no output, got %q", stdout.String())
+	}
+	if summary := "jsonformat: 2 changed, 1 unchanged, 0 failed, 0 skipped\n"; !strings.HasSuffix(stderr.String(), summary) {
+		t.Errorf("Expected the summary %q, got %q", summary, stderr.String())
+	}
+}
+
+func TestRunFormatFilesEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.json")
+	if err := os.WriteFile(path, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-w", "-bom", path}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	expected := "\xEF\xBB\xBF{\n  \"a\": 1\n}\n"
+	if content, _ := os.ReadFile(path); string(content) != expected {
+		t.Errorf("Expected:\n%q\n\nGot:\n%q", expected, content)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"-w", "-encoding", "latin1", path}, nil, &stdout, &stderr); code != exitError {
+		t.Fatalf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), `unknown output encoding "latin1"`) {
+		t.Errorf("Expected an unknown encoding error, got %q", stderr.String())
+	}
+}
+
+func TestRunFormatFilesSummary(t *testing.T) {
+	formatted := writeTempFile(t, "formatted.json", "[\n  1\n]\n")
+	unformatted := writeTempFile(t, "unformatted.json", `[1]`)
+	broken := writeTempFile(t, "broken.json", `[`)
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-w", "-summary", summaryFile, formatted, unformatted, broken}, nil, &stdout, &stderr)
+	if code != exitError {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitError, code, stderr.String())
+	}
+
+	content, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("Failed to read the summary: %v", err)
+	}
+	var report struct {
+		Changed, Unchanged, Failed int
+		Files                      []struct {
+			Path    string
+			Status  string
+			Applied bool
+			Error   *struct{ Code string }
+		}
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatalf("Expected a JSON report, got %v:\n%s", err, content)
+	}
+	if !strings.Contains(string(content), "\n  \"files\": [\n") {
+		t.Errorf("Expected a formatted report, got:\n%s", content)
+	}
+	if report.Changed != 1 || report.Unchanged != 1 || report.Failed != 1 || len(report.Files) != 3 {
+		t.Fatalf("Unexpected report:\n%s", content)
+	}
+	if f := report.Files[1]; f.Path != unformatted || f.Status != "changed" || !f.Applied {
+		t.Errorf("Unexpected report for %s: %+v", unformatted, f)
+	}
+	if f := report.Files[2]; f.Status != "failed" || f.Error == nil || f.Error.Code != "syntax" {
+		t.Errorf("Unexpected report for %s: %+v", broken, f)
+	}
+}
+
+func TestRunFormatFilesQuietAndPorcelain(t *testing.T) {
+	formatted := writeTempFile(t, "formatted.json", "[\n  1\n]\n")
+	unformatted := writeTempFile(t, "unformatted.json", `[1]`)
+	broken := writeTempFile(t, "broken.json", `[`)
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name: "quiet with changes",
+			args: []string{"-d", "-quiet", formatted, unformatted},
+			code: exitChanges,
+		},
+		{
+			name: "quiet without changes",
+			args: []string{"-d", "-quiet", formatted},
+			code: exitOK,
+		},
+		{
+			name:   "porcelain",
+			args:   []string{"-d", "-porcelain", formatted, unformatted, broken},
+			code:   exitError,
+			stdout: "changed\t" + unformatted + "\nfailed\t" + broken + "\n",
+			stderr: "jsonformat: " + broken + ": malformed JSON: unclosed objects or arrays\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.stdout, stdout.String())
+			}
+			if stderr.String() != tt.stderr {
+				t.Errorf("Expected stderr %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunFormatFilesErrors(t *testing.T) {
+	broken := writeTempFile(t, "broken.json", `{"a":`)
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no paths", args: []string{"-w"}, code: exitUsage},
+		{name: "with header", args: []string{"-w", "-header", broken}, code: exitUsage},
+		{name: "invalid JSON", args: []string{"-d", broken}, code: exitError},
+		{name: "quiet", args: []string{"-d", "-quiet", broken}, code: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.code, code, stderr.String())
+			}
+			if stderr.Len() == 0 {
+				t.Error("Expected a diagnostic on stderr")
+			}
+		})
+	}
+}
+
+// setEnv replaces the environment seen by run for the duration of the test
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	saved := getenv
+	getenv = func(key string) string { return env[key] }
+	t.Cleanup(func() { getenv = saved })
+}
+
+func TestRunFormatEnvFlags(t *testing.T) {
+	setEnv(t, map[string]string{envFlags: "-preset expanded -indent 4"})
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-indent", "1"}, strings.NewReader(`{"a":[1]}`), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	expected := "{\n \"a\": [\n  1\n ]\n}\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%q\n\nGot:\n%q", expected, stdout.String())
+	}
+}
+
+func TestRunExplainConfig(t *testing.T) {
+	setEnv(t, map[string]string{envFlags: "-tabs -width 100"})
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-explain-config", "-preset", "compact", "-width", "80"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	for _, line := range []string{
+		"IndentSize = 2 (default)\n",
+		"UseTab = true (env)\n",
+		"MaxWidth = 80 (flag)\n",
+	} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, stdout.String())
+		}
+	}
+}
+
+func TestRunCapabilities(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-capabilities"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	var caps jsonformat.CapabilityInfo
+	if err := json.Unmarshal(stdout.Bytes(), &caps); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, stdout.String())
+	}
+	if !slices.Contains(caps.Presets, "compact") || caps.Limits.MaxDepth != 100 {
+		t.Errorf("Unexpected capabilities:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-version"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if stdout.String() != "jsonformat devel\n" {
+		t.Errorf("Expected the version line, got %q", stdout.String())
+	}
+}
+
+func TestRunStyleSheet(t *testing.T) {
+	sheet := filepath.Join(t.TempDir(), "style.json")
+	if err := os.WriteFile(sheet, []byte(`{"preset": "expanded", "indent": 4, "rules": [{"path": "b", "compact": true}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-stylesheet", sheet, "-indent", "1", "-explain-config"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	for _, line := range []string{
+		"IndentSize = 1 (flag)\n",
+		"CompactPaths = [b] (file)\n",
+	} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-stylesheet", sheet}, strings.NewReader(`{"a":[1],"b":[2]}`), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	expected := "{\n    \"a\": [\n        1\n    ],\n    \"b\": [2]\n}\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%q\n\nGot:\n%q", expected, stdout.String())
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"rules": [{"path": "a"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := run([]string{"-stylesheet", broken}, strings.NewReader(`{}`), &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d, got %d", exitUsage, code)
+	}
+	if !strings.Contains(stderr.String(), broken) {
+		t.Errorf("Expected a diagnostic naming %s, got %q", broken, stderr.String())
+	}
+}
+
+func TestRunEnvFlagsErrors(t *testing.T) {
+	for _, env := range []string{"-nope", "-indent 30", "file.json"} {
+		t.Run(env, func(t *testing.T) {
+			setEnv(t, map[string]string{envFlags: env})
+			var stdout, stderr bytes.Buffer
+			if code := run(nil, strings.NewReader(`{}`), &stdout, &stderr); code != exitUsage {
+				t.Errorf("Expected exit code %d, got %d", exitUsage, code)
+			}
+			if !strings.Contains(stderr.String(), envFlags) {
+				t.Errorf("Expected a diagnostic naming %s, got %q", envFlags, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	filename := writeTempFile(t, "input.json", `{"users":[{"id":1,"name":"Alice"}]}`)
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"bench", "-benchtime", "10ms", "-presets", "default,expanded", filename}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	output := stdout.String()
+	for _, expected := range []string{"ns/op", "MB/s", "allocs/op", "output bytes", "default", "expanded"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "tabs") {
+		t.Errorf("Expected only the selected presets, got:\n%s", output)
+	}
+}
+
+func TestRunBenchErrors(t *testing.T) {
+	filename := writeTempFile(t, "input.json", `{"a":`)
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no file", args: []string{"bench"}, code: exitUsage},
+		{name: "unknown preset", args: []string{"bench", "-presets", "nope", filename}, code: exitUsage},
+		{name: "invalid JSON", args: []string{"bench", "-benchtime", "1ms", filename}, code: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, nil, &stdout, &stderr); code != tt.code {
+				t.Errorf("Expected exit code %d, got %d (stderr: %s)", tt.code, code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	clean := writeTempFile(t, "clean.json", `{"userName":"alice"}`)
+	dirty := writeTempFile(t, "dirty.json", `{"user_name":"alice","password":"x","a":1,"a":2}`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"lint", "-key-pattern", "^[a-z][a-zA-Z0-9]*$", "-forbid", "password", "-max-key-length", "8", clean, dirty}, nil, &stdout, &stderr)
+	if code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitChanges, code, stderr.String())
+	}
+
+	expected := dirty + `: user_name: key "user_name" is 9 characters long (max 8) [key_too_long]
+` + dirty + `: user_name: key "user_name" does not match ^[a-z][a-zA-Z0-9]*$ [key_pattern]
+` + dirty + `: password: key "password" is forbidden [forbidden_key]
+` + dirty + `: a: duplicate key "a" [duplicate_key]
+`
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"lint", "-max-key-length", "8"}, strings.NewReader(`{"userName":1}`), &stdout, &stderr); code != 0 {
+		t.Errorf("Expected exit code 0 for clean input, got %d (output: %s)", code, stdout.String())
+	}
+	if code := run([]string{"lint", "-key-pattern", "("}, strings.NewReader(`{}`), &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d for invalid pattern, got %d", exitUsage, code)
+	}
+	if code := run([]string{"lint"}, strings.NewReader(`{"a":`), &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for invalid JSON, got %d", exitError, code)
+	}
+}
+
+func TestRunTypes(t *testing.T) {
+	a := writeTempFile(t, "a.json", `{"id":1,"name":"a"}`)
+	b := writeTempFile(t, "b.json", `{"id":"2","name":null}`)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"types", a, b}, nil, &stdout, &stderr)
+	if code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitChanges, code, stderr.String())
+	}
+	expected := "PATH  TYPE    VALUES  DOCUMENTS  FIRST SEEN IN\n" +
+		"id    number  1       1          " + a + "\n" +
+		"      string  1       1          " + b + "\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"types"}, strings.NewReader(`{"id":1}`), &stdout, &stderr); code != exitOK || stdout.Len() != 0 {
+		t.Errorf("Expected exit code 0 and no output, got %d (output: %s)", code, stdout.String())
+	}
+	if code := run([]string{"types"}, strings.NewReader(`{"a":`), &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for invalid JSON, got %d", exitError, code)
+	}
+}
+
+func TestRunTune(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tune", "-compact-depth", "2"}, strings.NewReader(`{"a":[1,2],"b":1}`), &stdout, &stderr)
+	if code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitChanges, code, stderr.String())
+	}
+	expected := `a: 4 lines → 1 line
+
+--- before
++++ after
+@@ -1,7 +1,4 @@
+ {
+-  "a": [
++  "a": [1, 2],
+-    1,
+-    2
+-  ],
+   "b": 1
+ }
+`
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"tune", "-from", "compact", "-preset", "compact"}, strings.NewReader(`{"a":[1]}`), &stdout, &stderr); code != exitOK {
+		t.Errorf("Expected exit code 0 without changes, got %d", code)
+	}
+	if stdout.String() != "no changes\n" {
+		t.Errorf("Expected no changes, got %q", stdout.String())
+	}
+
+	for _, args := range [][]string{{"tune", "-from", "nope"}, {"tune", "-preset", "nope"}, {"tune", "a.json", "b.json"}} {
+		if code := run(args, strings.NewReader(`{}`), &stdout, &stderr); code != exitUsage {
+			t.Errorf("%v: expected exit code %d, got %d", args, exitUsage, code)
+		}
+	}
+	if code := run([]string{"tune"}, strings.NewReader(`{"a":`), &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for invalid JSON, got %d", exitError, code)
+	}
+}
+
+func TestRunTestStyle(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.input.json":    `{"a":[1,2]}`,
+		"a.expected.json": "{\n  \"a\": [1, 2]\n}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"test-style", "-compact-depth", "2", dir}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stdout: %s, stderr: %s)", code, stdout.String(), stderr.String())
+	}
+	if stdout.String() != dir+": 1 passed, 0 failed\n" {
+		t.Errorf("Unexpected report: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"test-style", dir}, nil, &stdout, &stderr); code != exitChanges {
+		t.Fatalf("Expected exit code %d, got %d", exitChanges, code)
+	}
+	for _, line := range []string{"FAIL " + filepath.Join(dir, "a.input.json") + "\n", "  a: 1 line → 4 lines\n", "-  \"a\": [1, 2]\n", dir + ": 0 passed, 1 failed\n"} {
+		if !strings.Contains(stdout.String(), line) {
+			t.Errorf("Expected %q in report:\n%s", line, stdout.String())
+		}
+	}
+
+	if code := run([]string{"test-style"}, nil, &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d without a directory, got %d", exitUsage, code)
+	}
+	if code := run([]string{"test-style", filepath.Join(dir, "nope")}, nil, &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for a missing directory, got %d", exitError, code)
+	}
+}
+
+func TestRunFormatKeepGoing(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-keep-going", "-j", "2"}, strings.NewReader("{\"a\":1}\n{\"a\":\n[2]\n"), &stdout, &stderr); code != exitError {
+		t.Fatalf("Expected exit code %d, got %d", exitError, code)
+	}
+	expected := "{\n  \"a\": 1\n}\n// line 2, column 6: invalid JSON text in record 2: malformed JSON: unclosed objects or arrays\n// > {\"a\":\n[\n  2\n]\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "jsonformat: invalid JSON text in record 2") {
+		t.Errorf("Expected the error of record 2, got: %s", stderr.String())
+	}
+}
+
+func TestRunFormatSalvage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-salvage"}, strings.NewReader("\x00\x01log: {\"a\":[1]}\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if stdout.String() != "{\n  \"a\": [\n    1\n  ]\n}\n" {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+	if stderr.String() != "jsonformat: skipped 6 bytes at offset 0: \"\\x00\\x01log:\"\n" {
+		t.Errorf("Unexpected report: %q", stderr.String())
+	}
+
+	if code := run([]string{"-salvage"}, strings.NewReader("\x00\x01"), &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d without a value, got %d", exitError, code)
+	}
+	if code := run([]string{"-salvage", "-logs"}, strings.NewReader("{}"), &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d with -logs, got %d", exitUsage, code)
+	}
+}
+
+func TestRunFormatRepair(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-repair"}, strings.NewReader(`{"a":[1,"tex`), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if stdout.String() != "{\n  \"a\": [\n    1,\n    \"tex…\",\n    …\n  ]\n}\n" {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+	if stderr.String() != "jsonformat: input cut off at offset 12: closed a string, closed \"]}\"\n" {
+		t.Errorf("Unexpected report: %q", stderr.String())
+	}
+
+	if code := run([]string{"-repair", "-salvage"}, strings.NewReader("{}"), &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d with -salvage, got %d", exitUsage, code)
+	}
+}
+
+func TestRunFormatJSON5Hint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, strings.NewReader(`{a: 1, // count
+}`), &stdout, &stderr); code != exitError {
+		t.Fatalf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "the input looks like JSON5") {
+		t.Errorf("Expected a JSON5 hint, got: %s", stderr.String())
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"config", "-indent", "4"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "{\n  \"IndentSize\": 4,\n  \"UseTab\": false,\n") {
+		t.Errorf("Unexpected values:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"config", "--explain", "-tabs"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	var docs []jsonformat.OptionDoc
+	if err := json.Unmarshal(stdout.Bytes(), &docs); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, stdout.String())
+	}
+	if len(docs) < 2 || docs[1].Name != "UseTab" || docs[1].Value != true || docs[1].Source != "flag" || docs[1].Description == "" {
+		t.Errorf("Unexpected documentation: %+v", docs[:2])
+	}
+
+	if code := run([]string{"config", "extra"}, nil, &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d with an argument, got %d", exitUsage, code)
+	}
+}
+
+func TestRunDaemon(t *testing.T) {
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"format","params":{"input":"{\"a\":[1,2]}"}}`,
+		`{"jsonrpc":"2.0","method":"format","params":{"input":"[]"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"format","params":{"input":"{\"a\":[1,2]}","options":{"indent":4,"compactDepth":2}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"format","params":{"input":"{"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"format","params":{"input":"[]","options":{"indent":"x"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"reformat"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"capabilities"}`,
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"daemon", "-tabs"}, strings.NewReader(requests), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+	expected := `{"jsonrpc":"2.0","id":1,"result":{"output":"{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}"}}
+{"jsonrpc":"2.0","id":2,"result":{"output":"{\n\t\"a\": [1, 2]\n}"}}
+{"jsonrpc":"2.0","id":3,"error":{"code":-32000,"message":"malformed JSON: unclosed objects or arrays","data":{"code":"syntax"}}}
+`
+	if !strings.HasPrefix(stdout.String(), expected) {
+		t.Fatalf("Unexpected responses:\n%s", stdout.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected 7 responses up to shutdown, got %d:\n%s", len(lines), stdout.String())
+	}
+	for i, code := range []string{`"code":-32602`, `"code":-32601`, `"id":null,"error":{"code":-32700`} {
+		if !strings.Contains(lines[3+i], code) {
+			t.Errorf("Expected %s in %s", code, lines[3+i])
+		}
+	}
+	if lines[6] != `{"jsonrpc":"2.0","id":6,"result":null}` {
+		t.Errorf("Unexpected shutdown response: %s", lines[6])
+	}
+}
+
+func TestRunDaemonSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "d.sock")
+	var stderr bytes.Buffer
+	done := make(chan int)
+	go func() { done <- run([]string{"daemon", "-socket", socket}, nil, io.Discard, &stderr) }()
+
+	var conn net.Conn
+	var err error
+	for range 100 {
+		if conn, err = net.Dial("unix", socket); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for _, exchange := range []struct{ request, response string }{
+		{`{"jsonrpc":"2.0","id":1,"method":"format","params":{"input":"[1]"}}`, `{"jsonrpc":"2.0","id":1,"result":{"output":"[\n  1\n]"}}`},
+		{`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`, `{"jsonrpc":"2.0","id":2,"result":null}`},
+	} {
+		fmt.Fprintln(conn, exchange.request)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(response) != exchange.response {
+			t.Errorf("Expected %s, got %s", exchange.response, response)
+		}
+	}
+	select {
+	case code := <-done:
+		if code != exitOK {
+			t.Errorf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Daemon did not stop after shutdown")
+	}
+}
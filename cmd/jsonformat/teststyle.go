@@ -0,0 +1,79 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// runTestStyle formats the inputs of test corpus directories with the
+// configuration and compares them with their expected outputs, returning
+// exitChanges when any output differs and exitError when a directory cannot
+// be read
+func runTestStyle(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat test-style", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFlags := addConfigFlags(flags)
+	update := flags.Bool("update", false, "write the formatted output as NAME.expected.json for failing and new cases instead of reporting them")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintf(stderr, "jsonformat: test-style needs a corpus directory\n")
+		return exitUsage
+	}
+	config, _, err := configFlags.config(nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitUsage
+	}
+	formatter := jsonformat.NewFormatter(config)
+
+	mismatches, failed := false, false
+	for _, dir := range flags.Args() {
+		report, err := formatter.CheckCorpus(dir, *update)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			failed = true
+			continue
+		}
+		for _, mismatch := range report.Mismatches {
+			name := filepath.Join(dir, mismatch.Name)
+			if mismatch.Err != nil {
+				fmt.Fprintf(stdout, "FAIL %s: %v\n", name, mismatch.Err)
+				continue
+			}
+			fmt.Fprintf(stdout, "FAIL %s\n", name)
+			for _, change := range mismatch.Changes {
+				fmt.Fprintf(stdout, "  %s\n", change)
+			}
+			fmt.Fprint(stdout, mismatch.Diff)
+		}
+		fmt.Fprintf(stdout, "%s: %d passed, %d failed\n", dir, report.Cases-len(report.Mismatches), len(report.Mismatches))
+		mismatches = mismatches || !report.Passed()
+	}
+	switch {
+	case failed:
+		return exitError
+	case mismatches:
+		return exitChanges
+	}
+	return exitOK
+}
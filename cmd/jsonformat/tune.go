@@ -0,0 +1,74 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// runTune shows what the configuration flags change in the layout of a
+// document compared to a preset, returning exitChanges when the layout
+// differs and exitError when the document cannot be read or parsed
+func runTune(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat tune", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFlags := addConfigFlags(flags)
+	from := flags.String("from", "default", "preset or profile the configuration is compared with")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(stderr, "jsonformat: tune accepts a single file\n")
+		return exitUsage
+	}
+
+	before, ok := jsonformat.Preset(*from)
+	if !ok {
+		fmt.Fprintf(stderr, "jsonformat: unknown preset %q (available: %v)\n", *from, jsonformat.PresetNames())
+		return exitUsage
+	}
+	after, _, err := configFlags.config(nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitUsage
+	}
+
+	input, err := readInput(flags.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+	preview, err := jsonformat.Preview(string(input), before, after)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+		return exitError
+	}
+	if preview.Diff == "" {
+		fmt.Fprintln(stdout, "no changes")
+		return exitOK
+	}
+	for _, change := range preview.Changes {
+		fmt.Fprintln(stdout, change)
+	}
+	if len(preview.Changes) > 0 {
+		fmt.Fprintln(stdout)
+	}
+	fmt.Fprint(stdout, preview.Diff)
+	return exitChanges
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// runTypes reports the keys whose values have different types across the
+// documents, returning exitChanges when there are any and exitError when any
+// document cannot be read or parsed
+func runTypes(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonformat types", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stdinPath := flags.String("stdin-filepath", "-", "name of the file that standard input comes from, used in the report")
+	walker := &jsonformat.Walker{}
+	flags.BoolVar(&walker.NoGitignore, "no-gitignore", false, "include files excluded by .gitignore when searching directories")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	filenames := []string{"-"}
+	if flags.NArg() > 0 {
+		var skipped []jsonformat.SkippedFile
+		filenames, skipped = walker.Walk(flags.Args()...)
+		for _, s := range skipped {
+			fmt.Fprintf(stderr, "jsonformat: skipping %s: %v\n", s.Path, s.Reason)
+		}
+	}
+
+	survey := jsonformat.NewTypeSurvey()
+	failed := false
+	for _, filename := range filenames {
+		input, err := readInput(filename, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			failed = true
+			continue
+		}
+		if filename == "-" {
+			filename = *stdinPath
+		}
+		if err := survey.Add(filename, string(input)); err != nil {
+			fmt.Fprintf(stderr, "jsonformat: %v\n", err)
+			failed = true
+		}
+	}
+
+	table := survey.Table()
+	fmt.Fprint(stdout, table)
+	switch {
+	case failed:
+		return exitError
+	case table != "":
+		return exitChanges
+	}
+	return exitOK
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+)
+
+// WithAdaptiveCompaction writes objects in an array on one line only when
+// the array holds records: objects that all share the same key set. Objects in
+// arrays with differing keys or mixed content are expanded, even beyond the
+// compact depth. Other containers follow the compact depth as usual.
+//
+// Arrays that are not written on one line are held in memory until they end,
+// so that their elements can be compared before the first one is written.
+//
+// Example:
+//
+//	config := NewConfig(WithAdaptiveCompaction())
+//	// {
+//	//   "users": [
+//	//     {"id": 1, "name": "Alice"},
+//	//     {"id": 2, "name": "Bob"}
+//	//   ],
+//	//   "events": [
+//	//     {
+//	//       "type": "login"
+//	//     },
+//	//     {
+//	//       "type": "error",
+//	//       "code": 500
+//	//     }
+//	//   ]
+//	// }
+func WithAdaptiveCompaction() ConfigOption {
+	return func(c *Config) {
+		c.AdaptiveCompaction = true
+	}
+}
+
+// WithCompactKeys writes the containers held by the given keys with one
+// element or member per line, and each element or member value on one line,
+// regardless of the compact depth and the width limit. Lists of name/value
+// pairs, such as the Tags of cloud resources, then read as one row per pair.
+// Containers inside a line that is already compact stay on that line.
+//
+// Example:
+//
+//	config := NewConfig(WithCompactKeys("Tags"))
+//	// {
+//	//   "Tags": [
+//	//     {"Key": "Name", "Value": "web"},
+//	//     {"Key": "env", "Value": "prod"}
+//	//   ]
+//	// }
+func WithCompactKeys(keys ...string) ConfigOption {
+	return func(c *Config) {
+		c.CompactKeys = append(c.CompactKeys, keys...)
+	}
+}
+
+// WithCompactPaths writes the containers at paths matching the patterns on
+// one line, regardless of the compact depth. A container that exceeds the
+// width limit is still expanded. Patterns are dot-separated paths as in
+// ValueContext.MatchPath, with "" for the root.
+//
+// Example:
+//
+//	config := NewConfig(WithCompactDepth(0), WithCompactPaths("spec.ports.*"))
+//	// "ports": [
+//	//   {"name": "http", "port": 80}
+//	// ]
+func WithCompactPaths(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.CompactPaths = append(c.CompactPaths, patterns...)
+	}
+}
+
+// WithExpandedPaths writes the contents of the containers at paths matching
+// the patterns one element or member per line, regardless of the compact
+// depth. Containers inside a line that is already compact stay on that line.
+//
+// Example:
+//
+//	config := NewConfig(WithCompactDepth(2), WithExpandedPaths("metadata"))
+//	// "metadata": {
+//	//   "name": "web",
+//	//   "labels": {"app": "shop"}
+//	// },
+//	// "spec": {"replicas": 2}
+func WithExpandedPaths(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.ExpandedPaths = append(c.ExpandedPaths, patterns...)
+	}
+}
+
+// pathLayout reports whether a container opened at the current position is
+// compact or expanded by its path, and whether a pattern matched at all
+func (p *TokenParser) pathLayout() (compact bool, matched bool) {
+	if len(p.config.CompactPaths) == 0 && len(p.config.ExpandedPaths) == 0 {
+		return false, false
+	}
+	path := p.valuePath()
+	if len(path) != p.depth {
+		return false, false
+	}
+	rules := p.pathRules()
+	if anyRootedMatch(rules.expanded, path) {
+		return false, true
+	}
+	// An element that overflowed is replayed with compact formatting pushed deeper
+	if p.depth+1 < p.minCompactDepth {
+		return false, false
+	}
+	if anyRootedMatch(rules.compact, path) {
+		return true, true
+	}
+	return false, false
+}
+
+// opensRows reports whether a container opened at the current position is
+// held by a compact key
+func (p *TokenParser) opensRows() bool {
+	n := len(p.path)
+	return n >= 1 && n == len(p.inArray) && !p.inArray[n-1] && p.isCompactKey(p.path[n-1].key)
+}
+
+// opensRow reports whether a container opened at the current position is an
+// element or member value of a container held by a compact key
+func (p *TokenParser) opensRow() bool {
+	n := len(p.path)
+	return n >= 2 && n == len(p.inArray) && !p.inArray[n-2] && p.isCompactKey(p.path[n-2].key)
+}
+
+// isCompactKey reports whether the key is one of the compact keys
+func (p *TokenParser) isCompactKey(key string) bool {
+	for _, compactKey := range p.config.CompactKeys {
+		if key == compactKey {
+			return true
+		}
+	}
+	return false
+}
+
+// recordsState buffers an array until it is known whether its objects share a key set
+type recordsState struct {
+	tokens []json.Token // Tokens of the array, including delimiters
+	depth  int          // Nesting depth within the array
+}
+
+// startRecords begins buffering the array if the token opens an array whose
+// elements are not already written on one line
+func (p *TokenParser) startRecords(token json.Token) bool {
+	if p.skipRecords {
+		p.skipRecords = false
+		return false
+	}
+	if !p.config.AdaptiveCompaction || token != json.Delim('[') || p.shouldFormatCompact() {
+		return false
+	}
+	p.records = &recordsState{tokens: []json.Token{token}, depth: 1}
+	return true
+}
+
+// recordToken buffers an array token and replays the array once it is complete
+func (p *TokenParser) recordToken(token json.Token) error {
+	records := p.records
+	records.tokens = append(records.tokens, token)
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			records.depth++
+		} else {
+			records.depth--
+		}
+	}
+	if records.depth > 0 {
+		return nil
+	}
+	p.records = nil
+
+	p.skipRecords = true
+	p.homogeneous = isHomogeneous(records.tokens)
+	return p.replayTokens(records.tokens)
+}
+
+// isHomogeneous reports whether the tokens form an array whose elements are
+// all objects with the same key set
+func isHomogeneous(tokens []json.Token) bool {
+	var keySet map[string]bool
+	rest := tokens[1:]
+	for len(rest) > 0 && rest[0] != json.Delim(']') {
+		if rest[0] != json.Delim('{') {
+			return false
+		}
+		keys := map[string]bool{}
+		rest = rest[1:]
+		for len(rest) > 0 && rest[0] != json.Delim('}') {
+			key, _ := rest[0].(string)
+			keys[key] = true
+			rest = skipValue(rest[1:])
+		}
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+
+		if keySet == nil {
+			keySet = keys
+			continue
+		}
+		if len(keys) != len(keySet) {
+			return false
+		}
+		for key := range keys {
+			if !keySet[key] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// skipValue returns the tokens after the complete value at the start of tokens
+func skipValue(tokens []json.Token) []json.Token {
+	depth := 0
+	for i, token := range tokens {
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return tokens[i+1:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAdaptiveCompaction(t *testing.T) {
+	input := `{"users":[{"id":1,"name":"Alice"},{"name":"Bob","id":2}],"events":[{"type":"login"},{"type":"error","code":500}],"mixed":[1,{"a":1}],"nested":[[{"a":1},{"a":2}]]}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "records are compact and mixed elements are expanded",
+			options: []ConfigOption{WithAdaptiveCompaction()},
+			expected: `{
+  "users": [
+    {"id": 1, "name": "Alice"},
+    {"name": "Bob", "id": 2}
+  ],
+  "events": [
+    {
+      "type": "login"
+    },
+    {
+      "type": "error",
+      "code": 500
+    }
+  ],
+  "mixed": [
+    1,
+    {
+      "a": 1
+    }
+  ],
+  "nested": [
+    [{"a": 1}, {"a": 2}]
+  ]
+}`,
+		},
+		{
+			name:    "records are compact beyond compact depth",
+			options: []ConfigOption{WithAdaptiveCompaction(), WithCompactDepth(0)},
+			expected: `{
+  "users": [
+    {"id": 1, "name": "Alice"},
+    {"name": "Bob", "id": 2}
+  ],
+  "events": [
+    {
+      "type": "login"
+    },
+    {
+      "type": "error",
+      "code": 500
+    }
+  ],
+  "mixed": [
+    1,
+    {
+      "a": 1
+    }
+  ],
+  "nested": [
+    [
+      {"a": 1},
+      {"a": 2}
+    ]
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAdaptiveCompactionWithWidthLimit(t *testing.T) {
+	// Records inside an element expanded for the width limit stay compact
+	formatter := NewFormatter(NewConfig(WithAdaptiveCompaction(), WithMaxWidth(30)))
+	result, err := formatter.Format(`{"a":{"b":{"c":[{"x":1},{"x":2}],"d":[{"x":1},{"y":2}]}}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "a": {
+    "b": {
+      "c": [
+        {"x": 1},
+        {"x": 2}
+      ],
+      "d": [
+        {
+          "x": 1
+        },
+        {
+          "y": 2
+        }
+      ]
+    }
+  }
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestIsHomogeneous(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: `[]`, expected: true},
+		{input: `[{"a":1,"b":{"c":[1]}},{"b":2,"a":[{"z":1}]}]`, expected: true},
+		{input: `[{"a":1},{"a":1,"b":2}]`, expected: false},
+		{input: `[{"a":1,"b":2},{"a":1,"c":2}]`, expected: false},
+		{input: `[{"a":1},[1]]`, expected: false},
+		{input: `[1,2]`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := isHomogeneous(readTokens(t, tt.input)); result != tt.expected {
+				t.Errorf("Expected %t, got %t", tt.expected, result)
+			}
+		})
+	}
+}
+
+// readTokens decodes all tokens of a JSON text
+func readTokens(t *testing.T, input string) []json.Token {
+	t.Helper()
+	decoder := json.NewDecoder(strings.NewReader(input))
+	var tokens []json.Token
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return tokens
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func TestCompactKeys(t *testing.T) {
+	input := `{"Tags":[{"Key":"Name","Value":"web"},{"Key":"stack","Value":"arn:aws:cloudformation:us-east-1:123456789012:stack/web/1c2fa620"}],"labels":{"env":"prod","owner":{"team":"ops"}},"other":[{"Key":"a","Value":"b"}]}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "rows beyond width limit",
+			options: []ConfigOption{WithCompactKeys("Tags", "labels"), WithMaxWidth(40)},
+			expected: `{
+  "Tags": [
+    {"Key": "Name", "Value": "web"},
+    {"Key": "stack", "Value": "arn:aws:cloudformation:us-east-1:123456789012:stack/web/1c2fa620"}
+  ],
+  "labels": {
+    "env": "prod",
+    "owner": {"team": "ops"}
+  },
+  "other": [
+    {"Key": "a", "Value": "b"}
+  ]
+}`,
+		},
+		{
+			name:    "expanded",
+			options: []ConfigOption{WithCompactKeys("Tags"), WithCompactDepth(0)},
+			expected: `{
+  "Tags": [
+    {"Key": "Name", "Value": "web"},
+    {"Key": "stack", "Value": "arn:aws:cloudformation:us-east-1:123456789012:stack/web/1c2fa620"}
+  ],
+  "labels": {
+    "env": "prod",
+    "owner": {
+      "team": "ops"
+    }
+  },
+  "other": [
+    {
+      "Key": "a",
+      "Value": "b"
+    }
+  ]
+}`,
+		},
+		{
+			name:     "inside a compact line",
+			options:  []ConfigOption{WithCompactKeys("Tags"), WithCompactDepth(1)},
+			expected: `{"Tags": [{"Key": "Name", "Value": "web"}, {"Key": "stack", "Value": "arn:aws:cloudformation:us-east-1:123456789012:stack/web/1c2fa620"}], "labels": {"env": "prod", "owner": {"team": "ops"}}, "other": [{"Key": "a", "Value": "b"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPathLayout(t *testing.T) {
+	input := `{"metadata":{"name":"web","labels":{"app":"shop"}},"spec":{"ports":[{"name":"http","port":80}]}}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "compact paths",
+			options: []ConfigOption{WithCompactDepth(0), WithCompactPaths("spec.ports.*", "metadata.labels")},
+			expected: `{
+  "metadata": {
+    "name": "web",
+    "labels": {"app": "shop"}
+  },
+  "spec": {
+    "ports": [
+      {"name": "http", "port": 80}
+    ]
+  }
+}`,
+		},
+		{
+			name:    "expanded paths",
+			options: []ConfigOption{WithCompactDepth(2), WithExpandedPaths("metadata")},
+			expected: `{
+  "metadata": {
+    "name": "web",
+    "labels": {"app": "shop"}
+  },
+  "spec": {"ports": [{"name": "http", "port": 80}]}
+}`,
+		},
+		{
+			name:    "expanded path inside compact line",
+			options: []ConfigOption{WithCompactDepth(2), WithExpandedPaths("spec.ports")},
+			expected: `{
+  "metadata": {"name": "web", "labels": {"app": "shop"}},
+  "spec": {"ports": [{"name": "http", "port": 80}]}
+}`,
+		},
+		{
+			name:    "compact path beyond width limit",
+			options: []ConfigOption{WithCompactDepth(0), WithCompactPaths("spec"), WithMaxWidth(30)},
+			expected: `{
+  "metadata": {
+    "name": "web",
+    "labels": {
+      "app": "shop"
+    }
+  },
+  "spec": {
+    "ports": [
+      {
+        "name": "http",
+        "port": 80
+      }
+    ]
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
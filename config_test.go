@@ -218,6 +218,15 @@ func TestValidateConfig(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "negative max tokens",
+			config: &Config{
+				IndentSize:   2,
+				CompactDepth: 3,
+				MaxTokens:    -1,
+			},
+			expectError: true,
+		},
 		{
 			name: "zero indent size",
 			config: &Config{
@@ -315,6 +324,50 @@ func TestConfigOptions(t *testing.T) {
 			t.Error("Expected CompactDepth to be 2")
 		}
 	})
+
+	t.Run("WithMaxWidth", func(t *testing.T) {
+		config := &Config{}
+		WithMaxWidth(80)(config)
+		if config.MaxWidth != 80 {
+			t.Errorf("Expected MaxWidth 80, got %d", config.MaxWidth)
+		}
+
+		// Invalid negative width - should not change
+		WithMaxWidth(-1)(config)
+		if config.MaxWidth != 80 {
+			t.Errorf("Expected MaxWidth to remain 80, got %d", config.MaxWidth)
+		}
+	})
+
+	t.Run("WithWidthByDepth", func(t *testing.T) {
+		widths := map[int]int{3: 120, 5: 80, -1: 10, 4: -1}
+		config := &Config{}
+		WithWidthByDepth(widths)(config)
+		if len(config.WidthByDepth) != 2 || config.WidthByDepth[3] != 120 || config.WidthByDepth[5] != 80 {
+			t.Errorf("Expected only valid entries to be kept, got %v", config.WidthByDepth)
+		}
+
+		// The option copies the map
+		widths[3] = 40
+		if config.WidthByDepth[3] != 120 {
+			t.Error("Modifying the source map affected the config")
+		}
+	})
+
+	t.Run("WithObjectIndent and WithArrayIndent", func(t *testing.T) {
+		config := &Config{}
+		WithObjectIndent(IndentDouble)(config)
+		WithArrayIndent(IndentAligned)(config)
+		if config.ObjectIndent != IndentDouble || config.ArrayIndent != IndentAligned {
+			t.Errorf("Expected IndentDouble and IndentAligned, got %d and %d", config.ObjectIndent, config.ArrayIndent)
+		}
+
+		// Invalid values - should not change
+		WithArrayIndent(ContainerIndent(7))(config)
+		if config.ArrayIndent != IndentAligned {
+			t.Errorf("Expected ArrayIndent to remain IndentAligned, got %d", config.ArrayIndent)
+		}
+	})
 }
 
 func TestConfigOptionsCombinations(t *testing.T) {
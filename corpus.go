@@ -0,0 +1,167 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffixes of the files of a test corpus read by CheckCorpus
+const (
+	corpusInputSuffix    = ".input.json"
+	corpusExpectedSuffix = ".expected.json"
+)
+
+// CorpusMismatch is a case of a test corpus whose formatted output differs
+// from the expected output.
+type CorpusMismatch struct {
+	// Name is the path of the input file relative to the corpus directory.
+	Name string
+
+	// Err is the error formatting the input, or reading its files.
+	Err error
+
+	// Diff is a unified diff from the expected to the formatted output.
+	Diff string
+
+	// Changes lists the objects and arrays written on one line in one output
+	// and over several lines in the other, with BeforeLines counting the
+	// lines of the expected output. It is nil when an output is not JSON.
+	Changes []LayoutChange
+}
+
+// CorpusReport is the result of CheckCorpus.
+type CorpusReport struct {
+	// Cases is the number of input files checked.
+	Cases int
+
+	// Mismatches lists the cases that failed, in file name order.
+	Mismatches []CorpusMismatch
+}
+
+// Passed reports whether every case produced its expected output.
+func (r *CorpusReport) Passed() bool {
+	return len(r.Mismatches) == 0
+}
+
+// CheckCorpus formats every NAME.input.json file below dir and compares the
+// output with NAME.expected.json in the same directory, so teams can gate
+// their styles and profiles with a directory of examples instead of Go tests.
+// A trailing newline of the expected file is ignored. A missing expected
+// file is a mismatch; with update set, it is written instead, as are the
+// expected files of the other mismatches.
+//
+// Example:
+//
+//	report, err := formatter.CheckCorpus("testdata/style", false)
+//	for _, m := range report.Mismatches {
+//	    fmt.Printf("%s\n%s", m.Name, m.Diff)
+//	}
+func (f *Formatter) CheckCorpus(dir string, update bool) (*CorpusReport, error) {
+	var inputs []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), corpusInputSuffix) {
+			inputs = append(inputs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, WrapFormatError("failed to read corpus "+dir, err)
+	}
+	sort.Strings(inputs)
+
+	report := &CorpusReport{Cases: len(inputs)}
+	for _, input := range inputs {
+		if mismatch := f.checkCorpusCase(dir, input, update); mismatch != nil {
+			report.Mismatches = append(report.Mismatches, *mismatch)
+		}
+	}
+	return report, nil
+}
+
+// checkCorpusCase formats a corpus input and returns its mismatch, or nil if
+// the output is expected
+func (f *Formatter) checkCorpusCase(dir, input string, update bool) *CorpusMismatch {
+	name, err := filepath.Rel(dir, input)
+	if err != nil {
+		name = input
+	}
+	mismatch := &CorpusMismatch{Name: name}
+	expectedPath := strings.TrimSuffix(input, corpusInputSuffix) + corpusExpectedSuffix
+
+	source, err := os.ReadFile(input)
+	if err != nil {
+		mismatch.Err = WrapFormatError("failed to read input", err)
+		return mismatch
+	}
+	formatted, err := f.Format(string(source))
+	if err != nil {
+		mismatch.Err = err
+		return mismatch
+	}
+
+	expected, err := os.ReadFile(expectedPath)
+	switch {
+	case err == nil && strings.TrimSuffix(string(expected), "\n") == formatted:
+		return nil
+	case update:
+		if err := os.WriteFile(expectedPath, []byte(formatted+"\n"), 0o644); err != nil {
+			mismatch.Err = WrapFormatError("failed to write expected output", err)
+			return mismatch
+		}
+		return nil
+	case err != nil:
+		mismatch.Err = WrapFormatError("failed to read expected output", err)
+		return mismatch
+	}
+
+	expectedText := strings.TrimSuffix(string(expected), "\n")
+	mismatch.Diff = unifiedDiff(filepath.Base(expectedPath), "formatted", expectedText+"\n", formatted+"\n")
+	mismatch.Changes = layoutChanges(expectedText, formatted)
+	return mismatch
+}
+
+// layoutChanges returns the containers written on one line in one formatted
+// document and over several lines in the other, in the order of the second,
+// or nil if either is not JSON
+func layoutChanges(before, after string) []LayoutChange {
+	beforeContainers, ok := formattedContainers(before)
+	if !ok {
+		return nil
+	}
+	afterContainers, ok := formattedContainers(after)
+	if !ok {
+		return nil
+	}
+	beforeLines := make(map[string]int, len(beforeContainers))
+	for _, c := range beforeContainers {
+		beforeLines[layoutKey(c.path)] = c.lines
+	}
+	changes := []LayoutChange{}
+	for _, c := range afterContainers {
+		lines, ok := beforeLines[layoutKey(c.path)]
+		if ok && (lines == 1) != (c.lines == 1) {
+			changes = append(changes, LayoutChange{Path: c.path, BeforeLines: lines, AfterLines: c.lines})
+		}
+	}
+	return changes
+}
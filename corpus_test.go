@@ -0,0 +1,79 @@
+package jsonformat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCorpus writes the files of a test corpus to a temporary directory
+func writeCorpus(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheckCorpus(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{
+		"ok.input.json":               `{"a":[1,2]}`,
+		"ok.expected.json":            "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n",
+		"nested/layout.input.json":    `{"a":[1,2]}`,
+		"nested/layout.expected.json": "{\n  \"a\": [1, 2]\n}\n",
+		"missing.input.json":          `[]`,
+		"invalid.input.json":          `{"a":`,
+		"invalid.expected.json":       "{}\n",
+		"notes.json":                  `{"ignored": true}`,
+	})
+	formatter := NewFormatter(NewConfig())
+
+	report, err := formatter.CheckCorpus(dir, false)
+	if err != nil {
+		t.Fatalf("CheckCorpus failed: %v", err)
+	}
+	if report.Cases != 4 || report.Passed() {
+		t.Fatalf("Cases = %d, Passed = %v, want 4 cases with mismatches", report.Cases, report.Passed())
+	}
+	var names []string
+	for _, m := range report.Mismatches {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "invalid.input.json,missing.input.json,"+filepath.Join("nested", "layout.input.json") {
+		t.Fatalf("mismatches = %s", got)
+	}
+	if report.Mismatches[0].Err == nil || report.Mismatches[1].Err == nil {
+		t.Errorf("expected errors for invalid input and missing expected output")
+	}
+
+	layout := report.Mismatches[2]
+	if layout.Err != nil || !strings.Contains(layout.Diff, "-  \"a\": [1, 2]\n") {
+		t.Errorf("unexpected mismatch: %v\n%s", layout.Err, layout.Diff)
+	}
+	if len(layout.Changes) != 1 || layout.Changes[0].String() != "a: 1 line → 4 lines" {
+		t.Errorf("Changes = %v, want a: 1 line → 4 lines", layout.Changes)
+	}
+
+	if _, err := formatter.CheckCorpus(dir, true); err != nil {
+		t.Fatalf("CheckCorpus with update failed: %v", err)
+	}
+	report, err = formatter.CheckCorpus(dir, false)
+	if err != nil {
+		t.Fatalf("CheckCorpus failed: %v", err)
+	}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Name != "invalid.input.json" {
+		t.Errorf("expected only the invalid input to fail after updating, got %+v", report.Mismatches)
+	}
+
+	if _, err := formatter.CheckCorpus(filepath.Join(dir, "nope"), false); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvOptions holds the settings used by ReadCSVAndFormat.
+type csvOptions struct {
+	comma      rune
+	inferTypes bool
+}
+
+// CSVOption is a functional option for configuring CSV ingestion.
+type CSVOption func(*csvOptions)
+
+// WithCSVComma sets the field delimiter used in the CSV input.
+// The default delimiter is ','.
+//
+// Example:
+//
+//	formatted, err := ReadCSVAndFormat(r, nil, WithCSVComma('\t')) // TSV input
+func WithCSVComma(comma rune) CSVOption {
+	return func(o *csvOptions) {
+		o.comma = comma
+	}
+}
+
+// WithCSVTypeInference enables type inference for CSV cells.
+// When enabled, cells that look like JSON numbers or booleans are emitted
+// as numbers or booleans, and empty cells are emitted as null.
+// When disabled (the default), every cell is emitted as a string.
+func WithCSVTypeInference() CSVOption {
+	return func(o *csvOptions) {
+		o.inferTypes = true
+	}
+}
+
+// ReadCSVAndFormat reads CSV data from r and returns it as a formatted
+// JSON array of objects. The first record is used as the header row and
+// its fields become the object keys, in column order. A repeated header
+// name gets a suffix with its occurrence, e.g. "name", "name_2".
+// If config is nil, the default configuration is used. The limit of
+// WithMaxTokens guards against malformed JSON input, so it is not applied
+// to the JSON built from the CSV records.
+//
+// Example:
+//
+//	formatted, err := ReadCSVAndFormat(strings.NewReader("id,name\n1,Alice\n"), nil, WithCSVTypeInference())
+//	// [
+//	//   {"id": 1, "name": "Alice"}
+//	// ]
+func ReadCSVAndFormat(r io.Reader, config *Config, options ...CSVOption) (string, error) {
+	if r == nil {
+		return "", NewFormatError("CSV reader cannot be nil")
+	}
+
+	opts := csvOptions{comma: ','}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = opts.comma
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return "", NewFormatError("CSV input is empty")
+	}
+	if err != nil {
+		return "", WrapFormatError("failed to read CSV header", err)
+	}
+	keys := csvHeaderKeys(header)
+
+	var builder strings.Builder
+	builder.WriteString("[")
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", WrapFormatError(fmt.Sprintf("failed to read CSV record %d", row+1), err)
+		}
+		if row > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteString(",")
+			}
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return "", WrapFormatError("failed to encode CSV header", err)
+			}
+			builder.Write(encodedKey)
+			builder.WriteString(":")
+			builder.WriteString(csvCellToJSON(record[i], opts.inferTypes))
+		}
+		builder.WriteString("}")
+	}
+	builder.WriteString("]")
+
+	if config == nil {
+		config = DefaultConfig()
+	}
+	config = config.clone()
+	config.MaxTokens = 0
+	return NewFormatter(config).Format(builder.String())
+}
+
+// csvHeaderKeys returns the object keys for the header fields, adding a
+// suffix to repeated names so that every key is unique
+func csvHeaderKeys(header []string) []string {
+	used := make(map[string]bool, len(header))
+	for _, name := range header {
+		used[name] = true
+	}
+	seen := make(map[string]int, len(header))
+	keys := make([]string, len(header))
+	for i, name := range header {
+		seen[name]++
+		key := name
+		for n := seen[name]; n > 1; n++ {
+			key = fmt.Sprintf("%s_%d", name, n)
+			if !used[key] {
+				seen[name] = n
+				break
+			}
+		}
+		used[key] = true
+		keys[i] = key
+	}
+	return keys
+}
+
+// csvCellToJSON converts a single CSV cell into a JSON literal
+func csvCellToJSON(cell string, inferTypes bool) string {
+	if inferTypes {
+		switch {
+		case cell == "":
+			return "null"
+		case cell == "true" || cell == "false":
+			return cell
+		case isJSONNumber(cell):
+			return cell
+		}
+	}
+	encoded, _ := json.Marshal(cell)
+	return string(encoded)
+}
+
+// isJSONNumber reports whether s is a valid JSON number literal
+func isJSONNumber(s string) bool {
+	if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+		return false
+	}
+	var number json.Number
+	return json.Unmarshal([]byte(s), &number) == nil
+}
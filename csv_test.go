@@ -0,0 +1,138 @@
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVAndFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []CSVOption
+		expected string
+	}{
+		{
+			name:  "strings by default",
+			input: "id,name\n1,Alice\n2,Bob\n",
+			expected: `[
+  {
+    "id": "1",
+    "name": "Alice"
+  },
+  {
+    "id": "2",
+    "name": "Bob"
+  }
+]`,
+		},
+		{
+			name:    "type inference",
+			input:   "id,name,active,score\n1,Alice,true,\n2,Bob,false,1.5\n",
+			options: []CSVOption{WithCSVTypeInference()},
+			expected: `[
+  {
+    "id": 1,
+    "name": "Alice",
+    "active": true,
+    "score": null
+  },
+  {
+    "id": 2,
+    "name": "Bob",
+    "active": false,
+    "score": 1.5
+  }
+]`,
+		},
+		{
+			name:    "type inference keeps non-JSON numbers as strings",
+			input:   "zip,value\n01234,NaN\n",
+			options: []CSVOption{WithCSVTypeInference()},
+			expected: `[
+  {
+    "zip": "01234",
+    "value": "NaN"
+  }
+]`,
+		},
+		{
+			name:  "repeated header names",
+			input: "name,name,name_2,name\na,b,c,d\n",
+			expected: `[
+  {
+    "name": "a",
+    "name_3": "b",
+    "name_2": "c",
+    "name_4": "d"
+  }
+]`,
+		},
+		{
+			name:    "custom delimiter",
+			input:   "id\tname\n1\tAlice\n",
+			options: []CSVOption{WithCSVComma('\t')},
+			expected: `[
+  {
+    "id": "1",
+    "name": "Alice"
+  }
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ReadCSVAndFormat(strings.NewReader(tt.input), nil, tt.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReadCSVAndFormatManyRecords(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,name,email\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "%d,user%d,user%d@example.com\n", i, i, i)
+	}
+
+	config := NewConfig()
+	result, err := ReadCSVAndFormat(strings.NewReader(input.String()), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if records := strings.Count(result, `"email"`); records != 5000 {
+		t.Errorf("Expected 5000 records, got %d", records)
+	}
+	if config.MaxTokens != DefaultConfig().MaxTokens {
+		t.Errorf("Expected the config to be unchanged, got MaxTokens %d", config.MaxTokens)
+	}
+}
+
+func TestReadCSVAndFormatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty input", input: ""},
+		{name: "mismatched field count", input: "id,name\n1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSVAndFormat(strings.NewReader(tt.input), nil)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if _, ok := err.(*FormatError); !ok {
+				t.Errorf("Expected *FormatError, got %T", err)
+			}
+		})
+	}
+}
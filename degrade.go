@@ -0,0 +1,187 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// DegradationStep is a way of formatting a document on the degradation
+// ladder set with WithDegradation, from the most to the least readable.
+type DegradationStep int
+
+const (
+	// DegradePretty formats the document as configured.
+	DegradePretty DegradationStep = iota
+	// DegradeFold writes the containers from depth 2 on one line, without
+	// checking their width, so deep subtrees take one line each.
+	DegradeFold
+	// DegradeOutline writes the containers from depth 2 on one line within
+	// 120 columns, with objects folded after their first 10 members, as
+	// ModeOutline does. The output is for display only.
+	DegradeOutline
+	// DegradeMinify removes the whitespace between the tokens of the input
+	// without formatting it.
+	DegradeMinify
+)
+
+// String returns "pretty", "fold", "outline", or "minify".
+func (s DegradationStep) String() string {
+	switch s {
+	case DegradeFold:
+		return "fold"
+	case DegradeOutline:
+		return "outline"
+	case DegradeMinify:
+		return "minify"
+	default:
+		return "pretty"
+	}
+}
+
+// DegradationRung is a step of the degradation ladder with the budgets that
+// a document must fit to be formatted with it.
+type DegradationRung struct {
+	// Step is the way of formatting the document.
+	Step DegradationStep
+
+	// MaxSize is the size of the largest input in bytes formatted with the
+	// step, or 0 for no limit.
+	MaxSize int
+
+	// TimeBudget is the time after which the output of the step is dropped
+	// and the next rung is tried, or 0 for no limit.
+	TimeBudget time.Duration
+}
+
+// foldDepth is the depth from which the fold and outline steps write
+// containers on one line
+const foldDepth = 2
+
+// WithDegradation sets a ladder of ways to format a document, so that one
+// Formatter writes small payloads in full and still returns something
+// sensible for enormous ones, without branching in the caller. Format tries
+// the rungs in order: a rung is skipped if the input is larger than its
+// MaxSize, and its output is dropped if formatting takes longer than its
+// TimeBudget. The last rung is used regardless of its budgets, with its time
+// budget truncating the output as WithTimeBudget does.
+//
+// The ladder applies to Format and FormatBytes; other methods format as
+// configured. The cache set with SetCache is not used while a ladder is set.
+//
+// Example:
+//
+//	config := NewConfig(WithDegradation(
+//	    DegradationRung{Step: DegradePretty, MaxSize: 1 << 20, TimeBudget: 50 * time.Millisecond},
+//	    DegradationRung{Step: DegradeFold, MaxSize: 8 << 20, TimeBudget: 100 * time.Millisecond},
+//	    DegradationRung{Step: DegradeOutline, TimeBudget: 200 * time.Millisecond},
+//	    DegradationRung{Step: DegradeMinify},
+//	))
+func WithDegradation(rungs ...DegradationRung) ConfigOption {
+	return func(c *Config) {
+		c.Degradation = append([]DegradationRung(nil), rungs...)
+	}
+}
+
+// valid reports whether the rung has a known step and non-negative budgets
+func (r DegradationRung) valid() bool {
+	return r.Step >= DegradePretty && r.Step <= DegradeMinify && r.MaxSize >= 0 && r.TimeBudget >= 0
+}
+
+// degradationLadder returns a snapshot of the configuration of each rung of
+// the ladder, or nil for rungs that minify
+func degradationLadder(config *Config) []*FrozenConfig {
+	if len(config.Degradation) == 0 {
+		return nil
+	}
+	ladder := make([]*FrozenConfig, len(config.Degradation))
+	for i, rung := range config.Degradation {
+		if rung.Step == DegradeMinify {
+			continue
+		}
+		step := config.clone()
+		step.Degradation = nil
+		if rung.TimeBudget > 0 {
+			step.TimeBudget = rung.TimeBudget
+		}
+		switch rung.Step {
+		case DegradeFold:
+			if step.CompactDepth == 0 || step.CompactDepth > foldDepth {
+				step.CompactDepth = foldDepth
+			}
+			step.MaxWidth = 0
+			step.WidthByDepth = nil
+		case DegradeOutline:
+			step.CompactDepth = foldDepth
+			step.MaxWidth = recommendedWidth
+			step.WidthByDepth = nil
+			step.WideObjectKeys = outlineFoldKeys
+		}
+		ladder[i] = step.Freeze()
+	}
+	return ladder
+}
+
+// formatDegraded formats a JSON string with the first rung of the
+// degradation ladder whose budgets it fits
+func (f *Formatter) formatDegraded(jsonStr string) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+		}
+	}()
+
+	last := len(f.config.Degradation) - 1
+	for i, rung := range f.config.Degradation {
+		if i < last && rung.MaxSize > 0 && len(jsonStr) > rung.MaxSize {
+			continue
+		}
+		if rung.Step == DegradeMinify {
+			return f.minify(jsonStr)
+		}
+		step := f.ladder[i].NewFormatter()
+		result, parser, err := step.formatString(jsonStr, func(p *TokenParser) { p.degrading = i < last })
+		if err != nil {
+			return "", err
+		}
+		if parser.truncated && i < last {
+			continue
+		}
+		return result, nil
+	}
+	return "", NewFormatError("degradation ladder has no rungs")
+}
+
+// minify removes the whitespace between the tokens of a JSON string
+func (f *Formatter) minify(jsonStr string) (string, error) {
+	if jsonStr == "" {
+		return "", f.config.nameError(NewFormatError("input JSON string is empty"))
+	}
+	jsonStr, err := f.config.decodeInput(jsonStr)
+	if err != nil {
+		return "", f.config.nameError(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(jsonStr)); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			return "", f.config.nameError(WrapFormatErrorWithPosition("invalid JSON input", int(syntaxErr.Offset), err))
+		}
+		return "", f.config.nameError(WrapFormatError("invalid JSON input", err))
+	}
+	return compact.String(), nil
+}
@@ -0,0 +1,85 @@
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDegradationBySize(t *testing.T) {
+	config := NewConfig(WithDegradation(
+		DegradationRung{Step: DegradePretty, MaxSize: 30},
+		DegradationRung{Step: DegradeFold, MaxSize: 60},
+		DegradationRung{Step: DegradeMinify},
+	))
+	formatter := NewFormatter(config)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "pretty",
+			input:    `{"a":{"b":{"c":1}}}`,
+			expected: "{\n  \"a\": {\n    \"b\": {\"c\": 1}\n  }\n}",
+		},
+		{
+			name:     "fold",
+			input:    `{"a":{"b":{"c":1}},"padding":"0123456789012345"}`,
+			expected: "{\n  \"a\": {\"b\": {\"c\": 1}},\n  \"padding\": \"0123456789012345\"\n}",
+		},
+		{
+			name:     "minify",
+			input:    "{\"a\": {\"b\": {\"c\": 1}},\n \"padding\": \"01234567890123456789012345\"}",
+			expected: `{"a":{"b":{"c":1}},"padding":"01234567890123456789012345"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatter.Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDegradationByTime(t *testing.T) {
+	spendBudgetClock(t)
+	elements := make([]string, 300)
+	for i := range elements {
+		elements[i] = fmt.Sprint(i)
+	}
+	input := `{"items":[` + strings.Join(elements, ",") + `]}`
+
+	config := NewConfig(WithStrictJSON(), WithDegradation(
+		DegradationRung{Step: DegradePretty, TimeBudget: time.Millisecond},
+		DegradationRung{Step: DegradeMinify},
+	))
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if result != input {
+		t.Errorf("Format() = %q, want the minified input", result)
+	}
+}
+
+func TestDegradationErrors(t *testing.T) {
+	for _, step := range []DegradationStep{DegradePretty, DegradeMinify} {
+		formatter := NewFormatter(NewConfig(WithDegradation(DegradationRung{Step: step})))
+		if _, err := formatter.Format(`{"a":`); err == nil {
+			t.Errorf("%s: expected an error for invalid JSON", step)
+		}
+	}
+
+	config := NewConfig(WithIndentSize(4), WithDegradation(DegradationRung{Step: DegradationStep(9)}))
+	if config.IndentSize != 2 || config.Degradation != nil {
+		t.Errorf("expected the default configuration for an unknown step, got %+v", config)
+	}
+}
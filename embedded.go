@@ -0,0 +1,123 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxEmbeddedDepth is the number of strings holding embedded JSON that a
+// value can be nested in and still be expanded
+const maxEmbeddedDepth = 8
+
+// ErrEmbeddedTooDeep matches the error for embedded JSON nested in more than
+// maxEmbeddedDepth strings holding embedded JSON. Each level is shorter than
+// the string holding it, so expansion cannot cycle, but the limit bounds the
+// work adversarial documents cause.
+var ErrEmbeddedTooDeep = errors.New("embedded JSON nested too deeply")
+
+// WithEmbeddedJSON writes string values at paths matching the patterns as the
+// JSON object or array they hold, so payloads that were serialized twice, such
+// as the data of message envelopes, are formatted like the rest of the
+// document. Strings holding JSON encoded in standard base64 are decoded first.
+// Patterns are dot-separated paths as in ValueContext.MatchPath. Strings that
+// do not hold an object or array are written unchanged. The tokens of the
+// expanded JSON count toward the limit of WithMaxTokens, and embedded JSON
+// nested in more than 8 strings fails with ErrEmbeddedTooDeep.
+//
+// The output stays valid JSON, but the values change type, so use this option
+// for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithEmbeddedJSON("data"))
+//	// {"data": "{\"id\":1}"}  →  {"data": {"id": 1}}
+func WithEmbeddedJSON(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.EmbeddedJSON = append(c.EmbeddedJSON, patterns...)
+	}
+}
+
+// expandEmbedded replays the tokens of the JSON held by a string value at a
+// path of embedded JSON, and reports whether it did
+func (p *TokenParser) expandEmbedded(token json.Token) (bool, error) {
+	text, ok := token.(string)
+	if !ok || len(p.config.EmbeddedJSON) == 0 || !p.isScalarValue(token) {
+		return false, nil
+	}
+	if _, ok := firstMatch(p.pathRules().embedded, p.valuePath()); !ok {
+		return false, nil
+	}
+	tokens, ok := embeddedTokens(text)
+	if !ok {
+		if decoded, err := base64.StdEncoding.DecodeString(text); err == nil {
+			tokens, ok = embeddedTokens(string(decoded))
+		}
+	}
+	if !ok {
+		return false, nil
+	}
+
+	// The tokens replayed count toward MaxTokens like the tokens read, in
+	// place of the string holding them
+	p.expandedTokens += len(tokens) - 1
+	if p.tooManyTokens(p.readTokens) {
+		return true, NewFormatError("JSON structure too complex or malformed (too many tokens)")
+	}
+	if p.embeddedDepth >= maxEmbeddedDepth {
+		return true, NewFormatError(fmt.Sprintf("%s (max depth: %d)", ErrEmbeddedTooDeep, maxEmbeddedDepth))
+	}
+	p.embeddedDepth++
+	defer func() { p.embeddedDepth-- }()
+	return true, p.replayTokens(tokens)
+}
+
+// embeddedTokens returns the tokens of a text holding a single JSON object or array
+func embeddedTokens(text string) ([]json.Token, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	var tokens []json.Token
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		if delim, ok := token.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		tokens = append(tokens, token)
+		// Anything after the first value means the text is not a single value
+		if depth == 0 && decoder.More() {
+			return nil, false
+		}
+	}
+	return tokens, depth == 0
+}
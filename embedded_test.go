@@ -0,0 +1,110 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "object",
+			input:    `{"data":"{\"id\":1,\"tags\":[\"a\"]}"}`,
+			options:  []ConfigOption{WithEmbeddedJSON("data"), WithCompactDepth(1)},
+			expected: `{"data": {"id": 1, "tags": ["a"]}}`,
+		},
+		{
+			name:     "base64",
+			input:    `{"data_base64":"eyJrIjogInYifQ==","other":"eyJrIjogInYifQ=="}`,
+			options:  []ConfigOption{WithEmbeddedJSON("data_base64"), WithCompactDepth(1)},
+			expected: `{"data_base64": {"k": "v"}, "other": "eyJrIjogInYifQ=="}`,
+		},
+		{
+			name:     "not a container",
+			input:    `[{"data":"42"},{"data":"plain"},{"data":"{\"broken\":"},{"data":"[1] [2]"},{"data":7}]`,
+			options:  []ConfigOption{WithEmbeddedJSON("*.data"), WithCompactDepth(1)},
+			expected: `[{"data": "42"}, {"data": "plain"}, {"data": "{\"broken\":"}, {"data": "[1] [2]"}, {"data": 7}]`,
+		},
+		{
+			name:  "expanded with key order and hooks",
+			input: `{"data":"{\"secret\":\"s\",\"id\":1}"}`,
+			options: []ConfigOption{
+				WithEmbeddedJSON("data"), WithKeyOrder("data", "id"), WithRedaction("secret"),
+			},
+			expected: `{
+  "data": {
+    "id": 1,
+    "secret": "[REDACTED]"
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEmbeddedJSONMaxTokens(t *testing.T) {
+	elements := strings.TrimSuffix(strings.Repeat("1,", 20), ",")
+	config := NewConfig(WithEmbeddedJSON("data"), WithMaxTokens(15))
+
+	// The same array is rejected whether it is embedded or not
+	for _, input := range []string{
+		`{"data":[` + elements + `]}`,
+		`{"data":"[` + elements + `]"}`,
+	} {
+		_, err := NewFormatter(config).Format(input)
+		if err == nil || !strings.Contains(err.Error(), "too many tokens") {
+			t.Errorf("%s: expected too many tokens error, got %v", input, err)
+		}
+	}
+
+	if _, err := NewFormatter(config).Format(`{"data":"[1,2,3]"}`); err != nil {
+		t.Errorf("Unexpected error within the limit: %v", err)
+	}
+}
+
+func TestEmbeddedJSONDepth(t *testing.T) {
+	// nested returns a document whose value at "d" holds the next level as a string
+	nested := func(levels int) (string, []string) {
+		doc := `{"d":1}`
+		patterns := []string{"d"}
+		for i := 0; i < levels; i++ {
+			encoded, _ := json.Marshal(doc)
+			doc = `{"d":` + string(encoded) + `}`
+			patterns = append(patterns, patterns[len(patterns)-1]+".d")
+		}
+		return doc, patterns
+	}
+
+	doc, patterns := nested(maxEmbeddedDepth)
+	if _, err := NewFormatter(NewConfig(WithEmbeddedJSON(patterns...))).Format(doc); err != nil {
+		t.Errorf("Unexpected error at the limit: %v", err)
+	}
+
+	doc, patterns = nested(maxEmbeddedDepth + 1)
+	_, err := NewFormatter(NewConfig(WithEmbeddedJSON(patterns...))).Format(doc)
+	if !errors.Is(err, ErrEmbeddedTooDeep) {
+		t.Fatalf("Expected ErrEmbeddedTooDeep, got %v", err)
+	}
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Code() != CodeLimitExceeded {
+		t.Errorf("Expected CodeLimitExceeded, got %v", err)
+	}
+}
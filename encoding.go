@@ -0,0 +1,198 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EncodingPolicy controls how input that starts with a byte order mark is
+// handled. JSON exchanged between systems must be UTF-8, but files exported
+// on Windows often arrive in UTF-16 or with a UTF-8 byte order mark.
+type EncodingPolicy int
+
+const (
+	// EncodingTranscode converts UTF-16 and UTF-32 input with a byte order
+	// mark to UTF-8 and drops a UTF-8 byte order mark.
+	EncodingTranscode EncodingPolicy = iota
+	// EncodingReject reports input that starts with a byte order mark as an
+	// error naming its encoding.
+	EncodingReject
+)
+
+// WithEncodingPolicy sets how input that starts with a byte order mark is
+// handled. By default UTF-16 and UTF-32 input is converted to UTF-8 before
+// formatting, and the output is always UTF-8.
+//
+// Example:
+//
+//	config := NewConfig(WithEncodingPolicy(EncodingReject))
+//	// UTF-16 input fails with "input is encoded in UTF-16LE, not UTF-8"
+func WithEncodingPolicy(policy EncodingPolicy) ConfigOption {
+	return func(c *Config) {
+		if policy >= EncodingTranscode && policy <= EncodingReject {
+			c.Encoding = policy
+		}
+	}
+}
+
+// byteOrderMarks maps the byte order marks to their encodings, in match
+// order: the UTF-32LE mark starts with the UTF-16LE mark
+var byteOrderMarks = []struct {
+	mark     string
+	encoding string
+}{
+	{"\x00\x00\xFE\xFF", "UTF-32BE"},
+	{"\xFF\xFE\x00\x00", "UTF-32LE"},
+	{"\xFE\xFF", "UTF-16BE"},
+	{"\xFF\xFE", "UTF-16LE"},
+	{"\xEF\xBB\xBF", "UTF-8"},
+}
+
+// detectEncoding returns the encoding named by the byte order mark at the
+// start of the input and the length of the mark, or "" if there is none
+func detectEncoding(input string) (string, int) {
+	for _, bom := range byteOrderMarks {
+		if strings.HasPrefix(input, bom.mark) {
+			return bom.encoding, len(bom.mark)
+		}
+	}
+	return "", 0
+}
+
+// decodeInput converts input that starts with a byte order mark to UTF-8
+// without the mark, according to the encoding policy
+func (c *Config) decodeInput(input string) (string, error) {
+	encoding, size := detectEncoding(input)
+	if encoding == "" {
+		return input, nil
+	}
+	if c.Encoding == EncodingReject {
+		if encoding == "UTF-8" {
+			return "", NewFormatError("invalid JSON input: it starts with a UTF-8 byte order mark")
+		}
+		return "", NewFormatError(fmt.Sprintf("invalid JSON input: it is encoded in %s, not UTF-8", encoding))
+	}
+
+	data := input[size:]
+	switch encoding {
+	case "UTF-16BE", "UTF-16LE":
+		return decodeUTF16(data, encoding)
+	case "UTF-32BE", "UTF-32LE":
+		return decodeUTF32(data, encoding)
+	}
+	return data, nil
+}
+
+// endian reads and appends the code units of an encoding
+type endian interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// byteOrder returns the byte order of a UTF-16 or UTF-32 encoding
+func byteOrder(encoding string) endian {
+	if strings.HasSuffix(encoding, "BE") {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// decodeUTF16 converts UTF-16 text to UTF-8. Unpaired surrogates become
+// U+FFFD, as with invalid UTF-8 input.
+func decodeUTF16(data, encoding string) (string, error) {
+	if len(data)%2 != 0 {
+		return "", NewFormatError(fmt.Sprintf("invalid %s input: odd number of bytes", encoding))
+	}
+	order := byteOrder(encoding)
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16([]byte(data[2*i : 2*i+2]))
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// decodeUTF32 converts UTF-32 text to UTF-8
+func decodeUTF32(data, encoding string) (string, error) {
+	if len(data)%4 != 0 {
+		return "", NewFormatError(fmt.Sprintf("invalid %s input: number of bytes is not a multiple of 4", encoding))
+	}
+	order := byteOrder(encoding)
+	var builder strings.Builder
+	builder.Grow(len(data) / 4)
+	for i := 0; i < len(data); i += 4 {
+		r := rune(order.Uint32([]byte(data[i : i+4])))
+		if !utf8.ValidRune(r) {
+			return "", NewFormatErrorWithPosition(fmt.Sprintf("invalid %s input: code point U+%X", encoding, uint32(r)), i+4)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String(), nil
+}
+
+// decodeReader returns a reader of r converted to UTF-8 when it starts with a
+// byte order mark. Converted input is read whole.
+func (c *Config) decodeReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	prefix, _ := buffered.Peek(4)
+	if encoding, _ := detectEncoding(string(prefix)); encoding == "" {
+		return buffered, nil
+	}
+	input, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, WrapFormatError("failed to read input", err)
+	}
+	decoded, err := c.decodeInput(string(input))
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decoded), nil
+}
+
+// outputEncodings are the encodings files can be written in
+var outputEncodings = []string{"UTF-8", "UTF-16LE", "UTF-16BE", "UTF-32LE", "UTF-32BE"}
+
+// encodeText converts UTF-8 text to the encoding, with a byte order mark if
+// bom is set or the encoding is not UTF-8. An empty encoding is UTF-8.
+func encodeText(text, encoding string, bom bool) string {
+	switch encoding {
+	case "", "UTF-8":
+		if bom {
+			return "\xEF\xBB\xBF" + text
+		}
+		return text
+	}
+
+	order := byteOrder(encoding)
+	var buf []byte
+	if strings.HasPrefix(encoding, "UTF-16") {
+		buf = make([]byte, 0, 2*len(text)+2)
+		for _, unit := range utf16.Encode([]rune("\uFEFF" + text)) {
+			buf = order.AppendUint16(buf, unit)
+		}
+	} else {
+		buf = make([]byte, 0, 4*len(text)+4)
+		for _, r := range "\uFEFF" + text {
+			buf = order.AppendUint32(buf, uint32(r))
+		}
+	}
+	return string(buf)
+}
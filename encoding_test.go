@@ -0,0 +1,109 @@
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 returns text in UTF-16 with a byte order mark
+func encodeUTF16(text string, order binary.ByteOrder) string {
+	var buf bytes.Buffer
+	for _, unit := range utf16.Encode([]rune("\uFEFF" + text)) {
+		binary.Write(&buf, order, unit)
+	}
+	return buf.String()
+}
+
+// encodeUTF32 returns text in UTF-32 with a byte order mark
+func encodeUTF32(text string, order binary.ByteOrder) string {
+	var buf bytes.Buffer
+	for _, r := range "\uFEFF" + text {
+		binary.Write(&buf, order, uint32(r))
+	}
+	return buf.String()
+}
+
+func TestInputEncoding(t *testing.T) {
+	text := `{"name":"café 🍰","n":1}`
+	expected := `{
+  "name": "café 🍰",
+  "n": 1
+}`
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"UTF-8", text},
+		{"UTF-8 BOM", "\xEF\xBB\xBF" + text},
+		{"UTF-16LE", encodeUTF16(text, binary.LittleEndian)},
+		{"UTF-16BE", encodeUTF16(text, binary.BigEndian)},
+		{"UTF-32LE", encodeUTF32(text, binary.LittleEndian)},
+		{"UTF-32BE", encodeUTF32(text, binary.BigEndian)},
+	}
+
+	formatter := NewFormatter(DefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatter.Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+			}
+
+			var out bytes.Buffer
+			if err := formatter.FormatTo(&out, strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if streamed := strings.TrimSuffix(out.String(), "\n"); streamed != expected {
+				t.Errorf("FormatTo Expected:\n%s\n\nGot:\n%s", expected, streamed)
+			}
+
+			size, err := EstimateFormattedSize(tt.input, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if size != len(expected) {
+				t.Errorf("Expected size %d, got %d", len(expected), size)
+			}
+		})
+	}
+}
+
+func TestInputEncodingErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		config *Config
+		errMsg string
+	}{
+		{"reject UTF-16", encodeUTF16(`[1]`, binary.LittleEndian), NewConfig(WithEncodingPolicy(EncodingReject)), "invalid JSON input: it is encoded in UTF-16LE, not UTF-8"},
+		{"reject UTF-8 BOM", "\xEF\xBB\xBF[1]", NewConfig(WithEncodingPolicy(EncodingReject)), "starts with a UTF-8 byte order mark"},
+		{"odd UTF-16", "\xFE\xFF\x00[\x00", DefaultConfig(), "invalid UTF-16BE input: odd number of bytes"},
+		{"truncated UTF-32", "\xFF\xFE\x00\x00[\x00\x00", DefaultConfig(), "invalid UTF-32LE input"},
+		{"invalid code point", "\x00\x00\xFE\xFF\x00\x11\x00\x00", DefaultConfig(), "invalid UTF-32BE input: code point U+110000"},
+		{"syntax error", encodeUTF16(`[1,]`, binary.LittleEndian), DefaultConfig(), "invalid JSON input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(tt.config)
+			_, err := formatter.Format(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if formatErr, ok := err.(*FormatError); ok && formatErr.Code() != CodeSyntax {
+				t.Errorf("Expected code %s, got %s", CodeSyntax, formatErr.Code())
+			}
+
+			err = formatter.FormatTo(&bytes.Buffer{}, strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("FormatTo: expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorCode identifies the kind of a FormatError. Codes are stable across
+// releases and languages, so programs should match on codes rather than messages.
+type ErrorCode string
+
+const (
+	// CodeUnknown is used for errors that do not fall into another category.
+	CodeUnknown ErrorCode = "unknown"
+	// CodeEmptyInput reports input without any JSON value.
+	CodeEmptyInput ErrorCode = "empty_input"
+	// CodeSyntax reports malformed or invalid JSON input.
+	CodeSyntax ErrorCode = "syntax"
+	// CodeInvalidValue reports a value that cannot be written as JSON.
+	CodeInvalidValue ErrorCode = "invalid_value"
+	// CodeLimitExceeded reports input beyond the nesting, size, or token limits.
+	CodeLimitExceeded ErrorCode = "limit_exceeded"
+	// CodeInvalidArgument reports a nil or otherwise unusable argument.
+	CodeInvalidArgument ErrorCode = "invalid_argument"
+	// CodeInvalidConfig reports a configuration that fails validation.
+	CodeInvalidConfig ErrorCode = "invalid_config"
+	// CodeHook reports a value hook that returned an unsupported value.
+	CodeHook ErrorCode = "hook"
+	// CodeIO reports a failure to read input or write output.
+	CodeIO ErrorCode = "io"
+	// CodeInternal reports an unexpected internal state or a recovered panic.
+	CodeInternal ErrorCode = "internal"
+)
+
+// errorCodePrefixes maps message prefixes to error codes, in match order
+var errorCodePrefixes = []struct {
+	prefix string
+	code   ErrorCode
+}{
+	{"malformed JSON", CodeSyntax},
+	{"invalid JSON", CodeSyntax},
+	{"invalid UTF-", CodeSyntax},
+	{"unknown delimiter", CodeSyntax},
+	{"unknown token type", CodeSyntax},
+	{"invalid cell reference", CodeSyntax},
+	{"invalid shared string", CodeSyntax},
+	{"failed to parse", CodeSyntax},
+	{"input JSON string is empty", CodeEmptyInput},
+	{"input contains no valid JSON tokens", CodeEmptyInput},
+	{"input contains only", CodeEmptyInput},
+	{"CSV input is empty", CodeEmptyInput},
+	{"XLSX sheet is empty", CodeEmptyInput},
+	{"JSON structure too", CodeLimitExceeded},
+	{"embedded JSON nested too deeply", CodeLimitExceeded},
+	{"string value too large", CodeLimitExceeded},
+	{"string too large", CodeLimitExceeded},
+	{"indentation too large", CodeLimitExceeded},
+	{"cannot format", CodeInvalidValue},
+	{"cannot canonicalize", CodeInvalidValue},
+	{"unknown style version", CodeInvalidArgument},
+	{"unknown policy", CodeInvalidArgument},
+	{"unknown log color", CodeInvalidArgument},
+	{"unknown output encoding", CodeInvalidArgument},
+	{"invalid package name", CodeInvalidArgument},
+	{"invalid checkpoint", CodeInvalidArgument},
+	{"checkpoints ", CodeInvalidConfig},
+	{"invalid continuation token", CodeInvalidArgument},
+	{"invalid chunk quota", CodeInvalidArgument},
+	{"chunks are not available", CodeInvalidConfig},
+	{"invalid log color", CodeInvalidArgument},
+	{"invalid log predicate", CodeInvalidArgument},
+	{"value hook", CodeHook},
+	{"failed to read", CodeIO},
+	{"failed to save checkpoint", CodeIO},
+	{"failed to write", CodeIO},
+	{"failed to open", CodeIO},
+	{"invalid parser state", CodeInternal},
+	{"output is not idempotent", CodeInternal},
+	{"output changes", CodeInternal},
+	{"panic during", CodeInternal},
+	{"unexpected panic", CodeInternal},
+	{"failed to", CodeInternal},
+}
+
+// Code returns the code of the most specific FormatError in the chain of
+// wrapped errors, which is the one describing the root cause.
+func (e *FormatError) Code() ErrorCode {
+	cause := e
+	for {
+		var inner *FormatError
+		if cause.Original == nil || !errors.As(cause.Original, &inner) {
+			break
+		}
+		cause = inner
+	}
+	return classifyMessage(cause.Msg)
+}
+
+// classifyMessage returns the error code for a FormatError message
+func classifyMessage(msg string) ErrorCode {
+	for _, entry := range errorCodePrefixes {
+		if strings.HasPrefix(msg, entry.prefix) {
+			return entry.code
+		}
+	}
+	switch {
+	case strings.HasSuffix(msg, "cannot be nil"):
+		return CodeInvalidArgument
+	case strings.Contains(msg, " must "):
+		return CodeInvalidConfig
+	case strings.HasPrefix(msg, "XLSX"):
+		return CodeInvalidArgument
+	}
+	return CodeUnknown
+}
+
+// MessageCatalog translates FormatError messages into another language.
+// It is used by FormatError.Localize, so error codes and the English text
+// returned by Error stay unchanged for programmatic matching.
+type MessageCatalog interface {
+	// Message returns the localized form of an English message, or false to
+	// keep the English message. Messages may contain details such as record
+	// numbers, so catalogs usually match on the code or a message prefix.
+	Message(code ErrorCode, msg string) (string, bool)
+
+	// Position returns the localized phrase appended to messages that carry
+	// an input position, such as "at position 12".
+	Position(position int) string
+}
+
+// TranslatorFunc adapts a translator function to a MessageCatalog.
+// Positions are kept in English.
+//
+// Example:
+//
+//	catalog := TranslatorFunc(func(code ErrorCode, msg string) (string, bool) {
+//	    if code == CodeSyntax {
+//	        return "JSONの構文が正しくありません", true
+//	    }
+//	    return "", false
+//	})
+//	fmt.Println(formatErr.Localize(catalog))
+type TranslatorFunc func(code ErrorCode, msg string) (string, bool)
+
+// Message calls the function.
+func (f TranslatorFunc) Message(code ErrorCode, msg string) (string, bool) {
+	return f(code, msg)
+}
+
+// Position returns the English position phrase.
+func (f TranslatorFunc) Position(position int) string {
+	return fmt.Sprintf("at position %d", position)
+}
+
+// Localize returns the error message translated by the catalog. Every error in
+// the chain of wrapped FormatErrors is translated separately, and wrapped errors
+// of other types keep their own messages. A nil catalog returns Error().
+func (e *FormatError) Localize(catalog MessageCatalog) string {
+	if catalog == nil {
+		return e.Error()
+	}
+
+	msg := e.Msg
+	if translated, ok := catalog.Message(classifyMessage(e.Msg), e.Msg); ok {
+		msg = translated
+	}
+	if e.File != "" {
+		msg = e.File + ": " + msg
+	}
+	if e.Position > 0 {
+		msg += " " + catalog.Position(e.Position)
+	}
+
+	if e.Original != nil {
+		var inner *FormatError
+		if errors.As(e.Original, &inner) {
+			return msg + ": " + inner.Localize(catalog)
+		}
+		return msg + ": " + e.Original.Error()
+	}
+	return msg
+}
+
+// LocalizeError returns the localized message of the first FormatError in the
+// chain of err, or err.Error() when there is none.
+func LocalizeError(err error, catalog MessageCatalog) string {
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) {
+		return formatErr.Localize(catalog)
+	}
+	return err.Error()
+}
+
+// MarshalJSON encodes the error as a JSON object with the fields "code",
+// "message", "position", "line", "column", "path", and "file", so services can
+// return errors to clients as structured data. Fields with unknown values are omitted.
+//
+// Example output:
+//
+//	{"code":"syntax","message":"invalid JSON input at position 6: ...","position":6,"line":1,"column":6,"path":["a"]}
+func (e *FormatError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code     ErrorCode `json:"code"`
+		Message  string    `json:"message"`
+		Position int       `json:"position,omitempty"`
+		Line     int       `json:"line,omitempty"`
+		Column   int       `json:"column,omitempty"`
+		Path     []string  `json:"path,omitempty"`
+		File     string    `json:"file,omitempty"`
+	}{
+		Code:     e.Code(),
+		Message:  e.Error(),
+		Position: e.Position,
+		Line:     e.Line,
+		Column:   e.Column,
+		Path:     e.Path,
+		File:     e.File,
+	})
+}
+
+// WithFilename names the input in errors, like the file name of a file
+// argument. Use it for content that arrives over a pipe, such as the buffer
+// an editor plugin sends on stdin, so errors point at the edited file.
+//
+// Example:
+//
+//	config := NewConfig(WithFilename("config/app.json"))
+//	_, err := NewFormatter(config).Format(`{"a":`)
+//	// config/app.json: malformed JSON: ...
+func WithFilename(name string) ConfigOption {
+	return func(c *Config) {
+		c.Filename = name
+	}
+}
+
+// nameError sets the file of the outermost FormatError in the chain of err
+// to the configured filename
+func (c *Config) nameError(err error) error {
+	var formatErr *FormatError
+	if c.Filename != "" && errors.As(err, &formatErr) && formatErr.File == "" {
+		formatErr.File = c.Filename
+	}
+	return err
+}
+
+// locateError adds the path and, when the input is known, the line and column
+// where formatting stopped to an error returned by run
+func (p *TokenParser) locateError(err error, input string) error {
+	formatErr, ok := err.(*FormatError)
+	if !ok {
+		return err
+	}
+	if formatErr.Path == nil && formatErr.Code() != CodeEmptyInput {
+		formatErr.Path = p.valuePath()
+	}
+
+	// Other errors stop after the last token read, but syntax errors are
+	// located at the start of the offending token that follows it
+	offset := int(p.decoder.InputOffset())
+	var syntaxErr *json.SyntaxError
+	if errors.As(formatErr, &syntaxErr) {
+		offset = skipSeparators(input, offset)
+	}
+	if input != "" && formatErr.Line == 0 {
+		formatErr.Line, formatErr.Column = lineAndColumn(input, offset)
+	}
+	return p.config.nameError(formatErr)
+}
+
+// skipSeparators returns the offset of the first byte at or after offset
+// that is neither whitespace nor a comma or colon
+func skipSeparators(input string, offset int) int {
+	for offset < len(input) && strings.IndexByte(" \t\r\n,:", input[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// lineAndColumn converts a byte offset in the input into a 1-based line and
+// column, counting columns in characters
+func lineAndColumn(input string, offset int) (int, int) {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	before := input[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return line, column
+}
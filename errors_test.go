@@ -0,0 +1,234 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFormatErrorCode(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected ErrorCode
+	}{
+		{"empty input", "", CodeEmptyInput},
+		{"syntax error", `{"a":}`, CodeSyntax},
+		{"unclosed", `{"a":1`, CodeSyntax},
+		{"too deep", strings.Repeat("[", 101) + strings.Repeat("]", 101), CodeLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := formatter.Format(tt.input)
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected FormatError, got %T: %v", err, err)
+			}
+			if code := formatErr.Code(); code != tt.expected {
+				t.Errorf("Expected code %q, got %q (%v)", tt.expected, code, err)
+			}
+		})
+	}
+}
+
+func TestFormatErrorCodeUsesRootCause(t *testing.T) {
+	err := WrapFormatError("failed to write newline and indent", NewFormatError("indentation too large (exceeds 10000 characters)"))
+	if code := err.Code(); code != CodeLimitExceeded {
+		t.Errorf("Expected %q, got %q", CodeLimitExceeded, code)
+	}
+
+	err = WrapFormatError("failed to write output", io.ErrShortWrite)
+	if code := err.Code(); code != CodeIO {
+		t.Errorf("Expected %q, got %q", CodeIO, code)
+	}
+
+	for msg, expected := range map[string]ErrorCode{
+		"input reader cannot be nil":           CodeInvalidArgument,
+		"IndentSize must not exceed 20 spaces": CodeInvalidConfig,
+		"value hook returned unsupported type": CodeHook,
+		"panic during formatting: boom":        CodeInternal,
+		"something else":                       CodeUnknown,
+	} {
+		if code := NewFormatError(msg).Code(); code != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, msg, code)
+		}
+	}
+}
+
+// japaneseCatalog is a small catalog used to test localization
+type japaneseCatalog struct{}
+
+func (japaneseCatalog) Message(code ErrorCode, msg string) (string, bool) {
+	switch {
+	case code == CodeSyntax && strings.HasPrefix(msg, "invalid JSON text in record"):
+		var record int
+		fmt.Sscanf(msg, "invalid JSON text in record %d", &record)
+		return fmt.Sprintf("レコード%dのJSONが正しくありません", record), true
+	case code == CodeSyntax:
+		return "JSONの構文が正しくありません", true
+	}
+	return "", false
+}
+
+func (japaneseCatalog) Position(position int) string {
+	return fmt.Sprintf("（位置 %d）", position)
+}
+
+func TestFormatErrorLocalize(t *testing.T) {
+	err := WrapFormatError("invalid JSON text in record 2", NewFormatErrorWithPosition("malformed JSON: unclosed objects or arrays", 7))
+
+	expected := "レコード2のJSONが正しくありません: JSONの構文が正しくありません （位置 7）"
+	if got := err.Localize(japaneseCatalog{}); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// The English message and code are unchanged
+	if !strings.HasPrefix(err.Error(), "invalid JSON text in record 2") {
+		t.Errorf("Unexpected Error(): %q", err.Error())
+	}
+	if err.Code() != CodeSyntax {
+		t.Errorf("Expected %q, got %q", CodeSyntax, err.Code())
+	}
+
+	if got := err.Localize(nil); got != err.Error() {
+		t.Errorf("Expected nil catalog to return Error(), got %q", got)
+	}
+}
+
+func TestTranslatorFunc(t *testing.T) {
+	catalog := TranslatorFunc(func(code ErrorCode, msg string) (string, bool) {
+		if code == CodeIO {
+			return "書き込みに失敗しました", true
+		}
+		return "", false
+	})
+
+	err := WrapFormatErrorWithPosition("failed to write output", 3, io.ErrShortWrite)
+	expected := "書き込みに失敗しました at position 3: short write"
+	if got := err.Localize(catalog); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	wrapped := fmt.Errorf("request failed: %w", err)
+	if got := LocalizeError(wrapped, catalog); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := LocalizeError(io.EOF, catalog); got != "EOF" {
+		t.Errorf("Expected plain error message, got %q", got)
+	}
+}
+
+func TestFormatErrorLocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+		path   []string
+	}{
+		{"missing value", "{\n  \"a\": }", 2, 8, []string{"a"}},
+		{"bad literal in array", `{"a":[1,2,{"b":tru}]}`, 1, 16, []string{"a", "2", "b"}},
+		{"multibyte characters", "{\"名前\":\n  [1,}", 2, 6, []string{"名前", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFormatter(DefaultConfig()).Format(tt.input)
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("Expected FormatError, got %T: %v", err, err)
+			}
+			if formatErr.Line != tt.line || formatErr.Column != tt.column {
+				t.Errorf("Expected %d:%d, got %d:%d", tt.line, tt.column, formatErr.Line, formatErr.Column)
+			}
+			if strings.Join(formatErr.Path, ".") != strings.Join(tt.path, ".") {
+				t.Errorf("Expected path %v, got %v", tt.path, formatErr.Path)
+			}
+		})
+	}
+}
+
+func TestFormatErrorMarshalJSON(t *testing.T) {
+	_, err := NewFormatter(DefaultConfig()).Format("{\n  \"a\": }")
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatalf("Unexpected error: %v", marshalErr)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	if decoded["code"] != "syntax" || decoded["line"] != float64(2) || decoded["column"] != float64(8) {
+		t.Errorf("Unexpected fields: %s", data)
+	}
+	if decoded["message"] != err.Error() {
+		t.Errorf("Expected message %q, got %v", err.Error(), decoded["message"])
+	}
+	if path, _ := decoded["path"].([]interface{}); len(path) != 1 || path[0] != "a" {
+		t.Errorf("Expected path [a], got %v", decoded["path"])
+	}
+
+	// Unknown locations are omitted
+	data, _ = json.Marshal(NewFormatError("input JSON string is empty"))
+	expected := `{"code":"empty_input","message":"input JSON string is empty"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestWithFilename(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithFilename("config/app.json")))
+	tests := []struct {
+		name   string
+		format func() error
+		prefix string
+	}{
+		{
+			name:   "Format",
+			format: func() error { _, err := formatter.Format(`{"a":`); return err },
+			prefix: "config/app.json: malformed JSON",
+		},
+		{
+			name:   "empty input",
+			format: func() error { _, err := formatter.Format(""); return err },
+			prefix: "config/app.json: input JSON string is empty",
+		},
+		{
+			name:   "FormatTo",
+			format: func() error { return formatter.FormatTo(io.Discard, strings.NewReader(`[1,]`)) },
+			prefix: "config/app.json: invalid JSON",
+		},
+		{
+			name:   "FormatSeq",
+			format: func() error { _, err := formatter.FormatSeq("\x1e[1]\n\x1e[\n"); return err },
+			prefix: "config/app.json: invalid JSON text in record 2: malformed JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.format()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.prefix) {
+				t.Fatalf("Expected an error starting with %q, got %v", tt.prefix, err)
+			}
+			if strings.Count(err.Error(), "config/app.json") != 1 {
+				t.Errorf("Expected the filename once, got %q", err.Error())
+			}
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) || formatErr.File != "config/app.json" {
+				t.Errorf("Expected File to be set, got %+v", formatErr)
+			}
+			data, _ := json.Marshal(formatErr)
+			if !strings.Contains(string(data), `"file":"config/app.json"`) {
+				t.Errorf("Expected the file in the JSON form, got %s", data)
+			}
+		})
+	}
+}
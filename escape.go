@@ -0,0 +1,77 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Masks for scanning eight bytes at a time (SWAR: SIMD within a register)
+const (
+	swarOnes  = 0x0101010101010101
+	swarHighs = 0x8080808080808080
+)
+
+// swarHasZero reports whether any byte of x is zero
+func swarHasZero(x uint64) bool {
+	return (x-swarOnes)&^x&swarHighs != 0
+}
+
+// swarHasByte reports whether any byte of x equals b
+func swarHasByte(x uint64, b byte) bool {
+	return swarHasZero(x ^ (swarOnes * uint64(b)))
+}
+
+// swarHasLess reports whether any byte of x is less than b (b must be at most 128)
+func swarHasLess(x uint64, b byte) bool {
+	return (x-swarOnes*uint64(b))&^x&swarHighs != 0
+}
+
+// needsEscape reports whether s would be changed by JSON string escaping.
+// It scans eight bytes per step for quotes, backslashes, control characters,
+// the HTML-sensitive characters escaped by encoding/json, and non-ASCII bytes,
+// so the common case of plain ASCII text is checked without allocating.
+func needsEscape(s string) bool {
+	nonASCII := false
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		x := uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+			uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+		if high := x & swarHighs; high != 0 {
+			// Multi-byte UTF-8 is validated below; replace those bytes with 'A' for the ASCII checks
+			nonASCII = true
+			mask := (high >> 7) * 0xff
+			x = x&^mask | mask&(swarOnes*'A')
+		}
+		if swarHasLess(x, 0x20) || swarHasByte(x, '"') || swarHasByte(x, '\\') ||
+			swarHasByte(x, '<') || swarHasByte(x, '>') || swarHasByte(x, '&') {
+			return true
+		}
+	}
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			nonASCII = true
+			continue
+		}
+		if c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			return true
+		}
+	}
+
+	// encoding/json replaces invalid UTF-8 and escapes the JavaScript line separators
+	return nonASCII && (!utf8.ValidString(s) || strings.ContainsRune(s, '\u2028') || strings.ContainsRune(s, '\u2029'))
+}
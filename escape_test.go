@@ -0,0 +1,74 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// marshalChanges reports whether encoding/json escapes anything in s
+func marshalChanges(s string) bool {
+	encoded, _ := json.Marshal(s)
+	return string(encoded) != `"`+s+`"`
+}
+
+func TestNeedsEscape(t *testing.T) {
+	tests := []string{
+		"",
+		"plain",
+		"exactly8",
+		"longer plain ASCII text without specials",
+		`quote " inside`,
+		`back\slash`,
+		"tab\there",
+		"newline at end of a long string\n",
+		"<html>",
+		"a & b",
+		"日本語のテキスト",
+		"mixed 日本語 and ASCII text",
+		"line\u2028separator",
+		"paragraph\u2029separator",
+		"invalid \xff utf8",
+		"\x7f delete is fine",
+		strings.Repeat("a", 63) + `"`,
+	}
+
+	for _, s := range tests {
+		if result, expected := needsEscape(s), marshalChanges(s); result != expected {
+			t.Errorf("needsEscape(%q) = %t, expected %t", s, result, expected)
+		}
+	}
+}
+
+func TestNeedsEscapeRandom(t *testing.T) {
+	alphabet := []rune("abcXYZ019 \"\\<>&\t\n\x00\x1f\x7féあ😀\u2028")
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		runes := make([]rune, rng.Intn(40))
+		for j := range runes {
+			// Bias towards plain letters, so that most strings need no escaping
+			if rng.Intn(4) > 0 {
+				runes[j] = alphabet[rng.Intn(6)]
+			} else {
+				runes[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+		}
+		s := string(runes)
+		if result, expected := needsEscape(s), marshalChanges(s); result != expected {
+			t.Fatalf("needsEscape(%q) = %t, expected %t", s, result, expected)
+		}
+	}
+}
+
+func TestEscapeStringFastPath(t *testing.T) {
+	parser := &TokenParser{config: DefaultConfig()}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := parser.escapeString("a plain string that needs no escaping"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations for plain strings, got %v", allocs)
+	}
+}
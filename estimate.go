@@ -0,0 +1,445 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EstimateFormattedSize returns the size in bytes of the output of Format for
+// the input and configuration, without formatting it. The input is scanned
+// once without decoding it into tokens or writing any output, so servers can
+// cheaply decide whether to pretty-print, stream, or refuse a document.
+// A nil config uses the default configuration.
+//
+// The size is exact when the layout only depends on the depth of the values.
+// With options that lay out values depending on their contents, such as
+// WithMaxWidth, WithAdaptiveCompaction, WithCompactPaths, or
+// WithNamespaceGrouping, or hiding default values, it is an upper bound: the
+// size of the output with every object and array expanded. Options that
+// replace values or add display-only text, such as value hooks, key renames,
+// and raw paths, cannot be estimated and are reported as errors, the latter
+// unless WithStrictJSON is set.
+//
+// Example:
+//
+//	size, err := EstimateFormattedSize(body, config)
+//	if err == nil && size > 10<<20 {
+//	    // Stream the document instead of pretty-printing it
+//	}
+func EstimateFormattedSize(input string, cfg *Config) (int, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if err := validateConfig(cfg); err != nil {
+		return 0, err
+	}
+	if cfg.PostProcess != nil || len(cfg.ValueHooks) > 0 || cfg.UnicodeNormalization != nil || len(cfg.EmbeddedJSON) > 0 || len(cfg.KeyRenames) > 0 || len(cfg.RawPaths) > 0 || (!cfg.StrictJSON && (len(cfg.SectionComments) > 0 || cfg.HardWrap > 0 ||
+		cfg.ShowMissingRequired || cfg.DefaultFolding == FoldDefaultsAnnotate || cfg.OverflowSummaryKeys > 0 || len(cfg.Provenance) > 0 || cfg.SpanDurations || cfg.WideObjectKeys > 0 || cfg.ExpandBudget > 0 || cfg.SampleSize > 0 || cfg.BidiIsolation || cfg.QuoteStyle != nil || cfg.HeaderComment)) {
+		return 0, cfg.nameError(NewFormatError("cannot estimate the size with value hooks, Unicode normalization, key renames, raw paths, a post-processor, or display-only options"))
+	}
+	if input == "" {
+		return 0, cfg.nameError(NewFormatError("input JSON string is empty"))
+	}
+	input, err := cfg.decodeInput(input)
+	if err != nil {
+		return 0, cfg.nameError(err)
+	}
+
+	e := &sizeEstimator{
+		config: cfg,
+		input:  input,
+		bound: cfg.MaxWidth > 0 || len(cfg.WidthByDepth) > 0 || cfg.AdaptiveCompaction || len(cfg.CompactKeys) > 0 ||
+			len(cfg.CompactPaths) > 0 || len(cfg.ExpandedPaths) > 0 || cfg.NamespaceSeparators != "" ||
+			(cfg.Schema != nil && cfg.DefaultFolding == FoldDefaultsHide),
+	}
+	if err := e.run(); err != nil {
+		return 0, cfg.nameError(err)
+	}
+	return e.size, nil
+}
+
+// sizeEstimator scans a JSON document and adds up the size of its formatted
+// output, following the layout rules of TokenParser
+type sizeEstimator struct {
+	config *Config
+	input  string
+	pos    int
+	bound  bool // Whether every container is sized as expanded, for an upper bound
+
+	size          int
+	tokens        int
+	open          []estimatedContainer
+	topLevelStart int
+}
+
+// estimatedContainer is an object or array being scanned
+type estimatedContainer struct {
+	array   bool
+	compact bool
+	empty   bool
+}
+
+// run scans the whole input
+func (e *sizeEstimator) run() error {
+	e.skipSpace()
+	if e.pos == len(e.input) {
+		return NewFormatError("input contains no valid JSON tokens")
+	}
+	if err := e.value(); err != nil {
+		return err
+	}
+	for len(e.open) > 0 {
+		e.skipSpace()
+		if e.pos == len(e.input) {
+			return NewFormatError("malformed JSON: unclosed objects or arrays")
+		}
+		top := &e.open[len(e.open)-1]
+		c := e.input[e.pos]
+		switch {
+		case c == ']' && top.array, c == '}' && !top.array:
+			e.pos++
+			if err := e.closeContainer(); err != nil {
+				return err
+			}
+			continue
+		case !top.empty && c != ',':
+			return e.syntaxError("after element")
+		case !top.empty:
+			e.pos++
+			e.skipSpace()
+		}
+		if err := e.element(); err != nil {
+			return err
+		}
+	}
+	e.skipSpace()
+	if e.pos < len(e.input) {
+		return e.syntaxError("after top-level value")
+	}
+	return nil
+}
+
+// element scans an array element, or an object member, with its prefix
+func (e *sizeEstimator) element() error {
+	top := &e.open[len(e.open)-1]
+	e.prefix(top)
+	top.empty = false
+	if top.array {
+		return e.value()
+	}
+
+	if e.pos == len(e.input) || e.input[e.pos] != '"' {
+		return e.syntaxError("looking for beginning of object key string")
+	}
+	if err := e.countToken(); err != nil {
+		return err
+	}
+	size, err := e.scanString()
+	if err != nil {
+		return err
+	}
+	e.size += size + 1 // Followed by the colon
+	e.skipSpace()
+	if e.pos == len(e.input) || e.input[e.pos] != ':' {
+		return e.syntaxError("after object key")
+	}
+	e.pos++
+	e.skipSpace()
+	e.size++ // The space after the colon
+	return e.value()
+}
+
+// prefix adds the separator and line break written before an element of the
+// container, as TokenParser.writeElementPrefix does
+func (e *sizeEstimator) prefix(top *estimatedContainer) {
+	if top.compact || !e.config.LeadingCommas {
+		if !top.empty {
+			e.size++
+		}
+	}
+	if top.compact {
+		if !top.empty {
+			e.size++
+		}
+		return
+	}
+
+	depth := len(e.open)
+	if depth == 1 && !top.empty {
+		if top.array {
+			if size := e.config.BlankLineElementSize; size > 0 && e.size-e.topLevelStart >= size {
+				e.size++
+			}
+		} else if e.config.BlankLineBetweenTopLevelKeys {
+			e.size++
+		}
+	}
+	if e.bound && e.config.NamespaceSeparators != "" && !top.array && !top.empty {
+		e.size++ // A blank line between namespaces at most
+	}
+	e.size += 1 + e.indentWidth(depth)
+	if e.config.LeadingCommas && !top.empty {
+		e.size += 2
+	}
+	if depth == 1 {
+		e.topLevelStart = e.size
+	}
+}
+
+// indentWidth returns the width of the indentation inside the outermost
+// depth open containers
+func (e *sizeEstimator) indentWidth(depth int) int {
+	levels := 0
+	for _, container := range e.open[:depth] {
+		if container.array {
+			levels += e.config.ArrayIndent.levels()
+		} else {
+			levels += e.config.ObjectIndent.levels()
+		}
+	}
+	if e.config.UseTab {
+		return levels
+	}
+	return levels * e.config.IndentSize
+}
+
+// value scans a value, opening a container for objects and arrays
+func (e *sizeEstimator) value() error {
+	if e.pos == len(e.input) {
+		return NewFormatError("invalid JSON input: unexpected EOF")
+	}
+	if err := e.countToken(); err != nil {
+		return err
+	}
+	switch c := e.input[e.pos]; c {
+	case '{', '[':
+		e.pos++
+		return e.openContainer(c == '[')
+	case '"':
+		size, err := e.scanString()
+		e.size += size
+		return err
+	case 't':
+		return e.scanLiteral("true", 4)
+	case 'f':
+		return e.scanLiteral("false", 5)
+	case 'n':
+		return e.scanLiteral("null", 4)
+	default:
+		return e.scanNumber()
+	}
+}
+
+// countToken counts a token against the token limit
+func (e *sizeEstimator) countToken() error {
+	e.tokens++
+	if e.config.MaxTokens > 0 && e.tokens > e.config.MaxTokens {
+		return NewFormatError("JSON structure too complex or malformed (too many tokens)")
+	}
+	return nil
+}
+
+// openContainer adds the opening delimiter of an object or array
+func (e *sizeEstimator) openContainer(array bool) error {
+	depth := len(e.open)
+	if depth >= maxDepth {
+		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
+	}
+	compact := false
+	if !e.bound {
+		compact = depth > 0 && e.open[depth-1].compact || e.config.CompactDepth > 0 && depth+1 >= e.config.CompactDepth
+	}
+	e.open = append(e.open, estimatedContainer{array: array, compact: compact, empty: true})
+	e.size++
+	e.skipSpace()
+	return nil
+}
+
+// closeContainer adds the closing delimiter of the innermost container
+func (e *sizeEstimator) closeContainer() error {
+	if err := e.countToken(); err != nil {
+		return err
+	}
+	top := e.open[len(e.open)-1]
+	e.open = e.open[:len(e.open)-1]
+	e.size++
+	if !top.compact && !(top.empty && e.config.StyleVersion >= StyleV2) {
+		e.size += 1 + e.indentWidth(len(e.open))
+	}
+	return nil
+}
+
+// scanLiteral scans true, false, or null, whose output size is their length
+func (e *sizeEstimator) scanLiteral(literal string, size int) error {
+	if !strings.HasPrefix(e.input[e.pos:], literal) {
+		return e.scanSpecialFloat()
+	}
+	e.pos += len(literal)
+	e.size += size
+	return nil
+}
+
+// scanSpecialFloat scans NaN or an infinity, written according to the
+// special float policy
+func (e *sizeEstimator) scanSpecialFloat() error {
+	for _, special := range specialLiterals {
+		if !strings.HasPrefix(e.input[e.pos:], special.literal) || isIdentifierByte(e.input, e.pos+len(special.literal)) {
+			continue
+		}
+		switch e.config.SpecialFloats {
+		case SpecialFloatsAsNull:
+			e.size += len("null")
+		case SpecialFloatsAsString:
+			e.size += len(specialFloatName(special.value)) + 2
+		default:
+			return e.syntaxError("looking for beginning of value")
+		}
+		e.pos += len(special.literal)
+		return nil
+	}
+	return e.syntaxError("looking for beginning of value")
+}
+
+// scanNumber scans a number, which is written as its float64 value
+func (e *sizeEstimator) scanNumber() error {
+	start := e.pos
+	if e.pos < len(e.input) && e.input[e.pos] == '-' {
+		e.pos++
+	}
+	if e.pos < len(e.input) && (e.input[e.pos] == 'I' || e.input[e.pos] == 'N') {
+		e.pos = start
+		return e.scanSpecialFloat()
+	}
+	digits := e.skipDigits()
+	if digits == 0 || (digits > 1 && e.input[e.pos-digits] == '0') {
+		return e.syntaxError("in numeric literal")
+	}
+	if e.pos < len(e.input) && e.input[e.pos] == '.' {
+		e.pos++
+		if e.skipDigits() == 0 {
+			return e.syntaxError("after decimal point in numeric literal")
+		}
+	}
+	if e.pos < len(e.input) && (e.input[e.pos] == 'e' || e.input[e.pos] == 'E') {
+		e.pos++
+		if e.pos < len(e.input) && (e.input[e.pos] == '+' || e.input[e.pos] == '-') {
+			e.pos++
+		}
+		if e.skipDigits() == 0 {
+			return e.syntaxError("in exponent of numeric literal")
+		}
+	}
+
+	value, err := strconv.ParseFloat(e.input[start:e.pos], 64)
+	if err != nil {
+		return WrapFormatErrorWithPosition("invalid JSON input", start, err)
+	}
+	e.size += formattedFloatSize(value)
+	return nil
+}
+
+// skipDigits skips decimal digits and returns how many there were
+func (e *sizeEstimator) skipDigits() int {
+	start := e.pos
+	for e.pos < len(e.input) && e.input[e.pos] >= '0' && e.input[e.pos] <= '9' {
+		e.pos++
+	}
+	return e.pos - start
+}
+
+// formattedFloatSize returns the length of a number as written by
+// TokenParser.formatNumber, which uses the encoding of encoding/json
+func formattedFloatSize(value float64) int {
+	var buffer [32]byte
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b := strconv.AppendFloat(buffer[:0], value, format, -1, 64)
+	if n := len(b); format == 'e' && n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+		// Exponents are written without a leading zero, as in 1e-7
+		return n - 1
+	}
+	return len(b)
+}
+
+// scanString scans a string and returns its size when escaped again by
+// TokenParser.escapeString, including the quotes
+func (e *sizeEstimator) scanString() (int, error) {
+	start := e.pos
+	e.pos++
+	escaped := false
+	for {
+		if e.pos == len(e.input) {
+			return 0, NewFormatError("invalid JSON input: unexpected EOF")
+		}
+		c := e.input[e.pos]
+		if c == '"' {
+			break
+		}
+		if c < 0x20 {
+			return 0, e.syntaxError("in string literal")
+		}
+		if c == '\\' {
+			escaped = true
+			e.pos++
+		}
+		e.pos++
+	}
+	e.pos++
+
+	raw := e.input[start+1 : e.pos-1]
+	if !escaped && !needsEscape(raw) {
+		return len(raw) + 2, nil
+	}
+	// Strings with escapes or characters that are escaped on output are rare,
+	// so they are decoded and encoded again
+	var value string
+	if err := json.Unmarshal([]byte(e.input[start:e.pos]), &value); err != nil {
+		return 0, WrapFormatErrorWithPosition("invalid JSON input", start, err)
+	}
+	if !needsEscape(value) {
+		return len(value) + 2, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0, WrapFormatError("failed to escape string for JSON output", err)
+	}
+	return len(encoded), nil
+}
+
+// skipSpace skips insignificant whitespace
+func (e *sizeEstimator) skipSpace() {
+	for e.pos < len(e.input) {
+		switch e.input[e.pos] {
+		case ' ', '\t', '\n', '\r':
+			e.pos++
+		default:
+			return
+		}
+	}
+}
+
+// syntaxError reports an unexpected character at the current position
+func (e *sizeEstimator) syntaxError(context string) error {
+	if e.pos == len(e.input) {
+		return NewFormatErrorWithPosition("invalid JSON input: unexpected EOF", e.pos)
+	}
+	return NewFormatErrorWithPosition("invalid JSON input: invalid character "+strconv.QuoteRune(rune(e.input[e.pos]))+" "+context, e.pos)
+}
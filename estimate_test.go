@@ -0,0 +1,148 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+var estimateInputs = []string{
+	`{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{},"none":[]}`,
+	`[1.0, 1e3, 1E+2, -0, 1e-7, 0.000001, 123456789012345678901234, 3.14159, -2.5e-10]`,
+	`{"escaped":"line\nbreak \"quoted\" é \/","html":"<a href='x'>&amp;</a>","unicode":"日本語  ","key\twith\ttabs":true}`,
+	`"root string"`,
+	`42`,
+	`null`,
+	`[[[[[]]]],{},[{"a":[{"b":{}}]}]]`,
+	`  {"padded" :  [ true , false , null ] }  `,
+}
+
+func TestEstimateFormattedSizeExact(t *testing.T) {
+	configs := map[string]*Config{
+		"default":        DefaultConfig(),
+		"expanded":       NewConfig(WithCompactDepth(0)),
+		"compact":        NewConfig(WithCompactDepth(1)),
+		"tabs":           NewConfig(WithTabs(), WithCompactDepth(2)),
+		"indent 4":       NewConfig(WithIndentSize(4)),
+		"indent 0":       NewConfig(WithIndentSize(0), WithCompactDepth(0)),
+		"aligned":        NewConfig(WithObjectIndent(IndentAligned), WithArrayIndent(IndentDouble), WithCompactDepth(0)),
+		"leading commas": NewConfig(WithLeadingCommas(), WithCompactDepth(2)),
+		"style v2":       NewConfig(WithStyleVersion(StyleV2), WithCompactDepth(0)),
+		"blank lines":    NewConfig(WithBlankLineBetweenTopLevelKeys(), WithBlankLineBetweenLargeElements(10)),
+		"key order":      NewConfig(WithKeyOrderPolicy(KeyOrderScalarsFirstSorted), WithSortedKeys("*")),
+	}
+
+	for name, config := range configs {
+		for i, input := range estimateInputs {
+			expected, err := NewFormatter(config).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			size, err := EstimateFormattedSize(input, config)
+			if err != nil {
+				t.Fatalf("%s/%d: Unexpected error: %v", name, i, err)
+			}
+			if size != len(expected) {
+				t.Errorf("%s/%d: expected size %d, got %d for:\n%s", name, i, len(expected), size, expected)
+			}
+		}
+	}
+}
+
+func TestEstimateFormattedSizeBound(t *testing.T) {
+	configs := map[string]*Config{
+		"width":        NewConfig(WithMaxWidth(20)),
+		"wide":         NewConfig(WithMaxWidth(200), WithCompactDepth(1)),
+		"adaptive":     NewConfig(WithAdaptiveCompaction()),
+		"compact keys": NewConfig(WithCompactKeys("users"), WithMaxWidth(30)),
+		"compact path": NewConfig(WithCompactPaths("meta"), WithCompactDepth(0), WithIndentSize(0)),
+		"namespaces":   NewConfig(WithNamespaceGrouping(""), WithLeadingCommas()),
+	}
+
+	for name, config := range configs {
+		for i, input := range estimateInputs {
+			formatted, err := NewFormatter(config).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// The same layout with every container expanded, and no blank lines between namespaces
+			expandedConfig := config.clone()
+			expandedConfig.CompactDepth = 0
+			expandedConfig.MaxWidth = 0
+			expandedConfig.AdaptiveCompaction = false
+			expandedConfig.CompactKeys = nil
+			expandedConfig.CompactPaths = nil
+			expandedConfig.NamespaceSeparators = ""
+			expanded, err := NewFormatter(expandedConfig).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			size, err := EstimateFormattedSize(input, config)
+			if err != nil {
+				t.Fatalf("%s/%d: Unexpected error: %v", name, i, err)
+			}
+			if size < len(formatted) {
+				t.Errorf("%s/%d: size %d is below the formatted size %d", name, i, size, len(formatted))
+			}
+			if size < len(expanded) || size > len(expanded)+len(input) {
+				t.Errorf("%s/%d: size %d is not close to the expanded size %d", name, i, size, len(expanded))
+			}
+		}
+	}
+}
+
+func TestEstimateFormattedSizeSpecialFloats(t *testing.T) {
+	input := `[NaN, -Infinity, {"a": Infinity}]`
+	for _, policy := range []SpecialFloatPolicy{SpecialFloatsAsNull, SpecialFloatsAsString} {
+		config := NewConfig(WithSpecialFloats(policy))
+		expected, err := NewFormatter(config).Format(input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		size, err := EstimateFormattedSize(input, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if size != len(expected) {
+			t.Errorf("Expected size %d, got %d", len(expected), size)
+		}
+	}
+}
+
+func TestEstimateFormattedSizeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		config *Config
+		errMsg string
+	}{
+		{"empty", "", nil, "input JSON string is empty"},
+		{"whitespace", "  ", nil, "no valid JSON tokens"},
+		{"unclosed", `{"a":[1`, nil, "unclosed objects or arrays"},
+		{"trailing comma", `[1,]`, nil, "invalid character ']'"},
+		{"missing colon", `{"a" 1}`, nil, "after object key"},
+		{"leading zero", `[01]`, nil, "in numeric literal"},
+		{"trailing data", `{} x`, nil, "after top-level value"},
+		{"bad literal", `[nul]`, nil, "invalid character 'n'"},
+		{"NaN rejected", `[NaN]`, nil, "invalid character 'N'"},
+		{"out of range", `[1e400]`, nil, "invalid JSON input"},
+		{"too deep", strings.Repeat("[", 101), nil, "too deeply nested"},
+		{"too many tokens", `[1,2,3]`, NewConfig(WithMaxTokens(3)), "too many tokens"},
+		{"value hooks", `{}`, NewConfig(WithRedaction("a")), "value hooks"},
+		{"raw paths", `{}`, NewConfig(WithRawPaths("a")), "raw paths"},
+		{"display only", `{}`, NewConfig(WithSectionComments("a")), "display-only options"},
+		{"invalid config", `{}`, &Config{IndentSize: -1}, "IndentSize must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EstimateFormattedSize(tt.input, tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+
+	if _, err := EstimateFormattedSize(`{}`, NewConfig(WithSectionComments("a"), WithStrictJSON())); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// configDescriptions describes each Config field for ExplainConfig
+var configDescriptions = map[string]string{
+	"IndentSize":                   "Specifies the number of spaces to use for each level of indentation.",
+	"UseTab":                       "Determines whether to use tabs instead of spaces for indentation.",
+	"CompactDepth":                 "Specifies the depth at which elements should be formatted on a single line.",
+	"ObjectIndent":                 "Selects how far the members of objects are indented relative to the line of the opening brace.",
+	"ArrayIndent":                  "Selects how far the elements of arrays are indented relative to the line of the opening bracket.",
+	"LeadingCommas":                "Places the commas between expanded elements at the start of continuation lines instead of the end of the previous line.",
+	"BlankLineBetweenTopLevelKeys": "Separates the members of the root object with blank lines.",
+	"BlankLineElementSize":         "Separates an element of the root array from the previous one with a blank line when the previous element took at least this many bytes of output.",
+	"SectionComments":              "Lists path.Match patterns of root object keys that are preceded by a banner comment.",
+	"MaxWidth":                     "Specifies the maximum line width for compactly formatted elements.",
+	"WidthByDepth":                 "Overrides MaxWidth for compact elements at specific depths.",
+	"CompactPaths":                 "The path patterns of containers written on one line regardless of CompactDepth.",
+	"ExpandedPaths":                "The path patterns of containers whose contents are written one element or member per line regardless of CompactDepth.",
+	"Width":                        "Measures the display width of lines against MaxWidth and WidthByDepth.",
+	"HardWrap":                     "Breaks output lines wider than this many columns into continuation lines, unless StrictJSON is set.",
+	"SpecialFloats":                "Controls how NaN and Infinity literals in lenient input are handled.",
+	"Encoding":                     "Controls how input that starts with a byte order mark is handled.",
+	"MaxKeyLength":                 "The maximum key length in characters reported by FormatWithWarnings.",
+	"KeyPattern":                   "The naming pattern keys must match, reported by FormatWithWarnings.",
+	"ForbiddenKeys":                "Lists keys reported by FormatWithWarnings.",
+	"Schema":                       "JSON Schema used by schema-aware display options.",
+	"ShowMissingRequired":          "Shows required properties missing from objects as comments.",
+	"DefaultFolding":               "Selects how properties whose value equals the schema default are shown.",
+	"PostProcess":                  "Function called for each output line before it is written.",
+	"AdaptiveCompaction":           "Writes objects in an array on one line only when all elements are objects sharing the same key set.",
+	"CompactKeys":                  "The keys of containers whose elements or member values are each written on one line.",
+	"OverflowSummaryKeys":          "The number of members written for compact objects that do not fit within the width limit, followed by an ellipsis.",
+	"StyleVersion":                 "Selects the layout rules.",
+	"StrictJSON":                   "Guarantees valid JSON output by ignoring display-only options that write comments or ellipses.",
+	"HeaderComment":                "Prefixes the output with a metadata comment line, unless StrictJSON is set.",
+	"HeaderSource":                 "The source filename recorded in the header comment.",
+	"Filename":                     "Names the input in errors, for content that does not come from a file.",
+	"MaxTokens":                    "The number of tokens above which a document is rejected, or 0 for no limit.",
+	"VerifyIdempotence":            "Makes Format verify that formatting its output again does not change it.",
+	"ValueHooks":                   "Functions called for every scalar value before it is written, which may replace it.",
+	"AnonymizationSalt":            "The secret that anonymized fakes are derived from together with the original values.",
+	"Provenance":                   "Maps value paths to the names of the documents that supplied them, which are written as comments after the values unless StrictJSON is set.",
+	"KeyOrder":                     "Maps path patterns to the order of the members of the objects at matching paths.",
+	"KeyOrderPolicy":               "Orders the members of objects without a key order in KeyOrder.",
+	"SortedKeys":                   "The path patterns of objects whose members are sorted by key.",
+	"KeyRenames":                   "Maps the path patterns of members to the keys written in place of their own.",
+	"NamespaceSeparators":          "The characters that end the namespace of a key.",
+	"EmbeddedJSON":                 "The path patterns of string values that are written as the JSON object or array they hold, directly or encoded in base64.",
+	"RawPaths":                     "The path patterns of values copied from the input verbatim.",
+	"WideObjectKeys":               "The number of members after which the rest of the members of an object are replaced by their count, or 0 to write all members.",
+	"ExpandBudget":                 "The number of expanded lines written for each top-level member before the rest of its contents are replaced by their counts, or 0 to write all lines.",
+	"SampleSize":                   "The number of elements written of arrays with more elements, chosen at random, or 0 to write all elements.",
+	"SampleSeed":                   "Seeds the random choice of the elements written when SampleSize is set.",
+	"QuoteStyle":                   "Renders keys and strings with other quotes unless StrictJSON is set.",
+	"BidiIsolation":                "Wraps string values with right-to-left text in Unicode isolate controls unless StrictJSON is set.",
+	"UnicodeNormalization":         "Normalizes object keys and string values before they are written, or nil to keep them as they are.",
+	"TimeBudget":                   "The time after which formatting stops and the output is truncated, or formatting fails if StrictJSON is set.",
+	"Degradation":                  "The ladder of ways Format tries in order, each used only for documents that fit its budgets, or nil to format every document as configured.",
+	"CheckpointTokens":             "The minimum number of tokens read between checkpoints passed to Checkpoint.",
+	"Checkpoint":                   "Saves the checkpoints of FormatTo and ResumeFormatTo.",
+	"FlushPolicy":                  "Sets when streamed output is flushed to the writer.",
+	"RecordWorkers":                "The number of records FormatRecords, or documents FormatBatch, formats concurrently.",
+	"IsolateRecordErrors":          "Makes FormatRecords replace records that cannot be formatted with a marker instead of stopping.",
+	"RecordErrorMarker":            "Renders the records replaced because of IsolateRecordErrors.",
+	"SpanDurations":                "Writes the duration of OpenTelemetry spans as a comment after their endTimeUnixNano unless StrictJSON is set.",
+	"LogFields":                    "The fields of log records written in order at the start of each line by FormatLog.",
+	"LogColors":                    "Maps log levels in lower case to the color names of the lines written by FormatLog.",
+	"LogProjection":                "Holds the paths of the fields kept in the lines written by FormatLog.",
+	"LogFoldHidden":                "Writes the number of fields left out by LogProjection at the end of each line.",
+	"LogFilter":                    "Holds the predicates that records must match to be written by FormatLog.",
+	"LogSummary":                   "Holds the fields whose values are counted in the footer that FormatLog writes after the records.",
+	"Faults":                       "Selects failures injected into formatting for tests of code that uses the formatter.",
+}
+
+// OptionDoc documents a Config field with its effective value, as returned
+// by DescribeConfig.
+type OptionDoc struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value"`
+	Default     interface{} `json:"default"`
+	Source      string      `json:"source"`
+	Description string      `json:"description"`
+}
+
+// DescribeConfig documents every setting returned by ResolveConfig with its
+// description and default value. Functions, schemas, and patterns are given
+// by a placeholder or their source text, so every value can be encoded as
+// JSON.
+func DescribeConfig(settings []ConfigSetting) []OptionDoc {
+	defaults := reflect.ValueOf(DefaultConfig()).Elem()
+	docs := make([]OptionDoc, len(settings))
+	for i, s := range settings {
+		docs[i] = OptionDoc{
+			Name:        s.Name,
+			Value:       explainValue(reflect.ValueOf(s.Value)),
+			Source:      s.Source.String(),
+			Description: configDescriptions[s.Name],
+		}
+		if field := defaults.FieldByName(s.Name); field.IsValid() {
+			docs[i].Default = explainValue(field)
+		}
+	}
+	return docs
+}
+
+// ExplainConfig renders the settings returned by ResolveConfig as a JSON
+// array of OptionDoc, formatted with the default configuration, so the
+// effective configuration of a team serves as living documentation of its
+// style.
+//
+// Example:
+//
+//	_, settings, _ := ResolveConfig(nil, fileOptions, nil, nil)
+//	doc, err := ExplainConfig(settings)
+//	// [
+//	//   {
+//	//     "name": "IndentSize",
+//	//     "value": 4,
+//	//     "default": 2,
+//	//     "source": "file",
+//	//     "description": "Specifies the number of spaces to use for each level of indentation."
+//	//   },
+//	//   ...
+func ExplainConfig(settings []ConfigSetting) (string, error) {
+	data, err := json.Marshal(DescribeConfig(settings))
+	if err != nil {
+		return "", WrapFormatError("failed to encode options", err)
+	}
+	return NewFormatter(DefaultConfig()).Format(string(data))
+}
+
+// explainValue returns a setting in a form that can be encoded as JSON
+func explainValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	switch v := value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case *regexp.Regexp:
+		if v == nil {
+			return nil
+		}
+		return v.String()
+	case *Schema:
+		if v == nil {
+			return nil
+		}
+		return "(schema)"
+	}
+	switch value.Kind() {
+	case reflect.Func:
+		if value.IsNil() {
+			return nil
+		}
+		return "(function)"
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Func {
+			break
+		}
+		if value.Len() == 0 {
+			return nil
+		}
+		functions := make([]interface{}, value.Len())
+		for i := range functions {
+			functions[i] = explainValue(value.Index(i))
+		}
+		return functions
+	}
+	return value.Interface()
+}
@@ -0,0 +1,88 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestConfigDescriptions(t *testing.T) {
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		if configDescriptions[fields.Field(i).Name] == "" {
+			t.Errorf("Config.%s has no description", fields.Field(i).Name)
+		}
+	}
+	if len(configDescriptions) != fields.NumField() {
+		t.Errorf("Expected %d descriptions, got %d", fields.NumField(), len(configDescriptions))
+	}
+}
+
+func TestDescribeConfig(t *testing.T) {
+	_, settings, err := ResolveConfig(nil, []ConfigOption{
+		WithIndentSize(4),
+		WithKeyPattern(regexp.MustCompile(`^[a-z]+$`)),
+		WithTimeBudget(time.Second),
+		WithPostProcess(func(line string, depth int, path string) string { return line }),
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := map[string]OptionDoc{}
+	for _, doc := range DescribeConfig(settings) {
+		docs[doc.Name] = doc
+	}
+	tests := []struct {
+		name     string
+		value    interface{}
+		defValue interface{}
+		source   string
+	}{
+		{"IndentSize", 4, 2, "file"},
+		{"UseTab", false, false, "default"},
+		{"KeyPattern", "^[a-z]+$", nil, "file"},
+		{"TimeBudget", "1s", "0s", "file"},
+		{"PostProcess", "(function)", nil, "file"},
+		{"ValueHooks", nil, nil, "default"},
+	}
+	for _, tt := range tests {
+		doc := docs[tt.name]
+		if doc.Value != tt.value || doc.Default != tt.defValue || doc.Source != tt.source {
+			t.Errorf("%s: expected %v (default %v, %s), got %v (default %v, %s)", tt.name, tt.value, tt.defValue, tt.source, doc.Value, doc.Default, doc.Source)
+		}
+		if doc.Description == "" {
+			t.Errorf("%s: missing description", tt.name)
+		}
+	}
+}
+
+func TestExplainConfig(t *testing.T) {
+	_, settings, err := ResolveConfig(nil, nil, nil, []ConfigOption{WithIndentSize(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ExplainConfig(settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[
+  {
+    "name": "IndentSize",
+    "value": 4,
+    "default": 2,
+    "source": "flag",
+    "description": "` + configDescriptions["IndentSize"] + `"
+  },`
+	if result[:len(expected)] != expected {
+		t.Errorf("Unexpected output:\n%s", result[:len(expected)])
+	}
+	var docs []OptionDoc
+	if err := json.Unmarshal([]byte(result), &docs); err != nil {
+		t.Fatalf("Output is not JSON: %v", err)
+	}
+	if len(docs) != len(settings) {
+		t.Errorf("Expected %d options, got %d", len(settings), len(docs))
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrInjectedFault is the error of the writes that fail because of
+// Faults.WriteErrorAt.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults selects failures injected into formatting with WithFaults, so that
+// programs embedding the formatter can test their error handling against
+// the errors it returns for broken writers, cut off input, and corrupted
+// documents. Offsets and indexes start at 1, and the zero value injects
+// nothing.
+type Faults struct {
+	// WriteErrorAt is the offset of the first output byte whose write fails
+	// with ErrInjectedFault. It applies to the methods that write to an
+	// io.Writer, such as FormatTo, FormatSeqTo, and FormatLogTo.
+	WriteErrorAt int
+
+	// TruncateInputAt is the offset of the first input byte that is not
+	// read, as if the input ended there. It applies to Format, FormatBytes,
+	// and FormatTo.
+	TruncateInputAt int
+
+	// CorruptTokenAt is the index of the token of the document that is
+	// replaced with a closing delimiter that does not match it.
+	CorruptTokenAt int
+}
+
+// WithFaults injects failures into formatting. It is meant for tests of
+// code that uses the formatter and should not be set in production.
+//
+// Example:
+//
+//	formatter := NewFormatter(NewConfig(WithFaults(Faults{WriteErrorAt: 100})))
+//	err := formatter.FormatTo(w, r)
+//	// errors.Is(err, ErrInjectedFault) once 99 bytes have been written
+func WithFaults(faults Faults) ConfigOption {
+	return func(c *Config) {
+		if faults.valid() {
+			c.Faults = faults
+		}
+	}
+}
+
+// valid reports whether the offsets and indexes are not negative
+func (f Faults) valid() bool {
+	return f.WriteErrorAt >= 0 && f.TruncateInputAt >= 0 && f.CorruptTokenAt >= 0
+}
+
+// writer returns w, or a writer failing at WriteErrorAt
+func (f Faults) writer(w io.Writer) io.Writer {
+	if f.WriteErrorAt == 0 {
+		return w
+	}
+	return &faultyWriter{w: w, remaining: f.WriteErrorAt - 1}
+}
+
+// reader returns r, or a reader ending at TruncateInputAt
+func (f Faults) reader(r io.Reader) io.Reader {
+	if f.TruncateInputAt == 0 {
+		return r
+	}
+	return io.LimitReader(r, int64(f.TruncateInputAt-1))
+}
+
+// token returns the token read with the index, or a mismatched closing
+// delimiter at CorruptTokenAt
+func (f Faults) token(token json.Token, index int) json.Token {
+	if index != f.CorruptTokenAt {
+		return token
+	}
+	if token == json.Delim(']') {
+		return json.Delim('}')
+	}
+	return json.Delim(']')
+}
+
+// faultyWriter writes the bytes before the failing offset and fails after
+type faultyWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (w *faultyWriter) Write(data []byte) (int, error) {
+	if len(data) <= w.remaining {
+		n, err := w.w.Write(data)
+		w.remaining -= n
+		return n, err
+	}
+	n, err := w.w.Write(data[:w.remaining])
+	w.remaining -= n
+	if err != nil {
+		return n, err
+	}
+	return n, ErrInjectedFault
+}
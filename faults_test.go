@@ -0,0 +1,69 @@
+package jsonformat
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFaultsWriteError(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithFaults(Faults{WriteErrorAt: 6})))
+	var output bytes.Buffer
+	err := formatter.FormatTo(&output, strings.NewReader(`{"a":[1,2]}`))
+	if !errors.Is(err, ErrInjectedFault) {
+		t.Fatalf("Expected ErrInjectedFault, got %v", err)
+	}
+	if output.String() != "{\n  \"" {
+		t.Errorf("Expected the bytes before the fault, got %q", output.String())
+	}
+
+	output.Reset()
+	if err := formatter.FormatSeqTo(&output, strings.NewReader("\x1e{\"a\":1}\n")); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Expected ErrInjectedFault from FormatSeqTo, got %v", err)
+	}
+}
+
+func TestFaultsTruncateInput(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithFaults(Faults{TruncateInputAt: 8})))
+	if _, err := formatter.Format(`{"a":[1,2]}`); err == nil {
+		t.Error("Expected an error for truncated input")
+	}
+	var output bytes.Buffer
+	if err := formatter.FormatTo(&output, strings.NewReader(`{"a":[1,2]}`)); err == nil {
+		t.Error("Expected an error for truncated input from FormatTo")
+	}
+
+	// Input ending before the fault is formatted as usual
+	if _, err := formatter.Format(`[1]`); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFaultsCorruptToken(t *testing.T) {
+	tests := []struct {
+		input string
+		index int
+	}{
+		{`{"a":[1,2]}`, 1},
+		{`{"a":[1,2]}`, 4},
+		{`[[1],[2]]`, 4},
+	}
+	for _, tt := range tests {
+		formatter := NewFormatter(NewConfig(WithFaults(Faults{CorruptTokenAt: tt.index})))
+		if result, err := formatter.Format(tt.input); err == nil {
+			t.Errorf("%s at %d: expected an error, got %q", tt.input, tt.index, result)
+		}
+	}
+}
+
+func TestWithFaultsIgnoresNegative(t *testing.T) {
+	config := NewConfig(WithFaults(Faults{WriteErrorAt: -1}))
+	if config.Faults != (Faults{}) {
+		t.Errorf("Expected negative offsets to be ignored, got %+v", config.Faults)
+	}
+	config.Faults.CorruptTokenAt = -1
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected an error for a negative token index")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileOption is a functional option for FormatFile.
+type FileOption func(*fileOptions)
+
+// fileOptions holds the settings of FormatFile
+type fileOptions struct {
+	dryRun       bool
+	backupSuffix string // Suffix of the backup file name, or empty for no backup
+	workers      int    // Number of files formatted concurrently by FormatFiles, or 0 for GOMAXPROCS
+	encoding     string // Encoding of the written file, or empty for the encoding of the original
+	bom          bool   // Whether UTF-8 output starts with a byte order mark
+}
+
+// WithDryRun makes FormatFile leave the file unchanged and report the changes
+// it would make as a unified diff in FileResult.Diff, so wrappers can show
+// previews and CI can post patch suggestions.
+//
+// Example:
+//
+//	result, err := formatter.FormatFile("config.json", WithDryRun())
+//	if result.Changed {
+//	    fmt.Print(result.Diff)
+//	}
+func WithDryRun() FileOption {
+	return func(o *fileOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithBackup makes FormatFile keep the original content of a file it changes
+// in a file named by appending the suffix, ".orig" if empty. An existing
+// backup is replaced.
+//
+// Example:
+//
+//	// config.json.orig keeps the original content
+//	result, err := formatter.FormatFile("config.json", WithBackup(""))
+func WithBackup(suffix string) FileOption {
+	return func(o *fileOptions) {
+		if suffix == "" {
+			suffix = ".orig"
+		}
+		o.backupSuffix = suffix
+	}
+}
+
+// WithOutputEncoding makes FormatFile write the file in the encoding, one of
+// "UTF-8", "UTF-16LE", "UTF-16BE", "UTF-32LE", or "UTF-32BE", matched without
+// regard to case, for consumers that demand it. Formatting itself always
+// works on UTF-8. UTF-16 and UTF-32 files start with a byte order mark.
+// By default a file keeps the encoding and byte order mark it had, as
+// detected from its byte order mark.
+//
+// Example:
+//
+//	// Convert a UTF-16 file exported on Windows to plain UTF-8
+//	result, err := formatter.FormatFile("export.json", WithOutputEncoding("UTF-8"))
+func WithOutputEncoding(encoding string) FileOption {
+	return func(o *fileOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithByteOrderMark makes FormatFile start UTF-8 files with a byte order
+// mark, which some Windows tools need to recognize the encoding.
+//
+// Example:
+//
+//	result, err := formatter.FormatFile("config.json", WithByteOrderMark())
+func WithByteOrderMark() FileOption {
+	return func(o *fileOptions) {
+		o.bom = true
+	}
+}
+
+// FileResult describes the outcome of FormatFile.
+type FileResult struct {
+	// Path is the formatted file.
+	Path string
+
+	// Changed reports whether the formatted content differs from the file.
+	Changed bool
+
+	// Written reports whether the file was replaced with the formatted
+	// content, which is when it changed outside dry-run mode.
+	Written bool
+
+	// Diff is the unified diff from the file to the formatted content when
+	// WithDryRun is given, and empty otherwise, when nothing changes, or when
+	// only the encoding changes.
+	Diff string
+
+	// Err is the error that prevented formatting the file, and Duration is
+	// the time formatting took. They are only set in the results of FormatFiles.
+	Err      error
+	Duration time.Duration
+}
+
+// FormatFile formats a JSON file in place. The formatted content ends with
+// a line feed, and the file is only written when it changes.
+//
+// The file is replaced atomically: the content is written to a temporary
+// file in the same directory, synced, and renamed over the original, so a
+// crash never leaves a truncated file. The permissions and, where the
+// platform allows, the owner of the original are kept. Symbolic links are
+// followed, so the link stays and its target is replaced.
+//
+// Example:
+//
+//	result, err := formatter.FormatFile("config.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if result.Changed {
+//	    fmt.Println("formatted", result.Path)
+//	}
+func (f *Formatter) FormatFile(path string, options ...FileOption) (*FileResult, error) {
+	var settings fileOptions
+	for _, option := range options {
+		option(&settings)
+	}
+
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return nil, WrapFormatError("failed to read "+path, err)
+	}
+	encoding, bom, err := settings.outputEncoding(string(input))
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := f.Format(string(input))
+	if err != nil {
+		return nil, err
+	}
+	output := encodeText(formatted+"\n", encoding, bom)
+
+	result := &FileResult{Path: path, Changed: output != string(input)}
+	if !result.Changed {
+		return result, nil
+	}
+	if settings.dryRun {
+		// Compare the text, as Format read it
+		text, _ := f.config.decodeInput(string(input))
+		result.Diff = unifiedDiff(path, path, text, formatted+"\n")
+		return result, nil
+	}
+
+	if err := writeFileAtomic(path, []byte(output), settings.backupSuffix); err != nil {
+		return nil, err
+	}
+	result.Written = true
+	return result, nil
+}
+
+// outputEncoding returns the encoding of the written file and whether it
+// starts with a byte order mark, for a file with the input
+func (o *fileOptions) outputEncoding(input string) (string, bool, error) {
+	encoding, _ := detectEncoding(input)
+	bom := encoding != ""
+	if o.encoding != "" {
+		encoding, bom = "", false
+		for _, name := range outputEncodings {
+			if strings.EqualFold(o.encoding, name) {
+				encoding = name
+			}
+		}
+		if encoding == "" {
+			return "", false, NewFormatError(fmt.Sprintf("unknown output encoding %q", o.encoding))
+		}
+	}
+	return encoding, bom || o.bom, nil
+}
+
+// writeFileAtomic replaces the content of a file by renaming a temporary
+// file over it, after linking or copying the original to a backup
+func writeFileAtomic(path string, data []byte, backupSuffix string) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return WrapFormatError("failed to read "+path, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return WrapFormatError("failed to read "+path, err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp*")
+	if err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+	done := false
+	defer func() {
+		if !done {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.Write(data); err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+	if err := temp.Sync(); err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+	// The mode is set last, as changing the owner clears set-user-ID bits
+	if err := copyOwner(temp, info); err != nil {
+		return WrapFormatError("failed to write "+path+": cannot keep the owner", err)
+	}
+	if err := temp.Chmod(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)); err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+
+	if backupSuffix != "" {
+		if err := backupFile(target, target+backupSuffix); err != nil {
+			return WrapFormatError("failed to write backup of "+path, err)
+		}
+	}
+	if err := os.Rename(temp.Name(), target); err != nil {
+		return WrapFormatError("failed to write "+path, err)
+	}
+	done = true
+
+	// Persist the rename; not every platform can sync a directory
+	if dir, err := os.Open(filepath.Dir(target)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// backupFile makes backup a copy of the file, as a hard link when possible,
+// so the backup keeps the permissions and owner of the original
+func backupFile(path, backup string) error {
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	destination, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+	if err := destination.Sync(); err != nil {
+		destination.Close()
+		return err
+	}
+	return destination.Close()
+}
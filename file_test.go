@@ -0,0 +1,221 @@
+package jsonformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile writes content into a file in a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	writeTo(t, path, content)
+	return path
+}
+
+// writeTo writes content into the file at path
+func writeTo(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// readFile returns the content of a file
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestFormatFile(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	path := writeFile(t, "config.json", `{"a":[1,2]}`)
+
+	result, err := formatter.FormatFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n"
+	if got := readFile(t, path); got != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, got)
+	}
+	if !result.Changed || !result.Written || result.Diff != "" || result.Path != path {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+
+	// Formatting again finds nothing to change
+	result, err = formatter.FormatFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Changed {
+		t.Error("Expected a formatted file to be unchanged")
+	}
+}
+
+func TestFormatFileDryRun(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	input := "{\n  \"name\": \"Alice\",\n  \"tags\": [\"a\"]\n}\n"
+	path := writeFile(t, "config.json", input)
+
+	result, err := formatter.FormatFile(path, WithDryRun())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readFile(t, path); got != input {
+		t.Errorf("Expected the file to be unchanged, got:\n%s", got)
+	}
+	expected := "--- " + path + "\n+++ " + path + "\n" +
+		"@@ -1,4 +1,6 @@\n" +
+		" {\n" +
+		"   \"name\": \"Alice\",\n" +
+		"-  \"tags\": [\"a\"]\n" +
+		"+  \"tags\": [\n" +
+		"+    \"a\"\n" +
+		"+  ]\n" +
+		" }\n"
+	if !result.Changed || result.Written || result.Diff != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result.Diff)
+	}
+}
+
+func TestFormatFileErrors(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+
+	_, err := formatter.FormatFile(filepath.Join(t.TempDir(), "missing.json"))
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Code() != CodeIO {
+		t.Errorf("Expected an I/O error, got %v", err)
+	}
+
+	input := `{"a":`
+	path := writeFile(t, "broken.json", input)
+	if _, err := formatter.FormatFile(path); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+	if got := readFile(t, path); got != input {
+		t.Errorf("Expected the file to be unchanged, got:\n%s", got)
+	}
+}
+
+func TestFormatFileEncoding(t *testing.T) {
+	formatted := "[\n  1\n]\n"
+	tests := []struct {
+		name     string
+		input    string
+		options  []FileOption
+		expected string
+	}{
+		{"UTF-8", `[1]`, nil, formatted},
+		{"UTF-8 BOM kept", "\xEF\xBB\xBF[1]", nil, "\xEF\xBB\xBF" + formatted},
+		{"UTF-16LE kept", encodeUTF16(`[1]`, binary.LittleEndian), nil, encodeUTF16(formatted, binary.LittleEndian)},
+		{"UTF-32BE kept", encodeUTF32(`[1]`, binary.BigEndian), nil, encodeUTF32(formatted, binary.BigEndian)},
+		{"to UTF-8", encodeUTF16(`[1]`, binary.LittleEndian), []FileOption{WithOutputEncoding("utf-8")}, formatted},
+		{"to UTF-16BE", `[1]`, []FileOption{WithOutputEncoding("UTF-16BE")}, encodeUTF16(formatted, binary.BigEndian)},
+		{"BOM added", `[1]`, []FileOption{WithByteOrderMark()}, "\xEF\xBB\xBF" + formatted},
+		{"BOM removed", "\xEF\xBB\xBF" + formatted, []FileOption{WithOutputEncoding("UTF-8")}, formatted},
+	}
+
+	formatter := NewFormatter(DefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "config.json", tt.input)
+			result, err := formatter.FormatFile(path, tt.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := readFile(t, path); got != tt.expected {
+				t.Errorf("Expected:\n%q\n\nGot:\n%q", tt.expected, got)
+			}
+			if result.Changed != (tt.input != tt.expected) {
+				t.Errorf("Expected Changed to be %v", tt.input != tt.expected)
+			}
+		})
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		input := encodeUTF16(formatted, binary.LittleEndian)
+		path := writeFile(t, "config.json", input)
+		result, err := formatter.FormatFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Changed {
+			t.Error("Expected an unchanged UTF-16 file")
+		}
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		path := writeFile(t, "config.json", `[1]`)
+		_, err := formatter.FormatFile(path, WithOutputEncoding("Shift_JIS"))
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) || formatErr.Code() != CodeInvalidArgument {
+			t.Errorf("Expected an invalid argument error, got %v", err)
+		}
+	})
+}
+
+func TestFormatFileBackup(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	input := `{"a":1}`
+	path := writeFile(t, "config.json", input)
+	if err := os.WriteFile(path+".orig", []byte("stale"), 0o600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+
+	if _, err := formatter.FormatFile(path, WithBackup("")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := readFile(t, path+".orig"); got != input {
+		t.Errorf("Expected the backup to hold the original content, got:\n%s", got)
+	}
+	if got := readFile(t, path); got != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Expected the file to be formatted, got:\n%s", got)
+	}
+
+	// Unchanged files are not backed up
+	if _, err := formatter.FormatFile(path, WithBackup(".bak")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup for an unchanged file, got %v", err)
+	}
+}
+
+func TestFormatFileAtomicWrite(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	target := writeFile(t, "target.json", `[1]`)
+	link := filepath.Join(t.TempDir(), "link.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+
+	if _, err := formatter.FormatFile(link); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symbolic link to stay, got %v (%v)", info.Mode(), err)
+	}
+	if got := readFile(t, target); got != "[\n  1\n]\n" {
+		t.Errorf("Expected the link target to be formatted, got:\n%s", got)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(target))
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the formatted file, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// flushClock returns the current time when checking the flush interval
+var flushClock = time.Now
+
+// FlushPolicy sets when streamed output is flushed to the writer. Output is
+// held in a buffer of fixed size, and each flush blocks until the writer
+// accepts the data, so formatting into a slow sink, such as a network
+// connection or a pager, waits for it rather than buffering without bound.
+// The zero value keeps the defaults: FormatTo flushes when its 64 KiB
+// buffer is full, and FormatSeqTo, FormatRecordsTo, and FormatLogTo flush
+// after every record.
+type FlushPolicy struct {
+	// Records flushes after every record: each record of FormatSeqTo and
+	// FormatRecordsTo, each line of FormatLogTo, and each top-level member or
+	// element of FormatTo
+	Records bool
+
+	// Bytes is the size of the buffer, which is flushed once full, or 0
+	// for 64 KiB when Records or Interval is set
+	Bytes int
+
+	// Interval flushes the buffer when output is written this long after
+	// the last flush, or 0 to flush regardless of time
+	Interval time.Duration
+}
+
+// WithFlushPolicy sets when FormatTo, FormatSeqTo, FormatRecordsTo, and
+// FormatLogTo flush their output to the writer. A policy with a negative Bytes or Interval is
+// ignored.
+//
+// Example:
+//
+//	// Send each record to the pager at once, and at least every second
+//	config := NewConfig(WithFlushPolicy(FlushPolicy{Records: true, Interval: time.Second}))
+func WithFlushPolicy(policy FlushPolicy) ConfigOption {
+	return func(c *Config) {
+		if policy.Bytes >= 0 && policy.Interval >= 0 {
+			c.FlushPolicy = policy
+		}
+	}
+}
+
+// flushWriter buffers streamed output and flushes it according to a policy
+type flushWriter struct {
+	*bufio.Writer
+	policy    FlushPolicy
+	lastFlush time.Time
+}
+
+// newFlushWriter returns a writer to w that flushes according to the policy,
+// or according to the default policy if the policy is the zero value
+func newFlushWriter(w io.Writer, policy, defaultPolicy FlushPolicy) *flushWriter {
+	if policy == (FlushPolicy{}) {
+		policy = defaultPolicy
+	}
+	size := policy.Bytes
+	if size == 0 {
+		size = outputChunkSize
+	}
+	return &flushWriter{Writer: bufio.NewWriterSize(w, size), policy: policy, lastFlush: flushClock()}
+}
+
+// WriteString buffers s, and flushes the buffer if the flush interval has passed
+func (w *flushWriter) WriteString(s string) (int, error) {
+	n, err := w.Writer.WriteString(s)
+	if err != nil || w.policy.Interval == 0 || flushClock().Sub(w.lastFlush) < w.policy.Interval {
+		return n, err
+	}
+	return n, w.Flush()
+}
+
+// Flush writes the buffered output to the writer
+func (w *flushWriter) Flush() error {
+	w.lastFlush = flushClock()
+	return w.Writer.Flush()
+}
+
+// endRecord flushes the buffer after a record if the policy says so
+func (w *flushWriter) endRecord() error {
+	if !w.policy.Records {
+		return nil
+	}
+	return w.Flush()
+}
+
+// endRecord flushes streamed output before the next top-level member or
+// element if the policy flushes per record
+func (p *TokenParser) endRecord() error {
+	if p.output == nil || p.output.writer == nil || p.capture != nil || p.isFirstElement {
+		return nil
+	}
+	if err := p.output.writer.endRecord(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRecorder records the data of each write
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestFlushPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   func(f *Formatter, w *writeRecorder) error
+		policy   FlushPolicy
+		expected []string
+	}{
+		{
+			name: "FormatTo default",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatTo(w, strings.NewReader(`[1,2,3]`))
+			},
+			expected: []string{"[\n  1,\n  2,\n  3\n]"},
+		},
+		{
+			name: "FormatTo records",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatTo(w, strings.NewReader(`[1,2,3]`))
+			},
+			policy:   FlushPolicy{Records: true},
+			expected: []string{"[\n  1", ",\n  2", ",\n  3\n]"},
+		},
+		{
+			name: "FormatTo bytes",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatTo(w, strings.NewReader(`[1,2,3]`))
+			},
+			policy:   FlushPolicy{Bytes: 8},
+			expected: []string{"[\n  1,\n ", " 2,\n  3\n", "]"},
+		},
+		{
+			name: "FormatSeqTo default",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatSeqTo(w, strings.NewReader("\x1e1\n\x1e2\n"))
+			},
+			expected: []string{"\x1e1\n", "\x1e2\n"},
+		},
+		{
+			name: "FormatSeqTo bytes",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatSeqTo(w, strings.NewReader("\x1e1\n\x1e2\n"))
+			},
+			policy:   FlushPolicy{Bytes: 1024},
+			expected: []string{"\x1e1\n\x1e2\n"},
+		},
+		{
+			name: "FormatLogTo default",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatLogTo(w, strings.NewReader("{\"msg\":\"a\"}\n{\"msg\":\"b\"}\n"))
+			},
+			expected: []string{"- - a\n", "- - b\n"},
+		},
+		{
+			name: "FormatLogTo bytes",
+			format: func(f *Formatter, w *writeRecorder) error {
+				return f.FormatLogTo(w, strings.NewReader("{\"msg\":\"a\"}\n{\"msg\":\"b\"}\n"))
+			},
+			policy:   FlushPolicy{Bytes: 1024},
+			expected: []string{"- - a\n- - b\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &writeRecorder{}
+			f := NewFormatter(NewConfig(WithCompactDepth(0), WithFlushPolicy(tt.policy)))
+			if err := tt.format(f, w); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(w.writes, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected writes:\n%q\n\nGot:\n%q", tt.expected, w.writes)
+			}
+		})
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	now := time.Unix(0, 0)
+	flushClock = func() time.Time { return now }
+	defer func() { flushClock = time.Now }()
+
+	w := &writeRecorder{}
+	f := NewFormatter(NewConfig(WithCompactDepth(0), WithFlushPolicy(FlushPolicy{Interval: time.Second}),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			// The second element takes a second to format
+			if ctx.Value == float64(2) {
+				now = now.Add(time.Second)
+			}
+			return nil, false
+		})))
+	if err := f.FormatTo(w, strings.NewReader(`[1,2,3]`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The buffer is flushed at the first write after the interval
+	expected := []string{"[\n  1,", "\n  2,\n  3\n]"}
+	if strings.Join(w.writes, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected writes:\n%q\n\nGot:\n%q", expected, w.writes)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// DefaultFolding selects how properties whose value equals the schema default are shown.
+type DefaultFolding int
+
+const (
+	// FoldDefaultsOff shows properties with default values like any other property.
+	FoldDefaultsOff DefaultFolding = iota
+	// FoldDefaultsAnnotate writes properties with default values on one line,
+	// followed by a /* default */ comment. The output is then no longer valid JSON.
+	FoldDefaultsAnnotate
+	// FoldDefaultsHide omits properties with default values.
+	FoldDefaultsHide
+)
+
+// WithDefaultFolding folds properties whose value equals the default in the
+// schema set with WithSchema, so reviews focus on what deviates from the
+// defaults. FoldDefaultsAnnotate is meant for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithSchema(schema), WithDefaultFolding(FoldDefaultsAnnotate))
+//	// {
+//	//   "host": "example.com",
+//	//   "port": 80 /* default */,
+//	//   "tls": {"enabled": false} /* default */
+//	// }
+func WithDefaultFolding(folding DefaultFolding) ConfigOption {
+	return func(c *Config) {
+		if folding >= FoldDefaultsOff && folding <= FoldDefaultsHide {
+			c.DefaultFolding = folding
+		}
+	}
+}
+
+// foldState buffers a property whose value may equal its schema default
+type foldState struct {
+	key          string
+	defaultValue interface{}
+	tokens       []json.Token // Value tokens read so far
+	depth        int          // Nesting depth within the value
+}
+
+// startFold begins buffering the property if the token is a key whose
+// schema has a default value
+func (p *TokenParser) startFold(token json.Token) bool {
+	if p.skipFold {
+		p.skipFold = false
+		return false
+	}
+	key, ok := token.(string)
+	folding := p.config.DefaultFolding
+	if folding == FoldDefaultsAnnotate && p.config.StrictJSON {
+		folding = FoldDefaultsOff
+	}
+	if !ok || folding == FoldDefaultsOff || !p.expectingKey || p.isInArray() || len(p.path) == 0 {
+		return false
+	}
+	schema := p.path[len(p.path)-1].schema
+	if schema == nil || schema.Properties[key] == nil || !schema.Properties[key].HasDefault {
+		return false
+	}
+	p.fold = &foldState{key: key, defaultValue: schema.Properties[key].Default}
+	return true
+}
+
+// foldToken buffers a value token and folds or replays the property once
+// the value is complete
+func (p *TokenParser) foldToken(token json.Token) error {
+	fold := p.fold
+	fold.tokens = append(fold.tokens, token)
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			fold.depth++
+		} else {
+			fold.depth--
+		}
+	}
+	if fold.depth > 0 {
+		return nil
+	}
+	p.fold = nil
+
+	value, _ := tokensToValue(fold.tokens)
+	if !reflect.DeepEqual(value, fold.defaultValue) {
+		// Not a default value, so format the property as usual
+		p.skipFold = true
+		return p.replayTokens(append([]json.Token{fold.key}, fold.tokens...))
+	}
+
+	if p.config.DefaultFolding == FoldDefaultsHide {
+		// The property counts as present for required property checks
+		p.recordKey(fold.key)
+		return nil
+	}
+	if err := p.emitToken(fold.key); err != nil {
+		return err
+	}
+	compact, err := p.compactTokens(fold.tokens)
+	if err != nil {
+		return err
+	}
+	return p.emitToken(RawValue(compact + " /* default */"))
+}
+
+// tokensToValue decodes a complete value from its tokens, producing the same
+// types as encoding/json decoding into an interface{}. It returns the value
+// and the remaining tokens.
+func tokensToValue(tokens []json.Token) (interface{}, []json.Token) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	switch tokens[0] {
+	case json.Delim('{'):
+		object := map[string]interface{}{}
+		rest := tokens[1:]
+		for len(rest) > 0 && rest[0] != json.Delim('}') {
+			key, _ := rest[0].(string)
+			var value interface{}
+			value, rest = tokensToValue(rest[1:])
+			object[key] = value
+		}
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+		return object, rest
+	case json.Delim('['):
+		array := []interface{}{}
+		rest := tokens[1:]
+		for len(rest) > 0 && rest[0] != json.Delim(']') {
+			var value interface{}
+			value, rest = tokensToValue(rest)
+			array = append(array, value)
+		}
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+		return array, rest
+	default:
+		return tokens[0], tokens[1:]
+	}
+}
+
+// compactTokens writes a complete value on one line in the compact style
+func (p *TokenParser) compactTokens(tokens []json.Token) (string, error) {
+	var builder strings.Builder
+	afterOpen := false // Whether the previous token opened a container
+	afterKey := false  // Whether the previous token was an object key
+	var inObject []bool
+	for _, token := range tokens {
+		isClose := token == json.Delim('}') || token == json.Delim(']')
+		if len(inObject) > 0 && !afterOpen && !afterKey && !isClose {
+			builder.WriteString(", ")
+		}
+
+		isKey := false
+		switch v := token.(type) {
+		case json.Delim:
+			builder.WriteString(v.String())
+			switch v {
+			case '{':
+				inObject = append(inObject, true)
+			case '[':
+				inObject = append(inObject, false)
+			default:
+				inObject = inObject[:len(inObject)-1]
+			}
+		case string:
+			escaped, err := p.escapeString(v)
+			if err != nil {
+				return "", WrapFormatError("failed to escape string value", err)
+			}
+			isKey = len(inObject) > 0 && inObject[len(inObject)-1] && !afterKey
+			if isKey {
+				builder.WriteString(p.quoteKey(v, escaped) + ": ")
+			} else {
+				builder.WriteString(p.quoteString(escaped))
+			}
+		case float64:
+			formatted, err := p.formatNumber(v)
+			if err != nil {
+				return "", WrapFormatError("failed to format number", err)
+			}
+			builder.WriteString(formatted)
+		case bool:
+			if v {
+				builder.WriteString("true")
+			} else {
+				builder.WriteString("false")
+			}
+		case nil:
+			builder.WriteString("null")
+		}
+
+		afterOpen = token == json.Delim('{') || token == json.Delim('[')
+		afterKey = isKey
+	}
+	return builder.String(), nil
+}
+
+// replayTokens processes buffered tokens once it is known how to format them
+func (p *TokenParser) replayTokens(tokens []json.Token) error {
+	replaying := p.replaying
+	p.replaying = true
+	defer func() { p.replaying = replaying }()
+	for _, token := range tokens {
+		if err := p.processToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+const testDefaultsSchema = `{
+  "properties": {
+    "host": {},
+    "port": {"default": 80},
+    "tls": {"default": {"enabled": false, "ciphers": ["a", "b"]}},
+    "tags": {"default": []}
+  },
+  "required": ["host", "port"]
+}`
+
+func TestDefaultFolding(t *testing.T) {
+	schema, err := ParseSchema([]byte(testDefaultsSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := `{"host":"x","port":80.0,"tls":{"ciphers":["a","b"],"enabled":false},"tags":[1],"nested":{"port":80}}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "off",
+			options: []ConfigOption{WithSchema(schema), WithCompactDepth(2)},
+			expected: `{
+  "host": "x",
+  "port": 80,
+  "tls": {"ciphers": ["a", "b"], "enabled": false},
+  "tags": [1],
+  "nested": {"port": 80}
+}`,
+		},
+		{
+			name:    "annotate",
+			options: []ConfigOption{WithSchema(schema), WithCompactDepth(2), WithDefaultFolding(FoldDefaultsAnnotate)},
+			expected: `{
+  "host": "x",
+  "port": 80 /* default */,
+  "tls": {"ciphers": ["a", "b"], "enabled": false} /* default */,
+  "tags": [1],
+  "nested": {"port": 80}
+}`,
+		},
+		{
+			name:    "hide",
+			options: []ConfigOption{WithSchema(schema), WithCompactDepth(2), WithDefaultFolding(FoldDefaultsHide), WithMissingRequired()},
+			expected: `{
+  "host": "x",
+  "tags": [1],
+  "nested": {"port": 80}
+}`,
+		},
+		{
+			name:    "without schema",
+			options: []ConfigOption{WithCompactDepth(2), WithDefaultFolding(FoldDefaultsHide)},
+			expected: `{
+  "host": "x",
+  "port": 80,
+  "tls": {"ciphers": ["a", "b"], "enabled": false},
+  "tags": [1],
+  "nested": {"port": 80}
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDefaultFoldingWarnings(t *testing.T) {
+	schema, err := ParseSchema([]byte(testDefaultsSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	formatter := NewFormatter(NewConfig(WithSchema(schema), WithDefaultFolding(FoldDefaultsHide)))
+
+	result, warnings, err := formatter.FormatWithWarnings(`{"port":80,"host":"a","tags":[{"a":1,"a":2}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "{\n  \"host\": \"a\",\n  \"tags\": [\n    {\"a\": 1, \"a\": 2}\n  ]\n}"
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarningDuplicateKey || warnings[0].String() != "tags.0.a: duplicate key \"a\"" {
+		t.Errorf("Expected only a duplicate key warning in replayed tokens, got %v", warnings)
+	}
+}
@@ -53,7 +53,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
+	"time"
+)
+
+// Limits that protect the formatter from hostile input
+const (
+	maxDepth       = 100     // Deepest nesting of objects and arrays
+	maxStringBytes = 1000000 // Largest string value in bytes
+	maxIndentWidth = 10000   // Widest indentation in characters
 )
 
 // Config holds configuration options for JSON formatting.
@@ -71,6 +80,283 @@ type Config struct {
 	// Elements at this depth or deeper will be formatted compactly without line breaks.
 	// A value of 0 disables compact formatting. Default is 3.
 	CompactDepth int
+
+	// ObjectIndent selects how far the members of objects are indented
+	// relative to the line of the opening brace. Default is IndentNested.
+	ObjectIndent ContainerIndent
+
+	// ArrayIndent selects how far the elements of arrays are indented
+	// relative to the line of the opening bracket. Default is IndentNested.
+	ArrayIndent ContainerIndent
+
+	// LeadingCommas places the commas between expanded elements at the start
+	// of continuation lines instead of the end of the previous line.
+	// Compact elements are not affected. Default is false.
+	LeadingCommas bool
+
+	// BlankLineBetweenTopLevelKeys separates the members of the root object
+	// with blank lines. Default is false.
+	BlankLineBetweenTopLevelKeys bool
+
+	// BlankLineElementSize separates an element of the root array from the
+	// previous one with a blank line when the previous element took at least
+	// this many bytes of output. A value of 0 disables it. Default is 0.
+	BlankLineElementSize int
+
+	// SectionComments lists path.Match patterns of root object keys that are
+	// preceded by a banner comment. The output is then no longer valid JSON,
+	// so this is meant for display only. Default is nil.
+	SectionComments []string
+
+	// MaxWidth specifies the maximum line width for compactly formatted elements.
+	// When a compact element would not fit on its line, it is expanded by one level
+	// and its children are formatted compactly instead.
+	// A value of 0 disables the limit. Default is 0.
+	MaxWidth int
+
+	// WidthByDepth overrides MaxWidth for compact elements at specific depths.
+	// Keys are depths and values are maximum line widths (0 disables the limit
+	// at that depth). Depths without an entry use MaxWidth. Default is nil.
+	WidthByDepth map[int]int
+
+	// CompactPaths are the path patterns of containers written on one line
+	// regardless of CompactDepth. Default is nil.
+	CompactPaths []string
+
+	// ExpandedPaths are the path patterns of containers whose contents are
+	// written one element or member per line regardless of CompactDepth.
+	// Default is nil.
+	ExpandedPaths []string
+
+	// Width measures the display width of lines against MaxWidth and
+	// WidthByDepth. Default is nil, which uses DisplayWidth.
+	Width WidthFunc
+
+	// HardWrap breaks output lines wider than this many columns into
+	// continuation lines, unless StrictJSON is set. The output is then no
+	// longer valid JSON, so this is meant for display only. A value of 0
+	// disables wrapping. Default is 0.
+	HardWrap int
+
+	// SpecialFloats controls how NaN and Infinity literals in lenient input
+	// are handled. Default is SpecialFloatsReject.
+	SpecialFloats SpecialFloatPolicy
+
+	// Encoding controls how input that starts with a byte order mark is
+	// handled. Default is EncodingTranscode.
+	Encoding EncodingPolicy
+
+	// MaxKeyLength is the maximum key length in characters reported by
+	// FormatWithWarnings. A value of 0 disables the rule. Default is 0.
+	MaxKeyLength int
+
+	// KeyPattern is the naming pattern keys must match, reported by
+	// FormatWithWarnings. Default is nil.
+	KeyPattern *regexp.Regexp
+
+	// ForbiddenKeys lists keys reported by FormatWithWarnings. Default is nil.
+	ForbiddenKeys []string
+
+	// Schema is used by schema-aware display options. Default is nil.
+	Schema *Schema
+
+	// ShowMissingRequired shows required properties missing from objects as
+	// comments. The output is then no longer valid JSON. Default is false.
+	ShowMissingRequired bool
+
+	// DefaultFolding selects how properties whose value equals the schema
+	// default are shown. Default is FoldDefaultsOff.
+	DefaultFolding DefaultFolding
+
+	// PostProcess is called for each output line before it is written.
+	// Default is nil.
+	PostProcess PostProcessFunc
+
+	// AdaptiveCompaction writes objects in an array on one line only when all
+	// elements are objects sharing the same key set. Default is false.
+	AdaptiveCompaction bool
+
+	// CompactKeys are the keys of containers whose elements or member values
+	// are each written on one line. Default is nil.
+	CompactKeys []string
+
+	// OverflowSummaryKeys is the number of members written for compact objects
+	// that do not fit within the width limit, followed by an ellipsis. Default
+	// is 0, which expands such objects instead.
+	OverflowSummaryKeys int
+
+	// StyleVersion selects the layout rules. Output for a style version never
+	// changes, so layout improvements are adopted by choosing a newer version.
+	// Default is StyleV1.
+	StyleVersion StyleVersion
+
+	// StrictJSON guarantees valid JSON output by ignoring display-only options
+	// that write comments or ellipses. Default is false.
+	StrictJSON bool
+
+	// HeaderComment prefixes the output with a metadata comment line, unless
+	// StrictJSON is set. Default is false.
+	HeaderComment bool
+
+	// HeaderSource is the source filename recorded in the header comment.
+	// Default is empty, which omits it.
+	HeaderSource string
+
+	// Filename names the input in errors, for content that does not come
+	// from a file. Default is empty.
+	Filename string
+
+	// MaxTokens is the number of tokens above which a document is rejected,
+	// or 0 for no limit. Default is 10000.
+	MaxTokens int
+
+	// VerifyIdempotence makes Format verify that formatting its output again
+	// does not change it. Default is false.
+	VerifyIdempotence bool
+
+	// ValueHooks are called for every scalar value before it is written,
+	// and may replace it. Hooks run in order. Default is nil.
+	ValueHooks []ValueHook
+
+	// AnonymizationSalt is the secret that the fakes of WithAnonymize are
+	// derived from together with the original values. Default is empty, which
+	// leaves the original values open to dictionary attacks.
+	AnonymizationSalt string
+
+	// Provenance maps value paths to the names of the documents that supplied
+	// them, which are written as comments after the values unless StrictJSON
+	// is set. Default is nil.
+	Provenance map[string]string
+
+	// KeyOrder maps path patterns to the order of the members of the objects
+	// at matching paths. Default is nil.
+	KeyOrder map[string][]string
+
+	// KeyOrderPolicy orders the members of objects without a key order in
+	// KeyOrder. Default is KeyOrderInput.
+	KeyOrderPolicy KeyOrderPolicy
+
+	// SortedKeys are the path patterns of objects whose members are sorted by
+	// key. Default is nil.
+	SortedKeys []string
+
+	// KeyRenames maps the path patterns of members to the keys written in
+	// place of their own. Default is nil.
+	KeyRenames map[string]string
+
+	// NamespaceSeparators are the characters that end the namespace of a key.
+	// Members are grouped by namespace, with blank lines between the groups of
+	// expanded objects. Default is "", which disables grouping.
+	NamespaceSeparators string
+
+	// EmbeddedJSON are the path patterns of string values that are written as
+	// the JSON object or array they hold, directly or encoded in base64.
+	// Default is nil.
+	EmbeddedJSON []string
+
+	// RawPaths are the path patterns of values copied from the input
+	// verbatim. Default is nil.
+	RawPaths []string
+
+	// WideObjectKeys is the number of members after which the rest of the
+	// members of an object are replaced by their count, or 0 to write all
+	// members. Default is 0.
+	WideObjectKeys int
+
+	// ExpandBudget is the number of expanded lines written for each
+	// top-level member before the rest of its contents are replaced by
+	// their counts, or 0 to write all lines. Default is 0.
+	ExpandBudget int
+
+	// SampleSize is the number of elements written of arrays with more
+	// elements, chosen at random, or 0 to write all elements. Default is 0.
+	SampleSize int
+
+	// SampleSeed seeds the random choice of the elements written when
+	// SampleSize is set. Default is 0.
+	SampleSeed int64
+
+	// QuoteStyle renders keys and strings with other quotes unless StrictJSON
+	// is set. Default is nil, which writes JSON quotes.
+	QuoteStyle *QuoteStyle
+
+	// BidiIsolation wraps string values with right-to-left text in Unicode
+	// isolate controls unless StrictJSON is set. Default is false.
+	BidiIsolation bool
+
+	// UnicodeNormalization normalizes object keys and string values before
+	// they are written, or nil to keep them as they are.
+	UnicodeNormalization NormalizeFunc
+
+	// TimeBudget is the time after which formatting stops and the output is
+	// truncated, or formatting fails if StrictJSON is set. 0 disables the
+	// budget. Default is 0.
+	TimeBudget time.Duration
+
+	// Degradation is the ladder of ways Format tries in order, each used
+	// only for documents that fit its budgets, or nil to format every
+	// document as configured. Default is nil.
+	Degradation []DegradationRung
+
+	// CheckpointTokens is the minimum number of tokens read between
+	// checkpoints passed to Checkpoint. Default is 0.
+	CheckpointTokens int
+
+	// Checkpoint saves the checkpoints of FormatTo and ResumeFormatTo.
+	// Default is nil, which takes no checkpoints.
+	Checkpoint CheckpointFunc
+
+	// FlushPolicy sets when streamed output is flushed to the writer.
+	// Default is the zero value, which flushes when the buffer is full, or
+	// after every record of sequences, multi-document input, and logs.
+	FlushPolicy FlushPolicy
+
+	// RecordWorkers is the number of records FormatRecords, or documents
+	// FormatBatch, formats concurrently. Values below 2 format them one at a
+	// time. Default is 0.
+	RecordWorkers int
+
+	// IsolateRecordErrors makes FormatRecords replace records that cannot be
+	// formatted with a marker instead of stopping. Default is false.
+	IsolateRecordErrors bool
+
+	// RecordErrorMarker renders the records replaced because of
+	// IsolateRecordErrors. Default is nil, which writes RecordErrorComment
+	// unless StrictJSON is set.
+	RecordErrorMarker RecordErrorMarker
+
+	// SpanDurations writes the duration of OpenTelemetry spans as a comment
+	// after their endTimeUnixNano unless StrictJSON is set. Default is false.
+	SpanDurations bool
+
+	// LogFields are the fields of log records written in order at the start
+	// of each line by FormatLog. Each field lists alternative names separated
+	// by "|". Default is nil, which uses time, level, and message fields.
+	LogFields []string
+
+	// LogColors maps log levels in lower case to the color names of the lines
+	// written by FormatLog. Default is nil, which writes no colors.
+	LogColors map[string]string
+
+	// LogProjection holds the paths of the fields kept in the lines written
+	// by FormatLog. Default is nil, which keeps all fields.
+	LogProjection []string
+
+	// LogFoldHidden writes the number of fields left out by LogProjection at
+	// the end of each line. Default is false.
+	LogFoldHidden bool
+
+	// LogFilter holds the predicates that records must match to be written
+	// by FormatLog. Default is nil, which writes all records.
+	LogFilter []LogPredicate
+
+	// LogSummary holds the fields whose values are counted in the footer that
+	// FormatLog writes after the records. Default is nil, which writes no footer.
+	LogSummary []string
+
+	// Faults selects failures injected into formatting for tests of code
+	// that uses the formatter. Default is the zero value, which injects none.
+	Faults Faults
 }
 
 // ConfigOption is a functional option for configuring the formatter.
@@ -89,6 +375,7 @@ func DefaultConfig() *Config {
 		IndentSize:   2,
 		UseTab:       false,
 		CompactDepth: 3,
+		MaxTokens:    10000,
 	}
 }
 
@@ -136,6 +423,72 @@ func validateConfig(config *Config) error {
 		return NewFormatError("CompactDepth must be non-negative")
 	}
 
+	if !config.ObjectIndent.valid() || !config.ArrayIndent.valid() {
+		return NewFormatError("ObjectIndent and ArrayIndent must be IndentNested, IndentAligned, or IndentDouble")
+	}
+	if config.KeyOrderPolicy < KeyOrderInput || config.KeyOrderPolicy > KeyOrderScalarsFirstSorted {
+		return NewFormatError("KeyOrderPolicy must be KeyOrderInput, KeyOrderScalarsFirst, or KeyOrderScalarsFirstSorted")
+	}
+	if config.SpecialFloats < SpecialFloatsReject || config.SpecialFloats > SpecialFloatsAsString {
+		return NewFormatError("SpecialFloats must be SpecialFloatsReject, SpecialFloatsAsNull, or SpecialFloatsAsString")
+	}
+	if config.Encoding < EncodingTranscode || config.Encoding > EncodingReject {
+		return NewFormatError("Encoding must be EncodingTranscode or EncodingReject")
+	}
+	if config.DefaultFolding < FoldDefaultsOff || config.DefaultFolding > FoldDefaultsHide {
+		return NewFormatError("DefaultFolding must be FoldDefaultsOff, FoldDefaultsAnnotate, or FoldDefaultsHide")
+	}
+
+	if config.BlankLineElementSize < 0 {
+		return NewFormatError("BlankLineElementSize must be non-negative")
+	}
+
+	if config.MaxKeyLength < 0 {
+		return NewFormatError("MaxKeyLength must be non-negative")
+	}
+
+	if config.StyleVersion < StyleV1 || config.StyleVersion > StyleLatest {
+		return NewFormatError("StyleVersion must be StyleV1 or StyleV2")
+	}
+
+	if config.MaxWidth < 0 {
+		return NewFormatError("MaxWidth must be non-negative")
+	}
+
+	if config.MaxTokens < 0 {
+		return NewFormatError("MaxTokens must be non-negative")
+	}
+	if config.TimeBudget < 0 {
+		return NewFormatError("TimeBudget must be non-negative")
+	}
+	for _, rung := range config.Degradation {
+		if !rung.valid() {
+			return NewFormatError("Degradation entries must have a known step and non-negative budgets")
+		}
+	}
+
+	if !config.Faults.valid() {
+		return NewFormatError("Faults offsets and indexes must be non-negative")
+	}
+
+	for depth, width := range config.WidthByDepth {
+		if depth < 0 || width < 0 {
+			return NewFormatError("WidthByDepth entries must be non-negative")
+		}
+	}
+
+	for _, predicate := range config.LogFilter {
+		if err := predicate.validate(); err != nil {
+			return NewFormatError("LogFilter entries must be valid predicates: " + err.Error())
+		}
+	}
+
+	for _, color := range config.LogColors {
+		if _, ok := logColorCodes[color]; !ok {
+			return NewFormatError("LogColors entries must be color names: " + strings.Join(logColorNames(), ", "))
+		}
+	}
+
 	return nil
 }
 
@@ -198,27 +551,99 @@ func WithCompactDepth(depth int) ConfigOption {
 	}
 }
 
+// WithLeadingCommas places commas at the start of continuation lines
+// (comma-first style), which makes a missing comma visually obvious.
+// Elements on compact lines keep trailing commas.
+//
+// Example:
+//
+//	config := NewConfig(WithLeadingCommas())
+//	// {
+//	//   "id": 1
+//	//   , "name": "x"
+//	// }
+func WithLeadingCommas() ConfigOption {
+	return func(c *Config) {
+		c.LeadingCommas = true
+	}
+}
+
+// WithMaxWidth sets the maximum line width for compactly formatted elements.
+// A compact element that would exceed the width is expanded by one level,
+// and its children are formatted compactly instead. Negative values are ignored.
+// A value of 0 disables the limit.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxWidth(100)) // Keep compact lines within 100 characters
+func WithMaxWidth(width int) ConfigOption {
+	return func(c *Config) {
+		if width >= 0 {
+			c.MaxWidth = width
+		}
+	}
+}
+
+// WithMaxTokens sets the number of tokens above which a document is rejected,
+// such as 20,001 for an object with 10,000 members, so that unexpectedly large
+// inputs fail fast. A value of 0 removes the limit, for documents known to be
+// large. Negative values are ignored. The default is 10000.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxTokens(0)) // Format documents of any size
+func WithMaxTokens(tokens int) ConfigOption {
+	return func(c *Config) {
+		if tokens >= 0 {
+			c.MaxTokens = tokens
+		}
+	}
+}
+
+// WithWidthByDepth sets maximum line widths for compact elements at specific depths.
+// Depths without an entry fall back to MaxWidth. Entries with negative depths
+// or widths are ignored. The map is copied, so later changes to it do not
+// affect the configuration.
+//
+// Example:
+//
+//	// 120 characters at depth 3, but only 80 at depth 5
+//	config := NewConfig(WithWidthByDepth(map[int]int{3: 120, 5: 80}))
+func WithWidthByDepth(widths map[int]int) ConfigOption {
+	return func(c *Config) {
+		c.WidthByDepth = make(map[int]int, len(widths))
+		for depth, width := range widths {
+			if depth >= 0 && width >= 0 {
+				c.WidthByDepth[depth] = width
+			}
+		}
+	}
+}
+
 // Formatter handles JSON formatting with custom rules.
 // It provides methods to format JSON strings and byte slices according
 // to the configured formatting options.
 type Formatter struct {
-	config *Config
+	config   *Config
+	snapshot uint64 // ID of the FrozenConfig the formatter was created from
+	rules    *compiledRules
+	ladder   []*FrozenConfig
+	cache    *Cache
+	metrics  *MetricsCollector
 }
 
 // NewFormatter creates a new Formatter with the given configuration.
 // If config is nil, it uses the default configuration.
 //
+// The formatter uses a snapshot of the configuration taken with Freeze, so
+// changing config afterwards does not affect it.
+//
 // Example:
 //
 //	config := DefaultConfig()
 //	formatter := NewFormatter(config)
 func NewFormatter(config *Config) *Formatter {
-	if config == nil {
-		config = DefaultConfig()
-	}
-	return &Formatter{
-		config: config,
-	}
+	return config.Freeze().NewFormatter()
 }
 
 // Format formats a JSON string according to the configured rules.
@@ -241,81 +666,298 @@ func NewFormatter(config *Config) *Formatter {
 //	}
 //	fmt.Println(formatted)
 func (f *Formatter) Format(jsonStr string) (result string, err error) {
+	// Registered first so that it sees the recovered result
+	if f.metrics != nil {
+		start := metricsClock()
+		defer func() { f.metrics.observe(metricsClock().Sub(start), len(jsonStr), len(result), err) }()
+	}
+
+	if len(f.config.Degradation) > 0 {
+		return f.formatDegraded(jsonStr)
+	}
+
+	// Serve repeated inputs from the cache; registered first so that it sees the recovered result.
+	// Outputs with a header are not cached, as its timestamp would be stale.
+	// Truncated outputs are not cached either, as they depend on timing.
+	truncated := false
+	if f.cache != nil && jsonStr != "" && !f.config.writesHeader() {
+		key := newCacheKey(jsonStr, f.config, f.snapshot)
+		if cached, ok := f.cache.get(key); ok {
+			return cached, nil
+		}
+		defer func() {
+			if err == nil && !truncated {
+				f.cache.put(key, result)
+			}
+		}()
+	}
+
 	// Implement panic recovery to handle unexpected errors gracefully
 	defer func() {
 		if r := recover(); r != nil {
-			switch v := r.(type) {
-			case error:
-				err = WrapFormatError("panic during formatting", v)
-			case string:
-				err = NewFormatError("panic during formatting: " + v)
-			default:
-				err = NewFormatError("unexpected panic during formatting")
-			}
+			err = recoveredError(r)
 			result = ""
 		}
 	}()
 
+	result, parser, err := f.formatString(jsonStr, nil)
+	truncated = parser != nil && parser.truncated
+	if err == nil && f.config.VerifyIdempotence && !truncated {
+		if err = f.verifyIdempotence(jsonStr, result); err != nil {
+			result = ""
+		}
+	}
+	return result, err
+}
+
+// formatString formats a JSON string. The optional setup function configures
+// the parser before formatting, and the parser is returned so that callers can
+// collect what it recorded.
+func (f *Formatter) formatString(jsonStr string, setup func(p *TokenParser)) (string, *TokenParser, error) {
 	// Validate input
 	if jsonStr == "" {
-		return "", NewFormatError("input JSON string is empty")
+		return "", nil, f.config.nameError(NewFormatError("input JSON string is empty"))
+	}
+
+	// Convert UTF-16 and UTF-32 input to UTF-8
+	jsonStr, err := f.config.decodeInput(jsonStr)
+	if err != nil {
+		return "", nil, f.config.nameError(err)
+	}
+	if err := blankInputError(jsonStr); err != nil {
+		return "", nil, f.config.nameError(err)
 	}
 
-	// Create a decoder from the input string
+	// Replace NaN and Infinity literals before decoding when the policy accepts them
+	source := jsonStr
+	var specials []specialFloat
+	if f.config.SpecialFloats != SpecialFloatsReject {
+		jsonStr, specials = replaceSpecialFloats(jsonStr)
+	}
+
+	// Collect output in a chunked buffer, so it is copied only once into the result
 	reader := strings.NewReader(jsonStr)
-	decoder := json.NewDecoder(reader)
+	var output outputBuffer
+	parser := f.newParser(f.config.Faults.reader(reader), &output, len(jsonStr))
+	parser.specials = specials
+	parser.input = jsonStr
+	parser.source = source
+	if setup != nil {
+		setup(parser)
+	}
+	if err := f.writeHeader(&output); err != nil {
+		return "", nil, err
+	}
+	if err := parser.run(func() int { return parser.calculatePosition(reader) }); err != nil {
+		return "", nil, parser.locateError(err, jsonStr)
+	}
+
+	if err := output.finishLine(); err != nil {
+		return "", nil, WrapFormatError("failed to write output", err)
+	}
+	return output.String(), parser, nil
+}
+
+// FormatTo reads a JSON document from r and writes the formatted result to w.
+// Unlike Format, the output is streamed through a buffered writer rather than
+// collected in memory, so very large documents do not require the whole output
+// to be held in a single allocation.
+//
+// If an error is returned, part of the output may already have been written to w.
+// With WithCheckpoints, formatting can continue from the last checkpoint with
+// ResumeFormatTo.
+//
+// Example:
+//
+//	file, _ := os.Open("large.json")
+//	defer file.Close()
+//	if err := formatter.FormatTo(os.Stdout, file); err != nil {
+//	    log.Fatal(err)
+//	}
+func (f *Formatter) FormatTo(w io.Writer, r io.Reader) (err error) {
+	// Count the bytes read and written; registered first so that it sees the recovered error
+	if f.metrics != nil && w != nil && r != nil {
+		start := metricsClock()
+		input := &countingReader{Reader: r}
+		output := &countingWriter{Writer: w}
+		r, w = input, output
+		defer func() { f.metrics.observe(metricsClock().Sub(start), input.n, output.n, err) }()
+	}
+
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	if w == nil {
+		return NewFormatError("output writer cannot be nil")
+	}
+	if r == nil {
+		return NewFormatError("input reader cannot be nil")
+	}
+	r, w = f.config.Faults.reader(r), f.config.Faults.writer(w)
+	if f.config.Checkpoint != nil {
+		if err := f.config.checkCheckpoints(); err != nil {
+			return err
+		}
+		if r, err = checkpointReader(r); err != nil {
+			return err
+		}
+	}
+	r, err = f.config.decodeReader(r)
+	if err != nil {
+		return f.config.nameError(err)
+	}
+
+	// Lenient special floats need the whole input to locate literals outside
+	// strings, and raw paths to copy values from it
+	var source string
+	var specials []specialFloat
+	if f.config.SpecialFloats != SpecialFloatsReject || len(f.config.RawPaths) > 0 {
+		input, err := io.ReadAll(r)
+		if err != nil {
+			return WrapFormatError("failed to read input", err)
+		}
+		source = string(input)
+		replaced := source
+		if f.config.SpecialFloats != SpecialFloatsReject {
+			replaced, specials = replaceSpecialFloats(source)
+		}
+		r = strings.NewReader(replaced)
+	}
+
+	output := outputBuffer{writer: newFlushWriter(w, f.config.FlushPolicy, FlushPolicy{})}
+	parser := f.newParser(r, &output, 0)
+	parser.specials = specials
+	parser.source = source
+	if err := f.writeHeader(&output); err != nil {
+		return err
+	}
+	if err := parser.run(func() int { return int(parser.decoder.InputOffset()) }); err != nil {
+		return parser.locateError(err, "")
+	}
 
-	// Create a string builder for output
-	var builder strings.Builder
+	if err := output.finishLine(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	if err := output.Flush(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	return nil
+}
 
-	// Create token parser with decoder and configuration
+// recoveredError converts a recovered panic value into a FormatError
+func recoveredError(r interface{}) error {
+	switch v := r.(type) {
+	case error:
+		return WrapFormatError("panic during formatting", v)
+	case string:
+		return NewFormatError("panic during formatting: " + v)
+	default:
+		return NewFormatError("unexpected panic during formatting")
+	}
+}
+
+// newParser creates a token parser that reads from r and writes to output
+func (f *Formatter) newParser(r io.Reader, output io.StringWriter, inputLength int) *TokenParser {
 	parser := &TokenParser{
-		decoder:        decoder,
+		decoder:        json.NewDecoder(r),
 		depth:          0,
 		inArray:        make([]bool, 0),
-		builder:        &builder,
+		builder:        output,
 		config:         f.config,
+		rules:          f.rules,
 		isFirstElement: true,
 		expectingKey:   false,
-		inputLength:    len(jsonStr),
+		inputLength:    inputLength,
+	}
+	if buffer, ok := output.(*outputBuffer); ok {
+		buffer.postProcess = f.config.lineProcessor(parser.width)
+		buffer.width = parser.width
+		parser.output = buffer
 	}
+	return parser
+}
 
+// run processes all tokens of the input. The position function reports the
+// approximate input position for syntax errors.
+func (p *TokenParser) run(position func() int) error {
 	// Process all tokens sequentially
-	tokenCount := 0
+	p.startBudget()
+	tokenCount := p.resumedTokens
 	for {
-		token, err := decoder.Token()
+		start := p.decoder.InputOffset()
+		token, err := p.decoder.Token()
 		if err != nil {
 			if err == io.EOF {
 				// EOF indicates we've processed all tokens successfully
+				if p.repair != nil && len(p.repair.open) > 0 {
+					if err := p.closeRepaired(); err != nil {
+						return err
+					}
+				}
 				break
 			}
 			// Calculate approximate position in input
-			position := parser.calculatePosition(reader)
-			return "", WrapFormatErrorWithPosition("invalid JSON input", position, err)
+			return WrapFormatErrorWithPosition("invalid JSON input", position(), err)
+		}
+
+		if len(p.specials) > 0 {
+			token = p.restoreSpecialFloat(token)
+		}
+		if len(p.config.RawPaths) > 0 {
+			if token, err = p.readRaw(token, start); err != nil {
+				return err
+			}
 		}
 
 		tokenCount++
-		if tokenCount > 10000 { // Prevent infinite loops with malformed JSON
-			return "", NewFormatError("JSON structure too complex or malformed (too many tokens)")
+		p.readTokens = tokenCount
+		token = p.config.Faults.token(token, tokenCount)
+		if p.tooManyTokens(tokenCount) {
+			return NewFormatError("JSON structure too complex or malformed (too many tokens)")
+		}
+		if p.overBudget(tokenCount) {
+			return p.truncate()
 		}
 
-		err = parser.processToken(token)
+		err = p.processToken(token)
 		if err != nil {
-			return "", err
+			return err
+		}
+		if err := p.saveCheckpoint(tokenCount); err != nil {
+			return err
+		}
+		if p.chunkFull() {
+			return nil
 		}
 	}
 
 	// Validate that we ended in a valid state
-	if parser.depth != 0 {
-		return "", NewFormatError("malformed JSON: unclosed objects or arrays")
+	if p.depth != 0 {
+		return NewFormatError("malformed JSON: unclosed objects or arrays")
 	}
 
 	// Validate that we have at least one token (not just whitespace)
 	if tokenCount == 0 {
-		return "", NewFormatError("input contains no valid JSON tokens")
+		// The whitespace read is still buffered by the decoder
+		var whitespace [1]byte
+		if n, _ := p.decoder.Buffered().Read(whitespace[:]); n > 0 || p.decoder.InputOffset() > 0 {
+			return NewFormatError(ErrWhitespaceInput.Error())
+		}
+		return NewFormatError("input contains no valid JSON tokens")
 	}
 
-	return builder.String(), nil
+	return nil
+}
+
+// tooManyTokens reports whether the number of tokens read, together with the
+// tokens of the embedded JSON expanded so far and without the tokens left out
+// by folds, exceeds the limit
+func (p *TokenParser) tooManyTokens(count int) bool {
+	return p.config.MaxTokens > 0 && count+p.expandedTokens-p.hiddenTokens > p.config.MaxTokens
 }
 
 // FormatBytes formats JSON bytes according to the configured rules.
@@ -349,11 +991,102 @@ type TokenParser struct {
 	decoder        *json.Decoder
 	depth          int
 	inArray        []bool // Stack to track array context at each depth
-	builder        *strings.Builder
+	builder        io.StringWriter
 	config         *Config
 	isFirstElement bool // Track if this is the first element in current context
 	expectingKey   bool // Track if we're expecting an object key next
 	inputLength    int  // Length of original input for position calculation
+
+	rules           *compiledRules // Path patterns of config, compiled when the formatter was created
+	minCompactDepth int            // Compact formatting is suppressed below this depth after a width overflow
+	capture         *captureState  // Compact element currently being measured against the width limit
+	path            []pathLevel    // Current key or index at each depth, parallel to inArray
+	specials        []specialFloat // NaN and Infinity literals replaced in lenient input, in input order
+	topLevelStart   int            // Output offset where the current top-level member or element starts
+	input           string         // Complete input when formatting a string, empty when streaming
+	source          string         // Complete input before special floats were replaced, from which raw values are copied
+	raw             *rawTracker    // Position of the tokens read, when values at raw paths are copied verbatim
+	indentation     string         // Indentation characters, of which each line writes a prefix
+
+	output      *outputBuffer // Final output, when it is an outputBuffer
+	fold        *foldState    // Property buffered until it is known whether its value equals the schema default
+	skipFold    bool          // Whether the next key is a replayed property that must not be buffered again
+	records     *recordsState // Array buffered until it is known whether its objects share a key set
+	skipRecords bool          // Whether the next array is a replayed array that must not be buffered again
+	sample      *sampleState  // Sample of the elements of an array being read
+	skipSample  bool          // Whether the next array is a replayed sample that must not be sampled again
+	homogeneous bool          // Whether the next array holds objects that share a key set
+	order       *orderState   // Object buffered until its members can be reordered
+	skipOrder   bool          // Whether the next object is a replayed object that must not be buffered again
+	hiding      bool          // Whether the tokens of a member left out of a wide object are being skipped
+	hiddenDepth int           // Nesting depth within the value of the member left out
+	budgetLines int           // Expanded lines written for the current top-level member
+	deadline    time.Time     // Time at which the time budget is spent, zero without a budget
+	truncated   bool          // Whether the output was truncated when the time budget was spent
+	degrading   bool          // Whether a spent time budget truncates the output even with StrictJSON, as a lower rung takes over
+	replaying   bool          // Whether buffered tokens are processed after their input position has passed
+
+	inputBase     int64 // Input offset of the start of the decoder's input, before it when resuming from a checkpoint
+	resumedTokens int   // Tokens read before the checkpoint formatting resumed from
+	checkpointed  int   // Tokens read when the last checkpoint was saved
+
+	readTokens     int // Tokens read by run, checked against MaxTokens again when embedded JSON is expanded
+	expandedTokens int // Tokens of expanded embedded JSON, beyond the strings holding them
+	embeddedDepth  int // Number of strings holding embedded JSON being expanded around the current token
+	hiddenTokens   int // Tokens of members and elements left out by folds, which do not count toward MaxTokens
+
+	collectingWarnings bool      // Whether warnings are recorded
+	warnings           []Warning // Recorded warnings in input order
+
+	annotating  bool         // Whether output regions are recorded
+	annotations []Annotation // Recorded output regions in document order
+	openRegions []int        // Indices of annotations for containers that are not closed yet
+	valueStart  int          // Output offset where the most recent key or value literal starts
+
+	auditing bool         // Whether values replaced by audited rules are recorded
+	audit    []AuditEntry // Values replaced by audited rules, when auditing
+
+	trace *traceRecorder // Recorder of the tokens written and the parser states, when formatting is traced
+	chunk *chunkState    // Progress of the chunk being formatted by FormatChunk
+
+	previous map[string]bool // Whether each container of the previous version, by layoutKey of its path, was written on one line
+	repair   *truncation     // Where the input of FormatRepaired was cut off, or nil
+}
+
+// pathLevel tracks the position of the current element within one container
+type pathLevel struct {
+	key    string          // Most recent key when the container is an object
+	index  int             // Index of the current element when the container is an array
+	keys   map[string]bool // Keys seen so far when the container is an object and warnings or a schema need them
+	schema *Schema         // Schema of the container, or nil
+
+	compact     bool   // Whether the contents of the container are written on one line
+	homogeneous bool   // Whether the container is an array of objects sharing a key set
+	members     int    // Members written so far when wide objects are folded
+	folded      int    // Members left out of a wide object, or elements and members left out by the expand budget
+	spanStart   int64  // startTimeUnixNano of the object when span durations are written, or 0
+	namespace   string // Namespace of the most recent key when keys are grouped by namespace
+}
+
+// parserState is a snapshot of the parser state used to re-format an element
+type parserState struct {
+	depth          int
+	inArray        []bool
+	isFirstElement bool
+	expectingKey   bool
+	path           []pathLevel
+	openRegions    []int
+}
+
+// captureState records a compact element so that it can be re-formatted
+// in expanded form when it does not fit within the width limit
+type captureState struct {
+	depth       int             // Depth at which the element was opened
+	base        int             // Output offset at which the element starts
+	annotations int             // Number of annotations recorded before the element
+	state       parserState     // Parser state before the element was opened
+	outer       io.StringWriter // Builder to write the element to once measured
+	tokens      []json.Token    // Tokens of the element, including delimiters
 }
 
 // processToken processes a single JSON token with type switching
@@ -362,10 +1095,114 @@ func (p *TokenParser) processToken(token json.Token) error {
 	if p.depth < 0 {
 		return NewFormatError("invalid parser state: negative depth")
 	}
-	if p.depth > 100 { // Prevent stack overflow with deeply nested structures
+	if p.depth > maxDepth { // Prevent stack overflow with deeply nested structures
 		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
 	}
 
+	// Strings are normalized before members are buffered, so that sorting and
+	// folding see the normalized keys
+	token = p.normalizeToken(token)
+
+	// Properties that may have default values are buffered until their value is complete
+	if p.fold != nil {
+		return p.foldToken(token)
+	}
+	if p.records != nil {
+		return p.recordToken(token)
+	}
+	if p.sample != nil {
+		return p.sampleToken(token)
+	}
+	if p.order != nil {
+		return p.orderToken(token)
+	}
+	if p.startFold(token) || p.startRecords(token) || p.startSample(token) || p.startOrder(token) {
+		return nil
+	}
+	if p.hiding {
+		p.hiddenTokens++
+		return p.hideToken(token)
+	}
+	if p.startHiding(token) || p.startBudgetHiding(token) {
+		p.hiddenTokens++
+		return nil
+	}
+	if expanded, err := p.expandEmbedded(token); expanded || err != nil {
+		return err
+	}
+	if err := p.emitFolded(token); err != nil {
+		return err
+	}
+
+	if p.collectingWarnings {
+		p.checkToken(token)
+	}
+
+	token, err := p.applyValueHooks(token)
+	if err != nil {
+		return err
+	}
+	if token, err = p.isolateBidi(token); err != nil {
+		return err
+	}
+	if token, err = p.annotateProvenance(token); err != nil {
+		return err
+	}
+	if token, err = p.annotateSpanDuration(token); err != nil {
+		return err
+	}
+
+	return p.emitToken(token)
+}
+
+// emitToken writes a token that has already passed through the value hooks
+func (p *TokenParser) emitToken(token json.Token) error {
+	if p.trace != nil {
+		defer p.trace.finish(p, p.trace.start(p, token))
+	}
+	if p.chunk != nil {
+		defer p.chunk.noteLines(p.output, p.tokenPath(token, p.tokenKind(token)), len(p.output.lineStarts))
+	}
+	var valuePath []string
+	isKey := false
+	if p.annotating {
+		if p.isValueStart(token) {
+			valuePath = p.valuePath()
+		} else if _, ok := token.(string); ok {
+			isKey = true
+		}
+	}
+
+	// The capture snapshot is taken before the array index is counted,
+	// so that replaying the element counts it only once
+	if p.capture != nil {
+		p.capture.tokens = append(p.capture.tokens, token)
+	} else if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') && p.shouldCapture(delim == '{') {
+		p.startCapture(token)
+	}
+
+	if p.isValueStart(token) && p.isInArray() && len(p.path) == len(p.inArray) {
+		p.path[len(p.path)-1].index++
+	}
+
+	if err := p.dispatchToken(token); err != nil {
+		return err
+	}
+
+	if p.annotating {
+		p.annotate(token, valuePath, isKey)
+	}
+
+	if p.capture != nil && p.depth == p.capture.depth {
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			return p.finishCapture()
+		}
+	}
+	return nil
+}
+
+// dispatchToken calls the handler for the token type
+func (p *TokenParser) dispatchToken(token json.Token) error {
 	switch v := token.(type) {
 	case json.Delim:
 		return p.handleDelimiter(v)
@@ -377,6 +1214,16 @@ func (p *TokenParser) processToken(token json.Token) error {
 		return p.handleBoolean(v)
 	case nil:
 		return p.handleNull()
+	case RawValue:
+		return p.handleRaw(v)
+	case foldedMembers:
+		return p.handleFolded(v)
+	case foldedElements:
+		return p.handleFoldedElements(v)
+	case skippedElements:
+		return p.handleSkippedElements(v)
+	case truncatedMarker:
+		return p.handleTruncated()
 	default:
 		return NewFormatError(fmt.Sprintf("unknown token type: %T", token))
 	}
@@ -415,35 +1262,19 @@ func (p *TokenParser) startObject() error {
 	}
 
 	// Validate depth limits to prevent stack overflow
-	if p.depth >= 100 {
+	if p.depth >= maxDepth {
 		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
 	}
 
-	// Add comma if not the first element and we're in an array
-	if !p.isFirstElement && p.isInArray() {
-		if _, err := p.builder.WriteString(","); err != nil {
-			return WrapFormatError("failed to write comma separator", err)
-		}
-		if p.shouldFormatCompact() {
-			if _, err := p.builder.WriteString(" "); err != nil {
-				return WrapFormatError("failed to write space", err)
-			}
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
-		}
-	} else if p.depth > 0 && p.isInArray() {
-		if p.shouldFormatCompact() {
-			// For compact formatting, don't add newline
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
 	}
 
 	// Write opening brace with space if it's a value after a key
+	p.markValueStart()
 	if p.depth > 0 && !p.isInArray() {
 		// This is an object value, add space after colon
 		if _, err := p.builder.WriteString(" {"); err != nil {
@@ -491,14 +1322,31 @@ func (p *TokenParser) endObject() error {
 	// Check if this object should be formatted compactly BEFORE updating state
 	isCompact := p.shouldFormatCompact()
 
+	// Empty objects are closed on the same line from StyleV2 on
+	closeInline := isCompact || (p.isFirstElement && p.config.StyleVersion >= StyleV2)
+
+	if p.config.ShowMissingRequired && !p.config.StrictJSON {
+		written, err := p.writeMissingRequired(isCompact)
+		if err != nil {
+			return err
+		}
+		if written {
+			closeInline = isCompact
+		}
+	}
+
 	// Update parser state
 	if err := p.exitObject(); err != nil {
 		return WrapFormatError("failed to exit object state", err)
 	}
-	p.expectingKey = false
+
+	// The object was an element of its parent, so the parent is no longer empty,
+	// and if the parent is an object the next string is a key
+	p.isFirstElement = false
+	p.expectingKey = p.depth > 0 && !p.isInArray()
 
 	// Format closing brace based on compact status
-	if isCompact {
+	if closeInline {
 		// For compact objects, just add the closing brace without newline
 		if _, err := p.builder.WriteString("}"); err != nil {
 			return WrapFormatError("failed to write closing brace", err)
@@ -533,30 +1381,19 @@ func (p *TokenParser) startArray() error {
 	}
 
 	// Validate depth limits to prevent stack overflow
-	if p.depth >= 100 {
+	if p.depth >= maxDepth {
 		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
 	}
 
-	// Add comma if not the first element and we're in an array
-	if !p.isFirstElement && p.isInArray() {
-		if _, err := p.builder.WriteString(","); err != nil {
-			return WrapFormatError("failed to write comma separator", err)
-		}
-		if p.shouldFormatCompact() {
-			if _, err := p.builder.WriteString(" "); err != nil {
-				return WrapFormatError("failed to write space", err)
-			}
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
-	} else if p.depth > 0 {
-		// For arrays, don't add newline - they should start right after the colon
-		// This will be handled by the key-value separator logic
 	}
 
 	// Write opening bracket with space if it's a value after a key
+	p.markValueStart()
 	if p.depth > 0 && !p.isInArray() {
 		// This is an array value, add space after colon
 		if _, err := p.builder.WriteString(" ["); err != nil {
@@ -604,13 +1441,16 @@ func (p *TokenParser) endArray() error {
 	// Check if this array should be formatted compactly BEFORE updating state
 	isCompact := p.shouldFormatCompact()
 
+	// Empty arrays are closed on the same line from StyleV2 on
+	closeInline := isCompact || (p.isFirstElement && p.config.StyleVersion >= StyleV2)
+
 	// Update parser state first
 	if err := p.exitArray(); err != nil {
 		return WrapFormatError("failed to exit array state", err)
 	}
 
 	// Format closing bracket based on compact status
-	if isCompact {
+	if closeInline {
 		// For compact arrays, just add the closing bracket without newline
 		if _, err := p.builder.WriteString("]"); err != nil {
 			return WrapFormatError("failed to write closing bracket", err)
@@ -625,7 +1465,9 @@ func (p *TokenParser) endArray() error {
 		}
 	}
 
-	// If we're back in an object after the array, next string will be a key
+	// The array was an element of its parent, and if we're back in an object
+	// after the array, next string will be a key
+	p.isFirstElement = false
 	if !p.isInArray() {
 		p.expectingKey = true
 	}
@@ -644,7 +1486,7 @@ func (p *TokenParser) handleString(value string) error {
 	}
 
 	// Validate string length to prevent memory issues
-	if len(value) > 1000000 { // 1MB limit for individual strings
+	if len(value) > maxStringBytes { // 1MB limit for individual strings
 		return NewFormatError("string value too large (exceeds 1MB limit)")
 	}
 
@@ -655,38 +1497,28 @@ func (p *TokenParser) handleString(value string) error {
 			return NewFormatError("malformed JSON: unexpected object key outside of object context")
 		}
 
-		// Add comma if not the first element
-		if !p.isFirstElement {
-			if _, err := p.builder.WriteString(","); err != nil {
-				return WrapFormatError("failed to write comma separator", err)
-			}
-			if p.shouldFormatCompact() {
-				if _, err := p.builder.WriteString(" "); err != nil {
-					return WrapFormatError("failed to write space", err)
-				}
-			} else {
-				if err := p.writeNewlineAndIndent(); err != nil {
-					return WrapFormatError("failed to write newline and indent", err)
-				}
-			}
-		} else if p.depth > 0 && !p.shouldFormatCompact() {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+		if len(p.path) == len(p.inArray) {
+			p.path[len(p.path)-1].key = value
 		}
+		p.recordKey(value)
 
-		// Write the key with quotes and colon
-		if _, err := p.builder.WriteString(`"`); err != nil {
-			return WrapFormatError("failed to write opening quote for key", err)
+		// Keys are preceded by a separator
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
-		escapedKey, err := p.escapeString(value)
+
+		p.markValueStart()
+
+		// Write the key with quotes and colon
+		key := p.renamedKey(value)
+		escapedKey, err := p.escapeString(key)
 		if err != nil {
 			return WrapFormatError("failed to escape object key", err)
 		}
-		if _, err := p.builder.WriteString(escapedKey); err != nil {
+		if _, err := p.builder.WriteString(p.quoteKey(key, escapedKey)); err != nil {
 			return WrapFormatError("failed to write object key", err)
 		}
-		if _, err := p.builder.WriteString(`":`); err != nil {
+		if _, err := p.builder.WriteString(`:`); err != nil {
 			return WrapFormatError("failed to write key-value separator", err)
 		}
 
@@ -695,51 +1527,27 @@ func (p *TokenParser) handleString(value string) error {
 		p.expectingKey = false
 	} else {
 		// This is a value (either in array or object value)
-		// Only add comma if we're in an array and not the first element
-		if !p.isFirstElement && p.isInArray() {
-			if _, err := p.builder.WriteString(","); err != nil {
-				return WrapFormatError("failed to write comma separator", err)
-			}
-			if p.shouldFormatCompact() {
-				if _, err := p.builder.WriteString(" "); err != nil {
-					return WrapFormatError("failed to write space", err)
-				}
-			} else {
-				if err := p.writeNewlineAndIndent(); err != nil {
-					return WrapFormatError("failed to write newline and indent", err)
-				}
-			}
-		} else if p.depth > 0 && p.isInArray() {
-			if p.shouldFormatCompact() {
-				// For compact formatting, don't add newline
-			} else {
-				if err := p.writeNewlineAndIndent(); err != nil {
-					return WrapFormatError("failed to write newline and indent", err)
-				}
+		if p.isInArray() {
+			if err := p.writeElementPrefix(); err != nil {
+				return err
 			}
 		}
 
 		// Write the JSON-escaped string with quotes, add space if it's a value after a key
+		p.markValueStart()
 		if p.depth > 0 && !p.isInArray() {
 			// This is an object value, add space after colon
-			if _, err := p.builder.WriteString(` "`); err != nil {
-				return WrapFormatError("failed to write opening quote for string value", err)
-			}
-		} else {
-			if _, err := p.builder.WriteString(`"`); err != nil {
-				return WrapFormatError("failed to write opening quote for string value", err)
+			if _, err := p.builder.WriteString(" "); err != nil {
+				return WrapFormatError("failed to write space before string value", err)
 			}
 		}
 		escapedValue, err := p.escapeString(value)
 		if err != nil {
 			return WrapFormatError("failed to escape string value", err)
 		}
-		if _, err := p.builder.WriteString(escapedValue); err != nil {
+		if _, err := p.builder.WriteString(p.quoteString(escapedValue)); err != nil {
 			return WrapFormatError("failed to write string value", err)
 		}
-		if _, err := p.builder.WriteString(`"`); err != nil {
-			return WrapFormatError("failed to write closing quote for string value", err)
-		}
 
 		// Mark that we've processed an element
 		p.isFirstElement = false
@@ -768,6 +1576,16 @@ func (p *TokenParser) handleNumber(value float64) error {
 		return NewFormatError("malformed JSON: unexpected number, expected object key")
 	}
 
+	// Emit NaN and infinities according to the special float policy
+	if isSpecialFloat(value) {
+		switch p.config.SpecialFloats {
+		case SpecialFloatsAsNull:
+			return p.handleNull()
+		case SpecialFloatsAsString:
+			return p.handleString(specialFloatName(value))
+		}
+	}
+
 	// Validate number value for special cases
 	if value != value { // NaN check
 		return NewFormatError("invalid JSON: NaN values are not allowed")
@@ -776,27 +1594,10 @@ func (p *TokenParser) handleNumber(value float64) error {
 		return NewFormatError("invalid JSON: infinite values are not allowed")
 	}
 
-	// Only add comma if we're in an array and not the first element
-	if !p.isFirstElement && p.isInArray() {
-		if _, err := p.builder.WriteString(","); err != nil {
-			return WrapFormatError("failed to write comma separator", err)
-		}
-		if p.shouldFormatCompact() {
-			if _, err := p.builder.WriteString(" "); err != nil {
-				return WrapFormatError("failed to write space", err)
-			}
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
-		}
-	} else if p.depth > 0 && p.isInArray() {
-		if p.shouldFormatCompact() {
-			// For compact formatting, don't add newline
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
 	}
 
@@ -805,6 +1606,7 @@ func (p *TokenParser) handleNumber(value float64) error {
 	if err != nil {
 		return WrapFormatError("failed to format number", err)
 	}
+	p.markValueStart()
 	if p.depth > 0 && !p.isInArray() {
 		// This is an object value, add space after colon
 		if _, err := p.builder.WriteString(" " + formattedNumber); err != nil {
@@ -842,27 +1644,10 @@ func (p *TokenParser) handleBoolean(value bool) error {
 		return NewFormatError("malformed JSON: unexpected boolean, expected object key")
 	}
 
-	// Only add comma if we're in an array and not the first element
-	if !p.isFirstElement && p.isInArray() {
-		if _, err := p.builder.WriteString(","); err != nil {
-			return WrapFormatError("failed to write comma separator", err)
-		}
-		if p.shouldFormatCompact() {
-			if _, err := p.builder.WriteString(" "); err != nil {
-				return WrapFormatError("failed to write space", err)
-			}
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
-		}
-	} else if p.depth > 0 && p.isInArray() {
-		if p.shouldFormatCompact() {
-			// For compact formatting, don't add newline
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
 	}
 
@@ -873,6 +1658,7 @@ func (p *TokenParser) handleBoolean(value bool) error {
 	} else {
 		boolStr = "false"
 	}
+	p.markValueStart()
 	if p.depth > 0 && !p.isInArray() {
 		// This is an object value, add space after colon
 		if _, err := p.builder.WriteString(" " + boolStr); err != nil {
@@ -910,31 +1696,15 @@ func (p *TokenParser) handleNull() error {
 		return NewFormatError("malformed JSON: unexpected null, expected object key")
 	}
 
-	// Only add comma if we're in an array and not the first element
-	if !p.isFirstElement && p.isInArray() {
-		if _, err := p.builder.WriteString(","); err != nil {
-			return WrapFormatError("failed to write comma separator", err)
-		}
-		if p.shouldFormatCompact() {
-			if _, err := p.builder.WriteString(" "); err != nil {
-				return WrapFormatError("failed to write space", err)
-			}
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
-		}
-	} else if p.depth > 0 && p.isInArray() {
-		if p.shouldFormatCompact() {
-			// For compact formatting, don't add newline
-		} else {
-			if err := p.writeNewlineAndIndent(); err != nil {
-				return WrapFormatError("failed to write newline and indent", err)
-			}
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
 		}
 	}
 
 	// Write null value, add space if it's a value after a key
+	p.markValueStart()
 	if p.depth > 0 && !p.isInArray() {
 		// This is an object value, add space after colon
 		if _, err := p.builder.WriteString(" null"); err != nil {
@@ -957,18 +1727,68 @@ func (p *TokenParser) handleNull() error {
 	return nil
 }
 
+// handleRaw handles raw values produced by value hooks, which are written verbatim
+func (p *TokenParser) handleRaw(value RawValue) error {
+	// Validate parser state
+	if p.builder == nil {
+		return NewFormatError("invalid parser state: builder is nil")
+	}
+	if p.config == nil {
+		return NewFormatError("invalid parser state: config is nil")
+	}
+
+	// Validate that we're not expecting a key (raw values can't be object keys)
+	if p.expectingKey {
+		return NewFormatError("malformed JSON: unexpected raw value, expected object key")
+	}
+
+	// Array elements are preceded by a separator
+	if p.isInArray() {
+		if err := p.writeElementPrefix(); err != nil {
+			return err
+		}
+	}
+
+	// Write the raw value, add space if it's a value after a key
+	p.markValueStart()
+	if p.depth > 0 && !p.isInArray() {
+		if _, err := p.builder.WriteString(" " + string(value)); err != nil {
+			return WrapFormatError("failed to write raw value", err)
+		}
+	} else {
+		if _, err := p.builder.WriteString(string(value)); err != nil {
+			return WrapFormatError("failed to write raw value", err)
+		}
+	}
+
+	// Mark that we've processed an element
+	p.isFirstElement = false
+
+	// If we're in an object, next string will be a key
+	if !p.isInArray() {
+		p.expectingKey = true
+	}
+
+	return nil
+}
+
 // enterArray updates parser state when entering an array
 func (p *TokenParser) enterArray() error {
 	// Validate state before entering array
 	if p.depth < 0 {
 		return NewFormatError("invalid parser state: negative depth")
 	}
-	if p.depth >= 100 {
+	if p.depth >= maxDepth {
 		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
 	}
 
+	compact, layout := p.layoutDecision(false)
+	p.trace.noteLayout(layout)
+	level := pathLevel{index: -1, schema: p.childSchema(), compact: compact, homogeneous: p.homogeneous}
+	p.homogeneous = false
 	p.depth++
 	p.inArray = append(p.inArray, true)
+	p.path = append(p.path, level)
 	return nil
 }
 
@@ -987,6 +1807,9 @@ func (p *TokenParser) exitArray() error {
 
 	p.depth--
 	p.inArray = p.inArray[:len(p.inArray)-1]
+	if len(p.path) > 0 {
+		p.path = p.path[:len(p.path)-1]
+	}
 	return nil
 }
 
@@ -996,12 +1819,16 @@ func (p *TokenParser) enterObject() error {
 	if p.depth < 0 {
 		return NewFormatError("invalid parser state: negative depth")
 	}
-	if p.depth >= 100 {
+	if p.depth >= maxDepth {
 		return NewFormatError("JSON structure too deeply nested (max depth: 100)")
 	}
 
+	compact, layout := p.layoutDecision(true)
+	p.trace.noteLayout(layout)
+	level := pathLevel{index: -1, schema: p.childSchema(), compact: compact}
 	p.depth++
 	p.inArray = append(p.inArray, false)
+	p.path = append(p.path, level)
 	return nil
 }
 
@@ -1020,6 +1847,9 @@ func (p *TokenParser) exitObject() error {
 
 	p.depth--
 	p.inArray = p.inArray[:len(p.inArray)-1]
+	if len(p.path) > 0 {
+		p.path = p.path[:len(p.path)-1]
+	}
 	return nil
 }
 
@@ -1033,8 +1863,187 @@ func (p *TokenParser) isInArray() bool {
 
 // shouldFormatCompact determines if elements at current depth should be formatted compactly
 func (p *TokenParser) shouldFormatCompact() bool {
+	if p.depth > 0 && len(p.path) == p.depth {
+		return p.path[p.depth-1].compact
+	}
+	return p.isCompactDepth(p.depth)
+}
+
+// opensCompact determines if the contents of a container opened at the current
+// position should be formatted compactly
+func (p *TokenParser) opensCompact(object bool) bool {
+	compact, _ := p.layoutDecision(object)
+	return compact
+}
+
+// layoutDecision determines if the contents of a container opened at the
+// current position should be formatted compactly, and describes the rule
+// that decided it
+func (p *TokenParser) layoutDecision(object bool) (bool, string) {
+	if p.shouldFormatCompact() {
+		return true, "compact: inside a compact container"
+	}
+	if p.opensRows() {
+		return false, "expanded: held by a compact key"
+	}
+	if p.opensRow() {
+		return true, "compact: row of a compact key"
+	}
+	if compact, ok := p.pathLayout(); ok {
+		if compact {
+			return true, "compact: matches a compact path"
+		}
+		return false, "expanded: matches an expanded path"
+	}
+	if compact, ok := p.previousLayout(); ok {
+		if compact {
+			return true, "compact: written on one line in the previous version"
+		}
+		return false, "expanded: expanded in the previous version"
+	}
+	if p.config.AdaptiveCompaction && object && p.isInArray() && len(p.path) == p.depth && p.depth+1 >= p.minCompactDepth {
+		if p.path[p.depth-1].homogeneous {
+			return true, "compact: adaptive compaction, the objects of the array share a key set"
+		}
+		return false, "expanded: adaptive compaction, the objects of the array differ in keys"
+	}
+	switch depth := p.depth + 1; {
+	case p.isCompactDepth(depth):
+		return true, fmt.Sprintf("compact: depth %d reaches the compact depth %d", depth, p.config.CompactDepth)
+	case p.config.CompactDepth == 0:
+		return false, "expanded: compact depth disabled"
+	case depth >= p.config.CompactDepth:
+		return false, fmt.Sprintf("expanded: compact formatting starts at depth %d after a width overflow", p.minCompactDepth)
+	default:
+		return false, fmt.Sprintf("expanded: depth %d is below the compact depth %d", depth, p.config.CompactDepth)
+	}
+}
+
+// isCompactDepth determines if elements at the given depth should be formatted compactly
+func (p *TokenParser) isCompactDepth(depth int) bool {
 	// Format compactly if we're at or beyond the configured compact depth
-	return p.config.CompactDepth > 0 && p.depth >= p.config.CompactDepth
+	return p.config.CompactDepth > 0 && depth >= p.config.CompactDepth && depth >= p.minCompactDepth
+}
+
+// widthLimit returns the maximum line width for compact elements at the given depth
+func (p *TokenParser) widthLimit(depth int) int {
+	if width, ok := p.config.WidthByDepth[depth]; ok {
+		return width
+	}
+	return p.config.MaxWidth
+}
+
+// shouldCapture determines if a container opened at the current depth is the
+// outermost compact element and has a width limit to be checked against
+func (p *TokenParser) shouldCapture(object bool) bool {
+	return !p.shouldFormatCompact() && p.opensCompact(object) && !p.opensRow() && p.widthLimit(p.depth+1) > 0
+}
+
+// snapshot returns a copy of the current parser state
+func (p *TokenParser) snapshot() parserState {
+	return parserState{
+		depth:          p.depth,
+		inArray:        append([]bool(nil), p.inArray...),
+		isFirstElement: p.isFirstElement,
+		expectingKey:   p.expectingKey,
+		path:           append([]pathLevel(nil), p.path...),
+		openRegions:    append([]int(nil), p.openRegions...),
+	}
+}
+
+// restore resets the parser to a previously taken snapshot
+func (p *TokenParser) restore(state parserState) {
+	p.depth = state.depth
+	p.inArray = state.inArray
+	p.isFirstElement = state.isFirstElement
+	p.expectingKey = state.expectingKey
+	p.path = state.path
+	p.openRegions = state.openRegions
+}
+
+// startCapture begins recording a compact element into a temporary builder
+func (p *TokenParser) startCapture(token json.Token) {
+	p.capture = &captureState{
+		depth:       p.depth,
+		base:        p.outputOffset(),
+		annotations: len(p.annotations),
+		state:       p.snapshot(),
+		outer:       p.builder,
+		tokens:      []json.Token{token},
+	}
+	p.builder = &outputBuffer{}
+}
+
+// finishCapture writes the captured element if it fits within the width limit,
+// otherwise it summarizes the element or re-formats it with compact formatting
+// pushed one level deeper
+func (p *TokenParser) finishCapture() error {
+	capture := p.capture
+	p.capture = nil
+	captured := p.builder.(*outputBuffer).String()
+	p.builder = capture.outer
+
+	if width, limit := p.lineWidth(captured), p.widthLimit(capture.depth+1); width <= limit {
+		p.trace.noteLayout(fmt.Sprintf("compact: the line fits within %d columns", limit))
+		if _, err := p.builder.WriteString(captured); err != nil {
+			return WrapFormatError("failed to write compact element", err)
+		}
+		return nil
+	}
+
+	p.restore(capture.state)
+	p.annotations = p.annotations[:capture.annotations]
+
+	// Summarize objects with their first members when configured
+	if n := summaryLength(capture.tokens, p.config.OverflowSummaryKeys); n > 0 && !p.config.StrictJSON {
+		p.trace.noteLayout(fmt.Sprintf("summarized: the line exceeds %d columns", p.widthLimit(capture.depth+1)))
+		return p.writeSummary(capture.tokens[:n])
+	}
+	p.trace.noteLayout(fmt.Sprintf("expanded: the line exceeds %d columns", p.widthLimit(capture.depth+1)))
+
+	// Replay the element with its contents expanded
+	savedMinCompactDepth := p.minCompactDepth
+	p.minCompactDepth = capture.depth + 2
+	defer func() { p.minCompactDepth = savedMinCompactDepth }()
+	for i, token := range capture.tokens {
+		if p.config.AdaptiveCompaction && token == json.Delim('[') {
+			p.homogeneous = isHomogeneous(capture.tokens[i:])
+		}
+		if err := p.emitToken(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineWidth returns the width of the line the captured text ends on
+// once it is appended to the current builder
+func (p *TokenParser) lineWidth(captured string) int {
+	if i := strings.LastIndexByte(captured, '\n'); i >= 0 {
+		return p.width(captured[i+1:])
+	}
+	return p.currentColumn() + p.width(captured)
+}
+
+// width returns the display width of text with the configured width function
+func (p *TokenParser) width(s string) int {
+	if p.config.Width != nil {
+		return p.config.Width(s)
+	}
+	return DisplayWidth(s)
+}
+
+// currentColumn returns the width of the text written after the last newline
+func (p *TokenParser) currentColumn() int {
+	switch b := p.builder.(type) {
+	case *outputBuffer:
+		return b.column
+	case interface{ String() string }:
+		current := b.String()
+		return p.width(current[strings.LastIndexByte(current, '\n')+1:])
+	default:
+		return 0
+	}
 }
 
 // writeIndent writes the appropriate indentation based on current depth and config
@@ -1050,25 +2059,94 @@ func (p *TokenParser) writeIndent() error {
 		return NewFormatError("invalid parser state: negative depth")
 	}
 
-	var indentStr string
+	levels := p.indentLevels()
+	unit, width := " ", levels*p.config.IndentSize
 	if p.config.UseTab {
-		indentStr = strings.Repeat("\t", p.depth)
-	} else {
-		// Validate indent size to prevent excessive memory usage
-		totalSpaces := p.depth * p.config.IndentSize
-		if totalSpaces > 10000 { // Limit total indentation to prevent memory issues
-			return NewFormatError("indentation too large (exceeds 10000 characters)")
-		}
-		indentStr = strings.Repeat(" ", totalSpaces)
+		unit, width = "\t", levels
+	} else if width > maxIndentWidth { // Limit total indentation to prevent memory issues
+		return NewFormatError("indentation too large (exceeds 10000 characters)")
 	}
 
-	if _, err := p.builder.WriteString(indentStr); err != nil {
+	// Lines share one indentation string, so writing them does not allocate
+	if len(p.indentation) < width {
+		p.indentation = strings.Repeat(unit, max(width, 2*len(p.indentation)))
+	}
+	if _, err := p.builder.WriteString(p.indentation[:width]); err != nil {
 		return WrapFormatError("failed to write indentation", err)
 	}
 
 	return nil
 }
 
+// writeElementPrefix writes what precedes an array element or object key:
+// a comma after the previous element, then a space in compact containers or
+// a newline and indentation otherwise. With leading commas, the comma of an
+// expanded element follows the indentation instead.
+func (p *TokenParser) writeElementPrefix() error {
+	if p.depth == 1 {
+		if err := p.endRecord(); err != nil {
+			return err
+		}
+	}
+	compact := p.shouldFormatCompact()
+	if compact || !p.config.LeadingCommas {
+		if !p.isFirstElement {
+			if _, err := p.builder.WriteString(","); err != nil {
+				return WrapFormatError("failed to write comma separator", err)
+			}
+		}
+	}
+
+	if compact {
+		if !p.isFirstElement {
+			if _, err := p.builder.WriteString(" "); err != nil {
+				return WrapFormatError("failed to write space", err)
+			}
+		}
+		return nil
+	}
+
+	if p.namespaceBreak() || p.needsBlankLine() {
+		if _, err := p.builder.WriteString("\n"); err != nil {
+			return WrapFormatError("failed to write blank line", err)
+		}
+	}
+	if err := p.writeSectionComment(); err != nil {
+		return err
+	}
+	if err := p.writeNewlineAndIndent(); err != nil {
+		return WrapFormatError("failed to write newline and indent", err)
+	}
+	if p.config.LeadingCommas && !p.isFirstElement {
+		if _, err := p.builder.WriteString(", "); err != nil {
+			return WrapFormatError("failed to write comma separator", err)
+		}
+	}
+	if p.depth == 1 {
+		p.topLevelStart = p.outputOffset()
+		p.budgetLines = 0
+	} else {
+		p.budgetLines++
+	}
+	return nil
+}
+
+// indentLevels returns the number of indentation levels contributed by the open containers
+func (p *TokenParser) indentLevels() int {
+	levels := 0
+	for i := 0; i < p.depth; i++ {
+		switch {
+		case i >= len(p.inArray):
+			levels++
+		case p.inArray[i]:
+			levels += p.config.ArrayIndent.levels()
+		default:
+			levels += p.config.ObjectIndent.levels()
+		}
+	}
+	return levels
+}
+
 // writeNewlineAndIndent writes a newline followed by proper indentation
 func (p *TokenParser) writeNewlineAndIndent() error {
 	// Validate parser state
@@ -1079,6 +2157,7 @@ func (p *TokenParser) writeNewlineAndIndent() error {
 	if _, err := p.builder.WriteString("\n"); err != nil {
 		return WrapFormatError("failed to write newline", err)
 	}
+	p.startLine()
 
 	if err := p.writeIndent(); err != nil {
 		return WrapFormatError("failed to write indentation after newline", err)
@@ -1090,10 +2169,15 @@ func (p *TokenParser) writeNewlineAndIndent() error {
 // escapeString properly escapes a string for JSON output
 func (p *TokenParser) escapeString(s string) (string, error) {
 	// Validate input string
-	if len(s) > 1000000 { // 1MB limit for individual strings
+	if len(s) > maxStringBytes { // 1MB limit for individual strings
 		return "", NewFormatError("string too large for escaping (exceeds 1MB limit)")
 	}
 
+	// Most strings need no escaping at all, so avoid the marshaling round trip
+	if !needsEscape(s) {
+		return s, nil
+	}
+
 	// Use json.Marshal to properly escape the string, then remove the surrounding quotes
 	escaped, err := json.Marshal(s)
 	if err != nil {
@@ -1157,23 +2241,41 @@ type FormatError struct {
 	// Original contains the underlying error that caused this formatting error.
 	// It may be nil if the error originated within the formatter itself.
 	Original error
+
+	// Line and Column give the 1-based location in the input where formatting
+	// stopped. Zero values mean the location is not available.
+	Line   int
+	Column int
+
+	// Path contains the object keys and array indices leading to the value
+	// where formatting stopped, in the same form as ValueContext.Path.
+	// It is nil when the error is not related to a location in the document.
+	Path []string
+
+	// File is the name of the input set with WithFilename, which prefixes
+	// the message. It is empty when the input has no name.
+	File string
 }
 
 // Error implements the error interface and returns a formatted error message.
 // The message includes position information when available and details
 // about any underlying error.
 func (e *FormatError) Error() string {
+	msg := e.Msg
+	if e.File != "" {
+		msg = e.File + ": " + msg
+	}
 	if e.Position > 0 {
 		if e.Original != nil {
-			return fmt.Sprintf("%s at position %d: %v", e.Msg, e.Position, e.Original)
+			return fmt.Sprintf("%s at position %d: %v", msg, e.Position, e.Original)
 		}
-		return fmt.Sprintf("%s at position %d", e.Msg, e.Position)
+		return fmt.Sprintf("%s at position %d", msg, e.Position)
 	}
 
 	if e.Original != nil {
-		return fmt.Sprintf("%s: %v", e.Msg, e.Original)
+		return fmt.Sprintf("%s: %v", msg, e.Original)
 	}
-	return e.Msg
+	return msg
 }
 
 // Unwrap returns the underlying error for error unwrapping.
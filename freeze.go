@@ -0,0 +1,86 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import "sync/atomic"
+
+// snapshotIDs numbers the snapshots taken with Freeze
+var snapshotIDs atomic.Uint64
+
+// FrozenConfig is an immutable snapshot of a Config, taken with Freeze.
+// Changes to the Config after the snapshot, including to its slices and
+// maps, do not affect the snapshot, so one snapshot can be shared by the
+// formatters of many goroutines.
+//
+// Functions in the configuration, such as value hooks and the
+// post-processor, are shared with the Config and must be safe for
+// concurrent use. The schema is copied like the slices and maps.
+type FrozenConfig struct {
+	config *Config
+	id     uint64          // Unique among all snapshots, so caches can tell them apart
+	rules  *compiledRules  // Path patterns of config, compiled once for all formatters
+	ladder []*FrozenConfig // Configurations of the rungs of the degradation ladder
+}
+
+// Freeze returns an immutable snapshot of the configuration. A nil Config
+// is frozen as the default configuration.
+//
+// NewFormatter freezes its configuration too, so Freeze is only needed to
+// create many formatters from one snapshot without copying it each time,
+// for example one per request in a server with a configuration per tenant.
+//
+// Example:
+//
+//	frozen := NewConfig(WithIndentSize(4)).Freeze()
+//	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+//	    formatted, err := frozen.NewFormatter().Format(body)
+//	    // ...
+//	})
+func (c *Config) Freeze() *FrozenConfig {
+	if c == nil {
+		c = DefaultConfig()
+	}
+	snapshot := c.clone()
+	if c.KeyOrder != nil {
+		for pattern, keys := range c.KeyOrder {
+			snapshot.KeyOrder[pattern] = append([]string(nil), keys...)
+		}
+	}
+	if c.QuoteStyle != nil {
+		quoteStyle := *c.QuoteStyle
+		snapshot.QuoteStyle = &quoteStyle
+	}
+	snapshot.Schema = c.Schema.clone()
+	return &FrozenConfig{config: snapshot, id: snapshotIDs.Add(1), rules: compileRules(snapshot), ladder: degradationLadder(snapshot)}
+}
+
+// Config returns a copy of the snapshot that can be changed, for deriving
+// other configurations from it.
+func (fc *FrozenConfig) Config() *Config {
+	config := fc.config.clone()
+	if config.QuoteStyle != nil {
+		quoteStyle := *config.QuoteStyle
+		config.QuoteStyle = &quoteStyle
+	}
+	config.Schema = config.Schema.clone()
+	return config
+}
+
+// NewFormatter creates a Formatter using the snapshot. The snapshot is
+// shared, not copied, and so are its path patterns, which are compiled
+// when the snapshot is taken.
+func (fc *FrozenConfig) NewFormatter() *Formatter {
+	return &Formatter{config: fc.config, snapshot: fc.id, rules: fc.rules, ladder: fc.ladder}
+}
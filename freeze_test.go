@@ -0,0 +1,158 @@
+package jsonformat
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestNewFormatterSnapshotsConfig(t *testing.T) {
+	config := NewConfig(WithCompactDepth(0))
+	formatter := NewFormatter(config)
+
+	config.IndentSize = 4
+	config.CompactDepth = 1
+
+	result, err := formatter.Format(`{"a":[1]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "{\n  \"a\": [\n    1\n  ]\n}"
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFreezeCopiesSlicesAndMaps(t *testing.T) {
+	config := NewConfig(
+		WithCompactDepth(0),
+		WithCompactPaths("a"),
+		WithKeyOrder("", "b", "a"),
+		WithQuoteStyle(QuoteStyle{BareKeys: true}),
+	)
+	formatter := config.Freeze().NewFormatter()
+
+	config.CompactPaths[0] = "b"
+	config.KeyOrder[""][0] = "a"
+	config.QuoteStyle.BareKeys = false
+
+	result, err := formatter.Format(`{"a":[1],"b":[2]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "{\n  b: [\n    2\n  ],\n  a: [1]\n}"
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFrozenConfigConfig(t *testing.T) {
+	frozen := NewConfig(WithCompactDepth(0), WithQuoteStyle(QuoteStyle{BareKeys: true})).Freeze()
+
+	derived := frozen.Config()
+	derived.CompactDepth = 1
+	derived.QuoteStyle.BareKeys = false
+
+	result, err := frozen.NewFormatter().Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "{\n  a: 1\n}"; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+
+	result, err = NewFormatter(derived).Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"a": 1}`; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFreezeNil(t *testing.T) {
+	var config *Config
+	result, err := config.Freeze().NewFormatter().Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, _ := NewFormatter(DefaultConfig()).Format(`{"a":1}`)
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFormatterConcurrentWithConfigChanges(t *testing.T) {
+	config := DefaultConfig()
+	formatter := NewFormatter(config)
+	expected, err := formatter.Format(`{"a":[1,2],"b":{"c":true}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			config.IndentSize = i % 8
+			config.SectionComments = append(config.SectionComments, "x")
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				result, err := formatter.Format(`{"a":[1,2],"b":{"c":true}}`)
+				if err != nil || result != expected {
+					t.Errorf("Expected:\n%s\n\nGot:\n%s (%v)", expected, result, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func ExampleConfig_Freeze() {
+	frozen := NewConfig(WithIndentSize(4), WithCompactDepth(0)).Freeze()
+
+	// Each request gets its own formatter from the shared snapshot
+	for _, body := range []string{`{"id":1}`, `{"id":2}`} {
+		formatted, err := frozen.NewFormatter().Format(body)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(formatted)
+	}
+	// Output:
+	// {
+	//     "id": 1
+	// }
+	// {
+	//     "id": 2
+	// }
+}
+
+func TestFreezeCopiesSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"properties": {"a": {}, "b": {"default": {"c": [1]}}}, "required": ["a", "b"]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	schema.Items = schema
+	formatter := NewConfig(WithSchema(schema), WithMissingRequired(), WithCompactDepth(1)).Freeze().NewFormatter()
+
+	schema.Required[1] = "c"
+	schema.Properties["b"].Default.(map[string]interface{})["c"] = nil
+	delete(schema.Properties, "a")
+
+	result, err := formatter.Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"a": 1 /* missing: "b" */}`; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goSampleLines is the number of lines of the formatted sample written
+// above the generated root type
+const goSampleLines = 40
+
+// goInitialisms are the words written in upper case in Go identifiers
+var goInitialisms = map[string]bool{
+	"API": true, "CPU": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "TCP": true, "TLS": true, "TTL": true,
+	"UI": true, "UID": true, "URI": true, "URL": true, "UTC": true, "UUID": true, "XML": true,
+}
+
+// GenerateGoTypes infers Go struct definitions with json tags from a JSON
+// document, or from a corpus of documents written one after another, such
+// as NDJSON, and returns them as the source of a file in package pkg. The
+// root type is named Document, and the first document, formatted with the
+// default configuration, is written above it as an example.
+//
+// Fields that are null in a sample are pointers, and fields missing from
+// some objects are tagged omitempty. Numbers are int64 when every sample is
+// an integer and float64 otherwise, strings that are all RFC 3339
+// timestamps are time.Time, and values with several types are any.
+//
+// Example:
+//
+//	source, err := GenerateGoTypes(`{"user_id": 1, "tags": ["a"]}`, "model")
+//	// package model
+//	//
+//	// type Document struct {
+//	//     UserID int64    `json:"user_id"`
+//	//     Tags   []string `json:"tags"`
+//	// }
+func GenerateGoTypes(input string, pkg string) (string, error) {
+	if !token.IsIdentifier(pkg) {
+		return "", NewFormatError(fmt.Sprintf("invalid package name %q", pkg))
+	}
+
+	root := &goShape{}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	samples := 0
+	var sample string
+	for decoder.More() {
+		start := decoder.InputOffset()
+		if err := root.read(decoder); err != nil {
+			return "", WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+		}
+		if samples == 0 {
+			sample = input[start:decoder.InputOffset()]
+		}
+		samples++
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+	}
+	if samples == 0 {
+		return "", NewFormatError("input contains no valid JSON tokens")
+	}
+
+	formatted, err := NewFormatter(DefaultConfig()).Format(sample)
+	if err != nil {
+		return "", err
+	}
+	g := &goGenerator{names: map[string]bool{}}
+	g.declare("Document", "", root, exampleComment(formatted, samples))
+
+	var source strings.Builder
+	source.WriteString("// Code generated by jsonformat from sample documents.\n\n")
+	source.WriteString("package " + pkg + "\n\n")
+	if g.usesTime {
+		source.WriteString("import \"time\"\n\n")
+	}
+	source.WriteString(strings.Join(g.declarations, "\n"))
+	result, err := format.Source([]byte(source.String()))
+	if err != nil {
+		return "", WrapFormatError("failed to generate Go types", err)
+	}
+	return string(result), nil
+}
+
+// exampleComment returns the comment of the root type with the formatted
+// sample, cut after goSampleLines lines
+func exampleComment(formatted string, samples int) string {
+	lines := strings.Split(formatted, "\n")
+	cut := len(lines) > goSampleLines
+	if cut {
+		lines = lines[:goSampleLines]
+	}
+	var comment strings.Builder
+	if samples == 1 {
+		comment.WriteString("// Document is inferred from the sample document:\n//\n")
+	} else {
+		fmt.Fprintf(&comment, "// Document is inferred from %d sample documents, the first of which is:\n//\n", samples)
+	}
+	for _, line := range lines {
+		comment.WriteString("//\t" + line + "\n")
+	}
+	if cut {
+		comment.WriteString("//\t...\n")
+	}
+	return comment.String()
+}
+
+// goShape merges the values seen at one place in the samples
+type goShape struct {
+	values   int // Values seen, including nulls
+	nulls    int
+	strings  int
+	times    int // Strings that are RFC 3339 timestamps
+	bools    int
+	integers int
+	floats   int
+	objects  int
+	arrays   int
+	keys     []string            // Keys of the objects, in first-seen order
+	fields   map[string]*goShape // Values of the members of the objects
+	presence map[string]int      // Number of objects with each key
+	elements *goShape            // Values of the elements of the arrays
+}
+
+// read merges the next value of the decoder into the shape
+func (s *goShape) read(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	s.values++
+	switch value := token.(type) {
+	case nil:
+		s.nulls++
+	case string:
+		s.strings++
+		if _, ok := utcTimestamp(value); ok {
+			s.times++
+		}
+	case bool:
+		s.bools++
+	case json.Number:
+		if strings.ContainsAny(string(value), ".eE") {
+			s.floats++
+		} else {
+			s.integers++
+		}
+	case json.Delim:
+		if value == '{' {
+			return s.readObject(decoder)
+		}
+		s.arrays++
+		if s.elements == nil {
+			s.elements = &goShape{}
+		}
+		for decoder.More() {
+			if err := s.elements.read(decoder); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	}
+	return nil
+}
+
+// readObject merges the members of an object, after its opening brace,
+// into the shape
+func (s *goShape) readObject(decoder *json.Decoder) error {
+	s.objects++
+	if s.fields == nil {
+		s.fields = map[string]*goShape{}
+		s.presence = map[string]int{}
+	}
+	seen := map[string]bool{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		field := s.fields[key]
+		if field == nil {
+			field = &goShape{}
+			s.fields[key] = field
+			s.keys = append(s.keys, key)
+		}
+		if err := field.read(decoder); err != nil {
+			return err
+		}
+		if !seen[key] {
+			seen[key] = true
+			s.presence[key]++
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// kinds returns the number of kinds of non-null values, counting integers
+// and floats as one
+func (s *goShape) kinds() int {
+	kinds := 0
+	for _, count := range []int{s.strings, s.bools, s.integers + s.floats, s.objects, s.arrays} {
+		if count > 0 {
+			kinds++
+		}
+	}
+	return kinds
+}
+
+// goGenerator writes the declarations of the struct types
+type goGenerator struct {
+	declarations []string
+	names        map[string]bool // Type names in use
+	usesTime     bool
+}
+
+// declare adds the declaration of a named type for the shape of the values
+// at path, with a comment naming the path unless one is given
+func (g *goGenerator) declare(name, path string, shape *goShape, comment string) {
+	g.names[name] = true
+	if comment == "" {
+		comment = fmt.Sprintf("// %s describes the values at %q.\n", name, path)
+	}
+	index := len(g.declarations)
+	g.declarations = append(g.declarations, "")
+	if shape.kinds() == 1 && shape.objects > 0 {
+		g.declarations[index] = comment + "type " + name + " " + g.structType(name, path, shape)
+		return
+	}
+	g.declarations[index] = comment + "type " + name + " " + g.typeOf(name, "Items", path, shape, false) + "\n"
+}
+
+// structType returns the struct type of an object shape, declaring the
+// types of its nested objects after it
+func (g *goGenerator) structType(name, path string, shape *goShape) string {
+	var body strings.Builder
+	body.WriteString("struct {\n")
+	used := map[string]bool{}
+	for _, key := range shape.keys {
+		field := shape.fields[key]
+		fieldName := goName(key)
+		for i := 2; used[fieldName]; i++ {
+			fieldName = goName(key) + strconv.Itoa(i)
+		}
+		used[fieldName] = true
+
+		optional := shape.presence[key] < shape.objects
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		}
+		fieldType := g.typeOf(name, fieldName, joinSurveyPath(path, key), field, true)
+		fmt.Fprintf(&body, "\t%s %s `json:%s`", fieldName, fieldType, strconv.Quote(tag))
+		if field.kinds() > 1 {
+			body.WriteString(" // " + field.describe())
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString("}\n")
+	return body.String()
+}
+
+// typeOf returns the Go type of the values at path. Parent and field name
+// the type of nested objects. Nullable scalars and objects are pointers
+// when pointer is set.
+func (g *goGenerator) typeOf(parent, field, path string, shape *goShape, pointer bool) string {
+	nullable := pointer && shape.nulls > 0
+	var goType string
+	switch {
+	case shape.kinds() != 1:
+		return "any"
+	case shape.strings > 0 && shape.times == shape.strings:
+		g.usesTime = true
+		goType = "time.Time"
+	case shape.strings > 0:
+		goType = "string"
+	case shape.bools > 0:
+		goType = "bool"
+	case shape.floats > 0:
+		goType = "float64"
+	case shape.integers > 0:
+		goType = "int64"
+	case shape.arrays > 0:
+		if shape.elements == nil || shape.elements.values == 0 {
+			return "[]any"
+		}
+		return "[]" + g.typeOf(parent, singular(field), joinSurveyPath(path, "*"), shape.elements, false)
+	default:
+		name := g.typeName(parent, field)
+		g.declare(name, path, shape, "")
+		goType = name
+	}
+	if nullable {
+		return "*" + goType
+	}
+	return goType
+}
+
+// typeName returns an unused name for the type of a nested object, which is
+// the field name, or the parent and field names if the field name is taken
+func (g *goGenerator) typeName(parent, field string) string {
+	if !g.names[field] {
+		return field
+	}
+	name := parent + field
+	for i := 2; g.names[name]; i++ {
+		name = parent + field + strconv.Itoa(i)
+	}
+	return name
+}
+
+// describe lists the kinds of values of a shape with several kinds
+func (s *goShape) describe() string {
+	var kinds []string
+	for _, kind := range []struct {
+		name  string
+		count int
+	}{
+		{"string", s.strings}, {"boolean", s.bools}, {"number", s.integers + s.floats},
+		{"object", s.objects}, {"array", s.arrays}, {"null", s.nulls},
+	} {
+		if kind.count > 0 {
+			kinds = append(kinds, kind.name)
+		}
+	}
+	return strings.Join(kinds, " or ")
+}
+
+// goName converts a JSON key to an exported Go identifier, such as
+// "user_id" to UserID
+func goName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			// Split camelCase and the end of an acronym, as in HTTPServer
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	var name strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+		runes := []rune(w)
+		name.WriteString(strings.ToUpper(string(runes[0])) + string(runes[1:]))
+	}
+	result := name.String()
+	if result == "" || !unicode.IsLetter([]rune(result)[0]) {
+		result = "Field" + result
+	}
+	return result
+}
+
+// singular returns the name of the element type of a slice field, such as
+// Item for Items
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
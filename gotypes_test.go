@@ -0,0 +1,151 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "document",
+			input: `{"user_id":1,"createdAt":"2024-05-01T12:00:00Z","tags":["x"],"address":{"city":"c","zip":null},"HTTPServer":true}`,
+			expected: `// Code generated by jsonformat from sample documents.
+
+package model
+
+import "time"
+
+// Document is inferred from the sample document:
+//
+//	{
+//	  "user_id": 1,
+//	  "createdAt": "2024-05-01T12:00:00Z",
+//	  "tags": [
+//	    "x"
+//	  ],
+//	  "address": {
+//	    "city": "c",
+//	    "zip": null
+//	  },
+//	  "HTTPServer": true
+//	}
+type Document struct {
+	UserID     int64     ` + "`json:\"user_id\"`" + `
+	CreatedAt  time.Time ` + "`json:\"createdAt\"`" + `
+	Tags       []string  ` + "`json:\"tags\"`" + `
+	Address    Address   ` + "`json:\"address\"`" + `
+	HTTPServer bool      ` + "`json:\"HTTPServer\"`" + `
+}
+
+// Address describes the values at "address".
+type Address struct {
+	City string ` + "`json:\"city\"`" + `
+	Zip  any    ` + "`json:\"zip\"`" + `
+}
+`,
+		},
+		{
+			name: "corpus",
+			input: `{"id":1,"name":"a","items":[{"sku":"a","price":1.5}],"score":1}
+{"id":2,"name":null,"items":[{"sku":"b","qty":2}],"score":"high","note":"n"}`,
+			expected: `// Code generated by jsonformat from sample documents.
+
+package model
+
+// Document is inferred from 2 sample documents, the first of which is:
+//
+//	{
+//	  "id": 1,
+//	  "name": "a",
+//	  "items": [
+//	    {"sku": "a", "price": 1.5}
+//	  ],
+//	  "score": 1
+//	}
+type Document struct {
+	ID    int64   ` + "`json:\"id\"`" + `
+	Name  *string ` + "`json:\"name\"`" + `
+	Items []Item  ` + "`json:\"items\"`" + `
+	Score any     ` + "`json:\"score\"`" + ` // string or number
+	Note  string  ` + "`json:\"note,omitempty\"`" + `
+}
+
+// Item describes the values at "items.*".
+type Item struct {
+	Sku   string  ` + "`json:\"sku\"`" + `
+	Price float64 ` + "`json:\"price,omitempty\"`" + `
+	Qty   int64   ` + "`json:\"qty,omitempty\"`" + `
+}
+`,
+		},
+		{
+			name:  "array root",
+			input: `[{"id":1,"id-2":[]},{"id":2.5,"ID 2":[[1]]}]`,
+			expected: `// Code generated by jsonformat from sample documents.
+
+package model
+
+// Document is inferred from the sample document:
+//
+//	[
+//	  {
+//	    "id": 1,
+//	    "id-2": []
+//	  },
+//	  {
+//	    "id": 2.5,
+//	    "ID 2": [[1]]
+//	  }
+//	]
+type Document []Item
+
+// Item describes the values at "*".
+type Item struct {
+	ID   float64   ` + "`json:\"id\"`" + `
+	ID2  []any     ` + "`json:\"id-2,omitempty\"`" + `
+	ID22 [][]int64 ` + "`json:\"ID 2,omitempty\"`" + `
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GenerateGoTypes(tt.input, "model")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGenerateGoTypesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		pkg    string
+		errMsg string
+	}{
+		{"package name", `{}`, "my-pkg", `invalid package name "my-pkg"`},
+		{"invalid JSON", `{"a":`, "model", "invalid JSON input"},
+		{"trailing data", `{} ]`, "model", "invalid JSON input"},
+		{"empty", ` `, "model", "input contains no valid JSON tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateGoTypes(tt.input, tt.pkg)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// modulePath is the import path of this module, used to find its version
+const modulePath = "github.com/shibukawa/jsonformat"
+
+// headerTime returns the timestamp written to header comments
+var headerTime = time.Now
+
+// WithStrictJSON guarantees that the output is valid JSON. Display-only
+// options that write comments or ellipses, such as WithHeaderComment,
+// WithSectionComments, WithMissingRequired, FoldDefaultsAnnotate,
+// WithOverflowSummary, WithProvenance, WithSpanDurations, WithWideObjectFold,
+// WithBidiIsolation, WithQuoteStyle, and WithHardWrap, are ignored.
+func WithStrictJSON() ConfigOption {
+	return func(c *Config) {
+		c.StrictJSON = true
+	}
+}
+
+// WithHeaderComment prefixes the output with a comment line recording the
+// library version, a fingerprint of the configuration, the time of formatting,
+// and the source filename if it is not empty, so that formatted artifacts can
+// be traced. The header is never written in strict JSON mode.
+//
+// Example:
+//
+//	config := NewConfig(WithHeaderComment("config.json"))
+//	// // jsonformat v1.2.0 config=3f2a9c1e time=2024-05-01T12:00:00Z source=config.json
+//	// {
+//	//   ...
+//	// }
+func WithHeaderComment(source string) ConfigOption {
+	return func(c *Config) {
+		c.HeaderComment = true
+		c.HeaderSource = source
+	}
+}
+
+// writesHeader reports whether the output starts with a header comment
+func (c *Config) writesHeader() bool {
+	return c.HeaderComment && !c.StrictJSON
+}
+
+// writeHeader writes the header comment line when it is enabled
+func (f *Formatter) writeHeader(w io.StringWriter) error {
+	if !f.config.writesHeader() {
+		return nil
+	}
+	if _, err := w.WriteString(f.header() + "\n"); err != nil {
+		return WrapFormatError("failed to write header comment", err)
+	}
+	return nil
+}
+
+// header returns the header comment without a line break
+func (f *Formatter) header() string {
+	fingerprint := sha256.Sum256([]byte(f.config.fingerprint()))
+	fields := []string{
+		"// jsonformat " + moduleVersion(),
+		"config=" + hex.EncodeToString(fingerprint[:4]),
+		"time=" + headerTime().UTC().Format(time.RFC3339),
+	}
+	if f.config.HeaderSource != "" {
+		// Line breaks would end the comment early
+		source := strings.NewReplacer("\n", `\n`, "\r", `\r`).Replace(f.config.HeaderSource)
+		fields = append(fields, "source="+source)
+	}
+	return strings.Join(fields, " ")
+}
+
+// moduleVersion returns the version of this module in the running binary,
+// or "devel" when it is not known
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "devel"
+	}
+	return version
+}
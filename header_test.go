@@ -0,0 +1,107 @@
+package jsonformat
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderComment(t *testing.T) {
+	saved := headerTime
+	headerTime = func() time.Time { return time.Date(2024, 5, 1, 21, 0, 0, 0, time.FixedZone("JST", 9*60*60)) }
+	defer func() { headerTime = saved }()
+
+	formatter := NewFormatter(NewConfig(WithHeaderComment("config.json")))
+	header := regexp.MustCompile(`^// jsonformat \S+ config=[0-9a-f]{8} time=2024-05-01T12:00:00Z source=config\.json\n`)
+
+	result, err := formatter.Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !header.MatchString(result) || !strings.HasSuffix(result, "\n{\n  \"a\": 1\n}") {
+		t.Errorf("Unexpected output:\n%s", result)
+	}
+
+	var output bytes.Buffer
+	if err := formatter.FormatTo(&output, strings.NewReader(`{"a":1}`)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.String() != result {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", result, output.String())
+	}
+
+	// The fingerprint identifies the configuration
+	other, err := NewFormatter(NewConfig(WithHeaderComment("config.json"), WithIndentSize(4))).Format(`{"a":1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.SplitN(other, "\n", 2)[0] == strings.SplitN(result, "\n", 2)[0] {
+		t.Error("Expected different configurations to have different headers")
+	}
+
+	// Line breaks in the source name would end the comment early
+	result, err = NewFormatter(NewConfig(WithHeaderComment("a\nb"))).Format(`1`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(result, ` source=a\nb`+"\n1") {
+		t.Errorf("Unexpected output:\n%s", result)
+	}
+}
+
+func TestHeaderCommentIsNotCached(t *testing.T) {
+	saved := headerTime
+	defer func() { headerTime = saved }()
+
+	formatter := NewFormatter(NewConfig(WithHeaderComment("")))
+	formatter.SetCache(NewCache(4))
+	headerTime = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+	first, err := formatter.Format(`[]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	headerTime = func() time.Time { return time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC) }
+	second, err := formatter.Format(`[]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first == second || !strings.Contains(second, "time=2024-05-02T00:00:00Z") {
+		t.Errorf("Expected a fresh timestamp, got:\n%s", second)
+	}
+}
+
+func TestStrictJSON(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"properties":{"a":{"default":1}},"required":["b"]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := NewConfig(
+		WithHeaderComment("config.json"),
+		WithSectionComments("*"),
+		WithSchema(schema),
+		WithMissingRequired(),
+		WithDefaultFolding(FoldDefaultsAnnotate),
+		WithMaxWidth(10),
+		WithOverflowSummary(1),
+		WithStrictJSON(),
+	)
+
+	result, err := NewFormatter(config).Format(`{"a":1,"c":[{"x":1,"y":2}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "a": 1,
+  "c": [
+    {
+      "x": 1,
+      "y": 2
+    }
+  ]
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
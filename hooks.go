@@ -0,0 +1,281 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RawValue is a value hook result that is written to the output verbatim.
+// It allows hooks to emit text that is not a JSON literal, such as
+// placeholders in documentation templates.
+type RawValue string
+
+// ValueContext describes a scalar value passed to a ValueHook.
+type ValueContext struct {
+	// Path contains the object keys and array indices leading to the value.
+	// Array indices are given in decimal, e.g. ["users", "0", "email"].
+	Path []string
+
+	// Key is the object key of the value, or empty if the value is an array element.
+	Key string
+
+	// Value is the current value: a string, float64, bool, nil, or a RawValue
+	// produced by an earlier hook.
+	Value interface{}
+
+	salt string // AnonymizationSalt of the configuration
+}
+
+// ValueHook inspects a scalar value before it is written to the output.
+// It returns the replacement value and true to replace the value, or
+// false to keep it. Replacement values must be a string, float64, bool,
+// nil, or RawValue, or an AuditedValue holding one of them.
+type ValueHook func(ctx ValueContext) (interface{}, bool)
+
+// MatchPath reports whether the path of the value matches the pattern.
+// A pattern is a dot-separated list of segments, where "*" matches any
+// single key or array index, e.g. "users.*.email".
+func (c ValueContext) MatchPath(pattern string) bool {
+	return matchPath(pattern, c.Path)
+}
+
+// matchPath reports whether path matches a dot-separated pattern
+func matchPath(pattern string, path []string) bool {
+	return compilePattern(pattern).match(path)
+}
+
+// matchRootedPath reports whether path matches the pattern like matchPath,
+// with "" matching the root
+func matchRootedPath(pattern string, path []string) bool {
+	return compilePattern(pattern).matchRooted(path)
+}
+
+// pathPattern is a dot-separated path pattern split into its segments once,
+// so that matching it against the path of every token does not split it again
+type pathPattern struct {
+	source   string   // Pattern as given
+	segments []string // Segments of the pattern, "*" matching any key or index
+}
+
+// compilePattern splits a dot-separated pattern into its segments
+func compilePattern(pattern string) pathPattern {
+	return pathPattern{source: pattern, segments: strings.Split(pattern, ".")}
+}
+
+// compilePatterns splits each of the patterns into its segments
+func compilePatterns(patterns []string) []pathPattern {
+	if len(patterns) == 0 {
+		return nil
+	}
+	compiled := make([]pathPattern, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = compilePattern(pattern)
+	}
+	return compiled
+}
+
+// match reports whether path matches the pattern as in matchPath
+func (pp pathPattern) match(path []string) bool {
+	if len(pp.segments) != len(path) {
+		return false
+	}
+	for i, segment := range pp.segments {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRooted reports whether path matches the pattern as in matchRootedPath
+func (pp pathPattern) matchRooted(path []string) bool {
+	if pp.source == "" {
+		return len(path) == 0
+	}
+	return pp.match(path)
+}
+
+// firstMatch returns the first of the patterns that path matches
+func firstMatch(patterns []pathPattern, path []string) (string, bool) {
+	for _, pattern := range patterns {
+		if pattern.match(path) {
+			return pattern.source, true
+		}
+	}
+	return "", false
+}
+
+// anyRootedMatch reports whether path matches at least one of the patterns,
+// with "" matching the root
+func anyRootedMatch(patterns []pathPattern, path []string) bool {
+	for _, pattern := range patterns {
+		if pattern.matchRooted(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithValueHook adds a hook that can replace scalar values (strings, numbers,
+// booleans, and null) during formatting. Object keys are not passed to hooks.
+// Hooks run in the order they were added, and each hook sees the value
+// returned by the previous one. A nil hook is ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+//	    if ctx.Key == "password" {
+//	        return "********", true
+//	    }
+//	    return nil, false
+//	}))
+func WithValueHook(hook ValueHook) ConfigOption {
+	return func(c *Config) {
+		if hook != nil {
+			c.ValueHooks = append(c.ValueHooks, hook)
+		}
+	}
+}
+
+// PostProcessFunc rewrites a complete output line before it is written.
+// The line is given without its newline. Depth is the nesting depth of the
+// line, and path is the dot-separated path of the key or element that starts
+// on the line, or of the container for closing brackets, e.g. "users.0.email".
+// The root value has depth 0 and an empty path.
+type PostProcessFunc func(line string, depth int, path string) string
+
+// WithPostProcess sets a function that is called for each output line before
+// it is written, as a final escape hatch for adjustments that no option
+// provides, such as custom markers or extra padding. Width limits are applied
+// to the lines before post-processing. A nil function is ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithPostProcess(func(line string, depth int, path string) string {
+//	    if path == "version" {
+//	        return line + " // bump on release"
+//	    }
+//	    return line
+//	}))
+func WithPostProcess(postProcess PostProcessFunc) ConfigOption {
+	return func(c *Config) {
+		if postProcess != nil {
+			c.PostProcess = postProcess
+		}
+	}
+}
+
+// startLine records the depth and path of a new output line for the post-processor
+func (p *TokenParser) startLine() {
+	if p.output == nil || p.output.postProcess == nil || p.capture != nil {
+		return
+	}
+	segments := make([]string, len(p.path))
+	for i, level := range p.path {
+		if i < len(p.inArray) && p.inArray[i] {
+			segments[i] = strconv.Itoa(level.index)
+		} else {
+			segments[i] = level.key
+		}
+	}
+	p.output.startLine(p.depth, strings.Join(segments, "."))
+}
+
+// applyValueHooks passes scalar value tokens through the configured value hooks
+func (p *TokenParser) applyValueHooks(token json.Token) (json.Token, error) {
+	if len(p.config.ValueHooks) == 0 || !p.isScalarValue(token) {
+		return token, nil
+	}
+	if _, raw := token.(RawValue); raw {
+		// Only values at raw paths are RawValues here, and they are kept verbatim
+		return token, nil
+	}
+
+	path := p.valuePath()
+	key := ""
+	if !p.isInArray() && len(path) > 0 {
+		key = path[len(path)-1]
+	}
+
+	for _, hook := range p.config.ValueHooks {
+		replacement, ok := hook(ValueContext{Path: path, Key: key, Value: token, salt: p.config.AnonymizationSalt})
+		if !ok {
+			continue
+		}
+		if audited, ok := replacement.(AuditedValue); ok {
+			if p.auditing {
+				p.audit = append(p.audit, AuditEntry{Path: path, Rule: audited.Rule, Action: audited.Action})
+			}
+			replacement = audited.Value
+		}
+		switch replacement.(type) {
+		case string, float64, bool, nil, RawValue:
+			token = replacement
+		default:
+			return nil, NewFormatError(fmt.Sprintf("value hook returned unsupported type: %T", replacement))
+		}
+	}
+	if p.config.StrictJSON {
+		token = strictPlaceholder(token)
+	}
+	return token, nil
+}
+
+// isScalarValue reports whether the token is a scalar value rather than a delimiter or key
+func (p *TokenParser) isScalarValue(token json.Token) bool {
+	switch token.(type) {
+	case json.Delim:
+		return false
+	case string:
+		return !p.expectingKey
+	case foldedMembers, foldedElements, skippedElements, truncatedMarker:
+		return false
+	default:
+		return true
+	}
+}
+
+// isValueStart reports whether the token starts a new value (a scalar or a container)
+func (p *TokenParser) isValueStart(token json.Token) bool {
+	if delim, ok := token.(json.Delim); ok {
+		return delim == '{' || delim == '['
+	}
+	return p.isScalarValue(token)
+}
+
+// valuePath returns the path of the next value at the current position
+func (p *TokenParser) valuePath() []string {
+	if len(p.path) != len(p.inArray) {
+		return nil
+	}
+	path := make([]string, len(p.path))
+	for i, level := range p.path {
+		if !p.inArray[i] {
+			path[i] = level.key
+			continue
+		}
+		index := level.index
+		if i == len(p.path)-1 {
+			// The next value in the innermost array has not been counted yet
+			index++
+		}
+		path[i] = strconv.Itoa(index)
+	}
+	return path
+}
@@ -0,0 +1,230 @@
+package jsonformat
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueHookContext(t *testing.T) {
+	var contexts []ValueContext
+	config := NewConfig(WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		contexts = append(contexts, ctx)
+		return nil, false
+	}))
+
+	_, err := NewFormatter(config).Format(`{"name":"Alice","tags":["a",{"x":null}],"meta":{"age":30,"ok":true}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []ValueContext{
+		{Path: []string{"name"}, Key: "name", Value: "Alice"},
+		{Path: []string{"tags", "0"}, Key: "", Value: "a"},
+		{Path: []string{"tags", "1", "x"}, Key: "x", Value: nil},
+		{Path: []string{"meta", "age"}, Key: "age", Value: float64(30)},
+		{Path: []string{"meta", "ok"}, Key: "ok", Value: true},
+	}
+	if !reflect.DeepEqual(contexts, expected) {
+		t.Errorf("Expected contexts:\n%#v\n\nGot:\n%#v", expected, contexts)
+	}
+}
+
+func TestValueHookReplacement(t *testing.T) {
+	config := NewConfig(
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			if ctx.Key == "password" {
+				return "********", true
+			}
+			return nil, false
+		}),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			if ctx.Key == "count" {
+				return RawValue("0x10"), true
+			}
+			return nil, false
+		}),
+	)
+
+	result, err := NewFormatter(config).Format(`{"user":"alice","password":"secret","count":16}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "user": "alice",
+  "password": "********",
+  "count": 0x10
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestValueHookChaining(t *testing.T) {
+	config := NewConfig(
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			return ctx.Value.(float64) * 2, true
+		}),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			return ctx.Value.(float64) + 1, true
+		}),
+	)
+
+	result, err := NewFormatter(config).Format(`[1,2]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "[\n  3,\n  5\n]" {
+		t.Errorf("Expected hooks to be chained, got:\n%s", result)
+	}
+}
+
+func TestValueHookUnsupportedType(t *testing.T) {
+	config := NewConfig(WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		return 42, true
+	}))
+
+	_, err := NewFormatter(config).Format(`{"a":1}`)
+	if err == nil || !strings.Contains(err.Error(), "unsupported type: int") {
+		t.Errorf("Expected unsupported type error, got %v", err)
+	}
+}
+
+func TestValueHookWithWidthLimit(t *testing.T) {
+	calls := 0
+	config := NewConfig(
+		WithMaxWidth(20),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			calls++
+			return strings.ToUpper(ctx.Value.(string)), true
+		}),
+	)
+
+	result, err := NewFormatter(config).Format(`{"a":{"b":["alpha","beta","gamma"]}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "a": {
+    "b": [
+      "ALPHA",
+      "BETA",
+      "GAMMA"
+    ]
+  }
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	if calls != 3 {
+		t.Errorf("Expected hook to run once per value, ran %d times", calls)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     []string
+		expected bool
+	}{
+		{pattern: "users.0.email", path: []string{"users", "0", "email"}, expected: true},
+		{pattern: "users.*.email", path: []string{"users", "3", "email"}, expected: true},
+		{pattern: "users.*", path: []string{"users", "3", "email"}, expected: false},
+		{pattern: "users.*.name", path: []string{"users", "3", "email"}, expected: false},
+		{pattern: "*", path: []string{"id"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		ctx := ValueContext{Path: tt.path}
+		if result := ctx.MatchPath(tt.pattern); result != tt.expected {
+			t.Errorf("MatchPath(%q) on %v = %t, expected %t", tt.pattern, tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestValueHookPathsAfterWidthReplay(t *testing.T) {
+	var paths []string
+	config := NewConfig(WithMaxWidth(20), WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		paths = append(paths, strings.Join(ctx.Path, "."))
+		return nil, false
+	}))
+
+	_, err := NewFormatter(config).Format(`{"items":[{"name":"a long value here"},{"name":"b"},{"name":"another long value"},{"name":"c"}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"items.0.name", "items.1.name", "items.2.name", "items.3.name"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected paths %v, got %v", expected, paths)
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	var lines []string
+	config := NewConfig(
+		WithCompactDepth(3),
+		WithPostProcess(func(line string, depth int, path string) string {
+			lines = append(lines, fmt.Sprintf("%d %q", depth, path))
+			if path == "users.1" {
+				return line + " // changed"
+			}
+			return line
+		}),
+	)
+	input := `{"users":[{"id":1},{"id":2}],"meta":{"count":2}}`
+	expected := `{
+  "users": [
+    {"id": 1},
+    {"id": 2} // changed
+  ],
+  "meta": {
+    "count": 2
+  }
+}`
+	expectedLines := []string{`0 ""`, `1 "users"`, `2 "users.0"`, `2 "users.1"`, `1 "users"`, `1 "meta"`, `2 "meta.count"`, `1 "meta"`, `0 ""`}
+
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("Expected lines %v, got %v", expectedLines, lines)
+	}
+
+	var output bytes.Buffer
+	if err := NewFormatter(config).FormatTo(&output, strings.NewReader(input)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, output.String())
+	}
+
+	if _, _, err := NewFormatter(config).FormatWithAnnotations(input); err == nil {
+		t.Error("Expected annotations to be rejected with a post-processor")
+	}
+}
+
+func TestPostProcessWithWidthLimit(t *testing.T) {
+	// Lines are measured before post-processing, so padding does not expand them
+	config := NewConfig(
+		WithCompactDepth(2),
+		WithMaxWidth(20),
+		WithPostProcess(func(line string, depth int, path string) string {
+			return line + strings.Repeat(" ", 30) + "|"
+		}),
+	)
+	result, err := NewFormatter(config).Format(`{"a":[1,2]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pad := strings.Repeat(" ", 30) + "|"
+	expected := "{" + pad + "\n  \"a\": [1, 2]" + pad + "\n}" + pad
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithIdempotenceCheck makes Format and FormatBytes verify that formatting
+// their output again gives the same output byte for byte, and return an error
+// otherwise. Formatters that are not idempotent cause endless diff churn in
+// repositories, so this guards formatting steps in CI.
+//
+// The check formats every document twice. Comments and markers written by
+// display-only options, such as WithHeaderComment, are not JSON and cannot be
+// formatted again, so unless WithStrictJSON is set the check formats the
+// document a third time with it and verifies that output instead. Value hooks
+// must give the same result when applied to their own output.
+func WithIdempotenceCheck() ConfigOption {
+	return func(c *Config) {
+		c.VerifyIdempotence = true
+	}
+}
+
+// CheckIdempotence formats the input and then formats the result again with
+// the same configuration. It returns an error describing the first changed
+// line if the second pass changes the output, or the error of either pass.
+//
+// Example:
+//
+//	if err := formatter.CheckIdempotence(input); err != nil {
+//	    log.Fatal(err)
+//	}
+func (f *Formatter) CheckIdempotence(jsonStr string) (err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	formatted, _, err := f.formatString(jsonStr, nil)
+	if err != nil {
+		return err
+	}
+	return f.verifyIdempotence(jsonStr, formatted)
+}
+
+// verifyIdempotence returns an error if formatting the output of the input
+// again changes it
+func (f *Formatter) verifyIdempotence(jsonStr, formatted string) error {
+	if !f.config.StrictJSON {
+		// Display-only comments and markers are left out of the comparison
+		strict := f.config.clone()
+		strict.StrictJSON = true
+		f = NewFormatter(strict)
+		var err error
+		if formatted, _, err = f.formatString(jsonStr, nil); err != nil {
+			return err
+		}
+	}
+	reformatted, _, err := f.formatString(formatted, nil)
+	if err != nil {
+		return WrapFormatError("output is not idempotent: it cannot be formatted again", err)
+	}
+	if reformatted == formatted {
+		return nil
+	}
+
+	before := strings.Split(formatted, "\n")
+	after := strings.Split(reformatted, "\n")
+	for i := 0; ; i++ {
+		var a, b string
+		if i < len(before) {
+			a = before[i]
+		}
+		if i < len(after) {
+			b = after[i]
+		}
+		if a != b || i >= len(before) || i >= len(after) {
+			return NewFormatError(fmt.Sprintf("output is not idempotent: line %d changes from %q to %q", i+1, a, b))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckIdempotence(t *testing.T) {
+	exclaim := WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		if s, ok := ctx.Value.(string); ok {
+			return s + "!", true
+		}
+		return nil, false
+	})
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{name: "default", options: nil},
+		{name: "width limit", options: []ConfigOption{WithMaxWidth(20), WithAdaptiveCompaction()}},
+		{name: "changing hook", options: []ConfigOption{exclaim}, expected: `output is not idempotent: line 2 changes from "  \"a\": \"x!\"," to "  \"a\": \"x!!\","`},
+		{name: "display only", options: []ConfigOption{WithSectionComments("*")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewFormatter(NewConfig(tt.options...)).CheckIdempotence(`{"a":"x","b":[{},[],{"c":[1,2,{"d":null}]}]}`)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected error starting with %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestIdempotenceCheckMode(t *testing.T) {
+	config := NewConfig(WithIdempotenceCheck(), WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		if n, ok := ctx.Value.(float64); ok {
+			return n + 1, true
+		}
+		return nil, false
+	}))
+
+	result, err := NewFormatter(config).Format(`[1]`)
+	if err == nil || result != "" {
+		t.Fatalf("Expected an idempotence error, got %q, %v", result, err)
+	}
+	formatErr, ok := err.(*FormatError)
+	if !ok || formatErr.Code() != CodeInternal {
+		t.Errorf("Expected an internal error, got %v", err)
+	}
+
+	result, err = NewFormatter(NewConfig(WithIdempotenceCheck())).Format(`[1]`)
+	if err != nil || result != "[\n  1\n]" {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+}
+
+func TestIdempotenceCheckDisplayOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		option ConfigOption
+		marker string
+	}{
+		{name: "header", option: WithHeaderComment("config.json"), marker: "// jsonformat"},
+		{name: "provenance", option: WithProvenance(map[string]string{"a": "base.json"}), marker: "/* from: base.json */"},
+		{name: "hard wrap", option: WithHardWrap(10), marker: "↪"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(tt.option, WithIdempotenceCheck())
+			result, err := NewFormatter(config).Format(`{"a":"a long string value","b":[1,2]}`)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(result, tt.marker) {
+				t.Errorf("Expected output containing %q, got:\n%s", tt.marker, result)
+			}
+		})
+	}
+}
+
+func FuzzIdempotence(f *testing.F) {
+	for _, seed := range []string{
+		`{}`, `[]`, `[[],{}]`, `{"a":{"b":[]}}`, `"s"`, `1e21`,
+		`{"users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}}}`,
+		`[{"a":1},{"b":[{"c":{}}]},1,"x",null,true]`,
+	} {
+		f.Add(seed)
+	}
+
+	formatters := []*Formatter{
+		NewFormatter(DefaultConfig()),
+		NewFormatter(NewConfig(WithCompactDepth(0))),
+		NewFormatter(NewConfig(WithCompactDepth(1), WithMaxWidth(16))),
+		NewFormatter(NewConfig(WithLeadingCommas(), WithTabs())),
+		NewFormatter(NewConfig(WithAdaptiveCompaction(), WithMaxWidth(30))),
+		NewFormatter(NewConfig(WithObjectIndent(IndentAligned), WithArrayIndent(IndentDouble))),
+		NewFormatter(NewConfig(WithBlankLineBetweenTopLevelKeys(), WithBlankLineBetweenLargeElements(10))),
+		NewFormatter(NewConfig(WithStyleVersion(StyleV2), WithCompactDepth(0))),
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, formatter := range formatters {
+			formatted, err := formatter.Format(input)
+			if err != nil {
+				return
+			}
+			if err := formatter.CheckIdempotence(formatted); err != nil {
+				t.Errorf("Formatting is not idempotent for %q: %v", input, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Edit replaces the value at a path of a formatted document.
+type Edit struct {
+	// Path contains the object keys and array indices leading to the value,
+	// in the same form as Annotation.Path. An empty path replaces the root.
+	Path []string
+
+	// Value is the new value as JSON text.
+	Value string
+}
+
+// Reformat applies an edit to a document formatted by FormatWithAnnotations
+// with the same configuration, and returns the new output and annotations.
+// The annotations serve as the source map of the document: only the subtree
+// affected by the edit is formatted again and spliced into the output, so
+// interactive editors can re-format large documents on every keystroke.
+// The result equals formatting the edited document from scratch.
+//
+// The affected subtree is the edited value, or its outermost ancestor at the
+// compact depth, since the width limit measures such elements as a whole. With
+// options that lay out elements depending on their siblings, such as
+// WithAdaptiveCompaction, WithCompactPaths, or KeyOrderScalarsFirstSorted, the
+// whole document is formatted again. Value hooks, key renames, raw paths,
+// KeyOrderScalarsFirst, and display-only options are not supported, the latter
+// unless WithStrictJSON is set.
+//
+// Example:
+//
+//	formatted, annotations, _ := formatter.FormatWithAnnotations(doc)
+//	formatted, annotations, err := formatter.Reformat(formatted, annotations,
+//	    Edit{Path: []string{"users", "0", "name"}, Value: `"Carol"`})
+func (f *Formatter) Reformat(formatted string, annotations []Annotation, edit Edit) (result string, updated []Annotation, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+			updated = nil
+		}
+	}()
+
+	if f.config.PostProcess != nil || len(f.config.ValueHooks) > 0 || len(f.config.EmbeddedJSON) > 0 || len(f.config.KeyRenames) > 0 || len(f.config.RawPaths) > 0 || (!f.config.StrictJSON && (len(f.config.SectionComments) > 0 || f.config.HardWrap > 0 ||
+		f.config.ShowMissingRequired || f.config.DefaultFolding == FoldDefaultsAnnotate || f.config.OverflowSummaryKeys > 0 || len(f.config.Provenance) > 0 || f.config.SpanDurations || f.config.WideObjectKeys > 0 || f.config.ExpandBudget > 0 || f.config.SampleSize > 0 || f.config.TimeBudget > 0 || f.config.BidiIsolation || f.config.QuoteStyle != nil)) {
+		return "", nil, NewFormatError("cannot format incrementally with value hooks, key renames, raw paths, a post-processor, or display-only options")
+	}
+	if f.config.KeyOrderPolicy == KeyOrderScalarsFirst {
+		// The input order of the members is not kept in the output
+		return "", nil, NewFormatError("cannot format incrementally with KeyOrderScalarsFirst")
+	}
+
+	value := edit.Value
+	if f.config.SpecialFloats != SpecialFloatsReject {
+		value, _ = replaceSpecialFloats(value)
+	}
+	if !json.Valid([]byte(value)) {
+		return "", nil, NewFormatError("invalid JSON value in edit at " + strings.Join(edit.Path, "."))
+	}
+
+	target := findValueAnnotation(annotations, edit.Path)
+	if target == nil {
+		return "", nil, NewFormatError("cannot format incrementally: no value at " + strings.Join(edit.Path, "."))
+	}
+
+	// Find the subtree whose layout may change with the edited value
+	node := target
+	if f.config.AdaptiveCompaction || f.config.BlankLineElementSize > 0 || (f.config.Schema != nil && f.config.DefaultFolding == FoldDefaultsHide) || f.config.KeyOrderPolicy != KeyOrderInput || len(f.config.CompactPaths) > 0 {
+		node = findValueAnnotation(annotations, nil)
+	}
+	for node != nil && len(node.Path) > 0 {
+		// Containers at the compact depth are measured as a whole, whether
+		// they were written on one line or expanded after overflowing
+		depth := len(node.Path)
+		if f.config.CompactDepth <= 0 || depth < f.config.CompactDepth {
+			break
+		}
+		node = findValueAnnotation(annotations, node.Path[:depth-1])
+	}
+	if node == nil || node.End > len(formatted) || target.End > node.End {
+		return "", nil, NewFormatError("cannot format incrementally: annotations do not match the document")
+	}
+
+	text := formatted[node.Start:target.Start] + edit.Value + formatted[target.End:node.End]
+	region, regionAnnotations, err := f.formatRegion(formatted, annotations, node, text)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Splice the region into the output and move the annotations after it
+	start, end := node.Start, node.End
+	delta := len(region) - (end - start)
+	result = formatted[:start] + region + formatted[end:]
+	updated = make([]Annotation, 0, len(annotations)+len(regionAnnotations))
+	inserted := false
+	for _, a := range annotations {
+		if !inserted && a.Start >= start {
+			updated = append(updated, regionAnnotations...)
+			inserted = true
+		}
+		switch {
+		case a.Start < start:
+			if a.End >= end {
+				// The annotation encloses the region
+				a.End += delta
+			}
+			updated = append(updated, a)
+		case a.Start < end:
+			// Replaced by the annotations of the region
+		default:
+			a.Start += delta
+			a.End += delta
+			updated = append(updated, a)
+		}
+	}
+	if !inserted {
+		updated = append(updated, regionAnnotations...)
+	}
+	return result, updated, nil
+}
+
+// formatRegion formats the text of a value at the position of the node
+// within the formatted document, and returns the output and annotations
+// of the value with offsets in the document.
+func (f *Formatter) formatRegion(formatted string, annotations []Annotation, node *Annotation, text string) (string, []Annotation, error) {
+	var specials []specialFloat
+	if f.config.SpecialFloats != SpecialFloatsReject {
+		text, specials = replaceSpecialFloats(text)
+	}
+	var buffer outputBuffer
+	p := f.newParser(strings.NewReader(text), &buffer, len(text))
+	p.specials = specials
+	p.annotating = true
+
+	// Recreate the containers around the node. They are written on several
+	// lines, as the node is the outermost value at the compact depth.
+	for i, segment := range node.Path {
+		container := findValueAnnotation(annotations, node.Path[:i])
+		if container == nil || container.Start >= len(formatted) {
+			return "", nil, NewFormatError("cannot format incrementally: annotations do not match the document")
+		}
+		last := i == len(node.Path)-1
+		elementStart := node.Start
+		if formatted[container.Start] == '{' {
+			if err := p.enterObject(); err != nil {
+				return "", nil, err
+			}
+			p.path[i].key = segment
+			if last {
+				key := findAnnotation(annotations, node.Path, AnnotationKey)
+				if key == nil {
+					return "", nil, NewFormatError("cannot format incrementally: annotations do not match the document")
+				}
+				elementStart = key.Start
+			}
+		} else {
+			if err := p.enterArray(); err != nil {
+				return "", nil, err
+			}
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return "", nil, WrapFormatError("cannot format incrementally: invalid array index", err)
+			}
+			if last {
+				// The index is counted again when the element starts
+				index--
+			}
+			p.path[i].index = index
+		}
+		p.path[i].compact = false
+		if last {
+			p.isFirstElement = strings.TrimSpace(formatted[container.Start+1:elementStart]) == ""
+			p.expectingKey = !p.isInArray()
+		}
+	}
+	if len(node.Path) > 0 && !p.isInArray() {
+		if err := p.processToken(node.Path[len(node.Path)-1]); err != nil {
+			return "", nil, err
+		}
+	}
+
+	tokenCount := 0
+	for {
+		token, err := p.decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, WrapFormatError("invalid JSON input", err)
+		}
+		if len(p.specials) > 0 {
+			token = p.restoreSpecialFloat(token)
+		}
+		tokenCount++
+		p.readTokens = tokenCount
+		if p.tooManyTokens(tokenCount) {
+			return "", nil, NewFormatError("JSON structure too complex or malformed (too many tokens)")
+		}
+		if err := p.processToken(token); err != nil {
+			return "", nil, err
+		}
+	}
+	if p.depth != len(node.Path) {
+		return "", nil, NewFormatError("malformed JSON: unclosed objects or arrays")
+	}
+
+	value := findValueAnnotation(p.annotations, node.Path)
+	if value == nil {
+		return "", nil, NewFormatError("input contains no valid JSON tokens")
+	}
+	// Move the annotations of the value to the position of the node
+	var regionAnnotations []Annotation
+	offset := node.Start - value.Start
+	for _, a := range p.annotations {
+		if a.Start >= value.Start && a.End <= value.End {
+			a.Start += offset
+			a.End += offset
+			regionAnnotations = append(regionAnnotations, a)
+		}
+	}
+	return buffer.String()[value.Start:value.End], regionAnnotations, nil
+}
+
+// findValueAnnotation returns the value annotation with the path, or nil
+func findValueAnnotation(annotations []Annotation, path []string) *Annotation {
+	return findAnnotation(annotations, path, AnnotationValue)
+}
+
+// findAnnotation returns the annotation of the kind with the path, or nil
+func findAnnotation(annotations []Annotation, path []string, kind AnnotationKind) *Annotation {
+	for i := range annotations {
+		a := &annotations[i]
+		if a.Kind != kind || len(a.Path) != len(path) {
+			continue
+		}
+		match := true
+		for j := range path {
+			if a.Path[j] != path[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReformatMatchesFormat(t *testing.T) {
+	input := `{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{}}`
+	tests := []struct {
+		name   string
+		path   string
+		value  string
+		edited string
+	}{
+		{"root member", "name", `"Carol"`, `{"name":"Carol","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{}}`},
+		{"inside compact element", "users.0.tags.1", `"operations-and-maintenance"`, `{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","operations-and-maintenance"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{}}`},
+		{"array element", "users.1", `{"id":3,"name":"Dave","note":"a rather long note that overflows the width limit"}`, `{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":3,"name":"Dave","note":"a rather long note that overflows the width limit"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{}}`},
+		{"first element", "users.0", `1`, `{"name":"Alice","users":[1,{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{}}`},
+		{"scalar to container", "meta.a.b.c", `{"x":[true,null]}`, `{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":{"x":[true,null]},"d":[1,2,3]}}},"empty":{}}`},
+		{"empty container", "empty", `{"k":"v"}`, `{"name":"Alice","users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}},"empty":{"k":"v"}}`},
+		{"root", "", `[1,2]`, `[1,2]`},
+	}
+	configs := map[string]*Config{
+		"default":        DefaultConfig(),
+		"expanded":       NewConfig(WithCompactDepth(0)),
+		"width":          NewConfig(WithMaxWidth(30)),
+		"leading commas": NewConfig(WithLeadingCommas(), WithMaxWidth(40)),
+		"style v2":       NewConfig(WithStyleVersion(StyleV2), WithTabs()),
+		"adaptive":       NewConfig(WithAdaptiveCompaction(), WithMaxWidth(50)),
+		"key order":      NewConfig(WithKeyOrder("", "meta", "*"), WithKeyOrder("users.*", "name")),
+		"compact keys":   NewConfig(WithCompactKeys("users", "b"), WithMaxWidth(40)),
+		"scalars first":  NewConfig(WithKeyOrderPolicy(KeyOrderScalarsFirstSorted), WithKeyOrder("users.*", "name")),
+		"namespaces":     NewConfig(WithNamespaceGrouping("a"), WithCompactDepth(0)),
+		"path layout":    NewConfig(WithCompactPaths("users.*"), WithExpandedPaths("meta.a.b"), WithSortedKeys("meta.a.b"), WithMaxWidth(40)),
+	}
+
+	for name, config := range configs {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				formatter := NewFormatter(config)
+				formatted, annotations, err := formatter.FormatWithAnnotations(input)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				var path []string
+				if tt.path != "" {
+					path = strings.Split(tt.path, ".")
+				}
+
+				result, updated, err := formatter.Reformat(formatted, annotations, Edit{Path: path, Value: tt.value})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				expected, expectedAnnotations, err := formatter.FormatWithAnnotations(tt.edited)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if result != expected {
+					t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+				}
+				if !reflect.DeepEqual(updated, expectedAnnotations) {
+					t.Errorf("Expected annotations:\n%#v\n\nGot:\n%#v", expectedAnnotations, updated)
+				}
+			})
+		}
+	}
+}
+
+func TestReformatRepeatedEdits(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithMaxWidth(40)))
+	formatted, annotations, err := formatter.FormatWithAnnotations(`{"items":[{"id":1,"label":"a"},{"id":2,"label":"b"}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Typing into a value re-formats it on every keystroke
+	label := ""
+	for _, c := range "a label long enough to overflow" {
+		label += string(c)
+		formatted, annotations, err = formatter.Reformat(formatted, annotations, Edit{Path: []string{"items", "1", "label"}, Value: `"` + label + `"`})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected, err := formatter.Format(`{"items":[{"id":1,"label":"a"},{"id":2,"label":"` + label + `"}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if formatted != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestReformatErrors(t *testing.T) {
+	input := `{"a":{"b":1}}`
+	tests := []struct {
+		name   string
+		config *Config
+		edit   Edit
+		errMsg string
+	}{
+		{"missing path", DefaultConfig(), Edit{Path: []string{"a", "c"}, Value: `2`}, "no value at a.c"},
+		{"invalid value", DefaultConfig(), Edit{Path: []string{"a", "b"}, Value: `{"x":`}, "invalid JSON value in edit at a.b"},
+		{"several values", DefaultConfig(), Edit{Path: []string{"a", "b"}, Value: `1 2`}, "invalid JSON value"},
+		{"display only", NewConfig(WithSectionComments("a")), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"provenance", NewConfig(WithProvenance(map[string]string{"a": "base.json"})), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"embedded JSON", NewConfig(WithEmbeddedJSON("a")), Edit{Path: []string{"a", "b"}, Value: `2`}, "value hooks"},
+		{"span durations", NewConfig(WithSpanDurations()), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"wide object fold", NewConfig(WithWideObjectFold(1)), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"expand budget", NewConfig(WithExpandBudget(1)), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"sample array", NewConfig(WithSampleArray(1, 0)), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"time budget", NewConfig(WithTimeBudget(time.Second)), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"bidi isolation", NewConfig(WithBidiIsolation()), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"quote style", NewConfig(WithQuoteStyle(PresentationQuotes)), Edit{Path: []string{"a", "b"}, Value: `2`}, "display-only options"},
+		{"scalars first", NewConfig(WithKeyOrderPolicy(KeyOrderScalarsFirst)), Edit{Path: []string{"a", "b"}, Value: `2`}, "KeyOrderScalarsFirst"},
+		{"key rename", NewConfig(WithKeyRename("a.b", "c")), Edit{Path: []string{"a", "b"}, Value: `2`}, "key renames"},
+		{"raw paths", NewConfig(WithRawPaths("a")), Edit{Path: []string{"a", "b"}, Value: `2`}, "raw paths"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(tt.config)
+			formatted, annotations, err := formatter.FormatWithAnnotations(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_, _, err = formatter.Reformat(formatted, annotations, tt.edit)
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got none", tt.errMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
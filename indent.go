@@ -0,0 +1,80 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+// ContainerIndent selects how far the contents of a container are indented.
+type ContainerIndent int
+
+const (
+	// IndentNested indents contents one level deeper than the container.
+	IndentNested ContainerIndent = iota
+	// IndentAligned aligns contents with the opening line of the container,
+	// so they are not nested further.
+	IndentAligned
+	// IndentDouble indents contents two levels deeper than the container.
+	IndentDouble
+)
+
+// levels returns the number of indentation levels the container adds
+func (c ContainerIndent) levels() int {
+	switch c {
+	case IndentAligned:
+		return 0
+	case IndentDouble:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// valid reports whether c is one of the defined ContainerIndent values
+func (c ContainerIndent) valid() bool {
+	return c >= IndentNested && c <= IndentDouble
+}
+
+// WithObjectIndent sets how far object members are indented.
+// Invalid values are ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithObjectIndent(IndentDouble))
+func WithObjectIndent(indent ContainerIndent) ConfigOption {
+	return func(c *Config) {
+		if indent.valid() {
+			c.ObjectIndent = indent
+		}
+	}
+}
+
+// WithArrayIndent sets how far array elements are indented.
+// IndentAligned suits house styles that align array elements with the
+// bracket instead of nesting them.
+//
+// Example:
+//
+//	config := NewConfig(WithArrayIndent(IndentAligned))
+//	// {
+//	//   "tags": [
+//	//   "a",
+//	//   "b"
+//	//   ]
+//	// }
+func WithArrayIndent(indent ContainerIndent) ConfigOption {
+	return func(c *Config) {
+		if indent.valid() {
+			c.ArrayIndent = indent
+		}
+	}
+}
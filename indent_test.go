@@ -0,0 +1,78 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestContainerIndent(t *testing.T) {
+	input := `{"tags":["a","b"],"users":[{"id":1,"roles":["admin"]}],"meta":{"a":1}}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "arrays aligned with the bracket",
+			options: []ConfigOption{WithArrayIndent(IndentAligned)},
+			expected: `{
+  "tags": [
+  "a",
+  "b"
+  ],
+  "users": [
+  {"id": 1, "roles": ["admin"]}
+  ],
+  "meta": {
+    "a": 1
+  }
+}`,
+		},
+		{
+			name:    "arrays indented twice",
+			options: []ConfigOption{WithArrayIndent(IndentDouble), WithCompactDepth(0)},
+			expected: `{
+  "tags": [
+      "a",
+      "b"
+  ],
+  "users": [
+      {
+        "id": 1,
+        "roles": [
+            "admin"
+        ]
+      }
+  ],
+  "meta": {
+    "a": 1
+  }
+}`,
+		},
+		{
+			name:     "objects aligned with tabs",
+			options:  []ConfigOption{WithObjectIndent(IndentAligned), WithTabs()},
+			expected: "{\n\"tags\": [\n\t\"a\",\n\t\"b\"\n],\n\"users\": [\n\t{\"id\": 1, \"roles\": [\"admin\"]}\n],\n\"meta\": {\n\"a\": 1\n}\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestContainerIndentValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.ArrayIndent = ContainerIndent(-1)
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected validation error for invalid ArrayIndent")
+	}
+}
@@ -1288,3 +1288,126 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestLeadingCommas(t *testing.T) {
+	input := `{"tags":["a","b"],"users":[{"id":1,"name":"Alice"},{"id":2}],"n":null}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "expanded elements start with commas",
+			options: []ConfigOption{WithLeadingCommas()},
+			expected: `{
+  "tags": [
+    "a"
+    , "b"
+  ]
+  , "users": [
+    {"id": 1, "name": "Alice"}
+    , {"id": 2}
+  ]
+  , "n": null
+}`,
+		},
+		{
+			name:    "fully expanded",
+			options: []ConfigOption{WithLeadingCommas(), WithCompactDepth(0)},
+			expected: `{
+  "tags": [
+    "a"
+    , "b"
+  ]
+  , "users": [
+    {
+      "id": 1
+      , "name": "Alice"
+    }
+    , {
+      "id": 2
+    }
+  ]
+  , "n": null
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCommasAfterEmptyContainers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "in an object",
+			input: `{"a":[],"b":{}}`,
+			expected: `{
+  "a": [
+  ],
+  "b": {
+  }
+}`,
+		},
+		{
+			name:  "in an array",
+			input: `[[],{},1]`,
+			expected: `[
+  [
+  ],
+  {
+  },
+  1
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(DefaultConfig()).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestKeysAfterNestedContainers(t *testing.T) {
+	input := `{"a":{"b":1},"c":{"d":[1]},"e":[{"f":2}],"g":3}`
+	expected := `{
+  "a": {
+    "b": 1
+  },
+  "c": {
+    "d": [1]
+  },
+  "e": [
+    {"f": 2}
+  ],
+  "g": 3
+}`
+	result, err := NewFormatter(DefaultConfig()).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
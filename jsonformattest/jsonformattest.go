@@ -0,0 +1,41 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonformattest provides helpers for testing code that formats JSON
+// with jsonformat, such as configurations shared across a repository.
+package jsonformattest
+
+import (
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// AssertIdempotent reports a test error for each input whose formatted output
+// changes when it is formatted again with the same formatter.
+//
+// Example:
+//
+//	func TestFormatterConfig(t *testing.T) {
+//	    formatter := jsonformat.NewFormatter(config)
+//	    jsonformattest.AssertIdempotent(t, formatter, `{"a":[1,2]}`, `[{"b":{}}]`)
+//	}
+func AssertIdempotent(t testing.TB, formatter *jsonformat.Formatter, inputs ...string) {
+	t.Helper()
+	for _, input := range inputs {
+		if err := formatter.CheckIdempotence(input); err != nil {
+			t.Errorf("Formatting is not idempotent for %q: %v", input, err)
+		}
+	}
+}
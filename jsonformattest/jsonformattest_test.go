@@ -0,0 +1,40 @@
+package jsonformattest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// recorder captures errors reported through testing.TB
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertIdempotent(t *testing.T) {
+	r := &recorder{TB: t}
+	AssertIdempotent(r, jsonformat.NewFormatter(jsonformat.DefaultConfig()), `{"a":[1,2]}`, `[{"b":{}}]`)
+	if len(r.errors) != 0 {
+		t.Errorf("Unexpected errors: %v", r.errors)
+	}
+
+	counter := jsonformat.WithValueHook(func(ctx jsonformat.ValueContext) (interface{}, bool) {
+		if n, ok := ctx.Value.(float64); ok {
+			return n + 1, true
+		}
+		return nil, false
+	})
+	r = &recorder{TB: t}
+	AssertIdempotent(r, jsonformat.NewFormatter(jsonformat.NewConfig(counter)), `[1]`, `"x"`, `{`)
+	if len(r.errors) != 2 {
+		t.Errorf("Expected errors for the changing and the invalid input, got %v", r.errors)
+	}
+}
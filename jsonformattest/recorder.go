@@ -0,0 +1,305 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformattest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// UpdateEnv names the environment variable that makes recorders record new
+// snapshots instead of replaying existing ones, when set to a non-empty value.
+const UpdateEnv = "JSONFORMAT_UPDATE_SNAPSHOTS"
+
+// Recorder is an http.RoundTripper that records the requests and responses
+// of a test into a snapshot file and replays them in later runs, like a
+// cassette. Bodies that are JSON are stored as JSON and the whole snapshot
+// is formatted with the formatter, so snapshots are readable and their
+// changes review well.
+//
+// A recorder records when its snapshot file does not exist or Update is set,
+// sending requests through Transport, and writes the file when the test
+// ends. Otherwise it replays: each request gets the first unused recorded
+// response with the same method and request URI, so the host of an
+// httptest.Server may change between runs, and a request whose body differs
+// from the recorded one is reported as a test error.
+//
+// Example:
+//
+//	func TestClient(t *testing.T) {
+//	    recorder := jsonformattest.NewRecorder(t, jsonformat.NewFormatter(jsonformat.DefaultConfig()))
+//	    client := api.NewClient(recorder.Client())
+//	    // ...
+//	}
+type Recorder struct {
+	// Path is the snapshot file. NewRecorder sets it to
+	// testdata/snapshots/<test name>.json.
+	Path string
+
+	// Transport sends the requests while recording. Nil uses
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Update records a new snapshot even when the file exists. NewRecorder
+	// sets it when the UpdateEnv environment variable is set.
+	Update bool
+
+	t         testing.TB
+	formatter *jsonformat.Formatter
+
+	mu        sync.Mutex
+	started   bool
+	recording bool
+	exchanges []Exchange
+	used      []bool // Whether each replayed exchange has been used
+}
+
+// Exchange is a request and its response in a snapshot.
+type Exchange struct {
+	Request  SnapshotRequest  `json:"request"`
+	Response SnapshotResponse `json:"response"`
+}
+
+// SnapshotRequest is a recorded request. Body holds a JSON body, and Text
+// any other body.
+type SnapshotRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// SnapshotResponse is a recorded response. Body holds a JSON body, and Text
+// any other body.
+type SnapshotResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// NewRecorder returns a recorder for the test whose snapshot is formatted
+// with the formatter, or the default configuration if it is nil. A
+// recording recorder writes its snapshot when the test and its subtests
+// complete.
+func NewRecorder(t testing.TB, formatter *jsonformat.Formatter) *Recorder {
+	t.Helper()
+	if formatter == nil {
+		formatter = jsonformat.NewFormatter(jsonformat.DefaultConfig())
+	}
+	name := strings.NewReplacer("/", "_", `\`, "_", ":", "_", " ", "_").Replace(t.Name())
+	r := &Recorder{
+		Path:      filepath.Join("testdata", "snapshots", name+".json"),
+		Update:    os.Getenv(UpdateEnv) != "",
+		t:         t,
+		formatter: formatter,
+	}
+	t.Cleanup(func() {
+		if err := r.save(); err != nil {
+			t.Errorf("Failed to write snapshot %s: %v", r.Path, err)
+		}
+	})
+	return r
+}
+
+// Client returns an HTTP client that sends its requests through the recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays one request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.start(); err != nil {
+		return nil, err
+	}
+	if r.recording {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+// start decides whether to record or replay, and loads the snapshot to replay
+func (r *Recorder) start() error {
+	if r.started {
+		return nil
+	}
+	r.started = true
+	data, err := os.ReadFile(r.Path)
+	if r.Update || os.IsNotExist(err) {
+		r.recording = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &r.exchanges); err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", r.Path, err)
+	}
+	r.used = make([]bool, len(r.exchanges))
+	return nil
+}
+
+// record sends the request and keeps the exchange
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	exchange := Exchange{
+		Request:  SnapshotRequest{Method: req.Method, URL: req.URL.String()},
+		Response: SnapshotResponse{Status: resp.StatusCode, Header: resp.Header.Clone()},
+	}
+	exchange.Request.Body, exchange.Request.Text = splitBody(body)
+	exchange.Response.Body, exchange.Response.Text = splitBody(responseBody)
+	// The date changes on every run and would only add noise to diffs
+	exchange.Response.Header.Del("Date")
+	if len(exchange.Response.Header) == 0 {
+		exchange.Response.Header = nil
+	}
+	r.exchanges = append(r.exchanges, exchange)
+	return resp, nil
+}
+
+// replay returns the first unused recorded response to a matching request
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	for i, exchange := range r.exchanges {
+		if r.used[i] || exchange.Request.Method != req.Method || requestURI(exchange.Request.URL) != req.URL.RequestURI() {
+			continue
+		}
+		r.used[i] = true
+		if !sameBody(exchange.Request.Body, exchange.Request.Text, body) {
+			r.t.Errorf("Request body of %s %s differs from the snapshot %s:\n%s", req.Method, req.URL.RequestURI(), r.Path, body)
+		}
+
+		responseBody := []byte(exchange.Response.Text)
+		if exchange.Response.Body != nil {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, exchange.Response.Body); err != nil {
+				return nil, err
+			}
+			responseBody = compact.Bytes()
+		}
+		header := exchange.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", exchange.Response.Status, http.StatusText(exchange.Response.Status)),
+			StatusCode:    exchange.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(responseBody)),
+			ContentLength: int64(len(responseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no response to %s %s in snapshot %s", req.Method, req.URL.RequestURI(), r.Path)
+}
+
+// save writes the recorded exchanges to the snapshot file
+func (r *Recorder) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recording {
+		return nil
+	}
+	exchanges := r.exchanges
+	if exchanges == nil {
+		exchanges = []Exchange{}
+	}
+	data, err := json.Marshal(exchanges)
+	if err != nil {
+		return err
+	}
+	formatted, err := r.formatter.Format(string(data))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, []byte(formatted+"\n"), 0o644)
+}
+
+// splitBody returns a JSON body as JSON, or else as text
+func splitBody(body []byte) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ""
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body), ""
+	}
+	return nil, string(body)
+}
+
+// sameBody reports whether a request body matches a recorded one, comparing
+// JSON bodies as values
+func sameBody(recorded json.RawMessage, text string, body []byte) bool {
+	if recorded == nil {
+		return text == string(body) || text == "" && len(bytes.TrimSpace(body)) == 0
+	}
+	var a, b interface{}
+	if json.Unmarshal(recorded, &a) != nil || json.Unmarshal(body, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// requestURI returns the path and query of a recorded URL
+func requestURI(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return parsed.RequestURI()
+}
@@ -0,0 +1,108 @@
+package jsonformattest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if req.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "pong")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":7,"echo":`+string(body)+`}`)
+	}))
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	formatter := jsonformat.NewFormatter(jsonformat.NewConfig(jsonformat.WithCompactDepth(3)))
+
+	// exchange sends the requests of the test and checks the responses
+	exchange := func(t *testing.T, client *http.Client, baseURL string) {
+		resp, err := client.Post(baseURL+"/users?dry=1", "application/json", strings.NewReader(`{"name":"a"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusCreated || string(body) != `{"id":7,"echo":{"name":"a"}}` {
+			t.Errorf("Unexpected response %d: %s", resp.StatusCode, body)
+		}
+		resp, err = client.Get(baseURL + "/text")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		if string(body) != "pong" || resp.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("Unexpected response %q with %v", body, resp.Header)
+		}
+	}
+
+	t.Run("record", func(t *testing.T) {
+		recorder := NewRecorder(t, formatter)
+		recorder.Path = path
+		recorder.Update = false
+		exchange(t, recorder.Client(), server.URL)
+	})
+	server.Close()
+
+	snapshot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[
+  {
+    "request": {"method": "POST", "url": "` + server.URL + `/users?dry=1", "body": {"name": "a"}},
+    "response": {"status": 201, "header": {"Content-Length": ["28"], "Content-Type": ["application/json"]}, "body": {"id": 7, "echo": {"name": "a"}}}
+  },
+  {
+    "request": {"method": "GET", "url": "` + server.URL + `/text"},
+    "response": {"status": 200, "header": {"Content-Length": ["4"], "Content-Type": ["text/plain"]}, "text": "pong"}
+  }
+]
+`
+	if string(snapshot) != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, snapshot)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		recorder := NewRecorder(t, formatter)
+		recorder.Path = path
+		recorder.Update = false
+		// The server is closed, and the host of a new server would differ
+		exchange(t, recorder.Client(), "http://127.0.0.1:1")
+
+		if _, err := recorder.Client().Get("http://127.0.0.1:1/text"); err == nil || !strings.Contains(err.Error(), "no response to GET /text") {
+			t.Errorf("Expected an error for a used exchange, got %v", err)
+		}
+	})
+}
+
+func TestRecorderBodyMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := `[{"request": {"method": "POST", "url": "http://example.com/a", "body": {"n": 1}}, "response": {"status": 204}}]`
+	if err := os.WriteFile(path, []byte(snapshot), 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := &recorder{TB: t}
+	recorder := NewRecorder(r, nil)
+	recorder.Path = path
+	recorder.Update = false
+	resp, err := recorder.Client().Post("http://example.com/a", "application/json", strings.NewReader(`{"n": 2}`))
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Unexpected response %v, %v", resp, err)
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "Request body of POST /a differs") {
+		t.Errorf("Expected a body mismatch error, got %v", r.errors)
+	}
+}
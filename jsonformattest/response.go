@@ -0,0 +1,222 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformattest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// Body is the decoded JSON body of an HTTP response. Its assertions address
+// values by dot paths such as "items.0.id", where numbers index arrays and
+// "" is the whole body. A failing assertion reports a test error with the
+// whole body formatted and a pointer to the failing path, so the failure can
+// be understood without printing the response by hand.
+//
+// Example:
+//
+//	func TestGetUser(t *testing.T) {
+//	    recorder := httptest.NewRecorder()
+//	    handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/7", nil))
+//	    jsonformattest.RecordedBody(t, recorder, nil).
+//	        Equal("user.id", 7).
+//	        Equal("user.roles.0", "admin").
+//	        Absent("user.password")
+//	}
+//
+// A failure reads:
+//
+//	user.roles.0: expected "admin", got "viewer"
+//	{
+//	  "user": {
+//	    "id": 7,
+//	    "roles": ["viewer"]  // ← user.roles.0
+//	  }
+//	}
+type Body struct {
+	t         testing.TB
+	formatter *jsonformat.Formatter
+	raw       []byte
+	value     interface{}
+}
+
+// ResponseBody reads and decodes the JSON body of resp for assertions, and
+// replaces resp.Body so that it can be read again. Failures are formatted
+// with the formatter, or the default configuration if it is nil. A body
+// that cannot be read or is not JSON fails the test immediately.
+func ResponseBody(t testing.TB, resp *http.Response, formatter *jsonformat.Formatter) *Body {
+	t.Helper()
+	if formatter == nil {
+		formatter = jsonformat.NewFormatter(jsonformat.DefaultConfig())
+	}
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to read the response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+
+	b := &Body{t: t, formatter: formatter, raw: raw}
+	if err := json.Unmarshal(raw, &b.value); err != nil {
+		t.Fatalf("Response body is not JSON: %v\n%s", err, raw)
+	}
+	return b
+}
+
+// RecordedBody is ResponseBody for the response written to an
+// httptest.ResponseRecorder.
+func RecordedBody(t testing.TB, recorder *httptest.ResponseRecorder, formatter *jsonformat.Formatter) *Body {
+	t.Helper()
+	return ResponseBody(t, recorder.Result(), formatter)
+}
+
+// Value returns the value at the path, decoded as by encoding/json into an
+// interface{}, and whether it exists.
+func (b *Body) Value(path string) (interface{}, bool) {
+	value := b.value
+	for _, segment := range splitPath(path) {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = container[segment]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(container) {
+				return nil, false
+			}
+			value = container[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Equal asserts that the value at the path equals expected. Values are
+// compared as JSON, so numbers of any Go type equal the decoded numbers.
+func (b *Body) Equal(path string, expected interface{}) *Body {
+	b.t.Helper()
+	want, err := json.Marshal(expected)
+	if err != nil {
+		b.t.Errorf("%s: cannot encode the expected value: %v", displayPath(path), err)
+		return b
+	}
+	var normalized interface{}
+	json.Unmarshal(want, &normalized)
+
+	actual, ok := b.Value(path)
+	if !ok {
+		b.fail(path, fmt.Sprintf("expected %s, but the path does not exist", want))
+		return b
+	}
+	if !reflect.DeepEqual(actual, normalized) {
+		got, _ := json.Marshal(actual)
+		b.fail(path, fmt.Sprintf("expected %s, got %s", want, got))
+	}
+	return b
+}
+
+// Exists asserts that the path exists, with any value including null.
+func (b *Body) Exists(path string) *Body {
+	b.t.Helper()
+	if _, ok := b.Value(path); !ok {
+		b.fail(path, "expected a value, but the path does not exist")
+	}
+	return b
+}
+
+// Absent asserts that the path does not exist.
+func (b *Body) Absent(path string) *Body {
+	b.t.Helper()
+	if actual, ok := b.Value(path); ok {
+		got, _ := json.Marshal(actual)
+		b.fail(path, fmt.Sprintf("expected no value, got %s", got))
+	}
+	return b
+}
+
+// String returns the body formatted with the formatter, or the raw body if
+// it cannot be formatted.
+func (b *Body) String() string {
+	formatted, err := b.formatter.Format(string(b.raw))
+	if err != nil {
+		return string(b.raw)
+	}
+	return formatted
+}
+
+// fail reports a failed assertion on the path with the formatted body, in
+// which the line of the value at the path, or of the nearest value that
+// exists on the way to it, is marked
+func (b *Body) fail(path, message string) {
+	b.t.Helper()
+	formatted, annotations, err := b.formatter.FormatWithAnnotations(string(b.raw))
+	if err != nil {
+		b.t.Errorf("%s: %s\n%s", displayPath(path), message, b.raw)
+		return
+	}
+
+	// The value at the path, or else its nearest ancestor
+	segments := splitPath(path)
+	marked := -1
+	for i, annotation := range annotations {
+		if annotation.Kind != jsonformat.AnnotationValue || len(annotation.Path) > len(segments) || !slices.Equal(annotation.Path, segments[:len(annotation.Path)]) {
+			continue
+		}
+		if marked < 0 || len(annotation.Path) > len(annotations[marked].Path) {
+			marked = i
+		}
+	}
+	if marked >= 0 {
+		start := annotations[marked].Start
+		end := strings.IndexByte(formatted[start:], '\n')
+		if end < 0 {
+			end = len(formatted)
+		} else {
+			end += start
+		}
+		formatted = formatted[:end] + "  // ← " + displayPath(path) + formatted[end:]
+	}
+	b.t.Errorf("%s: %s\n%s", displayPath(path), message, formatted)
+}
+
+// splitPath returns the segments of a dot path, none for the root
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// displayPath returns the path as written in failures
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package jsonformattest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// serveJSON returns a recorder holding a JSON response with the body
+func serveJSON(body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", "application/json")
+	io.WriteString(recorder, body)
+	return recorder
+}
+
+func TestBodyAssertions(t *testing.T) {
+	r := &recorder{TB: t}
+	body := RecordedBody(r, serveJSON(`{"user":{"id":7,"roles":["admin"],"manager":null}}`), nil)
+	body.Equal("user.id", 7).
+		Equal("user.roles", []string{"admin"}).
+		Equal("user.roles.0", "admin").
+		Equal("user.manager", nil).
+		Exists("user.manager").
+		Absent("user.password").
+		Absent("user.roles.1")
+	if len(r.errors) != 0 {
+		t.Errorf("Unexpected errors: %v", r.errors)
+	}
+	if value, ok := body.Value(""); !ok || value == nil {
+		t.Errorf("Expected the root value, got %v", value)
+	}
+}
+
+func TestBodyFailure(t *testing.T) {
+	r := &recorder{TB: t}
+	formatter := jsonformat.NewFormatter(jsonformat.NewConfig(jsonformat.WithCompactDepth(3)))
+	body := RecordedBody(r, serveJSON(`{"user":{"id":7,"roles":["viewer"]}}`), formatter)
+	body.Equal("user.roles.0", "admin")
+	body.Exists("user.name")
+	body.Absent("user.id")
+	body.Equal("user.roles.x", 1)
+
+	expected := []string{
+		"user.roles.0: expected \"admin\", got \"viewer\"\n{\n  \"user\": {\n    \"id\": 7,\n    \"roles\": [\"viewer\"]  // ← user.roles.0\n  }\n}",
+		"user.name: expected a value, but the path does not exist\n{\n  \"user\": {  // ← user.name\n    \"id\": 7,\n    \"roles\": [\"viewer\"]\n  }\n}",
+		"user.id: expected no value, got 7\n{\n  \"user\": {\n    \"id\": 7,  // ← user.id\n    \"roles\": [\"viewer\"]\n  }\n}",
+		"user.roles.x: expected 1, but the path does not exist\n{\n  \"user\": {\n    \"id\": 7,\n    \"roles\": [\"viewer\"]  // ← user.roles.x\n  }\n}",
+	}
+	if len(r.errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(r.errors), r.errors)
+	}
+	for i := range expected {
+		if r.errors[i] != expected[i] {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected[i], r.errors[i])
+		}
+	}
+}
+
+func TestResponseBodyRestoresBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, `[1,2]`)
+	}))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recorder{TB: t}
+	ResponseBody(r, resp, nil).Equal("1", 2)
+	if len(r.errors) != 0 {
+		t.Errorf("Unexpected errors: %v", r.errors)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if string(data) != `[1,2]` {
+		t.Errorf("Expected the body to be readable again, got %q", data)
+	}
+}
+
+func TestBodyString(t *testing.T) {
+	body := RecordedBody(t, serveJSON(`{"a":1}`), nil)
+	if !strings.Contains(body.String(), "\"a\": 1") {
+		t.Errorf("Unexpected formatted body: %s", body.String())
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// WithKeyOrder writes the members of the objects at paths matching the
+// pattern in the order of the keys, so the fields that identify a document,
+// such as the metadata of a message envelope, come first. The pattern is a
+// dot-separated path as in ValueContext.MatchPath, with "" for the root
+// object. A "*" among the keys stands for the members not listed, in input
+// order; without it they follow the listed members. If several patterns
+// match an object, the first in lexicographic order is used.
+//
+// Example:
+//
+//	config := NewConfig(WithKeyOrder("", "id", "type", "*", "data"))
+//	// {"type": "created", "data": {}, "id": 1, "time": "12:00"}
+//	// →
+//	// {"id": 1, "type": "created", "time": "12:00", "data": {}}
+func WithKeyOrder(pattern string, keys ...string) ConfigOption {
+	return func(c *Config) {
+		if c.KeyOrder == nil {
+			c.KeyOrder = make(map[string][]string)
+		}
+		c.KeyOrder[pattern] = append([]string{}, keys...)
+	}
+}
+
+// WithSortedKeys writes the members of the objects at paths matching the
+// patterns sorted by key. Patterns are dot-separated paths as in
+// ValueContext.MatchPath, with "" for the root object. Objects with a key
+// order set with WithKeyOrder follow that order instead.
+//
+// Example:
+//
+//	config := NewConfig(WithSortedKeys("metadata.labels"))
+//	// {"tier": "web", "app": "shop"}  →  {"app": "shop", "tier": "web"}
+func WithSortedKeys(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.SortedKeys = append(c.SortedKeys, patterns...)
+	}
+}
+
+// KeyOrderPolicy selects how the members of objects without a key order set
+// with WithKeyOrder are ordered.
+type KeyOrderPolicy int
+
+const (
+	// KeyOrderInput keeps the members in input order.
+	KeyOrderInput KeyOrderPolicy = iota
+	// KeyOrderScalarsFirst writes members with scalar values before members
+	// with objects or arrays as values, each group in input order.
+	KeyOrderScalarsFirst
+	// KeyOrderScalarsFirstSorted writes members with scalar values before
+	// members with objects or arrays as values, each group sorted by key.
+	KeyOrderScalarsFirstSorted
+)
+
+// WithKeyOrderPolicy orders the members of every object by the policy, so
+// that, for example, the simple fields of a record are read before its nested
+// structures. Objects at paths with a key order set with WithKeyOrder follow
+// that order instead. Objects are held in memory until they end, so that their
+// members can be reordered.
+//
+// Example:
+//
+//	config := NewConfig(WithKeyOrderPolicy(KeyOrderScalarsFirstSorted))
+//	// {"tags": ["a"], "name": "x", "meta": {}, "id": 1}
+//	// →
+//	// {"id": 1, "name": "x", "meta": {}, "tags": ["a"]}
+func WithKeyOrderPolicy(policy KeyOrderPolicy) ConfigOption {
+	return func(c *Config) {
+		if policy >= KeyOrderInput && policy <= KeyOrderScalarsFirstSorted {
+			c.KeyOrderPolicy = policy
+		}
+	}
+}
+
+// orderState buffers an object until it is complete, so its members can be reordered
+type orderState struct {
+	keys   []string     // Order of the keys, or nil to order by the policy
+	sorted bool         // Whether the members are sorted by key
+	tokens []json.Token // Tokens of the object, including delimiters
+	depth  int          // Nesting depth within the object
+}
+
+// startOrder begins buffering the object if the token opens an object at a
+// path with a key order
+func (p *TokenParser) startOrder(token json.Token) bool {
+	if p.skipOrder {
+		p.skipOrder = false
+		return false
+	}
+	reorders := p.config.KeyOrderPolicy != KeyOrderInput || p.config.NamespaceSeparators != ""
+	if token != json.Delim('{') || (len(p.config.KeyOrder) == 0 && len(p.config.SortedKeys) == 0 && !reorders) {
+		return false
+	}
+	path := p.valuePath()
+	keys, ok := p.keyOrder(path)
+	sorted := anyRootedMatch(p.pathRules().sorted, path)
+	if !ok && !sorted && !reorders {
+		return false
+	}
+	p.order = &orderState{keys: keys, sorted: sorted, tokens: []json.Token{token}, depth: 1}
+	return true
+}
+
+// keyOrder returns the key order of the object at the path
+func (p *TokenParser) keyOrder(path []string) ([]string, bool) {
+	for _, pattern := range p.pathRules().keyOrder {
+		if pattern.matchRooted(path) {
+			return p.config.KeyOrder[pattern.source], true
+		}
+	}
+	return nil, false
+}
+
+// orderToken buffers an object token and replays the object with its members
+// reordered once it is complete
+func (p *TokenParser) orderToken(token json.Token) error {
+	order := p.order
+	order.tokens = append(order.tokens, token)
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			order.depth++
+		} else {
+			order.depth--
+		}
+	}
+	if order.depth > 0 {
+		return nil
+	}
+	p.order = nil
+
+	p.skipOrder = true
+	if order.keys != nil {
+		return p.replayTokens(reorderMembers(order.tokens, order.keys))
+	}
+	tokens := order.tokens
+	if order.sorted {
+		tokens = sortMembers(tokens)
+	}
+	if p.config.KeyOrderPolicy != KeyOrderInput {
+		tokens = groupMembers(tokens, p.config.KeyOrderPolicy == KeyOrderScalarsFirstSorted)
+	}
+	if p.config.NamespaceSeparators != "" {
+		tokens = p.groupNamespaces(tokens)
+	}
+	return p.replayTokens(tokens)
+}
+
+// reorderMembers returns the tokens of an object with its members in the
+// order of the keys
+func reorderMembers(tokens []json.Token, keys []string) []json.Token {
+	members := splitMembers(tokens)
+
+	// Index the members by key, so wide objects are reordered in linear time
+	byKey := make(map[string][]int, len(members))
+	for i, member := range members {
+		key := member[0].(string)
+		byKey[key] = append(byKey[key], i)
+	}
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key] = true
+	}
+
+	used := make([]bool, len(members))
+	ordered := make([]json.Token, 0, len(tokens))
+	ordered = append(ordered, tokens[0])
+	for _, key := range keys {
+		if key == "*" {
+			for i, member := range members {
+				if !used[i] && !listed[member[0].(string)] {
+					used[i] = true
+					ordered = append(ordered, member...)
+				}
+			}
+			continue
+		}
+		for _, i := range byKey[key] {
+			if !used[i] {
+				used[i] = true
+				ordered = append(ordered, members[i]...)
+			}
+		}
+	}
+	for i, member := range members {
+		if !used[i] {
+			ordered = append(ordered, member...)
+		}
+	}
+	return append(ordered, tokens[len(tokens)-1])
+}
+
+// groupMembers returns the tokens of an object with the members whose values
+// are scalars first, each group sorted by key if sorted is set
+func groupMembers(tokens []json.Token, sorted bool) []json.Token {
+	members := splitMembers(tokens)
+	sort.SliceStable(members, func(i, j int) bool {
+		iScalar, jScalar := isScalarMember(members[i]), isScalarMember(members[j])
+		if iScalar != jScalar {
+			return iScalar
+		}
+		return sorted && members[i][0].(string) < members[j][0].(string)
+	})
+
+	ordered := make([]json.Token, 0, len(tokens))
+	ordered = append(ordered, tokens[0])
+	for _, member := range members {
+		ordered = append(ordered, member...)
+	}
+	return append(ordered, tokens[len(tokens)-1])
+}
+
+// sortMembers returns the tokens of an object with its members sorted by key
+func sortMembers(tokens []json.Token) []json.Token {
+	members := splitMembers(tokens)
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i][0].(string) < members[j][0].(string)
+	})
+
+	ordered := make([]json.Token, 0, len(tokens))
+	ordered = append(ordered, tokens[0])
+	for _, member := range members {
+		ordered = append(ordered, member...)
+	}
+	return append(ordered, tokens[len(tokens)-1])
+}
+
+// isScalarMember reports whether the value of the member is a scalar
+func isScalarMember(member []json.Token) bool {
+	_, isDelim := member[1].(json.Delim)
+	return !isDelim
+}
+
+// splitMembers splits the tokens of an object into the key and value tokens
+// of each member
+func splitMembers(tokens []json.Token) [][]json.Token {
+	var members [][]json.Token
+	rest := tokens[1 : len(tokens)-1]
+	for len(rest) > 0 {
+		end := 2
+		for depth := 0; ; end++ {
+			if delim, ok := rest[end-1].(json.Delim); ok {
+				if delim == '{' || delim == '[' {
+					depth++
+				} else {
+					depth--
+				}
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		members = append(members, rest[:end])
+		rest = rest[end:]
+	}
+	return members
+}
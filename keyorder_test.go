@@ -0,0 +1,168 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestKeyOrder(t *testing.T) {
+	input := `{"data":{"b":1,"id":2},"type":"created","extra":true,"id":"e1","items":[{"z":1,"id":2}]}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "root with rest placeholder",
+			options:  []ConfigOption{WithKeyOrder("", "id", "type", "*", "data"), WithCompactDepth(1)},
+			expected: `{"id": "e1", "type": "created", "extra": true, "items": [{"z": 1, "id": 2}], "data": {"b": 1, "id": 2}}`,
+		},
+		{
+			name:     "rest last",
+			options:  []ConfigOption{WithKeyOrder("", "id", "missing", "type"), WithCompactDepth(1)},
+			expected: `{"id": "e1", "type": "created", "data": {"b": 1, "id": 2}, "extra": true, "items": [{"z": 1, "id": 2}]}`,
+		},
+		{
+			name:     "nested paths",
+			options:  []ConfigOption{WithKeyOrder("items.*", "id"), WithKeyOrder("data", "id"), WithCompactDepth(1)},
+			expected: `{"data": {"id": 2, "b": 1}, "type": "created", "extra": true, "id": "e1", "items": [{"id": 2, "z": 1}]}`,
+		},
+		{
+			name:    "expanded with adaptive compaction",
+			options: []ConfigOption{WithKeyOrder("", "items", "*"), WithAdaptiveCompaction()},
+			expected: `{
+  "items": [
+    {"z": 1, "id": 2}
+  ],
+  "data": {
+    "b": 1,
+    "id": 2
+  },
+  "type": "created",
+  "extra": true,
+  "id": "e1"
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestKeyOrderPolicy(t *testing.T) {
+	input := `{"tags":["a"],"name":"x","meta":{"z":{},"b":null,"a":1},"id":1,"active":true}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "input order",
+			options:  []ConfigOption{WithCompactDepth(1)},
+			expected: `{"tags": ["a"], "name": "x", "meta": {"z": {}, "b": null, "a": 1}, "id": 1, "active": true}`,
+		},
+		{
+			name:     "scalars first",
+			options:  []ConfigOption{WithKeyOrderPolicy(KeyOrderScalarsFirst), WithCompactDepth(1)},
+			expected: `{"name": "x", "id": 1, "active": true, "tags": ["a"], "meta": {"b": null, "a": 1, "z": {}}}`,
+		},
+		{
+			name:     "scalars first sorted",
+			options:  []ConfigOption{WithKeyOrderPolicy(KeyOrderScalarsFirstSorted), WithCompactDepth(1)},
+			expected: `{"active": true, "id": 1, "name": "x", "meta": {"a": 1, "b": null, "z": {}}, "tags": ["a"]}`,
+		},
+		{
+			name:     "key order takes precedence",
+			options:  []ConfigOption{WithKeyOrderPolicy(KeyOrderScalarsFirstSorted), WithKeyOrder("meta", "z", "*"), WithCompactDepth(1)},
+			expected: `{"active": true, "id": 1, "name": "x", "meta": {"z": {}, "b": null, "a": 1}, "tags": ["a"]}`,
+		},
+		{
+			name:    "expanded",
+			options: []ConfigOption{WithKeyOrderPolicy(KeyOrderScalarsFirstSorted), WithCompactDepth(0)},
+			expected: `{
+  "active": true,
+  "id": 1,
+  "name": "x",
+  "meta": {
+    "a": 1,
+    "b": null,
+    "z": {
+    }
+  },
+  "tags": [
+    "a"
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	input := `{"spec":{"b":1,"a":2},"metadata":{"labels":{"tier":"web","app":"shop"},"name":"x"}}`
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "matching paths only",
+			options:  []ConfigOption{WithSortedKeys("metadata.labels", ""), WithCompactDepth(1)},
+			expected: `{"metadata": {"labels": {"app": "shop", "tier": "web"}, "name": "x"}, "spec": {"b": 1, "a": 2}}`,
+		},
+		{
+			name:     "wildcard",
+			options:  []ConfigOption{WithSortedKeys("*"), WithCompactDepth(1)},
+			expected: `{"spec": {"a": 2, "b": 1}, "metadata": {"labels": {"tier": "web", "app": "shop"}, "name": "x"}}`,
+		},
+		{
+			name:     "key order takes precedence",
+			options:  []ConfigOption{WithSortedKeys("spec"), WithKeyOrder("spec", "b"), WithCompactDepth(1)},
+			expected: `{"spec": {"b": 1, "a": 2}, "metadata": {"labels": {"tier": "web", "app": "shop"}, "name": "x"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReorderMembersKeepsDuplicates(t *testing.T) {
+	result, err := NewFormatter(NewConfig(WithKeyOrder("", "b"), WithCompactDepth(1))).Format(`{"a":1,"b":2,"a":3,"b":[4]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"b": 2, "b": [4], "a": 1, "a": 3}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
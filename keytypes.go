@@ -0,0 +1,211 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// TypeSurvey records the JSON types of the values at each path across a
+// corpus of documents, to find keys that hold a string in one document and a
+// number in another before strict structs are written for the data. Array
+// elements share one path, the path of the array followed by "*".
+//
+// Example:
+//
+//	survey := NewTypeSurvey()
+//	for _, name := range files {
+//	    data, _ := os.ReadFile(name)
+//	    if err := survey.Add(name, string(data)); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//	fmt.Print(survey.Table())
+//	// PATH     TYPE    VALUES  DOCUMENTS  FIRST SEEN IN
+//	// user.id  number  12      12         a.json
+//	//          string  3       3          c.json
+type TypeSurvey struct {
+	paths     map[string]map[string]*typeTally // Counts of the types by path
+	documents int
+}
+
+// TypeCount counts the values of one type at a path.
+type TypeCount struct {
+	// Type is the JSON type: "string", "number", "boolean", "null",
+	// "object", or "array".
+	Type string
+
+	// Values is the number of values of the type, and Documents the number
+	// of documents that have at least one of them.
+	Values    int
+	Documents int
+
+	// FirstDocument is the name of the first document with a value of the type.
+	FirstDocument string
+}
+
+// typeTally is a TypeCount being collected
+type typeTally struct {
+	TypeCount
+	lastDocument int // Number of the last document counted, to count each once
+}
+
+// TypeConflict is a path whose values have more than one type, not counting
+// null, which only makes a field optional.
+type TypeConflict struct {
+	// Path is the dot-separated path, with "*" for array elements and ""
+	// for the root.
+	Path string
+
+	// Types are the counts of each type, most frequent first, including null.
+	Types []TypeCount
+}
+
+// NewTypeSurvey returns an empty survey.
+func NewTypeSurvey() *TypeSurvey {
+	return &TypeSurvey{paths: make(map[string]map[string]*typeTally)}
+}
+
+// Add records the types of the values of a JSON document. The name
+// identifies the document in the report and in errors.
+func (s *TypeSurvey) Add(name, input string) error {
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		formatErr := WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+		formatErr.File = name
+		return formatErr
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		formatErr := NewFormatErrorWithPosition("invalid JSON input: unexpected data after top-level value", int(decoder.InputOffset()))
+		formatErr.File = name
+		return formatErr
+	}
+
+	s.documents++
+	s.record(name, "", value)
+	return nil
+}
+
+// record counts the type of the value at path and the values below it
+func (s *TypeSurvey) record(name, path string, value interface{}) {
+	kind := "null"
+	switch value := value.(type) {
+	case string:
+		kind = "string"
+	case json.Number:
+		kind = "number"
+	case bool:
+		kind = "boolean"
+	case map[string]interface{}:
+		kind = "object"
+		for key, member := range value {
+			s.record(name, joinSurveyPath(path, key), member)
+		}
+	case []interface{}:
+		kind = "array"
+		for _, element := range value {
+			s.record(name, joinSurveyPath(path, "*"), element)
+		}
+	}
+
+	types := s.paths[path]
+	if types == nil {
+		types = make(map[string]*typeTally)
+		s.paths[path] = types
+	}
+	count := types[kind]
+	if count == nil {
+		count = &typeTally{TypeCount: TypeCount{Type: kind, FirstDocument: name}}
+		types[kind] = count
+	}
+	count.Values++
+	if count.lastDocument != s.documents {
+		count.lastDocument = s.documents
+		count.Documents++
+	}
+}
+
+// joinSurveyPath appends a key to a dot-separated path
+func joinSurveyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Documents returns the number of documents added.
+func (s *TypeSurvey) Documents() int {
+	return s.documents
+}
+
+// Conflicts returns the paths whose values have more than one type other
+// than null, in path order.
+func (s *TypeSurvey) Conflicts() []TypeConflict {
+	var conflicts []TypeConflict
+	for path, types := range s.paths {
+		nonNull := len(types)
+		if _, ok := types["null"]; ok {
+			nonNull--
+		}
+		if nonNull < 2 {
+			continue
+		}
+		conflict := TypeConflict{Path: path}
+		for _, count := range types {
+			conflict.Types = append(conflict.Types, count.TypeCount)
+		}
+		sort.Slice(conflict.Types, func(i, j int) bool {
+			a, b := conflict.Types[i], conflict.Types[j]
+			if a.Values != b.Values {
+				return a.Values > b.Values
+			}
+			return a.Type < b.Type
+		})
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Path < conflicts[j].Path })
+	return conflicts
+}
+
+// Table returns the conflicts as a diagnostic table with a line per type,
+// or "" when there are none.
+func (s *TypeSurvey) Table() string {
+	conflicts := s.Conflicts()
+	if len(conflicts) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	table := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PATH\tTYPE\tVALUES\tDOCUMENTS\tFIRST SEEN IN")
+	for _, conflict := range conflicts {
+		path := conflict.Path
+		if path == "" {
+			path = "(root)"
+		}
+		for _, count := range conflict.Types {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", path, count.Type, count.Values, count.Documents, count.FirstDocument)
+			path = ""
+		}
+	}
+	table.Flush()
+	return builder.String()
+}
@@ -0,0 +1,102 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTypeSurvey(t *testing.T) {
+	survey := NewTypeSurvey()
+	documents := []struct{ name, input string }{
+		{"a.json", `{"id":1,"tags":["x"],"owner":null,"meta":{"v":1}}`},
+		{"b.json", `{"id":"2","tags":["y",3],"owner":"bob","meta":{"v":2}}`},
+		{"c.json", `{"id":3,"tags":[],"meta":[]}`},
+	}
+	for _, document := range documents {
+		if err := survey.Add(document.name, document.input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []TypeConflict{
+		{Path: "id", Types: []TypeCount{
+			{Type: "number", Values: 2, Documents: 2, FirstDocument: "a.json"},
+			{Type: "string", Values: 1, Documents: 1, FirstDocument: "b.json"},
+		}},
+		{Path: "meta", Types: []TypeCount{
+			{Type: "object", Values: 2, Documents: 2, FirstDocument: "a.json"},
+			{Type: "array", Values: 1, Documents: 1, FirstDocument: "c.json"},
+		}},
+		{Path: "tags.*", Types: []TypeCount{
+			{Type: "string", Values: 2, Documents: 2, FirstDocument: "a.json"},
+			{Type: "number", Values: 1, Documents: 1, FirstDocument: "b.json"},
+		}},
+	}
+	conflicts := survey.Conflicts()
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("Expected:\n%+v\n\nGot:\n%+v", expected, conflicts)
+	}
+	if survey.Documents() != 3 {
+		t.Errorf("Expected 3 documents, got %d", survey.Documents())
+	}
+
+	expectedTable := `PATH    TYPE    VALUES  DOCUMENTS  FIRST SEEN IN
+id      number  2       2          a.json
+        string  1       1          b.json
+meta    object  2       2          a.json
+        array   1       1          c.json
+tags.*  string  2       2          a.json
+        number  1       1          b.json
+`
+	if table := survey.Table(); table != expectedTable {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expectedTable, table)
+	}
+}
+
+func TestTypeSurveyRoot(t *testing.T) {
+	survey := NewTypeSurvey()
+	for _, input := range []string{`[1]`, `{"a":1}`, `null`} {
+		if err := survey.Add("doc", input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if table := survey.Table(); !strings.HasPrefix(table, "PATH    TYPE") || !strings.Contains(table, "(root)  array") {
+		t.Errorf("Expected a root conflict, got:\n%s", table)
+	}
+
+	empty := NewTypeSurvey()
+	if err := empty.Add("doc", `{"a":null}`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := empty.Add("doc", `{"a":"x"}`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if table := empty.Table(); table != "" {
+		t.Errorf("Expected null to be no conflict, got:\n%s", table)
+	}
+}
+
+func TestTypeSurveyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{"invalid", `{"a":`, "broken.json: invalid JSON input"},
+		{"trailing data", `{} ]`, "broken.json: invalid JSON input: unexpected data after top-level value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			survey := NewTypeSurvey()
+			err := survey.Add("broken.json", tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if survey.Documents() != 0 {
+				t.Errorf("Expected no documents, got %d", survey.Documents())
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"reflect"
+)
+
+// ConfigSource identifies the configuration layer that set a value.
+type ConfigSource int
+
+const (
+	// SourceDefault is the configuration the layers start from.
+	SourceDefault ConfigSource = iota
+	// SourceFile is a configuration file.
+	SourceFile
+	// SourceEnv is the environment.
+	SourceEnv
+	// SourceFlag is the command line.
+	SourceFlag
+)
+
+// String returns the name of the source.
+func (s ConfigSource) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// ConfigSetting is the effective value of one Config field and the layer
+// that set it.
+type ConfigSetting struct {
+	Name   string      // Name of the Config field
+	Value  interface{} // Effective value
+	Source ConfigSource
+}
+
+// ResolveConfig merges configuration layers in order of increasing precedence:
+// the defaults, then the options from a configuration file, the environment,
+// and the command line flags. A nil defaults starts from DefaultConfig.
+//
+// Besides the configuration, it returns every Config field in declaration
+// order with the layer that set its effective value, so users can find out
+// which layer won. A layer is only reported when it changes the value, so a
+// flag repeating the value from the file leaves the file as the source.
+//
+// Example:
+//
+//	config, settings, err := ResolveConfig(nil, fileOptions, envOptions, flagOptions)
+//	for _, s := range settings {
+//	    fmt.Printf("%s = %v (%s)\n", s.Name, s.Value, s.Source)
+//	}
+func ResolveConfig(defaults *Config, file, env, flags []ConfigOption) (*Config, []ConfigSetting, error) {
+	if defaults == nil {
+		defaults = DefaultConfig()
+	}
+	config := defaults.clone()
+
+	fields := reflect.TypeOf(*config).NumField()
+	sources := make([]ConfigSource, fields)
+	layers := []struct {
+		source  ConfigSource
+		options []ConfigOption
+	}{
+		{SourceFile, file},
+		{SourceEnv, env},
+		{SourceFlag, flags},
+	}
+	for _, layer := range layers {
+		before := config.clone()
+		for _, option := range layer.options {
+			option(config)
+		}
+		previous := reflect.ValueOf(before).Elem()
+		current := reflect.ValueOf(config).Elem()
+		for i := 0; i < fields; i++ {
+			if !sameSetting(previous.Field(i), current.Field(i)) {
+				sources[i] = layer.source
+			}
+		}
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, nil, err
+	}
+
+	settings := make([]ConfigSetting, fields)
+	value := reflect.ValueOf(config).Elem()
+	for i := range settings {
+		settings[i] = ConfigSetting{
+			Name:   value.Type().Field(i).Name,
+			Value:  value.Field(i).Interface(),
+			Source: sources[i],
+		}
+	}
+	return config, settings, nil
+}
+
+// clone returns a copy of the configuration that shares no slices or maps,
+// so options applied to the copy do not change the original
+func (c *Config) clone() *Config {
+	copied := *c
+	copied.SectionComments = append([]string(nil), c.SectionComments...)
+	copied.ForbiddenKeys = append([]string(nil), c.ForbiddenKeys...)
+	copied.LogFields = append([]string(nil), c.LogFields...)
+	copied.LogProjection = append([]string(nil), c.LogProjection...)
+	copied.LogFilter = append([]LogPredicate(nil), c.LogFilter...)
+	copied.LogSummary = append([]string(nil), c.LogSummary...)
+	copied.ValueHooks = append([]ValueHook(nil), c.ValueHooks...)
+	copied.EmbeddedJSON = append([]string(nil), c.EmbeddedJSON...)
+	copied.RawPaths = append([]string(nil), c.RawPaths...)
+	copied.CompactKeys = append([]string(nil), c.CompactKeys...)
+	copied.CompactPaths = append([]string(nil), c.CompactPaths...)
+	copied.ExpandedPaths = append([]string(nil), c.ExpandedPaths...)
+	copied.SortedKeys = append([]string(nil), c.SortedKeys...)
+	copied.Degradation = append([]DegradationRung(nil), c.Degradation...)
+	if c.LogColors != nil {
+		copied.LogColors = make(map[string]string, len(c.LogColors))
+		for level, color := range c.LogColors {
+			copied.LogColors[level] = color
+		}
+	}
+	if c.Provenance != nil {
+		copied.Provenance = make(map[string]string, len(c.Provenance))
+		for path, source := range c.Provenance {
+			copied.Provenance[path] = source
+		}
+	}
+	if c.KeyRenames != nil {
+		copied.KeyRenames = make(map[string]string, len(c.KeyRenames))
+		for pattern, key := range c.KeyRenames {
+			copied.KeyRenames[pattern] = key
+		}
+	}
+	if c.KeyOrder != nil {
+		// The key slices are never modified, so they can be shared
+		copied.KeyOrder = make(map[string][]string, len(c.KeyOrder))
+		for pattern, keys := range c.KeyOrder {
+			copied.KeyOrder[pattern] = keys
+		}
+	}
+	if c.WidthByDepth != nil {
+		copied.WidthByDepth = make(map[int]int, len(c.WidthByDepth))
+		for depth, width := range c.WidthByDepth {
+			copied.WidthByDepth[depth] = width
+		}
+	}
+	return &copied
+}
+
+// sameSetting reports whether two values of a Config field are equal.
+// Functions are compared by identity, which reflect.DeepEqual does not do.
+func sameSetting(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Func:
+		return a.Pointer() == b.Pointer()
+	case reflect.Slice:
+		if a.Type().Elem().Kind() != reflect.Func {
+			break
+		}
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if a.Index(i).Pointer() != b.Index(i).Pointer() {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
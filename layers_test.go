@@ -0,0 +1,77 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveConfig(t *testing.T) {
+	defaults := NewConfig(WithSectionComments("a"))
+	file := []ConfigOption{WithIndentSize(4), WithMaxWidth(80), WithSectionComments("b")}
+	env := []ConfigOption{WithMaxWidth(100), WithTabs()}
+	flags := []ConfigOption{WithMaxWidth(120), WithTabs(), WithIndentSize(4)}
+
+	config, settings, err := ResolveConfig(defaults, file, env, flags)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.IndentSize != 4 || config.MaxWidth != 120 || !config.UseTab || config.CompactDepth != 3 {
+		t.Errorf("Unexpected configuration: %+v", config)
+	}
+	if !reflect.DeepEqual(defaults.SectionComments, []string{"a"}) {
+		t.Errorf("Expected defaults to be unchanged, got %v", defaults.SectionComments)
+	}
+
+	got := map[string]string{}
+	for _, s := range settings {
+		got[s.Name] = s.Source.String()
+	}
+	expected := map[string]string{
+		"IndentSize":      "file", // The flag repeats the value from the file
+		"UseTab":          "env",
+		"CompactDepth":    "default",
+		"MaxWidth":        "flag",
+		"SectionComments": "file",
+		"ValueHooks":      "default",
+	}
+	for name, source := range expected {
+		if got[name] != source {
+			t.Errorf("Expected %s to come from %s, got %s", name, source, got[name])
+		}
+	}
+	if len(settings) != reflect.TypeOf(Config{}).NumField() || settings[0].Name != "IndentSize" {
+		t.Errorf("Expected every field in declaration order, got %d settings starting with %s", len(settings), settings[0].Name)
+	}
+}
+
+func TestResolveConfigHooks(t *testing.T) {
+	hook := func(ctx ValueContext) (interface{}, bool) { return nil, false }
+	postProcess := func(line string, depth int, path string) string { return line }
+	base := NewConfig(WithValueHook(hook))
+
+	_, settings, err := ResolveConfig(base, nil, []ConfigOption{WithPostProcess(postProcess)}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, s := range settings {
+		switch s.Name {
+		case "ValueHooks":
+			if s.Source != SourceDefault {
+				t.Errorf("Expected unchanged hooks to come from the defaults, got %s", s.Source)
+			}
+		case "PostProcess":
+			if s.Source != SourceEnv {
+				t.Errorf("Expected the post-processor to come from env, got %s", s.Source)
+			}
+		}
+	}
+}
+
+func TestResolveConfigInvalid(t *testing.T) {
+	invalid := func(c *Config) { c.IndentSize = 50 }
+	_, _, err := ResolveConfig(nil, nil, nil, []ConfigOption{invalid})
+	if err == nil || !strings.Contains(err.Error(), "IndentSize") {
+		t.Errorf("Expected an IndentSize error, got %v", err)
+	}
+}
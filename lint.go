@@ -0,0 +1,89 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	// WarningKeyTooLong reports a key longer than the configured maximum.
+	WarningKeyTooLong WarningCode = "key_too_long"
+	// WarningKeyPattern reports a key that does not match the configured naming pattern.
+	WarningKeyPattern WarningCode = "key_pattern"
+	// WarningForbiddenKey reports a key that is on the forbidden list.
+	WarningForbiddenKey WarningCode = "forbidden_key"
+)
+
+// WithMaxKeyLength reports keys longer than length characters as
+// WarningKeyTooLong warnings. Values below 1 are ignored.
+//
+// Lint rules are evaluated by FormatWithWarnings and do not change the output.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxKeyLength(32))
+func WithMaxKeyLength(length int) ConfigOption {
+	return func(c *Config) {
+		if length > 0 {
+			c.MaxKeyLength = length
+		}
+	}
+}
+
+// WithKeyPattern reports keys that do not match the pattern as
+// WarningKeyPattern warnings. A nil pattern is ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithKeyPattern(regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`))) // camelCase
+func WithKeyPattern(pattern *regexp.Regexp) ConfigOption {
+	return func(c *Config) {
+		if pattern != nil {
+			c.KeyPattern = pattern
+		}
+	}
+}
+
+// WithForbiddenKeys reports the given keys as WarningForbiddenKey warnings
+// wherever they appear.
+//
+// Example:
+//
+//	config := NewConfig(WithForbiddenKeys("password", "secret"))
+func WithForbiddenKeys(keys ...string) ConfigOption {
+	return func(c *Config) {
+		c.ForbiddenKeys = append(c.ForbiddenKeys, keys...)
+	}
+}
+
+// lintKey records warnings for a key that breaks one of the configured lint rules
+func (p *TokenParser) lintKey(key string, path []string) {
+	if p.config.MaxKeyLength > 0 {
+		if length := utf8.RuneCountInString(key); length > p.config.MaxKeyLength {
+			p.warn(WarningKeyTooLong, path, "key %q is %d characters long (max %d)", key, length, p.config.MaxKeyLength)
+		}
+	}
+	if p.config.KeyPattern != nil && !p.config.KeyPattern.MatchString(key) {
+		p.warn(WarningKeyPattern, path, "key %q does not match %s", key, p.config.KeyPattern)
+	}
+	for _, forbidden := range p.config.ForbiddenKeys {
+		if key == forbidden {
+			p.warn(WarningForbiddenKey, path, "key %q is forbidden", key)
+			break
+		}
+	}
+}
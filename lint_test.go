@@ -0,0 +1,46 @@
+package jsonformat
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	config := NewConfig(
+		WithMaxKeyLength(6),
+		WithKeyPattern(regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)),
+		WithForbiddenKeys("password", "secret"),
+	)
+	input := `{"userId":1,"items":[{"Name":"x","secret":"y"}],"名前の長いキー":true}`
+
+	result, warnings, err := NewFormatter(config).FormatWithWarnings(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, _ := NewFormatter(DefaultConfig()).Format(input)
+	if result != expected {
+		t.Errorf("Lint rules must not change the output, got:\n%s", result)
+	}
+
+	// "userId" is exactly at the length limit and is not reported
+	expectedWarnings := []Warning{
+		{Code: WarningKeyPattern, Message: `key "Name" does not match ^[a-z][a-zA-Z0-9]*$`, Path: []string{"items", "0", "Name"}},
+		{Code: WarningForbiddenKey, Message: `key "secret" is forbidden`, Path: []string{"items", "0", "secret"}},
+		{Code: WarningKeyTooLong, Message: `key "名前の長いキー" is 7 characters long (max 6)`, Path: []string{"名前の長いキー"}},
+		{Code: WarningKeyPattern, Message: `key "名前の長いキー" does not match ^[a-z][a-zA-Z0-9]*$`, Path: []string{"名前の長いキー"}},
+	}
+	if !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("Expected warnings:\n%#v\n\nGot:\n%#v", expectedWarnings, warnings)
+	}
+}
+
+func TestLintOptionsIgnoreInvalidValues(t *testing.T) {
+	config := NewConfig(WithMaxKeyLength(10), WithMaxKeyLength(0), WithKeyPattern(nil))
+	if config.MaxKeyLength != 10 {
+		t.Errorf("Expected MaxKeyLength 10, got %d", config.MaxKeyLength)
+	}
+	if config.KeyPattern != nil {
+		t.Errorf("Expected no key pattern, got %v", config.KeyPattern)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// logColorCodes maps color names to ANSI SGR parameters
+var logColorCodes = map[string]string{
+	"black":   "30",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+	"none":    "",
+}
+
+// defaultLogColors colors levels by severity, including the numeric levels
+// of pino and bunyan
+var defaultLogColors = map[string]string{
+	"trace":    "gray",
+	"debug":    "gray",
+	"warn":     "yellow",
+	"warning":  "yellow",
+	"error":    "red",
+	"err":      "red",
+	"fatal":    "red",
+	"panic":    "red",
+	"critical": "red",
+	"10":       "gray",
+	"20":       "gray",
+	"40":       "yellow",
+	"50":       "red",
+	"60":       "red",
+}
+
+// logLevelNames are the keys of the level field of log records, in order of preference
+var logLevelNames = []string{"level", "lvl", "severity"}
+
+// WithLogColors colors the lines written by FormatLog by the level of their
+// record, read from the first of the fields level, lvl, and severity, using
+// ANSI escape sequences. Errors are red, warnings yellow, and debug and trace
+// messages gray. Colors maps levels, compared case-insensitively, to the color
+// names black, red, green, yellow, blue, magenta, cyan, white, gray, and none,
+// and replaces the defaults for those levels. Lines without a known level and
+// lines that are not JSON objects are not colored.
+//
+// Example:
+//
+//	config := NewConfig(WithLogColors(map[string]string{"info": "green", "debug": "none"}))
+func WithLogColors(colors map[string]string) ConfigOption {
+	return func(c *Config) {
+		if c.LogColors == nil {
+			c.LogColors = make(map[string]string, len(defaultLogColors)+len(colors))
+			for level, color := range defaultLogColors {
+				c.LogColors[level] = color
+			}
+		}
+		for level, color := range colors {
+			c.LogColors[strings.ToLower(level)] = color
+		}
+	}
+}
+
+// ParseLogColors parses level colors given as comma-separated level=color
+// pairs, such as "info=green,debug=none", for WithLogColors.
+func ParseLogColors(s string) (map[string]string, error) {
+	colors := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		level, color, ok := strings.Cut(pair, "=")
+		level, color = strings.TrimSpace(level), strings.TrimSpace(color)
+		if !ok || level == "" {
+			return nil, NewFormatError(fmt.Sprintf("invalid log color %q (expected level=color)", pair))
+		}
+		if _, known := logColorCodes[color]; !known {
+			return nil, NewFormatError(fmt.Sprintf("unknown log color %q (available: %s)", color, strings.Join(logColorNames(), ", ")))
+		}
+		colors[level] = color
+	}
+	return colors, nil
+}
+
+// logColorNames returns the sorted names of the log colors
+func logColorNames() []string {
+	names := make([]string, 0, len(logColorCodes))
+	for name := range logColorCodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logLevel returns the level of a log record in lower case, or false when
+// the record has no level
+func logLevel(members []logMember) (string, bool) {
+	for _, name := range logLevelNames {
+		for _, member := range members {
+			if member.key == name {
+				return strings.ToLower(logText(member.value)), true
+			}
+		}
+	}
+	return "", false
+}
+
+// colorLine wraps a formatted log line in the escape sequences of the color
+// of its level, if any
+func (l *logFormatter) colorLine(line string, members []logMember) string {
+	if l.colors == nil {
+		return line
+	}
+	level, ok := logLevel(members)
+	if !ok {
+		return line
+	}
+	code := logColorCodes[l.colors[level]]
+	if code == "" {
+		return line
+	}
+	return "\x1b[" + code + "m" + line + "\x1b[0m"
+}
@@ -0,0 +1,94 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestFormatLogColors(t *testing.T) {
+	input := `{"level":"ERROR","msg":"failed"}
+{"lvl":"warn","msg":"slow"}
+{"level":"info","msg":"started"}
+{"severity":"debug","msg":"tick"}
+{"level":50,"msg":"pino"}
+{"msg":"no level"}
+plain text
+`
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name: "off",
+			expected: `- ERROR failed
+- warn slow
+- info started
+- debug tick
+- 50 pino
+- - no level
+plain text
+`,
+		},
+		{
+			name:    "defaults",
+			options: []ConfigOption{WithLogColors(nil)},
+			expected: "\x1b[31m- ERROR failed\x1b[0m\n" +
+				"\x1b[33m- warn slow\x1b[0m\n" +
+				"- info started\n" +
+				"\x1b[90m- debug tick\x1b[0m\n" +
+				"\x1b[31m- 50 pino\x1b[0m\n" +
+				"- - no level\n" +
+				"plain text\n",
+		},
+		{
+			name:    "custom",
+			options: []ConfigOption{WithLogColors(map[string]string{"INFO": "green", "debug": "none"})},
+			expected: "\x1b[31m- ERROR failed\x1b[0m\n" +
+				"\x1b[33m- warn slow\x1b[0m\n" +
+				"\x1b[32m- info started\x1b[0m\n" +
+				"- debug tick\n" +
+				"\x1b[31m- 50 pino\x1b[0m\n" +
+				"- - no level\n" +
+				"plain text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(append([]ConfigOption{WithLogFields("time", "level|lvl|severity", "msg")}, tt.options...)...)
+			result, err := NewFormatter(config).FormatLog(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%q\n\nGot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseLogColors(t *testing.T) {
+	colors, err := ParseLogColors("info=green, debug=none,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(colors) != 2 || colors["info"] != "green" || colors["debug"] != "none" {
+		t.Errorf("Unexpected colors: %v", colors)
+	}
+
+	for _, s := range []string{"info", "=red", "info=pink"} {
+		_, err := ParseLogColors(s)
+		formatErr, ok := err.(*FormatError)
+		if !ok || formatErr.Code() != CodeInvalidArgument {
+			t.Errorf("Expected an invalid argument error for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestLogColorsValidation(t *testing.T) {
+	config := DefaultConfig()
+	WithLogColors(map[string]string{"info": "pink"})(config)
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected an error for an unknown color")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LogPredicate selects log records by the value of a field for WithLogFilter.
+type LogPredicate struct {
+	// Path is the dot-separated path of the field, with array indices in decimal.
+	Path string
+
+	// Op is the comparison: "==", "!=", "<", "<=", ">", ">=", "~" for a match
+	// of the regular expression in Value, or "!~" for no match.
+	Op string
+
+	// Value is compared with the field. When it is a log level name, such as
+	// "warn", levels are compared by severity; when it is a number, numbers are
+	// compared numerically; and otherwise the field is compared as a string.
+	// Fields that cannot be compared this way only match "!=".
+	Value string
+}
+
+// logOperators are the comparison operators of predicates, longest first
+var logOperators = []string{"==", "!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+// logLevelRanks orders log levels by severity, including the numeric levels
+// of pino and bunyan
+var logLevelRanks = map[string]int{
+	"trace": 0, "10": 0,
+	"debug": 1, "20": 1,
+	"info": 2, "30": 2,
+	"warn": 3, "warning": 3, "40": 3,
+	"error": 4, "err": 4, "50": 4,
+	"fatal": 5, "panic": 5, "critical": 5, "60": 5,
+}
+
+// ParseLogPredicate parses a predicate such as "level>=warn", "status!=200",
+// or "msg~timeout". A single "=" is the same as "==".
+func ParseLogPredicate(s string) (LogPredicate, error) {
+	i := strings.IndexAny(s, "=!<>~")
+	if i <= 0 {
+		return LogPredicate{}, NewFormatError(fmt.Sprintf("invalid log predicate %q (expected field, operator, and value)", s))
+	}
+	for _, op := range logOperators {
+		if !strings.HasPrefix(s[i:], op) {
+			continue
+		}
+		predicate := LogPredicate{Path: strings.TrimSpace(s[:i]), Op: op, Value: strings.TrimSpace(s[i+len(op):])}
+		if op == "=" {
+			predicate.Op = "=="
+		}
+		if err := predicate.validate(); err != nil {
+			return LogPredicate{}, err
+		}
+		return predicate, nil
+	}
+	return LogPredicate{}, NewFormatError(fmt.Sprintf("invalid log predicate %q (unknown operator)", s))
+}
+
+// validate reports an unknown operator or an invalid regular expression
+func (p LogPredicate) validate() error {
+	switch p.Op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return nil
+	case "~", "!~":
+		if _, err := regexp.Compile(p.Value); err != nil {
+			return WrapFormatError(fmt.Sprintf("invalid log predicate %s%s%s", p.Path, p.Op, p.Value), err)
+		}
+		return nil
+	}
+	return NewFormatError(fmt.Sprintf("invalid log predicate %s%s%s (unknown operator)", p.Path, p.Op, p.Value))
+}
+
+// WithLogFilter makes FormatLog write only the records that match all of the
+// predicates, evaluated before formatting, so it can replace a tail and jq
+// filtering pipeline. A field that is missing matches only "!=" and "!~".
+// Lines that are not JSON objects are left out while a filter is set.
+//
+// Example:
+//
+//	warn, _ := ParseLogPredicate("level>=warn")
+//	config := NewConfig(WithLogFilter(warn))
+func WithLogFilter(predicates ...LogPredicate) ConfigOption {
+	return func(c *Config) {
+		c.LogFilter = append(c.LogFilter, predicates...)
+	}
+}
+
+// logCondition is a predicate prepared for evaluation
+type logCondition struct {
+	LogPredicate
+	path    []string
+	pattern *regexp.Regexp
+}
+
+// newLogConditions prepares the predicates, which validateConfig has checked
+func newLogConditions(predicates []LogPredicate) []logCondition {
+	conditions := make([]logCondition, len(predicates))
+	for i, predicate := range predicates {
+		conditions[i] = logCondition{LogPredicate: predicate, path: strings.Split(predicate.Path, ".")}
+		if predicate.Op == "~" || predicate.Op == "!~" {
+			conditions[i].pattern = regexp.MustCompile(predicate.Value)
+		}
+	}
+	return conditions
+}
+
+// matches reports whether a record matches all of the conditions
+func (l *logFormatter) matches(members []logMember) bool {
+	for _, condition := range l.filter {
+		value, ok := lookupLogField(members, condition.path)
+		if !condition.match(value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// match evaluates the condition for the value of the field, if present
+func (c *logCondition) match(value json.RawMessage, present bool) bool {
+	if !present {
+		return c.Op == "!=" || c.Op == "!~"
+	}
+	text := logText(value)
+	switch c.Op {
+	case "~":
+		return c.pattern.MatchString(text)
+	case "!~":
+		return !c.pattern.MatchString(text)
+	}
+
+	var order int
+	if want, ok := logLevelRanks[strings.ToLower(c.Value)]; ok && !isNumber(c.Value) {
+		// Levels are ordered by severity, and unknown levels only compare unequal
+		got, known := logLevelRanks[strings.ToLower(text)]
+		if !known {
+			return c.Op == "!="
+		}
+		order = cmp.Compare(got, want)
+	} else if want, err := strconv.ParseFloat(c.Value, 64); err == nil {
+		// Numbers only compare unequal to other values
+		got, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return c.Op == "!="
+		}
+		order = cmp.Compare(got, want)
+	} else {
+		order = strings.Compare(text, c.Value)
+	}
+
+	switch c.Op {
+	case "==":
+		return order == 0
+	case "!=":
+		return order != 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+// isNumber reports whether s is a number
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// lookupLogField returns the raw value at the path in a log record
+func lookupLogField(members []logMember, path []string) (json.RawMessage, bool) {
+	var value json.RawMessage
+	found := false
+	for _, member := range members {
+		if member.key == path[0] {
+			value, found = member.value, true
+			break
+		}
+	}
+	for _, segment := range path[1:] {
+		if !found {
+			break
+		}
+		found = false
+		var object map[string]json.RawMessage
+		var array []json.RawMessage
+		if json.Unmarshal(value, &object) == nil {
+			value, found = object[segment]
+		} else if index, err := strconv.Atoi(segment); err == nil && json.Unmarshal(value, &array) == nil && index >= 0 && index < len(array) {
+			value, found = array[index], true
+		}
+	}
+	return value, found
+}
@@ -0,0 +1,103 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestParseLogPredicate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LogPredicate
+	}{
+		{"level>=warn", LogPredicate{Path: "level", Op: ">=", Value: "warn"}},
+		{"status!=200", LogPredicate{Path: "status", Op: "!=", Value: "200"}},
+		{"msg~time ?out", LogPredicate{Path: "msg", Op: "~", Value: "time ?out"}},
+		{"http.method = GET", LogPredicate{Path: "http.method", Op: "==", Value: "GET"}},
+		{"user!~^bot", LogPredicate{Path: "user", Op: "!~", Value: "^bot"}},
+		{"ms<", LogPredicate{Path: "ms", Op: "<", Value: ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			predicate, err := ParseLogPredicate(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if predicate != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, predicate)
+			}
+		})
+	}
+
+	for _, input := range []string{"level", ">=warn", "msg~(", "level!warn"} {
+		_, err := ParseLogPredicate(input)
+		formatErr, ok := err.(*FormatError)
+		if !ok || formatErr.Code() != CodeInvalidArgument {
+			t.Errorf("Expected an invalid argument error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestFormatLogFilter(t *testing.T) {
+	input := `{"level":"info","msg":"request served","status":200,"http":{"ms":12}}
+{"level":"WARN","msg":"slow request","status":200,"http":{"ms":950}}
+{"level":"error","msg":"upstream timeout","status":504}
+{"level":50,"msg":"pino error"}
+{"level":"notice","msg":"custom level","status":"n/a"}
+{"msg":"no level"}
+plain text
+`
+	tests := []struct {
+		predicates []string
+		expected   []string
+	}{
+		{[]string{"level>=warn"}, []string{"slow request", "upstream timeout", "pino error"}},
+		{[]string{"level==error"}, []string{"upstream timeout", "pino error"}},
+		{[]string{"level!=info"}, []string{"slow request", "upstream timeout", "pino error", "custom level", "no level"}},
+		{[]string{"status!=200"}, []string{"upstream timeout", "pino error", "custom level", "no level"}},
+		{[]string{"status>=500"}, []string{"upstream timeout"}},
+		{[]string{"http.ms>100"}, []string{"slow request"}},
+		{[]string{"msg~time(out)?"}, []string{"upstream timeout"}},
+		{[]string{"msg!~timeout", "level<error"}, []string{"request served", "slow request"}},
+		{[]string{"level>=40"}, []string{"pino error"}},
+		{[]string{"status==n/a"}, []string{"custom level"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.predicates[0], func(t *testing.T) {
+			var predicates []LogPredicate
+			for _, s := range tt.predicates {
+				predicate, err := ParseLogPredicate(s)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				predicates = append(predicates, predicate)
+			}
+			config := NewConfig(WithLogFields("msg"), WithLogProjection("msg"), WithLogFilter(predicates...))
+			result, err := NewFormatter(config).FormatLog(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := ""
+			for _, msg := range tt.expected {
+				expected += msg + "\n"
+			}
+			if result != expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+			}
+		})
+	}
+}
+
+func TestLogFilterValidation(t *testing.T) {
+	config := DefaultConfig()
+	WithLogFilter(LogPredicate{Path: "msg", Op: "~", Value: "("})(config)
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected an error for an invalid regular expression")
+	}
+	config = DefaultConfig()
+	WithLogFilter(LogPredicate{Path: "msg", Op: "=~", Value: "a"})(config)
+	if err := validateConfig(config); err == nil {
+		t.Error("Expected an error for an unknown operator")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strconv"
+)
+
+// WithLogProjection keeps only the fields at the given paths in the lines
+// written by FormatLog, since production records carry dozens of fields and
+// only a handful matter during an incident. Paths are dot-separated, with
+// array indices in decimal, and each segment may hold the wildcards of
+// path.Match, e.g. "http.status", "http.*", or "items.*.id". Selecting an
+// object selects all of its fields. Log fields that none of the paths select
+// are left out of the prefix.
+//
+// Example:
+//
+//	config := NewConfig(WithLogProjection("time", "level", "msg", "http.status"))
+//	// 12:00:00 INFO request served {"http": {"status": 200}}
+func WithLogProjection(paths ...string) ConfigOption {
+	return func(c *Config) {
+		c.LogProjection = append(c.LogProjection, paths...)
+	}
+}
+
+// WithLogFoldMarker writes the number of fields that WithLogProjection leaves
+// out as a "+N" marker at the end of each line, so that hidden detail is not
+// mistaken for missing detail.
+//
+// Example:
+//
+//	config := NewConfig(WithLogProjection("msg"), WithLogFoldMarker())
+//	// - - request served +12
+func WithLogFoldMarker() ConfigOption {
+	return func(c *Config) {
+		c.LogFoldHidden = true
+	}
+}
+
+// projectsField reports whether the projection selects a log field by one
+// of its names
+func (l *logFormatter) projectsField(names []string) bool {
+	for _, name := range names {
+		for _, tail := range matchingTails(l.projection, name) {
+			if len(tail) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchingTails returns the remaining segments of the patterns whose first
+// segment matches the key
+func matchingTails(patterns [][]string, key string) [][]string {
+	var tails [][]string
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern[0], key); matched {
+			tails = append(tails, pattern[1:])
+		}
+	}
+	return tails
+}
+
+// projectValue returns the parts of a value that the patterns, given as
+// segments below the value, select, or nil when they select nothing. It also
+// returns the number of members and elements left out.
+func projectValue(value json.RawMessage, patterns [][]string) (json.RawMessage, int) {
+	if len(patterns) == 0 {
+		return nil, 1
+	}
+	for _, pattern := range patterns {
+		if len(pattern) == 0 {
+			return value, 0
+		}
+	}
+
+	var output bytes.Buffer
+	hidden := 0
+	switch trimmed := bytes.TrimLeft(value, " \t\r\n"); {
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		members, ok := parseObjectMembers(bytes.NewReader(value))
+		if !ok {
+			return nil, 1
+		}
+		for _, member := range members {
+			selected, left := projectValue(member.value, matchingTails(patterns, member.key))
+			hidden += left
+			if selected == nil {
+				continue
+			}
+			if output.Len() == 0 {
+				output.WriteByte('{')
+			} else {
+				output.WriteByte(',')
+			}
+			key, _ := json.Marshal(member.key)
+			output.Write(key)
+			output.WriteByte(':')
+			output.Write(selected)
+		}
+		if output.Len() > 0 {
+			output.WriteByte('}')
+		}
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return nil, 1
+		}
+		for i, element := range elements {
+			selected, left := projectValue(element, matchingTails(patterns, strconv.Itoa(i)))
+			hidden += left
+			if selected == nil {
+				continue
+			}
+			if output.Len() == 0 {
+				output.WriteByte('[')
+			} else {
+				output.WriteByte(',')
+			}
+			output.Write(selected)
+		}
+		if output.Len() > 0 {
+			output.WriteByte(']')
+		}
+	default:
+		// Scalars have no fields to select below them
+		return nil, 1
+	}
+	if output.Len() == 0 {
+		return nil, hidden
+	}
+	return output.Bytes(), hidden
+}
@@ -0,0 +1,58 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestFormatLogProjection(t *testing.T) {
+	input := `{"ts":"12:00:00","level":"INFO","msg":"served","http":{"status":200,"path":"/users","headers":{"accept":"*/*"}},"items":[{"id":1,"name":"a"},{"id":2},3],"userId":7,"username":"alice","pid":42}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "dot paths",
+			options:  []ConfigOption{WithLogProjection("ts", "level", "msg", "http.status")},
+			expected: `12:00:00 INFO served {"http": {"status": 200}}`,
+		},
+		{
+			name:     "wildcards",
+			options:  []ConfigOption{WithLogProjection("msg", "items.*.id", "user*")},
+			expected: `served {"items": [{"id": 1}, {"id": 2}], "userId": 7, "username": "alice"}`,
+		},
+		{
+			name:     "whole objects",
+			options:  []ConfigOption{WithLogProjection("http.*")},
+			expected: `{"http": {"status": 200, "path": "/users", "headers": {"accept": "*/*"}}}`,
+		},
+		{
+			name:     "fold marker",
+			options:  []ConfigOption{WithLogProjection("ts", "level", "msg", "http.status"), WithLogFoldMarker()},
+			expected: `12:00:00 INFO served {"http": {"status": 200}} +6`,
+		},
+		{
+			name:     "nothing selected",
+			options:  []ConfigOption{WithLogProjection("missing.field"), WithLogFoldMarker()},
+			expected: `+8`,
+		},
+		{
+			name:     "fold marker without projection",
+			options:  []ConfigOption{WithLogFields("msg"), WithLogFoldMarker(), WithCompactDepth(1)},
+			expected: `served {"ts": "12:00:00", "level": "INFO", "http": {"status": 200, "path": "/users", "headers": {"accept": "*/*"}}, "items": [{"id": 1, "name": "a"}, {"id": 2}, 3], "userId": 7, "username": "alice", "pid": 42}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).FormatLog(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected+"\n" {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// defaultLogFields are the prefix fields of log lines when no others are set
+var defaultLogFields = []string{"time|ts|timestamp|@timestamp", "level|lvl|severity", "msg|message"}
+
+// WithLogFields sets the fields of log records that FormatLog writes in order
+// at the start of each line, before the remaining fields. Each field lists
+// alternative names separated by "|", and the first name present in a record
+// is used. The default is "time|ts|timestamp|@timestamp", "level|lvl|severity",
+// and "msg|message".
+//
+// Example:
+//
+//	config := NewConfig(WithLogFields("ts", "level", "msg"))
+func WithLogFields(fields ...string) ConfigOption {
+	return func(c *Config) {
+		c.LogFields = append(c.LogFields, fields...)
+	}
+}
+
+// FormatLog formats newline-delimited JSON (NDJSON) log records for reading.
+// Each record is written on one line, starting with the values of the log
+// fields in order, followed by the remaining fields as a compact object:
+//
+//	2024-05-01T12:00:00Z INFO request served {"status": 200, "path": "/users"}
+//
+// String values in the prefix are written without quotes, and missing fields
+// as "-". Lines that are not JSON objects, such as messages of other programs
+// mixed into the log, are written unchanged.
+//
+// Example:
+//
+//	formatted, err := formatter.FormatLog(`{"time":"12:00:00","level":"INFO","msg":"started","port":8080}`)
+//	// 12:00:00 INFO started {"port": 8080}
+func (f *Formatter) FormatLog(input string) (string, error) {
+	var output strings.Builder
+	if err := f.FormatLogTo(&output, strings.NewReader(input)); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// FormatLogTo reads NDJSON log records from r and writes them to w in the
+// form of FormatLog, one line at a time, so streams such as the output of
+// kubectl logs -f are formatted as they arrive.
+//
+// If an error is returned, the lines before the failing one have already
+// been written to w.
+func (f *Formatter) FormatLogTo(w io.Writer, r io.Reader) error {
+	if w == nil {
+		return NewFormatError("output writer cannot be nil")
+	}
+	if r == nil {
+		return NewFormatError("input reader cannot be nil")
+	}
+
+	logger := f.newLogFormatter()
+	reader := bufio.NewReader(r)
+	writer := newFlushWriter(f.config.Faults.writer(w), f.config.FlushPolicy, FlushPolicy{Records: true})
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return WrapFormatError("failed to read input", readErr)
+		}
+		if line != "" {
+			if formatted, ok := logger.formatLine(strings.TrimSuffix(line, "\n")); ok {
+				if _, err := writer.WriteString(formatted + "\n"); err != nil {
+					return WrapFormatError("failed to write output", err)
+				}
+				// By default, flush per line so that streamed records are visible immediately
+				if err := writer.endRecord(); err != nil {
+					return WrapFormatError("failed to write output", err)
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if logger.summary != nil {
+		if err := logger.summary.write(writer); err != nil {
+			return WrapFormatError("failed to write output", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	return nil
+}
+
+// logFormatter formats log records
+type logFormatter struct {
+	fields     [][]string        // Alternative names of each prefix field
+	rest       *Formatter        // Writes the remaining fields on one line
+	colors     map[string]string // Color names by level, or nil to write no colors
+	projection [][]string        // Segments of the projected paths, or nil to keep all fields
+	foldHidden bool              // Whether the number of fields left out is written
+	filter     []logCondition    // Conditions that records must match to be written
+	summary    *logSummary       // Counts of the written records, or nil
+}
+
+// newLogFormatter prepares the prefix fields and a formatter that writes the
+// remaining fields on one line without layout options that span lines
+func (f *Formatter) newLogFormatter() *logFormatter {
+	names := f.config.LogFields
+	if len(names) == 0 {
+		names = defaultLogFields
+	}
+	fields := make([][]string, len(names))
+	for i, name := range names {
+		fields[i] = strings.Split(name, "|")
+	}
+
+	config := f.config.clone()
+	config.CompactDepth = 1
+	config.MaxWidth = 0
+	config.WidthByDepth = nil
+	config.AdaptiveCompaction = false
+	config.OverflowSummaryKeys = 0
+	config.BlankLineBetweenTopLevelKeys = false
+	config.BlankLineElementSize = 0
+	config.SectionComments = nil
+	config.ShowMissingRequired = false
+	config.HeaderComment = false
+	config.PostProcess = nil
+	config.HardWrap = 0
+	logger := &logFormatter{
+		fields:     fields,
+		rest:       NewFormatter(config),
+		colors:     f.config.LogColors,
+		foldHidden: f.config.LogFoldHidden,
+		filter:     newLogConditions(f.config.LogFilter),
+		summary:    newLogSummary(f.config.LogSummary),
+	}
+	for _, pattern := range f.config.LogProjection {
+		logger.projection = append(logger.projection, strings.Split(pattern, "."))
+	}
+	return logger
+}
+
+// logMember is a member of a log record with its raw value
+type logMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// formatLine formats one log record, or returns the line unchanged when it
+// is not a JSON object. It returns false when the filter leaves out the line.
+func (l *logFormatter) formatLine(line string) (string, bool) {
+	members, ok := parseLogRecord(line)
+	if !ok {
+		if l.summary != nil && len(l.filter) == 0 {
+			l.summary.others++
+		}
+		return line, len(l.filter) == 0
+	}
+	if !l.matches(members) {
+		return "", false
+	}
+	if l.summary != nil {
+		l.summary.count(members)
+	}
+
+	used := make([]bool, len(members))
+	parts := make([]string, 0, len(l.fields)+2)
+	hidden := 0 // Members and elements left out by the projection
+	for _, names := range l.fields {
+		i := logField(members, used, names)
+		switch {
+		case l.projection != nil && !l.projectsField(names):
+			if i >= 0 {
+				hidden++
+			}
+		case i < 0:
+			parts = append(parts, "-")
+		default:
+			parts = append(parts, logText(members[i].value))
+		}
+	}
+
+	var rest bytes.Buffer
+	for i, member := range members {
+		if used[i] {
+			continue
+		}
+		value := member.value
+		if l.projection != nil {
+			var left int
+			value, left = projectValue(value, matchingTails(l.projection, member.key))
+			hidden += left
+			if value == nil {
+				continue
+			}
+		}
+		if rest.Len() > 0 {
+			rest.WriteByte(',')
+		}
+		key, _ := json.Marshal(member.key)
+		rest.Write(key)
+		rest.WriteByte(':')
+		rest.Write(value)
+	}
+	if rest.Len() > 0 {
+		formatted, err := l.rest.Format("{" + rest.String() + "}")
+		if err != nil {
+			return line, true
+		}
+		parts = append(parts, formatted)
+	}
+	if l.foldHidden && hidden > 0 {
+		parts = append(parts, "+"+strconv.Itoa(hidden))
+	}
+	return l.colorLine(strings.Join(parts, " "), members), true
+}
+
+// logField returns the index of the first of the names present in the
+// members and marks it as used, or -1 when none is present
+func logField(members []logMember, used []bool, names []string) int {
+	for _, name := range names {
+		for i, member := range members {
+			if used[i] || member.key != name {
+				continue
+			}
+			used[i] = true
+			return i
+		}
+	}
+	return -1
+}
+
+// logText returns a string value without quotes, or any other value in its
+// compact JSON form, on one line
+func logText(value json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		// Line breaks would split the record
+		return strings.NewReplacer("\n", `\n`, "\r", `\r`).Replace(text)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return string(value)
+	}
+	return compact.String()
+}
+
+// parseLogRecord splits a line holding one JSON object into its members,
+// keeping their order and the raw text of their values
+func parseLogRecord(line string) ([]logMember, bool) {
+	return parseObjectMembers(strings.NewReader(line))
+}
+
+// parseObjectMembers splits a JSON object into its members, keeping their
+// order and the raw text of their values
+func parseObjectMembers(r io.Reader) ([]logMember, bool) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, false
+	}
+	var members []logMember
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, false
+		}
+		members = append(members, logMember{key: key, value: value})
+	}
+	if token, err := decoder.Token(); err != nil || token != json.Delim('}') {
+		return nil, false
+	}
+	// Anything after the object means the line is not a single record
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, false
+	}
+	return members, true
+}
@@ -0,0 +1,82 @@
+package jsonformat
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFormatLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		input    string
+		expected string
+	}{
+		{
+			name:     "default fields",
+			input:    `{"status":200,"msg":"request served","level":"INFO","time":"2024-05-01T12:00:00Z","http":{"path":"/users","ids":[1,2]}}`,
+			expected: `2024-05-01T12:00:00Z INFO request served {"status": 200, "http": {"path": "/users", "ids": [1, 2]}}` + "\n",
+		},
+		{
+			name:     "alternative names",
+			input:    `{"ts":1714564800.5,"severity":"error","message":"failed\nretrying"}`,
+			expected: "1714564800.5 error failed\\nretrying\n",
+		},
+		{
+			name:     "missing fields",
+			input:    `{"msg":"started","port":8080}`,
+			expected: `- - started {"port": 8080}` + "\n",
+		},
+		{
+			name:     "custom fields",
+			options:  []ConfigOption{WithLogFields("level", "caller|source")},
+			input:    `{"time":"12:00:00","level":"warn","caller":{"file":"main.go","line":12},"msg":"slow"}`,
+			expected: `warn {"file":"main.go","line":12} {"time": "12:00:00", "msg": "slow"}` + "\n",
+		},
+		{
+			name:     "first name wins",
+			options:  []ConfigOption{WithLogFields("msg|message", "message")},
+			input:    `{"message":"a","msg":"b"}`,
+			expected: "b a\n",
+		},
+		{
+			name:     "other lines",
+			input:    "starting server\n\n[1,2]\n{\"a\":1} trailing\n{\"a\":\n",
+			expected: "starting server\n\n[1,2]\n{\"a\":1} trailing\n{\"a\":\n",
+		},
+		{
+			name:     "no trailing line feed",
+			input:    "{\"level\":\"INFO\"}\r\n{\"level\":\"WARN\"}",
+			expected: "- INFO -\n- WARN -\n",
+		},
+		{
+			name:     "layout options",
+			options:  []ConfigOption{WithMaxWidth(10), WithBlankLineBetweenTopLevelKeys(), WithSectionComments("*")},
+			input:    `{"msg":"hi","user":{"id":1,"name":"Alice"}}`,
+			expected: `- - hi {"user": {"id": 1, "name": "Alice"}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).FormatLog(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatLogToErrors(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	if err := formatter.FormatLogTo(nil, strings.NewReader("")); err == nil {
+		t.Error("Expected an error for a nil writer")
+	}
+	if err := formatter.FormatLogTo(io.Discard, nil); err == nil {
+		t.Error("Expected an error for a nil reader")
+	}
+}
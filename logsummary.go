@@ -0,0 +1,150 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultLogSummaryFields are the fields counted by the log summary when no others are set
+var defaultLogSummaryFields = []string{"level|lvl|severity", "status|statusCode|status_code|http.status"}
+
+// logSummaryTopErrors is the number of most frequent error messages in the log summary
+const logSummaryTopErrors = 5
+
+// WithLogSummary makes FormatLog end its output with a footer summarizing the
+// records it wrote: their number, the counts of the values of each field, and
+// the most frequent messages of records at error level or above. It is computed
+// during the same pass that formats the records. Each field is a dot path and
+// lists alternatives separated by "|". Without fields, levels and HTTP status
+// codes are counted.
+//
+// Example:
+//
+//	config := NewConfig(WithLogSummary())
+//	// --
+//	// records: 1240, other lines: 3
+//	// level: info=1180 warn=40 error=20
+//	// status: 200=1100 404=30 500=20
+//	// top errors:
+//	//   12 upstream timeout
+//	//   8 connection refused
+func WithLogSummary(fields ...string) ConfigOption {
+	return func(c *Config) {
+		if len(fields) == 0 {
+			fields = defaultLogSummaryFields
+		}
+		c.LogSummary = append(c.LogSummary, fields...)
+	}
+}
+
+// logSummary counts the records written by FormatLog
+type logSummary struct {
+	fields  [][][]string     // Alternative paths of each counted field, split into segments
+	names   []string         // Name of each counted field in the footer
+	counts  []map[string]int // Counts of the values of each field
+	records int              // Records written
+	others  int              // Lines that are not records
+	errors  map[string]int   // Counts of the messages of error records
+}
+
+// newLogSummary prepares the counts of the fields, or returns nil when no
+// summary is written
+func newLogSummary(fields []string) *logSummary {
+	if len(fields) == 0 {
+		return nil
+	}
+	summary := &logSummary{errors: map[string]int{}}
+	for _, field := range fields {
+		var alternatives [][]string
+		for _, name := range strings.Split(field, "|") {
+			alternatives = append(alternatives, strings.Split(name, "."))
+		}
+		summary.fields = append(summary.fields, alternatives)
+		summary.names = append(summary.names, strings.Split(field, "|")[0])
+		summary.counts = append(summary.counts, map[string]int{})
+	}
+	return summary
+}
+
+// count adds a written record to the summary
+func (s *logSummary) count(members []logMember) {
+	s.records++
+	for i, alternatives := range s.fields {
+		for _, path := range alternatives {
+			if value, ok := lookupLogField(members, path); ok {
+				s.counts[i][logText(value)]++
+				break
+			}
+		}
+	}
+
+	level, ok := logLevel(members)
+	if rank, known := logLevelRanks[level]; !ok || !known || rank < logLevelRanks["error"] {
+		return
+	}
+	for _, name := range []string{"msg", "message"} {
+		if value, ok := lookupLogField(members, []string{name}); ok {
+			s.errors[logText(value)]++
+			return
+		}
+	}
+}
+
+// write writes the footer
+func (s *logSummary) write(w io.StringWriter) error {
+	lines := []string{"--", fmt.Sprintf("records: %d, other lines: %d", s.records, s.others)}
+	for i, counts := range s.counts {
+		if len(counts) == 0 {
+			continue
+		}
+		var entries []string
+		for _, value := range sortedByCount(counts) {
+			entries = append(entries, fmt.Sprintf("%s=%d", value, counts[value]))
+		}
+		lines = append(lines, s.names[i]+": "+strings.Join(entries, " "))
+	}
+	if len(s.errors) > 0 {
+		lines = append(lines, "top errors:")
+		messages := sortedByCount(s.errors)
+		if len(messages) > logSummaryTopErrors {
+			messages = messages[:logSummaryTopErrors]
+		}
+		for _, message := range messages {
+			lines = append(lines, fmt.Sprintf("  %d %s", s.errors[message], message))
+		}
+	}
+	_, err := w.WriteString(strings.Join(lines, "\n") + "\n")
+	return err
+}
+
+// sortedByCount returns the keys of the counts, most frequent first and
+// equally frequent ones in order
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,70 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatLogSummary(t *testing.T) {
+	input := `{"level":"info","msg":"ok","status":200}
+{"level":"error","msg":"upstream timeout","status":504}
+{"level":"ERROR","msg":"upstream timeout","http":{"status":504}}
+{"level":60,"msg":"crash"}
+{"level":"warn","message":"slow","status":200}
+plain text
+`
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "defaults",
+			options: []ConfigOption{WithLogSummary()},
+			expected: `--
+records: 5, other lines: 1
+level: 60=1 ERROR=1 error=1 info=1 warn=1
+status: 200=2 504=2
+top errors:
+  2 upstream timeout
+  1 crash
+`,
+		},
+		{
+			name:    "custom fields",
+			options: []ConfigOption{WithLogSummary("msg|message")},
+			expected: `--
+records: 5, other lines: 1
+msg: upstream timeout=2 crash=1 ok=1 slow=1
+top errors:
+  2 upstream timeout
+  1 crash
+`,
+		},
+		{
+			name:    "filtered",
+			options: []ConfigOption{WithLogSummary(), WithLogFilter(LogPredicate{Path: "level", Op: "<", Value: "error"})},
+			expected: `--
+records: 2, other lines: 0
+level: info=1 warn=1
+status: 200=2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).FormatLog(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_, footer, found := strings.Cut(result, "\n--\n")
+			if !found {
+				t.Fatalf("Expected a footer, got:\n%s", result)
+			}
+			if footer = "--\n" + footer; footer != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, footer)
+			}
+		})
+	}
+}
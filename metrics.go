@@ -0,0 +1,219 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets of
+// the formatting duration histogram used when NewMetricsCollector is given
+// none: from 100µs to 10s.
+var DefaultDurationBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// metricsClock returns the current time when measuring formatting durations
+var metricsClock = time.Now
+
+// MetricsCollector collects Prometheus-style metrics of the documents
+// formatted by the formatters it is set on with SetMetrics: a histogram of
+// formatting durations, the input and output bytes, and the errors by
+// ErrorCode. It serves the metrics in the Prometheus text exposition format,
+// so services can expose the cost of pretty-printing in production without
+// depending on a metrics library. It is safe for concurrent use.
+//
+// Example:
+//
+//	metrics := NewMetricsCollector()
+//	formatter := NewFormatter(DefaultConfig())
+//	formatter.SetMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+type MetricsCollector struct {
+	mu          sync.Mutex
+	buckets     []float64 // Upper bounds of the duration buckets in seconds, ascending
+	counts      []uint64  // Durations observed per bucket, not cumulative, with one more for +Inf
+	sum         float64   // Sum of the durations observed in seconds
+	count       uint64    // Documents formatted, including failures
+	inputBytes  uint64
+	outputBytes uint64
+	errors      map[ErrorCode]uint64
+}
+
+// MetricsSnapshot holds the values of a MetricsCollector at one point in time.
+type MetricsSnapshot struct {
+	Buckets     []float64            // Upper bounds of the duration buckets in seconds
+	Counts      []uint64             // Cumulative count of durations up to each bucket bound
+	Sum         time.Duration        // Total time spent formatting
+	Count       uint64               // Documents formatted, including failures
+	InputBytes  uint64               // Bytes of input read
+	OutputBytes uint64               // Bytes of output written
+	Errors      map[ErrorCode]uint64 // Failures by error code
+}
+
+// NewMetricsCollector creates a collector whose duration histogram has the
+// given bucket upper bounds in seconds, or DefaultDurationBuckets if none
+// are given. Bounds are sorted, and duplicates are removed.
+func NewMetricsCollector(buckets ...float64) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	unique := sorted[:0]
+	for i, bound := range sorted {
+		if i == 0 || bound != sorted[i-1] {
+			unique = append(unique, bound)
+		}
+	}
+	return &MetricsCollector{
+		buckets: unique,
+		counts:  make([]uint64, len(unique)+1),
+		errors:  make(map[ErrorCode]uint64),
+	}
+}
+
+// SetMetrics sets the collector that Format, FormatBytes, and FormatTo
+// report to. A nil collector disables the metrics.
+func (f *Formatter) SetMetrics(metrics *MetricsCollector) {
+	f.metrics = metrics
+}
+
+// observe records a formatted document
+func (m *MetricsCollector) observe(duration time.Duration, input, output int, err error) {
+	seconds := duration.Seconds()
+	bucket := sort.SearchFloat64s(m.buckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[bucket]++
+	m.sum += seconds
+	m.count++
+	m.inputBytes += uint64(input)
+	m.outputBytes += uint64(output)
+	if err != nil {
+		m.errors[errorCode(err)]++
+	}
+}
+
+// errorCode returns the code of a formatting error, or CodeUnknown for
+// errors that are not a FormatError
+func errorCode(err error) ErrorCode {
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) {
+		return formatErr.Code()
+	}
+	return CodeUnknown
+}
+
+// Snapshot returns the current values of the metrics.
+func (m *MetricsCollector) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := MetricsSnapshot{
+		Buckets:     append([]float64(nil), m.buckets...),
+		Counts:      make([]uint64, len(m.buckets)),
+		Sum:         time.Duration(m.sum * float64(time.Second)),
+		Count:       m.count,
+		InputBytes:  m.inputBytes,
+		OutputBytes: m.outputBytes,
+		Errors:      make(map[ErrorCode]uint64, len(m.errors)),
+	}
+	var cumulative uint64
+	for i := range m.buckets {
+		cumulative += m.counts[i]
+		snapshot.Counts[i] = cumulative
+	}
+	for code, count := range m.errors {
+		snapshot.Errors[code] = count
+	}
+	return snapshot
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format:
+//
+//	jsonformat_format_duration_seconds  histogram of formatting durations
+//	jsonformat_input_bytes_total        bytes of input read
+//	jsonformat_output_bytes_total       bytes of output written
+//	jsonformat_errors_total{code="..."} failures by error code
+func (m *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	snapshot := m.Snapshot()
+	var b strings.Builder
+	b.WriteString("# HELP jsonformat_format_duration_seconds Time spent formatting documents.\n")
+	b.WriteString("# TYPE jsonformat_format_duration_seconds histogram\n")
+	for i, bound := range snapshot.Buckets {
+		fmt.Fprintf(&b, "jsonformat_format_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'g', -1, 64), snapshot.Counts[i])
+	}
+	fmt.Fprintf(&b, "jsonformat_format_duration_seconds_bucket{le=\"+Inf\"} %d\n", snapshot.Count)
+	fmt.Fprintf(&b, "jsonformat_format_duration_seconds_sum %s\n", strconv.FormatFloat(snapshot.Sum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(&b, "jsonformat_format_duration_seconds_count %d\n", snapshot.Count)
+	b.WriteString("# HELP jsonformat_input_bytes_total Bytes of input read.\n")
+	b.WriteString("# TYPE jsonformat_input_bytes_total counter\n")
+	fmt.Fprintf(&b, "jsonformat_input_bytes_total %d\n", snapshot.InputBytes)
+	b.WriteString("# HELP jsonformat_output_bytes_total Bytes of output written.\n")
+	b.WriteString("# TYPE jsonformat_output_bytes_total counter\n")
+	fmt.Fprintf(&b, "jsonformat_output_bytes_total %d\n", snapshot.OutputBytes)
+	b.WriteString("# HELP jsonformat_errors_total Documents that failed to format, by error code.\n")
+	b.WriteString("# TYPE jsonformat_errors_total counter\n")
+	codes := make([]string, 0, len(snapshot.Errors))
+	for code := range snapshot.Errors {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "jsonformat_errors_total{code=%q} %d\n", code, snapshot.Errors[ErrorCode(code)])
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format, so
+// the collector can be registered as the handler of a metrics endpoint.
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+// Read reads from the underlying reader and counts the bytes read
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	io.Writer
+	n int
+}
+
+// Write writes to the underlying writer and counts the bytes written
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += n
+	return n, err
+}
@@ -0,0 +1,85 @@
+package jsonformat
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stepMetricsClock makes each formatting take the given duration
+func stepMetricsClock(t *testing.T, step time.Duration) {
+	t.Helper()
+	saved := metricsClock
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	metricsClock = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	t.Cleanup(func() { metricsClock = saved })
+}
+
+func TestMetricsCollector(t *testing.T) {
+	stepMetricsClock(t, 2*time.Millisecond)
+	metrics := NewMetricsCollector(0.01, 0.001, 0.001)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetMetrics(metrics)
+
+	if _, err := formatter.Format(`{"a":1}`); err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if _, err := formatter.Format(`{"a":`); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+	var output bytes.Buffer
+	if err := formatter.FormatTo(&output, strings.NewReader(`[1]`)); err != nil {
+		t.Fatalf("FormatTo failed: %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot.Buckets) != 2 || snapshot.Counts[0] != 0 || snapshot.Counts[1] != 3 {
+		t.Errorf("histogram = %v %v, want [0.001 0.01] [0 3]", snapshot.Buckets, snapshot.Counts)
+	}
+	if snapshot.Count != 3 || snapshot.Sum != 6*time.Millisecond {
+		t.Errorf("Count = %d, Sum = %v, want 3, 6ms", snapshot.Count, snapshot.Sum)
+	}
+	expectedOutput := len("{\n  \"a\": 1\n}") + output.Len()
+	if snapshot.InputBytes != 15 || snapshot.OutputBytes != uint64(expectedOutput) {
+		t.Errorf("InputBytes = %d, OutputBytes = %d, want 15, %d", snapshot.InputBytes, snapshot.OutputBytes, expectedOutput)
+	}
+	if len(snapshot.Errors) != 1 || snapshot.Errors[CodeSyntax] != 1 {
+		t.Errorf("Errors = %v, want one syntax error", snapshot.Errors)
+	}
+}
+
+func TestMetricsCollectorServeHTTP(t *testing.T) {
+	stepMetricsClock(t, 2*time.Millisecond)
+	metrics := NewMetricsCollector(0.001, 0.01)
+	formatter := NewFormatter(DefaultConfig())
+	formatter.SetMetrics(metrics)
+	formatter.Format(`{"a":1}`)
+	formatter.Format(``)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", contentType)
+	}
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE jsonformat_format_duration_seconds histogram",
+		`jsonformat_format_duration_seconds_bucket{le="0.001"} 0`,
+		`jsonformat_format_duration_seconds_bucket{le="0.01"} 2`,
+		`jsonformat_format_duration_seconds_bucket{le="+Inf"} 2`,
+		"jsonformat_format_duration_seconds_sum 0.004",
+		"jsonformat_format_duration_seconds_count 2",
+		"jsonformat_input_bytes_total 7",
+		"jsonformat_output_bytes_total 12",
+		`jsonformat_errors_total{code="empty_input"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// FormatMmap formats the JSON file at path and writes the result to w. On
+// platforms that support it, the file is memory-mapped and read through the
+// mapping, so the operating system page cache holds the input instead of a
+// copy on the heap; elsewhere the file is streamed as with FormatTo. Only the
+// tokens being formatted are held in memory, which makes this the cheapest way
+// to format very large read-only files. Lenient special floats still need
+// the whole input as a string, as with FormatTo.
+//
+// The file must not be truncated while it is being formatted, as reading a
+// mapping past the end of its file raises a fault on some platforms. If an
+// error is returned, part of the output may already have been written to w.
+// The limit of WithMaxTokens still applies, and its default of 10000 tokens
+// rejects all but small files, so large files need WithMaxTokens(0).
+//
+// Example:
+//
+//	formatter := NewFormatter(NewConfig(WithMaxTokens(0)))
+//	if err := formatter.FormatMmap(os.Stdout, "dump.json"); err != nil {
+//	    log.Fatal(err)
+//	}
+func (f *Formatter) FormatMmap(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return WrapFormatError("failed to open "+path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return WrapFormatError("failed to read "+path, err)
+	}
+	data, unmap, err := mapFile(file, info.Size())
+	if err != nil {
+		return WrapFormatError("failed to map "+path, err)
+	}
+	if data == nil {
+		return f.FormatTo(w, file)
+	}
+	defer unmap()
+	return f.FormatTo(w, bytes.NewReader(data))
+}
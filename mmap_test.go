@@ -0,0 +1,60 @@
+package jsonformat
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatMmap(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "object",
+			content: `{"users":[{"id":1,"name":"Alice"}]}`,
+			expected: `{
+  "users": [
+    {"id": 1, "name": "Alice"}
+  ]
+}`,
+		},
+		{
+			name:     "special floats",
+			content:  `[NaN,1]`,
+			options:  []ConfigOption{WithSpecialFloats(SpecialFloatsAsNull), WithCompactDepth(1)},
+			expected: "[null, 1]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "input.json", tt.content)
+			var output strings.Builder
+			if err := NewFormatter(NewConfig(tt.options...)).FormatMmap(&output, path); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestFormatMmapErrors(t *testing.T) {
+	formatter := NewFormatter(NewConfig())
+	var output strings.Builder
+
+	if err := formatter.FormatMmap(&output, filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "failed to open") {
+		t.Errorf("Expected open error, got %v", err)
+	}
+	if err := formatter.FormatMmap(&output, writeFile(t, "empty.json", "")); err == nil {
+		t.Error("Expected error for empty file")
+	}
+	if err := formatter.FormatMmap(&output, writeFile(t, "broken.json", `{"a":`)); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+}
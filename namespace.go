@@ -0,0 +1,107 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DefaultNamespaceSeparators are the characters that end the namespace of a
+// key when WithNamespaceGrouping is given no separators
+const DefaultNamespaceSeparators = "./"
+
+// WithNamespaceGrouping groups the members of objects by the namespace of
+// their keys: the part before the first of the separator characters, such as
+// "aws" in "aws.region" or "app" in "app.kubernetes.io/name". Members sharing
+// a namespace are moved next to the first of them, and expanded objects have
+// a blank line wherever the namespace changes, which helps reading label and
+// annotation maps. Keys without a separator have no namespace and keep their
+// places. Empty separators select DefaultNamespaceSeparators.
+//
+// Objects are held in memory until they end, so that their members can be
+// reordered. Objects at paths with a key order set with WithKeyOrder keep that
+// order, and are only separated by blank lines.
+//
+// Example:
+//
+//	config := NewConfig(WithNamespaceGrouping(""))
+//	// {
+//	//   "name": "web",
+//	//
+//	//   "aws.region": "us-east-1",
+//	//   "aws.zone": "us-east-1a",
+//	//
+//	//   "k8s.pod": "web-1"
+//	// }
+func WithNamespaceGrouping(separators string) ConfigOption {
+	return func(c *Config) {
+		if separators == "" {
+			separators = DefaultNamespaceSeparators
+		}
+		c.NamespaceSeparators = separators
+	}
+}
+
+// keyNamespace returns the namespace of the key, or "" if it has none
+func (p *TokenParser) keyNamespace(key string) string {
+	if i := strings.IndexAny(key, p.config.NamespaceSeparators); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// groupNamespaces returns the tokens of an object with the members sharing a
+// namespace moved next to the first of them
+func (p *TokenParser) groupNamespaces(tokens []json.Token) []json.Token {
+	members := splitMembers(tokens)
+	groups := make(map[string][]int)
+	for i, member := range members {
+		if namespace := p.keyNamespace(member[0].(string)); namespace != "" {
+			groups[namespace] = append(groups[namespace], i)
+		}
+	}
+
+	used := make([]bool, len(members))
+	ordered := make([]json.Token, 0, len(tokens))
+	ordered = append(ordered, tokens[0])
+	for i, member := range members {
+		if used[i] {
+			continue
+		}
+		namespace := p.keyNamespace(member[0].(string))
+		if namespace == "" {
+			ordered = append(ordered, member...)
+			continue
+		}
+		for _, j := range groups[namespace] {
+			used[j] = true
+			ordered = append(ordered, members[j]...)
+		}
+	}
+	return append(ordered, tokens[len(tokens)-1])
+}
+
+// namespaceBreak reports whether a blank line precedes the key being written,
+// because its namespace differs from that of the previous key
+func (p *TokenParser) namespaceBreak() bool {
+	if p.config.NamespaceSeparators == "" || !p.expectingKey || len(p.path) == 0 || len(p.path) != len(p.inArray) || p.isInArray() {
+		return false
+	}
+	level := &p.path[len(p.path)-1]
+	previous := level.namespace
+	level.namespace = p.keyNamespace(level.key)
+	return !p.isFirstElement && level.namespace != previous
+}
@@ -0,0 +1,89 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestNamespaceGrouping(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "labels",
+			input:   `{"name":"web","aws.region":"us-east-1","k8s.pod":"web-1","aws.zone":"us-east-1a","id":1}`,
+			options: []ConfigOption{WithNamespaceGrouping("")},
+			expected: `{
+  "name": "web",
+
+  "aws.region": "us-east-1",
+  "aws.zone": "us-east-1a",
+
+  "k8s.pod": "web-1",
+
+  "id": 1
+}`,
+		},
+		{
+			name:    "kubernetes annotations",
+			input:   `{"metadata":{"labels":{"app.kubernetes.io/name":"web","team":"core","app.kubernetes.io/version":"1.2","tier":"front"}}}`,
+			options: []ConfigOption{WithNamespaceGrouping("/"), WithCompactDepth(0)},
+			expected: `{
+  "metadata": {
+    "labels": {
+      "app.kubernetes.io/name": "web",
+      "app.kubernetes.io/version": "1.2",
+
+      "team": "core",
+      "tier": "front"
+    }
+  }
+}`,
+		},
+		{
+			name:     "compact object",
+			input:    `{"a":{"x.1":1,"y":2,"x.2":3}}`,
+			options:  []ConfigOption{WithNamespaceGrouping("."), WithCompactDepth(1)},
+			expected: `{"a": {"x.1": 1, "x.2": 3, "y": 2}}`,
+		},
+		{
+			name:    "key order kept",
+			input:   `{"x.1":1,"y":2,"x.2":3}`,
+			options: []ConfigOption{WithNamespaceGrouping("."), WithKeyOrder("", "x.1", "y", "x.2")},
+			expected: `{
+  "x.1": 1,
+
+  "y": 2,
+
+  "x.2": 3
+}`,
+		},
+		{
+			name:    "scalars first",
+			input:   `{"b.x":[1],"a.y":2,"b.z":3}`,
+			options: []ConfigOption{WithNamespaceGrouping("."), WithKeyOrderPolicy(KeyOrderScalarsFirstSorted)},
+			expected: `{
+  "a.y": 2,
+
+  "b.z": 3,
+  "b.x": [
+    1
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
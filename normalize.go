@@ -0,0 +1,54 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import "encoding/json"
+
+// NormalizeFunc returns a Unicode normalization form of s, such as the
+// NFC form returned by norm.NFC.String of golang.org/x/text/unicode/norm.
+type NormalizeFunc func(s string) string
+
+// WithUnicodeNormalization normalizes object keys and string values with
+// normalize before they are written, so text that looks the same but is
+// composed differently, such as "é" written as one character or as "e"
+// followed by a combining accent, is written the same way. Keys are
+// normalized before they are sorted, matched against paths, and checked for
+// duplicates, so such keys no longer show up as spurious differences in
+// diffs and sorted output. A nil function disables normalization.
+//
+// This package has no dependencies, so the normalization forms themselves
+// come from golang.org/x/text/unicode/norm, or any other implementation.
+//
+// Example:
+//
+//	import "golang.org/x/text/unicode/norm"
+//
+//	config := NewConfig(WithUnicodeNormalization(norm.NFC.String))
+func WithUnicodeNormalization(normalize NormalizeFunc) ConfigOption {
+	return func(c *Config) {
+		c.UnicodeNormalization = normalize
+	}
+}
+
+// normalizeToken applies the Unicode normalization to keys and string values
+func (p *TokenParser) normalizeToken(token json.Token) json.Token {
+	if p.config.UnicodeNormalization == nil {
+		return token
+	}
+	if s, ok := token.(string); ok {
+		return p.config.UnicodeNormalization(s)
+	}
+	return token
+}
@@ -0,0 +1,69 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+// composeAcute is a tiny stand-in for NFC that composes "e" and "a"
+// followed by a combining acute accent
+func composeAcute(s string) string {
+	return strings.NewReplacer("é", "é", "á", "á").Replace(s)
+}
+
+func TestWithUnicodeNormalization(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		input    string
+		expected string
+	}{
+		{
+			name:     "keys and values",
+			options:  []ConfigOption{WithUnicodeNormalization(composeAcute)},
+			input:    "{\"café\":\"páté\",\"n\":1}",
+			expected: "{\n  \"café\": \"páté\",\n  \"n\": 1\n}",
+		},
+		{
+			name:     "sorted keys",
+			options:  []ConfigOption{WithUnicodeNormalization(composeAcute), WithSortedKeys("")},
+			input:    "{\"f\":1,\"é\":2,\"e\":3}",
+			expected: "{\n  \"e\": 3,\n  \"f\": 1,\n  \"é\": 2\n}",
+		},
+		{
+			name:     "disabled",
+			options:  []ConfigOption{WithUnicodeNormalization(composeAcute), WithUnicodeNormalization(nil)},
+			input:    "[\"é\"]",
+			expected: "[\n  \"é\"\n]",
+		},
+		{
+			name:     "numbers are kept",
+			options:  []ConfigOption{WithUnicodeNormalization(strings.ToUpper)},
+			input:    `{"a":1.5,"b":"x","c":null}`,
+			expected: "{\n  \"A\": 1.5,\n  \"B\": \"X\",\n  \"C\": null\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnicodeNormalizationDuplicateKeys(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithUnicodeNormalization(composeAcute)))
+	_, warnings, err := formatter.FormatWithWarnings("{\"café\":1,\"café\":2}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarningDuplicateKey {
+		t.Errorf("Expected a duplicate key warning, got %v", warnings)
+	}
+}
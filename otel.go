@@ -0,0 +1,90 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// WithSpanDurations writes the duration of each OpenTelemetry span, computed
+// from its startTimeUnixNano and endTimeUnixNano, as a comment after the end
+// time, so OTLP JSON exports can be reviewed without subtracting nanosecond
+// timestamps by hand. Timestamps may be strings, as OTLP JSON writes them, or
+// numbers. The comments make the output invalid JSON, so use this option for
+// display only.
+//
+// Example:
+//
+//	config := NewConfig(WithSpanDurations())
+//	// {
+//	//   "name": "GET /users",
+//	//   "startTimeUnixNano": "1700000000000000000",
+//	//   "endTimeUnixNano": "1700000000012500000" /* 12.5ms */
+//	// }
+func WithSpanDurations() ConfigOption {
+	return func(c *Config) {
+		c.SpanDurations = true
+	}
+}
+
+// annotateSpanDuration records the start time of a span and appends the
+// duration to its end time
+func (p *TokenParser) annotateSpanDuration(token json.Token) (json.Token, error) {
+	if !p.config.SpanDurations || p.config.StrictJSON || !p.isScalarValue(token) {
+		return token, nil
+	}
+	if len(p.path) == 0 || p.inArray[len(p.inArray)-1] {
+		return token, nil
+	}
+	level := &p.path[len(p.path)-1]
+	if level.key == "startTimeUnixNano" {
+		level.spanStart, _ = unixNano(token)
+		return token, nil
+	}
+	if level.key != "endTimeUnixNano" {
+		return token, nil
+	}
+	end, ok := unixNano(token)
+	if !ok || level.spanStart == 0 || end < level.spanStart {
+		return token, nil
+	}
+
+	value, isRaw := token.(RawValue)
+	if !isRaw {
+		compact, err := p.compactTokens([]json.Token{token})
+		if err != nil {
+			return nil, err
+		}
+		value = RawValue(compact)
+	}
+	return value + RawValue(" /* "+time.Duration(end-level.spanStart).String()+" */"), nil
+}
+
+// unixNano returns the nanoseconds of a timestamp written as a string or a number
+func unixNano(token json.Token) (int64, bool) {
+	switch value := token.(type) {
+	case string:
+		nanos, err := strconv.ParseInt(value, 10, 64)
+		return nanos, err == nil && nanos > 0
+	case json.Number:
+		nanos, err := strconv.ParseInt(string(value), 10, 64)
+		return nanos, err == nil && nanos > 0
+	case float64:
+		return int64(value), value > 0
+	}
+	return 0, false
+}
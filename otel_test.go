@@ -0,0 +1,117 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestSpanDurations(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "string timestamps",
+			input:   `{"name":"GET /users","startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000012500000"}`,
+			options: []ConfigOption{WithSpanDurations()},
+			expected: `{
+  "name": "GET /users",
+  "startTimeUnixNano": "1700000000000000000",
+  "endTimeUnixNano": "1700000000012500000" /* 12.5ms */
+}`,
+		},
+		{
+			name:     "number timestamps",
+			input:    `{"startTimeUnixNano":1000,"endTimeUnixNano":3000001000}`,
+			options:  []ConfigOption{WithSpanDurations(), WithCompactDepth(1)},
+			expected: `{"startTimeUnixNano": 1000, "endTimeUnixNano": 3000001000 /* 3s */}`,
+		},
+		{
+			name:    "nested values between timestamps",
+			input:   `{"spans":[{"startTimeUnixNano":"1000","attributes":[{"key":"k","value":{"intValue":"1"}}],"endTimeUnixNano":"2000"}]}`,
+			options: []ConfigOption{WithSpanDurations(), WithCompactDepth(2)},
+			expected: `{
+  "spans": [{"startTimeUnixNano": "1000", "attributes": [{"key": "k", "value": {"intValue": "1"}}], "endTimeUnixNano": "2000" /* 1µs */}]
+}`,
+		},
+		{
+			name:    "start time of another span",
+			input:   `{"spans":[{"startTimeUnixNano":"1000"},{"endTimeUnixNano":"2000"}]}`,
+			options: []ConfigOption{WithSpanDurations(), WithCompactDepth(2)},
+			expected: `{
+  "spans": [{"startTimeUnixNano": "1000"}, {"endTimeUnixNano": "2000"}]
+}`,
+		},
+		{
+			name:     "invalid timestamps",
+			input:    `{"a":{"startTimeUnixNano":"2000","endTimeUnixNano":"1000"},"b":{"startTimeUnixNano":"soon","endTimeUnixNano":"1000"}}`,
+			options:  []ConfigOption{WithSpanDurations(), WithCompactDepth(1)},
+			expected: `{"a": {"startTimeUnixNano": "2000", "endTimeUnixNano": "1000"}, "b": {"startTimeUnixNano": "soon", "endTimeUnixNano": "1000"}}`,
+		},
+		{
+			name:     "strict JSON",
+			input:    `{"startTimeUnixNano":"1000","endTimeUnixNano":"2000"}`,
+			options:  []ConfigOption{WithSpanDurations(), WithStrictJSON(), WithCompactDepth(1)},
+			expected: `{"startTimeUnixNano": "1000", "endTimeUnixNano": "2000"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestOtelPreset(t *testing.T) {
+	input := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},"scopeSpans":[{"scope":{"name":"net/http"},"spans":[` +
+		`{"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","name":"GET /users","kind":2,"startTimeUnixNano":"1700000000000000000","endTimeUnixNano":"1700000000012500000","attributes":[{"key":"http.status_code","value":{"intValue":"200"}}]},` +
+		`{"spanId":"eee19b7ec3c1b175","name":"SELECT","startTimeUnixNano":"1700000000001000000","endTimeUnixNano":"1700000000011000000"}]}]}]}`
+	expected := `{
+  "resourceSpans": [
+    {
+      "resource": {
+        "attributes": [
+          {"key": "service.name", "value": {"stringValue": "api"}}
+        ]
+      },
+      "scopeSpans": [
+        {
+          "scope": {"name": "net/http"},
+          "spans": [
+            {
+              "traceId": "5b8efff798038103d269b633813fc60c",
+              "spanId": "eee19b7ec3c1b174",
+              "name": "GET /users",
+              "kind": 2,
+              "startTimeUnixNano": "1700000000000000000",
+              "endTimeUnixNano": "1700000000012500000" /* 12.5ms */,
+              "attributes": [{"key": "http.status_code", "value": {"intValue": "200"}}]
+            },
+            {"spanId": "eee19b7ec3c1b175", "name": "SELECT", "startTimeUnixNano": "1700000000001000000", "endTimeUnixNano": "1700000000011000000" /* 10ms */}
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+	config, ok := Preset("otel")
+	if !ok {
+		t.Fatal("Expected otel preset to exist")
+	}
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package jsonformat
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestFormatFileKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Changing the owner of a file requires root")
+	}
+	path := writeFile(t, "config.json", `{"a":1}`)
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatalf("Failed to change the owner: %v", err)
+	}
+
+	if _, err := NewFormatter(DefaultConfig()).FormatFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", path, err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	if stat.Uid != 1234 || stat.Gid != 5678 {
+		t.Errorf("Expected owner 1234:5678, got %d:%d", stat.Uid, stat.Gid)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import "encoding/json"
+
+// Placeholders written in place of values by WithPlaceholders.
+const (
+	StringPlaceholder  RawValue = `"<string>"`
+	NumberPlaceholder  RawValue = "<number>"
+	BooleanPlaceholder RawValue = "<boolean>"
+	NullPlaceholder    RawValue = "<null>"
+)
+
+// WithPlaceholders replaces every scalar value with a placeholder for its type,
+// turning a real payload into a sanitized template for documentation:
+//
+//	{"name": "Alice", "age": 30}  →  {"name": "<string>", "age": <number>}
+//
+// The output keeps the structure and keys of the input, but it is not valid JSON
+// because number, boolean, and null placeholders are written unquoted, unless
+// WithStrictJSON is set, which writes them as strings such as "<number>".
+// Placeholders are applied after any previously added value hooks.
+func WithPlaceholders() ConfigOption {
+	return WithValueHook(placeholderHook)
+}
+
+// placeholderHook replaces a value with the placeholder for its type
+func placeholderHook(ctx ValueContext) (interface{}, bool) {
+	switch ctx.Value.(type) {
+	case string:
+		return StringPlaceholder, true
+	case float64:
+		return NumberPlaceholder, true
+	case bool:
+		return BooleanPlaceholder, true
+	case nil:
+		return NullPlaceholder, true
+	default:
+		// Raw values produced by other hooks are left untouched
+		return nil, false
+	}
+}
+
+// strictPlaceholder writes unquoted placeholders as strings, so that the
+// output stays valid JSON with WithStrictJSON
+func strictPlaceholder(token json.Token) json.Token {
+	switch token {
+	case NumberPlaceholder, BooleanPlaceholder, NullPlaceholder:
+		return RawValue(`"` + string(token.(RawValue)) + `"`)
+	}
+	return token
+}
@@ -0,0 +1,72 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	config := NewConfig(WithPlaceholders())
+
+	result, err := NewFormatter(config).Format(`{"name":"Alice","age":30,"admin":false,"manager":null,"tags":[{"id":1,"label":"x"}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "name": "<string>",
+  "age": <number>,
+  "admin": <boolean>,
+  "manager": <null>,
+  "tags": [
+    {"id": <number>, "label": "<string>"}
+  ]
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestPlaceholdersKeepRawValues(t *testing.T) {
+	config := NewConfig(
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			if ctx.Key == "id" {
+				return RawValue("<uuid>"), true
+			}
+			return nil, false
+		}),
+		WithPlaceholders(),
+	)
+
+	result, err := NewFormatter(config).Format(`{"id":"0f8e","name":"Alice"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "id": <uuid>,
+  "name": "<string>"
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestPlaceholdersStrictJSON(t *testing.T) {
+	config := NewConfig(WithPlaceholders(), WithStrictJSON())
+
+	result, err := NewFormatter(config).Format(`{"name":"Alice","age":30,"admin":false,"manager":null}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "name": "<string>",
+  "age": "<number>",
+  "admin": "<boolean>",
+  "manager": "<null>"
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	if !json.Valid([]byte(result)) {
+		t.Errorf("Expected valid JSON, got:\n%s", result)
+	}
+}
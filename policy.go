@@ -0,0 +1,203 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of the values of the policy enums, in value order. They are the
+// vocabulary shared by String, the Parse functions, text encoding in style
+// sheets and other configuration files, and the command line flags.
+var (
+	specialFloatNames    = []string{"reject", "null", "string"}
+	encodingPolicyNames  = []string{"transcode", "reject"}
+	keyOrderPolicyNames  = []string{"input", "scalars-first", "scalars-first-sorted"}
+	defaultFoldingNames  = []string{"off", "annotate", "hide"}
+	containerIndentNames = []string{"nested", "aligned", "double"}
+)
+
+// policyName returns the name of a policy value, or the type and number of
+// a value without a name
+func policyName(names []string, typeName string, value int) string {
+	if value >= 0 && value < len(names) {
+		return names[value]
+	}
+	return fmt.Sprintf("%s(%d)", typeName, value)
+}
+
+// parsePolicy returns the value of a policy name, or an error listing the names
+func parsePolicy(names []string, policy, s string) (int, error) {
+	for value, name := range names {
+		if s == name {
+			return value, nil
+		}
+	}
+	return 0, NewFormatError(fmt.Sprintf("unknown policy %q for %s (available: %s)", s, policy, strings.Join(names, ", ")))
+}
+
+// marshalPolicy returns the name of a policy value as text, or an error for
+// a value without a name
+func marshalPolicy(names []string, typeName string, value int) ([]byte, error) {
+	if value < 0 || value >= len(names) {
+		return nil, NewFormatError(fmt.Sprintf("unknown policy %s", policyName(names, typeName, value)))
+	}
+	return []byte(names[value]), nil
+}
+
+// String returns the name of the policy: "reject", "null", or "string".
+func (p SpecialFloatPolicy) String() string {
+	return policyName(specialFloatNames, "SpecialFloatPolicy", int(p))
+}
+
+// ParseSpecialFloatPolicy returns the policy named as by String.
+func ParseSpecialFloatPolicy(s string) (SpecialFloatPolicy, error) {
+	value, err := parsePolicy(specialFloatNames, "special floats", s)
+	return SpecialFloatPolicy(value), err
+}
+
+// MarshalText encodes the policy as its name.
+func (p SpecialFloatPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(specialFloatNames, "SpecialFloatPolicy", int(p))
+}
+
+// UnmarshalText decodes a policy name, failing for unknown names.
+func (p *SpecialFloatPolicy) UnmarshalText(text []byte) error {
+	value, err := ParseSpecialFloatPolicy(string(text))
+	if err == nil {
+		*p = value
+	}
+	return err
+}
+
+// String returns the name of the policy: "transcode" or "reject".
+func (p EncodingPolicy) String() string {
+	return policyName(encodingPolicyNames, "EncodingPolicy", int(p))
+}
+
+// ParseEncodingPolicy returns the policy named as by String.
+func ParseEncodingPolicy(s string) (EncodingPolicy, error) {
+	value, err := parsePolicy(encodingPolicyNames, "encodings", s)
+	return EncodingPolicy(value), err
+}
+
+// MarshalText encodes the policy as its name.
+func (p EncodingPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(encodingPolicyNames, "EncodingPolicy", int(p))
+}
+
+// UnmarshalText decodes a policy name, failing for unknown names.
+func (p *EncodingPolicy) UnmarshalText(text []byte) error {
+	value, err := ParseEncodingPolicy(string(text))
+	if err == nil {
+		*p = value
+	}
+	return err
+}
+
+// String returns the name of the policy: "input", "scalars-first", or
+// "scalars-first-sorted".
+func (p KeyOrderPolicy) String() string {
+	return policyName(keyOrderPolicyNames, "KeyOrderPolicy", int(p))
+}
+
+// ParseKeyOrderPolicy returns the policy named as by String.
+func ParseKeyOrderPolicy(s string) (KeyOrderPolicy, error) {
+	value, err := parsePolicy(keyOrderPolicyNames, "key order", s)
+	return KeyOrderPolicy(value), err
+}
+
+// MarshalText encodes the policy as its name.
+func (p KeyOrderPolicy) MarshalText() ([]byte, error) {
+	return marshalPolicy(keyOrderPolicyNames, "KeyOrderPolicy", int(p))
+}
+
+// UnmarshalText decodes a policy name, failing for unknown names.
+func (p *KeyOrderPolicy) UnmarshalText(text []byte) error {
+	value, err := ParseKeyOrderPolicy(string(text))
+	if err == nil {
+		*p = value
+	}
+	return err
+}
+
+// String returns the name of the folding: "off", "annotate", or "hide".
+func (f DefaultFolding) String() string {
+	return policyName(defaultFoldingNames, "DefaultFolding", int(f))
+}
+
+// ParseDefaultFolding returns the folding named as by String.
+func ParseDefaultFolding(s string) (DefaultFolding, error) {
+	value, err := parsePolicy(defaultFoldingNames, "default folding", s)
+	return DefaultFolding(value), err
+}
+
+// MarshalText encodes the folding as its name.
+func (f DefaultFolding) MarshalText() ([]byte, error) {
+	return marshalPolicy(defaultFoldingNames, "DefaultFolding", int(f))
+}
+
+// UnmarshalText decodes a folding name, failing for unknown names.
+func (f *DefaultFolding) UnmarshalText(text []byte) error {
+	value, err := ParseDefaultFolding(string(text))
+	if err == nil {
+		*f = value
+	}
+	return err
+}
+
+// String returns the name of the indentation: "nested", "aligned", or
+// "double".
+func (c ContainerIndent) String() string {
+	return policyName(containerIndentNames, "ContainerIndent", int(c))
+}
+
+// ParseContainerIndent returns the indentation named as by String.
+func ParseContainerIndent(s string) (ContainerIndent, error) {
+	value, err := parsePolicy(containerIndentNames, "container indentation", s)
+	return ContainerIndent(value), err
+}
+
+// MarshalText encodes the indentation as its name.
+func (c ContainerIndent) MarshalText() ([]byte, error) {
+	return marshalPolicy(containerIndentNames, "ContainerIndent", int(c))
+}
+
+// UnmarshalText decodes an indentation name, failing for unknown names.
+func (c *ContainerIndent) UnmarshalText(text []byte) error {
+	value, err := ParseContainerIndent(string(text))
+	if err == nil {
+		*c = value
+	}
+	return err
+}
+
+// MarshalText encodes the style version as its name, such as "v1".
+func (v StyleVersion) MarshalText() ([]byte, error) {
+	if v < StyleV1 || v > StyleLatest {
+		return nil, NewFormatError(fmt.Sprintf("unknown style version %d", int(v)+1))
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText decodes a style version as ParseStyleVersion does.
+func (v *StyleVersion) UnmarshalText(text []byte) error {
+	version, err := ParseStyleVersion(string(text))
+	if err == nil {
+		*v = version
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPolicyNames(t *testing.T) {
+	type policy interface {
+		String() string
+		MarshalText() ([]byte, error)
+	}
+	tests := []struct {
+		name  string
+		value policy
+		parse func(string) (policy, error)
+	}{
+		{"reject", SpecialFloatsReject, func(s string) (policy, error) { return ParseSpecialFloatPolicy(s) }},
+		{"null", SpecialFloatsAsNull, func(s string) (policy, error) { return ParseSpecialFloatPolicy(s) }},
+		{"string", SpecialFloatsAsString, func(s string) (policy, error) { return ParseSpecialFloatPolicy(s) }},
+		{"transcode", EncodingTranscode, func(s string) (policy, error) { return ParseEncodingPolicy(s) }},
+		{"reject", EncodingReject, func(s string) (policy, error) { return ParseEncodingPolicy(s) }},
+		{"input", KeyOrderInput, func(s string) (policy, error) { return ParseKeyOrderPolicy(s) }},
+		{"scalars-first", KeyOrderScalarsFirst, func(s string) (policy, error) { return ParseKeyOrderPolicy(s) }},
+		{"scalars-first-sorted", KeyOrderScalarsFirstSorted, func(s string) (policy, error) { return ParseKeyOrderPolicy(s) }},
+		{"off", FoldDefaultsOff, func(s string) (policy, error) { return ParseDefaultFolding(s) }},
+		{"annotate", FoldDefaultsAnnotate, func(s string) (policy, error) { return ParseDefaultFolding(s) }},
+		{"hide", FoldDefaultsHide, func(s string) (policy, error) { return ParseDefaultFolding(s) }},
+		{"nested", IndentNested, func(s string) (policy, error) { return ParseContainerIndent(s) }},
+		{"aligned", IndentAligned, func(s string) (policy, error) { return ParseContainerIndent(s) }},
+		{"double", IndentDouble, func(s string) (policy, error) { return ParseContainerIndent(s) }},
+		{"v2", StyleV2, func(s string) (policy, error) { return ParseStyleVersion(s) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.String(); got != tt.name {
+				t.Errorf("Expected String() %q, got %q", tt.name, got)
+			}
+			text, err := tt.value.MarshalText()
+			if err != nil || string(text) != tt.name {
+				t.Errorf("Expected MarshalText() %q, got %q (%v)", tt.name, text, err)
+			}
+			parsed, err := tt.parse(tt.name)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if parsed != tt.value {
+				t.Errorf("Expected %v, got %v", tt.value, parsed)
+			}
+		})
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	_, err := ParseKeyOrderPolicy("sorted")
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Code() != CodeInvalidArgument {
+		t.Fatalf("Expected an invalid argument error, got %v", err)
+	}
+	expected := `unknown policy "sorted" for key order (available: input, scalars-first, scalars-first-sorted)`
+	if err.Error() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, err.Error())
+	}
+
+	if got := SpecialFloatPolicy(7).String(); got != "SpecialFloatPolicy(7)" {
+		t.Errorf("Expected the type and number of an unknown value, got %q", got)
+	}
+	if _, err := json.Marshal(struct{ P DefaultFolding }{DefaultFolding(9)}); err == nil {
+		t.Error("Expected an error for encoding an unknown value")
+	}
+
+	var decoded struct{ P SpecialFloatPolicy }
+	if err := json.Unmarshal([]byte(`{"P": "null"}`), &decoded); err != nil || decoded.P != SpecialFloatsAsNull {
+		t.Errorf("Expected SpecialFloatsAsNull, got %v (%v)", decoded.P, err)
+	}
+	if err := json.Unmarshal([]byte(`{"P": "nan"}`), &decoded); err == nil {
+		t.Error("Expected an error for decoding an unknown name")
+	}
+}
+
+func TestStyleSheetPolicies(t *testing.T) {
+	options, _, err := ParseStyleSheet([]byte(`{"specialFloats": "string", "keyOrder": "scalars-first"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := NewConfig(options...)
+	if config.SpecialFloats != SpecialFloatsAsString || config.KeyOrderPolicy != KeyOrderScalarsFirst {
+		t.Errorf("Expected the policies of the style sheet, got %v and %v", config.SpecialFloats, config.KeyOrderPolicy)
+	}
+
+	if _, _, err := ParseStyleSheet([]byte(`{"keyOrder": "sorted"}`)); err == nil {
+		t.Error("Expected an error for an unknown policy name")
+	}
+}
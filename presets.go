@@ -0,0 +1,98 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"sort"
+)
+
+// presets maps preset names to the options that make up each preset
+var presets = map[string][]ConfigOption{
+	"default":  nil,
+	"expanded": {WithCompactDepth(0)},
+	"compact":  {WithCompactDepth(2)},
+	"tabs":     {WithTabs()},
+	"wide":     {WithIndentSize(4), WithMaxWidth(120)},
+	"otel":     {WithSpanDurations(), WithCompactDepth(6), WithMaxWidth(160)},
+	"aws":      {WithMaxWidth(120), WithCompactKeys("Tags", "TagSet", "TagList"), WithUTCTimestamps()},
+	"gcloud":   {WithMaxWidth(120), WithCompactKeys("items", "labels"), WithUTCTimestamps()},
+	"cloudevents": {
+		WithKeyOrder("", cloudEventsKeys...),
+		WithKeyOrder("*", cloudEventsKeys...),
+		WithEmbeddedJSON("data", "data_base64", "*.data", "*.data_base64"),
+		WithRedaction(webhookSignatureKeys...),
+	},
+}
+
+// cloudEventsKeys is the order of the members of CloudEvents envelopes: the
+// required and optional context attributes, extensions, and then the data
+var cloudEventsKeys = []string{"specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema", "*", "data", "data_base64"}
+
+// webhookSignatureKeys are keys and headers that carry webhook signatures
+var webhookSignatureKeys = []string{
+	"signature", "authorization", "x-hub-signature", "x-hub-signature-256",
+	"x-signature", "stripe-signature", "webhook-signature", "svix-signature",
+}
+
+// Preset returns a new Config for a named preset, and false if there is
+// no preset with that name. Available presets are:
+//   - default: the DefaultConfig settings
+//   - expanded: compact formatting disabled
+//   - compact: compact formatting from depth 2
+//   - tabs: tab indentation
+//   - wide: 4-space indentation with compact lines limited to 120 characters
+//   - aws: aws-cli output, with compact lines limited to 120 characters, each
+//     entry of Tags, TagSet, and TagList on its own line however long, and
+//     timestamps in UTC
+//   - gcloud: gcloud --format=json output, like aws but with each entry of
+//     labels and of items lists, such as metadata items, on its own line
+//   - cloudevents: CloudEvents and webhook envelopes, single or batched, with
+//     the context attributes first in specification order, the data written
+//     as JSON when it holds embedded or base64-encoded JSON, and signatures
+//     redacted (display only)
+//   - otel: OTLP JSON traces, with spans compact within 160 characters and
+//     their durations written after endTimeUnixNano (display only)
+//
+// Profiles registered with RegisterProfile are available by their names too.
+// Additional options are applied on top of the preset.
+//
+// Example:
+//
+//	config, ok := Preset("wide", WithCompactDepth(4))
+func Preset(name string, options ...ConfigOption) (*Config, bool) {
+	presetOptions, ok := presets[name]
+	if !ok {
+		profile, ok := lookupProfile(name)
+		if !ok {
+			return nil, false
+		}
+		presetOptions = profile.Options()
+	}
+	return NewConfig(append(append([]ConfigOption(nil), presetOptions...), options...)...), true
+}
+
+// PresetNames returns the names of all presets and registered profiles in
+// alphabetical order.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	for _, profile := range Profiles() {
+		names = append(names, profile.Name())
+	}
+	sort.Strings(names)
+	return names
+}
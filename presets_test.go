@@ -0,0 +1,134 @@
+package jsonformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreset(t *testing.T) {
+	config, ok := Preset("default")
+	if !ok {
+		t.Fatal("Expected default preset to exist")
+	}
+	if config.fingerprint() != DefaultConfig().fingerprint() {
+		t.Errorf("Expected default preset to match DefaultConfig, got %+v", config)
+	}
+
+	config, ok = Preset("wide", WithMaxWidth(80))
+	if !ok {
+		t.Fatal("Expected wide preset to exist")
+	}
+	if config.IndentSize != 4 || config.MaxWidth != 80 {
+		t.Errorf("Expected options to be applied on top of the preset, got %+v", config)
+	}
+
+	if _, ok := Preset("nope"); ok {
+		t.Error("Expected unknown preset to be reported")
+	}
+}
+
+func TestPresetReturnsNewInstance(t *testing.T) {
+	first, _ := Preset("tabs")
+	first.IndentSize = 8
+	second, _ := Preset("tabs")
+	if second.IndentSize == 8 {
+		t.Error("Modifying one preset config affected another")
+	}
+}
+
+func TestPresetNames(t *testing.T) {
+	expected := []string{"aws", "cloudevents", "compact", "default", "expanded", "gcloud", "otel", "tabs", "wide"}
+	if names := PresetNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestCloudEventsPreset(t *testing.T) {
+	input := `[{"data":"{\"orderId\":7,\"id\":\"c1\"}","type":"com.example.order.created","traceparent":"00-abc","id":"A1","specversion":"1.0","source":"/orders","signature":"sha256=4f2a"},` +
+		`{"id":"A2","data_base64":"eyJrIjogInYifQ==","specversion":"1.0"}]`
+	expected := `[
+  {
+    "specversion": "1.0",
+    "id": "A1",
+    "source": "/orders",
+    "type": "com.example.order.created",
+    "traceparent": "00-abc",
+    "signature": "[REDACTED]",
+    "data": {"orderId": 7, "id": "c1"}
+  },
+  {
+    "specversion": "1.0",
+    "id": "A2",
+    "data_base64": {"k": "v"}
+  }
+]`
+
+	config, ok := Preset("cloudevents")
+	if !ok {
+		t.Fatal("Expected cloudevents preset to exist")
+	}
+	result, err := NewFormatter(config).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestCloudPresets(t *testing.T) {
+	tests := []struct {
+		preset   string
+		input    string
+		expected string
+	}{
+		{
+			preset: "aws",
+			input: `{"Instances":[{"InstanceId":"i-0abc","LaunchTime":"2024-05-01T21:00:00+09:00",` +
+				`"Tags":[{"Key":"Name","Value":"web-1"},{"Key":"aws:cloudformation:stack-id","Value":"arn:aws:cloudformation:us-east-1:123456789012:stack/my-stack/1c2fa620-982a-11e3-aff7-50e2416294e0"}]}]}`,
+			expected: `{
+  "Instances": [
+    {
+      "InstanceId": "i-0abc",
+      "LaunchTime": "2024-05-01T12:00:00Z",
+      "Tags": [
+        {"Key": "Name", "Value": "web-1"},
+        {"Key": "aws:cloudformation:stack-id", "Value": "arn:aws:cloudformation:us-east-1:123456789012:stack/my-stack/1c2fa620-982a-11e3-aff7-50e2416294e0"}
+      ]
+    }
+  ]
+}`,
+		},
+		{
+			preset: "gcloud",
+			input:  `{"creationTimestamp":"2024-05-01T05:00:00.123-07:00","labels":{"env":"prod"},"metadata":{"items":[{"key":"enable-oslogin","value":"TRUE"}]}}`,
+			expected: `{
+  "creationTimestamp": "2024-05-01T12:00:00.123Z",
+  "labels": {
+    "env": "prod"
+  },
+  "metadata": {
+    "items": [
+      {"key": "enable-oslogin", "value": "TRUE"}
+    ]
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			config, ok := Preset(tt.preset)
+			if !ok {
+				t.Fatalf("Expected %s preset to exist", tt.preset)
+			}
+			result, err := NewFormatter(config).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
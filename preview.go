@@ -0,0 +1,132 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LayoutPreview compares the renderings of a document with two
+// configurations, as returned by Preview.
+type LayoutPreview struct {
+	// Before and After are the renderings with the first and the second
+	// configuration.
+	Before string
+	After  string
+
+	// Diff is a unified diff from Before to After, or empty if they are equal.
+	Diff string
+
+	// Changes lists the objects and arrays written on one line with one
+	// configuration and over several lines with the other, in document order.
+	// It is nil when a configuration has a post-processor or hard wrapping,
+	// as the regions of values are not known then.
+	Changes []LayoutChange
+}
+
+// LayoutChange is an object or array whose layout differs between the
+// renderings of a LayoutPreview.
+type LayoutChange struct {
+	// Path contains the object keys and array indices leading to the value,
+	// in the same form as ValueContext.Path.
+	Path []string
+
+	// BeforeLines and AfterLines are the numbers of lines the value spans in
+	// each rendering.
+	BeforeLines int
+	AfterLines  int
+}
+
+// String returns the change as "path: 1 line → 4 lines".
+func (c LayoutChange) String() string {
+	path := "(root)"
+	if len(c.Path) > 0 {
+		path = strings.Join(c.Path, ".")
+	}
+	return path + ": " + lineCount(c.BeforeLines) + " → " + lineCount(c.AfterLines)
+}
+
+// lineCount returns "1 line" or "n lines"
+func lineCount(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return strconv.Itoa(n) + " lines"
+}
+
+// Preview formats the input with two configurations and compares the
+// renderings, to show precisely what changing options does to a document.
+// Besides both renderings and a line diff, it lists the objects and arrays
+// that moved between one line and several lines. A nil configuration uses
+// the default configuration.
+//
+// Example:
+//
+//	preview, err := Preview(payload, DefaultConfig(), NewConfig(WithMaxWidth(60)))
+//	for _, change := range preview.Changes {
+//	    fmt.Println(change) // users.0: 1 line → 4 lines
+//	}
+func Preview(input string, a, b *Config) (*LayoutPreview, error) {
+	before, beforeRegions, err := previewRendering(input, a)
+	if err != nil {
+		return nil, WrapFormatError("failed to format with the first configuration", err)
+	}
+	after, afterRegions, err := previewRendering(input, b)
+	if err != nil {
+		return nil, WrapFormatError("failed to format with the second configuration", err)
+	}
+
+	preview := &LayoutPreview{
+		Before: before,
+		After:  after,
+		Diff:   unifiedDiff("before", "after", before+"\n", after+"\n"),
+	}
+	if beforeRegions == nil || afterRegions == nil {
+		return preview, nil
+	}
+	preview.Changes = []LayoutChange{}
+	for _, region := range afterRegions {
+		if region.Kind != AnnotationValue || !opensContainer(after, region) {
+			continue
+		}
+		previous := findValueAnnotation(beforeRegions, region.Path)
+		if previous == nil {
+			continue
+		}
+		beforeLines := strings.Count(before[previous.Start:previous.End], "\n") + 1
+		afterLines := strings.Count(after[region.Start:region.End], "\n") + 1
+		if (beforeLines == 1) != (afterLines == 1) {
+			preview.Changes = append(preview.Changes, LayoutChange{Path: region.Path, BeforeLines: beforeLines, AfterLines: afterLines})
+		}
+	}
+	return preview, nil
+}
+
+// previewRendering formats the input for Preview, with the regions of its
+// values unless the configuration rewrites output lines
+func previewRendering(input string, config *Config) (string, []Annotation, error) {
+	formatter := NewFormatter(config)
+	if formatter.config.rewritesLines() {
+		rendering, err := formatter.Format(input)
+		return rendering, nil, err
+	}
+	return formatter.FormatWithAnnotations(input)
+}
+
+// opensContainer reports whether the region holds an object or array
+func opensContainer(output string, region Annotation) bool {
+	return region.End > region.Start && (output[region.Start] == '{' || output[region.Start] == '[')
+}
@@ -0,0 +1,107 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	input := `{"name":"web","users":[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}],"tags":["a","b"]}`
+	preview, err := Preview(input, NewConfig(WithCompactDepth(2)), NewConfig(WithCompactDepth(3)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedBefore := `{
+  "name": "web",
+  "users": [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}],
+  "tags": ["a", "b"]
+}`
+	expectedAfter := `{
+  "name": "web",
+  "users": [
+    {"id": 1, "name": "Alice"},
+    {"id": 2, "name": "Bob"}
+  ],
+  "tags": [
+    "a",
+    "b"
+  ]
+}`
+	expectedDiff := `--- before
++++ after
+@@ -1,5 +1,11 @@
+ {
+   "name": "web",
++  "users": [
++    {"id": 1, "name": "Alice"},
++    {"id": 2, "name": "Bob"}
+-  "users": [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}],
+-  "tags": ["a", "b"]
++  ],
++  "tags": [
++    "a",
++    "b"
++  ]
+ }
+`
+	if preview.Before != expectedBefore {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expectedBefore, preview.Before)
+	}
+	if preview.After != expectedAfter {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expectedAfter, preview.After)
+	}
+	if preview.Diff != expectedDiff {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expectedDiff, preview.Diff)
+	}
+
+	var changes []string
+	for _, change := range preview.Changes {
+		changes = append(changes, change.String())
+	}
+	expectedChanges := []string{"users: 1 line → 4 lines", "tags: 1 line → 4 lines"}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("Expected changes %v, got %v", expectedChanges, changes)
+	}
+}
+
+func TestPreviewUnchanged(t *testing.T) {
+	preview, err := Preview(`{"a":[1]}`, nil, DefaultConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if preview.Diff != "" || len(preview.Changes) != 0 || preview.Changes == nil {
+		t.Errorf("Expected no differences, got diff %q and changes %v", preview.Diff, preview.Changes)
+	}
+}
+
+func TestPreviewPostProcess(t *testing.T) {
+	upper := NewConfig(WithPostProcess(func(line string, depth int, path string) string { return strings.ToUpper(line) }))
+	preview, err := Preview(`{"a":"b"}`, nil, upper)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if preview.Changes != nil {
+		t.Errorf("Expected no layout changes, got %v", preview.Changes)
+	}
+	if !strings.Contains(preview.Diff, `+  "A": "B"`) {
+		t.Errorf("Expected the post-processed line in the diff, got:\n%s", preview.Diff)
+	}
+}
+
+func TestPreviewErrors(t *testing.T) {
+	if _, err := Preview(`{"a":`, nil, nil); err == nil || !strings.Contains(err.Error(), "first configuration") {
+		t.Errorf("Expected error for the first configuration, got %v", err)
+	}
+	if _, err := Preview(`{"a":NaN}`, NewConfig(WithSpecialFloats(SpecialFloatsAsNull)), nil); err == nil || !strings.Contains(err.Error(), "second configuration") {
+		t.Errorf("Expected error for the second configuration, got %v", err)
+	}
+}
+
+func TestLayoutChangeString(t *testing.T) {
+	change := LayoutChange{BeforeLines: 3, AfterLines: 1}
+	if s := change.String(); s != "(root): 3 lines → 1 line" {
+		t.Errorf("Unexpected string %q", s)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatLikePrevious formats a JSON string like Format, but keeps the layout
+// of a previous version of the document where the configuration leaves a
+// choice, to minimize the diff noise in version control when a document
+// changes slightly. A container found at the same path in previousFormatted
+// is written on one line if it was written on one line before, and expanded
+// if it was expanded before, instead of by the compact depth. The width
+// limit still applies, so a container that no longer fits is expanded, and
+// containers at paths set with WithCompactPaths or WithExpandedPaths follow
+// those options.
+//
+// Containers are matched by the keys and indices leading to them in both
+// versions, so paths changed by key renames are not matched. If
+// previousFormatted is not JSON, for example display-only output, the input
+// is formatted as Format does. The cache set with SetCache is not used.
+//
+// Example:
+//
+//	previous, _ := os.ReadFile("config.json")
+//	formatted, err := formatter.FormatLikePrevious(edited, string(previous))
+func (f *Formatter) FormatLikePrevious(jsonStr, previousFormatted string) (result string, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+		}
+	}()
+
+	previous := previousLayouts(previousFormatted)
+	result, _, err = f.formatString(jsonStr, func(p *TokenParser) { p.previous = previous })
+	return result, err
+}
+
+// layoutKey returns the key of a path in a map of layouts
+func layoutKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// previousContainer is an object or array being read by formattedContainers
+type previousContainer struct {
+	start     int    // Offset after the opening bracket
+	array     bool   // Whether the container is an array
+	key       string // Most recent key of an object
+	index     int    // Index of the most recent element of an array
+	expectKey bool   // Whether the next string in an object is a key
+	values    int    // Number of values read
+}
+
+// formattedContainer is a non-empty object or array of a formatted document
+type formattedContainer struct {
+	path  []string
+	start int // Offset after the opening bracket
+	lines int // Number of lines the container spans
+}
+
+// previousLayouts reads a formatted document and returns whether each of its
+// non-empty containers, by path, was written on one line, or nil if the
+// document is not JSON
+func previousLayouts(formatted string) map[string]bool {
+	containers, ok := formattedContainers(formatted)
+	if !ok {
+		return nil
+	}
+	layouts := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		layouts[layoutKey(c.path)] = c.lines == 1
+	}
+	return layouts
+}
+
+// formattedContainers reads a formatted document and returns its non-empty
+// containers in document order, and false if the document is not JSON
+func formattedContainers(formatted string) ([]formattedContainer, bool) {
+	var containers []formattedContainer
+	decoder := json.NewDecoder(strings.NewReader(formatted))
+	var open []*previousContainer
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && len(open) == 0 {
+			sort.Slice(containers, func(i, j int) bool { return containers[i].start < containers[j].start })
+			return containers, true
+		}
+		if err != nil {
+			return nil, false
+		}
+		var top *previousContainer
+		if len(open) > 0 {
+			top = open[len(open)-1]
+		}
+
+		if token == json.Delim('}') || token == json.Delim(']') {
+			if top.values > 0 {
+				end := int(decoder.InputOffset())
+				lines := strings.Count(formatted[top.start:end], "\n") + 1
+				containers = append(containers, formattedContainer{path: containerPath(open[:len(open)-1]), start: top.start, lines: lines})
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+		if top != nil {
+			if !top.array && top.expectKey {
+				top.key, _ = token.(string)
+				top.expectKey = false
+				continue
+			}
+			top.values++
+			top.index++
+			top.expectKey = !top.array
+		}
+		if token == json.Delim('{') || token == json.Delim('[') {
+			array := token == json.Delim('[')
+			open = append(open, &previousContainer{start: int(decoder.InputOffset()), array: array, index: -1, expectKey: !array})
+		}
+	}
+}
+
+// containerPath returns the path of the value being read in the innermost
+// of the containers
+func containerPath(open []*previousContainer) []string {
+	path := make([]string, len(open))
+	for i, c := range open {
+		if c.array {
+			path[i] = strconv.Itoa(c.index)
+		} else {
+			path[i] = c.key
+		}
+	}
+	return path
+}
+
+// previousLayout reports whether a container opened at the current position
+// was written on one line in the previous version of the document, and
+// whether the previous version has a container there at all
+func (p *TokenParser) previousLayout() (compact bool, found bool) {
+	if p.previous == nil {
+		return false, false
+	}
+	path := p.valuePath()
+	if len(path) != p.depth {
+		return false, false
+	}
+	compact, found = p.previous[layoutKey(path)]
+	if compact && p.depth+1 < p.minCompactDepth {
+		// An element that overflowed is replayed with compact formatting pushed deeper
+		return false, false
+	}
+	return compact, found
+}
@@ -0,0 +1,78 @@
+package jsonformat
+
+import "testing"
+
+func TestFormatLikePrevious(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		input    string
+		previous string
+		expected string
+	}{
+		{
+			name:     "keeps expanded object that now fits",
+			options:  []ConfigOption{WithCompactDepth(2), WithMaxWidth(30)},
+			input:    `{"server":{"host":"a","port":80}}`,
+			previous: "{\n  \"server\": {\n    \"host\": \"example.com\",\n    \"port\": 80\n  }\n}",
+			expected: "{\n  \"server\": {\n    \"host\": \"a\",\n    \"port\": 80\n  }\n}",
+		},
+		{
+			name:     "keeps compact object above the compact depth",
+			options:  []ConfigOption{WithCompactDepth(3)},
+			input:    `{"server":{"host":"a","port":81},"tags":["x"]}`,
+			previous: "{\n  \"server\": {\"host\": \"a\", \"port\": 80},\n  \"tags\": [\n    \"x\"\n  ]\n}",
+			expected: "{\n  \"server\": {\"host\": \"a\", \"port\": 81},\n  \"tags\": [\n    \"x\"\n  ]\n}",
+		},
+		{
+			name:     "expands compact object that no longer fits",
+			options:  []ConfigOption{WithCompactDepth(3), WithMaxWidth(30)},
+			input:    `{"server":{"host":"example.com","port":80}}`,
+			previous: "{\n  \"server\": {\"host\": \"a\", \"port\": 80}\n}",
+			expected: "{\n  \"server\": {\n    \"host\": \"example.com\",\n    \"port\": 80\n  }\n}",
+		},
+		{
+			name:     "new containers follow the configuration",
+			options:  []ConfigOption{WithCompactDepth(2)},
+			input:    `{"a":{"b":1},"c":{"d":2}}`,
+			previous: "{\n  \"a\": {\n    \"b\": 1\n  }\n}",
+			expected: "{\n  \"a\": {\n    \"b\": 1\n  },\n  \"c\": {\"d\": 2}\n}",
+		},
+		{
+			name:     "previous output is not JSON",
+			options:  []ConfigOption{WithCompactDepth(2)},
+			input:    `{"a":{"b":1}}`,
+			previous: "// header\n{\n  \"a\": {\n    \"b\": 1\n  }\n}",
+			expected: "{\n  \"a\": {\"b\": 1}\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).FormatLikePrevious(tt.input, tt.previous)
+			if err != nil {
+				t.Fatalf("FormatLikePrevious failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("FormatLikePrevious() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPreviousLayouts(t *testing.T) {
+	layouts := previousLayouts("{\n  \"a\": [\n    {\"b\": []},\n    {\n      \"c\": 1\n    }\n  ]\n}")
+	expected := map[string]bool{
+		"":                            false,
+		"a":                           false,
+		layoutKey([]string{"a", "0"}): true,
+		layoutKey([]string{"a", "1"}): false,
+	}
+	if len(layouts) != len(expected) {
+		t.Fatalf("previousLayouts() = %v, want %v", layouts, expected)
+	}
+	for key, compact := range expected {
+		if got, ok := layouts[key]; !ok || got != compact {
+			t.Errorf("layout of %q = %v, %v, want %v", key, got, ok, compact)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// Profile is a named set of options for a kind of document, such as the
+// output of a particular tool, that other packages can provide. A profile
+// is registered with RegisterProfile, usually from the init function of its
+// package, and is then available through Preset and DetectProfile like the
+// built-in presets. Value hooks and post-processors are options too, so a
+// profile can bring its own through Options.
+//
+// Example:
+//
+//	package k8sprofile
+//
+//	func init() {
+//	    jsonformat.RegisterProfile(jsonformat.NewProfile("k8s",
+//	        func(info jsonformat.DocumentInfo) bool { return info.HasKey("apiVersion") && info.HasKey("kind") },
+//	        jsonformat.WithKeyOrder("", "apiVersion", "kind", "metadata", "*"),
+//	    ))
+//	}
+type Profile interface {
+	// Name returns the name under which the profile is registered.
+	Name() string
+
+	// Matches reports whether the profile suits the document.
+	Matches(info DocumentInfo) bool
+
+	// Options returns the options that make up the profile.
+	Options() []ConfigOption
+}
+
+// DocumentInfo describes a document to Profile.Matches.
+type DocumentInfo struct {
+	// Filename is the name of the document, or empty if it has none.
+	Filename string
+
+	// Keys are the keys of the top-level object in input order, or nil if
+	// the document is not an object.
+	Keys []string
+}
+
+// NewDocumentInfo describes the document named filename with the given content.
+func NewDocumentInfo(filename string, data []byte) DocumentInfo {
+	info := DocumentInfo{Filename: filename}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return info
+	}
+	keys := []string{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		keys = append(keys, token.(string))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+	}
+	info.Keys = keys
+	return info
+}
+
+// HasKey reports whether the top-level object has the key.
+func (i DocumentInfo) HasKey(key string) bool {
+	for _, k := range i.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// funcProfile is a Profile made of a name, a match function, and options
+type funcProfile struct {
+	name    string
+	matches func(DocumentInfo) bool
+	options []ConfigOption
+}
+
+func (p *funcProfile) Name() string                   { return p.name }
+func (p *funcProfile) Matches(info DocumentInfo) bool { return p.matches != nil && p.matches(info) }
+func (p *funcProfile) Options() []ConfigOption        { return p.options }
+
+// NewProfile returns a Profile with the name and options, which matches the
+// documents for which the function returns true. A nil function matches no
+// document, so the profile is only used when selected by name.
+func NewProfile(name string, matches func(DocumentInfo) bool, options ...ConfigOption) Profile {
+	return &funcProfile{name: name, matches: matches, options: append([]ConfigOption(nil), options...)}
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]Profile{}
+)
+
+// RegisterProfile makes a profile available by its name. It panics if the
+// profile is nil, or if its name is empty or already used by a preset or
+// another profile.
+func RegisterProfile(profile Profile) {
+	if profile == nil {
+		panic("jsonformat: RegisterProfile profile is nil")
+	}
+	name := profile.Name()
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	if name == "" {
+		panic("jsonformat: RegisterProfile profile name is empty")
+	}
+	if _, ok := presets[name]; ok {
+		panic("jsonformat: RegisterProfile called for the preset " + name)
+	}
+	if _, ok := profiles[name]; ok {
+		panic("jsonformat: RegisterProfile called twice for profile " + name)
+	}
+	profiles[name] = profile
+}
+
+// Profiles returns the registered profiles sorted by name.
+func Profiles() []Profile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	list := make([]Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// DetectProfile returns the first registered profile, in name order, that
+// matches the document, and false if none does.
+func DetectProfile(info DocumentInfo) (Profile, bool) {
+	for _, profile := range Profiles() {
+		if profile.Matches(info) {
+			return profile, true
+		}
+	}
+	return nil, false
+}
+
+// lookupProfile returns the registered profile with the name
+func lookupProfile(name string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	profile, ok := profiles[name]
+	return profile, ok
+}
@@ -0,0 +1,133 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// registerTestProfile registers a profile for the duration of the test
+func registerTestProfile(t *testing.T, profile Profile) {
+	t.Helper()
+	RegisterProfile(profile)
+	t.Cleanup(func() {
+		profilesMu.Lock()
+		delete(profiles, profile.Name())
+		profilesMu.Unlock()
+	})
+}
+
+func TestRegisterProfile(t *testing.T) {
+	k8s := NewProfile("k8s", func(info DocumentInfo) bool { return info.HasKey("apiVersion") && info.HasKey("kind") },
+		WithKeyOrder("", "apiVersion", "kind", "*"), WithCompactDepth(1))
+	manual := NewProfile("manual", nil, WithTabs())
+	registerTestProfile(t, k8s)
+	registerTestProfile(t, manual)
+
+	config, ok := Preset("k8s", WithIndentSize(4))
+	if !ok {
+		t.Fatal("Expected registered profile to be available as a preset")
+	}
+	result, err := NewFormatter(config).Format(`{"spec":{},"kind":"Pod","apiVersion":"v1"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"apiVersion": "v1", "kind": "Pod", "spec": {}}`; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+	if config.IndentSize != 4 {
+		t.Errorf("Expected options to be applied on top of the profile, got %+v", config)
+	}
+
+	names := PresetNames()
+	if !strings.Contains(strings.Join(names, ","), ",k8s,manual,") {
+		t.Errorf("Expected profiles among the preset names, got %v", names)
+	}
+	if profiles := Profiles(); len(profiles) != 2 || profiles[0] != k8s || profiles[1] != manual {
+		t.Errorf("Expected profiles sorted by name, got %v", profiles)
+	}
+}
+
+func TestRegisterProfilePanics(t *testing.T) {
+	registerTestProfile(t, NewProfile("taken", nil))
+
+	tests := []struct {
+		name    string
+		profile Profile
+		message string
+	}{
+		{"nil", nil, "profile is nil"},
+		{"empty name", NewProfile("", nil), "name is empty"},
+		{"preset name", NewProfile("wide", nil), "preset wide"},
+		{"duplicate", NewProfile("taken", nil), "twice for profile taken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("Expected a panic")
+				}
+				if message, _ := r.(string); !strings.Contains(message, tt.message) {
+					t.Errorf("Expected panic containing %q, got %v", tt.message, r)
+				}
+			}()
+			RegisterProfile(tt.profile)
+		})
+	}
+}
+
+func TestDetectProfile(t *testing.T) {
+	registerTestProfile(t, NewProfile("b-otlp", func(info DocumentInfo) bool { return info.HasKey("resourceSpans") }))
+	registerTestProfile(t, NewProfile("a-any", func(info DocumentInfo) bool { return strings.HasSuffix(info.Filename, ".trace.json") }))
+	registerTestProfile(t, NewProfile("manual", nil))
+
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		expected string
+	}{
+		{"by keys", "", `{"resourceSpans":[]}`, "b-otlp"},
+		{"first in name order", "x.trace.json", `{"resourceSpans":[]}`, "a-any"},
+		{"no match", "", `{"spans":[]}`, ""},
+		{"not an object", "", `[{"resourceSpans":[]}]`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, ok := DetectProfile(NewDocumentInfo(tt.filename, []byte(tt.input)))
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no profile, got %s", profile.Name())
+				}
+				return
+			}
+			if !ok || profile.Name() != tt.expected {
+				t.Errorf("Expected profile %s, got %v", tt.expected, profile)
+			}
+		})
+	}
+}
+
+func TestNewDocumentInfo(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`{"b":{"x":1},"a":[1,2],"c":null}`, []string{"b", "a", "c"}},
+		{`{}`, []string{}},
+		{`[1]`, nil},
+		{`{"a":1,"b":`, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			info := NewDocumentInfo("doc.json", []byte(tt.input))
+			if info.Filename != "doc.json" || !reflect.DeepEqual(info.Keys, tt.expected) {
+				t.Errorf("Expected keys %#v, got %#v", tt.expected, info)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// WithProvenance annotates each scalar value with the name of the document
+// that supplied it, so the output of a document merged from several sources,
+// such as a base configuration and environment overlays, answers why a value
+// is what it is. Sources maps dot-separated value paths, with array indices
+// in decimal as in ValueContext.Path, to source names. Values without an
+// entry take the source of their nearest ancestor, and the empty path names
+// the source of the whole document. The comments make the output invalid
+// JSON, so use this option for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithProvenance(map[string]string{
+//	    "":            "base.json",
+//	    "server.port": "production.json",
+//	}))
+//	// {
+//	//   "server": {
+//	//     "host": "example.com" /* from: base.json */,
+//	//     "port": 443 /* from: production.json */
+//	//   }
+//	// }
+func WithProvenance(sources map[string]string) ConfigOption {
+	return func(c *Config) {
+		if len(sources) == 0 {
+			return
+		}
+		if c.Provenance == nil {
+			c.Provenance = make(map[string]string, len(sources))
+		}
+		for path, source := range sources {
+			c.Provenance[path] = source
+		}
+	}
+}
+
+// provenanceReplacer keeps source names from ending the comment or the line
+var provenanceReplacer = strings.NewReplacer("*/", "* /", "\n", `\n`, "\r", `\r`)
+
+// annotateProvenance appends the source of a scalar value as a comment
+func (p *TokenParser) annotateProvenance(token json.Token) (json.Token, error) {
+	if len(p.config.Provenance) == 0 || p.config.StrictJSON || !p.isScalarValue(token) {
+		return token, nil
+	}
+	source, ok := p.provenance(p.valuePath())
+	if !ok {
+		return token, nil
+	}
+
+	value, isRaw := token.(RawValue)
+	if !isRaw {
+		compact, err := p.compactTokens([]json.Token{token})
+		if err != nil {
+			return nil, err
+		}
+		value = RawValue(compact)
+	}
+	return value + RawValue(" /* from: "+provenanceReplacer.Replace(source)+" */"), nil
+}
+
+// provenance returns the source of the value at the path, or of its nearest
+// ancestor with a source
+func (p *TokenParser) provenance(path []string) (string, bool) {
+	for i := len(path); i >= 0; i-- {
+		if source, ok := p.config.Provenance[strings.Join(path[:i], ".")]; ok {
+			return source, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,94 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	input := `{"server":{"host":"example.com","port":443},"tags":["a","b"],"debug":false}`
+	sources := map[string]string{
+		"":            "base.json",
+		"server.port": "production.json",
+		"tags":        "env.json",
+		"tags.1":      "local*/.json",
+	}
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "annotated",
+			options: []ConfigOption{WithProvenance(sources)},
+			expected: `{
+  "server": {
+    "host": "example.com" /* from: base.json */,
+    "port": 443 /* from: production.json */
+  },
+  "tags": [
+    "a" /* from: env.json */,
+    "b" /* from: local* /.json */
+  ],
+  "debug": false /* from: base.json */
+}`,
+		},
+		{
+			name:     "compact",
+			options:  []ConfigOption{WithProvenance(sources), WithCompactDepth(1)},
+			expected: `{"server": {"host": "example.com" /* from: base.json */, "port": 443 /* from: production.json */}, "tags": ["a" /* from: env.json */, "b" /* from: local* /.json */], "debug": false /* from: base.json */}`,
+		},
+		{
+			name:    "unknown sources",
+			options: []ConfigOption{WithProvenance(map[string]string{"server": "base.json"}), WithCompactDepth(2)},
+			expected: `{
+  "server": {"host": "example.com" /* from: base.json */, "port": 443 /* from: base.json */},
+  "tags": ["a", "b"],
+  "debug": false
+}`,
+		},
+		{
+			name: "after value hooks",
+			options: []ConfigOption{WithProvenance(sources), WithCompactDepth(2), WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+				return NumberPlaceholder, ctx.Key == "port"
+			})},
+			expected: `{
+  "server": {"host": "example.com" /* from: base.json */, "port": <number> /* from: production.json */},
+  "tags": ["a" /* from: env.json */, "b" /* from: local* /.json */],
+  "debug": false /* from: base.json */
+}`,
+		},
+		{
+			name:    "strict JSON",
+			options: []ConfigOption{WithProvenance(sources), WithCompactDepth(2), WithStrictJSON()},
+			expected: `{
+  "server": {"host": "example.com", "port": 443},
+  "tags": ["a", "b"],
+  "debug": false
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWithProvenanceMerges(t *testing.T) {
+	config := NewConfig(
+		WithProvenance(map[string]string{"a": "base.json", "b": "base.json"}),
+		WithProvenance(map[string]string{"b": "override.json"}),
+		WithProvenance(nil),
+	)
+	if len(config.Provenance) != 2 || config.Provenance["a"] != "base.json" || config.Provenance["b"] != "override.json" {
+		t.Errorf("Unexpected provenance: %v", config.Provenance)
+	}
+}
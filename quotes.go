@@ -0,0 +1,112 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// QuoteStyle sets how keys and strings are quoted in display output.
+type QuoteStyle struct {
+	// BareKeys writes keys that are identifiers, such as name or user_id,
+	// without quotes. Other keys keep their quotes.
+	BareKeys bool
+
+	// Open and Close are written around string values instead of double
+	// quotes, if set. Double quotes inside the string are then not escaped.
+	Open, Close string
+}
+
+// PresentationQuotes writes identifier keys without quotes and strings in
+// typographic quotes, for slides and documentation.
+var PresentationQuotes = QuoteStyle{BareKeys: true, Open: "“", Close: "”"}
+
+// WithQuoteStyle renders keys and strings with the quote style, for
+// presentation rather than processing: the output is no longer valid JSON.
+// Strict JSON mode ignores the style.
+//
+// Example:
+//
+//	config := NewConfig(WithQuoteStyle(PresentationQuotes))
+//	// {"name": "Alice", "first name": "A"}
+//	// →
+//	// {name: “Alice”, "first name": “A”}
+func WithQuoteStyle(style QuoteStyle) ConfigOption {
+	return func(c *Config) {
+		c.QuoteStyle = &style
+	}
+}
+
+// quoteStyle returns the quote style in effect, or nil for JSON quotes
+func (p *TokenParser) quoteStyle() *QuoteStyle {
+	if p.config.StrictJSON {
+		return nil
+	}
+	return p.config.QuoteStyle
+}
+
+// quoteKey returns an escaped key with its quotes
+func (p *TokenParser) quoteKey(key, escaped string) string {
+	if style := p.quoteStyle(); style != nil && style.BareKeys && isIdentifier(key) {
+		return key
+	}
+	return `"` + escaped + `"`
+}
+
+// quoteString returns an escaped string value with its quotes
+func (p *TokenParser) quoteString(escaped string) string {
+	style := p.quoteStyle()
+	if style == nil || (style.Open == "" && style.Close == "") {
+		return `"` + escaped + `"`
+	}
+	return style.Open + unescapeQuotes(escaped) + style.Close
+}
+
+// isIdentifier reports whether the key can be written without quotes, as in
+// JavaScript object literals
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// unescapeQuotes replaces the escaped double quotes of an escaped string with
+// double quotes, keeping other escape sequences
+func unescapeQuotes(escaped string) string {
+	if !strings.Contains(escaped, `\"`) {
+		return escaped
+	}
+	var builder strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '\\' || i+1 >= len(escaped) {
+			builder.WriteByte(escaped[i])
+			continue
+		}
+		i++
+		if escaped[i] != '"' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(escaped[i])
+	}
+	return builder.String()
+}
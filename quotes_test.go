@@ -0,0 +1,70 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestQuoteStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "presentation quotes",
+			input:   `{"name":"Alice","first name":"A","tags":["x"],"n":1}`,
+			options: []ConfigOption{WithQuoteStyle(PresentationQuotes)},
+			expected: `{
+  name: “Alice”,
+  "first name": “A”,
+  tags: [
+    “x”
+  ],
+  n: 1
+}`,
+		},
+		{
+			name:     "compact object",
+			input:    `{"a":{"b":"c","1x":"d"}}`,
+			options:  []ConfigOption{WithQuoteStyle(PresentationQuotes), WithCompactDepth(1)},
+			expected: `{a: {b: “c”, "1x": “d”}}`,
+		},
+		{
+			name:     "unescaped double quotes",
+			input:    `{"q":"say \"hi\"\\\" \n"}`,
+			options:  []ConfigOption{WithQuoteStyle(PresentationQuotes), WithCompactDepth(1)},
+			expected: `{q: “say "hi"\\" \n”}`,
+		},
+		{
+			name:     "bare keys only",
+			input:    `{"user_id":"7","$ref":"#"}`,
+			options:  []ConfigOption{WithQuoteStyle(QuoteStyle{BareKeys: true}), WithCompactDepth(1)},
+			expected: `{user_id: "7", $ref: "#"}`,
+		},
+		{
+			name:     "custom quotes",
+			input:    `["a","b"]`,
+			options:  []ConfigOption{WithQuoteStyle(QuoteStyle{Open: "«", Close: "»"}), WithCompactDepth(1)},
+			expected: `[«a», «b»]`,
+		},
+		{
+			name:     "strict JSON",
+			input:    `{"name":"Alice"}`,
+			options:  []ConfigOption{WithQuoteStyle(PresentationQuotes), WithStrictJSON(), WithCompactDepth(1)},
+			expected: `{"name": "Alice"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
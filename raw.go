@@ -0,0 +1,391 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithRawPaths copies the values at paths matching the patterns from the
+// input verbatim, byte for byte, while the rest of the document is
+// formatted. This keeps subtrees that are signed or hashed intact, such as
+// the payload of a JWS or a document whose digest is checked downstream.
+// Patterns are dot-separated paths as in ValueContext.MatchPath, with "" for
+// the root. Value hooks and other options that change values are not applied
+// to raw values.
+//
+// Example:
+//
+//	config := NewConfig(WithRawPaths("payload"))
+//	// {"payload":{"b":1,  "a":2},"signature":"..."}
+//	// →
+//	// {
+//	//   "payload": {"b":1,  "a":2},
+//	//   "signature": "..."
+//	// }
+func WithRawPaths(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.RawPaths = append(c.RawPaths, patterns...)
+	}
+}
+
+// rawTracker follows the position of the tokens read from the decoder, which
+// runs ahead of the parser state while tokens are buffered, to find the
+// values at raw paths
+type rawTracker struct {
+	levels []rawLevel
+}
+
+// rawLevel is the position within one open container
+type rawLevel struct {
+	array bool
+	key   string // Most recent key when the container is an object
+	index int    // Number of values read when the container is an array
+	value bool   // Whether the next string in an object is a value
+}
+
+// nextPath returns the path of the value that the next token starts
+func (t *rawTracker) nextPath() []string {
+	path := make([]string, len(t.levels))
+	for i, level := range t.levels {
+		if level.array {
+			path[i] = strconv.Itoa(level.index)
+		} else {
+			path[i] = level.key
+		}
+	}
+	return path
+}
+
+// startsValue reports whether the token starts a value, rather than being
+// an object key or closing a container
+func (t *rawTracker) startsValue(token json.Token) bool {
+	switch token {
+	case json.Delim('}'), json.Delim(']'):
+		return false
+	}
+	if len(t.levels) == 0 {
+		return true
+	}
+	level := t.levels[len(t.levels)-1]
+	return level.array || level.value
+}
+
+// advance updates the position after a token. Values copied verbatim are
+// passed as a RawValue.
+func (t *rawTracker) advance(token json.Token) {
+	var level *rawLevel
+	if len(t.levels) > 0 {
+		level = &t.levels[len(t.levels)-1]
+	}
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		t.levels = append(t.levels, rawLevel{array: token == json.Delim('[')})
+		return
+	case json.Delim('}'), json.Delim(']'):
+		t.levels = t.levels[:len(t.levels)-1]
+		if len(t.levels) > 0 {
+			t.valueDone(&t.levels[len(t.levels)-1])
+		}
+		return
+	}
+	if level == nil {
+		return
+	}
+	if key, ok := token.(string); ok && !level.array && !level.value {
+		level.key = key
+		level.value = true
+		return
+	}
+	t.valueDone(level)
+}
+
+// valueDone moves past a complete value in the container
+func (t *rawTracker) valueDone(level *rawLevel) {
+	if level.array {
+		level.index++
+	} else {
+		level.value = false
+	}
+}
+
+// readRaw replaces a value at a raw path, which the token starts, by its
+// input text. Start is the input offset before the token was read.
+func (p *TokenParser) readRaw(token json.Token, start int64) (json.Token, error) {
+	if p.raw == nil {
+		p.raw = &rawTracker{}
+	}
+	if !p.raw.startsValue(token) || !anyRootedMatch(p.pathRules().raw, p.raw.nextPath()) {
+		p.raw.advance(token)
+		return token, nil
+	}
+
+	// Read the rest of a container
+	if token == json.Delim('{') || token == json.Delim('[') {
+		for depth := 1; depth > 0; {
+			next, err := p.decoder.Token()
+			if err != nil {
+				return nil, WrapFormatErrorWithPosition("invalid JSON input", int(p.decoder.InputOffset()), err)
+			}
+			switch next {
+			case json.Delim('{'), json.Delim('['):
+				depth++
+			case json.Delim('}'), json.Delim(']'):
+				depth--
+			}
+		}
+	}
+	end := p.decoder.InputOffset()
+	// The text before the value holds whitespace and the preceding colon or comma
+	text := strings.TrimLeft(p.source[start:end], " \t\r\n:,")
+	p.raw.advance(RawValue(text))
+	return RawValue(text), nil
+}
+
+// matchAnyRootedPath reports whether path matches at least one of the
+// patterns, with "" matching the root
+func matchAnyRootedPath(patterns []string, path []string) bool {
+	for _, pattern := range patterns {
+		if matchRootedPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyRawPaths checks that output is a faithful formatting of input whose
+// values at paths matching the patterns were copied verbatim, as with
+// WithRawPaths. Those values must be byte-identical in both documents, which
+// keeps detached or embedded signatures over them valid, and the rest of the
+// documents must be equal as JSON values, with object members in any order
+// and numbers compared by value. Both documents must be valid JSON. Input
+// with a byte order mark is converted to UTF-8 first, as Format does.
+//
+// It returns nil when the output passes, or an error whose Path is the first
+// value that differs.
+//
+// Example:
+//
+//	formatted, err := formatter.Format(input) // with WithRawPaths("payload")
+//	if err == nil {
+//	    err = VerifyRawPaths(input, formatted, "payload")
+//	}
+func VerifyRawPaths(input, output string, patterns ...string) error {
+	config := DefaultConfig()
+	input, err := config.decodeInput(input)
+	if err != nil {
+		return err
+	}
+	if output, err = config.decodeInput(output); err != nil {
+		return err
+	}
+
+	before, err := decodeRawDocument(input, patterns)
+	if err != nil {
+		return WrapFormatError("invalid JSON input", err)
+	}
+	after, err := decodeRawDocument(output, patterns)
+	if err != nil {
+		return WrapFormatError("invalid JSON output", err)
+	}
+
+	for _, raw := range before.raws {
+		if text, ok := after.texts[strings.Join(raw.path, ".")]; !ok || text != raw.text {
+			return pathError("output changes the raw value at", raw.path)
+		}
+	}
+	if path, ok := firstDifference(before.value, after.value, nil); ok {
+		return pathError("output changes the value at", path)
+	}
+	return nil
+}
+
+// rawDocument is a decoded document with the text of its values at raw paths
+type rawDocument struct {
+	value interface{}
+	raws  []rawText         // Values at raw paths, in document order
+	texts map[string]string // Text of the values at raw paths by dotted path
+}
+
+// rawText is the input text of a value at a raw path
+type rawText struct {
+	path []string
+	text string
+}
+
+// rawDecoder decodes a document, keeping the text of values at raw paths
+type rawDecoder struct {
+	decoder  *json.Decoder
+	source   string
+	patterns []string
+	document *rawDocument
+}
+
+// decodeRawDocument decodes a single JSON value. Values at raw paths are
+// decoded as a RawValue holding their text.
+func decodeRawDocument(source string, patterns []string) (*rawDocument, error) {
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+	d := &rawDecoder{
+		decoder:  decoder,
+		source:   source,
+		patterns: patterns,
+		document: &rawDocument{texts: make(map[string]string)},
+	}
+	value, err := d.value(nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, NewFormatErrorWithPosition("unexpected data after top-level value", int(decoder.InputOffset()))
+	}
+	d.document.value = value
+	return d.document, nil
+}
+
+// value decodes the value at path
+func (d *rawDecoder) value(path []string) (interface{}, error) {
+	start := d.decoder.InputOffset()
+	token, err := d.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{} = token
+	switch token {
+	case json.Delim('{'):
+		object := make(map[string]interface{})
+		for d.decoder.More() {
+			key, err := d.decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			member, err := d.value(append(path[:len(path):len(path)], key.(string)))
+			if err != nil {
+				return nil, err
+			}
+			object[key.(string)] = member
+		}
+		value = object
+	case json.Delim('['):
+		var array []interface{}
+		for d.decoder.More() {
+			element, err := d.value(append(path[:len(path):len(path)], strconv.Itoa(len(array))))
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, element)
+		}
+		value = array
+	}
+	if token == json.Delim('{') || token == json.Delim('[') {
+		if _, err := d.decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !matchAnyRootedPath(d.patterns, path) {
+		return value, nil
+	}
+	// The text before the value holds whitespace and the preceding colon or comma
+	text := strings.TrimLeft(d.source[start:d.decoder.InputOffset()], " \t\r\n:,")
+	d.document.raws = append(d.document.raws, rawText{path: path, text: text})
+	d.document.texts[strings.Join(path, ".")] = text
+	return RawValue(text), nil
+}
+
+// firstDifference returns the path of the first value that differs between
+// two decoded documents, visiting object members in key order. Raw values
+// are compared by their text separately.
+func firstDifference(a, b interface{}, path []string) ([]string, bool) {
+	switch a := a.(type) {
+	case RawValue:
+		if _, ok := b.(RawValue); ok {
+			return nil, false
+		}
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, inA := a[key]
+			_, inB := b[key]
+			if inA != inB {
+				return append(path[:len(path):len(path)], key), true
+			}
+			if diff, ok := firstDifference(a[key], b[key], append(path[:len(path):len(path)], key)); ok {
+				return diff, true
+			}
+		}
+		return nil, false
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(a) || i < len(b); i++ {
+			if i >= len(a) || i >= len(b) {
+				return append(path[:len(path):len(path)], strconv.Itoa(i)), true
+			}
+			if diff, ok := firstDifference(a[i], b[i], append(path[:len(path):len(path)], strconv.Itoa(i))); ok {
+				return diff, true
+			}
+		}
+		return nil, false
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			break
+		}
+		x, okA := new(big.Rat).SetString(string(a))
+		y, okB := new(big.Rat).SetString(string(b))
+		if okA && okB && x.Cmp(y) == 0 {
+			return nil, false
+		}
+	default:
+		if a == b {
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// pathError returns an error for the value at path, with the message
+// followed by the dotted path
+func pathError(msg string, path []string) error {
+	name := strings.Join(path, ".")
+	if len(path) == 0 {
+		name = "(root)"
+	}
+	formatErr := NewFormatError(msg + " " + name)
+	formatErr.Path = path
+	return formatErr
+}
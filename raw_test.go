@@ -0,0 +1,176 @@
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRawPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "object",
+			input:   `{"payload":{"b":1,  "a":[2, 3]},"signature":"x"}`,
+			options: []ConfigOption{WithRawPaths("payload")},
+			expected: `{
+  "payload": {"b":1,  "a":[2, 3]},
+  "signature": "x"
+}`,
+		},
+		{
+			name:    "scalars",
+			input:   `{"n":1.0,"s":"A","other":1.0}`,
+			options: []ConfigOption{WithRawPaths("n", "s")},
+			expected: `{
+  "n": 1.0,
+  "s": "A",
+  "other": 1
+}`,
+		},
+		{
+			name:    "wildcard",
+			input:   `[{"sig":[1 , 2]},{"sig":{ }}]`,
+			options: []ConfigOption{WithRawPaths("*.sig")},
+			expected: `[
+  {
+    "sig": [1 , 2]
+  },
+  {
+    "sig": { }
+  }
+]`,
+		},
+		{
+			name:     "root",
+			input:    ` {"b" : 1,"a":2}`,
+			options:  []ConfigOption{WithRawPaths("")},
+			expected: `{"b" : 1,"a":2}`,
+		},
+		{
+			name:    "key order",
+			input:   `{"payload":{"b":1,"a":2},"id":1}`,
+			options: []ConfigOption{WithRawPaths("payload"), WithKeyOrder("", "id")},
+			expected: `{
+  "id": 1,
+  "payload": {"b":1,"a":2}
+}`,
+		},
+		{
+			name:    "hooks not applied",
+			input:   `{"payload":{"secret":"s"},"secret":"s"}`,
+			options: []ConfigOption{WithRawPaths("payload"), WithRedaction("secret")},
+			expected: `{
+  "payload": {"secret":"s"},
+  "secret": "[REDACTED]"
+}`,
+		},
+		{
+			name:    "special floats",
+			input:   `{"raw":[NaN],"other":NaN}`,
+			options: []ConfigOption{WithRawPaths("raw"), WithSpecialFloats(SpecialFloatsAsNull)},
+			expected: `{
+  "raw": [NaN],
+  "other": null
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+
+			var out bytes.Buffer
+			if err := formatter.FormatTo(&out, strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if streamed := strings.TrimSuffix(out.String(), "\n"); streamed != tt.expected {
+				t.Errorf("FormatTo Expected:\n%s\n\nGot:\n%s", tt.expected, streamed)
+			}
+		})
+	}
+}
+
+func TestVerifyRawPaths(t *testing.T) {
+	input := `{"payload":{"b":1,  "a":[2, 3]},"n":1.0,"list":[{"sig":"x"}]}`
+	tests := []struct {
+		name     string
+		output   string
+		patterns []string
+		errMsg   string
+		path     []string
+	}{
+		{"formatted", "{\n  \"list\": [{\"sig\": \"x\"}],\n  \"n\": 1,\n  \"payload\": {\"b\":1,  \"a\":[2, 3]}\n}", []string{"payload"}, "", nil},
+		{"wildcard", `{"payload":{"b":1,  "a":[2, 3]},"n":10e-1,"list":[{"sig":"x"}]}`, []string{"payload", "list.*.sig"}, "", nil},
+		{"raw reformatted", `{"payload":{"b":1,"a":[2,3]},"n":1.0,"list":[{"sig":"x"}]}`, []string{"payload"}, "output changes the raw value at payload", []string{"payload"}},
+		{"value changed", `{"payload":{"b":1,  "a":[2, 3]},"n":2,"list":[{"sig":"x"}]}`, []string{"payload"}, "output changes the value at n", []string{"n"}},
+		{"member missing", `{"payload":{"b":1,  "a":[2, 3]},"n":1}`, []string{"payload"}, "output changes the value at list", []string{"list"}},
+		{"element added", `{"payload":{"b":1,  "a":[2, 3]},"n":1,"list":[{"sig":"x"},null]}`, []string{"payload"}, "output changes the value at list.1", []string{"list", "1"}},
+		{"root", ` {"payload":{"b":1,  "a":[2, 3]},"n":1.0,"list":[{"sig":"x"}]}`, []string{""}, "", nil},
+		{"root changed", `{"payload":{"b":1,"a":[2, 3]},"n":1.0,"list":[{"sig":"x"}]}`, []string{""}, "output changes the raw value at (root)", []string{}},
+		{"invalid output", `{"payload":`, []string{"payload"}, "invalid JSON output", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyRawPaths(input, tt.output, tt.patterns...)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			var formatErr *FormatError
+			if tt.path != nil && (!errors.As(err, &formatErr) || strings.Join(formatErr.Path, ".") != strings.Join(tt.path, ".")) {
+				t.Errorf("Expected path %v, got %v", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRawPathsFormatted(t *testing.T) {
+	input := `{"sig":"abc","payload":{"z":1,"a":  [1.50, "é"]},"meta":{"b":2,"a":1}}`
+	formatter := NewFormatter(NewConfig(WithRawPaths("payload"), WithSortedKeys("")))
+	result, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := VerifyRawPaths(input, result, "payload"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestVerifyRawPathsByteOrderMark(t *testing.T) {
+	text := `{"payload":{"z":1,  "a":2},"meta":{"b":2}}`
+	formatter := NewFormatter(NewConfig(WithRawPaths("payload")))
+	for name, input := range map[string]string{
+		"UTF-8":    "\xEF\xBB\xBF" + text,
+		"UTF-16LE": encodeUTF16(text, binary.LittleEndian),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := VerifyRawPaths(input, result, "payload"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
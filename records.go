@@ -0,0 +1,458 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// WithRecordWorkers sets the number of records FormatRecords, or documents
+// FormatBatch, formats concurrently. The output keeps the order of the input however long each
+// record takes. Values below 2 format the records one at a time.
+//
+// Example:
+//
+//	config := NewConfig(WithRecordWorkers(runtime.GOMAXPROCS(0)))
+func WithRecordWorkers(workers int) ConfigOption {
+	return func(c *Config) {
+		c.RecordWorkers = max(workers, 0)
+	}
+}
+
+// Limits of the raw text quoted by RecordErrorComment
+const (
+	recordQuoteLines = 5   // Lines quoted of a record
+	recordQuoteWidth = 200 // Characters quoted of a line
+)
+
+// RecordFailure describes a record that FormatRecords could not format.
+type RecordFailure struct {
+	// Record is the number of the record, starting at 1.
+	Record int
+
+	// Line and Column give the 1-based location of the error in the input,
+	// or of the start of the record when the error has no location.
+	Line   int
+	Column int
+
+	// Text is the raw text of the record, without surrounding whitespace.
+	Text string
+
+	// Err is the FormatError naming the record.
+	Err error
+}
+
+// RecordErrorMarker renders a record that could not be formatted, for
+// FormatRecords with error isolation. The returned text takes the place of
+// the record and is followed by a line feed; an empty string leaves the
+// record out.
+type RecordErrorMarker func(failure RecordFailure) string
+
+// WithRecordErrorIsolation makes FormatRecords write a comment in place of a
+// record that cannot be formatted and go on with the next one, instead of
+// stopping at the first bad record, so one malformed line does not end a long
+// job. The comment is rendered by RecordErrorComment unless another marker is
+// set with WithRecordErrorMarker. With WithStrictJSON the bad records are left
+// out without a comment.
+//
+// Example:
+//
+//	config := NewConfig(WithRecordErrorIsolation())
+//	// {"a":1}
+//	// {"b":
+//	// →
+//	// {
+//	//   "a": 1
+//	// }
+//	// // line 2, column 6: invalid JSON text in record 2: ...
+//	// // > {"b":
+func WithRecordErrorIsolation() ConfigOption {
+	return func(c *Config) {
+		c.IsolateRecordErrors = true
+	}
+}
+
+// WithRecordErrorMarker isolates record errors as WithRecordErrorIsolation
+// does, and renders the bad records with the marker, such as a JSON object
+// that downstream tools can pick out. Unlike the default comment, the marker
+// is also written with WithStrictJSON, which leaves valid JSON to the marker.
+// A nil marker restores the default comment.
+//
+// Example:
+//
+//	config := NewConfig(WithRecordErrorMarker(func(failure RecordFailure) string {
+//	    return fmt.Sprintf(`{"badRecord": %d, "line": %d}`, failure.Record, failure.Line)
+//	}))
+func WithRecordErrorMarker(marker RecordErrorMarker) ConfigOption {
+	return func(c *Config) {
+		c.IsolateRecordErrors = true
+		c.RecordErrorMarker = marker
+	}
+}
+
+// RecordErrorComment is the default RecordErrorMarker. It renders a comment
+// block with the location and error, followed by the first lines of the raw
+// record prefixed with "> ", so the bad lines can be found and fixed without
+// formatting the input again.
+//
+// Example output:
+//
+//	// line 2, column 6: invalid JSON text in record 2: malformed JSON: unclosed objects or arrays
+//	// > {"b":
+func RecordErrorComment(failure RecordFailure) string {
+	newlines := strings.NewReplacer("\r", " ", "\n", " ")
+	lines := []string{fmt.Sprintf("// line %d, column %d: %s", failure.Line, failure.Column, newlines.Replace(failure.Err.Error()))}
+	text := strings.Split(failure.Text, "\n")
+	for i, line := range text {
+		if i == recordQuoteLines {
+			lines = append(lines, fmt.Sprintf("// > … (%d more lines)", len(text)-i))
+			break
+		}
+		line = strings.TrimSuffix(line, "\r")
+		if utf8.RuneCountInString(line) > recordQuoteWidth {
+			line = string([]rune(line)[:recordQuoteWidth]) + "…"
+		}
+		lines = append(lines, "// > "+line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatRecords formats newline-delimited JSON or concatenated JSON
+// documents, each record separately, and writes the formatted records one
+// after another, each followed by a line feed. Input whose first line holds
+// one complete value is read as newline-delimited JSON, one record per line;
+// other input is split where each top-level value ends. Blank lines are
+// skipped.
+//
+// If a record cannot be formatted, the returned FormatError names the record
+// number (starting at 1). With WithRecordErrorIsolation, the output of all
+// records is returned together with the errors of the bad records joined.
+//
+// Example:
+//
+//	formatted, err := formatter.FormatRecords("{\"a\":1}\n{\"b\":[1,2]}\n")
+func (f *Formatter) FormatRecords(input string) (string, error) {
+	var output strings.Builder
+	err := f.FormatRecordsTo(&output, strings.NewReader(input))
+	if err != nil && !f.config.IsolateRecordErrors {
+		return "", err
+	}
+	return output.String(), err
+}
+
+// FormatRecordsTo reads newline-delimited JSON or concatenated JSON documents
+// from r and writes the formatted records to w as FormatRecords does, as they
+// arrive. With WithRecordWorkers, records are formatted concurrently while
+// the next ones are read, and written in input order.
+//
+// If an error is returned without WithRecordErrorIsolation, the records
+// before the failing one have already been written to w.
+func (f *Formatter) FormatRecordsTo(w io.Writer, r io.Reader) error {
+	if w == nil {
+		return NewFormatError("output writer cannot be nil")
+	}
+	if r == nil {
+		return NewFormatError("input reader cannot be nil")
+	}
+
+	workers := max(f.config.RecordWorkers, 1)
+	records := &recordReader{reader: bufio.NewReader(r)}
+	// Records are queued in input order for the writer, and formatted by
+	// whichever worker is free
+	order := make(chan *recordJob, 2*workers)
+	jobs := make(chan *recordJob)
+	stop := make(chan struct{})
+	var readErr error
+	go func() {
+		defer close(order)
+		defer close(jobs)
+		for number := 1; ; number++ {
+			record, err := records.next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			job := &recordJob{number: number, rawRecord: record, done: make(chan struct{})}
+			for _, queue := range []chan *recordJob{order, jobs} {
+				select {
+				case queue <- job:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.formatted, job.err = f.Format(job.text)
+				close(job.done)
+			}
+		}()
+	}
+
+	writer := newFlushWriter(f.config.Faults.writer(w), f.config.FlushPolicy, FlushPolicy{Records: true})
+	errs, err := f.writeRecords(writer, order)
+	close(stop)
+	for range order {
+		// Drain the queue so the reader stops
+	}
+	wg.Wait()
+	switch {
+	case err != nil:
+		return err
+	case readErr != nil:
+		return readErr
+	}
+	if err := writer.Flush(); err != nil {
+		return WrapFormatError("failed to write output", err)
+	}
+	return errors.Join(errs...)
+}
+
+// recordJob is a record being formatted by FormatRecordsTo
+type recordJob struct {
+	rawRecord
+	number    int
+	formatted string
+	err       error
+	done      chan struct{} // Closed when the record is formatted
+}
+
+// writeRecords writes the formatted records in input order. It returns the
+// errors of the records isolated with WithRecordErrorIsolation, or the error
+// that stopped the output.
+func (f *Formatter) writeRecords(writer *flushWriter, order <-chan *recordJob) ([]error, error) {
+	var errs []error
+	for job := range order {
+		<-job.done
+		text := job.formatted
+		if job.err != nil {
+			// The filename moves to the front of the message
+			var formatErr *FormatError
+			if errors.As(job.err, &formatErr) {
+				formatErr.File = ""
+			}
+			wrapped := WrapFormatError(fmt.Sprintf("invalid JSON text in record %d", job.number), job.err)
+			wrapped.Line, wrapped.Column = job.locate(formatErr)
+			err := f.config.nameError(wrapped)
+			if !f.config.IsolateRecordErrors {
+				return nil, err
+			}
+			errs = append(errs, err)
+			marker := f.config.RecordErrorMarker
+			if marker == nil {
+				if f.config.StrictJSON {
+					continue
+				}
+				marker = RecordErrorComment
+			}
+			text = marker(RecordFailure{Record: job.number, Line: wrapped.Line, Column: wrapped.Column, Text: job.text, Err: err})
+			if text == "" {
+				continue
+			}
+		}
+		if _, err := writer.WriteString(text + "\n"); err != nil {
+			return nil, WrapFormatError("failed to write output", err)
+		}
+		// By default, flush per record so that streamed records are visible immediately
+		if err := writer.endRecord(); err != nil {
+			return nil, WrapFormatError("failed to write output", err)
+		}
+	}
+	return errs, nil
+}
+
+// locate returns the location in the input of an error in the record, or
+// of the start of the record if the error has no location
+func (r rawRecord) locate(err *FormatError) (int, int) {
+	switch {
+	case err == nil || err.Line == 0:
+		return r.line, r.column
+	case err.Line == 1:
+		return r.line, r.column + err.Column - 1
+	default:
+		return r.line + err.Line - 1, err.Column
+	}
+}
+
+// recordReader splits newline-delimited JSON or concatenated documents into
+// records
+type recordReader struct {
+	reader  *bufio.Reader
+	pending string // Text read but not yet split into records
+	line    int    // 1-based line of the start of pending
+	column  int    // 1-based column of the start of pending
+	eof     bool
+	started bool // Whether the first record was read
+	lines   bool // Whether every line is a record
+}
+
+// rawRecord is the text of a record and the location where it starts
+type rawRecord struct {
+	text         string
+	line, column int
+}
+
+// next returns the next non-blank record, or io.EOF
+func (r *recordReader) next() (rawRecord, error) {
+	if r.line == 0 {
+		r.line, r.column = 1, 1
+	}
+	var record strings.Builder
+	var scanner recordScanner
+	line, column := r.line, r.column
+	for {
+		if r.pending == "" {
+			if r.eof {
+				if text := record.String(); strings.TrimSpace(text) != "" {
+					return r.trimmed(text, line, column), nil // A record cut off by the end of the input
+				}
+				return rawRecord{}, io.EOF
+			}
+			text, err := r.reader.ReadString('\n')
+			if err == io.EOF {
+				r.eof = true
+			} else if err != nil {
+				return rawRecord{}, WrapFormatError("failed to read input", err)
+			}
+			r.pending = text
+			if r.lines {
+				line, column = r.line, r.column
+				r.consume(len(text))
+				if strings.TrimSpace(text) != "" {
+					return r.trimmed(text, line, column), nil
+				}
+			}
+			continue
+		}
+
+		end, done := scanner.scan(r.pending)
+		record.WriteString(r.pending[:end])
+		r.consume(end)
+		if !done {
+			continue
+		}
+		text := record.String()
+		if strings.TrimSpace(text) == "" {
+			record.Reset()
+			scanner = recordScanner{}
+			line, column = r.line, r.column
+			continue
+		}
+		if !r.started {
+			// A first record that fills its line makes the input NDJSON, so
+			// that a malformed line only spoils its own record
+			r.started = true
+			r.lines = !strings.Contains(strings.TrimSpace(text), "\n") && strings.TrimSpace(r.pending) == ""
+			if r.lines {
+				r.consume(len(r.pending))
+			}
+		}
+		return r.trimmed(text, line, column), nil
+	}
+}
+
+// consume drops the first n bytes of the pending text, keeping track of the
+// location of the rest
+func (r *recordReader) consume(n int) {
+	r.line, r.column = advance(r.line, r.column, r.pending[:n])
+	r.pending = r.pending[n:]
+}
+
+// trimmed returns a record without surrounding whitespace, located at its
+// first character
+func (r *recordReader) trimmed(text string, line, column int) rawRecord {
+	rest := strings.TrimLeft(text, " \t\r\n")
+	line, column = advance(line, column, text[:len(text)-len(rest)])
+	return rawRecord{text: strings.TrimSpace(rest), line: line, column: column}
+}
+
+// advance returns the location after text that starts at the given line
+// and column, counting columns in characters
+func advance(line, column int, text string) (int, int) {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return line + strings.Count(text, "\n"), utf8.RuneCountInString(text[i+1:]) + 1
+	}
+	return line, column + utf8.RuneCountInString(text)
+}
+
+// recordScanner finds the end of a top-level JSON value
+type recordScanner struct {
+	depth    int
+	started  bool
+	inString bool
+	escaped  bool
+}
+
+// scan advances over text and returns the offset where the value ends and
+// true, or the length of the text and false if the value goes on
+func (s *recordScanner) scan(text string) (int, bool) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+				if s.depth == 0 {
+					return i + 1, true
+				}
+			case c == '\n':
+				return i + 1, true // Strings cannot span lines, so the record is cut off
+			}
+			continue
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			if s.started && s.depth == 0 {
+				return i, true
+			}
+		case '"', '{', '[':
+			if s.started && s.depth == 0 {
+				return i, true
+			}
+			s.started = true
+			if c == '"' {
+				s.inString = true
+			} else {
+				s.depth++
+			}
+		case '}', ']':
+			s.started = true
+			s.depth--
+			if s.depth <= 0 {
+				return i + 1, true
+			}
+		default:
+			s.started = true
+		}
+	}
+	return len(text), false
+}
@@ -0,0 +1,163 @@
+package jsonformat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFormatRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "newline-delimited",
+			input:    "{\"a\":1}\n\n[1,2]\r\n\"text\"\n",
+			expected: "{\n  \"a\": 1\n}\n[\n  1,\n  2\n]\n\"text\"\n",
+		},
+		{
+			name:     "concatenated documents",
+			input:    "{\n  \"a\": 1\n}\n{\"b\":{\"c\":\"}\"}}[3] 4 \"x\"",
+			expected: "{\n  \"a\": 1\n}\n{\n  \"b\": {\n    \"c\": \"}\"\n  }\n}\n[\n  3\n]\n4\n\"x\"\n",
+		},
+		{
+			name:     "concurrent records keep their order",
+			input:    strings.Repeat("{\"n\":[1,2,3]}\n", 3) + "{\"last\":true}\n",
+			options:  []ConfigOption{WithRecordWorkers(4)},
+			expected: strings.Repeat("{\n  \"n\": [\n    1,\n    2,\n    3\n  ]\n}\n", 3) + "{\n  \"last\": true\n}\n",
+		},
+		{
+			name:     "empty input",
+			input:    "\n  \n",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.FormatRecords(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatRecordsOrderUnderLoad(t *testing.T) {
+	var input, expected strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&input, "[%d]\n", i)
+		fmt.Fprintf(&expected, "[\n  %d\n]\n", i)
+	}
+	formatter := NewFormatter(NewConfig(WithRecordWorkers(8)))
+	result, err := formatter.FormatRecords(input.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected.String() {
+		t.Error("Expected the records in input order")
+	}
+}
+
+func TestFormatRecordsErrors(t *testing.T) {
+	input := "{\"a\":1}\n{\"b\":\n{\"c\":\"unterminated\n[2]\n"
+
+	formatter := NewFormatter(NewConfig(WithRecordWorkers(2)))
+	_, err := formatter.FormatRecords(input)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid JSON text in record 2") {
+		t.Fatalf("Expected an error naming record 2, got %v", err)
+	}
+
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Line != 2 || formatErr.Column != 6 {
+		t.Errorf("Expected the error at line 2, column 6, got %+v", formatErr)
+	}
+
+	var output strings.Builder
+	if err := formatter.FormatRecordsTo(&output, strings.NewReader(input)); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if output.String() != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Expected the records before the error, got:\n%s", output.String())
+	}
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "isolated",
+			options:  []ConfigOption{WithRecordErrorIsolation(), WithRecordWorkers(2)},
+			expected: "{\n  \"a\": 1\n}\n// line 2, column 6: invalid JSON text in record 2: malformed JSON: unclosed objects or arrays\n// > {\"b\":\n// line 3, column 5: ",
+		},
+		{
+			name:     "isolated with strict JSON",
+			options:  []ConfigOption{WithRecordErrorIsolation(), WithStrictJSON()},
+			expected: "{\n  \"a\": 1\n}\n[\n  2\n]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.FormatRecords(input)
+			if err == nil {
+				t.Fatal("Expected the errors of the bad records")
+			}
+			var formatErr *FormatError
+			if !errors.As(err, &formatErr) {
+				t.Errorf("Expected FormatErrors, got %v", err)
+			}
+			if strings.Count(err.Error(), "invalid JSON text in record") != 2 {
+				t.Errorf("Expected errors for records 2 and 3, got %v", err)
+			}
+			if !strings.HasPrefix(result, tt.expected) || !strings.HasSuffix(result, "[\n  2\n]\n") {
+				t.Errorf("Expected:\n%s...\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRecordErrorMarker(t *testing.T) {
+	input := "{\"a\":1}\n  [1,,2]\n{\"b\":2}\n{\n"
+	marker := func(failure RecordFailure) string {
+		if failure.Record == 4 {
+			return ""
+		}
+		return fmt.Sprintf(`{"badRecord": %d, "line": %d, "column": %d, "text": %q}`, failure.Record, failure.Line, failure.Column, failure.Text)
+	}
+
+	formatter := NewFormatter(NewConfig(WithRecordErrorMarker(marker), WithStrictJSON()))
+	result, err := formatter.FormatRecords(input)
+	if err == nil {
+		t.Fatal("Expected the error of the bad record")
+	}
+	expected := "{\n  \"a\": 1\n}\n{\"badRecord\": 2, \"line\": 2, \"column\": 7, \"text\": \"[1,,2]\"}\n{\n  \"b\": 2\n}\n"
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestRecordErrorComment(t *testing.T) {
+	failure := RecordFailure{
+		Record: 4,
+		Line:   10,
+		Column: 3,
+		Text:   strings.Repeat("x", 205) + "\n2\n3\n4\n5\n6\n7",
+		Err:    NewFormatError("invalid JSON text in record 4"),
+	}
+	expected := "// line 10, column 3: invalid JSON text in record 4\n" +
+		"// > " + strings.Repeat("x", 200) + "…\n// > 2\n// > 3\n// > 4\n// > 5\n// > … (2 more lines)"
+	if result := RecordErrorComment(failure); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
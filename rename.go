@@ -0,0 +1,48 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+// WithKeyRename writes the key of the members at paths matching the pattern
+// as the given key, for example to shorten verbose keys in presentations.
+// The pattern is a dot-separated path as in ValueContext.MatchPath, and paths
+// given to other options keep using the original keys. If several patterns
+// match a member, the first in lexicographic order is used.
+//
+// Example:
+//
+//	config := NewConfig(WithKeyRename("metadata.creationTimestamp", "created"))
+//	// {"metadata": {"creationTimestamp": "2024-05-01T12:00:00Z"}}
+//	// →
+//	// {"metadata": {"created": "2024-05-01T12:00:00Z"}}
+func WithKeyRename(pattern, key string) ConfigOption {
+	return func(c *Config) {
+		if c.KeyRenames == nil {
+			c.KeyRenames = make(map[string]string)
+		}
+		c.KeyRenames[pattern] = key
+	}
+}
+
+// renamedKey returns the key written for the member whose key is being
+// written, which is its own key unless it is renamed
+func (p *TokenParser) renamedKey(key string) string {
+	if len(p.config.KeyRenames) == 0 {
+		return key
+	}
+	if pattern, ok := firstMatch(p.pathRules().renames, p.valuePath()); ok {
+		return p.config.KeyRenames[pattern]
+	}
+	return key
+}
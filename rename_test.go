@@ -0,0 +1,47 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestKeyRename(t *testing.T) {
+	input := `{"metadata":{"creationTimestamp":"2024","name":"x"},"items":[{"creationTimestamp":"2023"}],"creationTimestamp":"root"}`
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "exact path",
+			options:  []ConfigOption{WithKeyRename("metadata.creationTimestamp", "created")},
+			expected: `{"metadata": {"created": "2024", "name": "x"}, "items": [{"creationTimestamp": "2023"}], "creationTimestamp": "root"}`,
+		},
+		{
+			name:     "wildcard",
+			options:  []ConfigOption{WithKeyRename("items.*.creationTimestamp", "created")},
+			expected: `{"metadata": {"creationTimestamp": "2024", "name": "x"}, "items": [{"created": "2023"}], "creationTimestamp": "root"}`,
+		},
+		{
+			name:     "other options use original keys",
+			options:  []ConfigOption{WithKeyRename("metadata.name", "title"), WithKeyOrder("metadata", "name", "*")},
+			expected: `{"metadata": {"title": "x", "creationTimestamp": "2024"}, "items": [{"creationTimestamp": "2023"}], "creationTimestamp": "root"}`,
+		},
+		{
+			name:     "escaped",
+			options:  []ConfigOption{WithKeyRename("creationTimestamp", `"at"`)},
+			expected: `{"metadata": {"creationTimestamp": "2024", "name": "x"}, "items": [{"creationTimestamp": "2023"}], "\"at\"": "root"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(append(tt.options, WithCompactDepth(1))...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Repair describes how FormatRepaired completed a truncated document.
+type Repair struct {
+	// Offset is the length of the input kept, where the document was cut off.
+	Offset int
+
+	// Dropped is the incomplete token at the end of the input that was left
+	// out, such as part of a key, a literal, or an escape sequence.
+	Dropped string
+
+	// ClosedString tells whether an unterminated string was closed, with
+	// "…" at the end of its value.
+	ClosedString bool
+
+	// Closed holds the closing brackets and braces added, innermost first.
+	Closed string
+}
+
+// String describes the repair, for example
+// `input cut off at offset 42: dropped "tru", closed "]}"`.
+func (r *Repair) String() string {
+	var parts []string
+	if r.Dropped != "" {
+		parts = append(parts, fmt.Sprintf("dropped %q", r.Dropped))
+	}
+	if r.ClosedString {
+		parts = append(parts, "closed a string")
+	}
+	if r.Closed != "" {
+		parts = append(parts, fmt.Sprintf("closed %q", r.Closed))
+	}
+	return fmt.Sprintf("input cut off at offset %d: %s", r.Offset, strings.Join(parts, ", "))
+}
+
+// FormatRepaired formats a document that was cut off, such as a log field
+// capped in size, by completing it on a best-effort basis so that its
+// readable prefix can be inspected. An incomplete token at the end is left
+// out, an unterminated string value is closed with "…" at its end, and the
+// containers left open are closed after a "…" marker in place of the
+// missing members and elements. With WithStrictJSON, or while members are
+// held back by options such as WithSortedKeys, the marker is not written
+// and a member missing its value gets null, so the output stays valid.
+//
+// The returned Repair is nil when the input is a complete document. Input
+// that is malformed for other reasons than ending early still fails. The
+// cache set with SetCache is not used.
+//
+// Example:
+//
+//	formatted, repair, err := formatter.FormatRepaired(`{"user":{"name":"Ali`)
+//	// {
+//	//   "user": {
+//	//     "name": "Ali…",
+//	//     …
+//	//   }
+//	// }
+//	// repair.String(): input cut off at offset 20: closed a string, closed "}}"
+func (f *Formatter) FormatRepaired(jsonStr string) (result string, repair *Repair, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+		}
+	}()
+
+	cut, ok := findTruncation(jsonStr)
+	if !ok {
+		result, _, err = f.formatString(jsonStr, nil)
+		return result, nil, err
+	}
+	repaired := jsonStr[:cut.offset]
+	if cut.closeString {
+		repaired += "…\""
+	}
+	result, _, err = f.formatString(repaired, func(p *TokenParser) { p.repair = cut })
+	if err != nil {
+		return "", nil, err
+	}
+	return result, cut.report(jsonStr), nil
+}
+
+// truncation is where a document ends early and how it is completed
+type truncation struct {
+	offset      int    // Length of the input kept
+	closeString bool   // Whether the input ends in a string value, which is closed
+	pending     bool   // Whether the last member kept lacks its value
+	open        []byte // Containers left open, outermost first
+}
+
+// findTruncation scans the structure of input and returns where it is cut
+// off, or false if every string and container is closed. It does not check
+// the syntax, which is left to the decoder.
+func findTruncation(input string) (*truncation, bool) {
+	var stack []byte
+	expectingKey := false // Whether the next string is a key
+	afterKey := false     // Whether a key was read and its value was not
+	boundary := 0         // Offset after the last complete token
+	for i := 0; i < len(input); {
+		switch c := input[i]; c {
+		case ' ', '\t', '\n', '\r':
+			i++
+			continue
+		case '{', '[':
+			stack = append(stack, c)
+			expectingKey, afterKey = c == '{', false
+			i++
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			expectingKey, afterKey = false, false
+			i++
+		case ':':
+			i++
+		case ',':
+			expectingKey = len(stack) > 0 && stack[len(stack)-1] == '{'
+			i++
+		case '"':
+			end := stringEnd(input, i+1)
+			if end < 0 {
+				if expectingKey {
+					// Part of a key is no member yet
+					return &truncation{offset: boundary, open: stack}, true
+				}
+				return &truncation{offset: i + 1 + len(completeStringPrefix(input[i+1:])), closeString: true, open: stack}, true
+			}
+			afterKey, expectingKey = expectingKey, false
+			i = end
+		default:
+			end := i
+			for end < len(input) && !strings.ContainsRune(" \t\n\r{}[],:\"", rune(input[end])) {
+				end++
+			}
+			if end == len(input) && !json.Valid([]byte(input[i:])) {
+				// A literal or number cut off in the middle
+				return &truncation{offset: boundary, pending: afterKey, open: stack}, true
+			}
+			afterKey = false
+			i = end
+		}
+		boundary = i
+	}
+	if len(stack) == 0 {
+		return nil, false
+	}
+	return &truncation{offset: len(input), pending: afterKey, open: stack}, true
+}
+
+// stringEnd returns the offset after the quote that ends the string whose
+// content starts at start, or -1 if the string is not terminated
+func stringEnd(input string, start int) int {
+	for i := start; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// completeStringPrefix returns the content of an unterminated string without
+// an escape sequence or UTF-8 sequence cut off at its end
+func completeStringPrefix(content string) string {
+	// An odd number of trailing backslashes starts an escape sequence
+	backslashes := len(content) - len(strings.TrimRight(content, `\`))
+	if backslashes%2 == 1 {
+		return content[:len(content)-1]
+	}
+	if i := strings.LastIndex(content, `\u`); i >= 0 && len(content)-i < 6 {
+		escaped := len(content[:i]) - len(strings.TrimRight(content[:i], `\`))
+		if escaped%2 == 0 {
+			return content[:i]
+		}
+	}
+	for i := len(content) - 1; i >= 0 && i >= len(content)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(content[i]) {
+			if !utf8.FullRuneInString(content[i:]) {
+				return content[:i]
+			}
+			break
+		}
+	}
+	return content
+}
+
+// report describes the truncation of input for FormatRepaired
+func (t *truncation) report(input string) *Repair {
+	repair := &Repair{
+		Offset:       t.offset,
+		Dropped:      strings.TrimSpace(input[t.offset:]),
+		ClosedString: t.closeString,
+	}
+	for i := len(t.open) - 1; i >= 0; i-- {
+		if t.open[i] == '{' {
+			repair.Closed += "}"
+		} else {
+			repair.Closed += "]"
+		}
+	}
+	return repair
+}
+
+// closeRepaired completes the document of FormatRepaired when its input
+// ends with containers left open, marking where the input ended
+func (p *TokenParser) closeRepaired() error {
+	p.truncated = true
+	held := p.fold != nil || p.records != nil || p.sample != nil || p.order != nil || p.hiding
+	switch {
+	case !p.config.StrictJSON && !held:
+		if err := p.emitToken(truncatedMarker{}); err != nil {
+			return err
+		}
+	case p.repair.pending:
+		if err := p.processToken(nil); err != nil {
+			return err
+		}
+	}
+	for i := len(p.repair.open) - 1; i >= 0; i-- {
+		delim := json.Delim('}')
+		if p.repair.open[i] == '[' {
+			delim = ']'
+		}
+		if err := p.processToken(delim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
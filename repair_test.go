@@ -0,0 +1,114 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestFormatRepaired(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		strict   bool
+		expected string
+		repair   string
+	}{
+		{
+			name:     "string value",
+			input:    `{"user":{"name":"Ali`,
+			expected: "{\n  \"user\": {\n    \"name\": \"Ali…\",\n    …\n  }\n}",
+			repair:   `input cut off at offset 20: closed a string, closed "}}"`,
+		},
+		{
+			name:     "string value strict",
+			input:    `{"user":{"name":"Ali`,
+			strict:   true,
+			expected: "{\n  \"user\": {\n    \"name\": \"Ali…\"\n  }\n}",
+			repair:   `input cut off at offset 20: closed a string, closed "}}"`,
+		},
+		{
+			name:     "partial literal",
+			input:    `[1,2,tr`,
+			expected: "[\n  1,\n  2,\n  …\n]",
+			repair:   `input cut off at offset 5: dropped "tr", closed "]"`,
+		},
+		{
+			name:     "missing value",
+			input:    `{"a":`,
+			expected: "{\n  \"a\": …\n}",
+			repair:   `input cut off at offset 5: closed "}"`,
+		},
+		{
+			name:     "missing value strict",
+			input:    `{"a":`,
+			strict:   true,
+			expected: "{\n  \"a\": null\n}",
+			repair:   `input cut off at offset 5: closed "}"`,
+		},
+		{
+			name:     "partial key",
+			input:    `{"a":1,"b`,
+			strict:   true,
+			expected: "{\n  \"a\": 1\n}",
+			repair:   `input cut off at offset 7: dropped "\"b", closed "}"`,
+		},
+		{
+			name:     "partial escape",
+			input:    `{"a":"x\u00`,
+			strict:   true,
+			expected: "{\n  \"a\": \"x…\"\n}",
+			repair:   `input cut off at offset 7: dropped "\\u00", closed a string, closed "}"`,
+		},
+		{
+			name:     "partial UTF-8",
+			input:    "[\"\xe3\x81",
+			strict:   true,
+			expected: "[\n  \"…\"\n]",
+			repair:   `input cut off at offset 2: dropped "\xe3\x81", closed a string, closed "]"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []ConfigOption
+			if tt.strict {
+				options = append(options, WithStrictJSON())
+			}
+			result, repair, err := NewFormatter(NewConfig(options...)).FormatRepaired(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, result)
+			}
+			if repair == nil || repair.String() != tt.repair {
+				t.Errorf("Expected repair %s, got %v", tt.repair, repair)
+			}
+		})
+	}
+}
+
+func TestFormatRepairedComplete(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	result, repair, err := formatter.FormatRepaired(`{"a":[1,2]}`)
+	if err != nil || repair != nil {
+		t.Fatalf("Expected no repair, got %v, %v", repair, err)
+	}
+	if expected, _ := formatter.Format(`{"a":[1,2]}`); result != expected {
+		t.Errorf("Expected the output of Format, got %q", result)
+	}
+
+	// Input malformed before its end is not repaired
+	if _, _, err := formatter.FormatRepaired(`{"a":1 "b":[`); err == nil {
+		t.Error("Expected an error for malformed input")
+	}
+}
+
+func TestFormatRepairedHeldMembers(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithSortedKeys("")))
+	result, _, err := formatter.FormatRepaired(`{"b":1,"a":`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "{\n  \"a\": null,\n  \"b\": 1\n}"; result != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// responseSummaryLength is the maximum number of characters of the text
+// that follows the status in a response summary
+const responseSummaryLength = 200
+
+// responseMessagePaths are the paths of the error message in common error
+// response formats, in match order: RFC 9457 problem details, OAuth 2.0,
+// Google APIs, GraphQL, and ad hoc bodies
+var responseMessagePaths = [][]string{
+	{"detail"},
+	{"error_description"},
+	{"error", "message"},
+	{"errors", "0", "message"},
+	{"message"},
+	{"title"},
+	{"error"},
+	{"msg"},
+}
+
+// responseCodePaths are the paths of the machine-readable error code, in match order
+var responseCodePaths = [][]string{
+	{"code"},
+	{"error", "code"},
+	{"error", "status"},
+	{"errors", "0", "code"},
+	{"errors", "0", "extensions", "code"},
+	{"type"},
+	{"error"},
+}
+
+// ErrorResponse is a failed HTTP response prepared for logging.
+type ErrorResponse struct {
+	// Status is the HTTP status code.
+	Status int
+
+	// Summary is one line with the status, the error message, and the
+	// error code, such as
+	// `404 Not Found: user 7 does not exist [USER_NOT_FOUND]`.
+	Summary string
+
+	// Body is the formatted body, or the body as is when it is not JSON.
+	Body string
+}
+
+// String returns the summary followed by the body on the next lines.
+func (r *ErrorResponse) String() string {
+	if r.Body == "" {
+		return r.Summary
+	}
+	return r.Summary + "\n" + r.Body
+}
+
+// FormatErrorResponse prepares the body and status code of a failed HTTP
+// response for logging, so that every client logs them the same way. The
+// summary takes the message and code from the common error formats, such
+// as RFC 9457 problem details, OAuth 2.0 errors, and GraphQL errors, or
+// else the start of the body on one line. A body that is not JSON is
+// summarized and kept as is, so the method never fails.
+//
+// Example:
+//
+//	if resp.StatusCode >= 400 {
+//	    body, _ := io.ReadAll(resp.Body)
+//	    failed := formatter.FormatErrorResponse(resp.StatusCode, body)
+//	    log.Print(failed.Summary)
+//	    // 404 Not Found: user 7 does not exist [USER_NOT_FOUND]
+//	    log.Print(failed.Body)
+//	}
+func (f *Formatter) FormatErrorResponse(status int, body []byte) *ErrorResponse {
+	response := &ErrorResponse{Status: status}
+	prefix := fmt.Sprint(status)
+	if text := http.StatusText(status); text != "" {
+		prefix += " " + text
+	}
+
+	trimmed := strings.TrimSpace(string(body))
+	var value interface{}
+	if trimmed == "" {
+		response.Summary = prefix + ": empty body"
+		return response
+	}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || !atEOF(decoder) {
+		response.Summary = prefix + ": " + summaryText(trimmed)
+		response.Body = trimmed
+		return response
+	}
+
+	if formatted, err := f.Format(trimmed); err == nil {
+		response.Body = formatted
+	} else {
+		response.Body = trimmed
+	}
+	message, found := responseField(value, responseMessagePaths)
+	if !found {
+		var compact bytes.Buffer
+		if json.Compact(&compact, []byte(trimmed)) == nil {
+			message = compact.String()
+		}
+	}
+	summary := prefix + ": " + summaryText(message)
+	if code, ok := responseField(value, responseCodePaths); ok && code != message {
+		summary += " [" + summaryText(code) + "]"
+	}
+	response.Summary = summary
+	return response
+}
+
+// atEOF reports whether the decoder has no more tokens
+func atEOF(decoder *json.Decoder) bool {
+	_, err := decoder.Token()
+	return err == io.EOF
+}
+
+// responseField returns the first string or number found at the paths
+func responseField(value interface{}, paths [][]string) (string, bool) {
+	for _, path := range paths {
+		current := value
+		for _, segment := range path {
+			switch container := current.(type) {
+			case map[string]interface{}:
+				current = container[segment]
+			case []interface{}:
+				current = nil
+				if segment == "0" && len(container) > 0 {
+					current = container[0]
+				}
+			default:
+				current = nil
+			}
+		}
+		switch field := current.(type) {
+		case string:
+			// about:blank is the default type of problem details
+			if field != "" && field != "about:blank" {
+				return field, true
+			}
+		case json.Number:
+			return field.String(), true
+		}
+	}
+	return "", false
+}
+
+// summaryText returns the first line of text, cut after
+// responseSummaryLength characters
+func summaryText(text string) string {
+	if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+		text = strings.TrimSpace(text[:i]) + " …"
+	}
+	if utf8.RuneCountInString(text) > responseSummaryLength {
+		text = string([]rune(text)[:responseSummaryLength]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,104 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatErrorResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		summary string
+		output  string
+	}{
+		{
+			name:    "problem details",
+			status:  403,
+			body:    `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","detail":"Your balance is 30, but that costs 50."}`,
+			summary: "403 Forbidden: Your balance is 30, but that costs 50. [https://example.com/probs/out-of-credit]",
+			output:  "{\n  \"type\": \"https://example.com/probs/out-of-credit\",\n  \"title\": \"You do not have enough credit.\",\n  \"detail\": \"Your balance is 30, but that costs 50.\"\n}",
+		},
+		{
+			name:    "nested error",
+			status:  404,
+			body:    `{"error":{"code":404,"message":"user 7 does not exist","status":"NOT_FOUND"}}`,
+			summary: "404 Not Found: user 7 does not exist [404]",
+			output:  "{\n  \"error\": {\n    \"code\": 404,\n    \"message\": \"user 7 does not exist\",\n    \"status\": \"NOT_FOUND\"\n  }\n}",
+		},
+		{
+			name:    "OAuth",
+			status:  400,
+			body:    `{"error":"invalid_grant","error_description":"code expired"}`,
+			summary: "400 Bad Request: code expired [invalid_grant]",
+			output:  "{\n  \"error\": \"invalid_grant\",\n  \"error_description\": \"code expired\"\n}",
+		},
+		{
+			name:    "GraphQL",
+			status:  200,
+			body:    `{"errors":[{"message":"field missing","extensions":{"code":"BAD_USER_INPUT"}}]}`,
+			summary: "200 OK: field missing [BAD_USER_INPUT]",
+			output:  "{\n  \"errors\": [\n    {\"message\": \"field missing\", \"extensions\": {\"code\": \"BAD_USER_INPUT\"}}\n  ]\n}",
+		},
+		{
+			name:    "plain error",
+			status:  500,
+			body:    `{"error":"boom"}`,
+			summary: "500 Internal Server Error: boom",
+			output:  "{\n  \"error\": \"boom\"\n}",
+		},
+		{
+			name:    "unknown format",
+			status:  422,
+			body:    `{"fields":["a"]}`,
+			summary: `422 Unprocessable Entity: {"fields":["a"]}`,
+			output:  "{\n  \"fields\": [\n    \"a\"\n  ]\n}",
+		},
+		{
+			name:    "not JSON",
+			status:  502,
+			body:    "<html>\n<body>Bad Gateway</body>\n</html>\n",
+			summary: "502 Bad Gateway: <html> …",
+			output:  "<html>\n<body>Bad Gateway</body>\n</html>",
+		},
+		{
+			name:    "empty",
+			status:  599,
+			body:    " \n",
+			summary: "599: empty body",
+		},
+		{
+			name:    "long message",
+			status:  500,
+			body:    `{"message":"` + strings.Repeat("x", 250) + `"}`,
+			summary: "500 Internal Server Error: " + strings.Repeat("x", 200) + "…",
+			output:  "{\n  \"message\": \"" + strings.Repeat("x", 250) + "\"\n}",
+		},
+	}
+
+	formatter := NewFormatter(DefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := formatter.FormatErrorResponse(tt.status, []byte(tt.body))
+			if response.Status != tt.status || response.Summary != tt.summary {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.summary, response.Summary)
+			}
+			if response.Body != tt.output {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.output, response.Body)
+			}
+		})
+	}
+}
+
+func TestErrorResponseString(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithCompactDepth(1)))
+	response := formatter.FormatErrorResponse(409, []byte(`{"message":"conflict"}`))
+	expected := "409 Conflict: conflict\n{\"message\": \"conflict\"}"
+	if got := response.String(); got != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, got)
+	}
+	if got := formatter.FormatErrorResponse(500, nil).String(); got != "500 Internal Server Error: empty body" {
+		t.Errorf("Expected the summary only, got %q", got)
+	}
+}
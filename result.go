@@ -0,0 +1,80 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+// Result is formatted output with an index of its lines, so that viewers can
+// paginate huge outputs without splitting the string themselves.
+type Result struct {
+	text       string
+	lineStarts []int // Offset of the first byte of each line
+}
+
+// FormatResult formats a JSON string like Format and returns the output
+// together with its line index, which is built while the output is written.
+//
+// Example:
+//
+//	result, err := formatter.FormatResult(input)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(result.Page(0, 50)) // first screen
+func (f *Formatter) FormatResult(jsonStr string) (result *Result, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = nil
+		}
+	}()
+
+	text, parser, err := f.formatString(jsonStr, func(p *TokenParser) { p.output.indexLines = true })
+	if err != nil {
+		return nil, err
+	}
+	return &Result{text: text, lineStarts: append([]int{0}, parser.output.lineStarts...)}, nil
+}
+
+// String returns the whole formatted output
+func (r *Result) String() string {
+	return r.text
+}
+
+// LineCount returns the number of lines of the output
+func (r *Result) LineCount() int {
+	return len(r.lineStarts)
+}
+
+// Page returns the lines from index from up to, but not including, index to,
+// joined by line breaks. Indices start at 0 and are clamped to the available
+// lines, so Page(0, r.LineCount()) returns the whole output, and a range
+// without lines returns an empty string.
+func (r *Result) Page(from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(r.lineStarts) {
+		to = len(r.lineStarts)
+	}
+	if from >= to {
+		return ""
+	}
+	end := len(r.text)
+	if to < len(r.lineStarts) {
+		// Exclude the line break that ends the page
+		end = r.lineStarts[to] - 1
+	}
+	return r.text[r.lineStarts[from]:end]
+}
@@ -0,0 +1,65 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatResult(t *testing.T) {
+	input := `{"users":[{"id":1},{"id":2}],"meta":{"count":2}}`
+	result, err := NewFormatter(DefaultConfig()).FormatResult(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	formatted, err := NewFormatter(DefaultConfig()).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(formatted, "\n")
+
+	if result.String() != formatted {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", formatted, result.String())
+	}
+	if result.LineCount() != len(lines) {
+		t.Errorf("Expected %d lines, got %d", len(lines), result.LineCount())
+	}
+
+	tests := []struct {
+		from, to int
+		expected string
+	}{
+		{from: 0, to: 1, expected: lines[0]},
+		{from: 1, to: 3, expected: strings.Join(lines[1:3], "\n")},
+		{from: 5, to: 100, expected: strings.Join(lines[5:], "\n")},
+		{from: -1, to: 2, expected: strings.Join(lines[:2], "\n")},
+		{from: 0, to: len(lines), expected: formatted},
+		{from: 3, to: 3, expected: ""},
+		{from: 100, to: 200, expected: ""},
+	}
+	for _, tt := range tests {
+		if page := result.Page(tt.from, tt.to); page != tt.expected {
+			t.Errorf("Page(%d, %d): expected %q, got %q", tt.from, tt.to, tt.expected, page)
+		}
+	}
+}
+
+func TestFormatResultWithPostProcess(t *testing.T) {
+	// The index describes the output after post-processing
+	config := NewConfig(WithPostProcess(func(line string, depth int, path string) string {
+		if path == "a" {
+			return line + "\n  // inserted"
+		}
+		return line
+	}))
+	result, err := NewFormatter(config).FormatResult(`{"a":1,"b":2}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.LineCount() != 5 || result.Page(2, 3) != "  // inserted" {
+		t.Errorf("Unexpected index for:\n%s", result.String())
+	}
+
+	if _, err := NewFormatter(DefaultConfig()).FormatResult(""); err == nil {
+		t.Error("Expected an error for empty input")
+	}
+}
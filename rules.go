@@ -0,0 +1,124 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import "sort"
+
+// compiledRules holds the path patterns of a configuration split into their
+// segments, so that they are compiled once per formatter instead of once per
+// token
+type compiledRules struct {
+	compact  []pathPattern // CompactPaths
+	expanded []pathPattern // ExpandedPaths
+	sorted   []pathPattern // SortedKeys
+	keyOrder []pathPattern // Keys of KeyOrder, in lexicographic order
+	renames  []pathPattern // Keys of KeyRenames, in lexicographic order
+	raw      []pathPattern // RawPaths
+	embedded []pathPattern // EmbeddedJSON
+}
+
+// compileRules compiles the path patterns of the configuration
+func compileRules(config *Config) *compiledRules {
+	return &compiledRules{
+		compact:  compilePatterns(config.CompactPaths),
+		expanded: compilePatterns(config.ExpandedPaths),
+		sorted:   compilePatterns(config.SortedKeys),
+		keyOrder: compilePatterns(sortedPatterns(config.KeyOrder)),
+		renames:  compilePatterns(sortedPatterns(config.KeyRenames)),
+		raw:      compilePatterns(config.RawPaths),
+		embedded: compilePatterns(config.EmbeddedJSON),
+	}
+}
+
+// sortedPatterns returns the keys of a map of patterns in lexicographic order
+func sortedPatterns[V any](rules map[string]V) []string {
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// pathRules returns the compiled path patterns of the configuration,
+// compiling them if the parser was not created by a Formatter
+func (p *TokenParser) pathRules() *compiledRules {
+	if p.rules == nil {
+		p.rules = compileRules(p.config)
+	}
+	return p.rules
+}
+
+// RuleSet is a style sheet that has been parsed and checked, so that a server
+// can validate its rule sets at startup and reuse them for every request.
+type RuleSet struct {
+	options []ConfigOption
+	preset  string
+}
+
+// CompileRules parses and checks a style sheet as ParseStyleSheet does, and
+// also reports a preset name that is neither a preset nor a registered
+// profile. Path patterns of the rules are split into their segments when a
+// configuration using them is frozen, so the frozen configuration returned
+// by Freeze can be cached and shared by the formatters of many requests
+// without compiling the patterns again.
+//
+// Example:
+//
+//	rules, err := CompileRules(data)
+//	if err != nil {
+//	    log.Fatal(err) // at startup, not on the first request
+//	}
+//	frozen := rules.Freeze()
+//	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+//	    formatted, err := frozen.NewFormatter().Format(body)
+//	    // ...
+//	})
+func CompileRules(data []byte) (*RuleSet, error) {
+	options, preset, err := ParseStyleSheet(data)
+	if err != nil {
+		return nil, err
+	}
+	if preset != "" {
+		if _, ok := Preset(preset); !ok {
+			return nil, NewFormatError("unknown preset in style sheet: " + preset)
+		}
+	}
+	return &RuleSet{options: options, preset: preset}, nil
+}
+
+// Options returns the configuration options of the rule set.
+func (rs *RuleSet) Options() []ConfigOption {
+	return append([]ConfigOption(nil), rs.options...)
+}
+
+// Preset returns the name of the preset the rule set selects, or "" if it
+// does not name one.
+func (rs *RuleSet) Preset() string {
+	return rs.preset
+}
+
+// Freeze returns a snapshot of the configuration of the rule set, starting
+// from its preset or the default configuration, with the given options
+// applied on top, and its path patterns compiled.
+func (rs *RuleSet) Freeze(options ...ConfigOption) *FrozenConfig {
+	options = append(rs.Options(), options...)
+	if rs.preset != "" {
+		if config, ok := Preset(rs.preset, options...); ok {
+			return config.Freeze()
+		}
+	}
+	return NewConfig(options...).Freeze()
+}
@@ -0,0 +1,93 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileRules(t *testing.T) {
+	rules, err := CompileRules([]byte(`{
+  "preset": "default",
+  "compactDepth": 0,
+  "rules": [
+    {"path": "", "sort": ["kind", "*"]},
+    {"path": "spec.ports.*", "compact": true},
+    {"path": "metadata.creationTimestamp", "rename": "created"}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("CompileRules failed: %v", err)
+	}
+	if rules.Preset() != "default" {
+		t.Errorf("Preset() = %q, want %q", rules.Preset(), "default")
+	}
+	if len(rules.Options()) != 4 {
+		t.Errorf("Options() returned %d options, want 4", len(rules.Options()))
+	}
+
+	frozen := rules.Freeze(WithIndentSize(4))
+	input := `{"spec":{"ports":[{"port":80}]},"metadata":{"creationTimestamp":"2024"},"kind":"Pod"}`
+	expected := `{
+    "kind": "Pod",
+    "spec": {
+        "ports": [
+            {"port": 80}
+        ]
+    },
+    "metadata": {
+        "created": "2024"
+    }
+}`
+	for i := 0; i < 2; i++ {
+		result, err := frozen.NewFormatter().Format(input)
+		if err != nil {
+			t.Fatalf("Format failed: %v", err)
+		}
+		if result != expected {
+			t.Errorf("Format() =\n%s\nwant\n%s", result, expected)
+		}
+	}
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet string
+		want  string
+	}{
+		{"invalid JSON", `{"rules": [`, "invalid style sheet"},
+		{"missing path", `{"rules": [{"compact": true}]}`, "path is required"},
+		{"unknown preset", `{"preset": "no-such-preset"}`, "unknown preset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileRules([]byte(tt.sheet))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CompileRules() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    []string
+		match   bool
+		rooted  bool
+	}{
+		{"", nil, false, true},
+		{"users.*.email", []string{"users", "0", "email"}, true, true},
+		{"users.*.email", []string{"users", "0"}, false, false},
+		{"users.0", []string{"users", "1"}, false, false},
+	}
+	for _, tt := range tests {
+		compiled := compilePattern(tt.pattern)
+		if got := compiled.match(tt.path); got != tt.match || got != matchPath(tt.pattern, tt.path) {
+			t.Errorf("match(%q, %v) = %v, want %v", tt.pattern, tt.path, got, tt.match)
+		}
+		if got := compiled.matchRooted(tt.path); got != tt.rooted {
+			t.Errorf("matchRooted(%q, %v) = %v, want %v", tt.pattern, tt.path, got, tt.rooted)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// salvagePreviewLength is the number of skipped bytes quoted in a
+// SkippedBytes preview
+const salvagePreviewLength = 16
+
+// SkippedBytes is a range of input that FormatSalvaged left out because it
+// is not part of the JSON value it formatted.
+type SkippedBytes struct {
+	// Offset is the byte offset of the range in the input.
+	Offset int
+
+	// Length is the number of bytes in the range.
+	Length int
+
+	// Preview holds the first bytes of the range.
+	Preview string
+}
+
+// String describes the range with its preview quoted, for example
+// `12 bytes at offset 0: "\x00\x01GET /api"`.
+func (s SkippedBytes) String() string {
+	preview := fmt.Sprintf("%q", s.Preview)
+	if len(s.Preview) < s.Length {
+		preview += "..."
+	}
+	return fmt.Sprintf("%d bytes at offset %d: %s", s.Length, s.Offset, preview)
+}
+
+// FormatSalvaged formats the largest well-formed JSON object or array in
+// input that holds binary garbage or text before or after the document,
+// such as copy-paste accidents and mixed log streams, instead of failing
+// outright. It also returns the ranges of input that were left out, which
+// are empty when the whole input is a JSON document. Whitespace around the
+// value is not reported.
+//
+// An error is returned if the input holds no well-formed object or array,
+// or if the value found cannot be formatted.
+//
+// Example:
+//
+//	formatted, skipped, err := formatter.FormatSalvaged("\x00\x1b[0mresponse: {\"ok\":true} (200)")
+//	for _, s := range skipped {
+//	    log.Printf("skipped %s", s)
+//	}
+//	// skipped 14 bytes at offset 0: "\x00\x1b[0mresponse:"
+//	// skipped 5 bytes at offset 27: "(200)"
+func (f *Formatter) FormatSalvaged(input string) (result string, skipped []SkippedBytes, err error) {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" || json.Valid([]byte(trimmed)) {
+		result, err = f.Format(input)
+		return result, nil, err
+	}
+
+	start, end, ok := largestValue(input)
+	if !ok {
+		return "", nil, f.config.nameError(NewFormatError("malformed JSON: input holds no well-formed object or array"))
+	}
+	if result, err = f.Format(input[start:end]); err != nil {
+		return "", nil, err
+	}
+
+	for _, bounds := range [][2]int{{0, start}, {end, len(input)}} {
+		if s, ok := skippedBytes(input, bounds[0], bounds[1]); ok {
+			skipped = append(skipped, s)
+		}
+	}
+	return result, skipped, nil
+}
+
+// largestValue returns the byte range of the longest object or array in
+// input that decodes as JSON
+func largestValue(input string) (start, end int, ok bool) {
+	for i := 0; i < len(input); i++ {
+		if input[i] != '{' && input[i] != '[' {
+			continue
+		}
+		// No value starting here can be longer than the one found
+		if len(input)-i <= end-start {
+			break
+		}
+		decoder := json.NewDecoder(strings.NewReader(input[i:]))
+		var value json.RawMessage
+		if decoder.Decode(&value) != nil {
+			continue
+		}
+		length := int(decoder.InputOffset())
+		if length > end-start {
+			start, end, ok = i, i+length, true
+		}
+		// Values nested in this one are shorter
+		i += length - 1
+	}
+	return start, end, ok
+}
+
+// skippedBytes describes the range of input from start to end without the
+// whitespace around it, returning false if it is only whitespace
+func skippedBytes(input string, start, end int) (SkippedBytes, bool) {
+	text := strings.TrimRightFunc(input[start:end], unicode.IsSpace)
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		return SkippedBytes{}, false
+	}
+	preview := trimmed
+	if len(preview) > salvagePreviewLength {
+		preview = preview[:salvagePreviewLength]
+	}
+	return SkippedBytes{Offset: start + len(text) - len(trimmed), Length: len(trimmed), Preview: preview}, true
+}
@@ -0,0 +1,99 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatSalvaged(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		skipped  []SkippedBytes
+	}{
+		{
+			name:     "valid document",
+			input:    "  {\"a\":1}\n",
+			expected: "{\n  \"a\": 1\n}",
+		},
+		{
+			name:     "garbage around",
+			input:    "\x00\x1b[0mresponse: {\"ok\":true} (200)",
+			expected: "{\n  \"ok\": true\n}",
+			skipped: []SkippedBytes{
+				{Offset: 0, Length: 14, Preview: "\x00\x1b[0mresponse:"},
+				{Offset: 27, Length: 5, Preview: "(200)"},
+			},
+		},
+		{
+			name:     "largest value wins",
+			input:    "[1] noise {\"a\":[1,2]} \xff\xfe",
+			expected: "{\n  \"a\": [\n    1,\n    2\n  ]\n}",
+			skipped: []SkippedBytes{
+				{Offset: 0, Length: 9, Preview: "[1] noise"},
+				{Offset: 22, Length: 2, Preview: "\xff\xfe"},
+			},
+		},
+		{
+			name:     "truncated value before",
+			input:    "{\"a\":[1,\n[2,3]",
+			expected: "[\n  2,\n  3\n]",
+			skipped: []SkippedBytes{
+				{Offset: 0, Length: 8, Preview: "{\"a\":[1,"},
+			},
+		},
+		{
+			name:     "long garbage",
+			input:    strings.Repeat("#", 40) + "[1]",
+			expected: "[\n  1\n]",
+			skipped: []SkippedBytes{
+				{Offset: 0, Length: 40, Preview: strings.Repeat("#", 16)},
+			},
+		},
+	}
+	formatter := NewFormatter(DefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, skipped, err := formatter.FormatSalvaged(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("Expected skipped %+v, got %+v", tt.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestFormatSalvagedWithoutValue(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+	for _, input := range []string{"\x00\x01binary", "{\"a\":", ""} {
+		if _, _, err := formatter.FormatSalvaged(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+	_, _, err := formatter.FormatSalvaged("\x00\x01binary")
+	if formatErr, ok := err.(*FormatError); !ok || formatErr.Code() != CodeSyntax {
+		t.Errorf("Expected a syntax error, got %v", err)
+	}
+}
+
+func TestSkippedBytesString(t *testing.T) {
+	tests := []struct {
+		skipped  SkippedBytes
+		expected string
+	}{
+		{SkippedBytes{Offset: 27, Length: 5, Preview: "(200)"}, `5 bytes at offset 27: "(200)"`},
+		{SkippedBytes{Offset: 0, Length: 40, Preview: "\x00abc"}, `40 bytes at offset 0: "\x00abc"...`},
+	}
+	for _, tt := range tests {
+		if got := tt.skipped.String(); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
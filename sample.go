@@ -0,0 +1,169 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// WithSampleArray writes arrays with more than n elements as a random sample
+// of n of their elements, in their original order, with a line counting the
+// elements skipped in each gap. Unlike keeping the first elements, a sample
+// shows records from the whole array, such as time-ordered logs whose
+// interesting records are near the end. The sample depends only on seed and
+// the length of the array, so the same input is always sampled the same
+// way. At most n elements of an array are held in memory. A value of 0
+// disables sampling. The output is no longer valid JSON, so use this option
+// for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithSampleArray(3, 1))
+//	// {
+//	//   "events": [
+//	//     … 4 items skipped,
+//	//     5,
+//	//     … 1 item skipped,
+//	//     7,
+//	//     8,
+//	//     … 2 items skipped
+//	//   ]
+//	// }
+func WithSampleArray(n int, seed int64) ConfigOption {
+	return func(c *Config) {
+		if n >= 0 {
+			c.SampleSize = n
+			c.SampleSeed = seed
+		}
+	}
+}
+
+// skippedElements is a token that stands for the elements of an array left
+// out of its sample
+type skippedElements int
+
+// text returns the line that replaces the elements
+func (n skippedElements) text() string {
+	if n == 1 {
+		return "… 1 item skipped"
+	}
+	return "… " + strconv.Itoa(int(n)) + " items skipped"
+}
+
+// sampleState buffers a sample of the elements of an array as it is read
+type sampleState struct {
+	random   *rand.Rand
+	depth    int              // Nesting depth within the array
+	count    int              // Elements read so far
+	element  sampledElement   // Element being read
+	elements []sampledElement // Elements in the sample, in no particular order
+}
+
+// sampledElement is an element of a sampled array with its index
+type sampledElement struct {
+	index  int
+	tokens []json.Token
+}
+
+// startSample begins sampling the array if the token opens an array
+func (p *TokenParser) startSample(token json.Token) bool {
+	if p.skipSample {
+		p.skipSample = false
+		return false
+	}
+	if p.config.SampleSize == 0 || p.config.StrictJSON || token != json.Delim('[') {
+		return false
+	}
+	p.sample = &sampleState{random: rand.New(rand.NewSource(p.config.SampleSeed)), depth: 1}
+	return true
+}
+
+// sampleToken adds an array token to the sample, and replays the sample
+// once the array is complete
+func (p *TokenParser) sampleToken(token json.Token) error {
+	sample := p.sample
+	if sample.depth == 1 {
+		if token == json.Delim(']') {
+			p.sample = nil
+			return p.replaySample(sample)
+		}
+		sample.element = sampledElement{index: sample.count}
+		sample.count++
+	}
+	sample.element.tokens = append(sample.element.tokens, token)
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			sample.depth++
+		} else {
+			sample.depth--
+		}
+	}
+	if sample.depth > 1 {
+		return nil
+	}
+
+	// Reservoir sampling keeps each element read so far with equal probability
+	if len(sample.elements) < p.config.SampleSize {
+		sample.elements = append(sample.elements, sample.element)
+	} else if i := sample.random.Intn(sample.count); i < p.config.SampleSize {
+		sample.elements[i] = sample.element
+	}
+	return nil
+}
+
+// replaySample replays the array with the elements of the sample in their
+// original order, and the count of the elements skipped in each gap
+func (p *TokenParser) replaySample(sample *sampleState) error {
+	sort.Slice(sample.elements, func(i, j int) bool {
+		return sample.elements[i].index < sample.elements[j].index
+	})
+	tokens := []json.Token{json.Delim('[')}
+	next := 0
+	for _, element := range sample.elements {
+		if element.index > next {
+			tokens = append(tokens, skippedElements(element.index-next))
+		}
+		tokens = append(tokens, element.tokens...)
+		next = element.index + 1
+	}
+	if sample.count > next {
+		tokens = append(tokens, skippedElements(sample.count-next))
+	}
+	tokens = append(tokens, json.Delim(']'))
+
+	// The array was already checked for records before it was sampled
+	p.skipRecords = true
+	p.skipSample = true
+	return p.replayTokens(tokens)
+}
+
+// handleSkippedElements writes the line counting the elements skipped by the sample
+func (p *TokenParser) handleSkippedElements(n skippedElements) error {
+	if err := p.writeElementPrefix(); err != nil {
+		return err
+	}
+	if _, err := p.builder.WriteString(n.text()); err != nil {
+		return WrapFormatError("failed to write skipped elements", err)
+	}
+	p.isFirstElement = false
+	if len(p.path) == len(p.inArray) && p.isInArray() {
+		// Later elements keep their index in the input
+		p.path[len(p.path)-1].index += int(n)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestSampleArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "scalars",
+			input:   `{"events":[1,2,3,4,5,6,7,8,9,10],"small":[1,2]}`,
+			options: []ConfigOption{WithSampleArray(3, 1)},
+			expected: `{
+  "events": [
+    … 4 items skipped,
+    5,
+    … 1 item skipped,
+    7,
+    8,
+    … 2 items skipped
+  ],
+  "small": [
+    1,
+    2
+  ]
+}`,
+		},
+		{
+			name:    "objects",
+			input:   `[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":6},{"id":7},{"id":8},{"id":9},{"id":10}]`,
+			options: []ConfigOption{WithSampleArray(3, 2)},
+			expected: `[
+  … 3 items skipped,
+  {
+    "id": 4
+  },
+  {
+    "id": 5
+  },
+  … 4 items skipped,
+  {
+    "id": 10
+  }
+]`,
+		},
+		{
+			name:     "compact",
+			input:    `[{"id":1},{"id":2},{"id":3},{"id":4},{"id":5},{"id":6},{"id":7},{"id":8},{"id":9},{"id":10}]`,
+			options:  []ConfigOption{WithSampleArray(3, 42), WithCompactDepth(1)},
+			expected: `[{"id": 1}, … 4 items skipped, {"id": 6}, {"id": 7}, … 3 items skipped]`,
+		},
+		{
+			name:    "nested",
+			input:   `{"a":[[1,2,3,4,5],[6,7,8,9,10],[1],[2]]}`,
+			options: []ConfigOption{WithSampleArray(2, 7), WithCompactDepth(0)},
+			expected: `{
+  "a": [
+    [
+      1,
+      2,
+      … 3 items skipped
+    ],
+    [
+      6,
+      7,
+      … 3 items skipped
+    ],
+    … 2 items skipped
+  ]
+}`,
+		},
+		{
+			name:     "strict JSON",
+			input:    `[1,2,3]`,
+			options:  []ConfigOption{WithSampleArray(1, 1), WithStrictJSON(), WithCompactDepth(0)},
+			expected: "[\n  1,\n  2,\n  3\n]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
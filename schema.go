@@ -0,0 +1,282 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Schema is the subset of JSON Schema used by schema-aware display options:
+// object properties, required properties, default values, and array items.
+// Other keywords, including $ref, are ignored.
+type Schema struct {
+	// Properties maps property names to their schemas.
+	Properties map[string]*Schema
+
+	// PropertyOrder lists the property names in the order of the schema document.
+	PropertyOrder []string
+
+	// Required lists the names of required properties.
+	Required []string
+
+	// Default is the default value, decoded like encoding/json decodes into an
+	// interface{}. It is only meaningful when HasDefault is true.
+	Default interface{}
+
+	// HasDefault reports whether the schema has a "default" keyword.
+	HasDefault bool
+
+	// Items is the schema of array elements.
+	Items *Schema
+}
+
+// ParseSchema parses a JSON Schema document.
+//
+// Example:
+//
+//	schema, err := ParseSchema([]byte(`{
+//	    "properties": {"name": {}, "email": {}},
+//	    "required": ["name", "email"]
+//	}`))
+func ParseSchema(data []byte) (*Schema, error) {
+	var raw struct {
+		Properties json.RawMessage `json:"properties"`
+		Required   []string        `json:"required"`
+		Default    json.RawMessage `json:"default"`
+		Items      json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, WrapFormatError("invalid JSON schema", err)
+	}
+
+	schema := &Schema{Required: raw.Required}
+	if raw.Default != nil {
+		if err := json.Unmarshal(raw.Default, &schema.Default); err != nil {
+			return nil, WrapFormatError("invalid JSON schema default", err)
+		}
+		schema.HasDefault = true
+	}
+	if raw.Items != nil && raw.Items[0] == '{' {
+		items, err := ParseSchema(raw.Items)
+		if err != nil {
+			return nil, err
+		}
+		schema.Items = items
+	}
+	if raw.Properties != nil {
+		if err := schema.parseProperties(raw.Properties); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// parseProperties parses the "properties" keyword, keeping the property order
+func (s *Schema) parseProperties(data json.RawMessage) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return NewFormatError("invalid JSON schema: properties must be an object")
+	}
+	s.Properties = make(map[string]*Schema)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return WrapFormatError("invalid JSON schema", err)
+		}
+		name := token.(string)
+		var property json.RawMessage
+		if err := decoder.Decode(&property); err != nil {
+			return WrapFormatError(fmt.Sprintf("invalid JSON schema for property %q", name), err)
+		}
+		propertySchema, err := ParseSchema(property)
+		if err != nil {
+			return err
+		}
+		if _, exists := s.Properties[name]; !exists {
+			s.PropertyOrder = append(s.PropertyOrder, name)
+		}
+		s.Properties[name] = propertySchema
+	}
+	return nil
+}
+
+// clone returns a deep copy of the schema, or nil for a nil schema
+func (s *Schema) clone() *Schema {
+	return s.cloneWith(make(map[*Schema]*Schema))
+}
+
+// cloneWith returns a deep copy of the schema, reusing the copies of the
+// schemas copied before so that schemas referring to themselves are copied
+func (s *Schema) cloneWith(copies map[*Schema]*Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if copied, ok := copies[s]; ok {
+		return copied
+	}
+	copied := &Schema{
+		PropertyOrder: append([]string(nil), s.PropertyOrder...),
+		Required:      append([]string(nil), s.Required...),
+		Default:       cloneJSONValue(s.Default),
+		HasDefault:    s.HasDefault,
+	}
+	copies[s] = copied
+	if s.Properties != nil {
+		copied.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			copied.Properties[name] = property.cloneWith(copies)
+		}
+	}
+	copied.Items = s.Items.cloneWith(copies)
+	return copied
+}
+
+// cloneJSONValue returns a deep copy of a value decoded by encoding/json
+func cloneJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, member := range v {
+			copied[key] = cloneJSONValue(member)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, element := range v {
+			copied[i] = cloneJSONValue(element)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// WithSchema sets the schema used by schema-aware options such as
+// WithMissingRequired. The schema itself does not change the output.
+//
+// Example:
+//
+//	schema, _ := ParseSchema(schemaJSON)
+//	config := NewConfig(WithSchema(schema), WithMissingRequired())
+func WithSchema(schema *Schema) ConfigOption {
+	return func(c *Config) {
+		c.Schema = schema
+	}
+}
+
+// WithMissingRequired shows required properties that are missing from an
+// object as comments at the end of the object, where they would be added
+// (`/* missing: "email" */`). Comments make the output invalid JSON, so use
+// this option for display only. It requires WithSchema.
+//
+// Missing required properties are also reported as WarningMissingRequired
+// warnings by FormatWithWarnings whenever a schema is set.
+func WithMissingRequired() ConfigOption {
+	return func(c *Config) {
+		c.ShowMissingRequired = true
+	}
+}
+
+// WarningMissingRequired reports a required property that is missing from an object.
+const WarningMissingRequired WarningCode = "missing_required"
+
+// childSchema returns the schema of a container that is opened at the current position
+func (p *TokenParser) childSchema() *Schema {
+	if len(p.path) == 0 {
+		return p.config.Schema
+	}
+	parent := p.path[len(p.path)-1].schema
+	if parent == nil {
+		return nil
+	}
+	if p.isInArray() {
+		return parent.Items
+	}
+	return parent.Properties[p.path[len(p.path)-1].key]
+}
+
+// recordKey remembers a key of the current object when something needs the set of keys
+func (p *TokenParser) recordKey(key string) {
+	if len(p.path) == 0 || len(p.path) != len(p.inArray) {
+		return
+	}
+	level := &p.path[len(p.path)-1]
+	if level.schema == nil && !p.collectingWarnings {
+		return
+	}
+	if level.keys == nil {
+		level.keys = make(map[string]bool)
+	}
+	level.keys[key] = true
+}
+
+// missingRequired returns the required properties not seen in the current object
+func (p *TokenParser) missingRequired() []string {
+	if len(p.path) == 0 || len(p.path) != len(p.inArray) {
+		return nil
+	}
+	level := p.path[len(p.path)-1]
+	if level.schema == nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range level.schema.Required {
+		if !level.keys[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// warnMissingRequired records a warning for every missing required property
+// of the object that is about to be closed
+func (p *TokenParser) warnMissingRequired() {
+	missing := p.missingRequired()
+	if len(missing) == 0 {
+		return
+	}
+	// The last segment of the value path is the most recent key of the object
+	path := p.valuePath()
+	objectPath := path[:len(path)-1]
+	for _, name := range missing {
+		propertyPath := append(append([]string(nil), objectPath...), name)
+		p.warn(WarningMissingRequired, propertyPath, "required property %q is missing", name)
+	}
+}
+
+// writeMissingRequired writes a comment for every missing required property
+// before the current object is closed, and reports whether it wrote any
+func (p *TokenParser) writeMissingRequired(compact bool) (bool, error) {
+	missing := p.missingRequired()
+	for _, name := range missing {
+		escapedName, err := p.escapeString(name)
+		if err != nil {
+			return false, WrapFormatError("failed to escape property name", err)
+		}
+		if compact {
+			if _, err := p.builder.WriteString(" "); err != nil {
+				return false, WrapFormatError("failed to write space", err)
+			}
+		} else if err := p.writeNewlineAndIndent(); err != nil {
+			return false, WrapFormatError("failed to write newline and indent", err)
+		}
+		if _, err := p.builder.WriteString(`/* missing: "` + escapedName + `" */`); err != nil {
+			return false, WrapFormatError("failed to write missing property comment", err)
+		}
+	}
+	return len(missing) > 0, nil
+}
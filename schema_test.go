@@ -0,0 +1,152 @@
+package jsonformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string"},
+    "email": {"type": "string"},
+    "servers": {
+      "type": "array",
+      "items": {
+        "properties": {"host": {}, "port": {"default": 80}},
+        "required": ["host", "port"]
+      }
+    }
+  },
+  "required": ["name", "email"]
+}`
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(schema.PropertyOrder, []string{"name", "email", "servers"}) {
+		t.Errorf("Unexpected property order: %v", schema.PropertyOrder)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"name", "email"}) {
+		t.Errorf("Unexpected required properties: %v", schema.Required)
+	}
+	items := schema.Properties["servers"].Items
+	if items == nil {
+		t.Fatal("Expected items schema")
+	}
+	port := items.Properties["port"]
+	if !port.HasDefault || port.Default != float64(80) {
+		t.Errorf("Expected default 80, got %v (%v)", port.Default, port.HasDefault)
+	}
+	if items.Properties["host"].HasDefault {
+		t.Error("Expected no default for host")
+	}
+
+	for _, invalid := range []string{`{`, `{"properties":[]}`, `{"properties":{"a":{"default":}}}`} {
+		if _, err := ParseSchema([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestMissingRequired(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	input := `{"name":"x","servers":[{"host":"a"},{"port":1,"host":"b"},{}]}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "schema alone does not change the output",
+			options: []ConfigOption{WithSchema(schema)},
+			expected: `{
+  "name": "x",
+  "servers": [
+    {"host": "a"},
+    {"port": 1, "host": "b"},
+    {}
+  ]
+}`,
+		},
+		{
+			name:    "compact and expanded objects",
+			options: []ConfigOption{WithSchema(schema), WithMissingRequired()},
+			expected: `{
+  "name": "x",
+  "servers": [
+    {"host": "a" /* missing: "port" */},
+    {"port": 1, "host": "b"},
+    { /* missing: "host" */ /* missing: "port" */}
+  ]
+  /* missing: "email" */
+}`,
+		},
+		{
+			name:    "elements expanded by the width limit",
+			options: []ConfigOption{WithSchema(schema), WithMissingRequired(), WithMaxWidth(30)},
+			expected: `{
+  "name": "x",
+  "servers": [
+    {
+      "host": "a"
+      /* missing: "port" */
+    },
+    {"port": 1, "host": "b"},
+    {
+      /* missing: "host" */
+      /* missing: "port" */
+    }
+  ]
+  /* missing: "email" */
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMissingRequiredWarnings(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := NewConfig(WithSchema(schema), WithMaxWidth(20))
+	_, warnings, err := NewFormatter(config).FormatWithWarnings(`{"name":"x","servers":[{"host":"a long host name"},{}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, w := range warnings {
+		if w.Code != WarningMissingRequired {
+			t.Errorf("Unexpected warning: %v", w)
+		}
+		got = append(got, w.String())
+	}
+	expected := []string{
+		`servers.0.port: required property "port" is missing`,
+		`servers.1.host: required property "host" is missing`,
+		`servers.1.port: required property "port" is missing`,
+		`email: required property "email" is missing`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected warnings:\n%v\n\nGot:\n%v", expected, got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordSeparator is the ASCII RS character that starts every JSON text in an
+// RFC 7464 JSON text sequence (application/json-seq).
+const RecordSeparator = '\x1e'
+
+// IsJSONSeq reports whether the input looks like an RFC 7464 JSON text sequence,
+// that is, whether its first non-whitespace character is RecordSeparator.
+func IsJSONSeq(input []byte) bool {
+	for _, b := range input {
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case RecordSeparator:
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// FormatSeq formats an RFC 7464 JSON text sequence (application/json-seq).
+// Every record of the input is formatted separately, and the output is again a
+// JSON text sequence: each formatted record is preceded by RecordSeparator and
+// followed by a line feed. Empty records are skipped.
+//
+// If a record cannot be formatted, the returned FormatError names the record
+// number (starting at 1).
+//
+// Example:
+//
+//	formatted, err := formatter.FormatSeq("\x1e{\"a\":1}\n\x1e[1,2]\n")
+func (f *Formatter) FormatSeq(input string) (string, error) {
+	var output strings.Builder
+	if err := f.FormatSeqTo(&output, strings.NewReader(input)); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// FormatSeqTo reads an RFC 7464 JSON text sequence from r and writes the
+// formatted sequence to w, one record at a time. Records are read up to the next
+// RecordSeparator, so long-running streams such as logs are formatted as they
+// arrive.
+//
+// If an error is returned, the records before the failing one have already been
+// written to w.
+func (f *Formatter) FormatSeqTo(w io.Writer, r io.Reader) error {
+	if w == nil {
+		return NewFormatError("output writer cannot be nil")
+	}
+	if r == nil {
+		return NewFormatError("input reader cannot be nil")
+	}
+
+	reader := bufio.NewReader(r)
+	writer := newFlushWriter(f.config.Faults.writer(w), f.config.FlushPolicy, FlushPolicy{Records: true})
+	record := 0
+	for {
+		text, readErr := reader.ReadString(RecordSeparator)
+		if readErr != nil && readErr != io.EOF {
+			return WrapFormatError("failed to read input", readErr)
+		}
+		text = strings.TrimSuffix(text, string(RecordSeparator))
+
+		if strings.TrimSpace(text) != "" {
+			record++
+			formatted, err := f.Format(text)
+			if err != nil {
+				// The filename moves to the front of the message
+				var formatErr *FormatError
+				if errors.As(err, &formatErr) {
+					formatErr.File = ""
+				}
+				return f.config.nameError(WrapFormatError(fmt.Sprintf("invalid JSON text in record %d", record), err))
+			}
+			if _, err := writer.WriteString(string(RecordSeparator) + formatted + "\n"); err != nil {
+				return WrapFormatError("failed to write output", err)
+			}
+			// By default, flush per record so that streamed records are visible immediately
+			if err := writer.endRecord(); err != nil {
+				return WrapFormatError("failed to write output", err)
+			}
+		}
+
+		if readErr == io.EOF {
+			if err := writer.Flush(); err != nil {
+				return WrapFormatError("failed to write output", err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jsonformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatSeq(t *testing.T) {
+	formatter := NewFormatter(DefaultConfig())
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "records",
+			input:    "\x1e{\"a\":1}\n\x1e[1,2]\n\x1e\"text\"\n",
+			expected: "\x1e{\n  \"a\": 1\n}\n\x1e[\n  1,\n  2\n]\n\x1e\"text\"\n",
+		},
+		{
+			name:     "empty records and missing line feeds",
+			input:    "\x1e\x1e  \n\x1e{\"b\":true}\x1enull",
+			expected: "\x1e{\n  \"b\": true\n}\n\x1enull\n",
+		},
+		{
+			name:     "empty sequence",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatter.FormatSeq(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%q\n\nGot:\n%q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatSeqError(t *testing.T) {
+	_, err := NewFormatter(DefaultConfig()).FormatSeq("\x1e{\"a\":1}\n\x1e{\"b\":\n\x1e[]\n")
+	if err == nil {
+		t.Fatal("Expected error for truncated record")
+	}
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("Expected FormatError, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("Expected error to name record 2, got: %v", err)
+	}
+}
+
+func TestFormatSeqTo(t *testing.T) {
+	var output strings.Builder
+	err := NewFormatter(DefaultConfig()).FormatSeqTo(&output, strings.NewReader("\x1e{\"a\":1}\n\x1e{\"a\":2}\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1e{\n  \"a\": 1\n}\n\x1e{\n  \"a\": 2\n}\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%q\n\nGot:\n%q", expected, output.String())
+	}
+
+	if err := NewFormatter(DefaultConfig()).FormatSeqTo(nil, strings.NewReader("")); err == nil {
+		t.Error("Expected error for nil writer")
+	}
+}
+
+func TestIsJSONSeq(t *testing.T) {
+	tests := map[string]bool{
+		"\x1e{}\n":  true,
+		"\n \x1e[]": true,
+		"{}":        false,
+		"":          false,
+	}
+	for input, expected := range tests {
+		if got := IsJSONSeq([]byte(input)); got != expected {
+			t.Errorf("IsJSONSeq(%q) = %v, expected %v", input, got, expected)
+		}
+	}
+}
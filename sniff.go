@@ -0,0 +1,352 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sniffLimit is the number of bytes of the input examined by Sniff
+const sniffLimit = 64 << 10
+
+// Kind is the kind of content reported by Sniff.
+type Kind int
+
+const (
+	// KindNotJSON is content that is neither JSON nor JSON5.
+	KindNotJSON Kind = iota
+	// KindObject is a JSON document whose value is an object.
+	KindObject
+	// KindArray is a JSON document whose value is an array.
+	KindArray
+	// KindScalar is a JSON document whose value is a string, number,
+	// boolean, or null.
+	KindScalar
+	// KindNDJSON is newline-delimited JSON: several JSON values, each
+	// starting on a new line, such as log records.
+	KindNDJSON
+	// KindJSONSeq is an RFC 7464 JSON text sequence, formatted with FormatSeq.
+	KindJSONSeq
+	// KindJSON5 is a JSON5 document, which uses comments, single-quoted
+	// strings, unquoted keys, trailing commas, or other JSON5 syntax.
+	KindJSON5
+)
+
+// String returns the name of the kind, such as "object" or "NDJSON".
+func (k Kind) String() string {
+	switch k {
+	case KindObject:
+		return "object"
+	case KindArray:
+		return "array"
+	case KindScalar:
+		return "scalar"
+	case KindNDJSON:
+		return "NDJSON"
+	case KindJSONSeq:
+		return "JSON text sequence"
+	case KindJSON5:
+		return "JSON5"
+	default:
+		return "not JSON"
+	}
+}
+
+// Sniff guesses the kind of content of a blob of unknown provenance, so it
+// can be routed to the right formatting mode: Format for JSON documents,
+// FormatLog for NDJSON, and FormatSeq for JSON text sequences. Only the
+// first 64 KiB are examined, and a value cut off there counts as valid up
+// to the cut. Input with a UTF-16 or UTF-32 byte order mark is decoded
+// first.
+//
+// The content is JSON5 only if it is not valid JSON and becomes valid JSON
+// once its JSON5 syntax is rewritten. For KindNotJSON, the error describes
+// the first syntax error of the content read as JSON.
+//
+// Example:
+//
+//	kind, err := jsonformat.Sniff(body)
+//	switch kind {
+//	case jsonformat.KindNDJSON:
+//	    formatted, err = formatter.FormatLog(string(body))
+//	case jsonformat.KindObject, jsonformat.KindArray, jsonformat.KindScalar:
+//	    formatted, err = formatter.Format(string(body))
+//	}
+func Sniff(data []byte) (Kind, error) {
+	truncated := len(data) > sniffLimit
+	if truncated {
+		// The cut keeps whole UTF-16 and UTF-32 code units
+		data = data[:sniffLimit]
+	}
+	sample, err := DefaultConfig().decodeInput(string(data))
+	if err != nil {
+		return KindNotJSON, err
+	}
+	sample = strings.TrimLeft(sample, " \t\r\n")
+	if sample == "" {
+		return KindNotJSON, NewFormatError("input contains no valid JSON tokens")
+	}
+	if sample[0] == RecordSeparator {
+		return KindJSONSeq, nil
+	}
+
+	kind, err := sniffJSON(sample, truncated)
+	if err == nil {
+		return kind, nil
+	}
+	if converted, ok := json5ToJSON(sample); ok {
+		if _, json5Err := sniffJSON(converted, truncated); json5Err == nil {
+			return KindJSON5, nil
+		}
+	}
+	return KindNotJSON, err
+}
+
+// sniffJSON returns the kind of JSON content, or an error if the sample is
+// not JSON. With truncated set, a value cut off at the end is accepted.
+func sniffJSON(sample string, truncated bool) (Kind, error) {
+	decoder := json.NewDecoder(strings.NewReader(sample))
+	kind := KindNotJSON
+	depth := 0
+	records := 0
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if truncated && records > 0 && (errors.Is(err, io.ErrUnexpectedEOF) || strings.HasPrefix(err.Error(), "unexpected EOF")) {
+				break
+			}
+			return KindNotJSON, WrapFormatErrorWithPosition("invalid JSON input", int(decoder.InputOffset()), err)
+		}
+
+		if depth == 0 {
+			records++
+			if records > 1 {
+				// Values after the first must start on a new line
+				gap := sample[start:]
+				gap = gap[:len(gap)-len(strings.TrimLeft(gap, " \t\r\n"))]
+				if !strings.Contains(gap, "\n") {
+					return KindNotJSON, NewFormatErrorWithPosition("invalid JSON input: several values on one line", int(start)+len(gap))
+				}
+				kind = KindNDJSON
+			} else {
+				switch token {
+				case json.Delim('{'):
+					kind = KindObject
+				case json.Delim('['):
+					kind = KindArray
+				default:
+					kind = KindScalar
+				}
+			}
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	if depth > 0 && !truncated {
+		return KindNotJSON, NewFormatError("malformed JSON: unclosed objects or arrays")
+	}
+	return kind, nil
+}
+
+// json5ToJSON rewrites the JSON5 syntax of s as JSON: comments are removed,
+// single-quoted strings and unquoted keys are double-quoted, trailing
+// commas are dropped, and hexadecimal numbers, signs, and decimal points are
+// written as JSON numbers. Infinity and NaN become 0, as they cannot be
+// written in JSON. It reports whether any JSON5 syntax was found.
+func json5ToJSON(s string) (string, bool) {
+	var out strings.Builder
+	found := false
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end, text := json5String(s[i:])
+			if c == '\'' || strings.Contains(s[i:i+end], "\\\n") {
+				found = true
+			}
+			quoted, _ := json.Marshal(text)
+			out.Write(quoted)
+			i += end
+		case strings.HasPrefix(s[i:], "//"), strings.HasPrefix(s[i:], "/*"):
+			found = true
+			i += json5CommentEnd(s[i:])
+			out.WriteByte(' ')
+		case c == ',':
+			next := json5SkipSpace(s[i+1:])
+			if next < len(s[i+1:]) && (s[i+1+next] == '}' || s[i+1+next] == ']') {
+				found = true
+			} else {
+				out.WriteByte(c)
+			}
+			i++
+		case c == '+' && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '.' || s[i+1] == 'I'):
+			found = true
+			i++
+		case isDigit(c) || c == '.' || c == '-' && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '.'):
+			end, number, json5 := json5Number(s[i:])
+			found = found || json5
+			out.WriteString(number)
+			i += end
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i
+			for end < len(s) && (s[end] == '_' || s[end] == '$' || isDigit(s[end]) || s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+				end++
+			}
+			word := s[i:end]
+			next := json5SkipSpace(s[end:])
+			switch {
+			case end+next < len(s) && s[end+next] == ':':
+				found = true
+				out.WriteString(strconv.Quote(word))
+			case word == "Infinity" || word == "NaN":
+				found = true
+				out.WriteString("0")
+			default:
+				out.WriteString(word)
+			}
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), found
+}
+
+// json5String returns the length of the quoted string at the start of s and
+// its text
+func json5String(s string) (int, string) {
+	quote := s[0]
+	var text strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == quote:
+			return i + 1, text.String()
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch escaped := s[i]; escaped {
+			case '\n':
+				// A line continuation
+			case 'n':
+				text.WriteByte('\n')
+			case 't':
+				text.WriteByte('\t')
+			case 'r':
+				text.WriteByte('\r')
+			case 'b':
+				text.WriteByte('\b')
+			case 'f':
+				text.WriteByte('\f')
+			case 'u':
+				if i+4 < len(s) {
+					if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+						text.WriteRune(rune(r))
+						i += 4
+					}
+				}
+			default:
+				text.WriteByte(escaped)
+			}
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return len(s), text.String()
+}
+
+// json5CommentEnd returns the length of the comment at the start of s
+func json5CommentEnd(s string) int {
+	if strings.HasPrefix(s, "//") {
+		if end := strings.IndexByte(s, '\n'); end >= 0 {
+			return end
+		}
+		return len(s)
+	}
+	if end := strings.Index(s[2:], "*/"); end >= 0 {
+		return end + 4
+	}
+	return len(s)
+}
+
+// json5SkipSpace returns the length of the whitespace and comments at the start of s
+func json5SkipSpace(s string) int {
+	i := 0
+	for i < len(s) {
+		switch {
+		case s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n':
+			i++
+		case strings.HasPrefix(s[i:], "//"), strings.HasPrefix(s[i:], "/*"):
+			i += json5CommentEnd(s[i:])
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// json5Number returns the length of the number at the start of s, the
+// number written as JSON, and whether it used JSON5 syntax
+func json5Number(s string) (int, string, bool) {
+	sign := ""
+	i := 0
+	if s[0] == '-' {
+		sign = "-"
+		i++
+	}
+	if strings.HasPrefix(s[i:], "0x") || strings.HasPrefix(s[i:], "0X") {
+		end := i + 2
+		for end < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+			end++
+		}
+		value, err := strconv.ParseUint(s[i+2:end], 16, 64)
+		if err != nil {
+			return end, s[:end], false
+		}
+		return end, sign + strconv.FormatUint(value, 10), true
+	}
+
+	end := i
+	for end < len(s) && (isDigit(s[end]) || strings.IndexByte(".eE+-", s[end]) >= 0) {
+		end++
+	}
+	number := s[i:end]
+	json5 := false
+	if strings.HasPrefix(number, ".") {
+		number = "0" + number
+		json5 = true
+	}
+	if strings.HasSuffix(number, ".") || strings.Contains(number, ".e") || strings.Contains(number, ".E") {
+		number = strings.Replace(number, ".", ".0", 1)
+		json5 = true
+	}
+	return end, sign + number, json5
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,66 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Kind
+	}{
+		{"object", `{"a":1}`, KindObject},
+		{"array", " [1,2] \n", KindArray},
+		{"scalar", `"text"`, KindScalar},
+		{"byte order mark", "\xEF\xBB\xBF{}", KindObject},
+		{"UTF-16", "\xFF\xFE[\x00]\x00", KindArray},
+		{"NDJSON", "{\"a\":1}\n\n{\"a\":2}\n", KindNDJSON},
+		{"NDJSON of pretty documents", "{\n  \"a\": 1\n}\n[\n  2\n]\n", KindNDJSON},
+		{"JSON text sequence", "\x1e{}\n\x1e[]\n", KindJSONSeq},
+		{"JSON5 keys", `{a: 1}`, KindJSON5},
+		{"JSON5 syntax", "// config\n{'a': 0x1F, b: [.5, +1, Infinity,],}", KindJSON5},
+		{"JSON5 block comment", "/* c */ [1]", KindJSON5},
+		{"truncated document", `[{"a":1},` + strings.Repeat(`{"b":2},`, sniffLimit/8) + `{"c":3}]`, KindArray},
+		{"truncated NDJSON", strings.Repeat("{\"a\":\"aaaaaaaa\"}\n", sniffLimit/16+1), KindNDJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, err := Sniff([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if kind != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, kind)
+			}
+		})
+	}
+}
+
+func TestSniffNotJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"text", "hello world", "invalid JSON input"},
+		{"empty", " \n", "input contains no valid JSON tokens"},
+		{"unclosed", `{"a":`, "malformed JSON: unclosed objects or arrays"},
+		{"values on one line", `{"a":1} {"b":2}`, "invalid JSON input: several values on one line"},
+		{"YAML", "a: 1\nb: [1, 2]\n", "invalid JSON input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, err := Sniff([]byte(tt.input))
+			if kind != KindNotJSON {
+				t.Errorf("Expected %s, got %s", KindNotJSON, kind)
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
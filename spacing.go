@@ -0,0 +1,113 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"path"
+)
+
+// WithBlankLineBetweenTopLevelKeys separates the members of the root object
+// with blank lines, giving large configuration files visual breathing room
+// in the way gofmt separates declarations.
+//
+// Example:
+//
+//	config := NewConfig(WithBlankLineBetweenTopLevelKeys())
+//	// {
+//	//   "server": {...},
+//	//
+//	//   "database": {...}
+//	// }
+func WithBlankLineBetweenTopLevelKeys() ConfigOption {
+	return func(c *Config) {
+		c.BlankLineBetweenTopLevelKeys = true
+	}
+}
+
+// WithBlankLineBetweenLargeElements separates an element of the root array
+// from the previous one with a blank line when the previous element took at
+// least size bytes of output, so long records stand apart while short ones
+// stay together. Values below 1 are ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithBlankLineBetweenLargeElements(200))
+func WithBlankLineBetweenLargeElements(size int) ConfigOption {
+	return func(c *Config) {
+		if size > 0 {
+			c.BlankLineElementSize = size
+		}
+	}
+}
+
+// needsBlankLine reports whether a blank line goes before the next
+// expanded member or element of the root container
+func (p *TokenParser) needsBlankLine() bool {
+	if p.depth != 1 || p.isFirstElement {
+		return false
+	}
+	if p.isInArray() {
+		size := p.config.BlankLineElementSize
+		return size > 0 && p.outputOffset()-p.topLevelStart >= size
+	}
+	return p.config.BlankLineBetweenTopLevelKeys
+}
+
+// WithSectionComments inserts a banner comment before each root object key
+// that matches one of the patterns, to help navigate very long configurations.
+// Patterns use the syntax of path.Match. Comments make the output invalid
+// JSON, so use this option for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithSectionComments("services", "db*"))
+//	// {
+//	//   // ──── services ────
+//	//   "services": {...},
+//	//   // ──── database ────
+//	//   "database": {...}
+//	// }
+func WithSectionComments(patterns ...string) ConfigOption {
+	return func(c *Config) {
+		c.SectionComments = append(c.SectionComments, patterns...)
+	}
+}
+
+// writeSectionComment writes a banner line before a root object key that
+// matches one of the section comment patterns
+func (p *TokenParser) writeSectionComment() error {
+	if len(p.config.SectionComments) == 0 || p.config.StrictJSON || p.depth != 1 || p.isInArray() || !p.expectingKey || len(p.path) != 1 {
+		return nil
+	}
+	key := p.path[0].key
+	for _, pattern := range p.config.SectionComments {
+		if matched, _ := path.Match(pattern, key); !matched {
+			continue
+		}
+		if err := p.writeNewlineAndIndent(); err != nil {
+			return WrapFormatError("failed to write newline and indent", err)
+		}
+		// Escaping keeps keys with line breaks on the comment line
+		escapedKey, err := p.escapeString(key)
+		if err != nil {
+			return WrapFormatError("failed to escape section key", err)
+		}
+		if _, err := p.builder.WriteString("// ──── " + escapedKey + " ────"); err != nil {
+			return WrapFormatError("failed to write section comment", err)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlankLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "between top-level keys",
+			input:   `{"server":{"port":80},"tags":["a"],"debug":true}`,
+			options: []ConfigOption{WithBlankLineBetweenTopLevelKeys()},
+			expected: `{
+  "server": {
+    "port": 80
+  },
+
+  "tags": [
+    "a"
+  ],
+
+  "debug": true
+}`,
+		},
+		{
+			name:    "top-level keys with leading commas",
+			input:   `{"a":1,"b":2}`,
+			options: []ConfigOption{WithBlankLineBetweenTopLevelKeys(), WithLeadingCommas()},
+			expected: `{
+  "a": 1
+
+  , "b": 2
+}`,
+		},
+		{
+			name:    "nested objects are not separated",
+			input:   `[{"a":1,"b":2}]`,
+			options: []ConfigOption{WithBlankLineBetweenTopLevelKeys(), WithCompactDepth(0)},
+			expected: `[
+  {
+    "a": 1,
+    "b": 2
+  }
+]`,
+		},
+		{
+			name:    "after large array elements",
+			input:   `[{"id":1,"name":"Alice"},{"id":2},{"id":3},4]`,
+			options: []ConfigOption{WithBlankLineBetweenLargeElements(30)},
+			expected: `[
+  {
+    "id": 1,
+    "name": "Alice"
+  },
+
+  {
+    "id": 2
+  },
+  {
+    "id": 3
+  },
+  4
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWithBlankLineBetweenLargeElementsIgnoresInvalidSize(t *testing.T) {
+	config := NewConfig(WithBlankLineBetweenLargeElements(100), WithBlankLineBetweenLargeElements(-1))
+	if config.BlankLineElementSize != 100 {
+		t.Errorf("Expected BlankLineElementSize 100, got %d", config.BlankLineElementSize)
+	}
+}
+
+func TestSectionComments(t *testing.T) {
+	input := `{"services":{"web":{"port":80}},"database":{"host":"db"},"db_replica":null,"debug":true}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "off by default",
+			options:  nil,
+			expected: "{\n  \"services\": {\n    \"web\": {\"port\": 80}\n  },\n  \"database\": {\n    \"host\": \"db\"\n  },\n  \"db_replica\": null,\n  \"debug\": true\n}",
+		},
+		{
+			name:     "banners before matching keys",
+			options:  []ConfigOption{WithSectionComments("services", "d?tabase", "db_*")},
+			expected: "{\n  // ──── services ────\n  \"services\": {\n    \"web\": {\"port\": 80}\n  },\n  // ──── database ────\n  \"database\": {\n    \"host\": \"db\"\n  },\n  // ──── db_replica ────\n  \"db_replica\": null,\n  \"debug\": true\n}",
+		},
+		{
+			name:     "with blank lines and leading commas",
+			options:  []ConfigOption{WithSectionComments("database"), WithBlankLineBetweenTopLevelKeys(), WithLeadingCommas(), WithCompactDepth(2)},
+			expected: "{\n  \"services\": {\"web\": {\"port\": 80}}\n\n  // ──── database ────\n  , \"database\": {\"host\": \"db\"}\n\n  , \"db_replica\": null\n\n  , \"debug\": true\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSectionCommentsOnlyAtTopLevel(t *testing.T) {
+	result, err := NewFormatter(NewConfig(WithSectionComments("*"), WithCompactDepth(0))).Format(`{"a":{"b":1},"c":[{"d":2}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count := strings.Count(result, "// ────"); count != 2 {
+		t.Errorf("Expected 2 section comments, got %d:\n%s", count, result)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// SpecialFloatPolicy controls how NaN and Infinity values are handled.
+// JSON has no representation for them, but scientific data sources and
+// JavaScript-based tools often emit bare NaN, Infinity, and -Infinity literals.
+type SpecialFloatPolicy int
+
+const (
+	// SpecialFloatsReject reports NaN and Infinity literals as invalid JSON.
+	SpecialFloatsReject SpecialFloatPolicy = iota
+	// SpecialFloatsAsNull accepts NaN and Infinity literals and emits them as null.
+	SpecialFloatsAsNull
+	// SpecialFloatsAsString accepts NaN and Infinity literals and emits them as
+	// the strings "NaN", "Infinity", and "-Infinity".
+	SpecialFloatsAsString
+)
+
+// WithSpecialFloats sets how NaN, Infinity, and -Infinity literals in the input
+// are handled. By default they are rejected as invalid JSON. The same policy
+// applies to NaN and infinite numbers returned by value hooks.
+//
+// Example:
+//
+//	config := NewConfig(WithSpecialFloats(SpecialFloatsAsNull))
+//	// {"ratio": NaN} is formatted as {"ratio": null}
+func WithSpecialFloats(policy SpecialFloatPolicy) ConfigOption {
+	return func(c *Config) {
+		if policy >= SpecialFloatsReject && policy <= SpecialFloatsAsString {
+			c.SpecialFloats = policy
+		}
+	}
+}
+
+// specialFloat records a special literal that was replaced in the input
+type specialFloat struct {
+	end   int64   // Input offset just after the replacement number token
+	value float64 // NaN, +Inf, or -Inf
+}
+
+// specialLiterals maps the recognized literals to their values
+var specialLiterals = []struct {
+	literal string
+	value   float64
+}{
+	{"-Infinity", math.Inf(-1)},
+	{"+Infinity", math.Inf(1)},
+	{"Infinity", math.Inf(1)},
+	{"NaN", math.NaN()},
+}
+
+// replaceSpecialFloats replaces NaN and Infinity literals outside of strings
+// with number literals of the same length, so that the decoder accepts the
+// input and error positions are unchanged. It returns the new input and the
+// replaced literals in input order.
+func replaceSpecialFloats(input string) (string, []specialFloat) {
+	if !strings.Contains(input, "NaN") && !strings.Contains(input, "Infinity") {
+		return input, nil
+	}
+
+	var builder strings.Builder
+	var specials []specialFloat
+	inString := false
+	last := 0
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++ // Skip the escaped character
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			continue
+		}
+		for _, special := range specialLiterals {
+			if !strings.HasPrefix(input[i:], special.literal) || isIdentifierByte(input, i+len(special.literal)) {
+				continue
+			}
+			// "0" or "-0", padded with spaces to the literal length
+			replacement := "0"
+			if special.value < 0 {
+				replacement = "-0"
+			}
+			builder.WriteString(input[last:i])
+			builder.WriteString(replacement)
+			builder.WriteString(strings.Repeat(" ", len(special.literal)-len(replacement)))
+			specials = append(specials, specialFloat{end: int64(i + len(replacement)), value: special.value})
+			i += len(special.literal) - 1
+			last = i + 1
+			break
+		}
+	}
+	if len(specials) == 0 {
+		return input, nil
+	}
+	builder.WriteString(input[last:])
+	return builder.String(), specials
+}
+
+// isIdentifierByte reports whether the byte at i continues an identifier
+func isIdentifierByte(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// restoreSpecialFloat turns the placeholder number of a replaced literal
+// back into its special value
+func (p *TokenParser) restoreSpecialFloat(token json.Token) json.Token {
+	if _, ok := token.(float64); !ok {
+		return token
+	}
+	offset := p.decoder.InputOffset()
+	for len(p.specials) > 0 && p.specials[0].end < offset {
+		p.specials = p.specials[1:]
+	}
+	if len(p.specials) > 0 && p.specials[0].end == offset {
+		token = p.specials[0].value
+		p.specials = p.specials[1:]
+	}
+	return token
+}
+
+// isSpecialFloat reports whether value is NaN or infinite
+func isSpecialFloat(value float64) bool {
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
+
+// specialFloatName returns the JavaScript name of a special value
+func specialFloatName(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case value > 0:
+		return "Infinity"
+	default:
+		return "-Infinity"
+	}
+}
@@ -0,0 +1,118 @@
+package jsonformat
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSpecialFloatPolicies(t *testing.T) {
+	input := `{"a":NaN,"b":[Infinity,-Infinity,1],"c":"NaN Infinity","d":0}`
+
+	tests := []struct {
+		name     string
+		policy   SpecialFloatPolicy
+		expected string
+	}{
+		{
+			name:   "as null",
+			policy: SpecialFloatsAsNull,
+			expected: `{
+  "a": null,
+  "b": [
+    null,
+    null,
+    1
+  ],
+  "c": "NaN Infinity",
+  "d": 0
+}`,
+		},
+		{
+			name:   "as string",
+			policy: SpecialFloatsAsString,
+			expected: `{
+  "a": "NaN",
+  "b": [
+    "Infinity",
+    "-Infinity",
+    1
+  ],
+  "c": "NaN Infinity",
+  "d": 0
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(WithSpecialFloats(tt.policy)))
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+
+			var out bytes.Buffer
+			if err := formatter.FormatTo(&out, strings.NewReader(input)); err != nil {
+				t.Fatalf("Unexpected error from FormatTo: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected FormatTo output:\n%s\n\nGot:\n%s", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestSpecialFloatsRejectedByDefault(t *testing.T) {
+	for _, input := range []string{`{"a":NaN}`, `[Infinity]`, `[-Infinity]`} {
+		if _, err := NewFormatter(DefaultConfig()).Format(input); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
+
+func TestSpecialFloatsErrorPositionUnchanged(t *testing.T) {
+	input := `[NaN, Infinity, }`
+	_, errDefault := NewFormatter(DefaultConfig()).Format(`[0  , 0       , }`)
+	_, errLenient := NewFormatter(NewConfig(WithSpecialFloats(SpecialFloatsAsNull))).Format(input)
+	if errDefault == nil || errLenient == nil {
+		t.Fatal("Expected errors for invalid input")
+	}
+	if errDefault.(*FormatError).Position != errLenient.(*FormatError).Position {
+		t.Errorf("Expected equal positions, got %d and %d", errDefault.(*FormatError).Position, errLenient.(*FormatError).Position)
+	}
+}
+
+func TestSpecialFloatsFromValueHooks(t *testing.T) {
+	config := NewConfig(
+		WithSpecialFloats(SpecialFloatsAsString),
+		WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+			return math.Inf(1), true
+		}),
+	)
+	result, err := NewFormatter(config).Format(`[1]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "[\n  \"Infinity\"\n]" {
+		t.Errorf("Unexpected result:\n%s", result)
+	}
+}
+
+func TestReplaceSpecialFloats(t *testing.T) {
+	input := `{"s":"NaN \"Infinity\"","n":NaN,"m":-Infinity,"NaNx":1}`
+	replaced, specials := replaceSpecialFloats(input)
+	if len(replaced) != len(input) {
+		t.Errorf("Expected replacement to keep the input length, got %q", replaced)
+	}
+	if len(specials) != 2 || !math.IsNaN(specials[0].value) || !math.IsInf(specials[1].value, -1) {
+		t.Errorf("Unexpected specials: %+v", specials)
+	}
+	if !strings.Contains(replaced, `"s":"NaN \"Infinity\""`) || !strings.Contains(replaced, `"NaNx":1`) {
+		t.Errorf("Expected strings to be left untouched, got %q", replaced)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StyleVersion selects a set of layout rules. The output of a style version
+// is frozen, so callers that pin a version get the same output from every
+// release, and layout improvements are adopted deliberately by moving to a
+// newer version.
+type StyleVersion int
+
+const (
+	// StyleV1 is the original layout, in which empty containers that are not
+	// compact are written over two lines, e.g. "[" and "]".
+	StyleV1 StyleVersion = iota
+	// StyleV2 writes empty containers as {} and [] wherever they appear.
+	StyleV2
+
+	// StyleLatest is the newest style version. It changes between releases,
+	// so pin a specific version where output must stay stable.
+	StyleLatest = StyleV2
+)
+
+// String returns the version name, such as "v1"
+func (v StyleVersion) String() string {
+	return "v" + strconv.Itoa(int(v)+1)
+}
+
+// ParseStyleVersion parses a style version given as "1", "v1", or "latest".
+func ParseStyleVersion(s string) (StyleVersion, error) {
+	if s == "latest" {
+		return StyleLatest, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil || n < 1 || StyleVersion(n-1) > StyleLatest {
+		return StyleV1, NewFormatError(fmt.Sprintf("unknown style version %q (available: v1 to %s)", s, StyleLatest))
+	}
+	return StyleVersion(n - 1), nil
+}
+
+// WithStyleVersion selects the layout rules, like the -lang flag of gofumpt.
+// Invalid versions are ignored.
+//
+// Example:
+//
+//	config := NewConfig(WithStyleVersion(StyleV2))
+//	// {
+//	//   "items": [],
+//	//   "meta": {}
+//	// }
+func WithStyleVersion(version StyleVersion) ConfigOption {
+	return func(c *Config) {
+		if version >= StyleV1 && version <= StyleLatest {
+			c.StyleVersion = version
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestStyleVersion(t *testing.T) {
+	input := `{"a":{},"b":[],"c":[{},[]],"d":{"e":{"f":{},"g":[]}}}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "v1 keeps the original layout",
+			options: []ConfigOption{WithStyleVersion(StyleV1), WithCompactDepth(0)},
+			expected: `{
+  "a": {
+  },
+  "b": [
+  ],
+  "c": [
+    {
+    },
+    [
+    ]
+  ],
+  "d": {
+    "e": {
+      "f": {
+      },
+      "g": [
+      ]
+    }
+  }
+}`,
+		},
+		{
+			name:    "v2 writes empty containers on one line",
+			options: []ConfigOption{WithStyleVersion(StyleV2), WithCompactDepth(0)},
+			expected: `{
+  "a": {},
+  "b": [],
+  "c": [
+    {},
+    []
+  ],
+  "d": {
+    "e": {
+      "f": {},
+      "g": []
+    }
+  }
+}`,
+		},
+		{
+			name:    "v2 with leading commas",
+			options: []ConfigOption{WithStyleVersion(StyleV2), WithLeadingCommas()},
+			expected: `{
+  "a": {}
+  , "b": []
+  , "c": [
+    {}
+    , []
+  ]
+  , "d": {
+    "e": {"f": {}, "g": []}
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestStyleV2EmptyRoot(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithStyleVersion(StyleV2)))
+	for _, input := range []string{`{}`, `[]`} {
+		result, err := formatter.Format(input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result != input {
+			t.Errorf("Expected %s, got:\n%s", input, result)
+		}
+	}
+
+	// Comments of missing properties keep the closing brace on its own line
+	schema, err := ParseSchema([]byte(`{"required":["a"]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	formatter = NewFormatter(NewConfig(WithStyleVersion(StyleV2), WithSchema(schema), WithMissingRequired()))
+	result, err := formatter.Format(`{}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "{\n  /* missing: \"a\" */\n}"
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestParseStyleVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected StyleVersion
+		valid    bool
+	}{
+		{input: "1", expected: StyleV1, valid: true},
+		{input: "v2", expected: StyleV2, valid: true},
+		{input: "latest", expected: StyleLatest, valid: true},
+		{input: "0"},
+		{input: "v3"},
+		{input: "two"},
+	}
+
+	for _, tt := range tests {
+		version, err := ParseStyleVersion(tt.input)
+		if tt.valid != (err == nil) || (tt.valid && version != tt.expected) {
+			t.Errorf("ParseStyleVersion(%q) = %v, %v", tt.input, version, err)
+		}
+	}
+	if StyleV2.String() != "v2" {
+		t.Errorf("Expected v2, got %s", StyleV2)
+	}
+}
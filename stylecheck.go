@@ -0,0 +1,322 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StyleCheck is the kind of layout property a StyleAssertion expects.
+type StyleCheck int
+
+const (
+	// CheckOneLine expects the value to be written on one line.
+	CheckOneLine StyleCheck = iota
+	// CheckExpanded expects the value to span several lines.
+	CheckExpanded
+	// CheckRedacted expects the value, and every value below it, to be
+	// written as RedactedValue.
+	CheckRedacted
+	// CheckKey expects the member to be written with the key in
+	// StyleAssertion.Key.
+	CheckKey
+	// CheckKeyOrder expects the keys in StyleAssertion.Keys to be written in
+	// that order among the members of the object.
+	CheckKeyOrder
+)
+
+// String returns the name of the check.
+func (c StyleCheck) String() string {
+	switch c {
+	case CheckOneLine:
+		return "one line"
+	case CheckExpanded:
+		return "expanded"
+	case CheckRedacted:
+		return "redacted"
+	case CheckKey:
+		return "key"
+	case CheckKeyOrder:
+		return "key order"
+	default:
+		return "unknown"
+	}
+}
+
+// StyleAssertion is a property the formatted output of a sample document is
+// expected to have at the values matching Path, a dot-separated pattern as
+// in ValueContext.MatchPath with "" for the root. Paths use the keys of the
+// input, as style sheet rules do.
+type StyleAssertion struct {
+	Path  string
+	Check StyleCheck
+	Key   string   // Key expected by CheckKey
+	Keys  []string // Keys expected in this order by CheckKeyOrder
+}
+
+// StyleSample is a sample document with the assertions its formatted output
+// must satisfy.
+type StyleSample struct {
+	Name       string
+	Document   string
+	Assertions []StyleAssertion
+}
+
+// StyleFailure is an assertion that does not hold for a sample.
+type StyleFailure struct {
+	Sample    string
+	Assertion StyleAssertion
+	Message   string
+}
+
+// String returns the failure as "sample: message".
+func (f StyleFailure) String() string {
+	return f.Sample + ": " + f.Message
+}
+
+// StyleRuleMatch is a value of a sample matched by a style sheet rule.
+type StyleRuleMatch struct {
+	Sample string
+	Path   []string
+}
+
+// StyleRuleReport lists the values a style sheet rule matched in the samples.
+type StyleRuleReport struct {
+	Index   int    // Index is the position of the rule in the style sheet, from 1
+	Path    string // Path is the pattern of the rule
+	Matches []StyleRuleMatch
+}
+
+// StyleSheetReport is the result of CheckStyleSheet.
+type StyleSheetReport struct {
+	// Rules has a report for every rule, in style sheet order.
+	Rules []StyleRuleReport
+
+	// Failures lists the assertions that do not hold, by sample.
+	Failures []StyleFailure
+
+	// Outputs holds the formatted samples, by sample name.
+	Outputs map[string]string
+}
+
+// Passed reports whether every assertion holds.
+func (r *StyleSheetReport) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// UnusedRules returns the reports of the rules that matched no value in any
+// sample, which are either dead or not covered by the samples.
+func (r *StyleSheetReport) UnusedRules() []StyleRuleReport {
+	var unused []StyleRuleReport
+	for _, rule := range r.Rules {
+		if len(rule.Matches) == 0 {
+			unused = append(unused, rule)
+		}
+	}
+	return unused
+}
+
+// CheckStyleSheet formats the sample documents with the style sheet, on top
+// of the preset it names, and checks the assertions of every sample against
+// the output. It reports which values each rule matched and which assertions
+// failed, so teams can change large style sheets with confidence, for
+// example from a Go test:
+//
+//	report, err := CheckStyleSheet(sheet, StyleSample{
+//	    Name:     "pod",
+//	    Document: podJSON,
+//	    Assertions: []StyleAssertion{
+//	        {Path: "spec.containers.*.env", Check: CheckOneLine},
+//	        {Path: "data.password", Check: CheckRedacted},
+//	    },
+//	})
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	for _, failure := range report.Failures {
+//	    t.Error(failure)
+//	}
+//
+// An error is returned for invalid style sheets and sample documents, not for
+// failed assertions.
+func CheckStyleSheet(data []byte, samples ...StyleSample) (*StyleSheetReport, error) {
+	sheet, err := decodeStyleSheet(data)
+	if err != nil {
+		return nil, err
+	}
+	options, err := sheet.options()
+	if err != nil {
+		return nil, err
+	}
+	config := NewConfig(options...)
+	if sheet.Preset != "" {
+		var ok bool
+		if config, ok = Preset(sheet.Preset, options...); !ok {
+			return nil, NewFormatError(fmt.Sprintf("unknown preset %q in style sheet", sheet.Preset))
+		}
+	}
+	formatter := NewFormatter(config)
+
+	report := &StyleSheetReport{
+		Rules:   make([]StyleRuleReport, len(sheet.Rules)),
+		Outputs: make(map[string]string, len(samples)),
+	}
+	for i, rule := range sheet.Rules {
+		report.Rules[i] = StyleRuleReport{Index: i + 1, Path: *rule.Path}
+	}
+	for _, sample := range samples {
+		output, annotations, err := formatter.FormatWithAnnotations(sample.Document)
+		if err != nil {
+			return nil, WrapFormatError("failed to format sample "+sample.Name, err)
+		}
+		report.Outputs[sample.Name] = output
+
+		for i := range report.Rules {
+			rule := &report.Rules[i]
+			for _, a := range annotations {
+				if a.Kind == AnnotationValue && matchRootedPath(rule.Path, a.Path) {
+					rule.Matches = append(rule.Matches, StyleRuleMatch{Sample: sample.Name, Path: a.Path})
+				}
+			}
+		}
+		for _, assertion := range sample.Assertions {
+			for _, message := range checkAssertion(assertion, output, annotations) {
+				report.Failures = append(report.Failures, StyleFailure{Sample: sample.Name, Assertion: assertion, Message: message})
+			}
+		}
+	}
+	return report, nil
+}
+
+// checkAssertion returns a message for every value at which the assertion
+// does not hold
+func checkAssertion(assertion StyleAssertion, output string, annotations []Annotation) []string {
+	var messages []string
+	matched := false
+	for _, a := range annotations {
+		if a.Kind != AnnotationValue || !matchRootedPath(assertion.Path, a.Path) {
+			continue
+		}
+		matched = true
+		text := output[a.Start:a.End]
+		var message string
+		switch assertion.Check {
+		case CheckOneLine:
+			if lines := strings.Count(text, "\n") + 1; lines > 1 {
+				message = fmt.Sprintf("written on %d lines, expected one line", lines)
+			}
+		case CheckExpanded:
+			if !strings.Contains(text, "\n") {
+				message = "written on one line, expected it expanded"
+			}
+		case CheckRedacted:
+			message = checkRedacted(a.Path, output, annotations)
+		case CheckKey:
+			message = checkKey(a.Path, assertion.Key, output, annotations)
+		case CheckKeyOrder:
+			message = checkKeyOrder(a.Path, assertion.Keys, annotations)
+		default:
+			message = fmt.Sprintf("unknown check %d", assertion.Check)
+		}
+		if message == "" {
+			continue
+		}
+		if len(a.Path) > 0 {
+			message = strings.Join(a.Path, ".") + ": " + message
+		}
+		messages = append(messages, message)
+	}
+	if !matched {
+		messages = append(messages, fmt.Sprintf("no value matches %q (%s)", assertion.Path, assertion.Check))
+	}
+	return messages
+}
+
+// checkRedacted describes the first scalar value at or below path that is
+// not redacted, if any
+func checkRedacted(path []string, output string, annotations []Annotation) string {
+	redacted, _ := json.Marshal(RedactedValue)
+	for _, a := range annotations {
+		if a.Kind != AnnotationValue || !hasPathPrefix(a.Path, path) {
+			continue
+		}
+		text := output[a.Start:a.End]
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+			continue
+		}
+		if text != string(redacted) {
+			if len(a.Path) > len(path) {
+				return fmt.Sprintf("%s is written as %s, expected it redacted", strings.Join(a.Path, "."), text)
+			}
+			return fmt.Sprintf("written as %s, expected it redacted", text)
+		}
+	}
+	return ""
+}
+
+// checkKey describes how the key of the member at path differs from key
+func checkKey(path []string, key string, output string, annotations []Annotation) string {
+	if len(path) == 0 {
+		return "the root has no key"
+	}
+	a := findAnnotation(annotations, path, AnnotationKey)
+	if a == nil {
+		return "not an object member"
+	}
+	text := output[a.Start:a.End]
+	written := text
+	// Keys in other quote styles are compared as written
+	json.Unmarshal([]byte(text), &written)
+	if written != key {
+		return fmt.Sprintf("written with key %s, expected %q", text, key)
+	}
+	return ""
+}
+
+// checkKeyOrder describes how the order of the keys of the object at path
+// differs from keys. Members not listed may appear anywhere.
+func checkKeyOrder(path []string, keys []string, annotations []Annotation) string {
+	var written []string
+	for _, a := range annotations {
+		if a.Kind == AnnotationKey && len(a.Path) == len(path)+1 && hasPathPrefix(a.Path, path) {
+			written = append(written, a.Path[len(path)])
+		}
+	}
+	next := 0
+	for _, key := range written {
+		if next < len(keys) && key == keys[next] {
+			next++
+		}
+	}
+	if next < len(keys) {
+		return fmt.Sprintf("keys written in order %v, expected %v", written, keys)
+	}
+	return ""
+}
+
+// hasPathPrefix reports whether path starts with prefix
+func hasPathPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckStyleSheet(t *testing.T) {
+	sheet := `{
+  "compactDepth": 0,
+  "rules": [
+    {"path": "", "sort": ["kind", "*"]},
+    {"path": "spec.ports.*", "compact": true},
+    {"path": "metadata.creationTimestamp", "rename": "created"},
+    {"path": "data", "redact": true},
+    {"path": "status", "compact": true}
+  ]
+}`
+	sample := StyleSample{
+		Name:     "pod",
+		Document: `{"metadata":{"creationTimestamp":"2024"},"kind":"Pod","spec":{"ports":[{"port":80},{"port":443}]},"data":{"password":"x","keys":[1]}}`,
+		Assertions: []StyleAssertion{
+			{Path: "spec.ports.*", Check: CheckOneLine},
+			{Path: "spec.ports", Check: CheckExpanded},
+			{Path: "data", Check: CheckRedacted},
+			{Path: "metadata.creationTimestamp", Check: CheckKey, Key: "created"},
+			{Path: "", Check: CheckKeyOrder, Keys: []string{"kind", "spec"}},
+		},
+	}
+
+	report, err := CheckStyleSheet([]byte(sheet), sample)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Passed() {
+		t.Errorf("Unexpected failures: %v\n%s", report.Failures, report.Outputs["pod"])
+	}
+
+	matches := make([]int, len(report.Rules))
+	for i, rule := range report.Rules {
+		matches[i] = len(rule.Matches)
+	}
+	if expected := []int{1, 2, 1, 1, 0}; !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Expected matches %v, got %v", expected, matches)
+	}
+	unused := report.UnusedRules()
+	if len(unused) != 1 || unused[0].Index != 5 || unused[0].Path != "status" {
+		t.Errorf("Expected rule 5 unused, got %v", unused)
+	}
+	if match := report.Rules[1].Matches[1]; match.Sample != "pod" || !reflect.DeepEqual(match.Path, []string{"spec", "ports", "1"}) {
+		t.Errorf("Unexpected match %v", match)
+	}
+}
+
+func TestCheckStyleSheetFailures(t *testing.T) {
+	sheet := `{"compactDepth": 0, "rules": [{"path": "a.secret", "redact": true}]}`
+	sample := StyleSample{
+		Name:     "sample",
+		Document: `{"a":{"secret":"x","token":{"v":"t"},"b":[1,2]},"c":1}`,
+		Assertions: []StyleAssertion{
+			{Path: "a.*", Check: CheckOneLine},
+			{Path: "c", Check: CheckExpanded},
+			{Path: "a", Check: CheckRedacted},
+			{Path: "a.token", Check: CheckKey, Key: "tok"},
+			{Path: "", Check: CheckKeyOrder, Keys: []string{"c", "a"}},
+			{Path: "missing", Check: CheckOneLine},
+		},
+	}
+	expected := []string{
+		"sample: a.token: written on 3 lines, expected one line",
+		"sample: a.b: written on 4 lines, expected one line",
+		"sample: c: written on one line, expected it expanded",
+		`sample: a: a.token.v is written as "t", expected it redacted`,
+		`sample: a.token: written with key "token", expected "tok"`,
+		`sample: keys written in order [a c], expected [c a]`,
+		`sample: no value matches "missing" (one line)`,
+	}
+
+	report, err := CheckStyleSheet([]byte(sheet), sample)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var failures []string
+	for _, failure := range report.Failures {
+		failures = append(failures, failure.String())
+	}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(failures, "\n"))
+	}
+	if report.Passed() {
+		t.Error("Expected the report to fail")
+	}
+}
+
+func TestCheckStyleSheetErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheet  string
+		sample StyleSample
+		errMsg string
+	}{
+		{"invalid rule", `{"rules": [{"path": "a"}]}`, StyleSample{Name: "s", Document: `{}`}, "invalid style sheet rule 1"},
+		{"unknown preset", `{"preset": "nope"}`, StyleSample{Name: "s", Document: `{}`}, `unknown preset "nope"`},
+		{"invalid sample", `{}`, StyleSample{Name: "broken", Document: `{"a":`}, "failed to format sample broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CheckStyleSheet([]byte(tt.sheet), tt.sample)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
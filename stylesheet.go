@@ -0,0 +1,196 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// styleSheet is the JSON form of a style sheet
+type styleSheet struct {
+	Preset        string              `json:"preset"`
+	Indent        *int                `json:"indent"`
+	Tabs          *bool               `json:"tabs"`
+	CompactDepth  *int                `json:"compactDepth"`
+	MaxWidth      *int                `json:"maxWidth"`
+	MaxTokens     *int                `json:"maxTokens"`
+	SpecialFloats *SpecialFloatPolicy `json:"specialFloats"`
+	KeyOrder      *KeyOrderPolicy     `json:"keyOrder"`
+	Rules         []styleRule         `json:"rules"`
+}
+
+// styleRule is a rule of a style sheet, applying to the values at paths
+// matching its pattern
+type styleRule struct {
+	Path    *string         `json:"path"`
+	Compact bool            `json:"compact"`
+	Expand  bool            `json:"expand"`
+	Sort    json.RawMessage `json:"sort"`
+	Redact  bool            `json:"redact"`
+	Rename  string          `json:"rename"`
+}
+
+// ParseStyleSheet parses a style sheet: a JSON document of formatting rules
+// for path patterns, which can be shared between the command line tool,
+// middleware, and tests without changing Go code. It returns the options the
+// style sheet sets, to be passed to NewConfig or Preset, or given as the file
+// layer of ResolveConfig, and the preset it names, if any.
+//
+// The settings "indent", "tabs", "compactDepth", "maxWidth", and "maxTokens"
+// correspond to WithIndentSize, WithTabs, WithCompactDepth, WithMaxWidth, and
+// WithMaxTokens, and "specialFloats" and "keyOrder" to WithSpecialFloats and
+// WithKeyOrderPolicy, with the policy names returned by String, such as
+// "null" and "scalars-first". Each rule has a
+// "path", a dot-separated pattern as in ValueContext.MatchPath with "" for the
+// root, and the actions to take there:
+//
+//   - "compact": true writes the containers on one line (WithCompactPaths)
+//   - "expand": true writes their contents one per line (WithExpandedPaths)
+//   - "sort": true sorts the members by key (WithSortedKeys), and a list of
+//     keys orders them (WithKeyOrder)
+//   - "redact": true replaces the values with RedactedValue (WithRedactedPaths)
+//   - "rename": "key" writes the members with another key (WithKeyRename)
+//
+// Unknown fields are reported as errors, so misspelled rules do not go
+// unnoticed.
+//
+// Example:
+//
+//	options, preset, err := ParseStyleSheet([]byte(`{
+//	  "maxWidth": 100,
+//	  "rules": [
+//	    {"path": "", "sort": ["apiVersion", "kind", "*"]},
+//	    {"path": "spec.containers.*.env", "compact": true},
+//	    {"path": "data.*", "redact": true}
+//	  ]
+//	}`))
+func ParseStyleSheet(data []byte) (options []ConfigOption, preset string, err error) {
+	sheet, err := decodeStyleSheet(data)
+	if err != nil {
+		return nil, "", err
+	}
+	options, err = sheet.options()
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sheet.Preset, nil
+}
+
+// decodeStyleSheet decodes a style sheet without checking its rules
+func decodeStyleSheet(data []byte) (*styleSheet, error) {
+	var sheet styleSheet
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&sheet); err != nil {
+		return nil, WrapFormatError("invalid style sheet", err)
+	}
+	return &sheet, nil
+}
+
+// options returns the configuration options of the style sheet
+func (sheet *styleSheet) options() (options []ConfigOption, err error) {
+	if sheet.Indent != nil {
+		options = append(options, WithIndentSize(*sheet.Indent))
+	}
+	if sheet.Tabs != nil && *sheet.Tabs {
+		options = append(options, WithTabs())
+	}
+	if sheet.CompactDepth != nil {
+		options = append(options, WithCompactDepth(*sheet.CompactDepth))
+	}
+	if sheet.MaxWidth != nil {
+		options = append(options, WithMaxWidth(*sheet.MaxWidth))
+	}
+	if sheet.MaxTokens != nil {
+		options = append(options, WithMaxTokens(*sheet.MaxTokens))
+	}
+	if sheet.SpecialFloats != nil {
+		options = append(options, WithSpecialFloats(*sheet.SpecialFloats))
+	}
+	if sheet.KeyOrder != nil {
+		options = append(options, WithKeyOrderPolicy(*sheet.KeyOrder))
+	}
+	for i, rule := range sheet.Rules {
+		ruleOptions, err := rule.options()
+		if err != nil {
+			return nil, WrapFormatError(fmt.Sprintf("invalid style sheet rule %d", i+1), err)
+		}
+		options = append(options, ruleOptions...)
+	}
+	return options, nil
+}
+
+// LoadStyleSheet reads and parses the style sheet file at path, as
+// ParseStyleSheet does.
+func LoadStyleSheet(path string) (options []ConfigOption, preset string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", WrapFormatError("failed to read style sheet "+path, err)
+	}
+	options, preset, err = ParseStyleSheet(data)
+	if formatErr, ok := err.(*FormatError); ok {
+		formatErr.File = path
+	}
+	return options, preset, err
+}
+
+// options returns the configuration options of the rule
+func (r styleRule) options() ([]ConfigOption, error) {
+	if r.Path == nil {
+		return nil, NewFormatError("path is required")
+	}
+	path := *r.Path
+	if r.Compact && r.Expand {
+		return nil, NewFormatError("compact and expand cannot be combined")
+	}
+
+	var options []ConfigOption
+	if r.Compact {
+		options = append(options, WithCompactPaths(path))
+	}
+	if r.Expand {
+		options = append(options, WithExpandedPaths(path))
+	}
+	if len(r.Sort) > 0 {
+		var sorted bool
+		var keys []string
+		switch {
+		case json.Unmarshal(r.Sort, &sorted) == nil:
+			if sorted {
+				options = append(options, WithSortedKeys(path))
+			}
+		case json.Unmarshal(r.Sort, &keys) == nil:
+			options = append(options, WithKeyOrder(path, keys...))
+		default:
+			return nil, NewFormatError("sort must be a boolean or a list of keys")
+		}
+	}
+	if r.Redact {
+		options = append(options, WithRedactedPaths(path))
+	}
+	if r.Rename != "" {
+		if path == "" {
+			return nil, NewFormatError("the root has no key to rename")
+		}
+		options = append(options, WithKeyRename(path, r.Rename))
+	}
+	if len(options) == 0 {
+		return nil, NewFormatError(fmt.Sprintf("no action for path %q", path))
+	}
+	return options, nil
+}
@@ -0,0 +1,136 @@
+package jsonformat
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStyleSheet(t *testing.T) {
+	input := `{"kind":"Pod","apiVersion":"v1","metadata":{"name":"web","labels":{"tier":"front","app":"shop"},"creationTimestamp":"2024-05-01T12:00:00Z"},` +
+		`"spec":{"ports":[{"name":"http","port":80}]},"data":{"password":"hunter2","token":{"value":"t"}}}`
+	sheet := `{
+  "compactDepth": 0,
+  "indent": 2,
+  "rules": [
+    {"path": "", "sort": ["apiVersion", "kind", "*"]},
+    {"path": "metadata.labels", "sort": true, "compact": true},
+    {"path": "spec.ports.*", "compact": true},
+    {"path": "metadata.creationTimestamp", "rename": "created"},
+    {"path": "data.*", "redact": true}
+  ]
+}`
+	expected := `{
+  "apiVersion": "v1",
+  "kind": "Pod",
+  "metadata": {
+    "name": "web",
+    "labels": {"app": "shop", "tier": "front"},
+    "created": "2024-05-01T12:00:00Z"
+  },
+  "spec": {
+    "ports": [
+      {"name": "http", "port": 80}
+    ]
+  },
+  "data": {
+    "password": "[REDACTED]",
+    "token": {
+      "value": "[REDACTED]"
+    }
+  }
+}`
+
+	options, preset, err := ParseStyleSheet([]byte(sheet))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if preset != "" {
+		t.Errorf("Expected no preset, got %q", preset)
+	}
+	result, err := NewFormatter(NewConfig(options...)).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestStyleSheetExpand(t *testing.T) {
+	options, preset, err := ParseStyleSheet([]byte(`{"preset": "compact", "rules": [{"path": "b", "expand": true}]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if preset != "compact" {
+		t.Errorf("Expected preset compact, got %q", preset)
+	}
+	result, err := NewFormatter(NewConfig(append([]ConfigOption{WithCompactDepth(2)}, options...)...)).Format(`{"a":{"x":1},"b":{"y":2}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{
+  "a": {"x": 1},
+  "b": {
+    "y": 2
+  }
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestStyleSheetErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		sheet  string
+		errMsg string
+	}{
+		{"invalid JSON", `{"rules": [`, "invalid style sheet"},
+		{"unknown field", `{"rules": [{"path": "a", "compcat": true}]}`, "unknown field"},
+		{"missing path", `{"rules": [{"compact": true}]}`, "rule 1: path is required"},
+		{"no action", `{"rules": [{"path": "a"}, {"path": "b"}]}`, "rule 1: no action"},
+		{"compact and expand", `{"rules": [{"path": "a", "compact": true, "expand": true}]}`, "cannot be combined"},
+		{"invalid sort", `{"rules": [{"path": "a", "sort": "yes"}]}`, "sort must be a boolean or a list of keys"},
+		{"rename root", `{"rules": [{"path": "", "rename": "x"}]}`, "root has no key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseStyleSheet([]byte(tt.sheet))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoadStyleSheet(t *testing.T) {
+	path := writeFile(t, "style.json", `{"maxWidth": 40, "rules": [{"path": "a", "sort": true}]}`)
+	options, _, err := LoadStyleSheet(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := NewConfig(options...)
+	if config.MaxWidth != 40 || len(config.SortedKeys) != 1 {
+		t.Errorf("Unexpected configuration: MaxWidth %d, SortedKeys %v", config.MaxWidth, config.SortedKeys)
+	}
+
+	broken := writeFile(t, "broken.json", `{"rules": [{}]}`)
+	if _, _, err := LoadStyleSheet(broken); err == nil || !strings.HasPrefix(err.Error(), broken+": ") {
+		t.Errorf("Expected error naming %s, got %v", broken, err)
+	}
+	if _, _, err := LoadStyleSheet(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestStyleSheetMaxTokens(t *testing.T) {
+	options, _, err := ParseStyleSheet([]byte(`{"maxTokens": 0}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config := NewConfig(options...); config.MaxTokens != 0 {
+		t.Errorf("Expected no token limit, got %d", config.MaxTokens)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+)
+
+// WithOverflowSummary summarizes compact objects that do not fit within the
+// width limit instead of expanding them: the first keys members are written
+// on one line, followed by "…" in place of the rest. This is a middle ground
+// for logging, where full expansion is too noisy. Objects with no more than
+// keys members are expanded as usual. The option only takes effect together
+// with WithMaxWidth or WithWidthByDepth, and the output is no longer valid
+// JSON.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxWidth(40), WithOverflowSummary(2))
+//	// {
+//	//   "events": [
+//	//     {"id": 1, "type": "login"},
+//	//     {"id": 2, "type": "error", …}
+//	//   ]
+//	// }
+func WithOverflowSummary(keys int) ConfigOption {
+	return func(c *Config) {
+		if keys >= 0 {
+			c.OverflowSummaryKeys = keys
+		}
+	}
+}
+
+// summaryLength returns the number of tokens from the start of a captured
+// object up to the end of its first keys members, or 0 if the object is not
+// summarized
+func summaryLength(tokens []json.Token, keys int) int {
+	if keys <= 0 || len(tokens) == 0 || tokens[0] != json.Delim('{') {
+		return 0
+	}
+	rest := tokens[1:]
+	for i := 0; i < keys; i++ {
+		if len(rest) == 0 || !isKeyToken(rest[0]) {
+			return 0
+		}
+		rest = skipValue(rest[1:])
+	}
+	if len(rest) == 0 || !isKeyToken(rest[0]) {
+		return 0
+	}
+	return len(tokens) - len(rest)
+}
+
+// isKeyToken reports whether the token at the start of a member is a key,
+// rather than the end of the object or a marker written in place of members
+func isKeyToken(token json.Token) bool {
+	_, ok := token.(string)
+	return ok
+}
+
+// writeSummary writes the first members of a compact object followed by an ellipsis
+func (p *TokenParser) writeSummary(tokens []json.Token) error {
+	for _, token := range tokens {
+		if err := p.emitToken(token); err != nil {
+			return err
+		}
+	}
+	if _, err := p.builder.WriteString(", …"); err != nil {
+		return WrapFormatError("failed to write summary ellipsis", err)
+	}
+	return p.emitToken(json.Delim('}'))
+}
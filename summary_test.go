@@ -0,0 +1,77 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestOverflowSummary(t *testing.T) {
+	input := `{"events":[{"id":1,"type":"login"},{"id":2,"type":"error","detail":{"code":500}},{"id":3,"detail":"a very long detail"}]}`
+
+	tests := []struct {
+		name     string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "summary of first keys",
+			options: []ConfigOption{WithMaxWidth(34), WithOverflowSummary(2)},
+			expected: `{
+  "events": [
+    {"id": 1, "type": "login"},
+    {"id": 2, "type": "error", …},
+    {
+      "id": 3,
+      "detail": "a very long detail"
+    }
+  ]
+}`,
+		},
+		{
+			name:    "without width limit",
+			options: []ConfigOption{WithOverflowSummary(1)},
+			expected: `{
+  "events": [
+    {"id": 1, "type": "login"},
+    {"id": 2, "type": "error", "detail": {"code": 500}},
+    {"id": 3, "detail": "a very long detail"}
+  ]
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSummaryLength(t *testing.T) {
+	tokens := readTokens(t, `{"a":1,"b":{"c":[1,2]},"d":3}`)
+
+	tests := []struct {
+		keys     int
+		expected int
+	}{
+		{keys: 0, expected: 0},
+		{keys: 1, expected: 3},
+		{keys: 2, expected: 11},
+		{keys: 3, expected: 0},
+	}
+
+	for _, tt := range tests {
+		if result := summaryLength(tokens, tt.keys); result != tt.expected {
+			t.Errorf("Expected %d tokens for %d keys, got %d", tt.expected, tt.keys, result)
+		}
+	}
+	if result := summaryLength(readTokens(t, `[1,2,3]`), 1); result != 0 {
+		t.Errorf("Expected arrays not to be summarized, got %d", result)
+	}
+}
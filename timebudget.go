@@ -0,0 +1,132 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WarningTimeBudget reports that the output was truncated because formatting
+// exceeded the time budget set with WithTimeBudget.
+const WarningTimeBudget WarningCode = "time_budget"
+
+// budgetClock returns the current time when checking the time budget
+var budgetClock = time.Now
+
+// budgetCheckInterval is the number of tokens processed between clock readings
+const budgetCheckInterval = 256
+
+// WithTimeBudget bounds the time spent formatting a document, for interactive
+// UIs that would rather show a truncated view than block on a pathological
+// payload. Once the budget is spent, the rest of the input is not read: the
+// output ends with a "…" line in each open container, which is then closed,
+// and FormatWithWarnings reports a WarningTimeBudget warning. With StrictJSON,
+// formatting fails with an error instead. A duration of 0 disables the budget.
+//
+// The clock is read between tokens, so a single huge token may overrun the
+// budget. Truncated output is not cached.
+//
+// Example:
+//
+//	config := NewConfig(WithTimeBudget(50 * time.Millisecond))
+//	// {
+//	//   "items": [
+//	//     {"id": 1},
+//	//     …
+//	//   ]
+//	// }
+func WithTimeBudget(d time.Duration) ConfigOption {
+	return func(c *Config) {
+		if d >= 0 {
+			c.TimeBudget = d
+		}
+	}
+}
+
+// truncatedMarker is a token that stands for the input left unread when the time budget is spent
+type truncatedMarker struct{}
+
+// startBudget starts the clock of the time budget
+func (p *TokenParser) startBudget() {
+	if p.config.TimeBudget > 0 {
+		p.deadline = budgetClock().Add(p.config.TimeBudget)
+	}
+}
+
+// overBudget reports whether the time budget is spent, reading the clock
+// every budgetCheckInterval tokens
+func (p *TokenParser) overBudget(count int) bool {
+	return !p.deadline.IsZero() && count%budgetCheckInterval == 0 && budgetClock().After(p.deadline)
+}
+
+// truncate ends the output at the current position when the time budget is
+// spent, writing a marker in and then closing each open container
+func (p *TokenParser) truncate() error {
+	if p.config.StrictJSON && !p.degrading {
+		return NewFormatError("formatting exceeded the time budget of " + p.config.TimeBudget.String())
+	}
+	p.truncated = true
+	if p.collectingWarnings {
+		p.warn(WarningTimeBudget, p.valuePath(), "output truncated after the time budget of %s", p.config.TimeBudget)
+	}
+
+	// Tokens held back for later are dropped along with the rest of the input
+	p.fold = nil
+	p.records = nil
+	p.order = nil
+	p.hiding = false
+
+	if err := p.emitToken(truncatedMarker{}); err != nil {
+		return err
+	}
+	for p.depth > 0 {
+		delim := json.Delim('}')
+		if p.isInArray() {
+			delim = ']'
+		}
+		if err := p.processToken(delim); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTruncated writes the marker of the input left unread, as the value
+// of a pending member or as an element of its own
+func (p *TokenParser) handleTruncated() error {
+	marker := "…"
+	switch {
+	case p.depth == 0:
+	case !p.isInArray() && !p.expectingKey:
+		// The value of the member is left unread
+		marker = " …"
+		p.expectingKey = true
+	default:
+		// The marker is not a member, so no section comment precedes it
+		expectingKey := p.expectingKey
+		p.expectingKey = false
+		err := p.writeElementPrefix()
+		p.expectingKey = expectingKey
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := p.builder.WriteString(marker); err != nil {
+		return WrapFormatError("failed to write truncation marker", err)
+	}
+	p.isFirstElement = false
+	return nil
+}
@@ -0,0 +1,130 @@
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spendBudgetClock makes the time budget run out at the first check
+func spendBudgetClock(t *testing.T) {
+	t.Helper()
+	saved := budgetClock
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	budgetClock = func() time.Time {
+		calls++
+		if calls == 1 {
+			return start
+		}
+		return start.Add(time.Hour)
+	}
+	t.Cleanup(func() { budgetClock = saved })
+}
+
+// numberedMembers returns count object members "k0": 0, "k1": 1, ... as input and as compact output
+func numberedMembers(count int) (string, string) {
+	input := make([]string, count)
+	output := make([]string, count)
+	for i := range input {
+		input[i] = fmt.Sprintf(`"k%d":%d`, i, i)
+		output[i] = fmt.Sprintf(`"k%d": %d`, i, i)
+	}
+	return strings.Join(input, ","), strings.Join(output, ", ")
+}
+
+func TestTimeBudget(t *testing.T) {
+	// The clock is read at the 256th token, which is not processed
+	elements := make([]string, 300)
+	for i := range elements {
+		elements[i] = fmt.Sprint(i)
+	}
+	keys, _ := numberedMembers(200)
+	_, written := numberedMembers(126)
+
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:     "array element",
+			input:    `{"items":[` + strings.Join(elements, ",") + `]}`,
+			options:  []ConfigOption{WithTimeBudget(time.Millisecond), WithCompactDepth(1)},
+			expected: `{"items": [` + strings.Join(elements[:252], ", ") + `, …]}`,
+		},
+		{
+			name:     "member value",
+			input:    `{"a":[],` + keys + `}`,
+			options:  []ConfigOption{WithTimeBudget(time.Millisecond), WithCompactDepth(1)},
+			expected: `{"a": [], ` + written[:strings.LastIndex(written, ",")] + `, "k125": …}`,
+		},
+		{
+			name:    "nested containers",
+			input:   `{"a":{"b":[` + strings.Join(elements, ",") + `]}}`,
+			options: []ConfigOption{WithTimeBudget(time.Millisecond), WithCompactDepth(2)},
+			expected: `{
+  "a": {"b": [` + strings.Join(elements[:250], ", ") + `, …]}
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spendBudgetClock(t)
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTimeBudgetWarning(t *testing.T) {
+	spendBudgetClock(t)
+	keys, _ := numberedMembers(200)
+	_, warnings, err := NewFormatter(NewConfig(WithTimeBudget(time.Millisecond))).FormatWithWarnings(`{` + keys + `}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarningTimeBudget {
+		t.Fatalf("Expected one time budget warning, got %v", warnings)
+	}
+	if expected := "output truncated after the time budget of 1ms"; warnings[0].Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, warnings[0].Message)
+	}
+}
+
+func TestTimeBudgetStrictJSON(t *testing.T) {
+	spendBudgetClock(t)
+	keys, _ := numberedMembers(200)
+	_, err := NewFormatter(NewConfig(WithTimeBudget(time.Millisecond), WithStrictJSON())).Format(`{` + keys + `}`)
+	if err == nil || !strings.Contains(err.Error(), "exceeded the time budget") {
+		t.Errorf("Expected time budget error, got %v", err)
+	}
+}
+
+func TestTimeBudgetNotCached(t *testing.T) {
+	spendBudgetClock(t)
+	keys, written := numberedMembers(200)
+	formatter := NewFormatter(NewConfig(WithTimeBudget(time.Millisecond), WithCompactDepth(1)))
+	formatter.SetCache(NewCache(10))
+	input := `{` + keys + `}`
+	if _, err := formatter.Format(input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	budgetClock = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	result, err := formatter.Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{` + written + `}`; result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"time"
+)
+
+// WithUTCTimestamps rewrites RFC 3339 timestamp strings in UTC with a "Z"
+// suffix, dropping trailing zeros of fractional seconds, so timestamps that
+// different services write with different offsets and precisions compare at
+// a glance. Other strings are kept.
+//
+// Example:
+//
+//	config := NewConfig(WithUTCTimestamps())
+//	// "2024-05-01T21:00:00.000+09:00"  →  "2024-05-01T12:00:00Z"
+func WithUTCTimestamps() ConfigOption {
+	return WithValueHook(func(ctx ValueContext) (interface{}, bool) {
+		value, ok := ctx.Value.(string)
+		if !ok {
+			return nil, false
+		}
+		return utcTimestamp(value)
+	})
+}
+
+// utcTimestamp returns an RFC 3339 timestamp in UTC
+func utcTimestamp(value string) (string, bool) {
+	// Skip the parser for strings that cannot be timestamps
+	if len(value) < len("2006-01-02T15:04:05Z") || value[4] != '-' || value[10] != 'T' {
+		return "", false
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "", false
+	}
+	return t.UTC().Format(time.RFC3339Nano), true
+}
@@ -0,0 +1,34 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestUTCTimestamps(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{`"2024-05-01T21:00:00.000+09:00"`, `"2024-05-01T12:00:00Z"`},
+		{`"2024-05-01T05:00:00.123-07:00"`, `"2024-05-01T12:00:00.123Z"`},
+		{`"2024-05-01T12:00:00Z"`, `"2024-05-01T12:00:00Z"`},
+		{`"2024-05-01"`, `"2024-05-01"`},
+		{`"2024-05-01 12:00:00+00:00"`, `"2024-05-01 12:00:00+00:00"`},
+		{`"2024-13-01T12:00:00Z"`, `"2024-13-01T12:00:00Z"`},
+		{`"not a timestamp at all"`, `"not a timestamp at all"`},
+		{`20240501`, `20240501`},
+	}
+
+	formatter := NewFormatter(NewConfig(WithUTCTimestamps(), WithCompactDepth(1)))
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := formatter.Format(`[` + tt.value + `]`)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if expected := `[` + tt.expected + `]`; result != expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TraceState is the state of the token parser before or after a token.
+type TraceState struct {
+	// Depth is the number of containers open.
+	Depth int `json:"depth"`
+
+	// Context is "root", "object", or "array", the kind of the innermost
+	// open container.
+	Context string `json:"context"`
+
+	// FirstElement tells whether no element has been written yet in the
+	// innermost container.
+	FirstElement bool `json:"firstElement"`
+
+	// ExpectingKey tells whether the next string in an object is a key.
+	ExpectingKey bool `json:"expectingKey"`
+
+	// Compact tells whether the innermost container is written on one line.
+	Compact bool `json:"compact"`
+}
+
+// String describes the state, as in "object depth 1, expecting key, compact".
+func (s TraceState) String() string {
+	parts := []string{s.Context + " depth " + strconv.Itoa(s.Depth)}
+	if s.FirstElement {
+		parts = append(parts, "first element")
+	}
+	if s.ExpectingKey {
+		parts = append(parts, "expecting key")
+	}
+	if s.Compact {
+		parts = append(parts, "compact")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// TraceEvent records a token written by the formatter.
+type TraceEvent struct {
+	// Step numbers the events from 1 in the order the tokens were written.
+	Step int `json:"step"`
+
+	// Token is the token as written, such as "{", "\"name\"", or "1", or
+	// the text of a marker such as "… 3 more keys".
+	Token string `json:"token"`
+
+	// Kind is "open", "close", "key", "value", or "marker".
+	Kind string `json:"kind"`
+
+	// Path contains the object keys and array indices leading to the token,
+	// in the same form as ValueContext.Path. Closing delimiters and markers
+	// have the path of their container.
+	Path []string `json:"path"`
+
+	// Before and After are the parser states around the token.
+	Before TraceState `json:"before"`
+	After  TraceState `json:"after"`
+
+	// Layout explains a layout decision taken at the token: why a container
+	// is compact or expanded when it opens, and whether a compact element
+	// fit within the width limit when it closes. It is empty otherwise.
+	Layout string `json:"layout,omitempty"`
+
+	// Output is the text written for the token. Tokens of a compact element
+	// measured against the width limit are held back until it closes, so
+	// their output is written by the events that replay them.
+	Output string `json:"output"`
+
+	// Captured tells whether the token was held back to measure a compact
+	// element, and Replay whether the event writes a token held back before.
+	Captured bool `json:"captured,omitempty"`
+	Replay   bool `json:"replay,omitempty"`
+}
+
+// Trace records how the formatter processed a document, token by token.
+type Trace struct {
+	Events []TraceEvent `json:"events"`
+}
+
+// FormatWithTrace formats a JSON string like Format and also returns a trace
+// of every token written, the parser state before and after it, and the
+// layout decisions taken. It is meant for debugging the formatter and
+// explaining its output, not for production use, as the trace is much larger
+// than the document.
+//
+// Tokens removed before they are written, such as hidden members, buffered
+// members that are reordered, or elements left out of a sample, are not
+// traced until they are written. The cache set with SetCache is not used.
+//
+// Example:
+//
+//	formatted, trace, err := formatter.FormatWithTrace(`{"a":[1,2]}`)
+//	dump, _ := trace.JSON()
+//	graph := trace.DOT() // render with: dot -Tsvg
+func (f *Formatter) FormatWithTrace(jsonStr string) (result string, trace *Trace, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+			trace = nil
+		}
+	}()
+
+	recorder := &traceRecorder{}
+	result, _, err = f.formatString(jsonStr, func(p *TokenParser) {
+		p.trace = recorder
+		p.output.traced = &recorder.output
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return result, &Trace{Events: recorder.events}, nil
+}
+
+// JSON returns the trace as formatted JSON.
+func (t *Trace) JSON() (string, error) {
+	encoded, err := json.Marshal(t)
+	if err != nil {
+		return "", WrapFormatError("failed to encode the trace", err)
+	}
+	return NewFormatter(NewConfig(WithCompactDepth(3), WithMaxTokens(0))).Format(string(encoded))
+}
+
+// DOT returns the trace as a Graphviz graph of the parser states, with an
+// edge for each kind of token moving from one state to another, labeled with
+// the number of such tokens.
+func (t *Trace) DOT() string {
+	type edge struct {
+		from, to int
+		kind     string
+	}
+	nodes := map[string]int{}
+	var labels []string
+	node := func(state TraceState) int {
+		label := state.String()
+		id, ok := nodes[label]
+		if !ok {
+			id = len(labels)
+			nodes[label] = id
+			labels = append(labels, label)
+		}
+		return id
+	}
+	counts := map[edge]int{}
+	var edges []edge
+	for _, event := range t.Events {
+		e := edge{from: node(event.Before), to: node(event.After), kind: event.Kind}
+		if counts[e] == 0 {
+			edges = append(edges, e)
+		}
+		counts[e]++
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph trace {\n\tnode [shape=box];\n")
+	for id, label := range labels {
+		fmt.Fprintf(&b, "\ts%d [label=%s];\n", id, strconv.Quote(label))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\ts%d -> s%d [label=%s];\n", e.from, e.to, strconv.Quote(fmt.Sprintf("%s ×%d", e.kind, counts[e])))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// traceRecorder collects the events of a traced formatting
+type traceRecorder struct {
+	events []TraceEvent
+	open   []int           // Indices of the events being written, innermost last
+	starts []int           // Output offsets where the events being written started
+	output strings.Builder // Copy of the output written so far
+}
+
+// start records a token about to be written and returns its event index
+func (r *traceRecorder) start(p *TokenParser, token json.Token) int {
+	kind := p.tokenKind(token)
+	event := TraceEvent{
+		Step:     len(r.events) + 1,
+		Token:    traceToken(token),
+		Kind:     kind,
+		Path:     p.tokenPath(token, kind),
+		Before:   traceState(p),
+		Captured: p.capture != nil,
+		Replay:   len(r.open) > 0,
+	}
+	r.events = append(r.events, event)
+	r.open = append(r.open, len(r.events)-1)
+	r.starts = append(r.starts, r.output.Len())
+	return len(r.events) - 1
+}
+
+// finish completes the event of a token that has been written
+func (r *traceRecorder) finish(p *TokenParser, index int) {
+	start := r.starts[len(r.starts)-1]
+	r.open = r.open[:len(r.open)-1]
+	r.starts = r.starts[:len(r.starts)-1]
+	event := &r.events[index]
+	event.After = traceState(p)
+	event.Captured = event.Captured || p.capture != nil
+	if index == len(r.events)-1 {
+		// Output written by replayed tokens belongs to their own events
+		event.Output = r.output.String()[start:]
+	}
+}
+
+// noteLayout records a layout decision for the token being written
+func (r *traceRecorder) noteLayout(reason string) {
+	if r == nil || len(r.open) == 0 {
+		return
+	}
+	r.events[r.open[len(r.open)-1]].Layout = reason
+}
+
+// tokenKind classifies a token about to be written as "open", "close",
+// "key", "value", or "marker"
+func (p *TokenParser) tokenKind(token json.Token) string {
+	switch token {
+	case json.Delim('{'), json.Delim('['):
+		return "open"
+	case json.Delim('}'), json.Delim(']'):
+		return "close"
+	}
+	if _, ok := token.(string); ok && p.expectingKey {
+		return "key"
+	}
+	if p.isScalarValue(token) {
+		return "value"
+	}
+	return "marker"
+}
+
+// traceToken returns the text of a token for the trace
+func traceToken(token json.Token) string {
+	switch t := token.(type) {
+	case json.Delim:
+		return t.String()
+	case string:
+		return strconv.Quote(t)
+	case nil:
+		return "null"
+	case interface{ text() string }:
+		return t.text()
+	case truncatedMarker:
+		return "…"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// tokenPath returns the path of a token about to be written. Closing
+// delimiters and markers have the path of their container.
+func (p *TokenParser) tokenPath(token json.Token, kind string) []string {
+	path := p.valuePath()
+	switch {
+	case len(path) == 0:
+		return path
+	case kind == "key":
+		path[len(path)-1] = token.(string)
+	case kind == "close" || kind == "marker":
+		path = path[:len(path)-1]
+	}
+	return path
+}
+
+// traceState returns the current state of the parser
+func traceState(p *TokenParser) TraceState {
+	state := TraceState{
+		Depth:        p.depth,
+		Context:      "root",
+		FirstElement: p.isFirstElement,
+		Compact:      p.shouldFormatCompact(),
+	}
+	if p.depth > 0 {
+		state.Context = "array"
+		if !p.isInArray() {
+			state.Context = "object"
+			state.ExpectingKey = p.expectingKey
+		}
+	}
+	return state
+}
@@ -0,0 +1,189 @@
+package jsonformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatWithTrace(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithCompactDepth(2), WithMaxWidth(20)))
+	result, trace, err := formatter.FormatWithTrace(`{"a":[1,2],"b":{"c":"xxxxxxxxxxxxxxxxxxxxxxxxxx"}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type step struct {
+		Token  string
+		Kind   string
+		Path   string
+		Output string
+		Flags  string
+	}
+	var got []step
+	for _, e := range trace.Events {
+		var flags []string
+		if e.Captured {
+			flags = append(flags, "captured")
+		}
+		if e.Replay {
+			flags = append(flags, "replay")
+		}
+		got = append(got, step{e.Token, e.Kind, strings.Join(e.Path, "."), e.Output, strings.Join(flags, ",")})
+	}
+
+	expected := []step{
+		{"{", "open", "", "{", ""},
+		{`"a"`, "key", "a", "\n  \"a\":", ""},
+		{"[", "open", "a", "", "captured"},
+		{"1", "value", "a.0", "", "captured"},
+		{"2", "value", "a.1", "", "captured"},
+		{"]", "close", "a", " [1, 2]", "captured"},
+		{`"b"`, "key", "b", ",\n  \"b\":", ""},
+		{"{", "open", "b", "", "captured"},
+		{`"c"`, "key", "b.c", "", "captured"},
+		{`"xxxxxxxxxxxxxxxxxxxxxxxxxx"`, "value", "b.c", "", "captured"},
+		{"}", "close", "b", "", "captured"},
+		{"{", "open", "b", " {", "replay"},
+		{`"c"`, "key", "b.c", "\n    \"c\":", "replay"},
+		{`"xxxxxxxxxxxxxxxxxxxxxxxxxx"`, "value", "b.c", ` "xxxxxxxxxxxxxxxxxxxxxxxxxx"`, "replay"},
+		{"}", "close", "b", "\n  }", "replay"},
+		{"}", "close", "", "\n}", ""},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected events:\n%#v\n\nGot:\n%#v", expected, got)
+	}
+
+	var output strings.Builder
+	for _, e := range trace.Events {
+		output.WriteString(e.Output)
+	}
+	if output.String() != result {
+		t.Errorf("Expected the outputs of the events to make up the result:\n%s\n\nGot:\n%s", result, output.String())
+	}
+
+	layouts := map[int]string{
+		1:  "expanded: depth 1 is below the compact depth 2",
+		3:  "compact: depth 2 reaches the compact depth 2",
+		6:  "compact: the line fits within 20 columns",
+		8:  "compact: depth 2 reaches the compact depth 2",
+		11: "expanded: the line exceeds 20 columns",
+		12: "expanded: compact formatting starts at depth 3 after a width overflow",
+	}
+	for _, e := range trace.Events {
+		if e.Layout != layouts[e.Step] {
+			t.Errorf("Step %d: expected layout %q, got %q", e.Step, layouts[e.Step], e.Layout)
+		}
+	}
+}
+
+func TestFormatWithTraceStates(t *testing.T) {
+	_, trace, err := NewFormatter(DefaultConfig()).FormatWithTrace(`[{"a":[1]}]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, e := range trace.Events {
+		got = append(got, e.Before.String()+" -> "+e.After.String())
+	}
+	expected := []string{
+		"root depth 0, first element -> array depth 1, first element",
+		"array depth 1, first element -> object depth 2, first element, expecting key",
+		"object depth 2, first element, expecting key -> object depth 2",
+		"object depth 2 -> array depth 3, first element, compact",
+		"array depth 3, first element, compact -> array depth 3, compact",
+		"array depth 3, compact -> object depth 2, expecting key",
+		"object depth 2, expecting key -> array depth 1",
+		"array depth 1 -> root depth 0",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestFormatWithTraceMatchesFormat(t *testing.T) {
+	inputs := []string{
+		`"plain"`,
+		`{"users":[{"id":1,"name":"Alice","tags":["admin","ops"]},{"id":2,"name":"Bob"}],"meta":{"a":{"b":{"c":1,"d":[1,2,3]}}}}`,
+	}
+	configs := map[string]*Config{
+		"default":  DefaultConfig(),
+		"expanded": NewConfig(WithCompactDepth(0)),
+		"width":    NewConfig(WithMaxWidth(30)),
+		"summary":  NewConfig(WithMaxWidth(30), WithOverflowSummary(1)),
+	}
+
+	for name, config := range configs {
+		for _, input := range inputs {
+			formatter := NewFormatter(config)
+			expected, err := formatter.Format(input)
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %v", name, err)
+			}
+			result, trace, err := formatter.FormatWithTrace(input)
+			if err != nil {
+				t.Fatalf("%s: Unexpected error: %v", name, err)
+			}
+			if result != expected {
+				t.Errorf("%s: Expected:\n%s\n\nGot:\n%s", name, expected, result)
+			}
+			if len(trace.Events) == 0 {
+				t.Errorf("%s: expected trace events", name)
+			}
+		}
+	}
+}
+
+func TestTraceJSON(t *testing.T) {
+	_, trace, err := NewFormatter(DefaultConfig()).FormatWithTrace(`1`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := trace.JSON()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{
+  "events": [
+    {"step": 1, "token": "1", "kind": "value", "path": [], "before": {"depth": 0, "context": "root", "firstElement": true, "expectingKey": false, "compact": false}, "after": {"depth": 0, "context": "root", "firstElement": false, "expectingKey": false, "compact": false}, "output": "1"}
+  ]
+}`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestTraceDOT(t *testing.T) {
+	_, trace, err := NewFormatter(DefaultConfig()).FormatWithTrace(`[1,2,3]`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `digraph trace {
+	node [shape=box];
+	s0 [label="root depth 0, first element"];
+	s1 [label="array depth 1, first element"];
+	s2 [label="array depth 1"];
+	s3 [label="root depth 0"];
+	s0 -> s1 [label="open ×1"];
+	s1 -> s2 [label="value ×1"];
+	s2 -> s2 [label="value ×2"];
+	s2 -> s3 [label="close ×1"];
+}
+`
+	if result := trace.DOT(); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFormatWithTraceError(t *testing.T) {
+	_, trace, err := NewFormatter(DefaultConfig()).FormatWithTrace(`{"a":`)
+	if err == nil {
+		t.Fatal("Expected an error for truncated input")
+	}
+	if trace != nil {
+		t.Errorf("Expected no trace on error, got %d events", len(trace.Events))
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Walker finds the JSON files in directory trees, for tools that format
+// many files. The zero value finds .json files, does not follow symbolic
+// links, and skips files excluded by .gitignore files.
+type Walker struct {
+	// Extensions lists the file name extensions of the files to find,
+	// including the dot. Default is .json.
+	Extensions []string
+
+	// FollowSymlinks follows symbolic links to files and directories found
+	// in the trees. Links given as roots are always followed.
+	FollowSymlinks bool
+
+	// NoGitignore disables .gitignore handling. By default, files and
+	// directories excluded by the .gitignore files of the walked trees and
+	// of the repository enclosing them are skipped.
+	NoGitignore bool
+
+	// MaxFileSize skips files larger than this many bytes. A value of 0
+	// disables the limit.
+	MaxFileSize int64
+}
+
+// SkippedFile is a file or directory that Walk skipped because it cannot
+// be used, such as an unreadable or too large file.
+type SkippedFile struct {
+	Path   string
+	Reason error
+}
+
+// Walk returns the files found in the roots, in lexical order per root.
+// Roots that are files are returned whatever their extension. Files and
+// directories that cannot be read or are too large are skipped instead of
+// aborting the walk, and returned with the reason. Files excluded by
+// .gitignore, symbolic links not followed, and .git directories are left
+// out silently.
+//
+// Example:
+//
+//	files, skipped := (&Walker{MaxFileSize: 10 << 20}).Walk("configs")
+//	for _, s := range skipped {
+//	    log.Printf("skipping %s: %v", s.Path, s.Reason)
+//	}
+func (w *Walker) Walk(roots ...string) (files []string, skipped []SkippedFile) {
+	walk := &walkState{walker: w, visited: map[string]bool{}}
+	for _, root := range roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			walk.skip(root, err)
+			continue
+		}
+		if !info.IsDir() {
+			walk.addFile(root, info)
+			continue
+		}
+		absolute, err := filepath.Abs(root)
+		if err != nil {
+			walk.skip(root, err)
+			continue
+		}
+		var rules []ignoreRule
+		if !w.NoGitignore {
+			rules = enclosingGitignores(absolute)
+		}
+		walk.walkDir(root, absolute, rules)
+	}
+	return walk.files, walk.skipped
+}
+
+// walkState collects the results of one Walk call
+type walkState struct {
+	walker  *Walker
+	files   []string
+	skipped []SkippedFile
+	visited map[string]bool // Resolved paths of walked directories, to stop at symbolic link cycles
+}
+
+// skip records a file or directory that cannot be used
+func (s *walkState) skip(name string, reason error) {
+	s.skipped = append(s.skipped, SkippedFile{Path: name, Reason: reason})
+}
+
+// walkDir walks a directory, given by the path to report and its absolute
+// path for matching .gitignore rules
+func (s *walkState) walkDir(dir, absolute string, rules []ignoreRule) {
+	resolved, err := filepath.EvalSymlinks(absolute)
+	if err != nil {
+		s.skip(dir, err)
+		return
+	}
+	if s.visited[resolved] {
+		return
+	}
+	s.visited[resolved] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.skip(dir, err)
+		return
+	}
+	if !s.walker.NoGitignore {
+		rules = append(rules[:len(rules):len(rules)], readGitignore(absolute)...)
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		entryAbsolute := filepath.Join(absolute, entry.Name())
+		isDir := entry.IsDir()
+		var info os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 {
+			if !s.walker.FollowSymlinks {
+				continue
+			}
+			if info, err = os.Stat(name); err != nil {
+				s.skip(name, err)
+				continue
+			}
+			isDir = info.IsDir()
+		}
+		if isDir && entry.Name() == ".git" {
+			continue
+		}
+		if ignored(rules, filepath.ToSlash(entryAbsolute), isDir) {
+			continue
+		}
+
+		if isDir {
+			s.walkDir(name, entryAbsolute, rules)
+			continue
+		}
+		if !s.walker.hasExtension(entry.Name()) {
+			continue
+		}
+		if info == nil {
+			if info, err = entry.Info(); err != nil {
+				s.skip(name, err)
+				continue
+			}
+		}
+		s.addFile(name, info)
+	}
+}
+
+// addFile records a file if it is a regular file that is readable and
+// within the size limit
+func (s *walkState) addFile(name string, info os.FileInfo) {
+	if !info.Mode().IsRegular() {
+		s.skip(name, fmt.Errorf("not a regular file"))
+		return
+	}
+	if limit := s.walker.MaxFileSize; limit > 0 && info.Size() > limit {
+		s.skip(name, fmt.Errorf("file too large (%d bytes, limit %d)", info.Size(), limit))
+		return
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		s.skip(name, err)
+		return
+	}
+	file.Close()
+	s.files = append(s.files, name)
+}
+
+// hasExtension reports whether the file name has one of the extensions
+func (w *Walker) hasExtension(name string) bool {
+	extensions := w.Extensions
+	if len(extensions) == 0 {
+		extensions = []string{".json"}
+	}
+	for _, extension := range extensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingGitignores returns the rules of the .gitignore files between the
+// root of the git repository enclosing dir and the parent of dir, so that
+// walking a subdirectory skips the same files as walking the repository
+func enclosingGitignores(dir string) []ignoreRule {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		// dir is the repository root itself
+		return nil
+	}
+	var parents []string
+	for current := filepath.Dir(dir); ; current = filepath.Dir(current) {
+		parents = append(parents, current)
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(current) == current {
+			// Not in a repository
+			return nil
+		}
+	}
+
+	var rules []ignoreRule
+	for i := len(parents) - 1; i >= 0; i-- {
+		rules = append(rules, readGitignore(parents[i])...)
+	}
+	return rules
+}
@@ -0,0 +1,175 @@
+package jsonformat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// makeTree creates files in dir from a map of slash-separated paths to
+// contents. Paths ending with a slash are directories.
+func makeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatalf("Failed to create %s: %v", path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+// relativePaths returns the paths relative to dir with slashes
+func relativePaths(t *testing.T, dir string, paths []string) []string {
+	t.Helper()
+	var result []string
+	for _, path := range paths {
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			t.Fatalf("Failed to make %s relative: %v", path, err)
+		}
+		result = append(result, filepath.ToSlash(relative))
+	}
+	return result
+}
+
+func TestWalker(t *testing.T) {
+	base := t.TempDir()
+	makeTree(t, base, map[string]string{
+		"repo/.git/config.json": "{}",
+		"repo/.gitignore":       "# generated\nbuild/\n*.tmp.json\n!keep.tmp.json\n/top.json\nsub/deep/*.json\n",
+		"repo/a.json":           "{}",
+		"repo/b.txt":            "{}",
+		"repo/big.json":         `{"padding":"` + strings.Repeat("x", 100) + `"}`,
+		"repo/build/c.json":     "{}",
+		"repo/keep.tmp.json":    "{}",
+		"repo/x.tmp.json":       "{}",
+		"repo/top.json":         "{}",
+		"repo/sub/.gitignore":   "d.json\n",
+		"repo/sub/d.json":       "{}",
+		"repo/sub/top.json":     "{}",
+		"repo/sub/deep/e.json":  "{}",
+		"repo/sub/deep/f.jsonc": "{}",
+		"other/linked.json":     "{}",
+	})
+	symlinks := map[string]string{
+		"repo/link.json":   "repo/a.json",
+		"repo/linkdir":     "other",
+		"repo/broken.json": "repo/missing.json",
+		"repo/loop":        "repo",
+	}
+	for link, target := range symlinks {
+		if err := os.Symlink(filepath.Join(base, target), filepath.Join(base, link)); err != nil {
+			t.Skipf("Symbolic links are not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		walker   Walker
+		roots    []string
+		expected []string
+		skipped  []string
+	}{
+		{
+			name:     "defaults",
+			walker:   Walker{MaxFileSize: 100},
+			roots:    []string{"repo"},
+			expected: []string{"repo/a.json", "repo/keep.tmp.json", "repo/sub/top.json"},
+			skipped:  []string{"repo/big.json"},
+		},
+		{
+			name:     "without gitignore",
+			walker:   Walker{NoGitignore: true, Extensions: []string{".json", ".jsonc"}},
+			roots:    []string{"repo"},
+			expected: []string{"repo/a.json", "repo/big.json", "repo/build/c.json", "repo/keep.tmp.json", "repo/sub/d.json", "repo/sub/deep/e.json", "repo/sub/deep/f.jsonc", "repo/sub/top.json", "repo/top.json", "repo/x.tmp.json"},
+		},
+		{
+			name:     "following symbolic links",
+			walker:   Walker{FollowSymlinks: true},
+			roots:    []string{"repo"},
+			expected: []string{"repo/a.json", "repo/big.json", "repo/keep.tmp.json", "repo/link.json", "repo/linkdir/linked.json", "repo/sub/top.json"},
+			skipped:  []string{"repo/broken.json"},
+		},
+		{
+			name:     "subdirectory of a repository",
+			roots:    []string{"repo/sub"},
+			expected: []string{"repo/sub/top.json"},
+		},
+		{
+			name:     "explicit files",
+			roots:    []string{"repo/b.txt", "repo/link.json", "repo/missing.json"},
+			expected: []string{"repo/b.txt", "repo/link.json"},
+			skipped:  []string{"repo/missing.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var roots []string
+			for _, root := range tt.roots {
+				roots = append(roots, filepath.Join(base, root))
+			}
+			files, skipped := tt.walker.Walk(roots...)
+
+			if got := relativePaths(t, base, files); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected files:\n%v\n\nGot:\n%v", tt.expected, got)
+			}
+			var skippedPaths []string
+			for _, s := range skipped {
+				if s.Reason == nil {
+					t.Errorf("Expected a reason for skipping %s", s.Path)
+				}
+				skippedPaths = append(skippedPaths, s.Path)
+			}
+			if got := relativePaths(t, base, skippedPaths); !reflect.DeepEqual(got, tt.skipped) {
+				t.Errorf("Expected skipped files:\n%v\n\nGot:\n%v", tt.skipped, got)
+			}
+		})
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"*.json", "/r/a.json", false, true},
+		{"*.json", "/r/x/y/a.json", false, true},
+		{"/a.json", "/r/x/a.json", false, false},
+		{"/a.json", "/r/a.json", false, true},
+		{"x/*.json", "/r/x/a.json", false, true},
+		{"x/*.json", "/r/y/x/a.json", false, false},
+		{"**/x/*.json", "/r/y/x/a.json", false, true},
+		{"x/**", "/r/x/y/z.json", false, true},
+		{"build/", "/r/build", true, true},
+		{"build/", "/r/build", false, false},
+		{`\#file.json`, "/r/#file.json", false, true},
+		{"# comment", "/r/# comment", false, false},
+		{"*.json", "/other/a.json", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			var rules []ignoreRule
+			if rule, ok := parseIgnoreRule("/r", tt.pattern); ok {
+				rules = append(rules, rule)
+			}
+			if got := ignored(rules, tt.path, tt.isDir); got != tt.ignored {
+				t.Errorf("Expected ignored=%v, got %v", tt.ignored, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// WarningCode identifies the kind of a Warning. Codes are stable across releases.
+type WarningCode string
+
+const (
+	// WarningPrecisionLoss reports a number that cannot be represented exactly
+	// as a float64, so the formatted output differs from the input.
+	WarningPrecisionLoss WarningCode = "precision_loss"
+	// WarningDuplicateKey reports a key that appears more than once in an object.
+	WarningDuplicateKey WarningCode = "duplicate_key"
+	// WarningInvalidUTF8 reports a string whose invalid UTF-8 bytes were
+	// replaced with U+FFFD.
+	WarningInvalidUTF8 WarningCode = "invalid_utf8"
+)
+
+// Warning describes a recoverable issue found while formatting. Unlike errors,
+// warnings do not stop formatting.
+type Warning struct {
+	// Code identifies the kind of warning.
+	Code WarningCode
+
+	// Message is a human-readable description of the issue.
+	Message string
+
+	// Path contains the object keys and array indices leading to the key or
+	// value, in the same form as ValueContext.Path.
+	Path []string
+}
+
+// String returns the warning as "path: message", or just the message for the root value.
+func (w Warning) String() string {
+	if len(w.Path) == 0 {
+		return w.Message
+	}
+	return strings.Join(w.Path, ".") + ": " + w.Message
+}
+
+// FormatWithWarnings formats a JSON string like Format and also returns the
+// warnings for recoverable issues in the input: numbers that lose precision,
+// duplicate object keys, and invalid UTF-8 that was replaced. Keys that break
+// the lint rules set with WithMaxKeyLength, WithKeyPattern, and WithForbiddenKeys
+// are reported as well. Callers can surface warnings without failing the whole
+// format.
+//
+// The cache set with SetCache is not used.
+//
+// Example:
+//
+//	formatted, warnings, err := formatter.FormatWithWarnings(input)
+//	for _, w := range warnings {
+//	    log.Printf("warning: %s", w)
+//	}
+func (f *Formatter) FormatWithWarnings(jsonStr string) (result string, warnings []Warning, err error) {
+	// Implement panic recovery to handle unexpected errors gracefully
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+			result = ""
+			warnings = nil
+		}
+	}()
+
+	result, parser, err := f.formatString(jsonStr, func(p *TokenParser) { p.collectingWarnings = true })
+	if err != nil {
+		return "", nil, err
+	}
+	return result, parser.warnings, nil
+}
+
+// warn records a warning
+func (p *TokenParser) warn(code WarningCode, path []string, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, Warning{Code: code, Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+// checkToken records warnings for a token read from the input
+func (p *TokenParser) checkToken(token json.Token) {
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '}' {
+			p.warnMissingRequired()
+		}
+	case float64:
+		p.checkPrecision(value)
+	case string:
+		isKey := p.expectingKey && p.depth > 0 && !p.isInArray()
+		path := p.valuePath()
+		if isKey && len(path) > 0 {
+			path[len(path)-1] = value
+			p.checkDuplicateKey(value, path)
+			p.lintKey(value, path)
+		}
+		if strings.ContainsRune(value, utf8.RuneError) && !utf8.ValidString(p.input) {
+			p.warn(WarningInvalidUTF8, path, "invalid UTF-8 replaced with U+FFFD")
+		}
+	}
+}
+
+// checkDuplicateKey records a warning when the key was already used in the current object
+func (p *TokenParser) checkDuplicateKey(key string, path []string) {
+	// Keys are recorded when they are written, so earlier keys are already known
+	if p.path[len(p.path)-1].keys[key] {
+		p.warn(WarningDuplicateKey, path, "duplicate key %q", key)
+	}
+}
+
+// checkPrecision records a warning when the number literal just read from the
+// input changes its value when formatted as a float64
+func (p *TokenParser) checkPrecision(value float64) {
+	if p.input == "" || p.replaying || isSpecialFloat(value) {
+		return
+	}
+	end := int(p.decoder.InputOffset())
+	if end > len(p.input) {
+		return
+	}
+	start := end
+	for start > 0 && strings.IndexByte("0123456789+-.eE", p.input[start-1]) >= 0 {
+		start--
+	}
+	literal := p.input[start:end]
+
+	formatted, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	original, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return
+	}
+	output, ok := new(big.Rat).SetString(string(formatted))
+	if ok && original.Cmp(output) != 0 {
+		p.warn(WarningPrecisionLoss, p.valuePath(), "number %s loses precision and is written as %s", literal, formatted)
+	}
+}
@@ -0,0 +1,70 @@
+package jsonformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatWithWarnings(t *testing.T) {
+	input := "{\"id\":12345678901234567890,\"ratio\":0.1,\"big\":1e2,\"name\":\"x\xff\",\"id\":1,\"list\":[1.00000000000000000001,{\"a\":1,\"a\":2}]}"
+	result, warnings, err := NewFormatter(DefaultConfig()).FormatWithWarnings(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected, err := NewFormatter(DefaultConfig()).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != expected {
+		t.Errorf("Expected output:\n%s\n\nGot:\n%s", expected, result)
+	}
+
+	expectedWarnings := []Warning{
+		{Code: WarningPrecisionLoss, Message: "number 12345678901234567890 loses precision and is written as 12345678901234567000", Path: []string{"id"}},
+		{Code: WarningInvalidUTF8, Message: "invalid UTF-8 replaced with U+FFFD", Path: []string{"name"}},
+		{Code: WarningDuplicateKey, Message: `duplicate key "id"`, Path: []string{"id"}},
+		{Code: WarningPrecisionLoss, Message: "number 1.00000000000000000001 loses precision and is written as 1", Path: []string{"list", "0"}},
+		{Code: WarningDuplicateKey, Message: `duplicate key "a"`, Path: []string{"list", "1", "a"}},
+	}
+	if !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("Expected warnings:\n%#v\n\nGot:\n%#v", expectedWarnings, warnings)
+	}
+}
+
+func TestFormatWithWarningsWidthReplay(t *testing.T) {
+	// Elements re-formatted after exceeding the width limit are checked only once
+	config := NewConfig(WithMaxWidth(20))
+	_, warnings, err := NewFormatter(config).FormatWithWarnings(`{"items":[{"key":"a long value here","key":1}]}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarningDuplicateKey {
+		t.Errorf("Expected one duplicate key warning, got %v", warnings)
+	}
+}
+
+func TestFormatWithWarningsClean(t *testing.T) {
+	_, warnings, err := NewFormatter(DefaultConfig()).FormatWithWarnings(`{"a":[1,2.5,-3e-2],"b":{"a":true}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	if _, _, err := NewFormatter(DefaultConfig()).FormatWithWarnings(`{"a":`); err == nil {
+		t.Error("Expected error for incomplete JSON")
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	w := Warning{Code: WarningDuplicateKey, Message: `duplicate key "a"`, Path: []string{"list", "1", "a"}}
+	if got := w.String(); got != `list.1.a: duplicate key "a"` {
+		t.Errorf("Unexpected string: %q", got)
+	}
+	w.Path = nil
+	if got := w.String(); got != `duplicate key "a"` {
+		t.Errorf("Unexpected string: %q", got)
+	}
+}
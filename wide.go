@@ -0,0 +1,125 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// WithWideObjectFold writes objects with more than keys members, such as maps
+// with thousands of entries, as their first keys members followed by a line
+// counting the rest, so the output stays readable and its size bounded. The
+// members left out are skipped as they are read, without being held in
+// memory, and do not count toward the limit of WithMaxTokens. A value of 0
+// disables folding. The output is no longer valid JSON, so use this option
+// for display only.
+//
+// Example:
+//
+//	config := NewConfig(WithWideObjectFold(2))
+//	result, err := NewFormatter(config).Format(objectWith20000Keys)
+//	// {
+//	//   "k00001": 1,
+//	//   "k00002": 2,
+//	//   … 19998 more keys
+//	// }
+func WithWideObjectFold(keys int) ConfigOption {
+	return func(c *Config) {
+		if keys >= 0 {
+			c.WideObjectKeys = keys
+		}
+	}
+}
+
+// foldedMembers is a token that stands for the members left out of a wide object
+type foldedMembers int
+
+// text returns the line that replaces the members
+func (n foldedMembers) text() string {
+	if n == 1 {
+		return "… 1 more key"
+	}
+	return "… " + strconv.Itoa(int(n)) + " more keys"
+}
+
+// startHiding counts the members of a wide object, and reports whether the
+// member starting with the key token is left out
+func (p *TokenParser) startHiding(token json.Token) bool {
+	if p.config.WideObjectKeys == 0 || p.config.StrictJSON {
+		return false
+	}
+	if _, ok := token.(string); !ok || !p.expectingKey || len(p.path) == 0 || len(p.path) != len(p.inArray) || p.isInArray() {
+		return false
+	}
+	level := &p.path[len(p.path)-1]
+	if level.members < p.config.WideObjectKeys {
+		level.members++
+		return false
+	}
+	level.folded++
+	p.hiding = true
+	p.hiddenDepth = 0
+	return true
+}
+
+// hideToken skips a token of the value of a member that is left out
+func (p *TokenParser) hideToken(token json.Token) error {
+	if delim, ok := token.(json.Delim); ok {
+		if delim == '{' || delim == '[' {
+			p.hiddenDepth++
+		} else {
+			p.hiddenDepth--
+		}
+	}
+	if p.hiddenDepth == 0 {
+		p.hiding = false
+	}
+	return nil
+}
+
+// emitFolded writes the count of the members or elements left out before
+// the object or array closes
+func (p *TokenParser) emitFolded(token json.Token) error {
+	if len(p.path) == 0 || len(p.path) != len(p.inArray) {
+		return nil
+	}
+	folded := p.path[len(p.path)-1].folded
+	switch {
+	case folded == 0:
+		return nil
+	case token == json.Delim('}') && !p.isInArray():
+		return p.emitToken(foldedMembers(folded))
+	case token == json.Delim(']') && p.isInArray():
+		return p.emitToken(foldedElements(folded))
+	}
+	return nil
+}
+
+// handleFolded writes the line counting the members left out
+func (p *TokenParser) handleFolded(n foldedMembers) error {
+	// The line is not a member, so no section comment precedes it
+	p.expectingKey = false
+	err := p.writeElementPrefix()
+	p.expectingKey = true
+	if err != nil {
+		return err
+	}
+	if _, err := p.builder.WriteString(n.text()); err != nil {
+		return WrapFormatError("failed to write folded members", err)
+	}
+	p.isFirstElement = false
+	return nil
+}
@@ -0,0 +1,137 @@
+package jsonformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// wideObject returns an object with the given number of members
+func wideObject(keys int) string {
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i := 0; i < keys; i++ {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		fmt.Fprintf(&builder, `"k%05d":{"v":[%d]}`, i, i)
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+func TestWideObjectFold(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "folded members",
+			input:   `{"a":1,"b":{"c":[1,2]},"d":3,"e":4}`,
+			options: []ConfigOption{WithWideObjectFold(2), WithCompactDepth(0)},
+			expected: `{
+  "a": 1,
+  "b": {
+    "c": [
+      1,
+      2
+    ]
+  },
+  … 2 more keys
+}`,
+		},
+		{
+			name:    "one more key",
+			input:   `{"a":1,"b":2,"c":3}`,
+			options: []ConfigOption{WithWideObjectFold(2)},
+			expected: `{
+  "a": 1,
+  "b": 2,
+  … 1 more key
+}`,
+		},
+		{
+			name:     "compact object",
+			input:    `{"a":{"b":1,"c":2,"d":3}}`,
+			options:  []ConfigOption{WithWideObjectFold(1), WithCompactDepth(1)},
+			expected: `{"a": {"b": 1, … 2 more keys}}`,
+		},
+		{
+			name:    "objects in arrays",
+			input:   `[{"a":1,"b":2},{"a":1}]`,
+			options: []ConfigOption{WithWideObjectFold(1), WithCompactDepth(2)},
+			expected: `[
+  {"a": 1, … 1 more key},
+  {"a": 1}
+]`,
+		},
+		{
+			name:    "leading commas",
+			input:   `{"a":1,"b":2}`,
+			options: []ConfigOption{WithWideObjectFold(1), WithLeadingCommas()},
+			expected: `{
+  "a": 1
+  , … 1 more key
+}`,
+		},
+		{
+			name:    "key order",
+			input:   `{"a":1,"b":2,"c":3}`,
+			options: []ConfigOption{WithWideObjectFold(1), WithKeyOrder("", "c")},
+			expected: `{
+  "c": 3,
+  … 2 more keys
+}`,
+		},
+		{
+			name:     "strict JSON",
+			input:    `{"a":1,"b":2}`,
+			options:  []ConfigOption{WithWideObjectFold(1), WithStrictJSON(), WithCompactDepth(1)},
+			expected: `{"a": 1, "b": 2}`,
+		},
+		{
+			name:    "thousands of keys",
+			input:   wideObject(20000),
+			options: []ConfigOption{WithWideObjectFold(2), WithCompactDepth(2)},
+			expected: `{
+  "k00000": {"v": [0]},
+  "k00001": {"v": [1]},
+  … 19998 more keys
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	input := wideObject(5000)
+
+	_, err := NewFormatter(NewConfig()).Format(input)
+	if err == nil || !strings.Contains(err.Error(), "too many tokens") {
+		t.Errorf("Expected too many tokens error, got %v", err)
+	}
+
+	result, err := NewFormatter(NewConfig(WithMaxTokens(0), WithCompactDepth(2), WithKeyOrder("", "k04999", "*"))).Format(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result, "{\n  \"k04999\": {\"v\": [4999]},\n  \"k00000\": {\"v\": [0]},") {
+		t.Errorf("Unexpected output: %s", result[:80])
+	}
+	if lines := strings.Count(result, "\n"); lines != 5001 {
+		t.Errorf("Expected 5001 line breaks, got %d", lines)
+	}
+}
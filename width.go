@@ -0,0 +1,211 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// WidthFunc returns the number of terminal columns text occupies. It is used
+// to measure lines against the width limit.
+type WidthFunc func(s string) int
+
+// WithWidthFunc sets how the display width of lines is measured against
+// MaxWidth and WidthByDepth. The default, DisplayWidth, counts East Asian wide
+// characters and emoji as two columns and combining marks as none; use
+// DisplayWidthAmbiguousWide for terminals that also render East Asian
+// ambiguous characters, such as Greek and box drawing, two columns wide. A nil
+// function restores the default.
+//
+// Example:
+//
+//	config := NewConfig(WithMaxWidth(80), WithWidthFunc(DisplayWidthAmbiguousWide))
+func WithWidthFunc(width WidthFunc) ConfigOption {
+	return func(c *Config) {
+		c.Width = width
+	}
+}
+
+// DisplayWidth returns the number of terminal columns s occupies following
+// the East Asian Width rules of Unicode, with ambiguous characters counted as
+// one column. Grapheme clusters are measured as a whole: combining marks,
+// emoji modifiers, and characters joined by a zero width joiner add no width,
+// a pair of regional indicators forms one flag, and an emoji presentation
+// selector makes the preceding character two columns wide.
+func DisplayWidth(s string) int {
+	return displayWidth(s, false)
+}
+
+// DisplayWidthAmbiguousWide returns the number of terminal columns s occupies
+// like DisplayWidth, but counts East Asian ambiguous characters as two
+// columns, as terminals configured for CJK locales render them.
+func DisplayWidthAmbiguousWide(s string) int {
+	return displayWidth(s, true)
+}
+
+// displayWidth returns the display width of s, counting ambiguous characters
+// as two columns if ambiguousWide is set
+func displayWidth(s string, ambiguousWide bool) int {
+	width := 0
+	last := 0       // Width of the last rune that started a grapheme cluster
+	joined := false // Whether the previous rune was a zero width joiner
+	indicators := 0 // Number of consecutive regional indicators
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			// Fast path for ASCII, where only printable characters have width
+			joined, indicators = false, 0
+			if r >= 0x20 && r < 0x7f {
+				width++
+				last = 1
+			} else {
+				last = 0
+			}
+			continue
+		}
+		switch {
+		case joined:
+			// Joined emoji render as one glyph
+			joined = false
+			continue
+		case r == 0x200d:
+			joined = true
+			continue
+		case r == 0xfe0f:
+			// Emoji presentation widens a text symbol to two columns
+			if last == 1 {
+				width++
+				last = 2
+			}
+			continue
+		case r >= 0x1f1e6 && r <= 0x1f1ff:
+			// A pair of regional indicators forms one flag
+			indicators++
+			if indicators%2 == 0 {
+				continue
+			}
+			width += 2
+			last = 2
+			continue
+		}
+		indicators = 0
+		w := runeWidth(r, ambiguousWide)
+		width += w
+		if w > 0 {
+			last = w
+		}
+	}
+	return width
+}
+
+// runeWidth returns the number of columns of a rune outside a grapheme cluster
+func runeWidth(r rune, ambiguousWide bool) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || (r >= 0x1160 && r <= 0x11ff) || (r >= 0x1f3fb && r <= 0x1f3ff):
+		// Combining marks, format characters, Hangul medial vowels and
+		// final consonants, and emoji modifiers join the preceding character
+		return 0
+	case inRanges(r, wideRanges):
+		return 2
+	case ambiguousWide && inRanges(r, ambiguousRanges):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// runeRange is an inclusive range of code points
+type runeRange struct {
+	first, last rune
+}
+
+// inRanges reports whether the rune is in one of the sorted ranges
+func inRanges(r rune, ranges []runeRange) bool {
+	low, high := 0, len(ranges)
+	for low < high {
+		mid := (low + high) / 2
+		switch {
+		case r < ranges[mid].first:
+			high = mid
+		case r > ranges[mid].last:
+			low = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// wideRanges lists the East Asian Wide and Fullwidth characters, including
+// emoji with default emoji presentation
+var wideRanges = []runeRange{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x17000, 0x18aff}, {0x1b000, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f251}, {0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7}, {0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945}, {0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+// ambiguousRanges lists the most common East Asian Ambiguous characters:
+// Latin-1 symbols, Greek, Cyrillic, punctuation, arrows, mathematical
+// operators, box drawing, geometric shapes, and private use characters
+var ambiguousRanges = []runeRange{
+	{0xa1, 0xa1}, {0xa4, 0xa4}, {0xa7, 0xa8}, {0xaa, 0xaa}, {0xae, 0xae},
+	{0xb0, 0xb4}, {0xb6, 0xba}, {0xbc, 0xbf}, {0xc6, 0xc6}, {0xd0, 0xd0},
+	{0xd7, 0xd8}, {0xde, 0xe1}, {0xe6, 0xe6}, {0xe8, 0xea}, {0xec, 0xed},
+	{0xf0, 0xf0}, {0xf2, 0xf3}, {0xf7, 0xfa}, {0xfc, 0xfc}, {0xfe, 0xfe},
+	{0x391, 0x3a9}, {0x3b1, 0x3c9}, {0x401, 0x401}, {0x410, 0x44f}, {0x451, 0x451},
+	{0x2010, 0x2010}, {0x2013, 0x2016}, {0x2018, 0x2019}, {0x201c, 0x201d},
+	{0x2020, 0x2022}, {0x2024, 0x2027}, {0x2030, 0x2030}, {0x2032, 0x2033},
+	{0x2035, 0x2035}, {0x203b, 0x203b}, {0x203e, 0x203e}, {0x20ac, 0x20ac},
+	{0x2103, 0x2103}, {0x2105, 0x2105}, {0x2109, 0x2109}, {0x2113, 0x2113},
+	{0x2116, 0x2116}, {0x2121, 0x2122}, {0x2126, 0x2126}, {0x212b, 0x212b},
+	{0x2153, 0x2154}, {0x215b, 0x215e}, {0x2160, 0x216b}, {0x2170, 0x2179},
+	{0x2190, 0x2199}, {0x21d2, 0x21d2}, {0x21d4, 0x21d4}, {0x2200, 0x2200},
+	{0x2202, 0x2203}, {0x2207, 0x2208}, {0x220b, 0x220b}, {0x220f, 0x220f},
+	{0x2211, 0x2211}, {0x2215, 0x2215}, {0x221a, 0x221a}, {0x221d, 0x2220},
+	{0x2223, 0x2223}, {0x2225, 0x2225}, {0x2227, 0x222c}, {0x222e, 0x222e},
+	{0x2234, 0x2237}, {0x223c, 0x223d}, {0x2248, 0x2248}, {0x224c, 0x224c},
+	{0x2252, 0x2252}, {0x2260, 0x2261}, {0x2264, 0x2267}, {0x226a, 0x226b},
+	{0x226e, 0x226f}, {0x2282, 0x2283}, {0x2286, 0x2287}, {0x2295, 0x2295},
+	{0x2299, 0x2299}, {0x22a5, 0x22a5}, {0x22bf, 0x22bf}, {0x2312, 0x2312},
+	{0x2460, 0x24e9}, {0x24eb, 0x254b}, {0x2550, 0x2573}, {0x2580, 0x258f},
+	{0x2592, 0x2595}, {0x25a0, 0x25a1}, {0x25a3, 0x25a9}, {0x25b2, 0x25b3},
+	{0x25b6, 0x25b7}, {0x25bc, 0x25bd}, {0x25c0, 0x25c1}, {0x25c6, 0x25c8},
+	{0x25cb, 0x25cb}, {0x25ce, 0x25d1}, {0x25e2, 0x25e5}, {0x25ef, 0x25ef},
+	{0x2605, 0x2606}, {0x2609, 0x2609}, {0x260e, 0x260f}, {0x261c, 0x261c},
+	{0x261e, 0x261e}, {0x2640, 0x2640}, {0x2642, 0x2642}, {0x2660, 0x2661},
+	{0x2663, 0x2665}, {0x2667, 0x266a}, {0x266c, 0x266d}, {0x266f, 0x266f},
+	{0x273d, 0x273d}, {0x2776, 0x277f}, {0xe000, 0xf8ff}, {0xfffd, 0xfffd},
+}
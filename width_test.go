@@ -0,0 +1,98 @@
+package jsonformat
+
+import (
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      int
+		ambiguousWide int
+	}{
+		{name: "ASCII", input: `"name": "Alice"`, expected: 15, ambiguousWide: 15},
+		{name: "CJK", input: "日本語", expected: 6, ambiguousWide: 6},
+		{name: "hangul", input: "한국어", expected: 6, ambiguousWide: 6},
+		{name: "fullwidth", input: "ＡＢ", expected: 4, ambiguousWide: 4},
+		{name: "combining mark", input: "é", expected: 1, ambiguousWide: 1},
+		{name: "emoji", input: "🎉", expected: 2, ambiguousWide: 2},
+		{name: "emoji modifier", input: "👍🏽", expected: 2, ambiguousWide: 2},
+		{name: "zero width joiner sequence", input: "👨‍👩‍👧", expected: 2, ambiguousWide: 2},
+		{name: "flag", input: "🇯🇵", expected: 2, ambiguousWide: 2},
+		{name: "two flags", input: "🇯🇵🇫🇷", expected: 4, ambiguousWide: 4},
+		{name: "emoji presentation", input: "❤️", expected: 2, ambiguousWide: 2},
+		{name: "ambiguous", input: "αβ→", expected: 3, ambiguousWide: 6},
+		{name: "control characters", input: "a\tb", expected: 2, ambiguousWide: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DisplayWidth(tt.input); got != tt.expected {
+				t.Errorf("DisplayWidth(%q) = %d, expected %d", tt.input, got, tt.expected)
+			}
+			if got := DisplayWidthAmbiguousWide(tt.input); got != tt.ambiguousWide {
+				t.Errorf("DisplayWidthAmbiguousWide(%q) = %d, expected %d", tt.input, got, tt.ambiguousWide)
+			}
+		})
+	}
+}
+
+func TestMaxWidthDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			// 31 runes, but 41 columns
+			name:    "CJK exceeds width",
+			input:   `{"a":{"名前":"山田太郎","国":"日本"}}`,
+			options: []ConfigOption{WithMaxWidth(35), WithCompactDepth(1)},
+			expected: `{
+  "a": {
+    "名前": "山田太郎",
+    "国": "日本"
+  }
+}`,
+		},
+		{
+			name:     "CJK fits width",
+			input:    `{"a":{"名前":"山田太郎","国":"日本"}}`,
+			options:  []ConfigOption{WithMaxWidth(41), WithCompactDepth(1)},
+			expected: `{"a": {"名前": "山田太郎", "国": "日本"}}`,
+		},
+		{
+			name:    "ambiguous wide",
+			input:   `{"a":["α","β","γ","δ"]}`,
+			options: []ConfigOption{WithMaxWidth(29), WithCompactDepth(1), WithWidthFunc(DisplayWidthAmbiguousWide)},
+			expected: `{
+  "a": [
+    "α",
+    "β",
+    "γ",
+    "δ"
+  ]
+}`,
+		},
+		{
+			name:     "ambiguous narrow",
+			input:    `{"a":["α","β","γ","δ"]}`,
+			options:  []ConfigOption{WithMaxWidth(29), WithCompactDepth(1)},
+			expected: `{"a": ["α", "β", "γ", "δ"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(NewConfig(tt.options...)).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
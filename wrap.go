@@ -0,0 +1,124 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import "strings"
+
+// continuationMarker starts each continuation of a line broken by WithHardWrap
+const continuationMarker = "↪ "
+
+// wrapBreaks are the characters after which long lines are preferably broken
+const wrapBreaks = " /&?,;=-"
+
+// WithHardWrap breaks every output line wider than columns, such as lines
+// with long string values or URLs, so the output stays readable in 80-column
+// terminals and email. Unlike MaxWidth, which decides whether containers are
+// written on one line, it splits the lines themselves: each continuation is
+// indented like the line it continues and starts with "↪ ". Lines are broken
+// after a space or a URL separator when one is near the limit. Widths are
+// measured with the function set with WithWidthFunc.
+//
+// The continuation lines make the output invalid JSON, so this option is for
+// display only, and is ignored with WithStrictJSON. Lines of FormatLog are not
+// wrapped, as each record stays on one line. A value of 0 disables wrapping.
+//
+// Example:
+//
+//	config := NewConfig(WithHardWrap(40))
+//	// {
+//	//   "url": "https://example.com/api/v1/
+//	//   ↪ users/42?fields=name,email"
+//	// }
+func WithHardWrap(columns int) ConfigOption {
+	return func(c *Config) {
+		c.HardWrap = max(columns, 0)
+	}
+}
+
+// rewritesLines reports whether output lines are rewritten after formatting,
+// by a post-processor or by hard wrapping, so offsets into the output no
+// longer match the formatted text
+func (c *Config) rewritesLines() bool {
+	return c.PostProcess != nil || c.hardWraps()
+}
+
+// hardWraps reports whether output lines are broken by WithHardWrap
+func (c *Config) hardWraps() bool {
+	return c.HardWrap > 0 && !c.StrictJSON
+}
+
+// lineProcessor returns the function rewriting complete output lines: the
+// post-processor followed by hard wrapping, or nil if lines are kept
+func (c *Config) lineProcessor(width WidthFunc) PostProcessFunc {
+	if !c.hardWraps() {
+		return c.PostProcess
+	}
+	postProcess, columns := c.PostProcess, c.HardWrap
+	return func(line string, depth int, path string) string {
+		if postProcess != nil {
+			line = postProcess(line, depth, path)
+		}
+		return hardWrap(line, columns, width)
+	}
+}
+
+// hardWrap breaks a line wider than columns into continuation lines
+func hardWrap(line string, columns int, width WidthFunc) string {
+	if width(line) <= columns {
+		return line
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	prefix := line[:indent] + continuationMarker
+	if width(prefix) >= columns {
+		// Deeply indented lines continue at the start of the line
+		prefix = continuationMarker
+	}
+
+	var builder strings.Builder
+	rest, limit := line, columns
+	for {
+		n := wrapPoint(rest, indent, limit, width)
+		builder.WriteString(rest[:n])
+		rest = rest[n:]
+		if rest == "" {
+			return builder.String()
+		}
+		builder.WriteString("\n")
+		builder.WriteString(prefix)
+		indent, limit = 0, columns-width(prefix)
+	}
+}
+
+// wrapPoint returns the length of the part of s written before a break: the
+// longest prefix no wider than limit, shortened to end after a break
+// character if one is in its second half. At least one character after the
+// indentation of the given length is taken.
+func wrapPoint(s string, indent, limit int, width WidthFunc) int {
+	fit, preferred := 0, 0
+	for i, r := range s {
+		end := i + len(string(r))
+		if fit > indent && width(s[:end]) > limit {
+			break
+		}
+		fit = end
+		if end > indent && strings.ContainsRune(wrapBreaks, r) {
+			preferred = end
+		}
+	}
+	if fit == len(s) || preferred == 0 || width(s[:preferred])*2 < limit {
+		return fit
+	}
+	return preferred
+}
@@ -0,0 +1,106 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHardWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  []ConfigOption
+		expected string
+	}{
+		{
+			name:    "short lines are kept",
+			input:   `{"a":"short"}`,
+			options: []ConfigOption{WithHardWrap(40)},
+			expected: `{
+  "a": "short"
+}`,
+		},
+		{
+			name:    "breaks after URL separators",
+			input:   `{"url":"https://example.com/api/v1/users/42?fields=name,email"}`,
+			options: []ConfigOption{WithHardWrap(40)},
+			expected: `{
+  "url": "https://example.com/api/v1/
+  ↪ users/42?fields=name,email"
+}`,
+		},
+		{
+			name:    "breaks at the column without separators",
+			input:   `["abcdefghijklmnopqrstuvwxyz0123456789"]`,
+			options: []ConfigOption{WithHardWrap(20)},
+			expected: `[
+  "abcdefghijklmnopq
+  ↪ rstuvwxyz0123456
+  ↪ 789"
+]`,
+		},
+		{
+			name:    "wide characters",
+			input:   `["日本語の長い文字列です"]`,
+			options: []ConfigOption{WithHardWrap(16)},
+			expected: `[
+  "日本語の長い
+  ↪ 文字列です"
+]`,
+		},
+		{
+			name:    "deep indentation is dropped",
+			input:   `[[["abcdefghijkl"]]]`,
+			options: []ConfigOption{WithHardWrap(8), WithIndentSize(4)},
+			expected: `[
+    [
+        [
+↪ "abcde
+↪ fghijk
+↪ l"]
+    ]
+]`,
+		},
+		{
+			name:    "disabled with strict JSON",
+			input:   `{"url":"https://example.com/api/v1/users/42?fields=name,email"}`,
+			options: []ConfigOption{WithHardWrap(40), WithStrictJSON()},
+			expected: `{
+  "url": "https://example.com/api/v1/users/42?fields=name,email"
+}`,
+		},
+		{
+			name:    "after post-processing",
+			input:   `{"a":"xxxxxxxx"}`,
+			options: []ConfigOption{WithHardWrap(12), WithPostProcess(func(line string, depth int, path string) string { return strings.ToUpper(line) })},
+			expected: `{
+  "A": 
+  ↪ "XXXXXXX
+  ↪ X"
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(NewConfig(tt.options...))
+			result, err := formatter.Format(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestHardWrapRejectsOffsets(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithHardWrap(40)))
+	if _, _, err := formatter.FormatWithAnnotations(`{"a":1}`); err == nil {
+		t.Error("Expected an error for annotations with hard wrapping")
+	}
+	if _, err := formatter.FormatChunk(`{"a":1}`, Quota{Lines: 1}, ""); err == nil {
+		t.Error("Expected an error for chunks with hard wrapping")
+	}
+}
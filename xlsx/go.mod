@@ -0,0 +1,7 @@
+module github.com/shibukawa/jsonformat/xlsx
+
+go 1.24.5
+
+require github.com/shibukawa/jsonformat v0.0.0-00010101000000-000000000000
+
+replace github.com/shibukawa/jsonformat => ../
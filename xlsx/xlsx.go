@@ -0,0 +1,433 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package xlsx converts a sheet of an Excel (XLSX) workbook into a formatted
+// JSON array of row objects.
+//
+// It lives in its own module so that the core jsonformat package stays free
+// of spreadsheet handling code. The first row of the sheet is used as the
+// header row and its cells become the object keys.
+//
+// Basic Usage:
+//
+//	formatted, err := xlsx.ReadFileAndFormat("users.xlsx", "Sheet1", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(formatted)
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// ReadFileAndFormat opens the XLSX file at filename and formats the named
+// sheet as a JSON array of row objects.
+// If sheet is empty, the first sheet in the workbook is used. Repeated
+// header names get a suffix with their occurrence, e.g. "name", "name_2".
+// If config is nil, the default configuration is used. Its MaxTokens limit
+// is not applied to the JSON built from the cells.
+func ReadFileAndFormat(filename, sheet string, config *jsonformat.Config) (string, error) {
+	reader, err := zip.OpenReader(filename)
+	if err != nil {
+		return "", jsonformat.WrapFormatError("failed to open XLSX file", err)
+	}
+	defer reader.Close()
+
+	return formatWorkbook(&reader.Reader, sheet, config)
+}
+
+// ReadAndFormat reads an XLSX workbook of the given size from r and formats
+// the named sheet as a JSON array of row objects.
+// If sheet is empty, the first sheet in the workbook is used. Repeated
+// header names get a suffix with their occurrence, e.g. "name", "name_2".
+// If config is nil, the default configuration is used. Its MaxTokens limit
+// is not applied to the JSON built from the cells.
+func ReadAndFormat(r io.ReaderAt, size int64, sheet string, config *jsonformat.Config) (string, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", jsonformat.WrapFormatError("failed to read XLSX archive", err)
+	}
+
+	return formatWorkbook(reader, sheet, config)
+}
+
+// formatWorkbook locates the sheet inside the archive and formats its rows
+func formatWorkbook(archive *zip.Reader, sheet string, config *jsonformat.Config) (string, error) {
+	sheetPath, err := findSheetPath(archive, sheet)
+	if err != nil {
+		return "", err
+	}
+
+	sharedStrings, err := readSharedStrings(archive)
+	if err != nil {
+		return "", err
+	}
+
+	var data worksheet
+	if err := readXML(archive, sheetPath, &data); err != nil {
+		return "", err
+	}
+
+	rows := make([][]string, 0, len(data.Rows))
+	previous := 0 // Number of the previous row, or 0 before the first row
+	for _, row := range data.Rows {
+		// Rows without a number, or out of order, follow the previous row
+		number := row.Number
+		if number <= previous {
+			number = previous + 1
+		}
+		if previous > 0 {
+			// Rows left out of sparse sheets are empty
+			for i := previous + 1; i < number; i++ {
+				rows = append(rows, nil)
+			}
+		}
+		previous = number
+
+		values, err := rowToJSON(row, sharedStrings)
+		if err != nil {
+			return "", err
+		}
+		rows = append(rows, values)
+	}
+
+	compact, err := rowsToJSON(rows)
+	if err != nil {
+		return "", err
+	}
+
+	// The limit guards against malformed JSON input, not generated JSON
+	if config == nil {
+		config = jsonformat.DefaultConfig()
+	}
+	unlimited := *config
+	unlimited.MaxTokens = 0
+	return jsonformat.NewFormatter(&unlimited).Format(compact)
+}
+
+// rowsToJSON builds a compact JSON array of objects using the first row as keys
+func rowsToJSON(rows [][]string) (string, error) {
+	if len(rows) == 0 {
+		return "", jsonformat.NewFormatError("XLSX sheet is empty")
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, cell := range rows[0] {
+		var key string
+		if err := json.Unmarshal([]byte(cell), &key); err != nil {
+			// Non-string header cells are used verbatim as keys
+			key = cell
+		}
+		if cell == "null" {
+			key = columnName(i)
+		}
+		header[i] = key
+	}
+	header = uniqueKeys(header)
+
+	var builder strings.Builder
+	builder.WriteString("[")
+	for r, row := range rows[1:] {
+		if r > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString("{")
+		for i, key := range header {
+			if i > 0 {
+				builder.WriteString(",")
+			}
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return "", jsonformat.WrapFormatError("failed to encode XLSX header", err)
+			}
+			builder.Write(encodedKey)
+			builder.WriteString(":")
+			if i < len(row) {
+				builder.WriteString(row[i])
+			} else {
+				builder.WriteString("null")
+			}
+		}
+		builder.WriteString("}")
+	}
+	builder.WriteString("]")
+
+	return builder.String(), nil
+}
+
+// rowToJSON converts the cells of a row into JSON literals indexed by column
+func rowToJSON(row sheetRow, sharedStrings []string) ([]string, error) {
+	var values []string
+	for _, c := range row.Cells {
+		// Cells without a reference follow the previous cell
+		column := len(values)
+		if c.Ref != "" {
+			var err error
+			if column, err = columnIndex(c.Ref); err != nil {
+				return nil, err
+			}
+		}
+		if column < len(values) {
+			column = len(values)
+		}
+		for len(values) < column {
+			values = append(values, "null")
+		}
+		value, err := cellToJSON(c, sharedStrings)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// cellToJSON converts a single cell into a JSON literal based on its type
+func cellToJSON(c sheetCell, sharedStrings []string) (string, error) {
+	switch c.Type {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || index < 0 || index >= len(sharedStrings) {
+			return "", jsonformat.NewFormatError(fmt.Sprintf("invalid shared string index in cell %s", c.Ref))
+		}
+		return encodeString(sharedStrings[index])
+	case "inlineStr":
+		return encodeString(c.Inline.text())
+	case "str", "e":
+		return encodeString(c.Value)
+	case "b":
+		if strings.TrimSpace(c.Value) == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		value := strings.TrimSpace(c.Value)
+		if value == "" {
+			return "null", nil
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return encodeString(value)
+		}
+		number, err := json.Marshal(json.Number(value))
+		if err != nil {
+			return encodeString(value)
+		}
+		return string(number), nil
+	}
+}
+
+// encodeString encodes s as a JSON string literal
+func encodeString(s string) (string, error) {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return "", jsonformat.WrapFormatError("failed to encode XLSX cell", err)
+	}
+	return string(encoded), nil
+}
+
+// uniqueKeys adds a suffix to repeated header names, e.g. "name_2", so that
+// every key is unique
+func uniqueKeys(header []string) []string {
+	used := make(map[string]bool, len(header))
+	for _, name := range header {
+		used[name] = true
+	}
+	seen := make(map[string]int, len(header))
+	keys := make([]string, len(header))
+	for i, name := range header {
+		seen[name]++
+		key := name
+		for n := seen[name]; n > 1; n++ {
+			key = fmt.Sprintf("%s_%d", name, n)
+			if !used[key] {
+				seen[name] = n
+				break
+			}
+		}
+		used[key] = true
+		keys[i] = key
+	}
+	return keys
+}
+
+// columnIndex returns the zero-based column index of a cell reference like "AB12"
+func columnIndex(ref string) (int, error) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return 0, jsonformat.NewFormatError(fmt.Sprintf("invalid cell reference %q", ref))
+	}
+	return index - 1, nil
+}
+
+// columnName returns the spreadsheet column name for a zero-based index
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// findSheetPath resolves a sheet name to the path of its worksheet part
+func findSheetPath(archive *zip.Reader, sheet string) (string, error) {
+	var book workbook
+	if err := readXML(archive, "xl/workbook.xml", &book); err != nil {
+		return "", err
+	}
+	if len(book.Sheets) == 0 {
+		return "", jsonformat.NewFormatError("XLSX workbook contains no sheets")
+	}
+
+	relationID := ""
+	if sheet == "" {
+		relationID = book.Sheets[0].RelationID
+	} else {
+		for _, s := range book.Sheets {
+			if s.Name == sheet {
+				relationID = s.RelationID
+				break
+			}
+		}
+	}
+	if relationID == "" {
+		return "", jsonformat.NewFormatError(fmt.Sprintf("XLSX sheet %q not found", sheet))
+	}
+
+	var rels relationships
+	if err := readXML(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID == relationID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", jsonformat.NewFormatError(fmt.Sprintf("XLSX sheet %q has no worksheet part", sheet))
+}
+
+// readSharedStrings loads the shared string table, which is optional
+func readSharedStrings(archive *zip.Reader) ([]string, error) {
+	if !hasFile(archive, "xl/sharedStrings.xml") {
+		return nil, nil
+	}
+	var table sharedStringTable
+	if err := readXML(archive, "xl/sharedStrings.xml", &table); err != nil {
+		return nil, err
+	}
+	result := make([]string, len(table.Items))
+	for i, item := range table.Items {
+		result[i] = item.text()
+	}
+	return result, nil
+}
+
+// hasFile reports whether the archive contains the named file
+func hasFile(archive *zip.Reader, name string) bool {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readXML decodes the named XML part of the archive into v
+func readXML(archive *zip.Reader, name string, v interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return jsonformat.WrapFormatError(fmt.Sprintf("failed to open XLSX part %s", name), err)
+	}
+	defer file.Close()
+
+	if err := xml.NewDecoder(file).Decode(v); err != nil {
+		return jsonformat.WrapFormatError(fmt.Sprintf("failed to parse XLSX part %s", name), err)
+	}
+	return nil
+}
+
+type workbook struct {
+	Sheets []struct {
+		Name       string `xml:"name,attr"`
+		RelationID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type sharedStringTable struct {
+	Items []richText `xml:"si"`
+}
+
+// richText is a plain or rich-text string as stored in XLSX parts
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// text returns the plain text content of the string
+func (r richText) text() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var builder strings.Builder
+	builder.WriteString(r.Text)
+	for _, run := range r.Runs {
+		builder.WriteString(run.Text)
+	}
+	return builder.String()
+}
+
+type worksheet struct {
+	Rows []sheetRow `xml:"sheetData>row"`
+}
+
+type sheetRow struct {
+	Number int         `xml:"r,attr"`
+	Cells  []sheetCell `xml:"c"`
+}
+
+type sheetCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
@@ -0,0 +1,230 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shibukawa/jsonformat"
+)
+
+// buildWorkbook creates an in-memory XLSX archive with the given parts
+func buildWorkbook(t *testing.T, parts map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create part %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write part %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+var testParts = map[string]string{
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="Users" sheetId="1" r:id="rId1"/>
+    <sheet name="Other" sheetId="2" r:id="rId2"/>
+    <sheet name="Sparse" sheetId="3" r:id="rId3"/>
+  </sheets>
+</workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="worksheet" Target="worksheets/sheet2.xml"/>
+  <Relationship Id="rId3" Type="worksheet" Target="worksheets/sheet3.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>id</t></si>
+  <si><t>name</t></si>
+  <si><t>active</t></si>
+  <si><r><t>Ali</t></r><r><t>ce</t></r></si>
+</sst>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c></row>
+    <row r="2"><c r="A2"><v>1</v></c><c r="B2" t="s"><v>3</v></c><c r="C2" t="b"><v>1</v></c></row>
+    <row r="3"><c r="A3"><v>2.5</v></c><c r="C3" t="b"><v>0</v></c></row>
+  </sheetData>
+</worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="inlineStr"><is><t>key</t></is></c></row>
+    <row r="2"><c r="A2" t="inlineStr"><is><t>value</t></is></c></row>
+  </sheetData>
+</worksheet>`,
+	"xl/worksheets/sheet3.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="2"><c t="inlineStr"><is><t>a</t></is></c><c t="inlineStr"><is><t>b</t></is></c></row>
+    <row r="3"><c><v>1</v></c><c><v>2</v></c></row>
+    <row r="5"><c r="B5"><v>4</v></c></row>
+    <row><c><v>5</v></c></row>
+  </sheetData>
+</worksheet>`,
+}
+
+func TestReadAndFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		sheet    string
+		expected string
+	}{
+		{
+			name:  "first sheet by default",
+			sheet: "",
+			expected: `[
+  {
+    "id": 1,
+    "name": "Alice",
+    "active": true
+  },
+  {
+    "id": 2.5,
+    "name": null,
+    "active": false
+  }
+]`,
+		},
+		{
+			name:  "sparse rows and cells without references",
+			sheet: "Sparse",
+			expected: `[
+  {
+    "a": 1,
+    "b": 2
+  },
+  {
+    "a": null,
+    "b": null
+  },
+  {
+    "a": null,
+    "b": 4
+  },
+  {
+    "a": 5,
+    "b": null
+  }
+]`,
+		},
+		{
+			name:  "named sheet with inline strings",
+			sheet: "Other",
+			expected: `[
+  {
+    "key": "value"
+  }
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := buildWorkbook(t, testParts)
+			result, err := ReadAndFormat(reader, reader.Size(), tt.sheet, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReadAndFormatWithConfig(t *testing.T) {
+	reader := buildWorkbook(t, testParts)
+	config := jsonformat.NewConfig(jsonformat.WithCompactDepth(2))
+	result, err := ReadAndFormat(reader, reader.Size(), "Users", config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[
+  {"id": 1, "name": "Alice", "active": true},
+  {"id": 2.5, "name": null, "active": false}
+]`
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestReadAndFormatMissingSheet(t *testing.T) {
+	reader := buildWorkbook(t, testParts)
+	_, err := ReadAndFormat(reader, reader.Size(), "Missing", nil)
+	if err == nil {
+		t.Fatal("Expected error for missing sheet, got nil")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27}
+	for ref, expected := range tests {
+		index, err := columnIndex(ref)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", ref, err)
+		}
+		if index != expected {
+			t.Errorf("columnIndex(%q) = %d, expected %d", ref, index, expected)
+		}
+		if name := columnName(index); name+ref[len(name):] != ref {
+			t.Errorf("columnName(%d) = %q, does not match %q", index, name, ref)
+		}
+	}
+}
+
+func TestReadAndFormatLargeSheet(t *testing.T) {
+	var sheet strings.Builder
+	sheet.WriteString(`<worksheet><sheetData><row r="1"><c t="inlineStr"><is><t>id</t></is></c><c t="inlineStr"><is><t>value</t></is></c></row>`)
+	for i := 2; i <= 3001; i++ {
+		fmt.Fprintf(&sheet, `<row r="%d"><c><v>%d</v></c><c><v>%d</v></c></row>`, i, i, i*2)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	parts := make(map[string]string, len(testParts))
+	for name, content := range testParts {
+		parts[name] = content
+	}
+	parts["xl/worksheets/sheet1.xml"] = sheet.String()
+
+	reader := buildWorkbook(t, parts)
+	config := jsonformat.DefaultConfig()
+	result, err := ReadAndFormat(reader, reader.Size(), "Users", config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rows := strings.Count(result, `"value"`); rows != 3000 {
+		t.Errorf("Expected 3000 rows, got %d", rows)
+	}
+	if config.MaxTokens != jsonformat.DefaultConfig().MaxTokens {
+		t.Errorf("Expected the config to be unchanged, got MaxTokens %d", config.MaxTokens)
+	}
+}
+
+func TestRowsToJSONRepeatedHeaders(t *testing.T) {
+	result, err := rowsToJSON([][]string{
+		{`"name"`, `"name"`, `"name_2"`, `"name"`},
+		{`"a"`, `"b"`, `"c"`, `"d"`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `[{"name":"a","name_3":"b","name_2":"c","name_4":"d"}]`
+	if result != expected {
+		t.Errorf("Expected %s, got %s", expected, result)
+	}
+}
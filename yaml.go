@@ -0,0 +1,276 @@
+// Copyright 2024 Yoshiki Shibukawa
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// yamlIndent is the indentation of block scalars written in place of quoted
+// values, relative to their key or sequence entry
+const yamlIndent = 2
+
+// FormatJSONInYAML formats the JSON objects and arrays stored as strings in
+// a YAML document, such as Kubernetes annotations and Helm values, and
+// writes them as literal block scalars, so they can be read and reviewed.
+// Single-quoted, double-quoted, and literal block scalar values (| and |-)
+// whose text is a JSON object or array are formatted; the rest of the
+// document, including comments, is left as it is. Values that are not valid
+// JSON and multi-line quoted values are left unchanged.
+//
+// The YAML is scanned line by line rather than parsed, so keys and values
+// must follow the usual block style of configuration files.
+//
+// Example:
+//
+//	formatted, err := formatter.FormatJSONInYAML("metadata:\n  annotations:\n    config: '{\"a\":1}'\n")
+//	// metadata:
+//	//   annotations:
+//	//     config: |-
+//	//       {
+//	//         "a": 1
+//	//       }
+func (f *Formatter) FormatJSONInYAML(input string) (string, error) {
+	newline := "\n"
+	if strings.Contains(input, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(input, "\n")
+	if newline == "\r\n" {
+		for i, line := range lines {
+			lines[i] = strings.TrimSuffix(line, "\r")
+		}
+	}
+
+	var output []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		node, ok := parseYAMLNode(line)
+		if !ok {
+			output = append(output, line)
+			continue
+		}
+
+		if node.value == "|" || node.value == "|-" {
+			end, indent, text := yamlBlock(lines[i+1:], node.column)
+			formatted, err := f.formatEmbeddedYAML(text, i+2)
+			if err != nil {
+				return "", err
+			}
+			output = append(output, line)
+			if formatted == "" {
+				output = append(output, lines[i+1:i+1+end]...)
+			} else {
+				output = append(output, indentYAML(formatted, indent)...)
+			}
+			i += end
+			continue
+		}
+
+		text, ok := yamlQuoted(node.value)
+		if !ok {
+			output = append(output, line)
+			continue
+		}
+		formatted, err := f.formatEmbeddedYAML(text, i+1)
+		if err != nil {
+			return "", err
+		}
+		if formatted == "" {
+			output = append(output, line)
+			continue
+		}
+		header := node.head + "|-"
+		if strings.HasSuffix(text, "\n") {
+			header = node.head + "|"
+		}
+		if node.comment != "" {
+			header += " " + node.comment
+		}
+		output = append(output, header)
+		output = append(output, indentYAML(formatted, strings.Repeat(" ", node.column+yamlIndent))...)
+	}
+	return strings.Join(output, newline), nil
+}
+
+// formatEmbeddedYAML formats text if it is a JSON object or array, and
+// returns an empty string otherwise. Line is the line of the text in the
+// YAML document, for errors.
+func (f *Formatter) formatEmbeddedYAML(text string, line int) (string, error) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") || !json.Valid([]byte(trimmed)) {
+		return "", nil
+	}
+	formatted, err := f.Format(trimmed)
+	if err != nil {
+		return "", WrapFormatError(fmt.Sprintf("cannot format the JSON at line %d", line), err)
+	}
+	return formatted, nil
+}
+
+// yamlNode is a line of a YAML document with a scalar value
+type yamlNode struct {
+	column  int    // Column of the key, or of the sequence entry without a key
+	head    string // Line up to the value
+	value   string // Value without a trailing comment
+	comment string // Trailing comment, including #
+}
+
+// parseYAMLNode splits a line holding a key and a value, or a sequence
+// entry, into its parts
+func parseYAMLNode(line string) (yamlNode, bool) {
+	column := len(line) - len(strings.TrimLeft(line, " "))
+	rest := line[column:]
+	entry := false
+	for strings.HasPrefix(rest, "- ") {
+		entry = true
+		spaces := len(rest) - len(strings.TrimLeft(rest[1:], " "))
+		if strings.TrimSpace(rest[spaces:]) == "" {
+			return yamlNode{}, false
+		}
+		if !strings.HasPrefix(rest[spaces:], "- ") && yamlKeyEnd(rest[spaces:]) >= 0 {
+			column += spaces
+			entry = false
+		} else if strings.HasPrefix(rest[spaces:], "- ") {
+			column += spaces
+		}
+		rest = rest[spaces:]
+	}
+
+	start := len(line) - len(rest)
+	if !entry {
+		end := yamlKeyEnd(rest)
+		if end < 0 {
+			return yamlNode{}, false
+		}
+		start += end
+	}
+	value := strings.TrimLeft(line[start:], " ")
+	head := line[:len(line)-len(value)]
+
+	comment := ""
+	switch {
+	case strings.HasPrefix(value, "'") || strings.HasPrefix(value, `"`):
+		end := yamlQuoteEnd(value)
+		if end < 0 {
+			return yamlNode{}, false
+		}
+		comment = strings.TrimSpace(value[end:])
+		value = value[:end]
+	case strings.HasPrefix(value, "|"):
+		if i := strings.Index(value, " #"); i >= 0 {
+			comment = strings.TrimSpace(value[i:])
+			value = strings.TrimSpace(value[:i])
+		}
+		value = strings.TrimSpace(value)
+	default:
+		return yamlNode{}, false
+	}
+	if comment != "" && !strings.HasPrefix(comment, "#") {
+		return yamlNode{}, false
+	}
+	return yamlNode{column: column, head: head, value: value, comment: comment}, true
+}
+
+// yamlKeyEnd returns the offset after the ": " ending the key at the start
+// of s, or -1 if s does not start with a key and a value
+func yamlKeyEnd(s string) int {
+	if s == "" || strings.HasPrefix(s, "#") {
+		return -1
+	}
+	offset := 0
+	if s[0] == '\'' || s[0] == '"' {
+		offset = yamlQuoteEnd(s)
+		if offset < 0 {
+			return -1
+		}
+	}
+	i := strings.Index(s[offset:], ": ")
+	if i < 0 || strings.Contains(s[:offset+i], " #") {
+		return -1
+	}
+	return offset + i + 2
+}
+
+// yamlQuoteEnd returns the offset after the quoted scalar at the start of s,
+// or -1 if it does not end on the line
+func yamlQuoteEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// yamlQuoted returns the text of a quoted scalar. Double-quoted scalars are
+// decoded as JSON strings, so YAML-only escapes are not supported.
+func yamlQuoted(value string) (string, bool) {
+	if strings.HasPrefix(value, "'") {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), true
+	}
+	var text string
+	if err := json.Unmarshal([]byte(value), &text); err != nil {
+		return "", false
+	}
+	return text, true
+}
+
+// yamlBlock returns the number of lines of the block scalar at the start of
+// lines, whose content is indented more than column, the indentation of
+// its content, and its text. Trailing blank lines are not part of the block.
+func yamlBlock(lines []string, column int) (int, string, string) {
+	end := 0
+	indent := -1
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		lineIndent := len(line) - len(trimmed)
+		if lineIndent <= column {
+			break
+		}
+		if indent < 0 || lineIndent < indent {
+			indent = lineIndent
+		}
+		end = i + 1
+	}
+
+	text := make([]string, end)
+	for i, line := range lines[:end] {
+		if len(line) >= indent {
+			text[i] = line[indent:]
+		}
+	}
+	return end, strings.Repeat(" ", max(indent, 0)), strings.Join(text, "\n")
+}
+
+// indentYAML returns the lines of text with the indentation prefixed
+func indentYAML(text, indent string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = indent + line
+	}
+	return lines
+}
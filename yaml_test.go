@@ -0,0 +1,122 @@
+package jsonformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatJSONInYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "single-quoted annotation",
+			input: "metadata:\n  annotations:\n    config: '{\"it''s\":[1,2]}' # note\n",
+			expected: `metadata:
+  annotations:
+    config: |- # note
+      {
+        "it's": [
+          1,
+          2
+        ]
+      }
+`,
+		},
+		{
+			name:  "double-quoted value",
+			input: "value: \"{\\\"a\\\":{\\\"b\\\":null}}\\n\"\n",
+			expected: `value: |
+  {
+    "a": {
+      "b": null
+    }
+  }
+`,
+		},
+		{
+			name: "block scalar",
+			input: `kubectl.kubernetes.io/last-applied-configuration: |
+    {"kind":"ConfigMap","data":{"a":"b"}}
+
+next: 1
+`,
+			expected: `kubectl.kubernetes.io/last-applied-configuration: |
+    {
+      "kind": "ConfigMap",
+      "data": {
+        "a": "b"
+      }
+    }
+
+next: 1
+`,
+		},
+		{
+			name: "sequence entries",
+			input: `list:
+  - '[1,2]'
+  - name: x
+    value: '{"k":true}'
+  - - '{"deep":1}'
+`,
+			expected: `list:
+  - |-
+    [
+      1,
+      2
+    ]
+  - name: x
+    value: |-
+      {
+        "k": true
+      }
+  - - |-
+      {
+        "deep": 1
+      }
+`,
+		},
+		{
+			name:     "not JSON",
+			input:    "plain: hello\nbroken: '{oops'\nnumber: '42'\nflow: {\"a\": 1}\n# '{\"a\":1}'\n",
+			expected: "plain: hello\nbroken: '{oops'\nnumber: '42'\nflow: {\"a\": 1}\n# '{\"a\":1}'\n",
+		},
+		{
+			name:     "CRLF",
+			input:    "a: '{\"b\":1}'\r\nc: d\r\n",
+			expected: "a: |-\r\n  {\r\n    \"b\": 1\r\n  }\r\nc: d\r\n",
+		},
+	}
+
+	formatter := NewFormatter(DefaultConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatter.FormatJSONInYAML(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+
+			again, err := formatter.FormatJSONInYAML(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if again != result {
+				t.Errorf("Formatting again changed the output:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatJSONInYAMLError(t *testing.T) {
+	formatter := NewFormatter(NewConfig(WithMaxTokens(2)))
+	_, err := formatter.FormatJSONInYAML("a: 1\nb: '[1,2,3]'\n")
+	if err == nil || !strings.HasPrefix(err.Error(), "cannot format the JSON at line 2") {
+		t.Fatalf("Expected an error at line 2, got %v", err)
+	}
+}